@@ -8,10 +8,12 @@ import (
 	"time"
 
 	"github.com/joho/godotenv" //nolint:depguard
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/auth"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/http"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/postgres"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/tlsconfig"
 	"github.com/spf13/viper"
 )
 
@@ -49,6 +51,16 @@ var (
 	ErrGRPCServerTimeNotPositive              = errors.New("serverGRPC time cannot be negative")
 	ErrGRPCServerHost                         = errors.New("serverGRPC host must not be empty")
 	ErrGRPCServerPortWrongNumber              = errors.New("serverGRPC port must be a number")
+	ErrTLSCertWithoutKey                      = errors.New("tls cert file and key file must be set together")
+	ErrTLSClientCAWithoutCert                 = errors.New("tls client ca file requires cert file and key file")
+	ErrTLSFileNotExist                        = errors.New("tls file does not exist")
+	ErrTLSMinVersion                          = errors.New("tls min version must be 1.2 or 1.3")
+	ErrTLSCipherPolicy                        = errors.New("tls cipher policy must be default or strict")
+	ErrParseTLSReloadInterval                 = errors.New("invalid tls reload interval")
+	ErrTLSReloadIntervalNotPositive           = errors.New("tls reload interval must be greater than 0")
+	ErrParseAuthLeeway                        = errors.New("invalid auth leeway")
+	ErrAuthLeewayNegative                     = errors.New("auth leeway cannot be negative")
+	ErrParseAuthAPIKeys                       = errors.New("invalid auth api keys")
 )
 
 type Config struct {
@@ -57,6 +69,7 @@ type Config struct {
 	ServerGRPC  grpc.Config
 	StorageType string
 	Storage     postgres.Config
+	Auth        auth.Config
 }
 
 func NewConfig(path string) (*Config, error) {
@@ -107,6 +120,15 @@ func NewConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	authentication, err := newAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = validateAuthConfig(authentication)
+	if err != nil {
+		return nil, err
+	}
+
 	storageType := viper.GetString("storage.type")
 
 	config := Config{
@@ -115,6 +137,7 @@ func NewConfig(path string) (*Config, error) {
 		ServerGRPC:  serverGRPC,
 		StorageType: storageType,
 		Storage:     storage,
+		Auth:        authentication,
 	}
 
 	return &config, nil
@@ -147,11 +170,17 @@ func newServerHTTPConfig() (internalhttp.Config, error) {
 		return internalhttp.Config{}, ErrParseHTTPServerWriteTimeout
 	}
 
+	tlsConfig, err := newTLSConfig("server_http.tls")
+	if err != nil {
+		return internalhttp.Config{}, fmt.Errorf("serverHTTP %w", err)
+	}
+
 	return internalhttp.Config{
 		Host:         host,
 		Port:         port,
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
+		TLS:          tlsConfig,
 	}, nil
 }
 
@@ -183,6 +212,11 @@ func newServerGRPCConfig() (grpc.Config, error) {
 		return grpc.Config{}, ErrParseGRPCServerTimeout
 	}
 
+	tlsConfig, err := newTLSConfig("server_grpc.tls")
+	if err != nil {
+		return grpc.Config{}, fmt.Errorf("serverGRPC %w", err)
+	}
+
 	return grpc.Config{
 		Host:              host,
 		Port:              port,
@@ -190,6 +224,28 @@ func newServerGRPCConfig() (grpc.Config, error) {
 		MaxConnectionAge:  maxConnectionAge,
 		Time:              parsedTime,
 		Timeout:           timeout,
+		TLS:               tlsConfig,
+	}, nil
+}
+
+// newTLSConfig reads the TLS config of the server from the section with the key.
+func newTLSConfig(key string) (tlsconfig.Config, error) {
+	reloadInterval := tlsconfig.DefaultReloadInterval
+	if reloadIntervalStr := viper.GetString(key + ".reload_interval"); reloadIntervalStr != "" {
+		var err error
+		reloadInterval, err = time.ParseDuration(reloadIntervalStr)
+		if err != nil {
+			return tlsconfig.Config{}, ErrParseTLSReloadInterval
+		}
+	}
+
+	return tlsconfig.Config{
+		CertFile:       viper.GetString(key + ".cert_file"),
+		KeyFile:        viper.GetString(key + ".key_file"),
+		ClientCAFile:   viper.GetString(key + ".client_ca_file"),
+		MinVersion:     viper.GetString(key + ".min_version"),
+		CipherPolicy:   viper.GetString(key + ".cipher_policy"),
+		ReloadInterval: reloadInterval,
 	}, nil
 }
 
@@ -227,6 +283,46 @@ func newStoragePostgresConfig() (postgres.Config, error) {
 	}, nil
 }
 
+type authAPIKey struct {
+	Name   string `mapstructure:"name"`
+	UserID int    `mapstructure:"user_id"`
+	SHA256 string `mapstructure:"sha256"`
+}
+
+func newAuthConfig() (auth.Config, error) {
+	var leeway time.Duration
+	if leewayStr := viper.GetString("auth.leeway"); leewayStr != "" {
+		var err error
+		leeway, err = time.ParseDuration(leewayStr)
+		if err != nil {
+			return auth.Config{}, ErrParseAuthLeeway
+		}
+	}
+
+	var keys []authAPIKey
+	if err := viper.UnmarshalKey("auth.api_keys", &keys); err != nil {
+		return auth.Config{}, ErrParseAuthAPIKeys
+	}
+
+	apiKeys := make([]auth.APIKey, 0, len(keys))
+	for _, key := range keys {
+		apiKeys = append(apiKeys, auth.APIKey{
+			Name:   key.Name,
+			UserID: key.UserID,
+			SHA256: key.SHA256,
+		})
+	}
+
+	return auth.Config{
+		HMACSecret: viper.GetString("AUTH_HMAC_SECRET"),
+		JWKSFile:   viper.GetString("auth.jwks_file"),
+		Issuer:     viper.GetString("auth.issuer"),
+		Audience:   viper.GetString("auth.audience"),
+		Leeway:     leeway,
+		APIKeys:    apiKeys,
+	}, nil
+}
+
 func validateLoggerConfig(l logger.Config) error {
 	loggerLevels := map[string]struct{}{"INFO": {}, "DEBUG": {}, "ERROR": {}, "WARN": {}}
 	if _, ok := loggerLevels[l.Level]; !ok {
@@ -267,6 +363,9 @@ func validateServerHTTPConfig(s internalhttp.Config) error {
 	if s.ReadTimeout <= 0 {
 		return ErrHTTPServerWriteTimeoutNotPositive
 	}
+	if err := validateTLSConfig(s.TLS); err != nil {
+		return fmt.Errorf("serverHTTP %w", err)
+	}
 
 	return nil
 }
@@ -294,6 +393,52 @@ func validateServerGRPCConfig(s grpc.Config) error {
 	if s.Time < 0 {
 		return ErrGRPCServerTimeNotPositive
 	}
+	if err := validateTLSConfig(s.TLS); err != nil {
+		return fmt.Errorf("serverGRPC %w", err)
+	}
+
+	return nil
+}
+
+func validateTLSConfig(t tlsconfig.Config) error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return ErrTLSCertWithoutKey
+	}
+	if t.ClientCAFile != "" && t.CertFile == "" {
+		return ErrTLSClientCAWithoutCert
+	}
+	if _, err := tlsconfig.ParseVersion(t.MinVersion); err != nil {
+		return ErrTLSMinVersion
+	}
+	if _, err := tlsconfig.CipherSuites(t.CipherPolicy); err != nil {
+		return ErrTLSCipherPolicy
+	}
+	if !t.Enabled() {
+		return nil
+	}
+	if t.ReloadInterval <= 0 {
+		return ErrTLSReloadIntervalNotPositive
+	}
+	for _, path := range []string{t.CertFile, t.KeyFile, t.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%w: %s", ErrTLSFileNotExist, path)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAuthConfig leaves the keys to auth.New, which reports invalid ones by their names.
+func validateAuthConfig(a auth.Config) error {
+	if a.Leeway < 0 {
+		return ErrAuthLeewayNegative
+	}
 
 	return nil
 }
@@ -12,6 +12,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/auth"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
+	postgresfeed "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed/postgres"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/health"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/http"
@@ -31,6 +35,13 @@ var (
 
 var ErrInvalidStorageType = errors.New("invalid storage type")
 
+const (
+	// readinessTimeout limits every readiness check.
+	readinessTimeout = 2 * time.Second
+	// readinessInterval is how often the status of the gRPC health service is updated.
+	readinessInterval = 5 * time.Second
+)
+
 func init() {
 	flag.StringVar(&configFile, "config", "./configs/calendar_config.toml", "Path to configuration file")
 }
@@ -56,12 +67,18 @@ func main() {
 		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
-	var st storage.Storage
+	var (
+		st      storage.Storage
+		changes changefeed.Feed
+	)
+
+	checker := health.NewChecker(readinessTimeout)
 
 	// use memory storage or sql storage
 	switch cfg.StorageType {
 	case memSt:
 		st = memorystorage.NewStorageMemory()
+		changes = changefeed.NewBus(changefeed.DefaultBuffer)
 		logg.Info("use memory calendar storage")
 	case postgresSt:
 		postgresStorage := postgres.NewStoragePostgres()
@@ -75,6 +92,19 @@ func main() {
 		defer postgresStorage.Close()
 
 		st = postgresStorage
+		checker.Add("postgres", postgresStorage.Ping)
+
+		// changes made by other replicas are streamed through LISTEN on the connection of its own pool
+		feedDB, err := postgres.NewPool(ctx, cfg.Storage)
+		if err != nil {
+			logg.Error("error connecting calendar change feed db", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		defer feedDB.Close()
+
+		feed := postgresfeed.NewFeed(feedDB, postgresStorage, logg, changefeed.DefaultBuffer)
+		go feed.Listen(ctx)
+		changes = feed
 
 		logg.Info("use postgres calendar storage")
 	default:
@@ -82,20 +112,48 @@ func main() {
 		os.Exit(1)
 	}
 
-	services := service.NewService(st)
+	authenticator, err := auth.New(cfg.Auth)
+	if err != nil {
+		logg.Error("calendar auth", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	services := service.NewService(st, changes)
 
 	handlerHTTP := internalhttp.NewHandlerHTTP(services, logg)
 	handlerGRPC := grpc.NewHandlerGRPC(services, logg)
 
-	serverHTTP := internalhttp.NewServerHTTP(cfg.ServerHTTP, handlerHTTP.InitRoutes(cfg.Logger.LogFilePath))
-	serverGRPC := grpc.NewServerGRPC(handlerGRPC, logg, cfg.ServerGRPC, cfg.Logger.LogFilePath)
+	serverHTTP, err := internalhttp.NewServerHTTP(cfg.ServerHTTP,
+		handlerHTTP.InitRoutes(cfg.Logger.LogFilePath, checker, authenticator))
+	if err != nil {
+		logg.Error("error creating HTTPServer", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	serverGRPC, err := grpc.NewServerGRPC(handlerGRPC, logg, cfg.ServerGRPC, cfg.Logger.LogFilePath, authenticator)
+	if err != nil {
+		logg.Error("error creating GRPCServer", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// renewed certificates are served without restarting the servers
+	go serverHTTP.WatchTLS(ctx, func(err error) {
+		logg.Error("error reloading HTTPServer certificate", slog.String("error", err.Error()))
+	})
+	go serverGRPC.WatchTLS(ctx, func(err error) {
+		logg.Error("error reloading GRPCServer certificate", slog.String("error", err.Error()))
+	})
+
+	go checker.Watch(ctx, readinessInterval, serverGRPC.SetServing)
 
 	go func() {
 		<-ctx.Done()
 
+		checker.Shutdown()
+
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 		defer cancel()
 
+		handlerHTTP.StopStreams()
 		if err := serverHTTP.Stop(ctx); err != nil {
 			logg.Error("error stopping HTTPServer",
 				slog.String("address http", net.JoinHostPort(cfg.ServerHTTP.Host, cfg.ServerHTTP.Port)))
@@ -9,6 +9,9 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/rabbitmq"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/outbox"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/scheduler"
+	internalhttp "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/http"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/postgres"
 	"github.com/spf13/viper"
 )
@@ -26,6 +29,10 @@ var (
 	ErrRabbitSchedulerEmptyRoutingKey     = errors.New("routingKey cannot be empty")
 	ErrRabbitSchedulerInvalidDeliveryMode = errors.New("invalid delivery mode: Transient (0 or 1) or Persistent (2)")
 	ErrRabbitSchedulerEmptyQueueName      = errors.New("queueName cannot be empty")
+	ErrRabbitSchedulerParseMinBackoff     = errors.New("invalid reconnect min backoff")
+	ErrRabbitSchedulerParseMaxBackoff     = errors.New("invalid reconnect max backoff")
+	ErrRabbitSchedulerMinBackoff          = errors.New("reconnect min backoff must be greater than 0")
+	ErrRabbitSchedulerIncompatibleBackoff = errors.New("reconnect max backoff must be greater or equal to min backoff")
 	ErrLoggerLevel                        = errors.New("invalid logger level")
 	ErrLoggerRepresentation               = errors.New("invalid logger representation")
 	ErrDBHost                             = errors.New("database host must not be empty")
@@ -40,15 +47,47 @@ var (
 	ErrParseMaxConnIdleTime               = errors.New("database errors parse MaxConnIdleTime")
 	ErrDBMaxConnLifetimeNotPositive       = errors.New("database MaxConnLifetime must be greater than 0")
 	ErrDBMaxConnIdleTimeNotPositive       = errors.New("database MaxConnIdleTime must be greater than 0")
+	ErrOutboxParseInterval                = errors.New("invalid outbox interval")
+	ErrOutboxParseMinBackoff              = errors.New("invalid outbox min backoff")
+	ErrOutboxParseMaxBackoff              = errors.New("invalid outbox max backoff")
+	ErrOutboxParseLease                   = errors.New("invalid outbox lease")
+	ErrOutboxIntervalNotPositive          = errors.New("outbox interval must be greater than 0")
+	ErrOutboxBatchSizeNotPositive         = errors.New("outbox batch size must be greater than 0")
+	ErrOutboxMinBackoffNotPositive        = errors.New("outbox min backoff must be greater than 0")
+	ErrOutboxIncompatibleBackoffs         = errors.New("outbox max backoff must be greater or equal to min backoff")
+	ErrOutboxLeaseNotPositive             = errors.New("outbox lease must be greater than 0")
+	ErrSchedulerParseInterval             = errors.New("invalid scheduler interval")
+	ErrSchedulerParseWindow               = errors.New("invalid scheduler window")
+	ErrSchedulerParseGrace                = errors.New("invalid scheduler grace")
+	ErrSchedulerParseLease                = errors.New("invalid scheduler lease")
+	ErrSchedulerIntervalNotPositive       = errors.New("scheduler interval must be greater than 0")
+	ErrSchedulerWindowNegative            = errors.New("scheduler window cannot be negative")
+	ErrSchedulerGraceNegative             = errors.New("scheduler grace cannot be negative")
+	ErrSchedulerLeaseNotPositive          = errors.New("scheduler lease must be greater than 0")
+	ErrSchedulerBatchSizeNotPositive      = errors.New("scheduler batch size must be greater than 0")
+	ErrInvalidMQType                      = errors.New("invalid mq type: rabbit, postgres, memory")
+	ErrMQPostgresStorage                  = errors.New("postgres mq requires postgres storage")
+	ErrHTTPServerHost                     = errors.New("serverHTTP host must not be empty")
+	ErrHTTPServerPortNotNumber            = errors.New("serverHTTP port must be a number")
+	ErrHTTPServerPortWrongNumber          = errors.New("serverHTTP port must be in the interval from 0 to 65535")
+	ErrParseHTTPServerReadTimeout         = errors.New("invalid serverHTTP read timeout")
+	ErrParseHTTPServerWriteTimeout        = errors.New("invalid serverHTTP write timeout")
+	ErrHTTPServerReadTimeoutNotPositive   = errors.New("serverHTTP read timeout must be greater than 0")
+	ErrHTTPServerWriteTimeoutNotPositive  = errors.New("serverHTTP write timeout must be greater than 0")
 )
 
 type Config struct {
+	// MQType is rabbit, postgres or memory. The postgres queue is kept in the storage database.
+	MQType               string
 	MQ                   rabbitmq.ProducerConfig
 	Logger               logger.Config
 	StorageType          string
 	Storage              postgres.Config
-	TimeToSchedule       time.Duration
+	Outbox               outbox.Config
+	Scheduler            scheduler.Config
 	TimeToDeleteOutdated time.Duration
+	// ServerHTTP serves health checks and metrics.
+	ServerHTTP internalhttp.Config
 }
 
 func NewConfig(path string) (*Config, error) {
@@ -66,13 +105,28 @@ func NewConfig(path string) (*Config, error) {
 	viper.SetEnvPrefix("scheduler")
 	viper.AutomaticEnv()
 
-	rabbitConfig, err := newRabbitSchedulerConfig()
-	if err != nil {
-		return nil, err
-	}
-	err = validateRabbitSchedulerConfig(rabbitConfig)
-	if err != nil {
-		return nil, err
+	mqType := viper.GetString("mq.type")
+	storageType := viper.GetString("storage.type")
+
+	var rabbitConfig rabbitmq.ProducerConfig
+
+	switch mqType {
+	case rabbitMQ:
+		rabbitConfig, err = newRabbitSchedulerConfig()
+		if err != nil {
+			return nil, err
+		}
+		err = validateRabbitSchedulerConfig(rabbitConfig)
+		if err != nil {
+			return nil, err
+		}
+	case postgresMQ:
+		if storageType != postgresSt {
+			return nil, ErrMQPostgresStorage
+		}
+	case memoryMQ:
+	default:
+		return nil, ErrInvalidMQType
 	}
 
 	log := newLoggerConfig()
@@ -90,10 +144,29 @@ func NewConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
-	storageType := viper.GetString("storage.type")
+	outboxConfig, err := newOutboxConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = validateOutboxConfig(outboxConfig)
+	if err != nil {
+		return nil, err
+	}
 
-	timeToScheduleStr := viper.GetString("general_preferences.time_to_schedule")
-	timeToSchedule, err := time.ParseDuration(timeToScheduleStr)
+	schedulerConfig, err := newSchedulerConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = validateSchedulerConfig(schedulerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	serverHTTP, err := newServerHTTPConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = validateServerHTTPConfig(serverHTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -105,12 +178,15 @@ func NewConfig(path string) (*Config, error) {
 	}
 
 	config := Config{
+		MQType:               mqType,
 		MQ:                   rabbitConfig,
 		Logger:               log,
 		StorageType:          storageType,
 		Storage:              storage,
-		TimeToSchedule:       timeToSchedule,
+		Outbox:               outboxConfig,
+		Scheduler:            schedulerConfig,
 		TimeToDeleteOutdated: timeToDeleteOutdated,
+		ServerHTTP:           serverHTTP,
 	}
 
 	return &config, nil
@@ -138,21 +214,33 @@ func newRabbitSchedulerConfig() (rabbitmq.ProducerConfig, error) {
 	routingKey := viper.GetString("rabbit_scheduler.routing_key")
 	deliveryMode := viper.GetInt("rabbit_scheduler.delivery_mode")
 
+	reconnectMinBackoff, err := time.ParseDuration(viper.GetString("rabbit_scheduler.reconnect_min_backoff"))
+	if err != nil {
+		return rabbitmq.ProducerConfig{}, ErrRabbitSchedulerParseMinBackoff
+	}
+
+	reconnectMaxBackoff, err := time.ParseDuration(viper.GetString("rabbit_scheduler.reconnect_max_backoff"))
+	if err != nil {
+		return rabbitmq.ProducerConfig{}, ErrRabbitSchedulerParseMaxBackoff
+	}
+
 	return rabbitmq.ProducerConfig{
-		Username:           username,
-		Password:           password,
-		Host:               host,
-		Port:               port,
-		Heartbeat:          heartbeat,
-		ExchangeName:       exchangeName,
-		QueueName:          queueName,
-		ExchangeType:       exchangeType,
-		DurableExchange:    durableExchange,
-		DurableQueue:       durableQueue,
-		AutoDeleteExchange: autoDeleteExchange,
-		AutoDeleteQueue:    autoDeleteQueue,
-		RoutingKey:         routingKey,
-		DeliveryMode:       deliveryMode,
+		Username:            username,
+		Password:            password,
+		Host:                host,
+		Port:                port,
+		Heartbeat:           heartbeat,
+		ExchangeName:        exchangeName,
+		QueueName:           queueName,
+		ExchangeType:        exchangeType,
+		DurableExchange:     durableExchange,
+		DurableQueue:        durableQueue,
+		AutoDeleteExchange:  autoDeleteExchange,
+		AutoDeleteQueue:     autoDeleteQueue,
+		RoutingKey:          routingKey,
+		DeliveryMode:        deliveryMode,
+		ReconnectMinBackoff: reconnectMinBackoff,
+		ReconnectMaxBackoff: reconnectMaxBackoff,
 	}, nil
 }
 
@@ -191,6 +279,12 @@ func validateRabbitSchedulerConfig(s rabbitmq.ProducerConfig) error {
 	if s.DeliveryMode < 0 || s.DeliveryMode > 2 {
 		return ErrRabbitSchedulerInvalidDeliveryMode
 	}
+	if s.ReconnectMinBackoff <= 0 {
+		return ErrRabbitSchedulerMinBackoff
+	}
+	if s.ReconnectMaxBackoff < s.ReconnectMinBackoff {
+		return ErrRabbitSchedulerIncompatibleBackoff
+	}
 	return nil
 }
 
@@ -289,3 +383,148 @@ func validateStoragePostgresConfig(st postgres.Config) error {
 
 	return nil
 }
+
+func newOutboxConfig() (outbox.Config, error) {
+	interval, err := time.ParseDuration(viper.GetString("outbox.interval"))
+	if err != nil {
+		return outbox.Config{}, ErrOutboxParseInterval
+	}
+
+	minBackoff, err := time.ParseDuration(viper.GetString("outbox.min_backoff"))
+	if err != nil {
+		return outbox.Config{}, ErrOutboxParseMinBackoff
+	}
+
+	maxBackoff, err := time.ParseDuration(viper.GetString("outbox.max_backoff"))
+	if err != nil {
+		return outbox.Config{}, ErrOutboxParseMaxBackoff
+	}
+
+	lease, err := time.ParseDuration(viper.GetString("outbox.lease"))
+	if err != nil {
+		return outbox.Config{}, ErrOutboxParseLease
+	}
+
+	return outbox.Config{
+		Interval:   interval,
+		BatchSize:  viper.GetInt("outbox.batch_size"),
+		Lease:      lease,
+		MinBackoff: minBackoff,
+		MaxBackoff: maxBackoff,
+	}, nil
+}
+
+func validateOutboxConfig(o outbox.Config) error {
+	if o.Interval <= 0 {
+		return ErrOutboxIntervalNotPositive
+	}
+	if o.BatchSize <= 0 {
+		return ErrOutboxBatchSizeNotPositive
+	}
+	if o.MinBackoff <= 0 {
+		return ErrOutboxMinBackoffNotPositive
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		return ErrOutboxIncompatibleBackoffs
+	}
+	if o.Lease <= 0 {
+		return ErrOutboxLeaseNotPositive
+	}
+
+	return nil
+}
+
+func newSchedulerConfig() (scheduler.Config, error) {
+	interval, err := time.ParseDuration(viper.GetString("scheduler.interval"))
+	if err != nil {
+		return scheduler.Config{}, ErrSchedulerParseInterval
+	}
+
+	window, err := time.ParseDuration(viper.GetString("scheduler.window"))
+	if err != nil {
+		return scheduler.Config{}, ErrSchedulerParseWindow
+	}
+
+	grace, err := time.ParseDuration(viper.GetString("scheduler.grace"))
+	if err != nil {
+		return scheduler.Config{}, ErrSchedulerParseGrace
+	}
+
+	lease, err := time.ParseDuration(viper.GetString("scheduler.lease"))
+	if err != nil {
+		return scheduler.Config{}, ErrSchedulerParseLease
+	}
+
+	return scheduler.Config{
+		Interval:  interval,
+		Window:    window,
+		Grace:     grace,
+		Lease:     lease,
+		BatchSize: viper.GetInt("scheduler.batch_size"),
+	}, nil
+}
+
+func validateSchedulerConfig(s scheduler.Config) error {
+	if s.Interval <= 0 {
+		return ErrSchedulerIntervalNotPositive
+	}
+	if s.Window < 0 {
+		return ErrSchedulerWindowNegative
+	}
+	if s.Grace < 0 {
+		return ErrSchedulerGraceNegative
+	}
+	if s.Lease <= 0 {
+		return ErrSchedulerLeaseNotPositive
+	}
+	if s.BatchSize <= 0 {
+		return ErrSchedulerBatchSizeNotPositive
+	}
+
+	return nil
+}
+
+func newServerHTTPConfig() (internalhttp.Config, error) {
+	host := viper.GetString("server_http.host")
+	port := viper.GetString("server_http.port")
+
+	readTimeoutStr := viper.GetString("server_http.read_timeout")
+	readTimeout, err := time.ParseDuration(readTimeoutStr)
+	if err != nil {
+		return internalhttp.Config{}, ErrParseHTTPServerReadTimeout
+	}
+
+	writeTimeoutStr := viper.GetString("server_http.write_timeout")
+	writeTimeout, err := time.ParseDuration(writeTimeoutStr)
+	if err != nil {
+		return internalhttp.Config{}, ErrParseHTTPServerWriteTimeout
+	}
+
+	return internalhttp.Config{
+		Host:         host,
+		Port:         port,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}, nil
+}
+
+func validateServerHTTPConfig(s internalhttp.Config) error {
+	if s.Host == "" {
+		return ErrHTTPServerHost
+	}
+	port, err := strconv.Atoi(s.Port)
+	if err != nil {
+		return ErrHTTPServerPortNotNumber
+	}
+	if port < 0 || port > 65535 {
+		return ErrHTTPServerPortWrongNumber
+	}
+	if s.ReadTimeout <= 0 {
+		return ErrHTTPServerReadTimeoutNotPositive
+	}
+	if s.WriteTimeout <= 0 {
+		return ErrHTTPServerWriteTimeoutNotPositive
+	}
+
+	return nil
+}
@@ -2,19 +2,27 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/health"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/metrics"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	memoryqueue "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/memory"
+	postgresqueue "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/postgres"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/rabbitmq"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/outbox"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/scheduler"
+	internalhttp "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/http"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage"
 	memorystorage "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/memory"
@@ -29,8 +37,17 @@ var (
 	postgresSt = "postgres"
 )
 
+var (
+	rabbitMQ   = "rabbit"
+	postgresMQ = "postgres"
+	memoryMQ   = "memory"
+)
+
 var ErrInvalidStorageType = errors.New("invalid storage type")
 
+// readinessTimeout limits every readiness check.
+const readinessTimeout = 2 * time.Second
+
 func init() {
 	flag.StringVar(&configFile, "config", "./configs/scheduler_config.toml", "Path to configuration file")
 }
@@ -50,6 +67,8 @@ func main() {
 
 	var st storage.Storage
 
+	checker := health.NewChecker(readinessTimeout)
+
 	// use memory storage or sql storage
 	switch cfg.StorageType {
 	case memSt:
@@ -67,6 +86,7 @@ func main() {
 		defer postgresStorage.Close()
 
 		st = postgresStorage
+		checker.Add("postgres", postgresStorage.Ping)
 
 		logg.Info("use postgres scheduler storage")
 	default:
@@ -74,29 +94,77 @@ func main() {
 		os.Exit(1)
 	}
 
-	services := service.NewService(st)
+	// the scheduler only deletes outdated events which are not streamed to the watchers
+	services := service.NewService(st, changefeed.NewBus(changefeed.DefaultBuffer))
 
-	scheduler, err := rabbitmq.NewProducer(cfg.MQ, logg)
-	if err != nil {
-		cancel()
-		logg.Error("error connecting scheduler rabbit",
-			slog.String("errors", err.Error()),
-			slog.String("address", cfg.MQ.Host+":"+strconv.Itoa(cfg.MQ.Port)))
+	var notificationProducer mq.NotificationProducer
+
+	switch cfg.MQType {
+	case postgresMQ:
+		db, err := postgres.NewPool(ctx, cfg.Storage)
+		if err != nil {
+			logg.Error("error connecting scheduler queue db", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		notificationProducer = postgresqueue.NewQueue(db, logg, postgresqueue.Config{})
+		checker.Add("queue", db.Ping)
+		logg.Info("use postgres scheduler queue")
+	case memoryMQ:
+		// without the broker notifications are logged by the scheduler itself
+		queue := memoryqueue.NewQueue(mq.RetryPolicy{Attempts: 1})
+		go logNotifications(queue, logg)
+
+		notificationProducer = queue
+		logg.Info("use memory scheduler queue")
+	default:
+		// the producer reconnects in the background, the relay keeps messages in the outbox until it is connected
+		rabbitProducer := rabbitmq.NewProducer(cfg.MQ, logg)
+		checker.Add("rabbit", rabbitProducer.Ping)
+
+		notificationProducer = rabbitProducer
+		logg.Info("use rabbit scheduler queue")
 	}
 
-	producer := mq.NewProducer(scheduler)
+	producer := mq.NewProducer(notificationProducer)
+
+	relay := outbox.NewRelay(st, producer, logg, cfg.Outbox)
+	go relay.Run(ctx)
+
+	go scheduler.New(services.Notification, logg, cfg.Scheduler).Run(ctx)
+
+	serverHTTP, err := internalhttp.NewServerHTTP(cfg.ServerHTTP, health.Handler(checker, metrics.Default.Handler()))
+	if err != nil {
+		logg.Error("error creating scheduler HTTPServer", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	go func() {
+		if err := serverHTTP.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logg.Error("error starting scheduler HTTPServer",
+				slog.String("error", err.Error()),
+				slog.String("address http", net.JoinHostPort(cfg.ServerHTTP.Host, cfg.ServerHTTP.Port)))
+		}
+	}()
 
-	tickerScheduler := time.NewTicker(cfg.TimeToSchedule)
 	tickerDeleteOutdated := time.NewTicker(cfg.TimeToDeleteOutdated)
 	done := make(chan struct{})
 
 	go func() {
 		<-ctx.Done()
 
+		checker.Shutdown()
+
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), 3*time.Second)
+		if err := serverHTTP.Stop(stopCtx); err != nil {
+			logg.Error("error stopping scheduler HTTPServer", slog.String("error", err.Error()))
+		}
+		stopCancel()
+
 		if err := producer.Shutdown(); err != nil {
-			logg.Error("error stopping rabbit scheduler",
+			logg.Error("error stopping scheduler queue",
 				slog.String("error", err.Error()),
-				slog.String("address", cfg.MQ.Host+":"+strconv.Itoa(cfg.MQ.Port)))
+				slog.String("mq", cfg.MQType))
 		}
 
 		done <- struct{}{}
@@ -106,49 +174,6 @@ func main() {
 
 	for {
 		select {
-		case <-tickerScheduler.C:
-			notifications, err := services.Notification.GetNotificationInAdvance(ctx)
-			if err != nil {
-				logg.Error("error getting notification", slog.String("error", err.Error()))
-			}
-
-			for _, notification := range notifications {
-				notificationMessageDate := notification.Date.Add(-notification.Interval).UTC()
-				now := time.Now().UTC()
-
-				// send notification ten seconds before it must happen
-				if !(notificationMessageDate.Sub(now) >= time.Second*1 && notificationMessageDate.Sub(now) < time.Second*11) {
-					continue
-				}
-
-				msg := mq.Message{
-					EventID: notification.EventID,
-					Title:   notification.Title,
-					Date:    notification.Date,
-					UserID:  notification.UserID,
-				}
-
-				body, err := json.Marshal(msg)
-				if err != nil {
-					logg.Error("error marshal notification",
-						slog.Any("notification", msg),
-						slog.String("error", err.Error()))
-				}
-
-				err = producer.Publish(ctx, body)
-				if err != nil {
-					logg.Error("error publish notification",
-						slog.Any("notification", msg),
-						slog.String("error", err.Error()))
-				}
-
-				err = services.Notification.UpdateScheduledNotification(ctx, notification.EventID)
-				if err != nil {
-					logg.Error("error updating scheduled notification",
-						slog.String("notification id", notification.EventID),
-						slog.String("error", err.Error()))
-				}
-			}
 		case <-tickerDeleteOutdated.C:
 			err := services.Event.DeleteOutdatedEvents(ctx)
 			if err != nil {
@@ -159,3 +184,19 @@ func main() {
 		}
 	}
 }
+
+// logNotifications logs and acks every notification published to the memory queue.
+func logNotifications(queue *memoryqueue.Queue, logg logger.Logger) {
+	notifications, _ := queue.Consume()
+
+	for notification := range notifications {
+		if notification.Err != nil {
+			logg.Error("error receiving notification", slog.String("error", notification.Err.Error()))
+			_ = notification.Delivery.Reject(context.Background(), notification.Err)
+			continue
+		}
+
+		logg.Info("notification is received", slog.Any("notification", notification.Message))
+		_ = notification.Delivery.Ack()
+	}
+}
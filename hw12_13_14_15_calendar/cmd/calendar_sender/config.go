@@ -3,34 +3,122 @@ package main
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	postgresqueue "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/postgres"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/rabbitmq"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/notifier"
+	internalhttp "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/http"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/postgres"
 	"github.com/spf13/viper"
 )
 
 var (
-	ErrRabbitSenderEmptyUsername        = errors.New("username cannot be empty")
-	ErrRabbitSenderEmptyPassword        = errors.New("password cannot be empty")
-	ErrRabbitSenderEmptyHost            = errors.New("host cannot be empty")
-	ErrRabbitSenderInvalidPort          = errors.New("port must be between 0 and 65535")
-	ErrRabbitSenderNegativeHeartbeat    = errors.New("heartbeat cannot be negative")
-	ErrRabbitSenderEmptyExchangeName    = errors.New("exchangeName cannot be empty")
-	ErrRabbitSenderEmptyExchangeType    = errors.New("exchangeType cannot be empty")
-	ErrRabbitSenderEmptyQueueName       = errors.New("queueName cannot be empty")
-	ErrRabbitSenderEmptyRoutingKey      = errors.New("routingKey cannot be empty")
-	ErrRabbitSenderEmptyTag             = errors.New("tag cannot be empty")
-	ErrRabbitSenderConfigParseHeartbeat = errors.New("invalid heartbeat")
-	ErrRabbitSenderInvalidExchangeType  = errors.New("invalid exchange type: direct, fanout, headers, topic")
-	ErrLoggerLevel                      = errors.New("invalid logger level")
-	ErrLoggerRepresentation             = errors.New("invalid logger representation")
+	ErrRabbitSenderEmptyUsername           = errors.New("username cannot be empty")
+	ErrRabbitSenderEmptyPassword           = errors.New("password cannot be empty")
+	ErrRabbitSenderEmptyHost               = errors.New("host cannot be empty")
+	ErrRabbitSenderInvalidPort             = errors.New("port must be between 0 and 65535")
+	ErrRabbitSenderNegativeHeartbeat       = errors.New("heartbeat cannot be negative")
+	ErrRabbitSenderEmptyExchangeName       = errors.New("exchangeName cannot be empty")
+	ErrRabbitSenderEmptyExchangeType       = errors.New("exchangeType cannot be empty")
+	ErrRabbitSenderEmptyQueueName          = errors.New("queueName cannot be empty")
+	ErrRabbitSenderEmptyRoutingKey         = errors.New("routingKey cannot be empty")
+	ErrRabbitSenderEmptyTag                = errors.New("tag cannot be empty")
+	ErrRabbitSenderConfigParseHeartbeat    = errors.New("invalid heartbeat")
+	ErrRabbitSenderInvalidExchangeType     = errors.New("invalid exchange type: direct, fanout, headers, topic")
+	ErrRabbitSenderEmptyDeadLetterExchange = errors.New("deadLetterExchange cannot be empty")
+	ErrRabbitSenderEmptyDeadLetterQueue    = errors.New("deadLetterQueue cannot be empty")
+	ErrRabbitSenderParseMinBackoff         = errors.New("invalid retry min backoff")
+	ErrRabbitSenderParseMaxBackoff         = errors.New("invalid retry max backoff")
+	ErrRabbitSenderRetryAttempts           = errors.New("retry attempts must be greater than 0")
+	ErrRabbitSenderMinBackoff              = errors.New("retry min backoff must be greater than 0")
+	ErrRabbitSenderIncompatibleBackoffs    = errors.New("retry max backoff must be greater or equal to min backoff")
+	ErrRabbitSenderParseReconnectMin       = errors.New("invalid reconnect min backoff")
+	ErrRabbitSenderParseReconnectMax       = errors.New("invalid reconnect max backoff")
+	ErrRabbitSenderReconnectMin            = errors.New("reconnect min backoff must be greater than 0")
+	ErrRabbitSenderReconnectBackoffs       = errors.New("reconnect max backoff must be greater or equal to min backoff")
+	ErrLoggerLevel                         = errors.New("invalid logger level")
+	ErrLoggerRepresentation                = errors.New("invalid logger representation")
+	ErrNotifierInvalidDefaultChannel       = errors.New("invalid notifier default channel: log, email, webhook")
+	ErrNotifierParseSMTPTimeout            = errors.New("invalid notifier smtp timeout")
+	ErrNotifierInvalidSMTPPort             = errors.New("notifier smtp port must be between 0 and 65535")
+	ErrNotifierEmptySMTPFrom               = errors.New("notifier smtp from cannot be empty")
+	ErrNotifierParseWebhookTimeout         = errors.New("invalid notifier webhook timeout")
+	ErrNotifierParseMinBackoff             = errors.New("invalid notifier channel min backoff")
+	ErrNotifierParseMaxBackoff             = errors.New("invalid notifier channel max backoff")
+	ErrNotifierNegativeAttempts            = errors.New("notifier channel attempts cannot be negative")
+	ErrNotifierIncompatibleBackoffs        = errors.New("notifier channel max backoff must be greater or equal to min backoff")
+	ErrNotifierNegativeRate                = errors.New("notifier channel rate cannot be negative")
+	ErrNotifierNegativeBurst               = errors.New("notifier channel burst cannot be negative")
+	ErrNotifierParseUsers                  = errors.New("invalid notifier users")
+	ErrNotifierInvalidUserChannel          = errors.New("invalid notifier user channel: log, email, webhook")
+	ErrInvalidMQType                       = errors.New("invalid mq type: rabbit, postgres")
+	ErrInvalidDedupType                    = errors.New("invalid dedup type: memory, postgres")
+	ErrDedupParseLease                     = errors.New("invalid dedup lease")
+	ErrDedupParseTTL                       = errors.New("invalid dedup ttl")
+	ErrDedupLeaseNotPositive               = errors.New("dedup lease must be greater than 0")
+	ErrDedupIncompatibleTTL                = errors.New("dedup ttl must be greater or equal to lease")
+	ErrHTTPServerHost                      = errors.New("serverHTTP host must not be empty")
+	ErrHTTPServerPortNotNumber             = errors.New("serverHTTP port must be a number")
+	ErrHTTPServerPortWrongNumber           = errors.New("serverHTTP port must be in the interval from 0 to 65535")
+	ErrParseHTTPServerReadTimeout          = errors.New("invalid serverHTTP read timeout")
+	ErrParseHTTPServerWriteTimeout         = errors.New("invalid serverHTTP write timeout")
+	ErrHTTPServerReadTimeoutNotPositive    = errors.New("serverHTTP read timeout must be greater than 0")
+	ErrHTTPServerWriteTimeoutNotPositive   = errors.New("serverHTTP write timeout must be greater than 0")
+	ErrDBHost                              = errors.New("database host must not be empty")
+	ErrDBPortNotNumber                     = errors.New("database port must be a number")
+	ErrDBPortWrongNumber                   = errors.New("database port must be in the interval from 0 to 65535")
+	ErrDBInvalidDBName                     = errors.New("database name must not be empty")
+	ErrDBInvalidSSLMode                    = errors.New("invalid database ssl mode")
+	ErrDBMaxConns                          = errors.New("database max conns must be greater than 0")
+	ErrDBMinConns                          = errors.New("database min conns must be greater than 0")
+	ErrDBIncompatibleMaxAndMinConns        = errors.New("database max conns must be greater or equal to min conns")
+	ErrParseMaxConnLifetime                = errors.New("database errors parse MaxConnLifetime")
+	ErrParseMaxConnIdleTime                = errors.New("database errors parse MaxConnIdleTime")
+	ErrDBMaxConnLifetimeNotPositive        = errors.New("database MaxConnLifetime must be greater than 0")
+	ErrDBMaxConnIdleTimeNotPositive        = errors.New("database MaxConnIdleTime must be greater than 0")
+	ErrQueueParsePollInterval              = errors.New("invalid queue poll interval")
+	ErrQueueParseLease                     = errors.New("invalid queue lease")
+	ErrQueueParseMinBackoff                = errors.New("invalid queue retry min backoff")
+	ErrQueueParseMaxBackoff                = errors.New("invalid queue retry max backoff")
+	ErrQueuePollIntervalNotPositive        = errors.New("queue poll interval must be greater than 0")
+	ErrQueueLeaseNotPositive               = errors.New("queue lease must be greater than 0")
+	ErrQueueBatchSizeNotPositive           = errors.New("queue batch size must be greater than 0")
+	ErrQueueRetryAttempts                  = errors.New("queue retry attempts must be greater than 0")
+	ErrQueueMinBackoff                     = errors.New("queue retry min backoff must be greater than 0")
+	ErrQueueIncompatibleBackoffs           = errors.New("queue retry max backoff must be greater or equal to min backoff")
 )
 
+const (
+	rabbitMQ   = "rabbit"
+	postgresMQ = "postgres"
+
+	memoryDedup   = "memory"
+	postgresDedup = "postgres"
+)
+
+var reminderChannels = []models.ReminderChannel{models.ChannelLog, models.ChannelEmail, models.ChannelWebhook}
+
 type Config struct {
+	// MQType is rabbit or postgres, only the config of the chosen backend is set.
+	MQType string
 	MQ     rabbitmq.ConsumerConfig
-	Logger logger.Config
+	Queue  postgresqueue.Config
+	// DedupType is memory or postgres, delivered notifications are remembered in the process or the database.
+	DedupType string
+	Dedup     dedup.Config
+	// Postgres is set if the queue or the dedup store is kept in the database.
+	Postgres postgres.Config
+	Logger   logger.Config
+	Notifier notifier.Config
+	// ServerHTTP serves health checks and metrics.
+	ServerHTTP internalhttp.Config
 }
 
 func NewConfig(path string) (*Config, error) {
@@ -48,26 +136,85 @@ func NewConfig(path string) (*Config, error) {
 	viper.SetEnvPrefix("sender_rabbit")
 	viper.AutomaticEnv()
 
-	rabbitConfig, err := newRabbitSenderConfig()
+	config := Config{
+		MQType:    viper.GetString("mq.type"),
+		DedupType: viper.GetString("dedup.type"),
+	}
+
+	switch config.MQType {
+	case rabbitMQ:
+		config.MQ, err = newRabbitSenderConfig()
+		if err != nil {
+			return nil, err
+		}
+		err = validateRabbitSenderConfig(config.MQ)
+		if err != nil {
+			return nil, err
+		}
+	case postgresMQ:
+		config.Queue, err = newQueueConfig()
+		if err != nil {
+			return nil, err
+		}
+		err = validateQueueConfig(config.Queue)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidMQType
+	}
+
+	if config.DedupType != memoryDedup && config.DedupType != postgresDedup {
+		return nil, ErrInvalidDedupType
+	}
+	config.Dedup, err = newDedupConfig()
 	if err != nil {
 		return nil, err
 	}
-	err = validateRabbitSenderConfig(rabbitConfig)
+	err = validateDedupConfig(config.Dedup)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.MQType == postgresMQ || config.DedupType == postgresDedup {
+		config.Postgres, err = newStoragePostgresConfig()
+		if err != nil {
+			return nil, err
+		}
+		err = validateStoragePostgresConfig(config.Postgres)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	log := newLoggerConfig()
 	err = validateLoggerConfig(log)
 	if err != nil {
 		return nil, err
 	}
 
-	config := Config{
-		MQ:     rabbitConfig,
-		Logger: log,
+	notifierConfig, err := newNotifierConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = validateNotifierConfig(notifierConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	serverHTTP, err := newServerHTTPConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = validateServerHTTPConfig(serverHTTP)
+	if err != nil {
+		return nil, err
 	}
 
+	config.Logger = log
+	config.Notifier = notifierConfig
+	config.ServerHTTP = serverHTTP
+
 	return &config, nil
 }
 
@@ -92,22 +239,52 @@ func newRabbitSenderConfig() (rabbitmq.ConsumerConfig, error) {
 	autoDeleteQueue := viper.GetBool("rabbit_sender.auto_delete_queue")
 	routingKey := viper.GetString("rabbit_sender.routing_key")
 	tag := viper.GetString("rabbit_sender.tag")
+	deadLetterExchange := viper.GetString("rabbit_sender.dead_letter_exchange")
+	deadLetterQueue := viper.GetString("rabbit_sender.dead_letter_queue")
+	retryAttempts := viper.GetInt("rabbit_sender.retry_attempts")
+
+	retryMinBackoff, err := time.ParseDuration(viper.GetString("rabbit_sender.retry_min_backoff"))
+	if err != nil {
+		return rabbitmq.ConsumerConfig{}, ErrRabbitSenderParseMinBackoff
+	}
+
+	retryMaxBackoff, err := time.ParseDuration(viper.GetString("rabbit_sender.retry_max_backoff"))
+	if err != nil {
+		return rabbitmq.ConsumerConfig{}, ErrRabbitSenderParseMaxBackoff
+	}
+
+	reconnectMinBackoff, err := time.ParseDuration(viper.GetString("rabbit_sender.reconnect_min_backoff"))
+	if err != nil {
+		return rabbitmq.ConsumerConfig{}, ErrRabbitSenderParseReconnectMin
+	}
+
+	reconnectMaxBackoff, err := time.ParseDuration(viper.GetString("rabbit_sender.reconnect_max_backoff"))
+	if err != nil {
+		return rabbitmq.ConsumerConfig{}, ErrRabbitSenderParseReconnectMax
+	}
 
 	return rabbitmq.ConsumerConfig{
-		Username:           username,
-		Password:           password,
-		Host:               host,
-		Port:               port,
-		Heartbeat:          heartbeat,
-		ExchangeName:       exchangeName,
-		ExchangeType:       exchangeType,
-		DurableExchange:    durableExchange,
-		AutoDeleteExchange: autoDeleteExchange,
-		QueueName:          queueName,
-		DurableQueue:       durableQueue,
-		AutoDeleteQueue:    autoDeleteQueue,
-		RoutingKey:         routingKey,
-		Tag:                tag,
+		Username:            username,
+		Password:            password,
+		Host:                host,
+		Port:                port,
+		Heartbeat:           heartbeat,
+		ExchangeName:        exchangeName,
+		ExchangeType:        exchangeType,
+		DurableExchange:     durableExchange,
+		AutoDeleteExchange:  autoDeleteExchange,
+		QueueName:           queueName,
+		DurableQueue:        durableQueue,
+		AutoDeleteQueue:     autoDeleteQueue,
+		RoutingKey:          routingKey,
+		Tag:                 tag,
+		DeadLetterExchange:  deadLetterExchange,
+		DeadLetterQueue:     deadLetterQueue,
+		RetryAttempts:       retryAttempts,
+		RetryMinBackoff:     retryMinBackoff,
+		RetryMaxBackoff:     retryMaxBackoff,
+		ReconnectMinBackoff: reconnectMinBackoff,
+		ReconnectMaxBackoff: reconnectMaxBackoff,
 	}, nil
 }
 
@@ -146,6 +323,27 @@ func validateRabbitSenderConfig(s rabbitmq.ConsumerConfig) error {
 	if s.Tag == "" {
 		return ErrRabbitSenderEmptyTag
 	}
+	if s.DeadLetterExchange == "" {
+		return ErrRabbitSenderEmptyDeadLetterExchange
+	}
+	if s.DeadLetterQueue == "" {
+		return ErrRabbitSenderEmptyDeadLetterQueue
+	}
+	if s.RetryAttempts <= 0 {
+		return ErrRabbitSenderRetryAttempts
+	}
+	if s.RetryMinBackoff <= 0 {
+		return ErrRabbitSenderMinBackoff
+	}
+	if s.RetryMaxBackoff < s.RetryMinBackoff {
+		return ErrRabbitSenderIncompatibleBackoffs
+	}
+	if s.ReconnectMinBackoff <= 0 {
+		return ErrRabbitSenderReconnectMin
+	}
+	if s.ReconnectMaxBackoff < s.ReconnectMinBackoff {
+		return ErrRabbitSenderReconnectBackoffs
+	}
 	return nil
 }
 
@@ -170,3 +368,336 @@ func validateLoggerConfig(l logger.Config) error {
 
 	return nil
 }
+
+type notifierUser struct {
+	ID         int    `mapstructure:"id"`
+	Channel    string `mapstructure:"channel"`
+	Email      string `mapstructure:"email"`
+	WebhookURL string `mapstructure:"webhook_url"`
+}
+
+func newNotifierConfig() (notifier.Config, error) {
+	smtpTimeout, err := parseOptionalDuration("notifier.smtp.timeout")
+	if err != nil {
+		return notifier.Config{}, ErrNotifierParseSMTPTimeout
+	}
+
+	webhookTimeout, err := parseOptionalDuration("notifier.webhook.timeout")
+	if err != nil {
+		return notifier.Config{}, ErrNotifierParseWebhookTimeout
+	}
+
+	channels := make(map[models.ReminderChannel]notifier.ChannelConfig, len(reminderChannels))
+	for _, channel := range reminderChannels {
+		key := "notifier.channels." + string(channel) + "."
+
+		minBackoff, err := parseOptionalDuration(key + "min_backoff")
+		if err != nil {
+			return notifier.Config{}, ErrNotifierParseMinBackoff
+		}
+
+		maxBackoff, err := parseOptionalDuration(key + "max_backoff")
+		if err != nil {
+			return notifier.Config{}, ErrNotifierParseMaxBackoff
+		}
+
+		channels[channel] = notifier.ChannelConfig{
+			Retry: notifier.RetryConfig{
+				Attempts:   viper.GetInt(key + "attempts"),
+				MinBackoff: minBackoff,
+				MaxBackoff: maxBackoff,
+			},
+			Rate:  viper.GetFloat64(key + "rate"),
+			Burst: viper.GetInt(key + "burst"),
+		}
+	}
+
+	var users []notifierUser
+	if err := viper.UnmarshalKey("notifier.users", &users); err != nil {
+		return notifier.Config{}, ErrNotifierParseUsers
+	}
+
+	recipients := make(notifier.Recipients, len(users))
+	for _, user := range users {
+		recipients[user.ID] = notifier.Recipient{
+			Channel:    models.ReminderChannel(user.Channel),
+			Email:      user.Email,
+			WebhookURL: user.WebhookURL,
+		}
+	}
+
+	return notifier.Config{
+		DefaultChannel: models.ReminderChannel(viper.GetString("notifier.default_channel")),
+		FilePath:       viper.GetString("notifier.file.path"),
+		SMTP: notifier.SMTPConfig{
+			Host:     viper.GetString("notifier.smtp.host"),
+			Port:     viper.GetInt("notifier.smtp.port"),
+			Username: viper.GetString("notifier.smtp.username"),
+			Password: viper.GetString("smtp_password"),
+			From:     viper.GetString("notifier.smtp.from"),
+			Timeout:  smtpTimeout,
+		},
+		Webhook: notifier.WebhookConfig{
+			Secret:  viper.GetString("webhook_secret"),
+			Timeout: webhookTimeout,
+		},
+		Channels:   channels,
+		Recipients: recipients,
+	}, nil
+}
+
+// parseOptionalDuration returns 0 if the key is not set.
+func parseOptionalDuration(key string) (time.Duration, error) {
+	value := viper.GetString(key)
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func validateNotifierConfig(n notifier.Config) error {
+	if !isReminderChannel(n.DefaultChannel) {
+		return ErrNotifierInvalidDefaultChannel
+	}
+	if n.SMTP.Host != "" {
+		if n.SMTP.Port < 0 || n.SMTP.Port > 65535 {
+			return ErrNotifierInvalidSMTPPort
+		}
+		if n.SMTP.From == "" {
+			return ErrNotifierEmptySMTPFrom
+		}
+	}
+	for _, channel := range n.Channels {
+		if channel.Retry.Attempts < 0 {
+			return ErrNotifierNegativeAttempts
+		}
+		if channel.Retry.MaxBackoff < channel.Retry.MinBackoff {
+			return ErrNotifierIncompatibleBackoffs
+		}
+		if channel.Rate < 0 {
+			return ErrNotifierNegativeRate
+		}
+		if channel.Burst < 0 {
+			return ErrNotifierNegativeBurst
+		}
+	}
+	for _, recipient := range n.Recipients {
+		if recipient.Channel != "" && !isReminderChannel(recipient.Channel) {
+			return ErrNotifierInvalidUserChannel
+		}
+	}
+
+	return nil
+}
+
+func isReminderChannel(channel models.ReminderChannel) bool {
+	for _, c := range reminderChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func newStoragePostgresConfig() (postgres.Config, error) {
+	host := viper.GetString("postgres.host")
+	port := viper.GetString("postgres.port")
+	username := viper.GetString("db_user")
+	password := viper.GetString("db_password")
+	dbName := viper.GetString("postgres.db_name")
+	sslmode := viper.GetString("postgres.sslmode")
+	maxConns := viper.GetInt("postgres.max_conns")
+	minConns := viper.GetInt("postgres.min_conns")
+
+	maxConnLifetimeStr := viper.GetString("postgres.max_conn_lifetime")
+	maxConnLifetime, err := time.ParseDuration(maxConnLifetimeStr)
+	if err != nil {
+		return postgres.Config{}, ErrParseMaxConnLifetime
+	}
+
+	maxConnIdleTimeStr := viper.GetString("postgres.max_conn_idle_time")
+	maxConnIdleTime, err := time.ParseDuration(maxConnIdleTimeStr)
+	if err != nil {
+		return postgres.Config{}, ErrParseMaxConnIdleTime
+	}
+
+	return postgres.Config{
+		Host:            host,
+		Port:            port,
+		Username:        username,
+		Password:        password,
+		DBName:          dbName,
+		SSLMode:         sslmode,
+		MaxConns:        maxConns,
+		MinConns:        minConns,
+		MaxConnLifetime: maxConnLifetime,
+		MaxConnIdleTime: maxConnIdleTime,
+	}, nil
+}
+
+func validateStoragePostgresConfig(st postgres.Config) error {
+	if st.Host == "" {
+		return ErrDBHost
+	}
+	port, err := strconv.Atoi(st.Port)
+	if err != nil {
+		return ErrDBPortNotNumber
+	}
+	if port < 0 || port > 65535 {
+		return ErrDBPortWrongNumber
+	}
+
+	if st.DBName == "" {
+		return ErrDBInvalidDBName
+	}
+	sslTypes := map[string]struct{}{"disable": {}, "verify-ca": {}, "require": {}, "verify-full": {}}
+	if _, ok := sslTypes[st.SSLMode]; !ok {
+		return ErrDBInvalidSSLMode
+	}
+	if st.MaxConns <= 0 {
+		return ErrDBMaxConns
+	}
+	if st.MinConns <= 0 {
+		return ErrDBMinConns
+	}
+	if st.MaxConns < st.MinConns {
+		return ErrDBIncompatibleMaxAndMinConns
+	}
+	if st.MaxConnLifetime <= 0 {
+		return ErrDBMaxConnLifetimeNotPositive
+	}
+	if st.MaxConnIdleTime <= 0 {
+		return ErrDBMaxConnIdleTimeNotPositive
+	}
+
+	return nil
+}
+
+func newQueueConfig() (postgresqueue.Config, error) {
+	pollInterval, err := time.ParseDuration(viper.GetString("mq.postgres.poll_interval"))
+	if err != nil {
+		return postgresqueue.Config{}, ErrQueueParsePollInterval
+	}
+
+	lease, err := time.ParseDuration(viper.GetString("mq.postgres.lease"))
+	if err != nil {
+		return postgresqueue.Config{}, ErrQueueParseLease
+	}
+
+	minBackoff, err := time.ParseDuration(viper.GetString("mq.postgres.retry_min_backoff"))
+	if err != nil {
+		return postgresqueue.Config{}, ErrQueueParseMinBackoff
+	}
+
+	maxBackoff, err := time.ParseDuration(viper.GetString("mq.postgres.retry_max_backoff"))
+	if err != nil {
+		return postgresqueue.Config{}, ErrQueueParseMaxBackoff
+	}
+
+	return postgresqueue.Config{
+		PollInterval: pollInterval,
+		Lease:        lease,
+		BatchSize:    viper.GetInt("mq.postgres.batch_size"),
+		Retry: mq.RetryPolicy{
+			Attempts:   viper.GetInt("mq.postgres.retry_attempts"),
+			MinBackoff: minBackoff,
+			MaxBackoff: maxBackoff,
+		},
+	}, nil
+}
+
+func validateQueueConfig(q postgresqueue.Config) error {
+	if q.PollInterval <= 0 {
+		return ErrQueuePollIntervalNotPositive
+	}
+	if q.Lease <= 0 {
+		return ErrQueueLeaseNotPositive
+	}
+	if q.BatchSize <= 0 {
+		return ErrQueueBatchSizeNotPositive
+	}
+	if q.Retry.Attempts <= 0 {
+		return ErrQueueRetryAttempts
+	}
+	if q.Retry.MinBackoff <= 0 {
+		return ErrQueueMinBackoff
+	}
+	if q.Retry.MaxBackoff < q.Retry.MinBackoff {
+		return ErrQueueIncompatibleBackoffs
+	}
+
+	return nil
+}
+
+func newDedupConfig() (dedup.Config, error) {
+	lease, err := time.ParseDuration(viper.GetString("dedup.lease"))
+	if err != nil {
+		return dedup.Config{}, ErrDedupParseLease
+	}
+
+	ttl, err := time.ParseDuration(viper.GetString("dedup.ttl"))
+	if err != nil {
+		return dedup.Config{}, ErrDedupParseTTL
+	}
+
+	return dedup.Config{
+		Lease: lease,
+		TTL:   ttl,
+	}, nil
+}
+
+func validateDedupConfig(d dedup.Config) error {
+	if d.Lease <= 0 {
+		return ErrDedupLeaseNotPositive
+	}
+	if d.TTL < d.Lease {
+		return ErrDedupIncompatibleTTL
+	}
+
+	return nil
+}
+
+func newServerHTTPConfig() (internalhttp.Config, error) {
+	host := viper.GetString("server_http.host")
+	port := viper.GetString("server_http.port")
+
+	readTimeoutStr := viper.GetString("server_http.read_timeout")
+	readTimeout, err := time.ParseDuration(readTimeoutStr)
+	if err != nil {
+		return internalhttp.Config{}, ErrParseHTTPServerReadTimeout
+	}
+
+	writeTimeoutStr := viper.GetString("server_http.write_timeout")
+	writeTimeout, err := time.ParseDuration(writeTimeoutStr)
+	if err != nil {
+		return internalhttp.Config{}, ErrParseHTTPServerWriteTimeout
+	}
+
+	return internalhttp.Config{
+		Host:         host,
+		Port:         port,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}, nil
+}
+
+func validateServerHTTPConfig(s internalhttp.Config) error {
+	if s.Host == "" {
+		return ErrHTTPServerHost
+	}
+	port, err := strconv.Atoi(s.Port)
+	if err != nil {
+		return ErrHTTPServerPortNotNumber
+	}
+	if port < 0 || port > 65535 {
+		return ErrHTTPServerPortWrongNumber
+	}
+	if s.ReadTimeout <= 0 {
+		return ErrHTTPServerReadTimeoutNotPositive
+	}
+	if s.WriteTimeout <= 0 {
+		return ErrHTTPServerWriteTimeoutNotPositive
+	}
+
+	return nil
+}
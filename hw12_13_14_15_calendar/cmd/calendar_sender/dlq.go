@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+// dlqConnectTimeout limits waiting for the connection to the broker.
+const dlqConnectTimeout = 30 * time.Second
+
+var (
+	ErrUnknownDLQCommand = errors.New("unknown dlq command: list, replay")
+	ErrNoDLQ             = errors.New("the queue has no dead letters")
+)
+
+type deadLetterResponse struct {
+	Message   mq.Message `json:"message"`
+	Body      string     `json:"body"`
+	Error     string     `json:"error"`
+	Retries   int        `json:"retries"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// runDLQ lists dead-lettered notifications as JSON lines or replays them to the notification queue.
+func runDLQ(ctx context.Context, consumer mq.NotificationConsumer, command string, limit int, w io.Writer) error {
+	dlq, ok := consumer.(mq.DeadLetterQueue)
+	if !ok {
+		return ErrNoDLQ
+	}
+
+	// the rabbit consumer connects in the background
+	if c, ok := consumer.(interface {
+		WaitConnected(ctx context.Context) error
+	}); ok {
+		connectCtx, cancel := context.WithTimeout(ctx, dlqConnectTimeout)
+		defer cancel()
+
+		if err := c.WaitConnected(connectCtx); err != nil {
+			return fmt.Errorf("connecting sender: %w", err)
+		}
+	}
+
+	switch command {
+	case "list":
+		letters, err := dlq.DeadLetters(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(w)
+		for _, letter := range letters {
+			err := enc.Encode(deadLetterResponse{
+				Message:   letter.Message,
+				Body:      string(letter.Body),
+				Error:     letter.Error,
+				Retries:   letter.Retries,
+				Timestamp: letter.Timestamp,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "replay":
+		replayed, err := dlq.Replay(ctx, limit)
+		fmt.Fprintf(w, "replayed %d notifications\n", replayed)
+		return err
+	default:
+		return ErrUnknownDLQCommand
+	}
+}
@@ -2,25 +2,62 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"log"
+	"net"
+	"net/http"
+	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup"
+	memorydedup "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup/memory"
+	postgresdedup "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup/postgres"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/health"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/metrics"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	postgresqueue "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/postgres"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/rabbitmq"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/notifier"
+	internalhttp "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/http"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/postgres"
 	"golang.org/x/exp/slog"
 )
 
-var configFile string
+var (
+	configFile string
+	dlqLimit   int
+)
+
+var (
+	deliveredTotal = metrics.NewCounter("calendar_notifications_delivered_total",
+		"Notifications delivered by the sender by channel.", "channel")
+	deliveryFailedTotal = metrics.NewCounter("calendar_notifications_delivery_failed_total",
+		"Failed deliveries of notifications by channel.", "channel")
+	duplicatesTotal = metrics.NewCounter("calendar_notifications_duplicates_total",
+		"Duplicate notifications dropped by the sender by channel.", "channel")
+	// consumerLag is how late notifications are received after their reminders are due.
+	consumerLag = metrics.NewHistogram("calendar_queue_consumer_lag_seconds",
+		"Delay between the time the reminder is due and the time the sender receives it.",
+		[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600})
+)
+
+// readinessTimeout limits every readiness check.
+const readinessTimeout = 2 * time.Second
 
 func init() {
 	flag.StringVar(&configFile, "config", "./configs/sender_config.toml", "Path to configuration file")
+	flag.IntVar(&dlqLimit, "limit", 100, "Max number of dead letters to list or replay")
 }
 
 func main() {
+	flag.Parse()
+
 	cfg, err := NewConfig(configFile)
 	if err != nil {
 		log.Fatalf("rabbit sender config error: %s", err.Error())
@@ -32,26 +69,69 @@ func main() {
 		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
-	sender, err := rabbitmq.NewSender(cfg.MQ, logg)
+	var db *pgxpool.Pool
+	if cfg.MQType == postgresMQ || cfg.DedupType == postgresDedup {
+		db, err = postgres.NewPool(ctx, cfg.Postgres)
+		if err != nil {
+			log.Fatalf("error connecting sender database: %s", err.Error()) //nolint:gocritic
+		}
+		defer db.Close()
+	}
+
+	notificationConsumer := newNotificationConsumer(cfg, db, logg)
+
+	// "dlq list" and "dlq replay" inspect and replay dead-lettered notifications instead of sending
+	if flag.Arg(0) == "dlq" {
+		err := runDLQ(ctx, notificationConsumer, flag.Arg(1), dlqLimit, os.Stdout)
+		if shutdownErr := notificationConsumer.Shutdown(); shutdownErr != nil {
+			logg.Error("error stopping sender", slog.String("error", shutdownErr.Error()))
+		}
+		if err != nil {
+			log.Fatalf("dlq error: %s", err.Error())
+		}
+		return
+	}
+
+	checker := health.NewChecker(readinessTimeout)
+	if db != nil {
+		checker.Add("postgres", db.Ping)
+	}
+	if rabbitConsumer, ok := notificationConsumer.(*rabbitmq.Consumer); ok {
+		checker.Add("rabbit", rabbitConsumer.Ping)
+	}
+
+	serverHTTP, err := internalhttp.NewServerHTTP(cfg.ServerHTTP, health.Handler(checker, metrics.Default.Handler()))
 	if err != nil {
-		logg.Error("error creating rabbit sender",
-			slog.String("error", err.Error()),
-			slog.String("address", cfg.MQ.Host+":"+strconv.Itoa(cfg.MQ.Port)))
-		cancel()
+		log.Fatalf("error creating sender HTTPServer: %s", err.Error())
 	}
+	go func() {
+		if err := serverHTTP.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logg.Error("error starting sender HTTPServer",
+				slog.String("error", err.Error()),
+				slog.String("address http", net.JoinHostPort(cfg.ServerHTTP.Host, cfg.ServerHTTP.Port)))
+		}
+	}()
 
-	consumer := mq.NewConsumer(sender)
+	consumer := mq.NewConsumer(notificationConsumer)
 
 	go func() {
 		<-ctx.Done()
 
+		checker.Shutdown()
+
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), 3*time.Second)
+		if err := serverHTTP.Stop(stopCtx); err != nil {
+			logg.Error("error stopping sender HTTPServer", slog.String("error", err.Error()))
+		}
+		stopCancel()
+
 		if err := consumer.Shutdown(); err != nil {
-			logg.Error("error stopping rabbit sender",
+			logg.Error("error stopping sender",
 				slog.String("error", err.Error()),
-				slog.String("address", cfg.MQ.Host+":"+strconv.Itoa(cfg.MQ.Port)))
+				slog.String("mq", cfg.MQType))
 		}
 
-		logg.Info("rabbit sender is stopped")
+		logg.Info("sender is stopped")
 	}()
 
 	notifications, err := consumer.Consume()
@@ -61,11 +141,155 @@ func main() {
 		cancel()
 	}
 
+	fileNotifier, err := notifier.NewFileNotifier(cfg.Notifier.FilePath)
+	if err != nil {
+		log.Fatalf("error opening notifications file: %s", err.Error())
+	}
+	defer fileNotifier.Close()
+
+	router := newRouter(cfg.Notifier, fileNotifier)
+
+	deliveries := newDedupStore(cfg, db)
+	go purgeDeliveries(ctx, deliveries, cfg.Dedup.Lease, logg)
+
 	for notification := range notifications {
-		if notification.Err != nil {
-			logg.Error("error receiving notification", slog.String("error", notification.Err.Error()))
-			continue
+		if err := send(ctx, router, deliveries, notification, logg); err != nil {
+			logg.Error("error settling notification",
+				slog.String("error", err.Error()),
+				slog.Any("notification", notification.Message))
 		}
-		logg.Info("notification is received", slog.Any("notification", notification.Message))
 	}
 }
+
+// newNotificationConsumer returns the consumer of the configured backend, db is set for the postgres one.
+func newNotificationConsumer(cfg *Config, db *pgxpool.Pool, logg logger.Logger) mq.NotificationConsumer {
+	if cfg.MQType == postgresMQ {
+		return postgresqueue.NewQueue(db, logg, cfg.Queue)
+	}
+	// the sender reconnects in the background and resumes consuming on every new connection
+	return rabbitmq.NewSender(cfg.MQ, logg)
+}
+
+// newDedupStore returns the store of delivered notifications, db is set for the postgres one.
+func newDedupStore(cfg *Config, db *pgxpool.Pool) dedup.Store {
+	if cfg.DedupType == postgresDedup {
+		return postgresdedup.NewStore(db, cfg.Dedup)
+	}
+	return memorydedup.NewStore(cfg.Dedup)
+}
+
+// purgeDeliveries forgets expired notifications every interval until ctx is done.
+func purgeDeliveries(ctx context.Context, deliveries dedup.Store, interval time.Duration, logg logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := deliveries.Purge(ctx); err != nil {
+				logg.Error("error purging delivered notifications", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// send delivers the notification and settles it: acks delivered ones and duplicates, dead-letters undecodable ones
+// and the ones which fail permanently, retries the rest. Every notification is delivered through the channel
+// at most once, duplicates of the one being delivered are retried in case that delivery fails.
+func send(ctx context.Context, router *notifier.Router, deliveries dedup.Store, notification mq.Notification,
+	logg logger.Logger,
+) error {
+	if notification.Err != nil {
+		logg.Error("error receiving notification", slog.String("error", notification.Err.Error()))
+		return notification.Delivery.Reject(ctx, notification.Err)
+	}
+
+	msg := notification.Message
+	channel := router.Channel(msg)
+
+	if !msg.FireAt.IsZero() {
+		consumerLag.Observe(time.Since(msg.FireAt).Seconds())
+	}
+
+	key := dedup.Key(msg, channel)
+	if key != "" {
+		err := deliveries.Acquire(ctx, key)
+		switch {
+		case errors.Is(err, dedup.ErrDelivered):
+			duplicatesTotal.Inc(string(channel))
+			logg.Info("duplicate notification is skipped",
+				slog.String("channel", string(channel)),
+				slog.Any("notification", msg))
+			return notification.Delivery.Ack()
+		case errors.Is(err, dedup.ErrInProgress):
+			logg.Info("duplicate notification is being delivered, it is retried",
+				slog.String("channel", string(channel)),
+				slog.Any("notification", msg))
+			return notification.Delivery.Retry(ctx, err)
+		case err != nil:
+			logg.Error("error acquiring notification",
+				slog.String("error", err.Error()),
+				slog.String("channel", string(channel)),
+				slog.Any("notification", msg))
+			return notification.Delivery.Retry(ctx, err)
+		}
+	}
+
+	err := router.Notify(ctx, msg)
+	if err == nil {
+		deliveredTotal.Inc(string(channel))
+		logg.Info("notification is delivered",
+			slog.String("channel", string(channel)),
+			slog.Any("notification", msg))
+		if key != "" {
+			if err := deliveries.Complete(ctx, key); err != nil {
+				logg.Error("error completing notification",
+					slog.String("error", err.Error()),
+					slog.Any("notification", msg))
+			}
+		}
+		return notification.Delivery.Ack()
+	}
+
+	if key != "" {
+		// the reservation expires after the lease if it is not released
+		if err := deliveries.Release(ctx, key); err != nil {
+			logg.Error("error releasing notification",
+				slog.String("error", err.Error()),
+				slog.Any("notification", msg))
+		}
+	}
+
+	deliveryFailedTotal.Inc(string(channel))
+
+	permanent := notifier.IsPermanent(err)
+	logg.Error("error delivering notification",
+		slog.String("error", err.Error()),
+		slog.String("channel", string(channel)),
+		slog.Bool("permanent", permanent),
+		slog.Any("notification", msg))
+
+	if permanent {
+		return notification.Delivery.Reject(ctx, err)
+	}
+	return notification.Delivery.Retry(ctx, err)
+}
+
+// newRouter creates the notifiers of the configured channels with their retries and rate limits.
+func newRouter(cfg notifier.Config, fileNotifier *notifier.FileNotifier) *notifier.Router {
+	notifiers := map[models.ReminderChannel]notifier.Notifier{
+		models.ChannelLog:     fileNotifier,
+		models.ChannelWebhook: notifier.NewWebhookNotifier(cfg.Webhook, cfg.Recipients),
+	}
+	if cfg.SMTP.Host != "" {
+		notifiers[models.ChannelEmail] = notifier.NewSMTPNotifier(cfg.SMTP, cfg.Recipients)
+	}
+
+	for channel, n := range notifiers {
+		notifiers[channel] = notifier.Wrap(n, cfg.Channels[channel])
+	}
+
+	return notifier.NewRouter(notifiers, cfg.Recipients, cfg.DefaultChannel)
+}
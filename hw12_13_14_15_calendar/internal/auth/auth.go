@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/identity"
+)
+
+const (
+	// HeaderAuthorization is the HTTP header with the bearer token.
+	HeaderAuthorization = "Authorization"
+	// HeaderAPIKey is the HTTP header with the static API key.
+	HeaderAPIKey = "X-API-Key"
+	// MetadataAuthorization is the gRPC metadata key with the bearer token.
+	MetadataAuthorization = "authorization"
+	// MetadataAPIKey is the gRPC metadata key with the static API key.
+	MetadataAPIKey = "x-api-key"
+)
+
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+const bearerPrefix = "Bearer "
+
+var (
+	ErrNoMethods          = errors.New("no authentication method is configured")
+	ErrInvalidAPIKeyHash  = errors.New("api key hash must be hex encoded sha256")
+	ErrInvalidAPIKeyUser  = errors.New("api key user id must be positive number")
+	ErrMissingCredentials = errors.New("credentials are missing")
+	ErrInvalidScheme      = errors.New("authorization scheme must be Bearer")
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrExpiredToken       = errors.New("token is expired")
+)
+
+// APIKey is the static key of the user. Only the SHA-256 hash of the key is kept in the config.
+type APIKey struct {
+	Name   string
+	UserID int
+	SHA256 string
+}
+
+type Config struct {
+	// HMACSecret verifies HS256 tokens without the key id.
+	HMACSecret string
+	// JWKSFile is the JSON Web Key Set with RS256 and HS256 keys found by the key id of tokens.
+	JWKSFile string
+	// Issuer and Audience are checked if they are not empty.
+	Issuer   string
+	Audience string
+	// Leeway is the allowed clock skew when expiration and not before times are checked.
+	Leeway  time.Duration
+	APIKeys []APIKey
+}
+
+type apiKey struct {
+	name   string
+	userID int
+	hash   []byte
+}
+
+// Authenticator validates JWTs and static API keys and returns the callers they belong to.
+type Authenticator struct {
+	cfg     Config
+	keys    keySet
+	apiKeys []apiKey
+	now     func() time.Time
+}
+
+func New(cfg Config) (*Authenticator, error) {
+	if cfg.HMACSecret == "" && cfg.JWKSFile == "" && len(cfg.APIKeys) == 0 {
+		return nil, ErrNoMethods
+	}
+
+	keys := keySet{byID: make(map[string]key)}
+	if cfg.JWKSFile != "" {
+		var err error
+		keys, err = loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.HMACSecret != "" {
+		keys.secret = []byte(cfg.HMACSecret)
+	}
+
+	apiKeys := make([]apiKey, 0, len(cfg.APIKeys))
+	for _, k := range cfg.APIKeys {
+		hash, err := hex.DecodeString(k.SHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key %q: %w", k.Name, ErrInvalidAPIKeyHash)
+		}
+		if k.UserID <= 0 {
+			return nil, fmt.Errorf("api key %q: %w", k.Name, ErrInvalidAPIKeyUser)
+		}
+		apiKeys = append(apiKeys, apiKey{name: k.Name, userID: k.UserID, hash: hash})
+	}
+
+	return &Authenticator{
+		cfg:     cfg,
+		keys:    keys,
+		apiKeys: apiKeys,
+		now:     time.Now,
+	}, nil
+}
+
+// Credentials are the values of the authorization and API key headers or metadata.
+type Credentials struct {
+	Authorization string
+	APIKey        string
+}
+
+// Authenticate returns the caller of the bearer token or, if there is no token, of the API key.
+func (a *Authenticator) Authenticate(creds Credentials) (identity.Principal, error) {
+	switch {
+	case creds.Authorization != "":
+		if !strings.HasPrefix(creds.Authorization, bearerPrefix) {
+			return identity.Principal{}, ErrInvalidScheme
+		}
+		return a.verifyToken(strings.TrimSpace(strings.TrimPrefix(creds.Authorization, bearerPrefix)))
+	case creds.APIKey != "":
+		return a.verifyAPIKey(creds.APIKey)
+	default:
+		return identity.Principal{}, ErrMissingCredentials
+	}
+}
+
+// verifyAPIKey compares the hash of the key with all configured ones in constant time.
+func (a *Authenticator) verifyAPIKey(value string) (identity.Principal, error) {
+	hash := sha256.Sum256([]byte(value))
+
+	var (
+		found   apiKey
+		matched bool
+	)
+	for _, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare(hash[:], k.hash) == 1 {
+			found, matched = k, true
+		}
+	}
+	if !matched {
+		return identity.Principal{}, ErrInvalidAPIKey
+	}
+
+	return identity.Principal{
+		UserID:  found.userID,
+		Subject: found.name,
+		Method:  MethodAPIKey,
+	}, nil
+}
@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/identity"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSecret    = "secret"
+	testAPIKey    = "calendar-api-key"
+	testJWKSecret = "jwks-secret"
+)
+
+var testNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func TestAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	a := newTestAuthenticator(t, &rsaKey.PublicKey)
+
+	valid := map[string]interface{}{
+		"sub": "42",
+		"iss": "calendar",
+		"aud": []string{"calendar-api", "other"},
+		"exp": testNow.Add(time.Hour).Unix(),
+		"nbf": testNow.Add(-time.Minute).Unix(),
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := make(map[string]interface{}, len(valid))
+		for k, v := range valid {
+			claims[k] = v
+		}
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	jwtPrincipal := identity.Principal{UserID: 42, Subject: "42", Method: MethodJWT}
+
+	testCases := []struct {
+		name     string
+		creds    Credentials
+		expected identity.Principal
+		err      error
+	}{
+		{
+			name:     "hs256 with secret",
+			creds:    bearer(signHS256(t, testSecret, "", valid)),
+			expected: jwtPrincipal,
+		},
+		{
+			name:     "hs256 with jwks key",
+			creds:    bearer(signHS256(t, testJWKSecret, "hmac", valid)),
+			expected: jwtPrincipal,
+		},
+		{
+			name:     "rs256",
+			creds:    bearer(signRS256(t, rsaKey, "rsa", valid)),
+			expected: jwtPrincipal,
+		},
+		{
+			name:     "rs256 without key id",
+			creds:    bearer(signRS256(t, rsaKey, "", valid)),
+			expected: jwtPrincipal,
+		},
+		{
+			name:     "expired within leeway",
+			creds:    bearer(signHS256(t, testSecret, "", with("exp", testNow.Add(-10*time.Second).Unix()))),
+			expected: jwtPrincipal,
+		},
+		{
+			name:     "api key",
+			creds:    Credentials{APIKey: testAPIKey},
+			expected: identity.Principal{UserID: 7, Subject: "ci", Method: MethodAPIKey},
+		},
+		{
+			name:  "missing credentials",
+			creds: Credentials{},
+			err:   ErrMissingCredentials,
+		},
+		{
+			name:  "basic scheme",
+			creds: Credentials{Authorization: "Basic dXNlcjpwYXNz"},
+			err:   ErrInvalidScheme,
+		},
+		{
+			name:  "invalid api key",
+			creds: Credentials{APIKey: "wrong"},
+			err:   ErrInvalidAPIKey,
+		},
+		{
+			name:  "malformed token",
+			creds: bearer("abc.def"),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "wrong secret",
+			creds: bearer(signHS256(t, "wrong", "", valid)),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "wrong rsa key",
+			creds: bearer(signRS256(t, otherKey, "rsa", valid)),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "unknown key id",
+			creds: bearer(signHS256(t, testSecret, "unknown", valid)),
+			err:   ErrUnknownKey,
+		},
+		{
+			name:  "hs256 signed with rsa key id",
+			creds: bearer(signHS256(t, testSecret, "rsa", valid)),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "alg none",
+			creds: bearer(encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, valid) + "."),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "expired",
+			creds: bearer(signHS256(t, testSecret, "", with("exp", testNow.Add(-time.Minute).Unix()))),
+			err:   ErrExpiredToken,
+		},
+		{
+			name:  "missing exp",
+			creds: bearer(signHS256(t, testSecret, "", with("exp", nil))),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "not valid yet",
+			creds: bearer(signHS256(t, testSecret, "", with("nbf", testNow.Add(time.Minute).Unix()))),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "wrong issuer",
+			creds: bearer(signHS256(t, testSecret, "", with("iss", "other"))),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "wrong audience",
+			creds: bearer(signHS256(t, testSecret, "", with("aud", "other"))),
+			err:   ErrInvalidToken,
+		},
+		{
+			name:  "subject is not user id",
+			creds: bearer(signHS256(t, testSecret, "", with("sub", "admin"))),
+			err:   ErrInvalidToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			principal, err := a.Authenticate(tc.creds)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, principal)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{})
+	require.ErrorIs(t, err, ErrNoMethods)
+
+	_, err = New(Config{APIKeys: []APIKey{{Name: "ci", UserID: 1, SHA256: "abc"}}})
+	require.ErrorIs(t, err, ErrInvalidAPIKeyHash)
+
+	_, err = New(Config{APIKeys: []APIKey{{Name: "ci", SHA256: hashAPIKey(testAPIKey)}}})
+	require.ErrorIs(t, err, ErrInvalidAPIKeyUser)
+
+	_, err = New(Config{JWKSFile: filepath.Join(t.TempDir(), "missing.json")})
+	require.Error(t, err)
+}
+
+func TestParseJWKS(t *testing.T) {
+	testCases := []struct {
+		name string
+		jwks string
+		err  error
+	}{
+		{
+			name: "other key types are skipped",
+			jwks: `{"keys":[{"kty":"EC","kid":"ec","crv":"P-256"},{"kty":"oct","kid":"enc","use":"enc","k":"c2VjcmV0"}]}`,
+		},
+		{
+			name: "missing key id",
+			jwks: `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`,
+			err:  ErrInvalidJWKS,
+		},
+		{
+			name: "duplicate key id",
+			jwks: `{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"},{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`,
+			err:  ErrInvalidJWKS,
+		},
+		{
+			name: "unsupported alg",
+			jwks: `{"keys":[{"kty":"oct","kid":"a","alg":"HS512","k":"c2VjcmV0"}]}`,
+			err:  ErrInvalidJWKS,
+		},
+		{
+			name: "invalid rsa key",
+			jwks: `{"keys":[{"kty":"RSA","kid":"a","n":"","e":"AQAB"}]}`,
+			err:  ErrInvalidJWKS,
+		},
+		{
+			name: "invalid json",
+			jwks: `{"keys":`,
+			err:  ErrInvalidJWKS,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseJWKS([]byte(tc.jwks))
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func newTestAuthenticator(t *testing.T, public *rsa.PublicKey) *Authenticator {
+	t.Helper()
+
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa",
+				"alg": algRS256,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			},
+			{
+				"kty": "oct",
+				"kid": "hmac",
+				"k":   base64.RawURLEncoding.EncodeToString([]byte(testJWKSecret)),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	a, err := New(Config{
+		HMACSecret: testSecret,
+		JWKSFile:   path,
+		Issuer:     "calendar",
+		Audience:   "calendar-api",
+		Leeway:     30 * time.Second,
+		APIKeys:    []APIKey{{Name: "ci", UserID: 7, SHA256: hashAPIKey(testAPIKey)}},
+	})
+	require.NoError(t, err)
+
+	a.now = func() time.Time { return testNow }
+	return a
+}
+
+func bearer(token string) Credentials {
+	return Credentials{Authorization: bearerPrefix + token}
+}
+
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signingInput(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	return encodeSegment(t, header) + "." + encodeSegment(t, claims)
+}
+
+func signHS256(t *testing.T, secret, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	input := signingInput(t, algHS256, kid, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, private *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	input := signingInput(t, algRS256, kid, claims)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
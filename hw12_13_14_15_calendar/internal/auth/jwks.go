@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+)
+
+var (
+	ErrInvalidJWKS = errors.New("invalid jwks")
+	ErrUnknownKey  = errors.New("unknown signing key")
+)
+
+// key verifies signatures of the algorithm.
+type key struct {
+	alg    string
+	secret []byte
+	public *rsa.PublicKey
+}
+
+// keySet keeps the keys of the JWKS file by their ids and the secret of tokens without the key id.
+type keySet struct {
+	byID   map[string]key
+	secret []byte
+}
+
+// jwk is the JSON Web Key, RFC 7517. Only RSA and symmetric keys are supported.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func loadJWKS(path string) (keySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return keySet{}, fmt.Errorf("error reading jwks file: %w", err)
+	}
+	return parseJWKS(data)
+}
+
+// parseJWKS skips keys of other types and the ones which are not for signatures.
+func parseJWKS(data []byte) (keySet, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return keySet{}, fmt.Errorf("%w: %s", ErrInvalidJWKS, err.Error())
+	}
+
+	keys := keySet{byID: make(map[string]key, len(set.Keys))}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var parsed key
+		switch k.Kty {
+		case "RSA":
+			public, err := parseRSAKey(k)
+			if err != nil {
+				return keySet{}, fmt.Errorf("%w: key %q: %s", ErrInvalidJWKS, k.Kid, err.Error())
+			}
+			parsed = key{alg: algRS256, public: public}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return keySet{}, fmt.Errorf("%w: key %q: invalid k", ErrInvalidJWKS, k.Kid)
+			}
+			parsed = key{alg: algHS256, secret: secret}
+		default:
+			continue
+		}
+
+		if k.Kid == "" {
+			return keySet{}, fmt.Errorf("%w: key id is missing", ErrInvalidJWKS)
+		}
+		if k.Alg != "" && k.Alg != parsed.alg {
+			return keySet{}, fmt.Errorf("%w: key %q: unsupported alg %s", ErrInvalidJWKS, k.Kid, k.Alg)
+		}
+		if _, ok := keys.byID[k.Kid]; ok {
+			return keySet{}, fmt.Errorf("%w: duplicate key %q", ErrInvalidJWKS, k.Kid)
+		}
+		keys.byID[k.Kid] = parsed
+	}
+
+	return keys, nil
+}
+
+func parseRSAKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("invalid n")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid e")
+	}
+
+	exponent := int(new(big.Int).SetBytes(e).Int64())
+	if exponent < 3 {
+		return nil, errors.New("invalid e")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+}
+
+// lookup returns the key of the token. The algorithm of the token must be the one of the key,
+// so RSA public keys are never used as HMAC secrets.
+func (s keySet) lookup(alg, kid string) (key, error) {
+	if kid != "" {
+		k, ok := s.byID[kid]
+		if !ok {
+			return key{}, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+		}
+		if k.alg != alg {
+			return key{}, fmt.Errorf("%w: alg %s does not match key %s", ErrInvalidToken, alg, kid)
+		}
+		return k, nil
+	}
+
+	switch alg {
+	case algHS256:
+		if len(s.secret) > 0 {
+			return key{alg: algHS256, secret: s.secret}, nil
+		}
+	case algRS256:
+		// the only RSA key does not need the id
+		var (
+			found key
+			count int
+		)
+		for _, k := range s.byID {
+			if k.alg == algRS256 {
+				found = k
+				count++
+			}
+		}
+		if count == 1 {
+			return found, nil
+		}
+	default:
+		return key{}, fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, alg)
+	}
+
+	return key{}, fmt.Errorf("%w: no key for %s token without key id", ErrUnknownKey, alg)
+}
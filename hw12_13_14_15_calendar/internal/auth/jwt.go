@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/identity"
+)
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// audience is either the single string or the array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyToken checks the signature and the claims of the token, the subject of the token is ID of the user.
+func (a *Authenticator) verifyToken(token string) (identity.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return identity.Principal{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return identity.Principal{}, fmt.Errorf("%w: header: %s", ErrInvalidToken, err.Error())
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return identity.Principal{}, fmt.Errorf("%w: signature: %s", ErrInvalidToken, err.Error())
+	}
+
+	k, err := a.keys.lookup(header.Alg, header.Kid)
+	if err != nil {
+		return identity.Principal{}, err
+	}
+	if !k.verify(parts[0]+"."+parts[1], signature) {
+		return identity.Principal{}, fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return identity.Principal{}, fmt.Errorf("%w: claims: %s", ErrInvalidToken, err.Error())
+	}
+	if err := a.validateClaims(claims); err != nil {
+		return identity.Principal{}, err
+	}
+
+	userID, err := identity.ParseUserID(claims.Subject)
+	if err != nil {
+		return identity.Principal{}, fmt.Errorf("%w: sub: %s", ErrInvalidToken, err.Error())
+	}
+
+	return identity.Principal{
+		UserID:  userID,
+		Subject: claims.Subject,
+		Method:  MethodJWT,
+	}, nil
+}
+
+func (a *Authenticator) validateClaims(claims tokenClaims) error {
+	now := a.now()
+
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: exp is missing", ErrInvalidToken)
+	}
+	if now.After(numericDate(*claims.ExpiresAt).Add(a.cfg.Leeway)) {
+		return ErrExpiredToken
+	}
+	if claims.NotBefore != nil && now.Add(a.cfg.Leeway).Before(numericDate(*claims.NotBefore)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if a.cfg.Issuer != "" && claims.Issuer != a.cfg.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if a.cfg.Audience != "" && !claims.Audience.contains(a.cfg.Audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	return nil
+}
+
+func (k key) verify(signingInput string, signature []byte) bool {
+	switch k.alg {
+	case algHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write([]byte(signingInput))
+		return hmac.Equal(mac.Sum(nil), signature)
+	case algRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(k.public, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts seconds since the epoch into time.
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
@@ -0,0 +1,197 @@
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+)
+
+// DefaultBuffer is the number of changes kept for the watcher which has not received them yet.
+const DefaultBuffer = 64
+
+var (
+	// ErrSlowWatcher is returned when the watcher does not keep up with the changes,
+	// the changes would be lost, so the subscription is closed and the watcher has to resubscribe.
+	ErrSlowWatcher = errors.New("watcher is too slow to receive changes")
+	ErrClosed      = errors.New("subscription is closed")
+)
+
+// Feed delivers changes of events to the watchers of their users.
+type Feed interface {
+	Publish(ctx context.Context, change models.EventChange)
+	Subscribe(filter Filter) *Subscription
+}
+
+// Filter selects changes of the user's events and of the events the user is invited to which take place
+// in [From, To). Zero From or To leaves the period open on that side. Deletions of the user's events
+// and of the events the user is invited to are always selected, because the deleted event is unknown.
+// Updates are selected by the state of the event either after or before the change, so the watcher
+// learns that the event has left the period or that the user is no longer invited.
+type Filter struct {
+	UserID int
+	From   time.Time
+	To     time.Time
+}
+
+func (f Filter) Match(change models.EventChange) bool {
+	if change.Event == nil {
+		return change.UserID == f.UserID || containsUser(change.Attendees, f.UserID)
+	}
+	if f.matchState(change.UserID, models.StateOf(*change.Event)) {
+		return true
+	}
+	return change.Previous != nil && f.matchState(change.UserID, *change.Previous)
+}
+
+// matchState reports whether the event of the owner in the given state is watched. Declined attendees
+// are notified too, so they remove the event from their calendars.
+func (f Filter) matchState(ownerID int, state models.EventState) bool {
+	if ownerID != f.UserID && !containsUser(state.Attendees, f.UserID) {
+		return false
+	}
+	if f.From.IsZero() && f.To.IsZero() {
+		return true
+	}
+
+	if state.Recurrence == nil {
+		end := state.Date.Add(state.Duration)
+		return (f.To.IsZero() || state.Date.Before(f.To)) && (f.From.IsZero() || end.After(f.From))
+	}
+
+	// occurrences which started before From but have not ended yet are in the period too
+	from := state.Date
+	if !f.From.IsZero() {
+		from = f.From.Add(-state.Duration)
+	}
+	if f.To.IsZero() {
+		last, ok := recurrence.End(state.Date, *state.Recurrence)
+		return !ok || last.After(from)
+	}
+
+	for _, date := range recurrence.Occurrences(state.Date, *state.Recurrence, from, f.To) {
+		if (f.From.IsZero() || date.After(from)) && date.Before(f.To) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUser(ids []int, userID int) bool {
+	for _, id := range ids {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus delivers changes within the process. Publish never blocks: the watcher whose buffer is full
+// is unsubscribed with ErrSlowWatcher.
+type Bus struct {
+	buffer int
+
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewBus(buffer int) *Bus {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Bus{
+		buffer: buffer,
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *Bus) Publish(_ context.Context, change models.EventChange) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if sub.filter.Match(change) {
+			sub.send(change)
+		}
+	}
+}
+
+func (b *Bus) Subscribe(filter Filter) *Subscription {
+	sub := &Subscription{
+		bus:     b,
+		filter:  filter,
+		changes: make(chan models.EventChange, b.buffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+func (b *Bus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subs, sub)
+}
+
+// Subscription receives the changes selected by its filter until it is closed.
+type Subscription struct {
+	bus    *Bus
+	filter Filter
+
+	mu      sync.Mutex
+	changes chan models.EventChange
+	err     error
+}
+
+// Changes returns the channel of changes, it is closed when the subscription is closed.
+func (s *Subscription) Changes() <-chan models.EventChange {
+	return s.changes
+}
+
+// Err returns the reason the subscription is closed, nil while it is open.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Close unsubscribes from the bus, it is safe to call it more than once.
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+	s.close(ErrClosed)
+}
+
+func (s *Subscription) send(change models.EventChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+
+	select {
+	case s.changes <- change:
+	default:
+		s.err = ErrSlowWatcher
+		close(s.changes)
+	}
+}
+
+func (s *Subscription) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	close(s.changes)
+}
@@ -0,0 +1,253 @@
+package changefeed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+var testDate = time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+func updated(event models.Event) models.EventChange {
+	return models.EventChange{
+		Type:    models.ChangeUpdated,
+		EventID: event.ID,
+		UserID:  event.UserID,
+		Version: event.Version,
+		Event:   &event,
+	}
+}
+
+// updatedFrom returns the update of the event which has been in the previous state.
+func updatedFrom(previous, event models.Event) models.EventChange {
+	change := updated(event)
+	state := models.StateOf(previous)
+	change.Previous = &state
+	return change
+}
+
+func TestFilterMatch(t *testing.T) {
+	single := models.Event{ID: "single", UserID: 1, Date: testDate, Duration: time.Hour}
+	daily := models.Event{
+		ID:         "daily",
+		UserID:     1,
+		Date:       testDate,
+		Duration:   time.Hour,
+		Recurrence: &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 3},
+	}
+	endless := daily
+	endless.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+	invited := single
+	invited.Attendees = []models.Attendee{{UserID: 2, Status: models.AttendeeDeclined}}
+	moved := single
+	moved.Date = testDate.AddDate(0, 0, 7)
+
+	testCases := []struct {
+		name     string
+		filter   Filter
+		change   models.EventChange
+		expected bool
+	}{
+		{
+			name:     "other user",
+			filter:   Filter{UserID: 2},
+			change:   updated(single),
+			expected: false,
+		},
+		{
+			name:     "no period",
+			filter:   Filter{UserID: 1},
+			change:   updated(single),
+			expected: true,
+		},
+		{
+			name:     "attendee",
+			filter:   Filter{UserID: 2},
+			change:   updated(invited),
+			expected: true,
+		},
+		{
+			name:     "deletion of the event of other user",
+			filter:   Filter{UserID: 2},
+			change:   models.EventChange{Type: models.ChangeDeleted, EventID: "invited", UserID: 1},
+			expected: false,
+		},
+		{
+			name:     "deletion of the event the user is invited to",
+			filter:   Filter{UserID: 2},
+			change:   models.EventChange{Type: models.ChangeDeleted, EventID: "invited", UserID: 1, Attendees: []int{3, 2}},
+			expected: true,
+		},
+		{
+			name:     "deletion outside of the period",
+			filter:   Filter{UserID: 1, From: testDate.AddDate(1, 0, 0)},
+			change:   models.EventChange{Type: models.ChangeDeleted, EventID: "single", UserID: 1},
+			expected: true,
+		},
+		{
+			name:     "event in the period",
+			filter:   Filter{UserID: 1, From: testDate.Add(-time.Hour), To: testDate.Add(time.Hour)},
+			change:   updated(single),
+			expected: true,
+		},
+		{
+			name:     "event overlaps from",
+			filter:   Filter{UserID: 1, From: testDate.Add(30 * time.Minute)},
+			change:   updated(single),
+			expected: true,
+		},
+		{
+			name:     "event ends at from",
+			filter:   Filter{UserID: 1, From: testDate.Add(time.Hour)},
+			change:   updated(single),
+			expected: false,
+		},
+		{
+			name:     "event starts at to",
+			filter:   Filter{UserID: 1, To: testDate},
+			change:   updated(single),
+			expected: false,
+		},
+		{
+			name:     "occurrence in the period",
+			filter:   Filter{UserID: 1, From: testDate.AddDate(0, 0, 2), To: testDate.AddDate(0, 0, 2).Add(time.Hour)},
+			change:   updated(daily),
+			expected: true,
+		},
+		{
+			name:     "first occurrence before to",
+			filter:   Filter{UserID: 1, To: testDate.Add(time.Minute)},
+			change:   updated(daily),
+			expected: true,
+		},
+		{
+			name:     "series is over",
+			filter:   Filter{UserID: 1, From: testDate.AddDate(0, 0, 3)},
+			change:   updated(daily),
+			expected: false,
+		},
+		{
+			name:     "endless series",
+			filter:   Filter{UserID: 1, From: testDate.AddDate(10, 0, 0)},
+			change:   updated(endless),
+			expected: true,
+		},
+		{
+			name:     "event moved out of the period",
+			filter:   Filter{UserID: 1, From: testDate, To: testDate.Add(time.Hour)},
+			change:   updatedFrom(single, moved),
+			expected: true,
+		},
+		{
+			name:     "event moved into the period",
+			filter:   Filter{UserID: 1, From: moved.Date, To: moved.Date.Add(time.Hour)},
+			change:   updatedFrom(single, moved),
+			expected: true,
+		},
+		{
+			name:     "event moved outside of the period",
+			filter:   Filter{UserID: 1, From: testDate.AddDate(0, 0, 1), To: testDate.AddDate(0, 0, 2)},
+			change:   updatedFrom(single, moved),
+			expected: false,
+		},
+		{
+			name:     "series moved out of the period",
+			filter:   Filter{UserID: 1, From: testDate.AddDate(0, 0, 2), To: testDate.AddDate(0, 0, 3)},
+			change:   updatedFrom(daily, single),
+			expected: true,
+		},
+		{
+			name:     "attendee removed",
+			filter:   Filter{UserID: 2},
+			change:   updatedFrom(invited, single),
+			expected: true,
+		},
+		{
+			name:     "attendee removed from the event outside of the period",
+			filter:   Filter{UserID: 2, From: testDate.AddDate(0, 0, 1)},
+			change:   updatedFrom(invited, single),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.filter.Match(tc.change))
+		})
+	}
+}
+
+func TestBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus(2)
+
+	first := bus.Subscribe(Filter{UserID: 1})
+	defer first.Close()
+	other := bus.Subscribe(Filter{UserID: 2})
+	defer other.Close()
+
+	change := updated(models.Event{ID: "id", UserID: 1, Date: testDate, Duration: time.Hour, Version: 2})
+	bus.Publish(ctx, change)
+
+	require.Equal(t, change, <-first.Changes())
+	require.Empty(t, other.Changes())
+	require.NoError(t, first.Err())
+
+	// the watcher which does not receive changes is dropped instead of blocking the publisher
+	for i := 0; i < 3; i++ {
+		bus.Publish(ctx, change)
+	}
+	require.ErrorIs(t, first.Err(), ErrSlowWatcher)
+
+	received := 0
+	for range first.Changes() {
+		received++
+	}
+	require.Equal(t, 2, received)
+
+	other.Close()
+	other.Close()
+	require.ErrorIs(t, other.Err(), ErrClosed)
+	_, ok := <-other.Changes()
+	require.False(t, ok)
+
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	require.Len(t, bus.subs, 1)
+}
+
+func TestBusAttendeeWatchesDeletion(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus(2)
+
+	attendee := bus.Subscribe(Filter{UserID: 2, From: testDate, To: testDate.Add(time.Hour)})
+	defer attendee.Close()
+	stranger := bus.Subscribe(Filter{UserID: 3})
+	defer stranger.Close()
+
+	invited := models.Event{
+		ID:        "id",
+		UserID:    1,
+		Date:      testDate,
+		Duration:  time.Hour,
+		Version:   1,
+		Attendees: []models.Attendee{{UserID: 2, Status: models.AttendeeAccepted}},
+	}
+	bus.Publish(ctx, updated(invited))
+	require.Equal(t, updated(invited), <-attendee.Changes())
+
+	deleted := models.EventChange{
+		Type:      models.ChangeDeleted,
+		EventID:   invited.ID,
+		UserID:    invited.UserID,
+		Version:   invited.Version,
+		Attendees: models.AttendeeIDs(invited.Attendees),
+	}
+	bus.Publish(ctx, deleted)
+	require.Equal(t, deleted, <-attendee.Changes())
+	require.Empty(t, stranger.Changes())
+}
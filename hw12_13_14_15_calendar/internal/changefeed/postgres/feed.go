@@ -0,0 +1,247 @@
+package postgresfeed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+	"golang.org/x/exp/slog"
+)
+
+const channel = "event_changes"
+
+const (
+	notifyTimeout = 5 * time.Second
+	minReconnect  = 100 * time.Millisecond
+	maxReconnect  = 10 * time.Second
+)
+
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// EventGetter loads events of the changes made by other replicas.
+type EventGetter interface {
+	GetEventByID(ctx context.Context, id string) (models.Event, error)
+}
+
+// notification is the payload of NOTIFY. It has no event, because the payload is limited to 8000 bytes.
+type notification struct {
+	Origin    string            `json:"origin"`
+	Type      models.ChangeType `json:"type"`
+	EventID   string            `json:"event_id"`
+	UserID    int               `json:"user_id"`
+	Version   int64             `json:"version"`
+	ChangedAt time.Time         `json:"changed_at"`
+	Attendees []int             `json:"attendees,omitempty"`
+	Previous  *previousState    `json:"previous,omitempty"`
+}
+
+// previousState is the state of the updated event before the change. The exceptions of the series
+// are left out to fit the payload, so the watchers of the skipped occurrences are notified too.
+type previousState struct {
+	Date      time.Time     `json:"date"`
+	Duration  time.Duration `json:"duration"`
+	Rule      string        `json:"rule,omitempty"`
+	TimeZone  string        `json:"time_zone,omitempty"`
+	Attendees []int         `json:"attendees,omitempty"`
+}
+
+func encodeState(state *models.EventState) *previousState {
+	if state == nil {
+		return nil
+	}
+	encoded := &previousState{
+		Date:      state.Date,
+		Duration:  state.Duration,
+		Attendees: state.Attendees,
+	}
+	if state.Recurrence != nil {
+		encoded.Rule = recurrence.Format(*state.Recurrence)
+		encoded.TimeZone = recurrence.TimeZone(*state.Recurrence)
+	}
+	return encoded
+}
+
+func decodeState(encoded *previousState) (*models.EventState, error) {
+	if encoded == nil {
+		return nil, nil
+	}
+	state := &models.EventState{
+		Date:      encoded.Date,
+		Duration:  encoded.Duration,
+		Attendees: encoded.Attendees,
+	}
+	if encoded.Rule == "" {
+		return state, nil
+	}
+
+	r, err := recurrence.Parse(encoded.Rule)
+	if err != nil {
+		return nil, err
+	}
+	if encoded.TimeZone != "" {
+		r.Location, err = time.LoadLocation(encoded.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	state.Recurrence = &r
+	return state, nil
+}
+
+// Feed delivers changes to the watchers of this replica and notifies other replicas sharing the database.
+// Changes made while the listener reconnects are not delivered.
+type Feed struct {
+	*changefeed.Bus
+	db     DB
+	events EventGetter
+	logger logger.Logger
+	// origin tells the notifications of this replica, their changes are already delivered.
+	origin string
+}
+
+func NewFeed(db DB, events EventGetter, logger logger.Logger, buffer int) *Feed {
+	return &Feed{
+		Bus:    changefeed.NewBus(buffer),
+		db:     db,
+		events: events,
+		logger: logger,
+		origin: uuid.New().String(),
+	}
+}
+
+// Publish delivers the change locally and notifies other replicas. The notification is sent
+// even if ctx is done, because the change has already been made.
+func (f *Feed) Publish(ctx context.Context, change models.EventChange) {
+	f.Bus.Publish(ctx, change)
+
+	payload, err := json.Marshal(notification{
+		Origin:    f.origin,
+		Type:      change.Type,
+		EventID:   change.EventID,
+		UserID:    change.UserID,
+		Version:   change.Version,
+		ChangedAt: change.ChangedAt,
+		Attendees: change.Attendees,
+		Previous:  encodeState(change.Previous),
+	})
+	if err != nil {
+		f.logger.Error("error encoding event change", slog.String("error", err.Error()))
+		return
+	}
+
+	notifyCtx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	if _, err := f.db.Exec(notifyCtx, `SELECT pg_notify($1, $2)`, channel, string(payload)); err != nil {
+		f.logger.Error("error notifying about event change",
+			slog.String("event_id", change.EventID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// Listen delivers changes of other replicas until ctx is done. The connection is reestablished
+// with the exponential backoff when it fails.
+func (f *Feed) Listen(ctx context.Context) {
+	delay := minReconnect
+	for {
+		listening, err := f.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if listening {
+			delay = minReconnect
+		}
+		f.logger.Error("error listening to event changes", slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxReconnect {
+			delay = maxReconnect
+		}
+	}
+}
+
+// listen reports whether LISTEN has succeeded together with the error which has stopped listening.
+func (f *Feed) listen(ctx context.Context) (bool, error) {
+	pooled, err := f.db.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	// the listening connection is not returned to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return false, err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		f.handle(ctx, n.Payload)
+	}
+}
+
+func (f *Feed) handle(ctx context.Context, payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		f.logger.Error("error decoding event change", slog.String("error", err.Error()))
+		return
+	}
+	if n.Origin == f.origin {
+		return
+	}
+	// the change is still delivered to the watchers of the event after the change
+	previous, err := decodeState(n.Previous)
+	if err != nil {
+		f.logger.Error("error decoding previous state of event",
+			slog.String("event_id", n.EventID),
+			slog.String("error", err.Error()))
+	}
+
+	change := models.EventChange{
+		Type:      n.Type,
+		EventID:   n.EventID,
+		UserID:    n.UserID,
+		Version:   n.Version,
+		ChangedAt: n.ChangedAt,
+		Attendees: n.Attendees,
+		Previous:  previous,
+	}
+
+	if n.Type != models.ChangeDeleted {
+		event, err := f.events.GetEventByID(ctx, n.EventID)
+		switch {
+		// the event has been deleted in between, the deletion is delivered by its own notification
+		case errors.Is(err, customerror.ErrNotFound):
+			return
+		case err != nil:
+			f.logger.Error("error getting changed event",
+				slog.String("event_id", n.EventID),
+				slog.String("error", err.Error()))
+			return
+		}
+		change.Event = &event
+	}
+
+	f.Bus.Publish(ctx, change)
+}
@@ -0,0 +1,273 @@
+package postgresfeed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/postgres/pgtest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// testDSN is the connection string of the real database, empty if it is unavailable.
+var testDSN string
+
+func TestMain(m *testing.M) {
+	srv, err := pgtest.Start(context.Background(), "../../../migrations")
+	switch {
+	case err == nil:
+		testDSN = srv.DSN
+	case !errors.Is(err, pgtest.ErrUnavailable):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	if srv != nil {
+		srv.Stop()
+	}
+	os.Exit(code)
+}
+
+var testDate = time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+// events is the storage of the other replica.
+type events map[string]models.Event
+
+func (e events) GetEventByID(_ context.Context, id string) (models.Event, error) {
+	event, ok := e[id]
+	if !ok {
+		return models.Event{}, customerror.CustomError{Field: "id", Message: "not found", Err: customerror.ErrNotFound}
+	}
+	return event, nil
+}
+
+func TestFeedPublish(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	feed := NewFeed(mock, events{}, mock_logger.NewMockLogger(ctrl), 1)
+	sub := feed.Subscribe(changefeed.Filter{UserID: 1})
+	defer sub.Close()
+
+	change := models.EventChange{
+		Type:      models.ChangeDeleted,
+		EventID:   "id",
+		UserID:    1,
+		Version:   3,
+		ChangedAt: testDate,
+		Attendees: []int{2},
+	}
+	payload, err := json.Marshal(notification{
+		Origin:    feed.origin,
+		Type:      models.ChangeDeleted,
+		EventID:   "id",
+		UserID:    1,
+		Version:   3,
+		ChangedAt: testDate,
+		Attendees: []int{2},
+	})
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+		WithArgs(channel, string(payload)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	feed.Publish(context.Background(), change)
+
+	require.Equal(t, change, <-sub.Changes())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFeedHandle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	event := models.Event{ID: "id", UserID: 1, Title: "title", Date: testDate, Duration: time.Hour, Version: 2}
+	feed := NewFeed(nil, events{event.ID: event}, mock_logger.NewMockLogger(ctrl), 10)
+
+	sub := feed.Subscribe(changefeed.Filter{UserID: 1})
+	defer sub.Close()
+
+	encode := func(n notification) string {
+		data, err := json.Marshal(n)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	ctx := context.Background()
+	// the change of this replica has already been delivered by Publish
+	feed.handle(ctx, encode(notification{Origin: feed.origin, Type: models.ChangeUpdated, EventID: "id", UserID: 1}))
+	// the event deleted after the notification is skipped
+	feed.handle(ctx, encode(notification{Origin: "other", Type: models.ChangeUpdated, EventID: "deleted", UserID: 1}))
+	require.Empty(t, sub.Changes())
+
+	feed.handle(ctx, encode(notification{
+		Origin:    "other",
+		Type:      models.ChangeUpdated,
+		EventID:   "id",
+		UserID:    1,
+		Version:   2,
+		ChangedAt: testDate,
+	}))
+	require.Equal(t, models.EventChange{
+		Type:      models.ChangeUpdated,
+		EventID:   "id",
+		UserID:    1,
+		Version:   2,
+		ChangedAt: testDate,
+		Event:     &event,
+	}, <-sub.Changes())
+
+	feed.handle(ctx, encode(notification{Origin: "other", Type: models.ChangeDeleted, EventID: "gone", UserID: 1, Version: 4}))
+	require.Equal(t, models.EventChange{
+		Type:    models.ChangeDeleted,
+		EventID: "gone",
+		UserID:  1,
+		Version: 4,
+	}, <-sub.Changes())
+	// the attendee learns about the deletion from the ids in the payload
+	attendee := feed.Subscribe(changefeed.Filter{UserID: 2})
+	defer attendee.Close()
+
+	feed.handle(ctx, encode(notification{
+		Origin:    "other",
+		Type:      models.ChangeDeleted,
+		EventID:   "invited",
+		UserID:    1,
+		Version:   5,
+		Attendees: []int{2},
+	}))
+	deleted := models.EventChange{
+		Type:      models.ChangeDeleted,
+		EventID:   "invited",
+		UserID:    1,
+		Version:   5,
+		Attendees: []int{2},
+	}
+	require.Equal(t, deleted, <-sub.Changes())
+	require.Equal(t, deleted, <-attendee.Changes())
+}
+
+func TestFeedPreviousState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	// the weekly series the attendee is removed from is moved to the next week
+	event := models.Event{ID: "id", UserID: 1, Date: testDate.AddDate(0, 0, 7), Duration: time.Hour, Version: 3}
+	previous := models.EventState{
+		Date:     testDate,
+		Duration: time.Hour,
+		Recurrence: &models.Recurrence{
+			Frequency:  models.FrequencyWeekly,
+			Interval:   1,
+			Count:      2,
+			Exceptions: []time.Time{testDate},
+			Location:   berlin,
+		},
+		Attendees: []int{2},
+	}
+	change := models.EventChange{
+		Type:      models.ChangeUpdated,
+		EventID:   event.ID,
+		UserID:    event.UserID,
+		Version:   event.Version,
+		ChangedAt: testDate,
+		Event:     &event,
+		Previous:  &previous,
+	}
+
+	publisher := NewFeed(mock, events{}, mock_logger.NewMockLogger(ctrl), 1)
+	payload, err := json.Marshal(notification{
+		Origin:    publisher.origin,
+		Type:      change.Type,
+		EventID:   change.EventID,
+		UserID:    change.UserID,
+		Version:   change.Version,
+		ChangedAt: change.ChangedAt,
+		Previous: &previousState{
+			Date:      testDate,
+			Duration:  time.Hour,
+			Rule:      "FREQ=WEEKLY;COUNT=2",
+			TimeZone:  "Europe/Berlin",
+			Attendees: []int{2},
+		},
+	})
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+		WithArgs(channel, string(payload)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	publisher.Publish(context.Background(), change)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	replica := NewFeed(nil, events{event.ID: event}, mock_logger.NewMockLogger(ctrl), 1)
+	attendee := replica.Subscribe(changefeed.Filter{UserID: 2, From: testDate, To: testDate.Add(time.Hour)})
+	defer attendee.Close()
+
+	replica.handle(context.Background(), string(payload))
+
+	received := <-attendee.Changes()
+	require.Equal(t, &event, received.Event)
+	require.Equal(t, previous.Date, received.Previous.Date)
+	require.Equal(t, previous.Attendees, received.Previous.Attendees)
+	// the exceptions are not sent
+	require.Equal(t, models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1, Count: 2, Location: berlin},
+		*received.Previous.Recurrence)
+}
+
+func TestFeedListen(t *testing.T) {
+	if testDSN == "" {
+		t.Skip(pgtest.ErrUnavailable.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, testDSN)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctrl := gomock.NewController(t)
+	event := models.Event{ID: "id", UserID: 1, Title: "title", Date: testDate, Duration: time.Hour, Version: 1}
+	stored := events{event.ID: event}
+
+	publisher := NewFeed(db, stored, mock_logger.NewMockLogger(ctrl), 10)
+	replica := NewFeed(db, stored, mock_logger.NewMockLogger(ctrl), 10)
+	go replica.Listen(ctx)
+
+	sub := replica.Subscribe(changefeed.Filter{UserID: 1})
+	defer sub.Close()
+
+	created := models.EventChange{Type: models.ChangeCreated, EventID: "id", UserID: 1, Version: 1, Event: &event}
+	// LISTEN is asynchronous, so the change is published until the replica receives it
+	require.Eventually(t, func() bool {
+		publisher.Publish(ctx, created)
+		select {
+		case change := <-sub.Changes():
+			require.Equal(t, created.EventID, change.EventID)
+			require.Equal(t, &event, change.Event)
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,46 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+var (
+	// ErrDelivered is returned when the notification is already delivered, the duplicate is dropped.
+	ErrDelivered = errors.New("notification is already delivered")
+	// ErrInProgress is returned when another copy of the notification is being delivered,
+	// the duplicate is retried later in case that delivery fails.
+	ErrInProgress = errors.New("notification is being delivered")
+)
+
+// Store remembers delivered notifications by their keys, so every one is delivered at most once.
+type Store interface {
+	// Acquire reserves the key for the delivery until it is completed, released or the lease expires.
+	Acquire(ctx context.Context, key string) error
+	// Complete marks the key delivered, it is remembered for TTL.
+	Complete(ctx context.Context, key string) error
+	// Release drops the reservation of the failed delivery, so the notification can be delivered again.
+	Release(ctx context.Context, key string) error
+	// Purge forgets expired keys and returns how many of them were removed.
+	Purge(ctx context.Context) (int64, error)
+}
+
+type Config struct {
+	// Lease is how long the key stays reserved, it is acquired again after the lease expires
+	// if the sender has stopped in the middle of the delivery.
+	Lease time.Duration
+	// TTL is how long delivered keys are remembered, duplicates arriving later are delivered again.
+	TTL time.Duration
+}
+
+// Key returns the key of the message delivered through the channel, it is empty if the message has no id.
+func Key(msg mq.Message, channel models.ReminderChannel) string {
+	if msg.ID == "" {
+		return ""
+	}
+	return msg.ID + "/" + string(channel)
+}
@@ -0,0 +1,110 @@
+package deduptest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup"
+	"github.com/stretchr/testify/require"
+)
+
+// expiry is the lease and the TTL of stores whose keys are expected to expire during the test.
+const expiry = 50 * time.Millisecond
+
+// NewStore returns the empty store with the config.
+type NewStore func(t *testing.T, cfg dedup.Config) dedup.Store
+
+// Run checks that the store follows the semantics shared by all implementations of dedup.Store.
+func Run(t *testing.T, newStore NewStore) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, newStore NewStore)
+	}{
+		{name: "delivered once", fn: testDeliveredOnce},
+		{name: "in progress", fn: testInProgress},
+		{name: "release", fn: testRelease},
+		{name: "lease expires", fn: testLeaseExpires},
+		{name: "ttl expires", fn: testTTLExpires},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore)
+		})
+	}
+}
+
+func testDeliveredOnce(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	s := newStore(t, dedup.Config{Lease: time.Minute, TTL: time.Hour})
+
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+	require.NoError(t, s.Complete(ctx, "1-1/log"))
+
+	require.ErrorIs(t, s.Acquire(ctx, "1-1/log"), dedup.ErrDelivered)
+	// the delivered key is not released
+	require.NoError(t, s.Release(ctx, "1-1/log"))
+	require.ErrorIs(t, s.Acquire(ctx, "1-1/log"), dedup.ErrDelivered)
+
+	// other channels and firings are delivered separately
+	require.NoError(t, s.Acquire(ctx, "1-1/email"))
+	require.NoError(t, s.Acquire(ctx, "1-2/log"))
+}
+
+func testInProgress(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	s := newStore(t, dedup.Config{Lease: time.Minute, TTL: time.Hour})
+
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+	require.ErrorIs(t, s.Acquire(ctx, "1-1/log"), dedup.ErrInProgress)
+}
+
+func testRelease(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	s := newStore(t, dedup.Config{Lease: time.Minute, TTL: time.Hour})
+
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+	require.NoError(t, s.Release(ctx, "1-1/log"))
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+}
+
+func testLeaseExpires(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	s := newStore(t, dedup.Config{Lease: expiry, TTL: time.Hour})
+
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+
+	time.Sleep(2 * expiry)
+
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+	require.NoError(t, s.Complete(ctx, "1-1/log"))
+
+	time.Sleep(2 * expiry)
+
+	// the delivered key lives for TTL rather than the lease
+	require.ErrorIs(t, s.Acquire(ctx, "1-1/log"), dedup.ErrDelivered)
+}
+
+func testTTLExpires(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	s := newStore(t, dedup.Config{Lease: time.Minute, TTL: expiry})
+
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+	require.NoError(t, s.Complete(ctx, "1-1/log"))
+	require.NoError(t, s.Acquire(ctx, "1-2/log"))
+	require.NoError(t, s.Complete(ctx, "1-2/log"))
+	require.NoError(t, s.Acquire(ctx, "1-3/log"))
+
+	time.Sleep(2 * expiry)
+
+	purged, err := s.Purge(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), purged)
+
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+	require.ErrorIs(t, s.Acquire(ctx, "1-3/log"), dedup.ErrInProgress)
+}
@@ -0,0 +1,86 @@
+package memorydedup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup"
+)
+
+// Store keeps keys in the process, so duplicates are recognized only by the same sender.
+type Store struct {
+	cfg dedup.Config
+	now func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]entry
+}
+
+type entry struct {
+	delivered bool
+	expiresAt time.Time
+}
+
+func NewStore(cfg dedup.Config) *Store {
+	return &Store{
+		cfg:  cfg,
+		now:  time.Now,
+		keys: make(map[string]entry),
+	}
+}
+
+func (s *Store) Acquire(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	if e, ok := s.keys[key]; ok && now.Before(e.expiresAt) {
+		if e.delivered {
+			return dedup.ErrDelivered
+		}
+		return dedup.ErrInProgress
+	}
+
+	s.keys[key] = entry{expiresAt: now.Add(s.cfg.Lease)}
+
+	return nil
+}
+
+func (s *Store) Complete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key] = entry{delivered: true, expiresAt: s.now().Add(s.cfg.TTL)}
+
+	return nil
+}
+
+func (s *Store) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.keys[key]; ok && !e.delivered {
+		delete(s.keys, key)
+	}
+
+	return nil
+}
+
+func (s *Store) Purge(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	var purged int64
+	for key, e := range s.keys {
+		if !now.Before(e.expiresAt) {
+			delete(s.keys, key)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
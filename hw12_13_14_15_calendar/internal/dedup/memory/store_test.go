@@ -0,0 +1,43 @@
+package memorydedup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup/deduptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreConformance(t *testing.T) {
+	deduptest.Run(t, func(t *testing.T, cfg dedup.Config) dedup.Store {
+		t.Helper()
+		return NewStore(cfg)
+	})
+}
+
+func TestStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC)
+
+	s := NewStore(dedup.Config{Lease: time.Minute, TTL: time.Hour})
+	s.now = func() time.Time { return now }
+
+	require.NoError(t, s.Acquire(ctx, "1-1/log"))
+	require.NoError(t, s.Complete(ctx, "1-1/log"))
+
+	s.now = func() time.Time { return now.Add(time.Hour - time.Nanosecond) }
+	require.ErrorIs(t, s.Acquire(ctx, "1-1/log"), dedup.ErrDelivered)
+
+	purged, err := s.Purge(ctx)
+	require.NoError(t, err)
+	require.Zero(t, purged)
+
+	s.now = func() time.Time { return now.Add(time.Hour) }
+
+	purged, err = s.Purge(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+	require.Empty(t, s.keys)
+}
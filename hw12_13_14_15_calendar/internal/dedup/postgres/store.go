@@ -0,0 +1,82 @@
+package postgresdedup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup"
+)
+
+const deliveriesTable = "notification_deliveries"
+
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Store keeps keys in the deliveries table, so duplicates are recognized by all senders sharing the database.
+type Store struct {
+	db  DB
+	cfg dedup.Config
+}
+
+func NewStore(db DB, cfg dedup.Config) *Store {
+	return &Store{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+func (s *Store) Acquire(ctx context.Context, key string) error {
+	// the expired key is taken over by the upsert, the live one is left as it is
+	query := `
+		INSERT INTO ` + deliveriesTable + ` (key, expires_at) VALUES ($1, now() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE SET delivered = false, expires_at = EXCLUDED.expires_at
+		WHERE ` + deliveriesTable + `.expires_at <= now()`
+
+	tag, err := s.db.Exec(ctx, query, key, s.cfg.Lease.Seconds())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 1 {
+		return nil
+	}
+
+	var delivered bool
+	err = s.db.QueryRow(ctx, `SELECT delivered FROM `+deliveriesTable+` WHERE key = $1`, key).Scan(&delivered)
+	switch {
+	// the key has been purged in between, the duplicate is retried
+	case errors.Is(err, pgx.ErrNoRows):
+		return dedup.ErrInProgress
+	case err != nil:
+		return err
+	case delivered:
+		return dedup.ErrDelivered
+	default:
+		return dedup.ErrInProgress
+	}
+}
+
+func (s *Store) Complete(ctx context.Context, key string) error {
+	query := `
+		INSERT INTO ` + deliveriesTable + ` (key, delivered, expires_at) VALUES ($1, true, now() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE SET delivered = true, expires_at = EXCLUDED.expires_at`
+
+	_, err := s.db.Exec(ctx, query, key, s.cfg.TTL.Seconds())
+	return err
+}
+
+func (s *Store) Release(ctx context.Context, key string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM `+deliveriesTable+` WHERE key = $1 AND NOT delivered`, key)
+	return err
+}
+
+func (s *Store) Purge(ctx context.Context) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM `+deliveriesTable+` WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,175 @@
+package postgresdedup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/dedup/deduptest"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/postgres/pgtest"
+	"github.com/stretchr/testify/require"
+)
+
+// testDSN is the connection string of the real database, empty if it is unavailable.
+var testDSN string
+
+func TestMain(m *testing.M) {
+	srv, err := pgtest.Start(context.Background(), "../../../migrations")
+	switch {
+	case err == nil:
+		testDSN = srv.DSN
+	case !errors.Is(err, pgtest.ErrUnavailable):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	if srv != nil {
+		srv.Stop()
+	}
+	os.Exit(code)
+}
+
+var testConfig = dedup.Config{
+	Lease: time.Minute,
+	TTL:   time.Hour,
+}
+
+func TestStoreConformance(t *testing.T) {
+	deduptest.Run(t, func(t *testing.T, cfg dedup.Config) dedup.Store {
+		t.Helper()
+
+		if testDSN == "" {
+			t.Skip(pgtest.ErrUnavailable.Error())
+		}
+
+		ctx := context.Background()
+
+		db, err := pgxpool.New(ctx, testDSN)
+		require.NoError(t, err)
+		t.Cleanup(db.Close)
+
+		_, err = db.Exec(ctx, "TRUNCATE "+deliveriesTable)
+		require.NoError(t, err)
+
+		return NewStore(db, cfg)
+	})
+}
+
+var (
+	acquireQuery = regexp.QuoteMeta(`
+		INSERT INTO ` + deliveriesTable + ` (key, expires_at) VALUES ($1, now() + make_interval(secs => $2))`)
+	selectQuery = regexp.QuoteMeta(`SELECT delivered FROM ` + deliveriesTable + ` WHERE key = $1`)
+)
+
+func TestStoreAcquire(t *testing.T) {
+	testCases := []struct {
+		name     string
+		affected int64
+		rows     *pgxmock.Rows
+		err      error
+		expected error
+	}{
+		{
+			name:     "acquired",
+			affected: 1,
+		},
+		{
+			name:     "delivered",
+			rows:     pgxmock.NewRows([]string{"delivered"}).AddRow(true),
+			expected: dedup.ErrDelivered,
+		},
+		{
+			name:     "in progress",
+			rows:     pgxmock.NewRows([]string{"delivered"}).AddRow(false),
+			expected: dedup.ErrInProgress,
+		},
+		{
+			name:     "purged",
+			err:      pgx.ErrNoRows,
+			expected: dedup.ErrInProgress,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			mock.ExpectExec(acquireQuery).
+				WithArgs("1-1/log", testConfig.Lease.Seconds()).
+				WillReturnResult(pgxmock.NewResult("INSERT", tc.affected))
+			if tc.affected == 0 {
+				expected := mock.ExpectQuery(selectQuery).WithArgs("1-1/log")
+				if tc.err != nil {
+					expected.WillReturnError(tc.err)
+				} else {
+					expected.WillReturnRows(tc.rows)
+				}
+			}
+
+			err = NewStore(mock, testConfig).Acquire(context.Background(), "1-1/log")
+			if tc.expected == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.expected)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+		})
+	}
+}
+
+func TestStoreComplete(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`ON CONFLICT (key) DO UPDATE SET delivered = true, expires_at = EXCLUDED.expires_at`)).
+		WithArgs("1-1/log", testConfig.TTL.Seconds()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	require.NoError(t, NewStore(mock, testConfig).Complete(context.Background(), "1-1/log"))
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStoreRelease(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM ` + deliveriesTable + ` WHERE key = $1 AND NOT delivered`)).
+		WithArgs("1-1/log").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	require.NoError(t, NewStore(mock, testConfig).Release(context.Background(), "1-1/log"))
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStorePurge(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM ` + deliveriesTable + ` WHERE expires_at <= now()`)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+
+	purged, err := NewStore(mock, testConfig).Purge(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), purged)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
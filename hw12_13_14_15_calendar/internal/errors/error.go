@@ -1,10 +1,108 @@
 package customerror
 
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrValidation      = errors.New("validation failed")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrNotFound        = errors.New("not found")
+	ErrForbidden       = errors.New("forbidden")
+	ErrConflict        = errors.New("conflict")
+	ErrDateBusy        = errors.New("date is busy")
+	ErrVersionMismatch = errors.New("version mismatch")
+	ErrUnavailable     = errors.New("unavailable")
+)
+
 type CustomError struct {
 	Field   string
 	Message string
+	// Err is an optional sentinel error which describes the kind of the error.
+	Err error
 }
 
 func (e CustomError) Error() string {
 	return e.Message
 }
+
+func (e CustomError) Unwrap() error {
+	return e.Err
+}
+
+// Kind is the category of the error. Transports map kinds to their status codes.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindValidation
+	KindNotFound
+	KindConflict
+	KindPermission
+	KindUnauthenticated
+	KindUnavailable
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindValidation:
+		return "VALIDATION"
+	case KindNotFound:
+		return "NOT_FOUND"
+	case KindConflict:
+		return "CONFLICT"
+	case KindPermission:
+		return "PERMISSION_DENIED"
+	case KindUnauthenticated:
+		return "UNAUTHENTICATED"
+	case KindUnavailable:
+		return "UNAVAILABLE"
+	case KindInternal:
+	}
+	return "INTERNAL"
+}
+
+// KindOf returns the kind of the error by its sentinel. Errors without a known sentinel are internal.
+func KindOf(err error) Kind {
+	switch {
+	case errors.Is(err, ErrValidation):
+		return KindValidation
+	case errors.Is(err, ErrNotFound):
+		return KindNotFound
+	case errors.Is(err, ErrConflict), errors.Is(err, ErrDateBusy), errors.Is(err, ErrVersionMismatch):
+		return KindConflict
+	case errors.Is(err, ErrForbidden):
+		return KindPermission
+	case errors.Is(err, ErrUnauthenticated):
+		return KindUnauthenticated
+	case errors.Is(err, ErrUnavailable), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return KindUnavailable
+	}
+	return KindInternal
+}
+
+// Reason returns the machine-readable reason of the error. It is the kind of the error
+// unless the sentinel tells more, e.g. which conflict has happened.
+func Reason(err error) string {
+	switch {
+	case errors.Is(err, ErrDateBusy):
+		return "DATE_BUSY"
+	case errors.Is(err, ErrVersionMismatch):
+		return "VERSION_MISMATCH"
+	case errors.Is(err, context.Canceled):
+		return "CANCELED"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "DEADLINE_EXCEEDED"
+	}
+	return KindOf(err).String()
+}
+
+// FieldOf returns the field which caused the error or an empty string.
+func FieldOf(err error) string {
+	var customError CustomError
+	if errors.As(err, &customError) {
+		return customError.Field
+	}
+	return ""
+}
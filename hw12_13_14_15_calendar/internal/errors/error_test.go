@@ -0,0 +1,44 @@
+package customerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	testCases := []struct {
+		err    error
+		kind   Kind
+		reason string
+	}{
+		{err: CustomError{Message: "title cannot be empty", Err: ErrValidation}, kind: KindValidation, reason: "VALIDATION"},
+		{err: CustomError{Message: "no event", Err: ErrNotFound}, kind: KindNotFound, reason: "NOT_FOUND"},
+		{err: CustomError{Message: "duplicate", Err: ErrConflict}, kind: KindConflict, reason: "CONFLICT"},
+		{err: CustomError{Message: "busy", Err: ErrDateBusy}, kind: KindConflict, reason: "DATE_BUSY"},
+		{err: CustomError{Message: "stale", Err: ErrVersionMismatch}, kind: KindConflict, reason: "VERSION_MISMATCH"},
+		{err: CustomError{Message: "other user", Err: ErrForbidden}, kind: KindPermission, reason: "PERMISSION_DENIED"},
+		{err: CustomError{Message: "no caller", Err: ErrUnauthenticated}, kind: KindUnauthenticated, reason: "UNAUTHENTICATED"},
+		{err: CustomError{Message: "refused", Err: ErrUnavailable}, kind: KindUnavailable, reason: "UNAVAILABLE"},
+		{err: CustomError{Message: "timeout", Err: context.DeadlineExceeded}, kind: KindUnavailable, reason: "DEADLINE_EXCEEDED"},
+		{err: fmt.Errorf("wrapped: %w", CustomError{Err: ErrNotFound}), kind: KindNotFound, reason: "NOT_FOUND"},
+		{err: CustomError{Message: "no lines were inserted"}, kind: KindInternal, reason: "INTERNAL"},
+		{err: errors.New("unknown"), kind: KindInternal, reason: "INTERNAL"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			require.Equal(t, tc.kind, KindOf(tc.err))
+			require.Equal(t, tc.reason, Reason(tc.err))
+		})
+	}
+}
+
+func TestFieldOf(t *testing.T) {
+	require.Equal(t, "title", FieldOf(fmt.Errorf("creating: %w", CustomError{Field: "title", Err: ErrValidation})))
+	require.Empty(t, FieldOf(errors.New("unknown")))
+}
@@ -0,0 +1,166 @@
+package freebusy
+
+import (
+	"sort"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/period"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+)
+
+// Busy returns the merged parts of [from, to) taken by the events and occurrences of recurring events.
+func Busy(events []models.Event, from, to time.Time) []models.Interval {
+	var intervals []models.Interval
+
+	for _, event := range events {
+		for _, occurrence := range recurrence.Expand(event, from.Add(-event.Duration), to) {
+			start, end := occurrence.Date, occurrence.Date.Add(occurrence.Duration)
+			if !start.Before(to) || !end.After(from) {
+				continue
+			}
+			intervals = append(intervals, clip(models.Interval{Start: start, End: end}, from, to))
+		}
+	}
+
+	return Merge(intervals)
+}
+
+// Merge sorts the intervals and joins the ones which overlap or touch each other.
+func Merge(intervals []models.Interval) []models.Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	sorted := make([]models.Interval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	merged := sorted[:1]
+	for _, interval := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if interval.Start.After(last.End) {
+			merged = append(merged, interval)
+			continue
+		}
+		if interval.End.After(last.End) {
+			last.End = interval.End
+		}
+	}
+
+	return merged
+}
+
+// FindSlots returns the windows of the working hours of the query in which none of the users is busy
+// for at least the duration of the query, sorted from the earliest one. Limit of the query caps
+// the number of windows if it is positive.
+func FindSlots(calendars []models.FreeBusy, q models.SlotQuery) []models.Interval {
+	var busy []models.Interval
+	for _, calendar := range calendars {
+		busy = append(busy, calendar.Busy...)
+	}
+
+	var slots []models.Interval
+	for _, window := range free(workingWindows(q), Merge(busy)) {
+		if window.End.Sub(window.Start) < q.Duration {
+			continue
+		}
+		slots = append(slots, window)
+		if q.Limit > 0 && len(slots) == q.Limit {
+			break
+		}
+	}
+
+	return slots
+}
+
+// workingWindows returns the working hours of every working day in [q.From, q.To).
+func workingWindows(q models.SlotQuery) []models.Interval {
+	if q.WorkdayStart == 0 && q.WorkdayEnd == 0 && len(q.Weekdays) == 0 {
+		return []models.Interval{{Start: q.From, End: q.To}}
+	}
+
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	workdayEnd := q.WorkdayEnd
+	if q.WorkdayStart == 0 && workdayEnd == 0 {
+		workdayEnd = 24 * time.Hour
+	}
+
+	var windows []models.Interval
+	for day, _ := period.Day(q.From, loc); day.Before(q.To); day = day.AddDate(0, 0, 1) {
+		if !isWorkday(day.Weekday(), q.Weekdays) {
+			continue
+		}
+		window := clip(models.Interval{
+			Start: wallClock(day, q.WorkdayStart),
+			End:   wallClock(day, workdayEnd),
+		}, q.From, q.To)
+		if window.Start.Before(window.End) {
+			windows = append(windows, window)
+		}
+	}
+
+	// working hours of adjacent days touch each other when the whole day is used
+	return Merge(windows)
+}
+
+// free returns the parts of the sorted windows which do not intersect the merged busy intervals.
+func free(windows, busy []models.Interval) []models.Interval {
+	var result []models.Interval
+
+	i := 0
+	for _, window := range windows {
+		for i < len(busy) && !busy[i].End.After(window.Start) {
+			i++
+		}
+
+		start := window.Start
+		// busy intervals are not consumed, the last one can reach the next window
+		for j := i; j < len(busy) && busy[j].Start.Before(window.End); j++ {
+			if busy[j].Start.After(start) {
+				result = append(result, models.Interval{Start: start, End: busy[j].Start})
+			}
+			if busy[j].End.After(start) {
+				start = busy[j].End
+			}
+		}
+		if start.Before(window.End) {
+			result = append(result, models.Interval{Start: start, End: window.End})
+		}
+	}
+
+	return result
+}
+
+func clip(interval models.Interval, from, to time.Time) models.Interval {
+	if interval.Start.Before(from) {
+		interval.Start = from
+	}
+	if interval.End.After(to) {
+		interval.End = to
+	}
+	return interval
+}
+
+// wallClock returns the moment of the day at the offset from its midnight on the wall clock,
+// so working hours do not move on days when the offset of the location changes.
+func wallClock(midnight time.Time, offset time.Duration) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), 0, 0, 0, int(offset), midnight.Location())
+}
+
+func isWorkday(day time.Weekday, weekdays []time.Weekday) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, d := range weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
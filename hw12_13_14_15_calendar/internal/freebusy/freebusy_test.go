@@ -0,0 +1,169 @@
+package freebusy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestBusy(t *testing.T) {
+	daily := models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+
+	events := []models.Event{
+		// starts before the range
+		{Date: at(18, 23, 0), Duration: 2 * time.Hour},
+		{Date: at(19, 9, 0), Duration: time.Hour},
+		// overlaps the previous one
+		{Date: at(19, 9, 30), Duration: time.Hour},
+		// touches the previous one
+		{Date: at(19, 10, 30), Duration: 30 * time.Minute},
+		{Date: at(17, 14, 0), Duration: time.Hour, Recurrence: &daily},
+		// starts at the end of the range
+		{Date: at(21, 0, 0), Duration: time.Hour},
+	}
+
+	busy := Busy(events, at(19, 0, 0), at(21, 0, 0))
+	require.Equal(t, []models.Interval{
+		{Start: at(19, 0, 0), End: at(19, 1, 0)},
+		{Start: at(19, 9, 0), End: at(19, 11, 0)},
+		{Start: at(19, 14, 0), End: at(19, 15, 0)},
+		{Start: at(20, 14, 0), End: at(20, 15, 0)},
+	}, busy)
+
+	require.Nil(t, Busy(nil, at(19, 0, 0), at(21, 0, 0)))
+}
+
+func TestFindSlots(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	calendars := []models.FreeBusy{
+		{UserID: 1, Busy: []models.Interval{
+			{Start: at(19, 9, 0), End: at(19, 10, 0)},
+			{Start: at(19, 16, 0), End: at(20, 10, 0)},
+		}},
+		{UserID: 2, Busy: []models.Interval{
+			{Start: at(19, 9, 30), End: at(19, 12, 0)},
+			{Start: at(19, 12, 30), End: at(19, 13, 0)},
+		}},
+		{UserID: 3},
+	}
+
+	testCases := []struct {
+		name     string
+		query    models.SlotQuery
+		expected []models.Interval
+	}{
+		{
+			name: "whole range",
+			query: models.SlotQuery{
+				From:     at(19, 8, 0),
+				To:       at(20, 12, 0),
+				Duration: time.Hour,
+			},
+			expected: []models.Interval{
+				{Start: at(19, 8, 0), End: at(19, 9, 0)},
+				{Start: at(19, 13, 0), End: at(19, 16, 0)},
+				{Start: at(20, 10, 0), End: at(20, 12, 0)},
+			},
+		},
+		{
+			name: "short windows",
+			query: models.SlotQuery{
+				From:     at(19, 8, 0),
+				To:       at(20, 12, 0),
+				Duration: 30 * time.Minute,
+				Limit:    2,
+			},
+			expected: []models.Interval{
+				{Start: at(19, 8, 0), End: at(19, 9, 0)},
+				{Start: at(19, 12, 0), End: at(19, 12, 30)},
+			},
+		},
+		{
+			name: "working hours",
+			query: models.SlotQuery{
+				From:         at(19, 0, 0),
+				To:           at(22, 0, 0),
+				Duration:     time.Hour,
+				WorkdayStart: 9 * time.Hour,
+				WorkdayEnd:   17 * time.Hour,
+			},
+			expected: []models.Interval{
+				{Start: at(19, 13, 0), End: at(19, 16, 0)},
+				{Start: at(20, 10, 0), End: at(20, 17, 0)},
+				{Start: at(21, 9, 0), End: at(21, 17, 0)},
+			},
+		},
+		{
+			name: "working days",
+			query: models.SlotQuery{
+				From:     at(19, 12, 0),
+				To:       at(25, 0, 0),
+				Duration: time.Hour,
+				Weekdays: []time.Weekday{time.Tuesday, time.Thursday},
+			},
+			expected: []models.Interval{
+				{Start: at(20, 10, 0), End: at(21, 0, 0)},
+				{Start: at(22, 0, 0), End: at(23, 0, 0)},
+			},
+		},
+		{
+			name: "working hours in time zone",
+			query: models.SlotQuery{
+				From:         time.Date(2026, 10, 20, 0, 0, 0, 0, newYork),
+				To:           time.Date(2026, 10, 21, 0, 0, 0, 0, newYork),
+				Duration:     time.Hour,
+				WorkdayStart: 9 * time.Hour,
+				WorkdayEnd:   17 * time.Hour,
+				Location:     newYork,
+			},
+			expected: []models.Interval{
+				{Start: time.Date(2026, 10, 20, 9, 0, 0, 0, newYork), End: time.Date(2026, 10, 20, 17, 0, 0, 0, newYork)},
+			},
+		},
+		{
+			name: "no common windows",
+			query: models.SlotQuery{
+				From:     at(19, 16, 0),
+				To:       at(20, 10, 0),
+				Duration: time.Minute,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, FindSlots(calendars, tc.query))
+		})
+	}
+}
+
+func TestWorkingWindowsOffsetChange(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// the offset of New York changes on November 1
+	windows := workingWindows(models.SlotQuery{
+		From:         time.Date(2026, 10, 31, 0, 0, 0, 0, newYork),
+		To:           time.Date(2026, 11, 3, 0, 0, 0, 0, newYork),
+		WorkdayStart: 9 * time.Hour,
+		WorkdayEnd:   17 * time.Hour,
+		Location:     newYork,
+	})
+
+	require.Len(t, windows, 3)
+	for _, window := range windows {
+		require.Equal(t, 9, window.Start.In(newYork).Hour())
+		require.Equal(t, 17, window.End.In(newYork).Hour())
+	}
+	// the day between them is 25 hours long
+	require.Equal(t, 25*time.Hour, windows[1].Start.Sub(windows[0].Start))
+}
@@ -0,0 +1,64 @@
+package freebusy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+)
+
+// OverlapHorizon limits the period in which occurrences of recurring events are checked for overlaps.
+const OverlapHorizon = 365 * 24 * time.Hour
+
+var ErrDateBusy = errors.New("user already has an event at this time")
+
+// Occupied returns the intervals taken by the event, recurring events take their occurrences
+// within the OverlapHorizon from their start.
+func Occupied(event models.Event) []models.Interval {
+	if event.Recurrence == nil {
+		return []models.Interval{{Start: event.Date, End: event.Date.Add(event.Duration)}}
+	}
+
+	dates := recurrence.Occurrences(event.Date, *event.Recurrence, event.Date, event.Date.Add(OverlapHorizon))
+	intervals := make([]models.Interval, 0, len(dates))
+	for _, date := range dates {
+		intervals = append(intervals, models.Interval{Start: date, End: date.Add(event.Duration)})
+	}
+
+	return intervals
+}
+
+// CheckOverlap returns the error if any of the busy events or occurrences overlaps the intervals.
+// The events with ignoreIDs and the occurrences detached from them are skipped.
+func CheckOverlap(intervals []models.Interval, busy []models.Event, ignoreIDs ...string) error {
+	for _, event := range busy {
+		if isIgnored(event.ID, ignoreIDs) || isIgnored(event.RecurrenceID, ignoreIDs) {
+			continue
+		}
+		start, end := event.Date, event.Date.Add(event.Duration)
+		for _, interval := range intervals {
+			if interval.Start.Before(end) && start.Before(interval.End) {
+				return customerror.CustomError{
+					Field: "date",
+					Message: fmt.Sprintf("%s: event %s takes place at %s", ErrDateBusy.Error(), event.ID,
+						event.Date.Format(time.RFC3339)),
+					Err: customerror.ErrDateBusy,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func isIgnored(id string, ignoreIDs []string) bool {
+	for _, ignoreID := range ignoreIDs {
+		if id != "" && id == ignoreID {
+			return true
+		}
+	}
+	return false
+}
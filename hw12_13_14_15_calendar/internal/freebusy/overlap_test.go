@@ -0,0 +1,34 @@
+package freebusy
+
+import (
+	"testing"
+	"time"
+
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOverlap(t *testing.T) {
+	daily := models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+	series := models.Event{ID: "series", Date: at(19, 9, 0), Duration: time.Hour, Recurrence: &daily}
+
+	intervals := Occupied(series)
+	require.Len(t, intervals, 366)
+	require.Equal(t, models.Interval{Start: at(20, 9, 0), End: at(20, 10, 0)}, intervals[1])
+
+	// touches the occurrence
+	require.NoError(t, CheckOverlap(intervals, []models.Event{{ID: "before", Date: at(20, 8, 0), Duration: time.Hour}}))
+
+	busy := []models.Event{{ID: "meeting", Date: at(21, 9, 30), Duration: time.Hour}}
+	err := CheckOverlap(intervals, busy)
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+
+	require.NoError(t, CheckOverlap(intervals, busy, "series", "meeting"))
+
+	// occurrences detached from the ignored series are skipped too
+	detached := []models.Event{{ID: "moved", RecurrenceID: "series", Date: at(21, 9, 30), Duration: time.Hour}}
+	require.NoError(t, CheckOverlap(intervals, detached, "series"))
+	require.ErrorIs(t, CheckOverlap(intervals, detached, "other"), customerror.ErrDateBusy)
+	require.NoError(t, CheckOverlap(Occupied(models.Event{Date: at(19, 10, 30), Duration: time.Hour}), busy))
+}
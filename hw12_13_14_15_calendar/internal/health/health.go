@@ -0,0 +1,172 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrShuttingDown = errors.New("shutting down")
+
+// Check returns nil if the dependency is available.
+type Check func(ctx context.Context) error
+
+// Checker reports whether the binary is alive and ready to serve. It is ready while all
+// its checks pass, e.g. the database answers pings and the broker is connected.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]Check
+
+	shuttingDown atomic.Bool
+}
+
+// NewChecker returns the checker whose checks are cancelled after timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Add adds the check of the dependency with the name.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Shutdown makes the checker not ready, so load balancers stop sending requests before the servers are stopped.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check runs all checks concurrently and returns the errors of the failed ones by their names.
+func (c *Checker) Check(ctx context.Context) map[string]error {
+	c.mu.RLock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed = make(map[string]error)
+	)
+	for name, check := range checks {
+		name, check := name, check
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := check(ctx); err != nil {
+				mu.Lock()
+				failed[name] = err
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.shuttingDown.Load() {
+		failed["shutdown"] = ErrShuttingDown
+	}
+
+	return failed
+}
+
+// Ready returns the errors of the failed checks joined.
+func (c *Checker) Ready(ctx context.Context) error {
+	failed := c.Check(ctx)
+
+	names := make([]string, 0, len(failed))
+	for name := range failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, 0, len(names))
+	for _, name := range names {
+		errs = append(errs, fmt.Errorf("%s: %w", name, failed[name]))
+	}
+	return errors.Join(errs...)
+}
+
+// Watch passes the readiness to fn every interval until ctx is done.
+func (c *Checker) Watch(ctx context.Context, interval time.Duration, fn func(ready bool)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(c.Ready(ctx) == nil)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// LivenessHandler answers 200 while the process is running.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeResponse(w, http.StatusOK, response{Status: statusOK})
+	})
+}
+
+// ReadinessHandler answers 200 if all checks pass and 503 with the failed ones otherwise.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failed := c.Check(r.Context())
+		if len(failed) == 0 {
+			writeResponse(w, http.StatusOK, response{Status: statusOK})
+			return
+		}
+
+		checks := make(map[string]string, len(failed))
+		for name, err := range failed {
+			checks[name] = err.Error()
+		}
+		writeResponse(w, http.StatusServiceUnavailable, response{Status: statusUnavailable, Checks: checks})
+	})
+}
+
+// Handler serves /healthz, /readyz and metrics on /metrics.
+func Handler(c *Checker, metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", c.LivenessHandler())
+	mux.Handle("/readyz", c.ReadinessHandler())
+	mux.Handle("/metrics", metrics)
+	return mux
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errDisconnected = errors.New("disconnected")
+
+func TestCheckerReady(t *testing.T) {
+	c := NewChecker(time.Second)
+	require.NoError(t, c.Ready(context.Background()))
+
+	c.Add("postgres", func(context.Context) error { return nil })
+	require.NoError(t, c.Ready(context.Background()))
+
+	c.Add("rabbit", func(context.Context) error { return errDisconnected })
+	err := c.Ready(context.Background())
+	require.ErrorIs(t, err, errDisconnected)
+	require.EqualError(t, err, "rabbit: disconnected")
+
+	c.Shutdown()
+	require.ErrorIs(t, c.Ready(context.Background()), ErrShuttingDown)
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	c := NewChecker(10 * time.Millisecond)
+	c.Add("postgres", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	require.ErrorIs(t, c.Ready(context.Background()), context.DeadlineExceeded)
+}
+
+func TestHandler(t *testing.T) {
+	c := NewChecker(time.Second)
+	ready := false
+	c.Add("rabbit", func(context.Context) error {
+		if !ready {
+			return errDisconnected
+		}
+		return nil
+	})
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test_total 1\n"))
+	})
+	handler := Handler(c, metrics)
+
+	testCases := []struct {
+		name     string
+		path     string
+		ready    bool
+		status   int
+		expected response
+	}{
+		{
+			name:     "alive while not ready",
+			path:     "/healthz",
+			status:   http.StatusOK,
+			expected: response{Status: statusOK},
+		},
+		{
+			name:   "not ready",
+			path:   "/readyz",
+			status: http.StatusServiceUnavailable,
+			expected: response{
+				Status: statusUnavailable,
+				Checks: map[string]string{"rabbit": errDisconnected.Error()},
+			},
+		},
+		{
+			name:     "ready",
+			path:     "/readyz",
+			ready:    true,
+			status:   http.StatusOK,
+			expected: response{Status: statusOK},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ready = tc.ready
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.status, w.Code)
+
+			var resp response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.expected, resp)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, "test_total 1\n", w.Body.String())
+}
+
+func TestCheckerWatch(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("rabbit", func(context.Context) error { return errDisconnected })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	states := make(chan bool, 1)
+	go c.Watch(ctx, time.Hour, func(ready bool) { states <- ready })
+
+	require.False(t, <-states)
+}
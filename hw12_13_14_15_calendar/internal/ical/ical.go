@@ -0,0 +1,666 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+)
+
+const (
+	productID = "-//romandnk//calendar//EN"
+
+	dateTimeUTCLayout = "20060102T150405Z"
+	dateTimeLayout    = "20060102T150405"
+	dateLayout        = "20060102"
+
+	maxLineLength = 75
+	maxScanToken  = 1 << 20
+)
+
+var (
+	ErrNotCalendar        = errors.New("data is not an iCalendar VCALENDAR object")
+	ErrUnterminated       = errors.New("component is not terminated")
+	ErrInvalidLine        = errors.New("invalid content line")
+	ErrMissingStart       = errors.New("DTSTART is required")
+	ErrInvalidDateTime    = errors.New("invalid DATE-TIME value")
+	ErrInvalidTimezone    = errors.New("unknown TZID")
+	ErrInvalidDuration    = errors.New("invalid DURATION value")
+	ErrInvalidRecurrence  = errors.New("invalid RRULE value")
+	ErrEndBeforeStart     = errors.New("DTEND must not be before DTSTART")
+	ErrDurationWithDTEnd  = errors.New("DTEND and DURATION cannot be used together")
+	ErrUnsupportedTrigger = errors.New("only TRIGGER related to start is supported")
+)
+
+// Result is a parsed VEVENT. Err is set if the VEVENT cannot be converted to the event.
+type Result struct {
+	UID   string
+	Event models.Event
+	Err   error
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+type component struct {
+	props  []property
+	alarms [][]property
+}
+
+// Decode parses the VCALENDAR and converts every VEVENT to the event.
+// Overridden occurrences (RECURRENCE-ID) are imported as separate events and excluded from their series.
+func Decode(r io.Reader) ([]Result, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, ErrNotCalendar
+	}
+
+	var (
+		components []component
+		current    *component
+		alarm      []property
+		stack      []string
+	)
+
+	for i, line := range lines {
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			name := strings.ToUpper(prop.value)
+			stack = append(stack, name)
+			switch {
+			case name == "VEVENT":
+				current = &component{}
+			case name == "VALARM" && current != nil:
+				alarm = []property{}
+			}
+			continue
+		case "END":
+			name := strings.ToUpper(prop.value)
+			if len(stack) == 0 || stack[len(stack)-1] != name {
+				return nil, fmt.Errorf("line %d: %w: %s", i+1, ErrUnterminated, name)
+			}
+			stack = stack[:len(stack)-1]
+			switch {
+			case name == "VEVENT" && current != nil:
+				components = append(components, *current)
+				current = nil
+			case name == "VALARM" && current != nil:
+				current.alarms = append(current.alarms, alarm)
+				alarm = nil
+			}
+			continue
+		}
+
+		switch {
+		case alarm != nil:
+			alarm = append(alarm, prop)
+		case current != nil && len(stack) > 0 && stack[len(stack)-1] == "VEVENT":
+			current.props = append(current.props, prop)
+		}
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnterminated, stack[len(stack)-1])
+	}
+
+	results := make([]Result, 0, len(components))
+	for _, c := range components {
+		results = append(results, toResult(c))
+	}
+	excludeOverrides(results)
+
+	return results, nil
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxScanToken)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+func parseLine(line string) (property, error) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		}
+		if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return property{}, ErrInvalidLine
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string, len(parts)-1),
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return property{}, ErrInvalidLine
+		}
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return prop, nil
+}
+
+func toResult(c component) Result {
+	var (
+		res      Result
+		start    time.Time
+		startLoc *time.Location
+		end      time.Time
+		duration time.Duration
+		hasStart bool
+		hasEnd   bool
+		hasDur   bool
+		allDay   bool
+	)
+
+	for _, prop := range c.props {
+		var err error
+
+		switch prop.name {
+		case "UID":
+			res.UID = prop.value
+		case "SUMMARY":
+			res.Event.Title = unescape(prop.value)
+		case "DESCRIPTION":
+			res.Event.Description = unescape(prop.value)
+		case "DTSTART":
+			start, err = parseDateTime(prop.value, prop.params)
+			if err != nil {
+				break
+			}
+			startLoc, err = parseLocation(prop.params)
+			hasStart = true
+			allDay = prop.params["VALUE"] == "DATE"
+		case "DTEND":
+			end, err = parseDateTime(prop.value, prop.params)
+			hasEnd = true
+		case "DURATION":
+			duration, err = parseDuration(prop.value)
+			hasDur = true
+		case "RRULE":
+			var r models.Recurrence
+			r, err = recurrence.Parse(prop.value)
+			if err != nil {
+				err = fmt.Errorf("%w: %w", ErrInvalidRecurrence, err)
+				break
+			}
+			if res.Event.Recurrence != nil {
+				r.Exceptions = res.Event.Recurrence.Exceptions
+			}
+			res.Event.Recurrence = &r
+		case "EXDATE":
+			var exceptions []time.Time
+			exceptions, err = parseDateTimeList(prop.value, prop.params)
+			if err != nil {
+				break
+			}
+			if res.Event.Recurrence == nil {
+				res.Event.Recurrence = &models.Recurrence{}
+			}
+			res.Event.Recurrence.Exceptions = append(res.Event.Recurrence.Exceptions, exceptions...)
+		case "RECURRENCE-ID":
+			res.Event.OriginalDate, err = parseDateTime(prop.value, prop.params)
+		}
+
+		if err != nil {
+			res.Err = fmt.Errorf("%s: %w", prop.name, err)
+			return res
+		}
+	}
+
+	if !hasStart {
+		res.Err = ErrMissingStart
+		return res
+	}
+	res.Event.Date = start
+
+	switch {
+	case hasEnd && hasDur:
+		res.Err = ErrDurationWithDTEnd
+		return res
+	case hasEnd:
+		if end.Before(start) {
+			res.Err = ErrEndBeforeStart
+			return res
+		}
+		res.Event.Duration = end.Sub(start)
+	case hasDur:
+		res.Event.Duration = duration
+	case allDay:
+		res.Event.Duration = 24 * time.Hour
+	}
+
+	// EXDATE without RRULE has no meaning.
+	if res.Event.Recurrence != nil && res.Event.Recurrence.Frequency == "" {
+		res.Event.Recurrence = nil
+	}
+	// the series keeps the wall clock time of DTSTART in its time zone
+	if res.Event.Recurrence != nil && startLoc != time.UTC {
+		res.Event.Recurrence.Location = startLoc
+	}
+
+	// every alarm is the reminder, the first one is also the notification interval
+	for i, alarm := range c.alarms {
+		interval, err := alarmInterval(alarm, start)
+		if err != nil {
+			res.Err = fmt.Errorf("TRIGGER: %w", err)
+			return res
+		}
+		if i == 0 {
+			res.Event.NotificationInterval = interval
+		}
+		if !containsReminder(res.Event.Reminders, interval) {
+			res.Event.Reminders = append(res.Event.Reminders, models.Reminder{Before: interval})
+		}
+	}
+
+	return res
+}
+
+// excludeOverrides adds dates of overridden occurrences to exceptions of their series.
+func excludeOverrides(results []Result) {
+	series := make(map[string]int, len(results))
+	for i, res := range results {
+		if res.Err == nil && res.Event.Recurrence != nil && res.UID != "" {
+			series[res.UID] = i
+		}
+	}
+
+	for i, res := range results {
+		if res.Err != nil || res.Event.OriginalDate.IsZero() {
+			continue
+		}
+		originalDate := res.Event.OriginalDate
+		results[i].Event.OriginalDate = time.Time{}
+
+		j, ok := series[res.UID]
+		if !ok {
+			continue
+		}
+		r := *results[j].Event.Recurrence
+		if containsTime(r.Exceptions, originalDate) {
+			continue
+		}
+		r.Exceptions = append(append([]time.Time(nil), r.Exceptions...), originalDate)
+		results[j].Event.Recurrence = &r
+	}
+}
+
+func containsReminder(reminders []models.Reminder, before time.Duration) bool {
+	for _, reminder := range reminders {
+		if reminder.Before == before {
+			return true
+		}
+	}
+	return false
+}
+
+func alarmInterval(props []property, start time.Time) (time.Duration, error) {
+	for _, prop := range props {
+		if prop.name != "TRIGGER" {
+			continue
+		}
+		if prop.params["VALUE"] == "DATE-TIME" {
+			t, err := parseDateTime(prop.value, prop.params)
+			if err != nil {
+				return 0, err
+			}
+			return start.Sub(t), nil
+		}
+		if prop.params["RELATED"] == "END" {
+			return 0, ErrUnsupportedTrigger
+		}
+		d, err := parseDuration(prop.value)
+		if err != nil {
+			return 0, err
+		}
+		return -d, nil
+	}
+
+	return 0, nil
+}
+
+func parseDateTimeList(value string, params map[string]string) ([]time.Time, error) {
+	var dates []time.Time
+	for _, v := range strings.Split(value, ",") {
+		date, err := parseDateTime(v, params)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+	return dates, nil
+}
+
+// parseLocation returns the time zone of the TZID parameter, UTC if there is none.
+func parseLocation(params map[string]string) (*time.Location, error) {
+	tzid, ok := params["TZID"]
+	if !ok {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tzid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimezone, tzid)
+	}
+	return loc, nil
+}
+
+// parseDateTime parses DATE and DATE-TIME values. Floating times are treated as UTC.
+func parseDateTime(value string, params map[string]string) (time.Time, error) {
+	loc, err := parseLocation(params)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var t time.Time
+	switch {
+	case params["VALUE"] == "DATE" || len(value) == len(dateLayout):
+		t, err = time.ParseInLocation(dateLayout, value, loc)
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse(dateTimeUTCLayout, value)
+	default:
+		t, err = time.ParseInLocation(dateTimeLayout, value, loc)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDateTime, value)
+	}
+
+	return t.UTC(), nil
+}
+
+// parseDuration parses RFC 5545 DURATION values like "PT1H30M", "-P1D" or "P2W".
+func parseDuration(value string) (time.Duration, error) {
+	s := value
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = -1
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidDuration, value)
+	}
+	s = s[1:]
+
+	var (
+		d      time.Duration
+		inTime bool
+		number string
+	)
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			number += string(r)
+			continue
+		case r == 'T' && !inTime && number == "":
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidDuration, value)
+		}
+		number = ""
+
+		var unit time.Duration
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("%w: %s", ErrInvalidDuration, value)
+		}
+		d += time.Duration(n) * unit
+	}
+	if number != "" {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidDuration, value)
+	}
+
+	return sign * d, nil
+}
+
+// Encode writes events as the VCALENDAR. Recurring events are written once with RRULE and EXDATE,
+// events detached from a series share its UID and have RECURRENCE-ID.
+func Encode(w io.Writer, events []models.Event) error {
+	lw := &lineWriter{w: w}
+	stamp := time.Now().UTC().Format(dateTimeUTCLayout)
+
+	lw.writeLine("BEGIN:VCALENDAR")
+	lw.writeLine("VERSION:2.0")
+	lw.writeLine("PRODID:" + productID)
+	lw.writeLine("CALSCALE:GREGORIAN")
+
+	for _, event := range events {
+		lw.writeLine("BEGIN:VEVENT")
+		if event.RecurrenceID != "" {
+			lw.writeLine("UID:" + event.RecurrenceID)
+			lw.writeLine("RECURRENCE-ID:" + formatDateTime(event.OriginalDate))
+		} else {
+			lw.writeLine("UID:" + event.ID)
+		}
+		lw.writeLine("DTSTAMP:" + stamp)
+		loc := seriesLocation(event)
+		lw.writeLine("DTSTART" + tzidParam(loc) + ":" + formatDateTimeIn(event.Date, loc))
+		lw.writeLine("DURATION:" + formatDuration(event.Duration))
+		lw.writeLine("SUMMARY:" + escape(event.Title))
+		if event.Description != "" {
+			lw.writeLine("DESCRIPTION:" + escape(event.Description))
+		}
+		if event.Recurrence != nil {
+			lw.writeLine("RRULE:" + recurrence.Format(*event.Recurrence))
+			if len(event.Recurrence.Exceptions) > 0 {
+				exceptions := make([]string, 0, len(event.Recurrence.Exceptions))
+				for _, exception := range event.Recurrence.Exceptions {
+					exceptions = append(exceptions, formatDateTimeIn(exception, loc))
+				}
+				lw.writeLine("EXDATE" + tzidParam(loc) + ":" + strings.Join(exceptions, ","))
+			}
+		}
+		for _, before := range alarmIntervals(event) {
+			lw.writeLine("BEGIN:VALARM")
+			lw.writeLine("ACTION:DISPLAY")
+			lw.writeLine("DESCRIPTION:" + escape(event.Title))
+			lw.writeLine("TRIGGER:-" + formatDuration(before))
+			lw.writeLine("END:VALARM")
+		}
+		lw.writeLine("END:VEVENT")
+	}
+
+	lw.writeLine("END:VCALENDAR")
+
+	return lw.err
+}
+
+// lineWriter writes CRLF terminated lines folded to 75 octets.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) writeLine(line string) {
+	if lw.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	limit := maxLineLength
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > limit {
+			b.WriteString("\r\n ")
+			limit = maxLineLength - 1
+			length = 0
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	b.WriteString("\r\n")
+
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(dateTimeUTCLayout)
+}
+
+// formatDateTimeIn formats the time as the local time of the location or as UTC.
+func formatDateTimeIn(t time.Time, loc *time.Location) string {
+	if loc == time.UTC {
+		return formatDateTime(t)
+	}
+	return t.In(loc).Format(dateTimeLayout)
+}
+
+// tzidParam returns the TZID parameter of the times in the location.
+// The TZID is the IANA name of the zone, VTIMEZONE components are not written.
+func tzidParam(loc *time.Location) string {
+	if loc == time.UTC {
+		return ""
+	}
+	return ";TZID=" + loc.String()
+}
+
+// seriesLocation returns the time zone of the recurring event, UTC for other events.
+func seriesLocation(event models.Event) *time.Location {
+	if event.Recurrence == nil {
+		return time.UTC
+	}
+	return recurrence.Location(*event.Recurrence)
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	b.WriteString("P")
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		b.WriteString(strconv.FormatInt(int64(days), 10) + "D")
+	}
+	if d == 0 {
+		return b.String()
+	}
+
+	b.WriteString("T")
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+	if hours > 0 {
+		b.WriteString(strconv.FormatInt(int64(hours), 10) + "H")
+	}
+	if minutes > 0 {
+		b.WriteString(strconv.FormatInt(int64(minutes), 10) + "M")
+	}
+	if seconds > 0 {
+		b.WriteString(strconv.FormatInt(int64(seconds), 10) + "S")
+	}
+
+	return b.String()
+}
+
+var (
+	escaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, tt := range times {
+		if tt.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// alarmIntervals returns distinct times of the reminders of the event or its notification interval
+// if it has no reminders.
+func alarmIntervals(event models.Event) []time.Duration {
+	if len(event.Reminders) == 0 {
+		if event.NotificationInterval > 0 {
+			return []time.Duration{event.NotificationInterval}
+		}
+		return nil
+	}
+
+	var intervals []time.Duration
+	for i, reminder := range event.Reminders {
+		if containsReminder(event.Reminders[:i], reminder.Before) {
+			continue
+		}
+		intervals = append(intervals, reminder.Before)
+	}
+	return intervals
+}
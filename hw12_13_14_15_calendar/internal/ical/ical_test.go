@@ -0,0 +1,241 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Google Inc//Google Calendar 70.9054//EN",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Moscow",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:series@google.com",
+		"DTSTART;TZID=Europe/Moscow:20230724T100000",
+		"DTEND;TZID=Europe/Moscow:20230724T101500",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE",
+		"SUMMARY:Daily standup\\, team A",
+		"DESCRIPTION:first line\\nsecond",
+		"  line",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-PT10M",
+		"END:VALARM",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER;VALUE=DATE-TIME:20230724T065900Z",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:series@google.com",
+		"RECURRENCE-ID;TZID=Europe/Moscow:20230726T100000",
+		"DTSTART:20230726T080000Z",
+		"DURATION:PT30M",
+		"SUMMARY:Moved standup",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:holiday",
+		"DTSTART;VALUE=DATE:20230801",
+		"SUMMARY:Holiday",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:broken",
+		"SUMMARY:No start",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	results, err := Decode(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[0].Err)
+	require.Equal(t, "series@google.com", results[0].UID)
+	require.Equal(t, models.Event{
+		Title:                "Daily standup, team A",
+		Date:                 time.Date(2023, 7, 24, 7, 0, 0, 0, time.UTC),
+		Duration:             15 * time.Minute,
+		Description:          "first line\nsecond line",
+		NotificationInterval: 10 * time.Minute,
+		Reminders:            []models.Reminder{{Before: 10 * time.Minute}, {Before: time.Minute}},
+		Recurrence: &models.Recurrence{
+			Frequency:  models.FrequencyWeekly,
+			Interval:   1,
+			ByDay:      []time.Weekday{time.Monday, time.Wednesday},
+			Exceptions: []time.Time{time.Date(2023, 7, 26, 7, 0, 0, 0, time.UTC)},
+			Location:   moscow,
+		},
+	}, results[0].Event)
+
+	require.NoError(t, results[1].Err)
+	require.Equal(t, models.Event{
+		Title:    "Moved standup",
+		Date:     time.Date(2023, 7, 26, 8, 0, 0, 0, time.UTC),
+		Duration: 30 * time.Minute,
+	}, results[1].Event)
+
+	require.NoError(t, results[2].Err)
+	require.Equal(t, time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC), results[2].Event.Date)
+	require.Equal(t, 24*time.Hour, results[2].Event.Duration)
+
+	require.ErrorIs(t, results[3].Err, ErrMissingStart)
+	require.Equal(t, "broken", results[3].UID)
+}
+
+func TestDecodeError(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{
+			name: "not calendar",
+			data: "BEGIN:VCARD\r\nEND:VCARD",
+			err:  ErrNotCalendar,
+		},
+		{
+			name: "unterminated",
+			data: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR",
+			err:  ErrUnterminated,
+		},
+		{
+			name: "invalid line",
+			data: "BEGIN:VCALENDAR\r\nno colon\r\nEND:VCALENDAR",
+			err:  ErrInvalidLine,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tc.data))
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		err      bool
+	}{
+		{value: "PT1H30M", expected: time.Hour + 30*time.Minute},
+		{value: "-PT15M", expected: -15 * time.Minute},
+		{value: "P1DT2S", expected: 24*time.Hour + 2*time.Second},
+		{value: "P2W", expected: 14 * 24 * time.Hour},
+		{value: "PT", err: true},
+		{value: "P1H", err: true},
+		{value: "1H", err: true},
+		{value: "PT5", err: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			d, err := parseDuration(tc.value)
+			if tc.err {
+				require.ErrorIs(t, err, ErrInvalidDuration)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, d)
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	events := []models.Event{
+		{
+			ID:                   "series",
+			Title:                "Standup; daily, short",
+			Date:                 time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC),
+			Duration:             15 * time.Minute,
+			Description:          strings.Repeat("long description ", 10),
+			UserID:               1,
+			NotificationInterval: 5 * time.Minute,
+			Reminders:            []models.Reminder{{Before: 5 * time.Minute}, {Before: 0}},
+			Recurrence: &models.Recurrence{
+				Frequency:  models.FrequencyDaily,
+				Interval:   1,
+				Count:      10,
+				Exceptions: []time.Time{time.Date(2023, 7, 25, 10, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			ID:           "detached",
+			Title:        "Moved standup",
+			Date:         time.Date(2023, 7, 25, 12, 0, 0, 0, time.UTC),
+			Duration:     time.Hour,
+			UserID:       1,
+			RecurrenceID: "series",
+			OriginalDate: time.Date(2023, 7, 25, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, events))
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		require.LessOrEqual(t, len(line), maxLineLength)
+	}
+	require.Contains(t, buf.String(), "RECURRENCE-ID:20230725T100000Z\r\n")
+	require.Contains(t, buf.String(), "RRULE:FREQ=DAILY;COUNT=10\r\n")
+
+	results, err := Decode(&buf)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	for i, res := range results {
+		require.NoError(t, res.Err)
+
+		expected := events[i]
+		expected.ID = ""
+		expected.UserID = 0
+		expected.RecurrenceID = ""
+		expected.OriginalDate = time.Time{}
+		require.Equal(t, expected, res.Event)
+	}
+	require.Equal(t, "series", results[1].UID)
+}
+
+func TestEncodeDecodeTimeZone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	series := models.Event{
+		Title:    "Standup",
+		Date:     time.Date(2023, 3, 24, 8, 0, 0, 0, time.UTC),
+		Duration: 15 * time.Minute,
+		Recurrence: &models.Recurrence{
+			Frequency:  models.FrequencyDaily,
+			Interval:   1,
+			Exceptions: []time.Time{time.Date(2023, 3, 27, 7, 0, 0, 0, time.UTC)},
+			Location:   berlin,
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, []models.Event{series}))
+
+	require.Contains(t, buf.String(), "DTSTART;TZID=Europe/Berlin:20230324T090000\r\n")
+	require.Contains(t, buf.String(), "EXDATE;TZID=Europe/Berlin:20230327T090000\r\n")
+
+	results, err := Decode(&buf)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, series, results[0].Event)
+}
@@ -0,0 +1,59 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrMissingUserID = errors.New("user id is missing")
+	ErrInvalidUserID = errors.New("user id must be positive number")
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	UserID int
+	// Subject is the subject of the token or the name of the API key.
+	Subject string
+	// Method is the way the caller is authenticated.
+	Method string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the authenticated caller stored in ctx.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// WithUserID returns a copy of ctx carrying the caller with ID of the user.
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return WithPrincipal(ctx, Principal{UserID: userID})
+}
+
+// UserID returns ID of the calling user stored in ctx.
+func UserID(ctx context.Context) (int, bool) {
+	principal, ok := PrincipalFrom(ctx)
+	return principal.UserID, ok
+}
+
+// ParseUserID parses ID of the calling user from the subject of its credentials.
+func ParseUserID(value string) (int, error) {
+	if value == "" {
+		return 0, ErrMissingUserID
+	}
+
+	userID, err := strconv.Atoi(value)
+	if err != nil || userID <= 0 {
+		return 0, ErrInvalidUserID
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,60 @@
+package identity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserID(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := UserID(ctx)
+	require.False(t, ok)
+
+	userID, ok := UserID(WithUserID(ctx, 42))
+	require.True(t, ok)
+	require.Equal(t, 42, userID)
+}
+
+func TestParseUserID(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected int
+		err      error
+	}{
+		{value: "1", expected: 1},
+		{value: "", err: ErrMissingUserID},
+		{value: "abc", err: ErrInvalidUserID},
+		{value: "-1", err: ErrInvalidUserID},
+		{value: "0", err: ErrInvalidUserID},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			userID, err := ParseUserID(tc.value)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, userID)
+		})
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := PrincipalFrom(ctx)
+	require.False(t, ok)
+
+	expected := Principal{UserID: 7, Subject: "ci", Method: "api_key"}
+	ctx = WithPrincipal(ctx, expected)
+
+	principal, ok := PrincipalFrom(ctx)
+	require.True(t, ok)
+	require.Equal(t, expected, principal)
+
+	userID, ok := UserID(ctx)
+	require.True(t, ok)
+	require.Equal(t, 7, userID)
+}
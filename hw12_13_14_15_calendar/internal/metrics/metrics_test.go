@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounter("test_requests_total", "Requests by method and route.", "method", "route")
+	requests.Inc("GET", "/api/v1/events")
+	requests.Inc("GET", "/api/v1/events")
+	requests.Add(3, "POST", `/quoted"\`)
+
+	published := r.NewCounter("test_published_total", "Published messages.")
+	published.Inc()
+
+	latency := r.NewHistogram("test_duration_seconds", "Latency.\nIn seconds.", []float64{1, 0.1}, "method")
+	latency.Observe(0.05, "GET")
+	latency.Observe(0.5, "GET")
+	latency.Observe(2, "GET")
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf))
+
+	expected := `# HELP test_duration_seconds Latency.\nIn seconds.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{method="GET",le="0.1"} 1
+test_duration_seconds_bucket{method="GET",le="1"} 2
+test_duration_seconds_bucket{method="GET",le="+Inf"} 3
+test_duration_seconds_sum{method="GET"} 2.55
+test_duration_seconds_count{method="GET"} 3
+# HELP test_published_total Published messages.
+# TYPE test_published_total counter
+test_published_total 1
+# HELP test_requests_total Requests by method and route.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",route="/api/v1/events"} 2
+test_requests_total{method="POST",route="/quoted\"\\"} 3
+`
+	require.Equal(t, expected, buf.String())
+
+	require.Equal(t, float64(2), requests.Value("GET", "/api/v1/events"))
+	require.Zero(t, requests.Value("DELETE", "/api/v1/events"))
+	require.Equal(t, uint64(3), latency.Count("GET"))
+}
+
+func TestRegistryHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Test.").Inc()
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Header().Get("Content-Type"), "version=0.0.4")
+	require.Contains(t, w.Body.String(), "test_total 1\n")
+}
+
+func TestRegistryMisuse(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_total", "Test.", "method")
+
+	require.Panics(t, func() { r.NewCounter("test_total", "Test.") })
+	require.Panics(t, func() { c.Inc() })
+	require.Panics(t, func() { c.Add(-1, "GET") })
+}
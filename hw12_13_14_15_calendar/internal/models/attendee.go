@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// AttendeeStatus is the answer of the invited user.
+type AttendeeStatus string
+
+const (
+	// AttendeeNeedsAction attendees have not answered the invitation yet.
+	AttendeeNeedsAction AttendeeStatus = "needs-action"
+	AttendeeAccepted    AttendeeStatus = "accepted"
+	AttendeeDeclined    AttendeeStatus = "declined"
+	AttendeeTentative   AttendeeStatus = "tentative"
+)
+
+// Attendee is the user invited to the event by its owner.
+type Attendee struct {
+	UserID int
+	Status AttendeeStatus
+	// RespondedAt is zero until the attendee answers.
+	RespondedAt time.Time
+}
+
+// FindAttendee returns the attendee with the user id.
+func FindAttendee(attendees []Attendee, userID int) (Attendee, bool) {
+	for _, attendee := range attendees {
+		if attendee.UserID == userID {
+			return attendee, true
+		}
+	}
+	return Attendee{}, false
+}
+
+// AttendeeIDs returns ids of the attendees.
+func AttendeeIDs(attendees []Attendee) []int {
+	if len(attendees) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(attendees))
+	for _, attendee := range attendees {
+		ids = append(ids, attendee.UserID)
+	}
+	return ids
+}
+
+// IsInvited reports whether the user is the attendee who has not declined the invitation.
+func (e Event) IsInvited(userID int) bool {
+	attendee, ok := FindAttendee(e.Attendees, userID)
+	return ok && attendee.Status != AttendeeDeclined
+}
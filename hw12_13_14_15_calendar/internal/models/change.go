@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// ChangeType is the kind of the change of the event.
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// EventChange is the change of the user's event streamed to the watchers.
+type EventChange struct {
+	Type    ChangeType
+	EventID string
+	UserID  int
+	// Version is the version of the event after the change, the deleted version for deletions.
+	Version   int64
+	ChangedAt time.Time
+	// Event is the state of the event after the change, nil for deletions.
+	Event *Event
+	// Attendees are ids of the users invited to the deleted event, nil for other changes.
+	Attendees []int
+	// Previous is the state of the updated event before the change, nil for other changes or if it is unknown.
+	Previous *EventState
+}
+
+// EventState is the part of the event which tells whose watchers are interested in its change.
+type EventState struct {
+	Date       time.Time
+	Duration   time.Duration
+	Recurrence *Recurrence
+	// Attendees are ids of the invited users.
+	Attendees []int
+}
+
+func StateOf(event Event) EventState {
+	return EventState{
+		Date:       event.Date,
+		Duration:   event.Duration,
+		Recurrence: event.Recurrence,
+		Attendees:  AttendeeIDs(event.Attendees),
+	}
+}
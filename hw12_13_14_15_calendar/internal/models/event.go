@@ -10,5 +10,15 @@ type Event struct {
 	Description          string
 	UserID               int
 	NotificationInterval time.Duration
-	Scheduled            bool
+	// Reminders are sorted from the earliest one.
+	Reminders []Reminder
+	// Attendees are the users invited by the owner of the event.
+	Attendees    []Attendee
+	Recurrence   *Recurrence
+	RecurrenceID string
+	OriginalDate time.Time
+	// Version is incremented by every change of the event, updates and deletes must provide
+	// the version they are based on.
+	Version   int64
+	UpdatedAt time.Time
 }
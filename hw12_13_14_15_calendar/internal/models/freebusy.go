@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Interval is the period [Start, End).
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// FreeBusy is the time the user is busy in, intervals are sorted and do not overlap.
+type FreeBusy struct {
+	UserID int
+	Busy   []Interval
+}
+
+// SlotQuery asks for windows in [From, To) in which all users are free for at least Duration.
+type SlotQuery struct {
+	UserIDs  []int
+	From     time.Time
+	To       time.Time
+	Duration time.Duration
+	// WorkdayStart and WorkdayEnd are the working hours as offsets from midnight in Location,
+	// the whole day is used when both are zero.
+	WorkdayStart time.Duration
+	WorkdayEnd   time.Duration
+	// Location of the working hours, UTC if nil.
+	Location *time.Location
+	// Weekdays are the working days, every day is used if empty.
+	Weekdays []time.Weekday
+	Limit    int
+}
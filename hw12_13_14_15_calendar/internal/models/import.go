@@ -0,0 +1,8 @@
+package models
+
+// ImportResult is the result of importing a single event. ID is set if the event was created.
+type ImportResult struct {
+	UID string
+	ID  string
+	Err error
+}
@@ -2,10 +2,43 @@ package models
 
 import "time"
 
+// Notification is the pending reminder of the event.
 type Notification struct {
-	EventID  string
-	Title    string
-	Date     time.Time
-	UserID   int
-	Interval time.Duration
+	ReminderID int64
+	Channel    ReminderChannel
+	EventID    string
+	Title      string
+	Date       time.Time
+	UserID     int
+	Interval   time.Duration
+	// Attendees are ids of the users who have accepted the invitation, they are notified with the owner.
+	Attendees []int
+}
+
+// NotifyAt is the moment the reminder is due.
+func (n Notification) NotifyAt() time.Time {
+	return n.Date.Add(-n.Interval)
+}
+
+// NotificationClaim selects pending reminders due in [Now-Grace, Now+Window] and leases them
+// to the claiming scheduler, so other schedulers skip them until the lease expires.
+type NotificationClaim struct {
+	Now time.Time
+	// Window is how far ahead of Now reminders are claimed.
+	Window time.Duration
+	// Grace is how long overdue reminders are still claimed, e.g. after the scheduler outage.
+	Grace time.Duration
+	// Lease is how long the claimed reminder stays hidden from other claims unless it is scheduled.
+	Lease time.Duration
+	Limit int
+}
+
+// From returns the start of the claimed window.
+func (c NotificationClaim) From() time.Time {
+	return c.Now.Add(-c.Grace)
+}
+
+// To returns the end of the claimed window.
+func (c NotificationClaim) To() time.Time {
+	return c.Now.Add(c.Window)
 }
@@ -0,0 +1,7 @@
+package models
+
+// EventOptions are per-request options of creating and updating events.
+type EventOptions struct {
+	// AllowOverlap disables the check that the user has no other events at the same time.
+	AllowOverlap bool
+}
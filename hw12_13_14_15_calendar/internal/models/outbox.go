@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// OutboxMessage is the notification waiting to be published to the message queue.
+// It is written in the same transaction which marks its reminder as queued, so CreatedAt is QueuedAt
+// of the reminder.
+type OutboxMessage struct {
+	ID            int64
+	EventID       string
+	ReminderID    int64
+	Payload       []byte
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
+
+// OutboxClaim selects the messages due at Now for one relay.
+type OutboxClaim struct {
+	Now time.Time
+	// Lease is how long the claimed message stays hidden from other claims unless it is retried.
+	Lease time.Duration
+	Limit int
+}
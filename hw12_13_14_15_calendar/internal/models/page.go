@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// SortOrder is the order of events by date.
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// Cursor points to the last event of the previous page.
+// Occurrences of the same recurring event share the ID, so the date is a part of the cursor.
+type Cursor struct {
+	Date time.Time
+	ID   string
+}
+
+// EventRange selects events and occurrences of recurring events which start in [From, To).
+type EventRange struct {
+	From time.Time
+	To   time.Time
+	// Query is matched case-insensitively against title and description if not empty.
+	Query string
+	Order SortOrder
+	// After skips events up to and including the cursor in the chosen order.
+	After *Cursor
+	// Limit is the maximum number of events in the page, zero means no limit for the storage
+	// and the default page size for the service.
+	Limit int
+}
+
+// EventPage is the page of events. Next is nil if there are no more events.
+type EventPage struct {
+	Events []Event
+	Next   *Cursor
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+	FrequencyYearly  Frequency = "YEARLY"
+)
+
+// Recurrence is a subset of the RFC 5545 RRULE plus the list of excluded occurrences (EXDATE).
+type Recurrence struct {
+	Frequency  Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	Count      int
+	Until      time.Time
+	Exceptions []time.Time
+	// Location is the time zone (TZID) in which occurrences keep the wall clock time of the start, UTC if nil.
+	Location *time.Location
+}
+
+// RecurrenceScope defines which occurrences of a recurring event are affected by update or delete.
+type RecurrenceScope string
+
+const (
+	ScopeAll       RecurrenceScope = "all"
+	ScopeThis      RecurrenceScope = "this"
+	ScopeFollowing RecurrenceScope = "following"
+)
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// ReminderChannel is the way the reminder is delivered to the user.
+type ReminderChannel string
+
+const (
+	ChannelLog     ReminderChannel = "log"
+	ChannelEmail   ReminderChannel = "email"
+	ChannelWebhook ReminderChannel = "webhook"
+)
+
+// ReminderStatus is the delivery state of the reminder.
+type ReminderStatus string
+
+const (
+	// StatusPending reminders wait for their time to come.
+	StatusPending ReminderStatus = "pending"
+	// StatusQueued reminders are put to the outbox and wait to be published.
+	StatusQueued ReminderStatus = "queued"
+	// StatusSent reminders are confirmed by the message queue.
+	StatusSent ReminderStatus = "sent"
+)
+
+// Reminder notifies the user Before the start of the event.
+type Reminder struct {
+	// ID is assigned by the storage.
+	ID       int64
+	Before   time.Duration
+	Channel  ReminderChannel
+	Status   ReminderStatus
+	QueuedAt time.Time
+	SentAt   time.Time
+	// Occurrence is the start of the occurrence of the event the status refers to, every occurrence
+	// of the recurring event is reminded of in turn. It is zero when no occurrences are left.
+	Occurrence time.Time
+}
+
+// SameAs reports whether both reminders are sent at the same time through the same channel.
+func (r Reminder) SameAs(other Reminder) bool {
+	return r.Before == other.Before && r.Channel == other.Channel
+}
+
+// MergeReminders returns wanted reminders where those already present in current keep
+// their ids and delivery state, so replacing the list does not send them again.
+// The rest of wanted reminders are new and pending.
+func MergeReminders(current, wanted []Reminder) []Reminder {
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	merged := make([]Reminder, 0, len(wanted))
+
+	for _, reminder := range wanted {
+		next := Reminder{
+			Before:  reminder.Before,
+			Channel: reminder.Channel,
+			Status:  StatusPending,
+		}
+		for _, existing := range current {
+			if existing.SameAs(reminder) {
+				next = existing
+				break
+			}
+		}
+		merged = append(merged, next)
+	}
+
+	return merged
+}
+
+// ResetReminders returns copies of reminders which are not stored and not delivered yet.
+func ResetReminders(reminders []Reminder) []Reminder {
+	return MergeReminders(nil, reminders)
+}
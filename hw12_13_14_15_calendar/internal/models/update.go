@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// EventUpdate is a partial update of the event. Nil fields are left unchanged,
+// set fields are written even if they hold zero values.
+type EventUpdate struct {
+	Title                *string
+	Date                 *time.Time
+	Duration             *time.Duration
+	Description          *string
+	NotificationInterval *time.Duration
+	// Reminders replace the reminders of the event if they are not nil.
+	Reminders *[]Reminder
+	// Recurrence replaces the recurrence of the event if it is not nil.
+	Recurrence *Recurrence
+	// ClearRecurrence turns the recurring event into a single one.
+	ClearRecurrence bool
+	// RecurrenceExceptions replace the exceptions of the resulting recurrence if they are not nil.
+	RecurrenceExceptions *[]time.Time
+	// RecurrenceLocation replaces the time zone of the resulting recurrence if it is not nil.
+	RecurrenceLocation *time.Location
+}
+
+// IsEmpty reports whether the update changes nothing.
+func (u EventUpdate) IsEmpty() bool {
+	return u.Title == nil && u.Date == nil && u.Duration == nil && u.Description == nil &&
+		u.NotificationInterval == nil && u.Reminders == nil && u.Recurrence == nil && !u.ClearRecurrence &&
+		u.RecurrenceExceptions == nil && u.RecurrenceLocation == nil
+}
+
+// Apply returns the event with the update applied.
+func (u EventUpdate) Apply(event Event) Event {
+	if u.Title != nil {
+		event.Title = *u.Title
+	}
+	if u.Date != nil {
+		event.Date = *u.Date
+	}
+	if u.Duration != nil {
+		event.Duration = *u.Duration
+	}
+	if u.Description != nil {
+		event.Description = *u.Description
+	}
+	if u.NotificationInterval != nil {
+		event.NotificationInterval = *u.NotificationInterval
+	}
+	if u.Reminders != nil {
+		event.Reminders = MergeReminders(event.Reminders, *u.Reminders)
+	}
+	if u.ClearRecurrence {
+		event.Recurrence = nil
+	}
+	if u.Recurrence != nil {
+		r := *u.Recurrence
+		r.ByDay = append([]time.Weekday(nil), r.ByDay...)
+		r.Exceptions = append([]time.Time(nil), r.Exceptions...)
+		event.Recurrence = &r
+	}
+	if event.Recurrence != nil && (u.RecurrenceExceptions != nil || u.RecurrenceLocation != nil) {
+		r := *event.Recurrence
+		if u.RecurrenceExceptions != nil {
+			r.Exceptions = append([]time.Time(nil), *u.RecurrenceExceptions...)
+		}
+		if u.RecurrenceLocation != nil {
+			r.Location = u.RecurrenceLocation
+		}
+		event.Recurrence = &r
+	}
+	return event
+}
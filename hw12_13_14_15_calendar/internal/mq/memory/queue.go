@@ -0,0 +1,254 @@
+package memoryqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+var ErrQueueClosed = errors.New("memory queue: closed")
+
+// Queue keeps notifications in the process. It is both the producer and the consumer,
+// so it is used by tests and to run the scheduler and the sender without a broker.
+type Queue struct {
+	policy mq.RetryPolicy
+
+	mu          sync.Mutex
+	ready       []message
+	deadLetters []mq.DeadLetter
+	closed      bool
+	// wake is signaled when the message is ready.
+	wake chan struct{}
+	// timers are the pending redeliveries.
+	timers map[*time.Timer]struct{}
+
+	notifications chan mq.Notification
+	consumeOnce   sync.Once
+	closing       chan struct{}
+	closeOnce     sync.Once
+	done          chan struct{}
+}
+
+type message struct {
+	body      []byte
+	retries   int
+	timestamp time.Time
+}
+
+func NewQueue(policy mq.RetryPolicy) *Queue {
+	return &Queue{
+		policy:        policy,
+		wake:          make(chan struct{}, 1),
+		timers:        make(map[*time.Timer]struct{}),
+		notifications: make(chan mq.Notification, 10),
+		closing:       make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+func (q *Queue) Publish(_ context.Context, body []byte) error {
+	return q.push(message{body: body, timestamp: time.Now()})
+}
+
+func (q *Queue) push(msg message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	q.ready = append(q.ready, msg)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Consume starts consuming, the returned channel is closed on Shutdown.
+func (q *Queue) Consume() (<-chan mq.Notification, error) {
+	q.consumeOnce.Do(func() {
+		go q.consume()
+	})
+	return q.notifications, nil
+}
+
+func (q *Queue) consume() {
+	defer close(q.done)
+	defer close(q.notifications)
+
+	for {
+		msg, ok := q.pop()
+		if !ok {
+			select {
+			case <-q.closing:
+				return
+			case <-q.wake:
+			}
+			continue
+		}
+
+		var decoded mq.Message
+		err := json.Unmarshal(msg.body, &decoded)
+
+		notification := mq.Notification{
+			Message:  decoded,
+			Err:      err,
+			Delivery: &delivery{queue: q, msg: msg},
+		}
+
+		select {
+		case <-q.closing:
+			return
+		case q.notifications <- notification:
+		}
+	}
+}
+
+func (q *Queue) pop() (message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.ready) == 0 {
+		return message{}, false
+	}
+	msg := q.ready[0]
+	q.ready = q.ready[1:]
+	return msg, true
+}
+
+// retry returns the message to the queue after the delay.
+func (q *Queue) retry(msg message, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		delete(q.timers, timer)
+		q.mu.Unlock()
+
+		_ = q.push(msg)
+	})
+	q.timers[timer] = struct{}{}
+}
+
+func (q *Queue) deadLetter(msg message, cause error) {
+	letter := mq.DeadLetter{
+		Body:      msg.body,
+		Retries:   msg.retries,
+		Timestamp: msg.timestamp,
+	}
+	if cause != nil {
+		letter.Error = cause.Error()
+	}
+	_ = json.Unmarshal(msg.body, &letter.Message)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.deadLetters = append(q.deadLetters, letter)
+}
+
+func (q *Queue) DeadLetters(_ context.Context, limit int) ([]mq.DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if limit > len(q.deadLetters) {
+		limit = len(q.deadLetters)
+	}
+	letters := make([]mq.DeadLetter, limit)
+	copy(letters, q.deadLetters)
+
+	return letters, nil
+}
+
+func (q *Queue) Replay(ctx context.Context, limit int) (int, error) {
+	q.mu.Lock()
+	if limit > len(q.deadLetters) {
+		limit = len(q.deadLetters)
+	}
+	letters := q.deadLetters[:limit]
+	q.deadLetters = q.deadLetters[limit:]
+	q.mu.Unlock()
+
+	for i, letter := range letters {
+		if err := q.Publish(ctx, letter.Body); err != nil {
+			return i, err
+		}
+	}
+
+	return len(letters), nil
+}
+
+// Shutdown stops consuming and drops pending redeliveries.
+func (q *Queue) Shutdown() error {
+	q.closeOnce.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		for timer := range q.timers {
+			timer.Stop()
+		}
+		q.mu.Unlock()
+
+		close(q.closing)
+	})
+
+	// notifications are closed here if consuming has not been started
+	q.consumeOnce.Do(func() {
+		close(q.notifications)
+		close(q.done)
+	})
+	<-q.done
+
+	return nil
+}
+
+// delivery settles the notification received from the queue.
+type delivery struct {
+	queue   *Queue
+	msg     message
+	settled sync.Once
+}
+
+func (d *delivery) settle(fn func()) error {
+	err := mq.ErrSettled
+	d.settled.Do(func() {
+		fn()
+		err = nil
+	})
+	return err
+}
+
+func (d *delivery) Ack() error {
+	return d.settle(func() {})
+}
+
+func (d *delivery) Retry(_ context.Context, cause error) error {
+	return d.settle(func() {
+		if d.queue.policy.Exhausted(d.msg.retries + 1) {
+			d.queue.deadLetter(d.msg, cause)
+			return
+		}
+
+		msg := d.msg
+		msg.retries++
+		d.queue.retry(msg, d.queue.policy.Delay(msg.retries))
+	})
+}
+
+func (d *delivery) Reject(_ context.Context, cause error) error {
+	return d.settle(func() {
+		d.queue.deadLetter(d.msg, cause)
+	})
+}
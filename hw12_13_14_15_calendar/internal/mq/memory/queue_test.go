@@ -0,0 +1,47 @@
+package memoryqueue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/mqtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueConformance(t *testing.T) {
+	mqtest.Run(t, func(t *testing.T) (mq.NotificationProducer, mq.NotificationConsumer) {
+		t.Helper()
+
+		q := NewQueue(mqtest.Policy)
+		t.Cleanup(func() { _ = q.Shutdown() })
+
+		return q, q
+	})
+}
+
+func TestQueueSettleTwice(t *testing.T) {
+	q := NewQueue(mqtest.Policy)
+	defer q.Shutdown()
+
+	notifications, err := q.Consume()
+	require.NoError(t, err)
+	require.NoError(t, q.Publish(context.Background(), []byte("{}")))
+
+	notification := <-notifications
+	require.NoError(t, notification.Delivery.Ack())
+	require.ErrorIs(t, notification.Delivery.Reject(context.Background(), nil), mq.ErrSettled)
+}
+
+func TestQueueShutdown(t *testing.T) {
+	q := NewQueue(mqtest.Policy)
+	require.NoError(t, q.Shutdown())
+	require.NoError(t, q.Shutdown())
+
+	require.ErrorIs(t, q.Publish(context.Background(), []byte("{}")), ErrQueueClosed)
+
+	notifications, err := q.Consume()
+	require.NoError(t, err)
+	_, ok := <-notifications
+	require.False(t, ok)
+}
@@ -1,15 +1,68 @@
 package mq
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrSettled is returned when the delivery is settled twice.
+var ErrSettled = errors.New("delivery is already settled")
 
 type Message struct {
-	EventID string    `json:"event_id"`
-	Title   string    `json:"title"`
-	Date    time.Time `json:"date"`
-	UserID  int       `json:"user_id"`
+	// ID is the same for every copy of the message to one recipient about one firing of the reminder,
+	// so duplicates published by the scheduler are recognized.
+	ID string `json:"id"`
+	// FireAt is the moment the reminder is due.
+	FireAt     time.Time `json:"fire_at"`
+	EventID    string    `json:"event_id"`
+	ReminderID int64     `json:"reminder_id"`
+	Channel    string    `json:"channel"`
+	Title      string    `json:"title"`
+	Date       time.Time `json:"date"`
+	// UserID is the recipient, the owner of the event or its attendee.
+	UserID int `json:"user_id"`
+}
+
+// MessageID returns the id of the message to the user about the reminder due at fireAt.
+func MessageID(reminderID int64, userID int, fireAt time.Time) string {
+	return strconv.FormatInt(reminderID, 10) + "-" + strconv.Itoa(userID) + "-" + strconv.FormatInt(fireAt.Unix(), 10)
+}
+
+// DecodeID returns the id of the encoded message, it is empty if the body cannot be decoded.
+func DecodeID(body []byte) string {
+	var msg struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(body, &msg)
+	return msg.ID
 }
 
 type Notification struct {
 	Message Message
 	Err     error
+	// Delivery settles the notification in the queue, exactly one of its methods must be called.
+	Delivery Delivery
+}
+
+// Delivery is the received notification which stays in the queue until it is settled.
+type Delivery interface {
+	// Ack removes the delivered notification from the queue.
+	Ack() error
+	// Retry redelivers the notification after the backoff or dead-letters it when the attempts are exhausted.
+	Retry(ctx context.Context, cause error) error
+	// Reject dead-letters the notification which cannot be delivered.
+	Reject(ctx context.Context, cause error) error
+}
+
+// DeadLetter is the notification kept in the dead letter queue.
+type DeadLetter struct {
+	Message Message
+	// Body is the raw notification, it is set even if the notification cannot be decoded.
+	Body      []byte
+	Error     string
+	Retries   int
+	Timestamp time.Time
 }
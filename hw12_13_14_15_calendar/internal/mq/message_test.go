@@ -0,0 +1,27 @@
+package mq
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageID(t *testing.T) {
+	fireAt := time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC)
+
+	id := MessageID(1, 42, fireAt)
+	require.Equal(t, "1-42-1792342800", id)
+	// the copy about the same firing has the same id
+	require.Equal(t, id, MessageID(1, 42, fireAt.In(time.FixedZone("UTC+3", 3*60*60))))
+	require.NotEqual(t, id, MessageID(1, 42, fireAt.Add(time.Hour)))
+	require.NotEqual(t, id, MessageID(2, 42, fireAt))
+	// every recipient of the reminder gets the own message
+	require.NotEqual(t, id, MessageID(1, 7, fireAt))
+
+	body, err := json.Marshal(Message{ID: id, FireAt: fireAt})
+	require.NoError(t, err)
+	require.Equal(t, id, DecodeID(body))
+	require.Empty(t, DecodeID([]byte("not json")))
+}
@@ -27,3 +27,11 @@ type Consumer struct {
 func NewConsumer(notificationConsumer NotificationConsumer) *Consumer {
 	return &Consumer{NotificationConsumer: notificationConsumer}
 }
+
+// DeadLetterQueue keeps notifications which are rejected or run out of attempts.
+type DeadLetterQueue interface {
+	// DeadLetters returns up to limit oldest dead letters, they are left in the queue.
+	DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error)
+	// Replay returns up to limit oldest dead letters to the queue with the reset number of attempts.
+	Replay(ctx context.Context, limit int) (int, error)
+}
@@ -0,0 +1,250 @@
+package mqtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/stretchr/testify/require"
+)
+
+// Policy is the retry policy the queues under test must use.
+var Policy = mq.RetryPolicy{
+	Attempts:   3,
+	MinBackoff: 10 * time.Millisecond,
+	MaxBackoff: 20 * time.Millisecond,
+}
+
+const (
+	// deliveryTimeout is enough for the redelivery after the backoff of Policy and the polling of the queue.
+	deliveryTimeout = 5 * time.Second
+	// quietPeriod is waited to check that nothing is redelivered.
+	quietPeriod = 300 * time.Millisecond
+)
+
+var errDelivery = errors.New("smtp is unavailable")
+
+// NewQueue returns the producer and the consumer of the same empty queue with Policy.
+type NewQueue func(t *testing.T) (mq.NotificationProducer, mq.NotificationConsumer)
+
+// Run checks that the queue follows the semantics shared by all implementations of mq.NotificationProducer
+// and mq.NotificationConsumer. Dead letters are checked if the consumer implements mq.DeadLetterQueue.
+func Run(t *testing.T, newQueue NewQueue) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, producer mq.NotificationProducer, consumer mq.NotificationConsumer)
+	}{
+		{name: "deliver and ack", fn: testDeliver},
+		{name: "retry", fn: testRetry},
+		{name: "retries are exhausted", fn: testRetriesExhausted},
+		{name: "reject", fn: testReject},
+		{name: "undecodable", fn: testUndecodable},
+		{name: "shutdown", fn: testShutdown},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			producer, consumer := newQueue(t)
+			tc.fn(t, producer, consumer)
+		})
+	}
+}
+
+func testDeliver(t *testing.T, producer mq.NotificationProducer, consumer mq.NotificationConsumer) {
+	notifications := consume(t, consumer)
+
+	messages := []mq.Message{newMessage("id1"), newMessage("id2"), newMessage("id3")}
+	for _, msg := range messages {
+		publish(t, producer, msg)
+	}
+
+	received := make([]mq.Message, 0, len(messages))
+	for range messages {
+		notification := receive(t, notifications)
+		require.NoError(t, notification.Err)
+		require.NoError(t, notification.Delivery.Ack())
+		received = append(received, notification.Message)
+	}
+	requireMessages(t, messages, received)
+
+	requireNoDelivery(t, notifications)
+}
+
+func testRetry(t *testing.T, producer mq.NotificationProducer, consumer mq.NotificationConsumer) {
+	notifications := consume(t, consumer)
+
+	msg := newMessage("id1")
+	publish(t, producer, msg)
+
+	notification := receive(t, notifications)
+	require.NoError(t, notification.Delivery.Retry(context.Background(), errDelivery))
+
+	notification = receive(t, notifications)
+	requireMessages(t, []mq.Message{msg}, []mq.Message{notification.Message})
+	require.NoError(t, notification.Delivery.Ack())
+
+	requireNoDelivery(t, notifications)
+	requireDeadLetters(t, consumer, 0)
+}
+
+func testRetriesExhausted(t *testing.T, producer mq.NotificationProducer, consumer mq.NotificationConsumer) {
+	notifications := consume(t, consumer)
+
+	msg := newMessage("id1")
+	publish(t, producer, msg)
+
+	for i := 0; i < Policy.Attempts; i++ {
+		notification := receive(t, notifications)
+		require.NoError(t, notification.Delivery.Retry(context.Background(), errDelivery))
+	}
+	requireNoDelivery(t, notifications)
+
+	letters := requireDeadLetters(t, consumer, 1)
+	if letters == nil {
+		return
+	}
+	requireMessages(t, []mq.Message{msg}, []mq.Message{letters[0].Message})
+	require.Equal(t, errDelivery.Error(), letters[0].Error)
+
+	// the replayed notification has all attempts again
+	replayed, err := consumer.(mq.DeadLetterQueue).Replay(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+	requireDeadLetters(t, consumer, 0)
+
+	notification := receive(t, notifications)
+	requireMessages(t, []mq.Message{msg}, []mq.Message{notification.Message})
+	require.NoError(t, notification.Delivery.Retry(context.Background(), errDelivery))
+
+	notification = receive(t, notifications)
+	require.NoError(t, notification.Delivery.Ack())
+	requireNoDelivery(t, notifications)
+}
+
+func testReject(t *testing.T, producer mq.NotificationProducer, consumer mq.NotificationConsumer) {
+	notifications := consume(t, consumer)
+
+	publish(t, producer, newMessage("id1"))
+
+	notification := receive(t, notifications)
+	require.NoError(t, notification.Delivery.Reject(context.Background(), errDelivery))
+
+	requireNoDelivery(t, notifications)
+	requireDeadLetters(t, consumer, 1)
+}
+
+func testUndecodable(t *testing.T, producer mq.NotificationProducer, consumer mq.NotificationConsumer) {
+	notifications := consume(t, consumer)
+
+	require.NoError(t, producer.Publish(context.Background(), []byte("not json")))
+
+	notification := receive(t, notifications)
+	require.Error(t, notification.Err)
+	require.NoError(t, notification.Delivery.Reject(context.Background(), notification.Err))
+
+	requireNoDelivery(t, notifications)
+
+	letters := requireDeadLetters(t, consumer, 1)
+	if letters != nil {
+		require.Equal(t, []byte("not json"), letters[0].Body)
+	}
+}
+
+func testShutdown(t *testing.T, _ mq.NotificationProducer, consumer mq.NotificationConsumer) {
+	notifications := consume(t, consumer)
+
+	require.NoError(t, consumer.Shutdown())
+
+	select {
+	case _, ok := <-notifications:
+		require.False(t, ok, "notification is received after shutdown")
+	case <-time.After(deliveryTimeout):
+		t.Fatal("notifications are not closed on shutdown")
+	}
+}
+
+func newMessage(eventID string) mq.Message {
+	return mq.Message{
+		EventID:    eventID,
+		ReminderID: 1,
+		Channel:    "log",
+		Title:      "title " + eventID,
+		Date:       time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC),
+		UserID:     1,
+	}
+}
+
+func consume(t *testing.T, consumer mq.NotificationConsumer) <-chan mq.Notification {
+	t.Helper()
+
+	notifications, err := consumer.Consume()
+	require.NoError(t, err)
+	return notifications
+}
+
+func publish(t *testing.T, producer mq.NotificationProducer, msg mq.Message) {
+	t.Helper()
+
+	body, err := json.Marshal(msg)
+	require.NoError(t, err)
+	require.NoError(t, producer.Publish(context.Background(), body))
+}
+
+func receive(t *testing.T, notifications <-chan mq.Notification) mq.Notification {
+	t.Helper()
+
+	select {
+	case notification, ok := <-notifications:
+		require.True(t, ok, "notifications are closed")
+		return notification
+	case <-time.After(deliveryTimeout):
+		t.Fatal("notification is not received")
+		return mq.Notification{}
+	}
+}
+
+func requireNoDelivery(t *testing.T, notifications <-chan mq.Notification) {
+	t.Helper()
+
+	select {
+	case notification := <-notifications:
+		t.Fatalf("unexpected notification: %+v", notification.Message)
+	case <-time.After(quietPeriod):
+	}
+}
+
+// requireDeadLetters checks the number of dead letters if the consumer keeps them and returns them.
+func requireDeadLetters(t *testing.T, consumer mq.NotificationConsumer, n int) []mq.DeadLetter {
+	t.Helper()
+
+	dlq, ok := consumer.(mq.DeadLetterQueue)
+	if !ok {
+		return nil
+	}
+
+	letters, err := dlq.DeadLetters(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, letters, n)
+	return letters
+}
+
+// requireMessages compares messages ignoring their order and the location of dates.
+func requireMessages(t *testing.T, expected, actual []mq.Message) {
+	t.Helper()
+
+	normalize := func(messages []mq.Message) []mq.Message {
+		result := make([]mq.Message, 0, len(messages))
+		for _, msg := range messages {
+			msg.Date = msg.Date.UTC()
+			result = append(result, msg)
+		}
+		return result
+	}
+	require.ElementsMatch(t, normalize(expected), normalize(actual))
+}
@@ -0,0 +1,408 @@
+package postgresqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	jobsTable = "notification_jobs"
+	// channel is notified about every new job, so consumers do not wait for the next poll.
+	channel = "notification_jobs"
+)
+
+// ErrLeaseLost is returned when the job is settled after its lease has expired and it has been claimed again.
+var ErrLeaseLost = errors.New("postgres queue: lease of the job is lost")
+
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// listener waits for notifications on channel.
+type listener interface {
+	WaitForNotification(ctx context.Context) error
+	Close()
+}
+
+type Config struct {
+	// PollInterval is the max time the consumer waits for new jobs without notifications,
+	// it is the delay of redeliveries in the worst case.
+	PollInterval time.Duration
+	// Lease hides the claimed job from other consumers until it is settled or the lease expires.
+	Lease     time.Duration
+	BatchSize int
+	Retry     mq.RetryPolicy
+}
+
+// Queue keeps notifications in the jobs table of the database. It is both the producer and the consumer.
+// Several consumers share jobs: every job is claimed by one of them with FOR UPDATE SKIP LOCKED.
+type Queue struct {
+	db     DB
+	listen func(ctx context.Context) (listener, error)
+	log    logger.Logger
+	cfg    Config
+
+	ctx           context.Context
+	cancel        context.CancelFunc
+	notifications chan mq.Notification
+	consumeOnce   sync.Once
+	done          chan struct{}
+}
+
+func NewQueue(db *pgxpool.Pool, log logger.Logger, cfg Config) *Queue {
+	q := newQueue(db, log, cfg)
+	q.listen = poolListener(db)
+	return q
+}
+
+func newQueue(db DB, log logger.Logger, cfg Config) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Queue{
+		db:            db,
+		log:           log,
+		cfg:           cfg,
+		ctx:           ctx,
+		cancel:        cancel,
+		notifications: make(chan mq.Notification, cfg.BatchSize),
+		done:          make(chan struct{}),
+	}
+}
+
+func (q *Queue) Publish(ctx context.Context, body []byte) error {
+	query := `
+		WITH job AS (INSERT INTO ` + jobsTable + ` (payload) VALUES ($1) RETURNING id)
+		SELECT pg_notify('` + channel + `', id::text) FROM job`
+
+	_, err := q.db.Exec(ctx, query, body)
+	return err
+}
+
+// Consume starts consuming, the returned channel is closed on Shutdown.
+func (q *Queue) Consume() (<-chan mq.Notification, error) {
+	q.consumeOnce.Do(func() {
+		go q.consume()
+	})
+	return q.notifications, nil
+}
+
+func (q *Queue) consume() {
+	defer close(q.done)
+	defer close(q.notifications)
+
+	var l listener
+	defer func() {
+		if l != nil {
+			l.Close()
+		}
+	}()
+
+	for {
+		if l == nil {
+			var err error
+			if l, err = q.listen(q.ctx); err != nil {
+				if q.ctx.Err() != nil {
+					return
+				}
+				q.log.Error("error listening postgres queue", slog.String("error", err.Error()))
+				l = nil
+			}
+		}
+
+		jobs, err := q.claim(q.ctx)
+		if err != nil {
+			if q.ctx.Err() != nil {
+				return
+			}
+			q.log.Error("error claiming postgres queue jobs", slog.String("error", err.Error()))
+		}
+
+		for _, j := range jobs {
+			select {
+			case <-q.ctx.Done():
+				return
+			case q.notifications <- q.notification(j):
+			}
+		}
+
+		if len(jobs) == q.cfg.BatchSize {
+			continue
+		}
+
+		l = q.wait(l)
+		if q.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// wait blocks until the job is published or the poll interval passes. It returns nil if the listener is broken.
+func (q *Queue) wait(l listener) listener {
+	ctx, cancel := context.WithTimeout(q.ctx, q.cfg.PollInterval)
+	defer cancel()
+
+	if l == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	err := l.WaitForNotification(ctx)
+	if err == nil || ctx.Err() != nil {
+		return l
+	}
+
+	q.log.Error("error waiting for postgres queue notification", slog.String("error", err.Error()))
+	l.Close()
+	return nil
+}
+
+type job struct {
+	id        int64
+	payload   []byte
+	attempts  int
+	createdAt time.Time
+}
+
+// claim leases the batch of available jobs and counts their attempts.
+func (q *Queue) claim(ctx context.Context) ([]job, error) {
+	query := `
+		UPDATE ` + jobsTable + `
+		SET leased_until = now() + make_interval(secs => $1), attempts = attempts + 1
+		WHERE id IN (
+			SELECT id
+			FROM ` + jobsTable + `
+			WHERE dead_at IS NULL AND available_at <= now() AND (leased_until IS NULL OR leased_until <= now())
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, payload, attempts, created_at`
+
+	rows, err := q.db.Query(ctx, query, q.cfg.Lease.Seconds(), q.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []job
+
+	for rows.Next() {
+		var j job
+
+		if err := rows.Scan(&j.id, &j.payload, &j.attempts, &j.createdAt); err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].id < jobs[k].id })
+
+	return jobs, nil
+}
+
+func (q *Queue) notification(j job) mq.Notification {
+	var msg mq.Message
+	err := json.Unmarshal(j.payload, &msg)
+
+	return mq.Notification{
+		Message:  msg,
+		Err:      err,
+		Delivery: &delivery{queue: q, job: j},
+	}
+}
+
+func (q *Queue) DeadLetters(ctx context.Context, limit int) ([]mq.DeadLetter, error) {
+	query := `
+		SELECT payload, attempts, last_error, created_at
+		FROM ` + jobsTable + `
+		WHERE dead_at IS NOT NULL
+		ORDER BY dead_at, id
+		LIMIT $1`
+
+	rows, err := q.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []mq.DeadLetter
+
+	for rows.Next() {
+		var (
+			letter   mq.DeadLetter
+			attempts int
+		)
+
+		if err := rows.Scan(&letter.Body, &attempts, &letter.Error, &letter.Timestamp); err != nil {
+			return nil, err
+		}
+		letter.Retries = attempts - 1
+		_ = json.Unmarshal(letter.Body, &letter.Message)
+
+		letters = append(letters, letter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return letters, nil
+}
+
+func (q *Queue) Replay(ctx context.Context, limit int) (int, error) {
+	query := `
+		UPDATE ` + jobsTable + `
+		SET dead_at = NULL, attempts = 0, available_at = now(), leased_until = NULL, last_error = ''
+		WHERE id IN (
+			SELECT id
+			FROM ` + jobsTable + `
+			WHERE dead_at IS NOT NULL
+			ORDER BY dead_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)`
+
+	tag, err := q.db.Exec(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := int(tag.RowsAffected())
+	if replayed > 0 {
+		if _, err := q.db.Exec(ctx, `SELECT pg_notify('`+channel+`', '')`); err != nil {
+			return replayed, err
+		}
+	}
+
+	return replayed, nil
+}
+
+// Shutdown stops consuming. Claimed jobs which are not settled are redelivered after their leases expire.
+func (q *Queue) Shutdown() error {
+	q.cancel()
+
+	// notifications are closed here if consuming has not been started
+	q.consumeOnce.Do(func() {
+		close(q.notifications)
+		close(q.done)
+	})
+	<-q.done
+
+	return nil
+}
+
+// delivery settles the job claimed by the queue. The job is only changed while the lease is held,
+// that is while its attempts are not changed by the next claim.
+type delivery struct {
+	queue   *Queue
+	job     job
+	settled sync.Once
+}
+
+func (d *delivery) settle(fn func() error) error {
+	err := mq.ErrSettled
+	d.settled.Do(func() {
+		err = fn()
+	})
+	return err
+}
+
+func (d *delivery) Ack() error {
+	return d.settle(func() error {
+		query := `DELETE FROM ` + jobsTable + ` WHERE id = $1 AND attempts = $2`
+
+		return d.exec(context.Background(), query, d.job.id, d.job.attempts)
+	})
+}
+
+func (d *delivery) Retry(ctx context.Context, cause error) error {
+	if d.queue.cfg.Retry.Exhausted(d.job.attempts) {
+		return d.Reject(ctx, cause)
+	}
+
+	return d.settle(func() error {
+		query := `
+			UPDATE ` + jobsTable + `
+			SET available_at = now() + make_interval(secs => $3), leased_until = NULL, last_error = $4
+			WHERE id = $1 AND attempts = $2`
+
+		delay := d.queue.cfg.Retry.Delay(d.job.attempts)
+
+		return d.exec(ctx, query, d.job.id, d.job.attempts, delay.Seconds(), reason(cause))
+	})
+}
+
+func (d *delivery) Reject(ctx context.Context, cause error) error {
+	return d.settle(func() error {
+		query := `
+			UPDATE ` + jobsTable + `
+			SET dead_at = now(), leased_until = NULL, last_error = $3
+			WHERE id = $1 AND attempts = $2`
+
+		return d.exec(ctx, query, d.job.id, d.job.attempts, reason(cause))
+	})
+}
+
+func (d *delivery) exec(ctx context.Context, query string, args ...interface{}) error {
+	tag, err := d.queue.db.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func reason(cause error) string {
+	if cause == nil {
+		return ""
+	}
+	return cause.Error()
+}
+
+type poolConn struct {
+	conn *pgxpool.Conn
+}
+
+func (p poolConn) WaitForNotification(ctx context.Context) error {
+	_, err := p.conn.Conn().WaitForNotification(ctx)
+	return err
+}
+
+func (p poolConn) Close() {
+	// the connection is in the listening state, so it is closed instead of being returned to the pool
+	_ = p.conn.Hijack().Close(context.Background())
+}
+
+// poolListener returns the function which takes the connection from the pool and listens on channel.
+func poolListener(db *pgxpool.Pool) func(ctx context.Context) (listener, error) {
+	return func(ctx context.Context) (listener, error) {
+		conn, err := db.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			conn.Release()
+			return nil, err
+		}
+
+		return poolConn{conn: conn}, nil
+	}
+}
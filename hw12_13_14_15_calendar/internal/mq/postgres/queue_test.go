@@ -0,0 +1,290 @@
+package postgresqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v2"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/mqtest"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/postgres/pgtest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// testDSN is the connection string of the real database, empty if it is unavailable.
+var testDSN string
+
+func TestMain(m *testing.M) {
+	srv, err := pgtest.Start(context.Background(), "../../../migrations")
+	switch {
+	case err == nil:
+		testDSN = srv.DSN
+	case !errors.Is(err, pgtest.ErrUnavailable):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	if srv != nil {
+		srv.Stop()
+	}
+	os.Exit(code)
+}
+
+var testConfig = Config{
+	PollInterval: 50 * time.Millisecond,
+	Lease:        time.Minute,
+	BatchSize:    2,
+	Retry:        mqtest.Policy,
+}
+
+func TestQueueConformance(t *testing.T) {
+	mqtest.Run(t, func(t *testing.T) (mq.NotificationProducer, mq.NotificationConsumer) {
+		t.Helper()
+
+		if testDSN == "" {
+			t.Skip(pgtest.ErrUnavailable.Error())
+		}
+
+		ctx := context.Background()
+
+		db, err := pgxpool.New(ctx, testDSN)
+		require.NoError(t, err)
+		t.Cleanup(db.Close)
+
+		_, err = db.Exec(ctx, "TRUNCATE "+jobsTable)
+		require.NoError(t, err)
+
+		ctrl := gomock.NewController(t)
+		logger := mock_logger.NewMockLogger(ctrl)
+		logger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+		q := NewQueue(db, logger, testConfig)
+		t.Cleanup(func() { _ = q.Shutdown() })
+
+		return q, q
+	})
+}
+
+// fakeListener is notified through the channel.
+type fakeListener chan struct{}
+
+func (f fakeListener) WaitForNotification(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-f:
+		return nil
+	}
+}
+
+func (f fakeListener) Close() {}
+
+var claimQuery = regexp.QuoteMeta(`
+		UPDATE ` + jobsTable + `
+		SET leased_until = now() + make_interval(secs => $1), attempts = attempts + 1`)
+
+func TestQueuePublish(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	q := newQueue(mock, nil, testConfig)
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		WITH job AS (INSERT INTO ` + jobsTable + ` (payload) VALUES ($1) RETURNING id)
+		SELECT pg_notify('` + channel + `', id::text) FROM job`)).
+		WithArgs([]byte(`{"event_id":"id1"}`)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	require.NoError(t, q.Publish(context.Background(), []byte(`{"event_id":"id1"}`)))
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestQueueConsume(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	// claims after the expected ones fail
+	logger.EXPECT().Error("error claiming postgres queue jobs", gomock.Any()).AnyTimes()
+
+	created := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+
+	// the full batch is followed by the next claim without waiting
+	mock.ExpectQuery(claimQuery).
+		WithArgs(testConfig.Lease.Seconds(), testConfig.BatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "payload", "attempts", "created_at"}).
+			AddRow(int64(2), []byte(`{"event_id":"id2"}`), 1, created).
+			AddRow(int64(1), []byte(`{"event_id":"id1"}`), 2, created))
+	mock.ExpectQuery(claimQuery).
+		WithArgs(testConfig.Lease.Seconds(), testConfig.BatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "payload", "attempts", "created_at"}).
+			AddRow(int64(3), []byte(`not json`), 1, created))
+	mock.ExpectQuery(claimQuery).
+		WithArgs(testConfig.Lease.Seconds(), testConfig.BatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "payload", "attempts", "created_at"}))
+
+	q := newQueue(mock, logger, testConfig)
+	q.listen = func(context.Context) (listener, error) {
+		return make(fakeListener), nil
+	}
+
+	notifications, err := q.Consume()
+	require.NoError(t, err)
+
+	// jobs are delivered in the order of publishing
+	require.Equal(t, "id1", (<-notifications).Message.EventID)
+	require.Equal(t, "id2", (<-notifications).Message.EventID)
+	require.Error(t, (<-notifications).Err)
+
+	require.Eventually(t, func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, q.Shutdown())
+	_, ok := <-notifications
+	require.False(t, ok)
+}
+
+func TestDeliverySettle(t *testing.T) {
+	errTest := errors.New("smtp is unavailable")
+
+	testCases := []struct {
+		name     string
+		attempts int
+		settle   func(d *delivery) error
+		query    string
+		args     []interface{}
+	}{
+		{
+			name:     "ack",
+			attempts: 1,
+			settle:   func(d *delivery) error { return d.Ack() },
+			query:    `DELETE FROM ` + jobsTable + ` WHERE id = $1 AND attempts = $2`,
+			args:     []interface{}{int64(1), 1},
+		},
+		{
+			name:     "retry",
+			attempts: 2,
+			settle:   func(d *delivery) error { return d.Retry(context.Background(), errTest) },
+			query: `
+			UPDATE ` + jobsTable + `
+			SET available_at = now() + make_interval(secs => $3), leased_until = NULL, last_error = $4
+			WHERE id = $1 AND attempts = $2`,
+			args: []interface{}{int64(1), 2, testConfig.Retry.Delay(2).Seconds(), errTest.Error()},
+		},
+		{
+			name:     "retries are exhausted",
+			attempts: 3,
+			settle:   func(d *delivery) error { return d.Retry(context.Background(), errTest) },
+			query: `
+			UPDATE ` + jobsTable + `
+			SET dead_at = now(), leased_until = NULL, last_error = $3
+			WHERE id = $1 AND attempts = $2`,
+			args: []interface{}{int64(1), 3, errTest.Error()},
+		},
+		{
+			name:     "reject",
+			attempts: 1,
+			settle:   func(d *delivery) error { return d.Reject(context.Background(), nil) },
+			query: `
+			UPDATE ` + jobsTable + `
+			SET dead_at = now(), leased_until = NULL, last_error = $3
+			WHERE id = $1 AND attempts = $2`,
+			args: []interface{}{int64(1), 1, ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta(tc.query)).
+				WithArgs(tc.args...).
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+			d := &delivery{queue: newQueue(mock, nil, testConfig), job: job{id: 1, attempts: tc.attempts}}
+			require.NoError(t, tc.settle(d))
+			require.ErrorIs(t, d.Ack(), mq.ErrSettled)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+		})
+	}
+}
+
+func TestDeliveryLeaseLost(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM `+jobsTable+` WHERE id = $1 AND attempts = $2`)).
+		WithArgs(int64(1), 1).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	d := &delivery{queue: newQueue(mock, nil, testConfig), job: job{id: 1, attempts: 1}}
+	require.ErrorIs(t, d.Ack(), ErrLeaseLost)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestQueueDeadLetters(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	created := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT payload, attempts, last_error, created_at
+		FROM ` + jobsTable + `
+		WHERE dead_at IS NOT NULL
+		ORDER BY dead_at, id
+		LIMIT $1`)).
+		WithArgs(10).
+		WillReturnRows(pgxmock.NewRows([]string{"payload", "attempts", "last_error", "created_at"}).
+			AddRow([]byte(`{"event_id":"id1"}`), 3, "smtp is unavailable", created))
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE ` + jobsTable + `
+		SET dead_at = NULL, attempts = 0, available_at = now(), leased_until = NULL, last_error = ''`)).
+		WithArgs(10).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify('` + channel + `', '')`)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	q := newQueue(mock, nil, testConfig)
+
+	letters, err := q.DeadLetters(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, []mq.DeadLetter{{
+		Message:   mq.Message{EventID: "id1"},
+		Body:      []byte(`{"event_id":"id1"}`),
+		Error:     "smtp is unavailable",
+		Retries:   2,
+		Timestamp: created,
+	}}, letters)
+
+	replayed, err := q.Replay(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
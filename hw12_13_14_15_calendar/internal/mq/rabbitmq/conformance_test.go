@@ -0,0 +1,81 @@
+package rabbitmq
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq/mqtest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// addrEnv is the environment variable with host:port of the broker with the guest user.
+const addrEnv = "CALENDAR_TEST_RABBIT_ADDR"
+
+func TestConformance(t *testing.T) {
+	mqtest.Run(t, func(t *testing.T) (mq.NotificationProducer, mq.NotificationConsumer) {
+		t.Helper()
+
+		addr := os.Getenv(addrEnv)
+		if addr == "" {
+			t.Skip("rabbit is unavailable: set " + addrEnv)
+		}
+
+		host, portStr, err := net.SplitHostPort(addr)
+		require.NoError(t, err)
+		port, err := strconv.Atoi(portStr)
+		require.NoError(t, err)
+
+		ctrl := gomock.NewController(t)
+		logger := mock_logger.NewMockLogger(ctrl)
+		logger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+		logger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+		// every subtest uses its own queues
+		name := "test-" + uuid.NewString()
+
+		producer := NewProducer(ProducerConfig{
+			Username:     "guest",
+			Password:     "guest",
+			Host:         host,
+			Port:         port,
+			ExchangeName: name,
+			QueueName:    name,
+			ExchangeType: "direct",
+			RoutingKey:   name,
+		}, logger)
+		t.Cleanup(func() { _ = producer.Shutdown() })
+
+		consumer := NewSender(ConsumerConfig{
+			Username:           "guest",
+			Password:           "guest",
+			Host:               host,
+			Port:               port,
+			ExchangeName:       name,
+			ExchangeType:       "direct",
+			QueueName:          name,
+			RoutingKey:         name,
+			Tag:                name,
+			DeadLetterExchange: name + ".dlx",
+			DeadLetterQueue:    name + ".dlq",
+			RetryAttempts:      mqtest.Policy.Attempts,
+			RetryMinBackoff:    mqtest.Policy.MinBackoff,
+			RetryMaxBackoff:    mqtest.Policy.MaxBackoff,
+		}, logger)
+		t.Cleanup(func() { _ = consumer.Shutdown() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, producer.WaitConnected(ctx))
+		require.NoError(t, consumer.WaitConnected(ctx))
+
+		return producer, consumer
+	})
+}
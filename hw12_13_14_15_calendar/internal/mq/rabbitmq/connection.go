@@ -0,0 +1,232 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	defaultReconnectMinBackoff = time.Second
+	defaultReconnectMaxBackoff = 30 * time.Second
+)
+
+var ErrRabbitDisconnected = errors.New("rabbit: not connected to broker")
+
+// State is the state of the connection to the broker.
+type State int
+
+const (
+	StateConnecting State = iota
+	StateConnected
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// connection is the part of *amqp.Connection used by the supervisor.
+type connection interface {
+	Channel() (*amqp.Channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// supervisor keeps the connection to the broker. It reconnects with the exponential backoff when the connection
+// is lost and calls setup on every new connection to declare the topology and open channels.
+type supervisor struct {
+	name       string
+	log        logger.Logger
+	dial       func() (connection, error)
+	setup      func(conn connection) error
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu    sync.Mutex
+	state State
+	// changed is closed and replaced on every change of the state.
+	changed chan struct{}
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newSupervisor(name string, log logger.Logger, dial func() (connection, error), setup func(conn connection) error,
+	minBackoff, maxBackoff time.Duration,
+) *supervisor {
+	if minBackoff <= 0 {
+		minBackoff = defaultReconnectMinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = defaultReconnectMaxBackoff
+	}
+
+	return &supervisor{
+		name:       name,
+		log:        log,
+		dial:       dial,
+		setup:      setup,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		changed:    make(chan struct{}),
+		closing:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// dialer returns the function which connects to the broker at url.
+func dialer(url string, cfg amqp.Config) func() (connection, error) {
+	return func() (connection, error) {
+		return amqp.DialConfig(url, cfg)
+	}
+}
+
+func (s *supervisor) start() {
+	go s.run()
+}
+
+func (s *supervisor) run() {
+	defer close(s.done)
+	defer s.setState(StateClosed)
+
+	delay := s.minBackoff
+	for {
+		s.log.Info("connecting " + s.name + "...")
+
+		conn, err := s.connect()
+		if err != nil {
+			s.log.Error("error connecting "+s.name,
+				slog.String("error", err.Error()),
+				slog.Duration("retry_in", delay))
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-s.closing:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			delay *= 2
+			if delay > s.maxBackoff {
+				delay = s.maxBackoff
+			}
+			continue
+		}
+
+		delay = s.minBackoff
+		closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		s.setState(StateConnected)
+		s.log.Info(s.name + " is connected")
+
+		select {
+		case <-s.closing:
+			if err := conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+				s.log.Error("error closing "+s.name+" connection", slog.String("error", err.Error()))
+			}
+			return
+		case amqpErr := <-closed:
+			s.setState(StateConnecting)
+
+			reason := "closed"
+			if amqpErr != nil {
+				reason = amqpErr.Error()
+			}
+			s.log.Error(s.name+" connection is lost", slog.String("error", reason))
+		}
+	}
+}
+
+func (s *supervisor) connect() (connection, error) {
+	conn, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setup(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (s *supervisor) setState(state State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = state
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// State returns the current state of the connection.
+func (s *supervisor) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// Ping returns ErrRabbitDisconnected unless the connection is established.
+func (s *supervisor) Ping(context.Context) error {
+	if s.State() != StateConnected {
+		return ErrRabbitDisconnected
+	}
+	return nil
+}
+
+// WaitConnected blocks until the connection is established.
+func (s *supervisor) WaitConnected(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		state, changed := s.state, s.changed
+		s.mu.Unlock()
+
+		switch state {
+		case StateConnected:
+			return nil
+		case StateClosed:
+			return ErrRabbitDisconnected
+		case StateConnecting:
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// close closes the connection and stops reconnecting.
+func (s *supervisor) close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+	<-s.done
+}
+
+// watchChannel closes the connection if the channel is closed by the broker, so the supervisor reopens both.
+func watchChannel(conn connection, ch *amqp.Channel) {
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		if amqpErr, ok := <-closed; ok && amqpErr != nil {
+			_ = conn.Close()
+		}
+	}()
+}
@@ -0,0 +1,173 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// fakeConnection is closed by the broker with lose.
+type fakeConnection struct {
+	mu       sync.Mutex
+	receiver chan *amqp.Error
+	closed   bool
+}
+
+func (f *fakeConnection) Channel() (*amqp.Channel, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.receiver = receiver
+	return receiver
+}
+
+func (f *fakeConnection) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return amqp.ErrClosed
+	}
+	f.closed = true
+	if f.receiver != nil {
+		close(f.receiver)
+	}
+	return nil
+}
+
+func (f *fakeConnection) lose() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	f.receiver <- amqp.ErrClosed
+	close(f.receiver)
+}
+
+// fakeBroker accepts connections after failures of the dial.
+type fakeBroker struct {
+	mu       sync.Mutex
+	failures int
+	conns    []*fakeConnection
+	setups   int
+}
+
+func (b *fakeBroker) dial() (connection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures > 0 {
+		b.failures--
+		return nil, errors.New("connection refused")
+	}
+	conn := &fakeConnection{}
+	b.conns = append(b.conns, conn)
+	return conn, nil
+}
+
+func (b *fakeBroker) setup(connection) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.setups++
+	return nil
+}
+
+func (b *fakeBroker) last() *fakeConnection {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.conns[len(b.conns)-1]
+}
+
+func TestSupervisorReconnect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Error("error connecting test", gomock.Any()).Times(2)
+	logger.EXPECT().Error("test connection is lost", gomock.Any()).Times(1)
+
+	broker := &fakeBroker{failures: 2}
+	sup := newSupervisor("test", logger, broker.dial, broker.setup, time.Millisecond, 2*time.Millisecond)
+	require.Equal(t, StateConnecting, sup.State())
+
+	sup.start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, sup.WaitConnected(ctx))
+	require.Equal(t, StateConnected, sup.State())
+	require.NoError(t, sup.Ping(context.Background()))
+
+	first := broker.last()
+	first.lose()
+
+	require.Eventually(t, func() bool {
+		return sup.State() == StateConnected && broker.last() != first
+	}, time.Second, time.Millisecond)
+
+	// the topology is declared on every connection
+	broker.mu.Lock()
+	require.Equal(t, 2, broker.setups)
+	broker.mu.Unlock()
+
+	sup.close()
+	require.Equal(t, StateClosed, sup.State())
+	require.True(t, broker.last().closed)
+	require.ErrorIs(t, sup.WaitConnected(ctx), ErrRabbitDisconnected)
+}
+
+func TestSupervisorCloseWhileConnecting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Error("error connecting test", gomock.Any()).MinTimes(1)
+
+	broker := &fakeBroker{failures: 1000}
+	sup := newSupervisor("test", logger, broker.dial, broker.setup, time.Hour, time.Hour)
+	sup.start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, sup.WaitConnected(ctx), context.DeadlineExceeded)
+
+	sup.close()
+	require.Equal(t, StateClosed, sup.State())
+}
+
+func TestProducerPublishDisconnected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Error("error connecting test", gomock.Any()).AnyTimes()
+
+	broker := &fakeBroker{failures: 1000}
+	p := &Producer{log: logger}
+	p.sup = newSupervisor("test", logger, broker.dial, p.setup, time.Hour, time.Hour)
+	p.sup.start()
+
+	require.ErrorIs(t, p.Publish(context.Background(), []byte("{}")), ErrRabbitDisconnected)
+	require.Equal(t, StateConnecting, p.State())
+	require.ErrorIs(t, p.Ping(context.Background()), ErrRabbitDisconnected)
+
+	require.NoError(t, p.Shutdown())
+	require.ErrorIs(t, p.Publish(context.Background(), []byte("{}")), ErrRabbitDisconnected)
+}
@@ -1,9 +1,11 @@
 package rabbitmq
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -12,7 +14,7 @@ import (
 	"golang.org/x/exp/slog"
 )
 
-var ErrSenderRabbitNilChannel = errors.New("rabbit sender: channel is nil")
+var ErrSenderRabbitNack = errors.New("rabbit sender: message is not confirmed by broker")
 
 type ConsumerConfig struct {
 	Username           string
@@ -29,34 +31,70 @@ type ConsumerConfig struct {
 	AutoDeleteQueue    bool
 	RoutingKey         string
 	Tag                string
+	// DeadLetterExchange receives notifications which are rejected or run out of attempts,
+	// they are kept in DeadLetterQueue until they are replayed.
+	DeadLetterExchange string
+	DeadLetterQueue    string
+	// RetryAttempts is the max number of deliveries of one notification.
+	RetryAttempts int
+	// RetryMinBackoff is the delay before the first redelivery, it doubles with every next one.
+	RetryMinBackoff time.Duration
+	RetryMaxBackoff time.Duration
+	// ReconnectMinBackoff is the delay before the first reconnection, it doubles with every next one.
+	ReconnectMinBackoff time.Duration
+	ReconnectMaxBackoff time.Duration
 }
 
 type Consumer struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	log     logger.Logger
-	cfg     ConsumerConfig
+	sup *supervisor
+	log logger.Logger
+	cfg ConsumerConfig
+	// publish sends the message and waits until the broker confirms it.
+	publish func(ctx context.Context, exchange, key string, msg amqp.Publishing) error
+
+	mu        sync.RWMutex
+	channel   *amqp.Channel
+	publisher *amqp.Channel
+	consuming bool
+
+	// notifications are received from all connections and closed on Shutdown.
+	notifications chan mq.Notification
+	handlers      sync.WaitGroup
+	closeOnce     sync.Once
 }
 
-func NewSender(cfg ConsumerConfig, log logger.Logger) (*Consumer, error) {
+// NewSender connects to the broker in the background and keeps reconnecting until Shutdown.
+// Consuming is resumed on every new connection.
+func NewSender(cfg ConsumerConfig, log logger.Logger) *Consumer {
 	url := fmt.Sprintf("amqp://%s:%s@%s:%d/", cfg.Username, cfg.Password, cfg.Host, cfg.Port)
 	conf := amqp.Config{
 		Heartbeat: cfg.Heartbeat,
 	}
 
-	log.Info("connecting sender...")
-	conn, err := amqp.DialConfig(url, conf)
-	if err != nil {
-		return nil, err
+	c := &Consumer{
+		log:           log,
+		cfg:           cfg,
+		notifications: make(chan mq.Notification, 10),
 	}
+	c.publish = c.publishConfirmed
+	c.sup = newSupervisor("rabbit sender", log, dialer(url, conf), c.setup,
+		cfg.ReconnectMinBackoff, cfg.ReconnectMaxBackoff)
+	c.sup.start()
+
+	return c
+}
 
-	log.Info("opening sender channel...")
+// setup declares the topology on the new connection and resumes consuming if it has been started.
+func (c *Consumer) setup(conn connection) error {
+	cfg := c.cfg
+
+	c.log.Info("opening sender channel...")
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	log.Info("declaring sender exchange...")
+	c.log.Info("declaring sender exchange...")
 	err = ch.ExchangeDeclare(
 		cfg.ExchangeName,
 		cfg.ExchangeType,
@@ -67,10 +105,10 @@ func NewSender(cfg ConsumerConfig, log logger.Logger) (*Consumer, error) {
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	log.Info("declaring sender queue...")
+	c.log.Info("declaring sender queue...")
 	queue, err := ch.QueueDeclare(
 		cfg.QueueName,
 		cfg.DurableQueue,
@@ -80,10 +118,10 @@ func NewSender(cfg ConsumerConfig, log logger.Logger) (*Consumer, error) {
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	log.Info("binding sender exchange...")
+	c.log.Info("binding sender exchange...")
 	err = ch.QueueBind(
 		queue.Name,
 		cfg.RoutingKey,
@@ -92,24 +130,72 @@ func NewSender(cfg ConsumerConfig, log logger.Logger) (*Consumer, error) {
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	c.log.Info("declaring sender dead letter exchange...")
+	err = declareDeadLetters(ch, cfg)
+	if err != nil {
+		return err
+	}
+
+	c.log.Info("declaring sender retry queues...")
+	err = declareRetryQueues(ch, cfg)
+	if err != nil {
+		return err
+	}
+
+	// retries and dead letters are published through the separate channel with publisher confirms,
+	// so the delivery is acked only after its copy is taken by the broker
+	publisher, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	err = publisher.Confirm(false)
+	if err != nil {
+		return err
 	}
 
-	return &Consumer{
-		conn:    conn,
-		channel: ch,
-		log:     log,
-		cfg:     cfg,
-	}, nil
+	watchChannel(conn, ch)
+	watchChannel(conn, publisher)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.channel = ch
+	c.publisher = publisher
+
+	if c.consuming {
+		return c.consume(ch)
+	}
+	return nil
 }
 
+// Consume starts consuming. The returned channel receives notifications from all connections
+// and is closed on Shutdown.
 func (c *Consumer) Consume() (<-chan mq.Notification, error) {
-	if c.channel == nil {
-		return nil, ErrSenderRabbitNilChannel
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.consuming {
+		return c.notifications, nil
+	}
+	c.consuming = true
+
+	// otherwise consuming is started by setup when the connection is established
+	if c.channel != nil && c.sup.State() == StateConnected {
+		if err := c.consume(c.channel); err != nil {
+			c.consuming = false
+			return nil, err
+		}
 	}
 
+	return c.notifications, nil
+}
+
+func (c *Consumer) consume(ch *amqp.Channel) error {
 	c.log.Info("starting consuming...")
-	deliveries, err := c.channel.Consume(
+	deliveries, err := ch.Consume(
 		c.cfg.QueueName,
 		c.cfg.Tag,
 		false,
@@ -120,48 +206,101 @@ func (c *Consumer) Consume() (<-chan mq.Notification, error) {
 	)
 	if err != nil {
 		c.log.Error("error while starting consuming", slog.String("error", err.Error()))
-		return nil, err
+		return err
 	}
 
-	return handle(deliveries), nil
+	c.handlers.Add(1)
+	go c.handle(deliveries)
+
+	return nil
+}
+
+// State returns the state of the connection to the broker.
+func (c *Consumer) State() State {
+	return c.sup.State()
+}
+
+// Ping returns ErrRabbitDisconnected unless the consumer is connected to the broker, it is the readiness check.
+func (c *Consumer) Ping(ctx context.Context) error {
+	return c.sup.Ping(ctx)
+}
+
+// WaitConnected blocks until the consumer is connected to the broker.
+func (c *Consumer) WaitConnected(ctx context.Context) error {
+	return c.sup.WaitConnected(ctx)
 }
 
 func (c *Consumer) Shutdown() error {
-	c.log.Info("closing deliveries...")
-	err := c.channel.Cancel(c.cfg.Tag, true)
-	if err != nil {
-		return err
+	c.mu.RLock()
+	ch := c.channel
+	c.mu.RUnlock()
+
+	var err error
+	if ch != nil && c.sup.State() == StateConnected {
+		c.log.Info("closing deliveries...")
+		err = ch.Cancel(c.cfg.Tag, true)
 	}
+
 	c.log.Info("closing sender connection...")
-	err = c.conn.Close()
-	if err != nil {
-		return err
-	}
+	c.sup.close()
 
-	return nil
+	c.closeOnce.Do(func() {
+		c.handlers.Wait()
+		close(c.notifications)
+	})
+
+	return err
 }
 
-func handle(deliveries <-chan amqp.Delivery) chan mq.Notification {
-	notifications := make(chan mq.Notification, 10)
+// handle passes every delivery to the caller which settles it after the notification is sent.
+// Deliveries are closed with their channel, unsettled ones are redelivered by the broker.
+func (c *Consumer) handle(deliveries <-chan amqp.Delivery) {
+	defer c.handlers.Done()
 
-	go func() {
-		for delivery := range deliveries {
-			var msg mq.Message
-			err := json.Unmarshal(delivery.Body, &msg)
+	for d := range deliveries {
+		var msg mq.Message
+		err := json.Unmarshal(d.Body, &msg)
+		if msg.ID == "" {
+			msg.ID = d.MessageId
+		}
 
-			if err == nil {
-				delivery.Ack(false)
-			}
+		c.notifications <- mq.Notification{
+			Message:  msg,
+			Err:      err,
+			Delivery: &delivery{consumer: c, d: d},
+		}
+	}
+}
 
-			notification := mq.Notification{
-				Message: msg,
-				Err:     err,
-			}
+// channels returns the channels of the current connection.
+func (c *Consumer) channels() (*amqp.Channel, *amqp.Channel, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-			notifications <- notification
-		}
-		close(notifications)
-	}()
+	if c.channel == nil || c.sup.State() != StateConnected {
+		return nil, nil, ErrRabbitDisconnected
+	}
+	return c.channel, c.publisher, nil
+}
+
+func (c *Consumer) publishConfirmed(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	_, publisher, err := c.channels()
+	if err != nil {
+		return err
+	}
+
+	confirmation, err := publisher.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
 
-	return notifications
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrSenderRabbitNack
+	}
+
+	return nil
 }
@@ -0,0 +1,88 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+// DeadLetters returns up to limit oldest dead letters. They are left in the queue.
+func (c *Consumer) DeadLetters(_ context.Context, limit int) ([]mq.DeadLetter, error) {
+	ch, _, err := c.channels()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		letters []mq.DeadLetter
+		last    uint64
+	)
+	for len(letters) < limit {
+		d, ok, err := ch.Get(c.cfg.DeadLetterQueue, false)
+		if err != nil {
+			return nil, errors.Join(err, requeue(ch, last))
+		}
+		if !ok {
+			break
+		}
+		last = d.DeliveryTag
+
+		letter := mq.DeadLetter{
+			Body:      d.Body,
+			Retries:   retries(d.Headers),
+			Timestamp: d.Timestamp,
+		}
+		letter.Error, _ = d.Headers[errorHeader].(string)
+		_ = json.Unmarshal(d.Body, &letter.Message)
+
+		letters = append(letters, letter)
+	}
+
+	return letters, requeue(ch, last)
+}
+
+// requeue returns all got deliveries up to the tag to the queue.
+func requeue(ch *amqp.Channel, tag uint64) error {
+	if tag == 0 {
+		return nil
+	}
+	return ch.Nack(tag, true, true)
+}
+
+// Replay publishes up to limit oldest dead letters to the exchange of the consumer with the reset
+// number of attempts and returns how many of them are replayed.
+func (c *Consumer) Replay(ctx context.Context, limit int) (int, error) {
+	ch, _, err := c.channels()
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for replayed < limit {
+		d, ok, err := ch.Get(c.cfg.DeadLetterQueue, false)
+		if err != nil {
+			return replayed, err
+		}
+		if !ok {
+			break
+		}
+
+		msg := republishing(d, nil)
+		delete(msg.Headers, retriesHeader)
+		delete(msg.Headers, errorHeader)
+
+		if err := c.publish(ctx, c.cfg.ExchangeName, c.cfg.RoutingKey, msg); err != nil {
+			return replayed, errors.Join(err, d.Nack(false, true))
+		}
+		if err := d.Ack(false); err != nil {
+			return replayed, err
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
+}
@@ -4,13 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
 )
 
-var ErrSchedulerRabbitNilChannel = errors.New("rabbit scheduler: channel is nil")
+var ErrSchedulerRabbitNack = errors.New("rabbit scheduler: message is not confirmed by broker")
 
 type ProducerConfig struct {
 	Username           string
@@ -27,35 +29,56 @@ type ProducerConfig struct {
 	AutoDeleteQueue    bool
 	RoutingKey         string
 	DeliveryMode       int
+	// ReconnectMinBackoff is the delay before the first reconnection, it doubles with every next one.
+	ReconnectMinBackoff time.Duration
+	ReconnectMaxBackoff time.Duration
 }
 
 type Producer struct {
-	conn    *amqp.Connection
+	sup *supervisor
+	log logger.Logger
+	cfg ProducerConfig
+
+	mu      sync.RWMutex
 	channel *amqp.Channel
-	log     logger.Logger
-	cfg     ProducerConfig
 }
 
-func NewProducer(cfg ProducerConfig, log logger.Logger) (*Producer, error) {
+// NewProducer connects to the broker in the background and keeps reconnecting until Shutdown.
+// Publish fails with ErrRabbitDisconnected while there is no connection.
+func NewProducer(cfg ProducerConfig, log logger.Logger) *Producer {
 	url := fmt.Sprintf("amqp://%s:%s@%s:%d/", cfg.Username, cfg.Password, cfg.Host, cfg.Port)
 	conf := amqp.Config{
 		Heartbeat: cfg.Heartbeat,
 	}
-	log.Info("connecting to rabbit scheduler...")
-	conn, err := amqp.DialConfig(url, conf)
-	if err != nil {
-		return nil, err
+
+	p := &Producer{
+		log: log,
+		cfg: cfg,
 	}
+	p.sup = newSupervisor("rabbit scheduler", log, dialer(url, conf), p.setup,
+		cfg.ReconnectMinBackoff, cfg.ReconnectMaxBackoff)
+	p.sup.start()
+
+	return p
+}
 
-	log.Info("connected to rabbit scheduler")
+// setup declares the topology on the new connection.
+func (p *Producer) setup(conn connection) error {
+	cfg := p.cfg
 
-	log.Info("opening scheduler channel...")
+	p.log.Info("opening scheduler channel...")
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	log.Info("declaring scheduler exchange...")
+	// publisher confirms let Publish report whether the broker has taken the message
+	err = ch.Confirm(false)
+	if err != nil {
+		return err
+	}
+
+	p.log.Info("declaring scheduler exchange...")
 	err = ch.ExchangeDeclare(
 		cfg.ExchangeName,
 		cfg.ExchangeType,
@@ -66,7 +89,7 @@ func NewProducer(cfg ProducerConfig, log logger.Logger) (*Producer, error) {
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	queue, err := ch.QueueDeclare(
@@ -78,7 +101,7 @@ func NewProducer(cfg ProducerConfig, log logger.Logger) (*Producer, error) {
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	err = ch.QueueBind(
@@ -89,39 +112,51 @@ func NewProducer(cfg ProducerConfig, log logger.Logger) (*Producer, error) {
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &Producer{
-		conn:    conn,
-		channel: ch,
-		log:     log,
-		cfg:     cfg,
-	}, nil
+	watchChannel(conn, ch)
+
+	p.mu.Lock()
+	p.channel = ch
+	p.mu.Unlock()
+
+	return nil
+}
+
+// State returns the state of the connection to the broker.
+func (p *Producer) State() State {
+	return p.sup.State()
+}
+
+// Ping returns ErrRabbitDisconnected unless the producer is connected to the broker, it is the readiness check.
+func (p *Producer) Ping(ctx context.Context) error {
+	return p.sup.Ping(ctx)
+}
+
+// WaitConnected blocks until the producer is connected to the broker.
+func (p *Producer) WaitConnected(ctx context.Context) error {
+	return p.sup.WaitConnected(ctx)
 }
 
 func (p *Producer) Shutdown() error {
-	p.log.Info("closing scheduler channel...")
-	err := p.channel.Close()
-	if err != nil {
-		return err
-	}
 	p.log.Info("closing scheduler connection...")
-	err = p.conn.Close()
-	if err != nil {
-		return err
-	}
+	p.sup.close()
 
 	return nil
 }
 
 func (p *Producer) Publish(ctx context.Context, body []byte) error {
-	if p.channel == nil {
-		return ErrSchedulerRabbitNilChannel
+	p.mu.RLock()
+	ch := p.channel
+	p.mu.RUnlock()
+
+	if ch == nil || p.sup.State() != StateConnected {
+		return ErrRabbitDisconnected
 	}
 
 	p.log.Info("publishing...")
-	err := p.channel.PublishWithContext(
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(
 		ctx,
 		p.cfg.ExchangeName,
 		p.cfg.RoutingKey,
@@ -131,6 +166,7 @@ func (p *Producer) Publish(ctx context.Context, body []byte) error {
 			ContentType:     "application/json",
 			ContentEncoding: "utf8",
 			DeliveryMode:    uint8(p.cfg.DeliveryMode),
+			MessageId:       mq.DecodeID(body),
 			Timestamp:       time.Now(),
 			Body:            body,
 		},
@@ -139,6 +175,14 @@ func (p *Producer) Publish(ctx context.Context, body []byte) error {
 		return err
 	}
 
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrSchedulerRabbitNack
+	}
+
 	p.log.Info("message is published...")
 
 	return nil
@@ -0,0 +1,162 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+const (
+	// retriesHeader holds the number of redeliveries of the notification.
+	retriesHeader = "x-retries"
+	// errorHeader holds the reason of the last failed delivery.
+	errorHeader = "x-error"
+)
+
+var errUnknownReason = errors.New("unknown reason")
+
+// declareDeadLetters declares the exchange and the queue which keep rejected notifications.
+func declareDeadLetters(ch *amqp.Channel, cfg ConsumerConfig) error {
+	err := ch.ExchangeDeclare(cfg.DeadLetterExchange, amqp.ExchangeDirect, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	queue, err := ch.QueueDeclare(cfg.DeadLetterQueue, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	return ch.QueueBind(queue.Name, cfg.RoutingKey, cfg.DeadLetterExchange, false, nil)
+}
+
+// declareRetryQueues declares the queue for every backoff. Notifications wait in it until their ttl expires
+// and then are dead-lettered back to the exchange of the consumer.
+func declareRetryQueues(ch *amqp.Channel, cfg ConsumerConfig) error {
+	for _, delay := range retryDelays(cfg) {
+		_, err := ch.QueueDeclare(
+			retryQueueName(cfg.QueueName, delay),
+			cfg.DurableQueue,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    cfg.ExchangeName,
+				"x-dead-letter-routing-key": cfg.RoutingKey,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// retryDelay returns the backoff before the redelivery with the number retry starting from 1.
+func retryDelay(cfg ConsumerConfig, retry int) time.Duration {
+	return mq.RetryPolicy{
+		Attempts:   cfg.RetryAttempts,
+		MinBackoff: cfg.RetryMinBackoff,
+		MaxBackoff: cfg.RetryMaxBackoff,
+	}.Delay(retry)
+}
+
+// retryDelays returns the distinct backoffs of all redeliveries.
+func retryDelays(cfg ConsumerConfig) []time.Duration {
+	var delays []time.Duration
+	for retry := 1; retry < cfg.RetryAttempts; retry++ {
+		delay := retryDelay(cfg, retry)
+		if len(delays) > 0 && delays[len(delays)-1] == delay {
+			break
+		}
+		delays = append(delays, delay)
+	}
+	return delays
+}
+
+func retryQueueName(queue string, delay time.Duration) string {
+	return queue + ".retry." + delay.String()
+}
+
+// retries returns the number of redeliveries from the headers of the message.
+func retries(headers amqp.Table) int {
+	switch n := headers[retriesHeader].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
+	}
+}
+
+// delivery settles the notification received by the consumer.
+type delivery struct {
+	consumer *Consumer
+	d        amqp.Delivery
+}
+
+func (d *delivery) Ack() error {
+	return d.d.Ack(false)
+}
+
+func (d *delivery) Retry(ctx context.Context, cause error) error {
+	cfg := d.consumer.cfg
+
+	retry := retries(d.d.Headers) + 1
+	if retry >= cfg.RetryAttempts {
+		return d.Reject(ctx, cause)
+	}
+
+	msg := republishing(d.d, cause)
+	msg.Headers[retriesHeader] = int32(retry)
+
+	// the default exchange routes the message to the queue with the name of the routing key
+	return d.move(ctx, "", retryQueueName(cfg.QueueName, retryDelay(cfg, retry)), msg)
+}
+
+func (d *delivery) Reject(ctx context.Context, cause error) error {
+	msg := republishing(d.d, cause)
+	msg.DeliveryMode = amqp.Persistent
+
+	return d.move(ctx, d.consumer.cfg.DeadLetterExchange, d.consumer.cfg.RoutingKey, msg)
+}
+
+// move publishes the copy of the delivery and acks it. The delivery is returned to the queue
+// if the copy is not published, so the notification is never lost.
+func (d *delivery) move(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	if err := d.consumer.publish(ctx, exchange, key, msg); err != nil {
+		return errors.Join(err, d.d.Nack(false, true))
+	}
+	return d.d.Ack(false)
+}
+
+// republishing returns the copy of the delivery with the reason of the failure.
+func republishing(d amqp.Delivery, cause error) amqp.Publishing {
+	if cause == nil {
+		cause = errUnknownReason
+	}
+
+	headers := make(amqp.Table, len(d.Headers)+1)
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[errorHeader] = cause.Error()
+
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Body:            d.Body,
+	}
+}
@@ -0,0 +1,154 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+var testConsumerConfig = ConsumerConfig{
+	ExchangeName:       "notification",
+	QueueName:          "notification",
+	RoutingKey:         "notification",
+	DeadLetterExchange: "notification.dlx",
+	DeadLetterQueue:    "notification.dlq",
+	RetryAttempts:      5,
+	RetryMinBackoff:    time.Second,
+	RetryMaxBackoff:    5 * time.Second,
+}
+
+type acknowledger struct {
+	acked    bool
+	requeued bool
+}
+
+func (a *acknowledger) Ack(uint64, bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *acknowledger) Nack(_ uint64, _ bool, requeue bool) error {
+	a.requeued = requeue
+	return nil
+}
+
+func (a *acknowledger) Reject(_ uint64, requeue bool) error {
+	a.requeued = requeue
+	return nil
+}
+
+type published struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+func newTestDelivery(retries int32, publishErr error) (*delivery, *acknowledger, *[]published) {
+	var messages []published
+
+	c := &Consumer{cfg: testConsumerConfig}
+	c.publish = func(_ context.Context, exchange, key string, msg amqp.Publishing) error {
+		if publishErr != nil {
+			return publishErr
+		}
+		messages = append(messages, published{exchange: exchange, key: key, msg: msg})
+		return nil
+	}
+
+	ack := &acknowledger{}
+	d := amqp.Delivery{
+		Acknowledger: ack,
+		Headers:      amqp.Table{"trace": "1"},
+		ContentType:  "application/json",
+		MessageId:    "1-1792335600",
+		Body:         []byte(`{"id":"1-1792335600","event_id":"id1"}`),
+	}
+	if retries > 0 {
+		d.Headers[retriesHeader] = retries
+	}
+
+	return &delivery{consumer: c, d: d}, ack, &messages
+}
+
+func TestRetryDelays(t *testing.T) {
+	require.Equal(t, time.Second, retryDelay(testConsumerConfig, 1))
+	require.Equal(t, 2*time.Second, retryDelay(testConsumerConfig, 2))
+	require.Equal(t, 4*time.Second, retryDelay(testConsumerConfig, 3))
+	require.Equal(t, 5*time.Second, retryDelay(testConsumerConfig, 4))
+	require.Equal(t, 5*time.Second, retryDelay(testConsumerConfig, 100))
+
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second},
+		retryDelays(testConsumerConfig))
+
+	cfg := testConsumerConfig
+	cfg.RetryAttempts = 1
+	require.Empty(t, retryDelays(cfg))
+}
+
+func TestDeliveryRetry(t *testing.T) {
+	errTest := errors.New("smtp is unavailable")
+
+	d, ack, messages := newTestDelivery(1, nil)
+	require.NoError(t, d.Retry(context.Background(), errTest))
+
+	require.True(t, ack.acked)
+	require.Len(t, *messages, 1)
+
+	msg := (*messages)[0]
+	require.Equal(t, "", msg.exchange)
+	require.Equal(t, "notification.retry.2s", msg.key)
+	require.Equal(t, int32(2), msg.msg.Headers[retriesHeader])
+	require.Equal(t, errTest.Error(), msg.msg.Headers[errorHeader])
+	require.Equal(t, "1", msg.msg.Headers["trace"])
+	require.Equal(t, d.d.Body, msg.msg.Body)
+	require.Equal(t, d.d.MessageId, msg.msg.MessageId)
+
+	// the headers of the delivery are not changed
+	require.Equal(t, int32(1), d.d.Headers[retriesHeader])
+}
+
+func TestDeliveryRetryExhausted(t *testing.T) {
+	d, ack, messages := newTestDelivery(4, nil)
+	require.NoError(t, d.Retry(context.Background(), errors.New("test")))
+
+	require.True(t, ack.acked)
+	require.Len(t, *messages, 1)
+
+	msg := (*messages)[0]
+	require.Equal(t, "notification.dlx", msg.exchange)
+	require.Equal(t, "notification", msg.key)
+	require.Equal(t, amqp.Persistent, msg.msg.DeliveryMode)
+	require.Equal(t, int32(4), msg.msg.Headers[retriesHeader])
+}
+
+func TestDeliveryReject(t *testing.T) {
+	d, ack, messages := newTestDelivery(0, nil)
+	require.NoError(t, d.Reject(context.Background(), nil))
+
+	require.True(t, ack.acked)
+	require.Len(t, *messages, 1)
+	require.Equal(t, "notification.dlx", (*messages)[0].exchange)
+	require.Equal(t, errUnknownReason.Error(), (*messages)[0].msg.Headers[errorHeader])
+}
+
+func TestDeliveryPublishFailed(t *testing.T) {
+	errPublish := errors.New("connection is closed")
+
+	d, ack, _ := newTestDelivery(0, errPublish)
+	require.ErrorIs(t, d.Retry(context.Background(), errors.New("test")), errPublish)
+
+	// the notification is returned to the queue instead of being lost
+	require.False(t, ack.acked)
+	require.True(t, ack.requeued)
+}
+
+func TestRetries(t *testing.T) {
+	require.Zero(t, retries(nil))
+	require.Zero(t, retries(amqp.Table{retriesHeader: "1"}))
+	require.Equal(t, 2, retries(amqp.Table{retriesHeader: int32(2)}))
+	require.Equal(t, 3, retries(amqp.Table{retriesHeader: int64(3)}))
+}
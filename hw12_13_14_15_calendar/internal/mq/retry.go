@@ -0,0 +1,29 @@
+package mq
+
+import "time"
+
+// RetryPolicy limits redeliveries of failed notifications.
+type RetryPolicy struct {
+	// Attempts is the max number of deliveries of one notification.
+	Attempts int
+	// MinBackoff is the delay before the first redelivery, it doubles with every next one.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Delay returns the backoff before the redelivery with the number retry starting from 1.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.MinBackoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Exhausted reports whether the notification delivered the number of times is not redelivered anymore.
+func (p RetryPolicy) Exhausted(deliveries int) bool {
+	return deliveries >= p.Attempts
+}
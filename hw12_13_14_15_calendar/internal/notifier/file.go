@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+// FileNotifier writes every reminder as the JSON line. It is used for the log channel.
+type FileNotifier struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewFileNotifier appends reminders to the file at path or writes them to stdout if path is empty.
+func NewFileNotifier(path string) (*FileNotifier, error) {
+	if path == "" {
+		return NewWriterNotifier(os.Stdout), nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileNotifier{w: f, closer: f}, nil
+}
+
+func NewWriterNotifier(w io.Writer) *FileNotifier {
+	return &FileNotifier{w: w}
+}
+
+func (f *FileNotifier) Notify(_ context.Context, msg mq.Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err = f.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file, stdout is left open.
+func (f *FileNotifier) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileNotifierNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+
+	n, err := NewFileNotifier(path)
+	require.NoError(t, err)
+
+	messages := []mq.Message{
+		{EventID: "id1", ReminderID: 1, Channel: "log", Title: "first", Date: time.Now().UTC(), UserID: 1},
+		{EventID: "id2", ReminderID: 2, Title: "second", Date: time.Now().UTC(), UserID: 2},
+	}
+	for _, msg := range messages {
+		require.NoError(t, n.Notify(context.Background(), msg))
+	}
+	require.NoError(t, n.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, len(messages))
+	for i, line := range lines {
+		var msg mq.Message
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		require.Equal(t, messages[i].EventID, msg.EventID)
+		require.Equal(t, messages[i].ReminderID, msg.ReminderID)
+		require.True(t, messages[i].Date.Equal(msg.Date))
+	}
+
+	// the file is appended to after the restart
+	n, err = NewFileNotifier(path)
+	require.NoError(t, err)
+	require.NoError(t, n.Notify(context.Background(), messages[0]))
+	require.NoError(t, n.Close())
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 3, bytes.Count(data, []byte("\n")))
+}
@@ -0,0 +1,108 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+var (
+	ErrUnknownChannel = errors.New("no notifier for the channel")
+	ErrNoRecipient    = errors.New("user has no address for the channel")
+)
+
+// Notifier delivers the reminder to the user.
+type Notifier interface {
+	Notify(ctx context.Context, msg mq.Message) error
+}
+
+// Recipient holds the addresses of the user and the channel used for reminders without their own one.
+type Recipient struct {
+	Channel    models.ReminderChannel
+	Email      string
+	WebhookURL string
+}
+
+// Recipients are the known users by their ids.
+type Recipients map[int]Recipient
+
+type Config struct {
+	DefaultChannel models.ReminderChannel
+	// FilePath is the file of the log channel, reminders are written to stdout if it is empty.
+	FilePath string
+	// SMTP enables the email channel if its host is not empty.
+	SMTP       SMTPConfig
+	Webhook    WebhookConfig
+	Channels   map[models.ReminderChannel]ChannelConfig
+	Recipients Recipients
+}
+
+// Router delivers every reminder through the notifier of its channel. Reminders without the channel
+// are delivered through the preferred channel of the user or the default one.
+type Router struct {
+	notifiers      map[models.ReminderChannel]Notifier
+	recipients     Recipients
+	defaultChannel models.ReminderChannel
+}
+
+func NewRouter(notifiers map[models.ReminderChannel]Notifier, recipients Recipients,
+	defaultChannel models.ReminderChannel,
+) *Router {
+	return &Router{
+		notifiers:      notifiers,
+		recipients:     recipients,
+		defaultChannel: defaultChannel,
+	}
+}
+
+func (r *Router) Notify(ctx context.Context, msg mq.Message) error {
+	channel := r.Channel(msg)
+
+	n, ok := r.notifiers[channel]
+	if !ok {
+		return Permanent(fmt.Errorf("%w: %s", ErrUnknownChannel, channel))
+	}
+
+	return n.Notify(ctx, msg)
+}
+
+// Channel returns the channel the message is delivered through.
+func (r *Router) Channel(msg mq.Message) models.ReminderChannel {
+	if msg.Channel != "" {
+		return models.ReminderChannel(msg.Channel)
+	}
+	if recipient, ok := r.recipients[msg.UserID]; ok && recipient.Channel != "" {
+		return recipient.Channel
+	}
+	return r.defaultChannel
+}
+
+// permanentError is not retried because the next attempt fails the same way.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the error as the one which cannot be fixed by retries.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether the error is marked as permanent.
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,73 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier returns errs one by one and records the delivered messages.
+type recordingNotifier struct {
+	messages []mq.Message
+	errs     []error
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, msg mq.Message) error {
+	r.messages = append(r.messages, msg)
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func TestRouterNotify(t *testing.T) {
+	logNotifier := &recordingNotifier{}
+	emailNotifier := &recordingNotifier{}
+	webhookNotifier := &recordingNotifier{}
+
+	router := NewRouter(map[models.ReminderChannel]Notifier{
+		models.ChannelLog:     logNotifier,
+		models.ChannelEmail:   emailNotifier,
+		models.ChannelWebhook: webhookNotifier,
+	}, Recipients{
+		1: {Channel: models.ChannelEmail, Email: "user@example.com"},
+		2: {WebhookURL: "http://localhost"},
+	}, models.ChannelLog)
+
+	ctx := context.Background()
+
+	// the channel of the reminder
+	require.NoError(t, router.Notify(ctx, mq.Message{EventID: "id1", Channel: "webhook", UserID: 1}))
+	// the preferred channel of the user
+	require.NoError(t, router.Notify(ctx, mq.Message{EventID: "id2", UserID: 1}))
+	// the default channel
+	require.NoError(t, router.Notify(ctx, mq.Message{EventID: "id3", UserID: 2}))
+	require.NoError(t, router.Notify(ctx, mq.Message{EventID: "id4", UserID: 3}))
+
+	require.Equal(t, []mq.Message{{EventID: "id1", Channel: "webhook", UserID: 1}}, webhookNotifier.messages)
+	require.Equal(t, []mq.Message{{EventID: "id2", UserID: 1}}, emailNotifier.messages)
+	require.Equal(t, []mq.Message{{EventID: "id3", UserID: 2}, {EventID: "id4", UserID: 3}}, logNotifier.messages)
+
+	err := router.Notify(ctx, mq.Message{Channel: "sms", UserID: 1})
+	require.ErrorIs(t, err, ErrUnknownChannel)
+	require.True(t, IsPermanent(err))
+}
+
+func TestPermanent(t *testing.T) {
+	require.NoError(t, Permanent(nil))
+
+	errTest := errors.New("test")
+	err := fmt.Errorf("wrapped: %w", Permanent(errTest))
+	require.True(t, IsPermanent(err))
+	require.ErrorIs(t, err, errTest)
+	require.Equal(t, "wrapped: test", err.Error())
+
+	require.False(t, IsPermanent(errTest))
+}
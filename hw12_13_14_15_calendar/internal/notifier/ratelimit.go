@@ -0,0 +1,88 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+// Limiter is the token bucket which allows rate events per second with bursts of burst events.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// Wait blocks until the event is allowed or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes the token and returns 0 or returns how long to wait for the next one.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+type rateLimited struct {
+	next    Notifier
+	limiter *Limiter
+}
+
+// WithRateLimit delays deliveries which exceed the rate of the limiter.
+func WithRateLimit(n Notifier, limiter *Limiter) Notifier {
+	return &rateLimited{next: n, limiter: limiter}
+}
+
+func (r *rateLimited) Notify(ctx context.Context, msg mq.Message) error {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return r.next.Notify(ctx, msg)
+}
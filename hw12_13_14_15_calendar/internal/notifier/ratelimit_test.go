@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	now := time.Now()
+
+	l := NewLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	// the burst is allowed at once
+	for i := 0; i < 3; i++ {
+		require.Zero(t, l.reserve())
+	}
+	require.Equal(t, 500*time.Millisecond, l.reserve())
+
+	now = now.Add(500 * time.Millisecond)
+	require.Zero(t, l.reserve())
+	require.Equal(t, 500*time.Millisecond, l.reserve())
+
+	// tokens are not accumulated above the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		require.Zero(t, l.reserve())
+	}
+	require.NotZero(t, l.reserve())
+}
+
+func TestWithRateLimit(t *testing.T) {
+	next := &recordingNotifier{}
+	n := WithRateLimit(next, NewLimiter(50, 1))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, n.Notify(context.Background(), mq.Message{}))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+	require.Len(t, next.messages, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, n.Notify(ctx, mq.Message{}), context.Canceled)
+	require.Len(t, next.messages, 3)
+}
@@ -0,0 +1,70 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+type RetryConfig struct {
+	// Attempts is the max number of deliveries of one reminder, 0 and 1 mean no retries.
+	Attempts int
+	// MinBackoff is the delay after the first failed attempt, it doubles with every next one.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// ChannelConfig limits deliveries through the channel.
+type ChannelConfig struct {
+	Retry RetryConfig
+	// Rate is the max number of deliveries per second, 0 means unlimited.
+	Rate  float64
+	Burst int
+}
+
+// Wrap returns the notifier which retries failed deliveries and keeps the rate of the channel.
+// Every attempt is counted by the rate limit.
+func Wrap(n Notifier, cfg ChannelConfig) Notifier {
+	if cfg.Rate > 0 {
+		n = WithRateLimit(n, NewLimiter(cfg.Rate, cfg.Burst))
+	}
+	if cfg.Retry.Attempts > 1 {
+		n = WithRetry(n, cfg.Retry)
+	}
+	return n
+}
+
+type retrying struct {
+	next Notifier
+	cfg  RetryConfig
+}
+
+// WithRetry repeats failed deliveries with the exponential backoff. Permanent errors are not retried.
+func WithRetry(n Notifier, cfg RetryConfig) Notifier {
+	return &retrying{next: n, cfg: cfg}
+}
+
+func (r *retrying) Notify(ctx context.Context, msg mq.Message) error {
+	delay := r.cfg.MinBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := r.next.Notify(ctx, msg)
+		if err == nil || IsPermanent(err) || attempt >= r.cfg.Attempts {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > r.cfg.MaxBackoff {
+			delay = r.cfg.MaxBackoff
+		}
+	}
+}
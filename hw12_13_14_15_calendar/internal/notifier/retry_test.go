@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRetry(t *testing.T) {
+	errTest := errors.New("test")
+	cfg := RetryConfig{Attempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	testCases := []struct {
+		name     string
+		errs     []error
+		err      error
+		attempts int
+	}{
+		{name: "first attempt", attempts: 1},
+		{name: "after retries", errs: []error{errTest, errTest}, attempts: 3},
+		{name: "attempts are exhausted", errs: []error{errTest, errTest, errTest, nil}, err: errTest, attempts: 3},
+		{name: "permanent error", errs: []error{Permanent(errTest)}, err: errTest, attempts: 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			next := &recordingNotifier{errs: tc.errs}
+
+			err := WithRetry(next, cfg).Notify(context.Background(), mq.Message{EventID: "id1"})
+			if tc.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.err)
+			}
+			require.Len(t, next.messages, tc.attempts)
+		})
+	}
+}
+
+func TestWithRetryContextDone(t *testing.T) {
+	errTest := errors.New("test")
+	next := &recordingNotifier{errs: []error{errTest, errTest}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	cfg := RetryConfig{Attempts: 3, MinBackoff: time.Minute, MaxBackoff: time.Minute}
+
+	start := time.Now()
+	err := WithRetry(next, cfg).Notify(ctx, mq.Message{})
+	require.ErrorIs(t, err, errTest)
+	require.Less(t, time.Since(start), time.Second)
+	require.Len(t, next.messages, 1)
+}
+
+func TestWrap(t *testing.T) {
+	next := &recordingNotifier{}
+	require.Same(t, next, Wrap(next, ChannelConfig{}))
+
+	errTest := errors.New("test")
+	next = &recordingNotifier{errs: []error{errTest}}
+
+	n := Wrap(next, ChannelConfig{
+		Retry: RetryConfig{Attempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		Rate:  1000,
+		Burst: 1,
+	})
+	require.NoError(t, n.Notify(context.Background(), mq.Message{}))
+	require.Len(t, next.messages, 2)
+}
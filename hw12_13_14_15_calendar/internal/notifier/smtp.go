@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+const defaultSMTPTimeout = 10 * time.Second
+
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username enables PLAIN authentication if it is not empty.
+	Username string
+	Password string
+	From     string
+	// Timeout limits the whole SMTP session unless the context has an earlier deadline.
+	Timeout time.Duration
+}
+
+// SMTPNotifier sends reminders by email to the address of the user. STARTTLS is used if the server supports it.
+type SMTPNotifier struct {
+	cfg        SMTPConfig
+	recipients Recipients
+}
+
+func NewSMTPNotifier(cfg SMTPConfig, recipients Recipients) *SMTPNotifier {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSMTPTimeout
+	}
+	return &SMTPNotifier{
+		cfg:        cfg,
+		recipients: recipients,
+	}
+}
+
+func (s *SMTPNotifier) Notify(ctx context.Context, msg mq.Message) error {
+	to := s.recipients[msg.UserID].Email
+	if to == "" {
+		return Permanent(fmt.Errorf("%w: user %d, email", ErrNoRecipient, msg.UserID))
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(s.cfg.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if err := s.send(c, to, msg); err != nil {
+		return smtpError(err)
+	}
+
+	return c.Quit()
+}
+
+func (s *SMTPNotifier) send(c *smtp.Client, to string, msg mq.Message) error {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(composeEmail(s.cfg.From, to, msg)); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// composeEmail returns the plain text email about the reminder.
+func composeEmail(from, to string, msg mq.Message) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", "Reminder: "+msg.Title) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.NewReplacer("\r", "", "\n", " ").Replace(msg.Title))
+	b.WriteString(" starts at " + msg.Date.Format(time.RFC3339) + ".\r\n")
+
+	return []byte(b.String())
+}
+
+// smtpError marks rejections with 5xx codes as permanent.
+func smtpError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package notifier
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedEmail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpSink is the minimal SMTP server which receives emails without STARTTLS and AUTH.
+// It rejects recipients listed in reject with 550.
+type smtpSink struct {
+	ln     net.Listener
+	reject map[string]struct{}
+	emails chan receivedEmail
+}
+
+func newSMTPSink(t *testing.T, reject ...string) *smtpSink {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpSink{
+		ln:     ln,
+		reject: make(map[string]struct{}),
+		emails: make(chan receivedEmail, 10),
+	}
+	for _, addr := range reject {
+		s.reject["<"+addr+">"] = struct{}{}
+	}
+
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+
+	return s
+}
+
+func (s *smtpSink) config() SMTPConfig {
+	addr := s.ln.Addr().(*net.TCPAddr)
+	return SMTPConfig{
+		Host:    addr.IP.String(),
+		Port:    addr.Port,
+		From:    "calendar@example.com",
+		Timeout: time.Second,
+	}
+}
+
+func (s *smtpSink) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+
+	c := textproto.NewConn(conn)
+	var email receivedEmail
+
+	_ = c.PrintfLine("220 localhost ready")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			_ = c.PrintfLine("250 localhost")
+		case "MAIL":
+			email = receivedEmail{from: strings.TrimPrefix(arg, "FROM:")}
+			_ = c.PrintfLine("250 OK")
+		case "RCPT":
+			to := strings.TrimPrefix(arg, "TO:")
+			if _, ok := s.reject[to]; ok {
+				_ = c.PrintfLine("550 no such user")
+				continue
+			}
+			email.to = append(email.to, to)
+			_ = c.PrintfLine("250 OK")
+		case "DATA":
+			_ = c.PrintfLine("354 go ahead")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			email.data = string(data)
+			s.emails <- email
+			_ = c.PrintfLine("250 OK")
+		case "QUIT":
+			_ = c.PrintfLine("221 bye")
+			return
+		default:
+			_ = c.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPNotifierNotify(t *testing.T) {
+	sink := newSMTPSink(t)
+
+	n := NewSMTPNotifier(sink.config(), Recipients{1: {Email: "user@example.com"}})
+
+	msg := mq.Message{
+		EventID: "id1",
+		Title:   "meeting",
+		Date:    time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC),
+		UserID:  1,
+	}
+	require.NoError(t, n.Notify(context.Background(), msg))
+
+	select {
+	case email := <-sink.emails:
+		require.Equal(t, "<calendar@example.com>", email.from)
+		require.Equal(t, []string{"<user@example.com>"}, email.to)
+		require.Contains(t, email.data, "To: user@example.com\n")
+		require.Contains(t, email.data, "Subject: Reminder: meeting\n")
+		require.Contains(t, email.data, "meeting starts at 2026-10-18T15:00:00Z.")
+	case <-time.After(time.Second):
+		t.Fatal("email is not received")
+	}
+}
+
+func TestSMTPNotifierNotifyErrors(t *testing.T) {
+	sink := newSMTPSink(t, "unknown@example.com")
+
+	n := NewSMTPNotifier(sink.config(), Recipients{
+		1: {Email: "unknown@example.com"},
+		2: {WebhookURL: "http://localhost"},
+	})
+
+	t.Run("rejected recipient", func(t *testing.T) {
+		err := n.Notify(context.Background(), mq.Message{UserID: 1})
+		require.Error(t, err)
+		require.True(t, IsPermanent(err))
+	})
+
+	t.Run("no email", func(t *testing.T) {
+		err := n.Notify(context.Background(), mq.Message{UserID: 2})
+		require.ErrorIs(t, err, ErrNoRecipient)
+		require.True(t, IsPermanent(err))
+	})
+
+	t.Run("server is unavailable", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		port := ln.Addr().(*net.TCPAddr).Port
+		ln.Close()
+
+		cfg := sink.config()
+		cfg.Port = port
+
+		err = NewSMTPNotifier(cfg, Recipients{1: {Email: "user@example.com"}}).
+			Notify(context.Background(), mq.Message{UserID: 1})
+		require.Error(t, err)
+		require.False(t, IsPermanent(err))
+	})
+}
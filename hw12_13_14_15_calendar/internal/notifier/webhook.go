@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+)
+
+const (
+	// SignatureHeader holds "sha256=" and the hex HMAC-SHA256 of the timestamp, "." and the body.
+	SignatureHeader = "X-Calendar-Signature"
+	// TimestampHeader holds the unix time of the request, receivers reject old ones to prevent replays.
+	TimestampHeader = "X-Calendar-Timestamp"
+
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+type WebhookConfig struct {
+	// Secret signs requests, they are not signed if it is empty.
+	Secret  string
+	Timeout time.Duration
+}
+
+// WebhookNotifier posts reminders as JSON to the webhook URL of the user.
+type WebhookNotifier struct {
+	client     *http.Client
+	secret     []byte
+	recipients Recipients
+	now        func() time.Time
+}
+
+func NewWebhookNotifier(cfg WebhookConfig, recipients Recipients) *WebhookNotifier {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	return &WebhookNotifier{
+		client:     &http.Client{Timeout: cfg.Timeout},
+		secret:     []byte(cfg.Secret),
+		recipients: recipients,
+		now:        time.Now,
+	}
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, msg mq.Message) error {
+	url := w.recipients[msg.UserID].WebhookURL
+	if url == "" {
+		return Permanent(fmt.Errorf("%w: user %d, webhook", ErrNoRecipient, msg.UserID))
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(w.secret) > 0 {
+		timestamp := strconv.FormatInt(w.now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err = fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	// the receiver rejects the request itself, so it is not repeated
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+// Sign returns the value of SignatureHeader for the request.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifierNotify(t *testing.T) {
+	secret := "secret"
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+
+	received := make(chan mq.Message, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		timestamp := r.Header.Get(TimestampHeader)
+		if timestamp != "1792335600" || r.Header.Get(SignatureHeader) != Sign([]byte(secret), timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var msg mq.Message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- msg
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	msg := mq.Message{
+		EventID:    "id1",
+		ReminderID: 2,
+		Channel:    "webhook",
+		Title:      "meeting",
+		Date:       now.Add(time.Hour),
+		UserID:     1,
+	}
+
+	t.Run("signed", func(t *testing.T) {
+		n := NewWebhookNotifier(WebhookConfig{Secret: secret}, Recipients{1: {WebhookURL: srv.URL}})
+		n.now = func() time.Time { return now }
+
+		require.NoError(t, n.Notify(context.Background(), msg))
+		require.Equal(t, msg, <-received)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		n := NewWebhookNotifier(WebhookConfig{Secret: "other"}, Recipients{1: {WebhookURL: srv.URL}})
+		n.now = func() time.Time { return now }
+
+		err := n.Notify(context.Background(), msg)
+		require.Error(t, err)
+		require.True(t, IsPermanent(err))
+	})
+
+	t.Run("no webhook", func(t *testing.T) {
+		n := NewWebhookNotifier(WebhookConfig{Secret: secret}, Recipients{1: {Email: "user@example.com"}})
+
+		err := n.Notify(context.Background(), msg)
+		require.ErrorIs(t, err, ErrNoRecipient)
+		require.True(t, IsPermanent(err))
+	})
+}
+
+func TestWebhookNotifierNotifyStatuses(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    int
+		err       bool
+		permanent bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "not found", status: http.StatusNotFound, err: true, permanent: true},
+		{name: "too many requests", status: http.StatusTooManyRequests, err: true},
+		{name: "request timeout", status: http.StatusRequestTimeout, err: true},
+		{name: "internal error", status: http.StatusInternalServerError, err: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				// requests are not signed without the secret
+				if r.Header.Get(SignatureHeader) != "" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			n := NewWebhookNotifier(WebhookConfig{}, Recipients{1: {WebhookURL: srv.URL}})
+
+			err := n.Notify(context.Background(), mq.Message{UserID: 1})
+			if !tc.err {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Equal(t, tc.permanent, IsPermanent(err))
+			}
+			require.Equal(t, int32(1), requests.Load())
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/metrics"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	publishedTotal = metrics.NewCounter("calendar_notifications_published_total",
+		"Outbox messages confirmed by the broker.")
+	publishFailedTotal = metrics.NewCounter("calendar_notifications_publish_failed_total",
+		"Failed attempts to publish outbox messages.")
+)
+
+// Publisher sends the message and returns nil only when the broker has confirmed it.
+type Publisher interface {
+	Publish(ctx context.Context, body []byte) error
+}
+
+type Config struct {
+	// Interval is the pause between polls of the outbox.
+	Interval time.Duration
+	// BatchSize is the max number of messages published per poll.
+	BatchSize int
+	// Lease hides the claimed messages from other relays, they are published again
+	// after the lease expires if the relay has failed to complete or retry them.
+	Lease time.Duration
+	// MinBackoff is the delay after the first failed attempt, it doubles with every next one.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Relay publishes messages from the outbox. A message is deleted after the broker confirms it,
+// otherwise it is postponed with the exponential backoff, so delivery is at least once.
+// Several relays can share the outbox because every message is leased by one of them.
+type Relay struct {
+	storage   storage.OutboxStorage
+	publisher Publisher
+	log       logger.Logger
+	cfg       Config
+	now       func() time.Time
+}
+
+func NewRelay(storage storage.OutboxStorage, publisher Publisher, log logger.Logger, cfg Config) *Relay {
+	return &Relay{
+		storage:   storage,
+		publisher: publisher,
+		log:       log,
+		cfg:       cfg,
+		now:       time.Now,
+	}
+}
+
+// Run flushes the outbox every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Flush(ctx); err != nil {
+				r.log.Error("error flushing outbox", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Flush publishes one batch of due messages and returns how many of them were delivered.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	messages, err := r.storage.ClaimOutboxMessages(ctx, models.OutboxClaim{
+		Now:   r.now(),
+		Lease: r.cfg.Lease,
+		Limit: r.cfg.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var published int
+
+	for _, message := range messages {
+		if err := r.publisher.Publish(ctx, message.Payload); err != nil {
+			publishFailedTotal.Inc()
+			r.retry(ctx, message, err)
+			continue
+		}
+		publishedTotal.Inc()
+
+		// the message is delivered, a failed delete only leads to a duplicate
+		if err := r.storage.CompleteOutboxMessage(ctx, message.ID); err != nil {
+			r.log.Error("error completing outbox message",
+				slog.Int64("id", message.ID),
+				slog.String("error", err.Error()))
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+func (r *Relay) retry(ctx context.Context, message models.OutboxMessage, publishErr error) {
+	next := r.now().Add(r.backoff(message.Attempts))
+
+	r.log.Error("error publishing outbox message",
+		slog.Int64("id", message.ID),
+		slog.String("event id", message.EventID),
+		slog.Int("attempts", message.Attempts+1),
+		slog.Time("next attempt", next),
+		slog.String("error", publishErr.Error()))
+
+	if err := r.storage.RetryOutboxMessage(ctx, message.ID, next, publishErr.Error()); err != nil {
+		r.log.Error("error postponing outbox message",
+			slog.Int64("id", message.ID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// backoff returns the delay before the next attempt when attempts have already failed.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.cfg.MinBackoff
+	for i := 0; i < attempts && delay < r.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.cfg.MaxBackoff {
+		delay = r.cfg.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,210 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	memorystorage "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var errBroker = errors.New("broker is unavailable")
+
+// publisher records published bodies and fails while err is set.
+type publisher struct {
+	mu     sync.Mutex
+	bodies [][]byte
+	err    error
+}
+
+func (p *publisher) Publish(_ context.Context, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.bodies = append(p.bodies, body)
+	return nil
+}
+
+func TestRelayFlush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	ctx := context.Background()
+	st := memorystorage.NewStorageMemory()
+	scheduleEvents(t, st, "id1", "id2")
+
+	pub := &publisher{}
+	relay := NewRelay(st, pub, logger, Config{
+		Interval:   time.Second,
+		BatchSize:  10,
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+	})
+
+	before := publishedTotal.Value()
+
+	published, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, published)
+	require.Equal(t, before+2, publishedTotal.Value())
+	require.Equal(t, [][]byte{[]byte("id1"), []byte("id2")}, pub.bodies)
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: time.Now().Add(time.Hour), Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
+
+func TestRelayFlushRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	ctx := context.Background()
+	st := memorystorage.NewStorageMemory()
+	scheduleEvents(t, st, "id1")
+
+	now := time.Now()
+	pub := &publisher{err: errBroker}
+	relay := NewRelay(st, pub, logger, Config{
+		Interval:   time.Second,
+		BatchSize:  10,
+		MinBackoff: time.Second,
+		MaxBackoff: 3 * time.Second,
+	})
+	relay.now = func() time.Time { return now }
+
+	failed := publishFailedTotal.Value()
+
+	// every failure doubles the delay until it reaches the max backoff
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		published, err := relay.Flush(ctx)
+		require.NoError(t, err)
+		require.Zero(t, published)
+
+		// the message isn't due until the backoff passes
+		published, err = relay.Flush(ctx)
+		require.NoError(t, err)
+		require.Zero(t, published)
+
+		now = now.Add(delay)
+	}
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: now, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, 4, messages[0].Attempts)
+	require.Equal(t, errBroker.Error(), messages[0].LastError)
+	require.Equal(t, failed+4, publishFailedTotal.Value())
+
+	pub.err = nil
+
+	published, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, published)
+	require.Equal(t, [][]byte{[]byte("id1")}, pub.bodies)
+}
+
+// blockingPublisher holds the first publish until release is closed.
+type blockingPublisher struct {
+	publisher
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, body []byte) error {
+	p.once.Do(func() {
+		close(p.started)
+		<-p.release
+	})
+	return p.publisher.Publish(ctx, body)
+}
+
+func TestRelayFlushConcurrent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	ctx := context.Background()
+	st := memorystorage.NewStorageMemory()
+	scheduleEvents(t, st, "id1", "id2", "id3", "id4", "id5")
+
+	cfg := Config{Interval: time.Second, BatchSize: 3, Lease: time.Minute, MinBackoff: time.Second, MaxBackoff: time.Minute}
+	slow := &blockingPublisher{started: make(chan struct{}), release: make(chan struct{})}
+	fast := &publisher{}
+
+	var (
+		wg            sync.WaitGroup
+		slowPublished int
+		slowErr       error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		slowPublished, slowErr = NewRelay(st, slow, logger, cfg).Flush(ctx)
+	}()
+
+	// the other relay flushes while the first one is publishing its batch
+	<-slow.started
+	published, err := NewRelay(st, fast, logger, cfg).Flush(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, published)
+	require.Equal(t, [][]byte{[]byte("id4"), []byte("id5")}, fast.bodies)
+
+	close(slow.release)
+	wg.Wait()
+
+	require.NoError(t, slowErr)
+	require.Equal(t, 3, slowPublished)
+	require.Equal(t, [][]byte{[]byte("id1"), []byte("id2"), []byte("id3")}, slow.bodies)
+}
+
+func TestRelayFlushCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	relay := NewRelay(memorystorage.NewStorageMemory(), &publisher{}, logger, Config{BatchSize: 10})
+
+	_, err := relay.Flush(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// scheduleEvents creates events with ids and puts the ids to the outbox as payloads one by one.
+func scheduleEvents(t *testing.T, st *memorystorage.Storage, ids ...string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	for _, id := range ids {
+		_, err := st.CreateEvent(ctx, models.Event{
+			ID:        id,
+			Date:      time.Now().Add(time.Hour),
+			Reminders: []models.Reminder{{Before: time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}},
+		}, models.EventOptions{AllowOverlap: true})
+		require.NoError(t, err)
+
+		event, err := st.GetEventByID(ctx, id)
+		require.NoError(t, err)
+		require.NoError(t, st.ScheduleNotification(ctx, event.Reminders[0].ID, []byte(id)))
+	}
+}
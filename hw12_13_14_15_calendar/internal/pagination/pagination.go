@@ -0,0 +1,153 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+)
+
+const cursorSeparator = "|"
+
+var ErrInvalidCursor = errors.New("cursor is invalid")
+
+// EncodeCursor returns the opaque representation of the cursor.
+func EncodeCursor(c models.Cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Date.UTC().Format(time.RFC3339Nano) + cursorSeparator + c.ID))
+}
+
+// DecodeCursor parses the cursor returned by EncodeCursor.
+func DecodeCursor(s string) (models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return models.Cursor{}, ErrInvalidCursor
+	}
+
+	dateStr, id, ok := strings.Cut(string(data), cursorSeparator)
+	if !ok || id == "" {
+		return models.Cursor{}, ErrInvalidCursor
+	}
+
+	date, err := time.Parse(time.RFC3339Nano, dateStr)
+	if err != nil {
+		return models.Cursor{}, ErrInvalidCursor
+	}
+
+	return models.Cursor{Date: date, ID: id}, nil
+}
+
+// Window narrows [rng.From, rng.To) to the events which can follow the cursor.
+// The returned bounds are inclusive, so they can be passed to recurrence.Expand.
+func Window(rng models.EventRange) (time.Time, time.Time) {
+	from, to := rng.From, rng.To.Add(-time.Nanosecond)
+	if rng.After == nil {
+		return from, to
+	}
+
+	if rng.Order == models.SortDesc {
+		if rng.After.Date.Before(to) {
+			to = rng.After.Date
+		}
+	} else if rng.After.Date.After(from) {
+		from = rng.After.Date
+	}
+
+	return from, to
+}
+
+// Matches reports whether the title or the description of the event contains the query ignoring case.
+func Matches(event models.Event, query string) bool {
+	if query == "" {
+		return true
+	}
+	query = strings.ToLower(query)
+	return strings.Contains(strings.ToLower(event.Title), query) ||
+		strings.Contains(strings.ToLower(event.Description), query)
+}
+
+// Paginate sorts events by date and ID, drops events outside of the range and
+// events up to the cursor and cuts the page of rng.Limit events.
+func Paginate(events []models.Event, rng models.EventRange) models.EventPage {
+	desc := rng.Order == models.SortDesc
+
+	filtered := make([]models.Event, 0, len(events))
+	for _, event := range events {
+		if event.Date.Before(rng.From) || !event.Date.Before(rng.To) {
+			continue
+		}
+		if rng.After != nil && !follows(event, *rng.After, desc) {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		if desc {
+			return less(filtered[j], filtered[i])
+		}
+		return less(filtered[i], filtered[j])
+	})
+
+	if rng.Limit <= 0 || len(filtered) <= rng.Limit {
+		return models.EventPage{Events: filtered}
+	}
+
+	page := filtered[:rng.Limit]
+	last := page[len(page)-1]
+
+	return models.EventPage{
+		Events: page,
+		Next:   &models.Cursor{Date: last.Date, ID: last.ID},
+	}
+}
+
+// Page cuts the page of rng.Limit events from the single events and the occurrences of the series.
+// single must hold at least the first rng.Limit+1 non-recurring events which follow the cursor in the range
+// in the chosen order. Series are expanded only as far as the page can reach.
+func Page(single, series []models.Event, rng models.EventRange) models.EventPage {
+	desc := rng.Order == models.SortDesc
+
+	head, limit := rng, 0
+	if rng.Limit > 0 {
+		head.Limit = rng.Limit + 1
+		// one more occurrence of the series can be skipped at the date of the cursor
+		limit = rng.Limit + 2
+	}
+	events := Paginate(single, head).Events
+
+	from, to := Window(rng)
+	// the page cannot reach beyond the last single event it can contain
+	if rng.Limit > 0 && len(events) > rng.Limit {
+		if desc {
+			from = events[rng.Limit-1].Date
+		} else {
+			to = events[rng.Limit-1].Date
+		}
+	}
+
+	for _, event := range series {
+		events = append(events, recurrence.ExpandLimit(event, from, to, limit, desc)...)
+	}
+
+	return Paginate(events, rng)
+}
+
+func less(a, b models.Event) bool {
+	if !a.Date.Equal(b.Date) {
+		return a.Date.Before(b.Date)
+	}
+	return a.ID < b.ID
+}
+
+// follows reports whether the event comes after the cursor in the chosen order.
+func follows(event models.Event, cursor models.Cursor, desc bool) bool {
+	last := models.Event{Date: cursor.Date, ID: cursor.ID}
+	if desc {
+		return less(event, last)
+	}
+	return less(last, event)
+}
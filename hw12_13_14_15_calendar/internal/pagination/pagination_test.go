@@ -0,0 +1,135 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := models.Cursor{
+		Date: time.Date(2023, 7, 24, 10, 0, 0, 123, time.UTC),
+		ID:   "a|b",
+	}
+
+	decoded, err := DecodeCursor(EncodeCursor(cursor))
+	require.NoError(t, err)
+	require.Equal(t, cursor, decoded)
+
+	for _, invalid := range []string{"!", EncodeCursor(models.Cursor{Date: cursor.Date}), "MjAyMw"} {
+		_, err := DecodeCursor(invalid)
+		require.ErrorIs(t, err, ErrInvalidCursor)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	last := time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		rng          models.EventRange
+		expectedFrom time.Time
+		expectedTo   time.Time
+	}{
+		{
+			name:         "without cursor",
+			rng:          models.EventRange{From: from, To: to},
+			expectedFrom: from,
+			expectedTo:   to.Add(-time.Nanosecond),
+		},
+		{
+			name:         "ascending",
+			rng:          models.EventRange{From: from, To: to, After: &models.Cursor{Date: last, ID: "1"}},
+			expectedFrom: last,
+			expectedTo:   to.Add(-time.Nanosecond),
+		},
+		{
+			name: "descending",
+			rng: models.EventRange{From: from, To: to, Order: models.SortDesc,
+				After: &models.Cursor{Date: last, ID: "1"}},
+			expectedFrom: from,
+			expectedTo:   last,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			actualFrom, actualTo := Window(tc.rng)
+			require.Equal(t, tc.expectedFrom, actualFrom)
+			require.Equal(t, tc.expectedTo, actualTo)
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{ID: "b", Date: date},
+		{ID: "c", Date: date.Add(time.Hour)},
+		{ID: "a", Date: date},
+		{ID: "d", Date: date.Add(-time.Hour)},
+	}
+
+	rng := models.EventRange{From: date, To: date.Add(2 * time.Hour), Limit: 2}
+
+	page := Paginate(events, rng)
+	require.Equal(t, []models.Event{events[2], events[0]}, page.Events)
+	require.Equal(t, &models.Cursor{Date: date, ID: "b"}, page.Next)
+
+	rng.After = page.Next
+	page = Paginate(events, rng)
+	require.Equal(t, []models.Event{events[1]}, page.Events)
+	require.Nil(t, page.Next)
+}
+
+func TestPage(t *testing.T) {
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	single := []models.Event{
+		{ID: "b", Date: date},
+		{ID: "c", Date: date.Add(26 * time.Hour)},
+		{ID: "a", Date: date.Add(24 * time.Hour)},
+		{ID: "d", Date: date.AddDate(0, 0, -1)},
+	}
+	series := []models.Event{
+		{ID: "endless", Date: date.AddDate(0, 0, -3), Recurrence: &models.Recurrence{
+			Frequency: models.FrequencyDaily, Interval: 1,
+		}},
+		// starts between the single events of the same day
+		{ID: "short", Date: date.Add(25 * time.Hour), Recurrence: &models.Recurrence{
+			Frequency: models.FrequencyDaily, Interval: 1, Count: 2,
+		}},
+	}
+
+	for _, order := range []models.SortOrder{models.SortAsc, models.SortDesc} {
+		rng := models.EventRange{From: date, To: date.AddDate(0, 0, 3), Order: order}
+
+		var all []models.Event
+		for _, event := range append(append([]models.Event(nil), single...), series...) {
+			all = append(all, recurrence.Expand(event, rng.From, rng.To)...)
+		}
+		expected := Paginate(all, rng).Events
+
+		// pages of every size put together list the same events
+		for limit := 1; limit <= len(expected)+1; limit++ {
+			rng.Limit, rng.After = limit, nil
+
+			var actual []models.Event
+			for {
+				page := Page(single, series, rng)
+				require.LessOrEqual(t, len(page.Events), limit)
+				actual = append(actual, page.Events...)
+				if page.Next == nil {
+					break
+				}
+				rng.After = page.Next
+			}
+			require.Equal(t, expected, actual, "order %s, limit %d", order, limit)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package period
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultWeekStart is the first day of the ISO 8601 week.
+const DefaultWeekStart = time.Monday
+
+var ErrInvalidWeekday = errors.New("week start must be a weekday name")
+
+// Day returns [from, to) of the calendar day containing date in loc.
+// The day can be shorter or longer than 24 hours when the offset of loc changes.
+func Day(date time.Time, loc *time.Location) (time.Time, time.Time) {
+	from := midnight(date, loc)
+	return from, from.AddDate(0, 0, 1)
+}
+
+// Week returns [from, to) of the calendar week containing date in loc which starts on weekStart.
+func Week(date time.Time, loc *time.Location, weekStart time.Weekday) (time.Time, time.Time) {
+	from := midnight(date, loc)
+	from = from.AddDate(0, 0, -((int(from.Weekday()) - int(weekStart) + 7) % 7))
+	return from, from.AddDate(0, 0, 7)
+}
+
+// Month returns [from, to) of the calendar month containing date in loc.
+func Month(date time.Time, loc *time.Location) (time.Time, time.Time) {
+	date = date.In(loc)
+	from := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, loc)
+	return from, from.AddDate(0, 1, 0)
+}
+
+// ParseWeekday parses the full or three-letter English name of the weekday ignoring case.
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(s)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+	return 0, ErrInvalidWeekday
+}
+
+func midnight(date time.Time, loc *time.Location) time.Time {
+	date = date.In(loc)
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
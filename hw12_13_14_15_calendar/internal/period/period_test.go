@@ -0,0 +1,125 @@
+package period
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDay(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		date         time.Time
+		loc          *time.Location
+		expectedFrom time.Time
+		expectedTo   time.Time
+	}{
+		{
+			name:         "utc",
+			date:         time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
+			loc:          time.UTC,
+			expectedFrom: time.Date(2023, 7, 22, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2023, 7, 23, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "previous day in the time zone",
+			date:         time.Date(2023, 7, 22, 2, 0, 0, 0, time.UTC),
+			loc:          newYork,
+			expectedFrom: time.Date(2023, 7, 21, 4, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2023, 7, 22, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "23 hours day",
+			date:         time.Date(2023, 3, 12, 12, 0, 0, 0, newYork),
+			loc:          newYork,
+			expectedFrom: time.Date(2023, 3, 12, 5, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2023, 3, 13, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "25 hours day",
+			date:         time.Date(2023, 11, 5, 12, 0, 0, 0, newYork),
+			loc:          newYork,
+			expectedFrom: time.Date(2023, 11, 5, 4, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2023, 11, 6, 5, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			from, to := Day(tc.date, tc.loc)
+			require.True(t, tc.expectedFrom.Equal(from), from)
+			require.True(t, tc.expectedTo.Equal(to), to)
+		})
+	}
+}
+
+func TestWeek(t *testing.T) {
+	date := time.Date(2023, 7, 23, 12, 0, 0, 0, time.UTC) // sunday
+
+	testCases := []struct {
+		weekStart    time.Weekday
+		expectedFrom time.Time
+	}{
+		{weekStart: time.Monday, expectedFrom: time.Date(2023, 7, 17, 0, 0, 0, 0, time.UTC)},
+		{weekStart: time.Sunday, expectedFrom: time.Date(2023, 7, 23, 0, 0, 0, 0, time.UTC)},
+		{weekStart: time.Saturday, expectedFrom: time.Date(2023, 7, 22, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.weekStart.String(), func(t *testing.T) {
+			from, to := Week(date, time.UTC, tc.weekStart)
+			require.Equal(t, tc.expectedFrom, from)
+			require.Equal(t, tc.expectedFrom.AddDate(0, 0, 7), to)
+		})
+	}
+}
+
+func TestMonth(t *testing.T) {
+	testCases := []struct {
+		date         time.Time
+		expectedFrom time.Time
+		expectedTo   time.Time
+	}{
+		{
+			date:         time.Date(2023, 2, 28, 23, 0, 0, 0, time.UTC),
+			expectedFrom: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			date:         time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			expectedFrom: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			date:         time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+			expectedFrom: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.date.String(), func(t *testing.T) {
+			from, to := Month(tc.date, time.UTC)
+			require.Equal(t, tc.expectedFrom, from)
+			require.Equal(t, tc.expectedTo, to)
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	for _, s := range []string{"Sunday", "sun", "SUN"} {
+		day, err := ParseWeekday(s)
+		require.NoError(t, err)
+		require.Equal(t, time.Sunday, day)
+	}
+
+	_, err := ParseWeekday("su")
+	require.ErrorIs(t, err, ErrInvalidWeekday)
+}
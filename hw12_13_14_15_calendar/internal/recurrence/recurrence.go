@@ -0,0 +1,558 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+const (
+	untilLayout     = "20060102T150405Z"
+	untilLayoutDate = "20060102"
+	untilLayoutTime = "20060102T150405"
+
+	// maxPeriods protects from endless iteration over rules which never produce dates.
+	maxPeriods = 1 << 20
+)
+
+var (
+	ErrInvalidRule      = errors.New("recurrence rule must be in RFC 5545 RRULE format")
+	ErrInvalidFrequency = errors.New("recurrence frequency must be one of DAILY, WEEKLY, MONTHLY, YEARLY")
+	ErrInvalidInterval  = errors.New("recurrence interval must be positive")
+	ErrInvalidByDay     = errors.New("recurrence by day must contain only MO, TU, WE, TH, FR, SA, SU")
+	ErrNegativeCount    = errors.New("recurrence count cannot be negative")
+	ErrCountWithUntil   = errors.New("recurrence cannot contain both count and until")
+	ErrNotRecurring     = errors.New("event is not recurring")
+	ErrNotOccurrence    = errors.New("date is not an occurrence of the event")
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+	time.Sunday:    "SU",
+}
+
+// Parse parses RRULE value like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10".
+func Parse(rule string) (models.Recurrence, error) {
+	var r models.Recurrence
+
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return r, ErrInvalidRule
+	}
+
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return models.Recurrence{}, ErrInvalidRule
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Frequency = models.Frequency(strings.ToUpper(value))
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil {
+				return models.Recurrence{}, ErrInvalidInterval
+			}
+			r.Interval = interval
+		case "BYDAY":
+			for _, name := range strings.Split(value, ",") {
+				day, ok := weekdays[strings.ToUpper(name)]
+				if !ok {
+					return models.Recurrence{}, ErrInvalidByDay
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return models.Recurrence{}, ErrInvalidRule
+			}
+			r.Count = count
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return models.Recurrence{}, ErrInvalidRule
+			}
+			r.Until = until
+		case "WKST":
+			// weeks always start on monday
+		default:
+			return models.Recurrence{}, ErrInvalidRule
+		}
+	}
+
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+
+	if err := Validate(r); err != nil {
+		return models.Recurrence{}, err
+	}
+
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse(untilLayout, value); err == nil {
+		return until, nil
+	}
+	if until, err := time.Parse(untilLayoutTime, value); err == nil {
+		return until, nil
+	}
+	until, err := time.Parse(untilLayoutDate, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// date value includes the whole day
+	return until.Add(24*time.Hour - time.Second), nil
+}
+
+// Format returns RRULE value of the recurrence without exceptions.
+func Format(r models.Recurrence) string {
+	parts := []string{"FREQ=" + string(r.Frequency)}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		names := make([]string, 0, len(r.ByDay))
+		for _, day := range sortedDays(r.ByDay) {
+			names = append(names, weekdayNames[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func Validate(r models.Recurrence) error {
+	switch r.Frequency {
+	case models.FrequencyDaily, models.FrequencyWeekly, models.FrequencyMonthly, models.FrequencyYearly:
+	default:
+		return ErrInvalidFrequency
+	}
+	if r.Interval <= 0 {
+		return ErrInvalidInterval
+	}
+	for _, day := range r.ByDay {
+		if _, ok := weekdayNames[day]; !ok {
+			return ErrInvalidByDay
+		}
+	}
+	if r.Count < 0 {
+		return ErrNegativeCount
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return ErrCountWithUntil
+	}
+	return nil
+}
+
+// Occurrences returns start dates of all occurrences in [from, to] excluding exceptions.
+func Occurrences(start time.Time, r models.Recurrence, from, to time.Time) []time.Time {
+	var dates []time.Time
+
+	iterate(start, r, func(date time.Time) bool {
+		if date.After(to) {
+			return false
+		}
+		if !date.Before(from) && !isException(r, date) {
+			dates = append(dates, date)
+		}
+		return true
+	})
+
+	return dates
+}
+
+// Expand materializes occurrences of the event which start in [from, to].
+// Non-recurring event is returned as is when it starts in the interval.
+func Expand(event models.Event, from, to time.Time) []models.Event {
+	return ExpandLimit(event, from, to, 0, false)
+}
+
+// ExpandLimit is Expand which materializes up to limit occurrences: the earliest ones,
+// or the latest ones if latest is set. Zero limit means no limit.
+func ExpandLimit(event models.Event, from, to time.Time, limit int, latest bool) []models.Event {
+	if event.Recurrence == nil {
+		if !event.Date.Before(from) && !event.Date.After(to) {
+			return []models.Event{event}
+		}
+		return nil
+	}
+
+	var dates []time.Time
+	iterate(event.Date, *event.Recurrence, func(date time.Time) bool {
+		if date.After(to) {
+			return false
+		}
+		if date.Before(from) || isException(*event.Recurrence, date) {
+			return true
+		}
+		dates = append(dates, date)
+		if limit <= 0 || len(dates) <= limit {
+			return true
+		}
+		if !latest {
+			dates = dates[:limit]
+			return false
+		}
+		dates = dates[1:]
+		return true
+	})
+
+	events := make([]models.Event, 0, len(dates))
+
+	for _, date := range dates {
+		occurrence := event
+		occurrence.Date = date
+		occurrence.OriginalDate = date
+		events = append(events, occurrence)
+	}
+
+	return events
+}
+
+// End returns start date of the last occurrence of the series.
+// The returned flag is false when the series is endless.
+func End(start time.Time, r models.Recurrence) (time.Time, bool) {
+	if r.Count == 0 && r.Until.IsZero() {
+		return time.Time{}, false
+	}
+
+	last := start
+	iterate(start, r, func(date time.Time) bool {
+		last = date
+		return true
+	})
+
+	return last, true
+}
+
+// ReminderOccurrence returns the occurrence of the recurring event the reminder is due for: the first one
+// starting at or after the occurrence of the reminder, or after it if the reminder is sent, which is not
+// reminded of before from. The flag is false when the series has no such occurrence.
+func ReminderOccurrence(series models.Event, reminder models.Reminder, from time.Time) (time.Time, bool) {
+	var next time.Time
+
+	iterate(series.Date, *series.Recurrence, func(date time.Time) bool {
+		if date.Before(reminder.Occurrence) ||
+			(date.Equal(reminder.Occurrence) && reminder.Status == models.StatusSent) ||
+			date.Add(-reminder.Before).Before(from) ||
+			isException(*series.Recurrence, date) {
+			return true
+		}
+		next = date
+		return false
+	})
+
+	return next, !next.IsZero()
+}
+
+// IsOccurrence reports whether the recurring event has a non-excluded occurrence at the date.
+func IsOccurrence(event models.Event, date time.Time) bool {
+	if event.Recurrence == nil {
+		return false
+	}
+	return len(Occurrences(event.Date, *event.Recurrence, date, date)) == 1
+}
+
+// Exclude adds the occurrence to exceptions of the series.
+func Exclude(series models.Event, occurrence time.Time) (models.Event, error) {
+	if err := checkOccurrence(series, occurrence); err != nil {
+		return models.Event{}, err
+	}
+
+	r := copyRecurrence(*series.Recurrence)
+	r.Exceptions = append(r.Exceptions, occurrence)
+	series.Recurrence = &r
+
+	return series, nil
+}
+
+// Detach excludes the occurrence from the series and returns the updated series
+// together with a standalone event with the given id which replaces the occurrence.
+func Detach(series models.Event, occurrence time.Time, id string, update models.EventUpdate,
+) (models.Event, models.Event, error) {
+	updated, err := Exclude(series, occurrence)
+	if err != nil {
+		return models.Event{}, models.Event{}, err
+	}
+
+	detached := series
+	detached.Date = occurrence
+	detached = update.Apply(detached)
+	detached.ID = id
+	detached.Recurrence = nil
+	detached.RecurrenceID = series.ID
+	detached.OriginalDate = occurrence
+	detached.Reminders = models.ResetReminders(detached.Reminders)
+
+	return updated, detached, nil
+}
+
+// Truncate ends the series right before the occurrence.
+// The returned flag is false when no occurrences are left in the series.
+func Truncate(series models.Event, occurrence time.Time) (models.Event, bool, error) {
+	if err := checkOccurrence(series, occurrence); err != nil {
+		return models.Event{}, false, err
+	}
+
+	if !occurrence.After(series.Date) {
+		return models.Event{}, false, nil
+	}
+
+	r := copyRecurrence(*series.Recurrence)
+
+	if r.Count > 0 {
+		r.Count = countBefore(series.Date, r, occurrence)
+	} else {
+		r.Until = occurrence.Add(-time.Second)
+	}
+
+	exceptions := r.Exceptions[:0]
+	for _, exception := range r.Exceptions {
+		if exception.Before(occurrence) {
+			exceptions = append(exceptions, exception)
+		}
+	}
+	r.Exceptions = exceptions
+
+	series.Recurrence = &r
+
+	return series, true, nil
+}
+
+// Split ends the series right before the occurrence and returns a new series with the given id
+// which starts from the occurrence with the update applied. The returned flag is false when
+// the original series has no occurrences left and must be removed.
+func Split(series models.Event, occurrence time.Time, id string, update models.EventUpdate,
+) (models.Event, models.Event, bool, error) {
+	head, ok, err := Truncate(series, occurrence)
+	if err != nil {
+		return models.Event{}, models.Event{}, false, err
+	}
+
+	r := copyRecurrence(*series.Recurrence)
+	if r.Count > 0 {
+		r.Count -= countBefore(series.Date, r, occurrence)
+	}
+
+	exceptions := r.Exceptions[:0]
+	for _, exception := range r.Exceptions {
+		if !exception.Before(occurrence) {
+			exceptions = append(exceptions, exception)
+		}
+	}
+	r.Exceptions = exceptions
+
+	tail := series
+	tail.Date = occurrence
+	tail.Recurrence = &r
+	tail = update.Apply(tail)
+	tail.ID = id
+	tail.RecurrenceID = ""
+	tail.OriginalDate = time.Time{}
+	tail.Reminders = models.ResetReminders(tail.Reminders)
+
+	return head, tail, ok, nil
+}
+
+// Location returns the time zone in which occurrences of the series are computed.
+func Location(r models.Recurrence) *time.Location {
+	if r.Location == nil {
+		return time.UTC
+	}
+	return r.Location
+}
+
+// TimeZone returns the IANA name of the time zone of the series, empty for UTC.
+func TimeZone(r models.Recurrence) string {
+	if loc := Location(r); loc != time.UTC {
+		return loc.String()
+	}
+	return ""
+}
+
+func checkOccurrence(series models.Event, occurrence time.Time) error {
+	if series.Recurrence == nil {
+		return ErrNotRecurring
+	}
+	if !IsOccurrence(series, occurrence) {
+		return fmt.Errorf("%w: %s", ErrNotOccurrence, occurrence.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func copyRecurrence(r models.Recurrence) models.Recurrence {
+	r.ByDay = append([]time.Weekday(nil), r.ByDay...)
+	r.Exceptions = append([]time.Time(nil), r.Exceptions...)
+	return r
+}
+
+func isException(r models.Recurrence, date time.Time) bool {
+	for _, exception := range r.Exceptions {
+		if exception.Equal(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// countBefore returns number of occurrences (including excluded ones) before the date.
+func countBefore(start time.Time, r models.Recurrence, date time.Time) int {
+	var count int
+	iterate(start, r, func(occurrence time.Time) bool {
+		if !occurrence.Before(date) {
+			return false
+		}
+		count++
+		return true
+	})
+	return count
+}
+
+// iterate calls fn for every occurrence of the series in chronological order
+// until fn returns false or the series ends. Exceptions are not skipped
+// because they are counted by COUNT. Occurrences are computed in the location of the series.
+func iterate(start time.Time, r models.Recurrence, fn func(date time.Time) bool) {
+	start = start.In(Location(r))
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+
+	var count int
+	for period := 0; period < maxPeriods; period++ {
+		for _, date := range periodDates(start, r, period*interval) {
+			if date.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && date.After(r.Until) {
+				return
+			}
+			if r.Count > 0 && count >= r.Count {
+				return
+			}
+			count++
+			if !fn(date) {
+				return
+			}
+		}
+	}
+}
+
+// periodDates returns candidate dates of the n-th period counting from the start.
+func periodDates(start time.Time, r models.Recurrence, n int) []time.Time {
+	year, month, day := start.Date()
+	hour, minute, sec := start.Clock()
+	loc := start.Location()
+
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, sec, start.Nanosecond(), loc)
+	}
+
+	switch r.Frequency {
+	case models.FrequencyDaily:
+		date := at(year, month, day+n)
+		if len(r.ByDay) > 0 && !containsDay(r.ByDay, date.Weekday()) {
+			return nil
+		}
+		return []time.Time{date}
+	case models.FrequencyWeekly:
+		monday := day - (int(start.Weekday())+6)%7 + 7*n
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		dates := make([]time.Time, 0, len(days))
+		for _, weekday := range sortedDays(days) {
+			dates = append(dates, at(year, month, monday+(int(weekday)+6)%7))
+		}
+		return dates
+	case models.FrequencyMonthly:
+		first := time.Date(year, month+time.Month(n), 1, 0, 0, 0, 0, loc)
+		return monthDates(first, day, r.ByDay, at)
+	case models.FrequencyYearly:
+		first := time.Date(year+n, month, 1, 0, 0, 0, 0, loc)
+		return monthDates(first, day, r.ByDay, at)
+	}
+
+	return nil
+}
+
+// monthDates returns either the same day of month as the start (skipping months without such day)
+// or all days of month which fall on the given weekdays.
+func monthDates(first time.Time, day int, byDay []time.Weekday,
+	at func(year int, month time.Month, day int) time.Time,
+) []time.Time {
+	year, month := first.Year(), first.Month()
+	daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, first.Location()).Day()
+
+	if len(byDay) == 0 {
+		if day > daysInMonth {
+			return nil
+		}
+		return []time.Time{at(year, month, day)}
+	}
+
+	var dates []time.Time
+	for d := 1; d <= daysInMonth; d++ {
+		date := at(year, month, d)
+		if containsDay(byDay, date.Weekday()) {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+func containsDay(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedDays returns unique weekdays in order starting from monday.
+func sortedDays(days []time.Weekday) []time.Weekday {
+	sorted := make([]time.Weekday, 0, len(days))
+	for _, day := range days {
+		if !containsDay(sorted, day) {
+			sorted = append(sorted, day)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return (sorted[i]+6)%7 < (sorted[j]+6)%7
+	})
+	return sorted
+}
@@ -0,0 +1,378 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     string
+		expected models.Recurrence
+		format   string
+	}{
+		{
+			name:     "daily",
+			rule:     "FREQ=DAILY",
+			expected: models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1},
+			format:   "FREQ=DAILY",
+		},
+		{
+			name: "weekly with by day and count",
+			rule: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=WE,MO;COUNT=10",
+			expected: models.Recurrence{
+				Frequency: models.FrequencyWeekly,
+				Interval:  2,
+				ByDay:     []time.Weekday{time.Wednesday, time.Monday},
+				Count:     10,
+			},
+			format: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10",
+		},
+		{
+			name: "monthly until",
+			rule: "freq=monthly;until=20231231T100000Z",
+			expected: models.Recurrence{
+				Frequency: models.FrequencyMonthly,
+				Interval:  1,
+				Until:     time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC),
+			},
+			format: "FREQ=MONTHLY;UNTIL=20231231T100000Z",
+		},
+		{
+			name: "yearly until date",
+			rule: "FREQ=YEARLY;UNTIL=20301231",
+			expected: models.Recurrence{
+				Frequency: models.FrequencyYearly,
+				Interval:  1,
+				Until:     time.Date(2030, 12, 31, 23, 59, 59, 0, time.UTC),
+			},
+			format: "FREQ=YEARLY;UNTIL=20301231T235959Z",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Parse(tc.rule)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, r)
+			require.Equal(t, tc.format, Format(r))
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []struct {
+		rule string
+		err  error
+	}{
+		{rule: "", err: ErrInvalidRule},
+		{rule: "FREQ", err: ErrInvalidRule},
+		{rule: "FREQ=HOURLY", err: ErrInvalidFrequency},
+		{rule: "FREQ=DAILY;INTERVAL=-1", err: ErrInvalidInterval},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", err: ErrInvalidByDay},
+		{rule: "FREQ=DAILY;COUNT=-1", err: ErrNegativeCount},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20230101", err: ErrCountWithUntil},
+		{rule: "FREQ=DAILY;BYMONTH=1", err: ErrInvalidRule},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.rule, func(t *testing.T) {
+			_, err := Parse(tc.rule)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	start := time.Date(2023, 1, 31, 10, 0, 0, 0, time.UTC) // tuesday
+
+	testCases := []struct {
+		name     string
+		r        models.Recurrence
+		from     time.Time
+		to       time.Time
+		expected []time.Time
+	}{
+		{
+			name: "daily with count",
+			r:    models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 3},
+			from: start,
+			to:   start.AddDate(1, 0, 0),
+			expected: []time.Time{
+				start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2),
+			},
+		},
+		{
+			name: "daily in the middle of series",
+			r:    models.Recurrence{Frequency: models.FrequencyDaily, Interval: 2},
+			from: time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2023, 2, 6, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2023, 2, 4, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "weekly by day",
+			r: models.Recurrence{
+				Frequency: models.FrequencyWeekly,
+				Interval:  1,
+				ByDay:     []time.Weekday{time.Monday, time.Thursday},
+				Count:     4,
+			},
+			from: start,
+			to:   start.AddDate(1, 0, 0),
+			expected: []time.Time{
+				time.Date(2023, 2, 2, 10, 0, 0, 0, time.UTC),
+				time.Date(2023, 2, 6, 10, 0, 0, 0, time.UTC),
+				time.Date(2023, 2, 9, 10, 0, 0, 0, time.UTC),
+				time.Date(2023, 2, 13, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "monthly skips months without the day",
+			r:    models.Recurrence{Frequency: models.FrequencyMonthly, Interval: 1, Count: 3},
+			from: start,
+			to:   start.AddDate(1, 0, 0),
+			expected: []time.Time{
+				start,
+				time.Date(2023, 3, 31, 10, 0, 0, 0, time.UTC),
+				time.Date(2023, 5, 31, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "yearly until",
+			r: models.Recurrence{
+				Frequency: models.FrequencyYearly,
+				Interval:  1,
+				Until:     time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC),
+			},
+			from: start,
+			to:   start.AddDate(10, 0, 0),
+			expected: []time.Time{
+				start,
+				time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC),
+				time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "exceptions are skipped but counted",
+			r: models.Recurrence{
+				Frequency:  models.FrequencyDaily,
+				Interval:   1,
+				Count:      3,
+				Exceptions: []time.Time{start.AddDate(0, 0, 1)},
+			},
+			from: start,
+			to:   start.AddDate(1, 0, 0),
+			expected: []time.Time{
+				start, start.AddDate(0, 0, 2),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, Occurrences(start, tc.r, tc.from, tc.to))
+		})
+	}
+}
+
+func TestOccurrencesLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	// 09:00 CET on friday, the clocks go forward on sunday
+	start := time.Date(2023, 3, 24, 8, 0, 0, 0, time.UTC)
+	from, to := start, start.AddDate(0, 0, 4)
+
+	r := models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Location: berlin}
+	require.Equal(t, []time.Time{
+		time.Date(2023, 3, 24, 9, 0, 0, 0, berlin),
+		time.Date(2023, 3, 25, 9, 0, 0, 0, berlin),
+		time.Date(2023, 3, 26, 9, 0, 0, 0, berlin),
+		time.Date(2023, 3, 27, 9, 0, 0, 0, berlin),
+		time.Date(2023, 3, 28, 9, 0, 0, 0, berlin),
+	}, Occurrences(start, r, from, to))
+
+	// without the location the series keeps the UTC time
+	r.Location = nil
+	require.Equal(t, []time.Time{start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2), start.AddDate(0, 0, 3), to},
+		Occurrences(start.In(berlin), r, from, to))
+
+	// weekdays are taken in the location: 00:30 on monday in Berlin is sunday in UTC
+	start = time.Date(2023, 3, 19, 23, 30, 0, 0, time.UTC)
+	r = models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1, ByDay: []time.Weekday{time.Monday}, Count: 2,
+		Location: berlin}
+	require.Equal(t, []time.Time{
+		time.Date(2023, 3, 20, 0, 30, 0, 0, berlin),
+		time.Date(2023, 3, 27, 0, 30, 0, 0, berlin),
+	}, Occurrences(start, r, start, start.AddDate(0, 1, 0)))
+}
+
+func TestExpandLimit(t *testing.T) {
+	start := time.Date(2023, 1, 31, 10, 0, 0, 0, time.UTC)
+	event := models.Event{
+		ID:   "series",
+		Date: start,
+		Recurrence: &models.Recurrence{
+			Frequency:  models.FrequencyDaily,
+			Interval:   1,
+			Exceptions: []time.Time{start.AddDate(0, 0, 2)},
+		},
+	}
+
+	dates := func(events []models.Event) []time.Time {
+		result := make([]time.Time, 0, len(events))
+		for _, event := range events {
+			result = append(result, event.Date)
+		}
+		return result
+	}
+
+	from, to := start.AddDate(0, 0, 1), start.AddDate(0, 0, 10)
+	require.Equal(t, []time.Time{start.AddDate(0, 0, 1), start.AddDate(0, 0, 3)},
+		dates(ExpandLimit(event, from, to, 2, false)))
+	require.Equal(t, []time.Time{start.AddDate(0, 0, 9), start.AddDate(0, 0, 10)},
+		dates(ExpandLimit(event, from, to, 2, true)))
+	require.Len(t, ExpandLimit(event, from, to, 0, true), 9)
+	require.Equal(t, Expand(event, from, to), ExpandLimit(event, from, to, 20, false))
+}
+
+func TestReminderOccurrence(t *testing.T) {
+	start := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	series := models.Event{
+		Date: start,
+		Recurrence: &models.Recurrence{
+			Frequency:  models.FrequencyWeekly,
+			Interval:   1,
+			Count:      4,
+			Exceptions: []time.Time{start.AddDate(0, 0, 14)},
+		},
+	}
+	second, fourth := start.AddDate(0, 0, 7), start.AddDate(0, 0, 21)
+
+	testCases := []struct {
+		name     string
+		reminder models.Reminder
+		from     time.Time
+		expected time.Time
+		ok       bool
+	}{
+		{
+			name:     "pending",
+			reminder: models.Reminder{Before: time.Hour, Status: models.StatusPending, Occurrence: start},
+			from:     start.Add(-2 * time.Hour),
+			expected: start,
+			ok:       true,
+		},
+		{
+			name:     "sent",
+			reminder: models.Reminder{Before: time.Hour, Status: models.StatusSent, Occurrence: start},
+			from:     start,
+			expected: second,
+			ok:       true,
+		},
+		{
+			name:     "overdue occurrences and exceptions are skipped",
+			reminder: models.Reminder{Before: time.Hour, Status: models.StatusPending, Occurrence: start},
+			from:     second,
+			expected: fourth,
+			ok:       true,
+		},
+		{
+			name:     "series is over",
+			reminder: models.Reminder{Before: time.Hour, Status: models.StatusSent, Occurrence: fourth},
+			from:     fourth,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			occurrence, ok := ReminderOccurrence(series, tc.reminder, tc.from)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, occurrence)
+		})
+	}
+}
+
+func TestDetach(t *testing.T) {
+	start := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	series := models.Event{
+		ID:          "series",
+		Title:       "standup",
+		Date:        start,
+		Duration:    time.Hour,
+		Description: "daily sync",
+		UserID:      1,
+		Reminders: []models.Reminder{
+			{ID: 7, Before: time.Hour, Channel: models.ChannelLog, Status: models.StatusSent, SentAt: start.Add(-time.Hour)},
+		},
+		Recurrence: &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1},
+	}
+
+	occurrence := start.AddDate(0, 0, 3)
+	title, description := "moved standup", ""
+	update := models.EventUpdate{Title: &title, Description: &description}
+	updated, detached, err := Detach(series, occurrence, "detached", update)
+	require.NoError(t, err)
+
+	require.Equal(t, []time.Time{occurrence}, updated.Recurrence.Exceptions)
+	require.Nil(t, series.Recurrence.Exceptions)
+	require.False(t, IsOccurrence(updated, occurrence))
+
+	// the reminders of the detached event are sent again
+	require.Equal(t, models.Event{
+		ID:           "detached",
+		Title:        "moved standup",
+		Date:         occurrence,
+		Duration:     time.Hour,
+		UserID:       1,
+		Reminders:    []models.Reminder{{Before: time.Hour, Channel: models.ChannelLog, Status: models.StatusPending}},
+		RecurrenceID: "series",
+		OriginalDate: occurrence,
+	}, detached)
+
+	_, _, err = Detach(series, occurrence.Add(time.Minute), "detached", models.EventUpdate{})
+	require.ErrorIs(t, err, ErrNotOccurrence)
+}
+
+func TestSplit(t *testing.T) {
+	start := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	series := models.Event{
+		ID:         "series",
+		Date:       start,
+		Recurrence: &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 10},
+	}
+
+	occurrence := start.AddDate(0, 0, 4)
+	title := "new title"
+	head, tail, ok, err := Split(series, occurrence, "tail", models.EventUpdate{Title: &title})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Equal(t, 4, head.Recurrence.Count)
+	require.Equal(t, 6, tail.Recurrence.Count)
+	require.Equal(t, occurrence, tail.Date)
+	require.Equal(t, "new title", tail.Title)
+	require.Equal(t, "tail", tail.ID)
+
+	_, _, ok, err = Split(series, start, "tail", models.EventUpdate{})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+	head, ok, err = Truncate(series, occurrence)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, occurrence.Add(-time.Second), head.Recurrence.Until)
+	require.Len(t, Occurrences(head.Date, *head.Recurrence, start, start.AddDate(1, 0, 0)), 4)
+}
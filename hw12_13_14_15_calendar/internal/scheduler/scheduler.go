@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/metrics"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	scheduledTotal = metrics.NewCounter("calendar_notifications_scheduled_total",
+		"Reminders put to the outbox by the scheduler.")
+	scheduleFailedTotal = metrics.NewCounter("calendar_notifications_schedule_failed_total",
+		"Reminders the scheduler has failed to put to the outbox.")
+)
+
+// Notifications claims due reminders and puts their messages to the outbox.
+type Notifications interface {
+	ClaimNotifications(ctx context.Context, claim models.NotificationClaim) ([]models.Notification, error)
+	ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error
+}
+
+type Config struct {
+	// Interval is the pause between claims.
+	Interval time.Duration
+	// Window is how long before their time reminders are scheduled.
+	Window time.Duration
+	// Grace is how long overdue reminders are still scheduled, older ones are skipped.
+	Grace time.Duration
+	// Lease hides the claimed reminder from other schedulers, it is claimed again
+	// after the lease expires if the scheduler has failed to put it to the outbox.
+	Lease time.Duration
+	// BatchSize is the max number of reminders claimed at once.
+	BatchSize int
+}
+
+// Scheduler puts due reminders to the outbox. Several schedulers can share the storage
+// because every reminder is leased by one of them.
+type Scheduler struct {
+	notifications Notifications
+	log           logger.Logger
+	cfg           Config
+	now           func() time.Time
+}
+
+func New(notifications Notifications, log logger.Logger, cfg Config) *Scheduler {
+	return &Scheduler{
+		notifications: notifications,
+		log:           log,
+		cfg:           cfg,
+		now:           time.Now,
+	}
+}
+
+// Run schedules due reminders every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Schedule(ctx); err != nil {
+				s.log.Error("error scheduling notifications", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Schedule claims batches of due reminders until there are none left and returns
+// how many of them were put to the outbox.
+func (s *Scheduler) Schedule(ctx context.Context) (int, error) {
+	var scheduled int
+
+	for {
+		notifications, err := s.notifications.ClaimNotifications(ctx, models.NotificationClaim{
+			Now:    s.now(),
+			Window: s.cfg.Window,
+			Grace:  s.cfg.Grace,
+			Lease:  s.cfg.Lease,
+			Limit:  s.cfg.BatchSize,
+		})
+		if err != nil {
+			return scheduled, err
+		}
+
+		for _, notification := range notifications {
+			if s.schedule(ctx, notification) {
+				scheduled++
+			}
+		}
+
+		if len(notifications) < s.cfg.BatchSize {
+			return scheduled, nil
+		}
+	}
+}
+
+// schedule puts the messages of the reminder to the owner of the event and to its accepted attendees
+// to the outbox. The failed reminder stays leased, so it is not claimed again by the current Schedule.
+func (s *Scheduler) schedule(ctx context.Context, notification models.Notification) bool {
+	fireAt := notification.NotifyAt()
+	recipients := append([]int{notification.UserID}, notification.Attendees...)
+
+	payloads := make([][]byte, 0, len(recipients))
+	for _, userID := range recipients {
+		msg := mq.Message{
+			ID:         mq.MessageID(notification.ReminderID, userID, fireAt),
+			FireAt:     fireAt,
+			EventID:    notification.EventID,
+			ReminderID: notification.ReminderID,
+			Channel:    string(notification.Channel),
+			Title:      notification.Title,
+			Date:       notification.Date,
+			UserID:     userID,
+		}
+
+		body, err := json.Marshal(msg)
+		if err != nil {
+			s.log.Error("error marshal notification",
+				slog.Any("notification", msg),
+				slog.String("error", err.Error()))
+			scheduleFailedTotal.Inc()
+			return false
+		}
+		payloads = append(payloads, body)
+	}
+
+	// the relay publishes the message after it is stored together with the queued reminder
+	if err := s.notifications.ScheduleNotification(ctx, notification.ReminderID, payloads...); err != nil {
+		s.log.Error("error scheduling notification",
+			slog.String("notification id", notification.EventID),
+			slog.Int64("reminder id", notification.ReminderID),
+			slog.String("error", err.Error()))
+		scheduleFailedTotal.Inc()
+		return false
+	}
+
+	scheduledTotal.Inc()
+	return true
+}
@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/mq"
+	memorystorage "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var testConfig = Config{
+	Interval:  time.Second,
+	Window:    10 * time.Second,
+	Grace:     time.Hour,
+	Lease:     30 * time.Second,
+	BatchSize: 2,
+}
+
+// failingNotifications fails to schedule reminders while fail is set.
+type failingNotifications struct {
+	*memorystorage.Storage
+	fail bool
+}
+
+func (f *failingNotifications) ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error {
+	if f.fail {
+		return errors.New("storage is unavailable")
+	}
+	return f.Storage.ScheduleNotification(ctx, reminderID, payloads...)
+}
+
+func TestSchedulerSchedule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	ctx := context.Background()
+	st := memorystorage.NewStorageMemory()
+
+	now := time.Now()
+	// the burst of reminders due at the same moment is larger than the batch
+	createEvent(t, st, "id1", now.Add(5*time.Second), 0)
+	createEvent(t, st, "id2", now.Add(5*time.Second), 0)
+	createEvent(t, st, "id3", now.Add(5*time.Second), 0)
+	// overdue after the outage
+	createEvent(t, st, "id4", now.Add(time.Minute), 30*time.Minute)
+	// too early and too late
+	createEvent(t, st, "id5", now.Add(time.Hour), 0)
+	createEvent(t, st, "id6", now.Add(-2*time.Hour), 0)
+
+	s := New(st, logger, testConfig)
+	s.now = func() time.Time { return now }
+
+	before := scheduledTotal.Value()
+
+	scheduled, err := s.Schedule(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 4, scheduled)
+	require.Equal(t, before+4, scheduledTotal.Value())
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: now.Add(time.Hour), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+
+	eventIDs := make([]string, 0, len(messages))
+	for _, message := range messages {
+		var msg mq.Message
+		require.NoError(t, json.Unmarshal(message.Payload, &msg))
+		require.Equal(t, message.EventID, msg.EventID)
+		require.Equal(t, message.ReminderID, msg.ReminderID)
+		require.Equal(t, string(models.ChannelLog), msg.Channel)
+		require.Equal(t, mq.MessageID(msg.ReminderID, msg.UserID, msg.FireAt), msg.ID)
+		require.False(t, msg.FireAt.After(msg.Date))
+		eventIDs = append(eventIDs, msg.EventID)
+	}
+	require.ElementsMatch(t, []string{"id1", "id2", "id3", "id4"}, eventIDs)
+
+	scheduled, err = s.Schedule(ctx)
+	require.NoError(t, err)
+	require.Zero(t, scheduled)
+}
+
+func TestSchedulerScheduleReplicas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	ctx := context.Background()
+	st := memorystorage.NewStorageMemory()
+
+	now := time.Now()
+	for i := 0; i < 50; i++ {
+		createEvent(t, st, "id"+strconv.Itoa(i), now.Add(time.Second), 0)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int
+		errs  []error
+	)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			scheduled, err := New(st, logger, testConfig).Schedule(ctx)
+
+			mu.Lock()
+			total += scheduled
+			errs = append(errs, err)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, errors.Join(errs...))
+
+	// every reminder is scheduled by exactly one replica
+	require.Equal(t, 50, total)
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: time.Now().Add(time.Hour), Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, messages, 50)
+}
+
+func TestSchedulerScheduleLeaseExpired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Error("error scheduling notification", gomock.Any()).Times(1)
+
+	ctx := context.Background()
+	st := &failingNotifications{Storage: memorystorage.NewStorageMemory(), fail: true}
+
+	now := time.Now()
+	createEvent(t, st.Storage, "id1", now.Add(time.Second), 0)
+
+	s := New(st, logger, testConfig)
+	s.now = func() time.Time { return now }
+
+	failed := scheduleFailedTotal.Value()
+
+	scheduled, err := s.Schedule(ctx)
+	require.NoError(t, err)
+	require.Zero(t, scheduled)
+	require.Equal(t, failed+1, scheduleFailedTotal.Value())
+
+	// the reminder is leased by the failed attempt
+	st.fail = false
+
+	scheduled, err = s.Schedule(ctx)
+	require.NoError(t, err)
+	require.Zero(t, scheduled)
+
+	s.now = func() time.Time { return now.Add(testConfig.Lease) }
+
+	scheduled, err = s.Schedule(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, scheduled)
+}
+
+func TestSchedulerScheduleAttendees(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	ctx := context.Background()
+	st := memorystorage.NewStorageMemory()
+
+	now := time.Now()
+	createEvent(t, st, "id1", now.Add(time.Second), 0)
+
+	_, err := st.InviteAttendees(ctx, 1, "id1", 1, []int{2, 3, 4})
+	require.NoError(t, err)
+	_, err = st.RespondToInvitation(ctx, 2, "id1", models.AttendeeAccepted)
+	require.NoError(t, err)
+	_, err = st.RespondToInvitation(ctx, 3, "id1", models.AttendeeDeclined)
+	require.NoError(t, err)
+
+	s := New(st, logger, testConfig)
+	s.now = func() time.Time { return now }
+
+	scheduled, err := s.Schedule(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, scheduled)
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: now.Add(time.Hour), Limit: 10})
+	require.NoError(t, err)
+
+	// the owner and the accepted attendee are notified
+	ids := make(map[int]string, len(messages))
+	for _, message := range messages {
+		var msg mq.Message
+		require.NoError(t, json.Unmarshal(message.Payload, &msg))
+		ids[msg.UserID] = msg.ID
+	}
+	require.Len(t, ids, 2)
+	require.Contains(t, ids, 1)
+	require.Contains(t, ids, 2)
+	require.NotEqual(t, ids[1], ids[2])
+}
+
+// createEvent creates the event with the log reminder before its date.
+func createEvent(t *testing.T, st *memorystorage.Storage, id string, date time.Time, before time.Duration) {
+	t.Helper()
+
+	_, err := st.CreateEvent(context.Background(), models.Event{
+		ID:        id,
+		Date:      date,
+		UserID:    1,
+		Reminders: []models.Reminder{{Before: before, Channel: models.ChannelLog, Status: models.StatusPending}},
+	}, models.EventOptions{AllowOverlap: true})
+	require.NoError(t, err)
+}
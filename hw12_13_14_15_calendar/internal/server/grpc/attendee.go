@@ -0,0 +1,85 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	eventpb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (h *HandlerGRPC) InviteAttendees(ctx context.Context, req *eventpb.InviteAttendeesRequest) (*eventpb.InviteAttendeesResponse, error) { //nolint:lll
+	parsedID, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, invalidArgument("id", err)
+	}
+
+	if req.GetExpectedVersion() <= 0 {
+		return nil, invalidArgument("expected_version", ErrMissingVersion)
+	}
+
+	event, err := h.service.InviteAttendees(ctx, parsedID.String(), req.GetExpectedVersion(),
+		fromPBUserIDs(req.GetUserIds()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventpb.InviteAttendeesResponse{
+		Event: toPBEvent(event),
+	}, nil
+}
+
+func (h *HandlerGRPC) RespondToInvitation(ctx context.Context, req *eventpb.RespondToInvitationRequest) (*eventpb.RespondToInvitationResponse, error) { //nolint:lll
+	parsedID, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, invalidArgument("id", err)
+	}
+
+	event, err := h.service.RespondToInvitation(ctx, parsedID.String(), fromPBAttendeeStatus(req.GetStatus()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventpb.RespondToInvitationResponse{
+		Event: toPBEvent(event),
+	}, nil
+}
+
+func toPBAttendee(attendee models.Attendee) *eventpb.Attendee {
+	pbAttendee := &eventpb.Attendee{
+		UserId: int64(attendee.UserID),
+	}
+
+	switch attendee.Status {
+	case models.AttendeeNeedsAction:
+		pbAttendee.Status = eventpb.AttendeeStatus_ATTENDEE_STATUS_NEEDS_ACTION
+	case models.AttendeeAccepted:
+		pbAttendee.Status = eventpb.AttendeeStatus_ATTENDEE_STATUS_ACCEPTED
+	case models.AttendeeDeclined:
+		pbAttendee.Status = eventpb.AttendeeStatus_ATTENDEE_STATUS_DECLINED
+	case models.AttendeeTentative:
+		pbAttendee.Status = eventpb.AttendeeStatus_ATTENDEE_STATUS_TENTATIVE
+	}
+
+	if !attendee.RespondedAt.IsZero() {
+		pbAttendee.RespondedAt = timestamppb.New(attendee.RespondedAt)
+	}
+	return pbAttendee
+}
+
+// fromPBAttendeeStatus returns the answer of the attendee. The statuses which are not answers are rejected by the service.
+func fromPBAttendeeStatus(status eventpb.AttendeeStatus) models.AttendeeStatus {
+	switch status {
+	case eventpb.AttendeeStatus_ATTENDEE_STATUS_NEEDS_ACTION:
+		return models.AttendeeNeedsAction
+	case eventpb.AttendeeStatus_ATTENDEE_STATUS_ACCEPTED:
+		return models.AttendeeAccepted
+	case eventpb.AttendeeStatus_ATTENDEE_STATUS_DECLINED:
+		return models.AttendeeDeclined
+	case eventpb.AttendeeStatus_ATTENDEE_STATUS_TENTATIVE:
+		return models.AttendeeTentative
+	default:
+		return models.AttendeeStatus(status.String())
+	}
+}
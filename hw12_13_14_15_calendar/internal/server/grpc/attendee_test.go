@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	event_pb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestHandlerGRPCAttendees(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	id := uuid.New().String()
+	respondedAt := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	// the version is required
+	_, err = client.InviteAttendees(ctx, &event_pb.InviteAttendeesRequest{Id: id, UserIds: []int64{2}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	services.EXPECT().InviteAttendees(gomock.Any(), id, int64(1), []int{2, 3}).
+		Return(models.Event{
+			ID:      id,
+			UserID:  1,
+			Version: 2,
+			Attendees: []models.Attendee{
+				{UserID: 2, Status: models.AttendeeAccepted, RespondedAt: respondedAt},
+				{UserID: 3, Status: models.AttendeeNeedsAction},
+			},
+		}, nil)
+
+	invited, err := client.InviteAttendees(ctx, &event_pb.InviteAttendeesRequest{
+		Id:              id,
+		UserIds:         []int64{2, 3},
+		ExpectedVersion: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), invited.GetEvent().GetVersion())
+	require.Len(t, invited.GetEvent().GetAttendees(), 2)
+	require.Equal(t, event_pb.AttendeeStatus_ATTENDEE_STATUS_ACCEPTED, invited.GetEvent().GetAttendees()[0].GetStatus())
+	require.Equal(t, respondedAt, invited.GetEvent().GetAttendees()[0].GetRespondedAt().AsTime())
+	require.Equal(t, event_pb.AttendeeStatus_ATTENDEE_STATUS_NEEDS_ACTION, invited.GetEvent().GetAttendees()[1].GetStatus())
+	require.Nil(t, invited.GetEvent().GetAttendees()[1].GetRespondedAt())
+
+	services.EXPECT().RespondToInvitation(gomock.Any(), id, models.AttendeeTentative).
+		Return(models.Event{
+			ID:        id,
+			Attendees: []models.Attendee{{UserID: 3, Status: models.AttendeeTentative, RespondedAt: respondedAt}},
+		}, nil)
+
+	responded, err := client.RespondToInvitation(ctx, &event_pb.RespondToInvitationRequest{
+		Id:     id,
+		Status: event_pb.AttendeeStatus_ATTENDEE_STATUS_TENTATIVE,
+	})
+	require.NoError(t, err)
+	require.Equal(t, event_pb.AttendeeStatus_ATTENDEE_STATUS_TENTATIVE, responded.GetEvent().GetAttendees()[0].GetStatus())
+
+	// the user who is not invited cannot answer
+	services.EXPECT().RespondToInvitation(gomock.Any(), id, models.AttendeeAccepted).
+		Return(models.Event{}, customerror.CustomError{
+			Field:   "id",
+			Message: "user is not invited to event with id " + id,
+			Err:     customerror.ErrForbidden,
+		})
+
+	_, err = client.RespondToInvitation(ctx, &event_pb.RespondToInvitationRequest{
+		Id:     id,
+		Status: event_pb.AttendeeStatus_ATTENDEE_STATUS_ACCEPTED,
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
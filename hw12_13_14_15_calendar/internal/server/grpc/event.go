@@ -2,31 +2,55 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/pagination"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/period"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
 	eventpb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+var (
+	ErrExceptionsWithoutRule     = errors.New("recurrence_exceptions require recurrence_rule")
+	ErrTimeZoneWithoutRule       = errors.New("recurrence_time_zone requires recurrence_rule")
+	ErrMissingPeriod             = errors.New("from and to are required")
+	ErrNegativePageSize          = errors.New("page_size cannot be negative")
+	ErrInvalidTimeZone           = errors.New("time_zone must be an IANA time zone name")
+	ErrInvalidRecurrenceTimeZone = errors.New("recurrence_time_zone must be an IANA time zone name")
+	ErrInvalidUpdateMask         = errors.New("update_mask contains unknown field")
+	ErrMissingVersion            = errors.New("expected_version must be positive")
+)
+
 func (h *HandlerGRPC) CreateEvent(ctx context.Context, req *eventpb.CreateEventRequest) (*eventpb.CreateEventResponse, error) {
+	rec, err := fromPBRecurrence(req.GetRecurrenceRule(), req.GetRecurrenceExceptions(), req.GetRecurrenceTimeZone())
+	if err != nil {
+		return nil, invalidArgument("recurrence_rule", err)
+	}
+
 	event := models.Event{
 		Title:                req.GetTitle(),
 		Date:                 req.GetDate().AsTime(),
 		Duration:             req.GetDuration().AsDuration(),
 		Description:          req.GetDescription(),
-		UserID:               int(req.GetUserId()),
 		NotificationInterval: req.GetNotificationInterval().AsDuration(),
+		Reminders:            fromPBReminders(req.GetReminders()),
+		Recurrence:           rec,
+	}
+
+	opts := models.EventOptions{
+		AllowOverlap: req.GetAllowOverlap(),
 	}
 
-	id, err := h.service.CreateEvent(ctx, event)
+	id, err := h.service.CreateEvent(ctx, event, opts)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 	return &eventpb.CreateEventResponse{
 		Id: id,
@@ -34,120 +58,197 @@ func (h *HandlerGRPC) CreateEvent(ctx context.Context, req *eventpb.CreateEventR
 }
 
 func (h *HandlerGRPC) UpdateEvent(ctx context.Context, req *eventpb.UpdateEventRequest) (*eventpb.UpdateEventResponse, error) {
-	var date time.Time
-	if req.Event.GetDate() != nil {
-		date = req.Event.GetDate().AsTime()
+	parsedID, err := uuid.Parse(req.GetEvent().GetId())
+	if err != nil {
+		return nil, invalidArgument("event.id", err)
+	}
+
+	if req.GetExpectedVersion() <= 0 {
+		return nil, invalidArgument("expected_version", ErrMissingVersion)
 	}
 
-	id := req.Event.GetId()
-	parsedID, err := uuid.Parse(id)
+	update, err := fromPBUpdate(req.GetEvent(), req.GetUpdateMask().GetPaths())
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument("event", err)
 	}
 
-	event := models.Event{
-		ID:                   parsedID.String(),
-		Title:                req.GetEvent().GetTitle(),
-		Date:                 date,
-		Duration:             req.GetEvent().GetDuration().AsDuration(),
-		Description:          req.GetEvent().GetDescription(),
-		UserID:               int(req.GetEvent().GetUserId()),
-		NotificationInterval: req.GetEvent().GetNotificationInterval().AsDuration(),
+	opts := models.EventOptions{
+		AllowOverlap: req.GetAllowOverlap(),
 	}
 
-	updatedEvent, err := h.service.UpdateEvent(ctx, event.ID, event)
+	var updatedEvent models.Event
+	if req.GetOccurrenceDate() == nil {
+		updatedEvent, err = h.service.UpdateEvent(ctx, parsedID.String(), req.GetExpectedVersion(), update, opts)
+	} else {
+		occurrence := req.GetOccurrenceDate().AsTime()
+		scope := fromPBScope(req.GetScope())
+		updatedEvent, err = h.service.UpdateEventOccurrence(ctx, parsedID.String(), req.GetExpectedVersion(),
+			occurrence, scope, update, opts)
+	}
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
-	pbUpdatedEvent := toPBEvent(updatedEvent)
 	resultEvent := eventpb.UpdateEventResponse{
-		Event: &pbUpdatedEvent,
+		Event: toPBEvent(updatedEvent),
 	}
 
 	return &resultEvent, nil
 }
 
+func (h *HandlerGRPC) GetEvent(ctx context.Context, req *eventpb.GetEventRequest) (*eventpb.GetEventResponse, error) {
+	parsedID, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, invalidArgument("id", err)
+	}
+
+	event, err := h.service.GetEventByID(ctx, parsedID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventpb.GetEventResponse{
+		Event: toPBEvent(event),
+	}, nil
+}
+
 func (h *HandlerGRPC) DeleteEvent(ctx context.Context, req *eventpb.DeleteEventRequest) (*emptypb.Empty, error) {
 	parsedID, err := uuid.Parse(req.Id)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument("id", err)
 	}
 
-	err = h.service.DeleteEvent(ctx, parsedID.String())
+	if req.GetExpectedVersion() <= 0 {
+		return nil, invalidArgument("expected_version", ErrMissingVersion)
+	}
+
+	if req.GetOccurrenceDate() == nil {
+		err = h.service.DeleteEvent(ctx, parsedID.String(), req.GetExpectedVersion())
+	} else {
+		occurrence := req.GetOccurrenceDate().AsTime()
+		err = h.service.DeleteEventOccurrence(ctx, parsedID.String(), req.GetExpectedVersion(), occurrence,
+			fromPBScope(req.GetScope()))
+	}
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
 	return &emptypb.Empty{}, nil
 }
 
 func (h *HandlerGRPC) ListEventsByDay(ctx context.Context, req *eventpb.ListEventsRequest) (*eventpb.ListEventsResponse, error) { //nolint:lll
-	events, err := h.service.GetAllByDayEvents(ctx, req.Date.AsTime())
+	loc, err := time.LoadLocation(req.GetTimeZone())
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, invalidArgument("time_zone", ErrInvalidTimeZone)
 	}
 
-	result := make([]*eventpb.Event, 0, len(events))
-
-	for _, event := range events {
-		pbEvent := toPBEvent(event)
-		result = append(result, &pbEvent)
+	events, err := h.service.GetAllByDayEvents(ctx, req.GetDate().AsTime(), loc)
+	if err != nil {
+		return nil, err
 	}
 
 	return &eventpb.ListEventsResponse{
-		Events: result,
+		Events: toPBEvents(events),
 	}, nil
 }
 
 func (h *HandlerGRPC) ListEventsByWeek(ctx context.Context, req *eventpb.ListEventsRequest) (*eventpb.ListEventsResponse, error) { //nolint:lll
-	parsedDate, err := time.Parse(time.RFC3339, req.Date.String())
+	loc, err := time.LoadLocation(req.GetTimeZone())
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument("time_zone", ErrInvalidTimeZone)
 	}
 
-	events, err := h.service.GetAllByWeekEvents(ctx, parsedDate)
+	events, err := h.service.GetAllByWeekEvents(ctx, req.GetDate().AsTime(), loc, fromPBWeekday(req.GetWeekStart()))
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
-	result := make([]*eventpb.Event, 0, len(events))
+	return &eventpb.ListEventsResponse{
+		Events: toPBEvents(events),
+	}, nil
+}
 
-	for _, event := range events {
-		pbEvent := toPBEvent(event)
-		result = append(result, &pbEvent)
+func (h *HandlerGRPC) ListEventsByMonth(ctx context.Context, req *eventpb.ListEventsRequest) (*eventpb.ListEventsResponse, error) { //nolint:lll
+	loc, err := time.LoadLocation(req.GetTimeZone())
+	if err != nil {
+		return nil, invalidArgument("time_zone", ErrInvalidTimeZone)
+	}
+
+	events, err := h.service.GetAllByMonthEvents(ctx, req.GetDate().AsTime(), loc)
+	if err != nil {
+		return nil, err
 	}
 
 	return &eventpb.ListEventsResponse{
-		Events: result,
+		Events: toPBEvents(events),
 	}, nil
 }
 
-func (h *HandlerGRPC) ListEventsByMonth(ctx context.Context, req *eventpb.ListEventsRequest) (*eventpb.ListEventsResponse, error) { //nolint:lll
-	parsedDate, err := time.Parse(time.RFC3339, req.Date.String())
-	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+func (h *HandlerGRPC) GetEventsInRange(ctx context.Context, req *eventpb.GetEventsInRangeRequest) (*eventpb.GetEventsInRangeResponse, error) { //nolint:lll
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, invalidArgument("from", ErrMissingPeriod)
+	}
+	if req.GetPageSize() < 0 {
+		return nil, invalidArgument("page_size", ErrNegativePageSize)
 	}
 
-	events, err := h.service.GetAllByMonthEvents(ctx, parsedDate)
+	rng := models.EventRange{
+		From:  req.GetFrom().AsTime(),
+		To:    req.GetTo().AsTime(),
+		Query: req.GetQuery(),
+		Order: fromPBSortOrder(req.GetOrder()),
+		Limit: int(req.GetPageSize()),
+	}
+
+	if req.GetPageToken() != "" {
+		after, err := pagination.DecodeCursor(req.GetPageToken())
+		if err != nil {
+			return nil, invalidArgument("page_token", err)
+		}
+		rng.After = &after
+	}
+
+	page, err := h.service.GetEventsInRange(ctx, rng)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
+	resp := &eventpb.GetEventsInRangeResponse{
+		Events: toPBEvents(page.Events),
+	}
+	if page.Next != nil {
+		resp.NextPageToken = pagination.EncodeCursor(*page.Next)
+	}
+
+	return resp, nil
+}
+
+func toPBEvents(events []models.Event) []*eventpb.Event {
 	result := make([]*eventpb.Event, 0, len(events))
 
 	for _, event := range events {
-		pbEvent := toPBEvent(event)
-		result = append(result, &pbEvent)
+		result = append(result, toPBEvent(event))
 	}
 
-	return &eventpb.ListEventsResponse{
-		Events: result,
-	}, nil
+	return result
+}
+
+func fromPBWeekday(day eventpb.Weekday) time.Weekday {
+	if day == eventpb.Weekday_WEEKDAY_UNSPECIFIED {
+		return period.DefaultWeekStart
+	}
+	return time.Weekday(day % 7)
 }
 
-func toPBEvent(event models.Event) eventpb.Event {
-	return eventpb.Event{
+func fromPBSortOrder(order eventpb.SortOrder) models.SortOrder {
+	if order == eventpb.SortOrder_SORT_ORDER_DESC {
+		return models.SortDesc
+	}
+	return models.SortAsc
+}
+
+func toPBEvent(event models.Event) *eventpb.Event {
+	pbEvent := &eventpb.Event{
 		Id:                   event.ID,
 		Title:                event.Title,
 		Date:                 timestamppb.New(event.Date),
@@ -155,5 +256,224 @@ func toPBEvent(event models.Event) eventpb.Event {
 		Description:          event.Description,
 		UserId:               int64(event.UserID),
 		NotificationInterval: durationpb.New(event.NotificationInterval),
+		RecurrenceId:         event.RecurrenceID,
+		Version:              event.Version,
+	}
+	if event.Recurrence != nil {
+		pbEvent.RecurrenceRule = recurrence.Format(*event.Recurrence)
+		pbEvent.RecurrenceTimeZone = recurrence.TimeZone(*event.Recurrence)
+		for _, exception := range event.Recurrence.Exceptions {
+			pbEvent.RecurrenceExceptions = append(pbEvent.RecurrenceExceptions, timestamppb.New(exception))
+		}
+	}
+	if !event.OriginalDate.IsZero() {
+		pbEvent.OriginalDate = timestamppb.New(event.OriginalDate)
+	}
+	if !event.UpdatedAt.IsZero() {
+		pbEvent.UpdatedAt = timestamppb.New(event.UpdatedAt)
+	}
+	for _, reminder := range event.Reminders {
+		pbEvent.Reminders = append(pbEvent.Reminders, toPBReminder(reminder))
+	}
+	for _, attendee := range event.Attendees {
+		pbEvent.Attendees = append(pbEvent.Attendees, toPBAttendee(attendee))
+	}
+	return pbEvent
+}
+
+func toPBReminder(reminder models.Reminder) *eventpb.Reminder {
+	pbReminder := &eventpb.Reminder{
+		Id:     reminder.ID,
+		Before: durationpb.New(reminder.Before),
+	}
+
+	switch reminder.Channel {
+	case models.ChannelLog:
+		pbReminder.Channel = eventpb.ReminderChannel_REMINDER_CHANNEL_LOG
+	case models.ChannelEmail:
+		pbReminder.Channel = eventpb.ReminderChannel_REMINDER_CHANNEL_EMAIL
+	case models.ChannelWebhook:
+		pbReminder.Channel = eventpb.ReminderChannel_REMINDER_CHANNEL_WEBHOOK
+	}
+
+	switch reminder.Status {
+	case models.StatusPending:
+		pbReminder.Status = eventpb.ReminderStatus_REMINDER_STATUS_PENDING
+	case models.StatusQueued:
+		pbReminder.Status = eventpb.ReminderStatus_REMINDER_STATUS_QUEUED
+	case models.StatusSent:
+		pbReminder.Status = eventpb.ReminderStatus_REMINDER_STATUS_SENT
+	}
+
+	if !reminder.QueuedAt.IsZero() {
+		pbReminder.QueuedAt = timestamppb.New(reminder.QueuedAt)
+	}
+	if !reminder.SentAt.IsZero() {
+		pbReminder.SentAt = timestamppb.New(reminder.SentAt)
+	}
+	return pbReminder
+}
+
+// fromPBReminders returns the wanted reminders. The state set by the server is ignored.
+func fromPBReminders(reminders []*eventpb.Reminder) []models.Reminder {
+	var result []models.Reminder
+	for _, reminder := range reminders {
+		var channel models.ReminderChannel
+		switch reminder.GetChannel() {
+		case eventpb.ReminderChannel_REMINDER_CHANNEL_LOG, eventpb.ReminderChannel_REMINDER_CHANNEL_UNSPECIFIED:
+			channel = models.ChannelLog
+		case eventpb.ReminderChannel_REMINDER_CHANNEL_EMAIL:
+			channel = models.ChannelEmail
+		case eventpb.ReminderChannel_REMINDER_CHANNEL_WEBHOOK:
+			channel = models.ChannelWebhook
+		default:
+			// unknown channels are rejected by the service
+			channel = models.ReminderChannel(reminder.GetChannel().String())
+		}
+		result = append(result, models.Reminder{
+			Before:  reminder.GetBefore().AsDuration(),
+			Channel: channel,
+		})
+	}
+	return result
+}
+
+func fromPBRecurrence(rule string, exceptions []*timestamppb.Timestamp, timeZone string) (*models.Recurrence, error) {
+	if rule == "" {
+		if len(exceptions) > 0 {
+			return nil, ErrExceptionsWithoutRule
+		}
+		if timeZone != "" {
+			return nil, ErrTimeZoneWithoutRule
+		}
+		return nil, nil //nolint:nilnil
+	}
+
+	r, err := recurrence.Parse(rule)
+	if err != nil {
+		return nil, err
+	}
+	for _, exception := range exceptions {
+		r.Exceptions = append(r.Exceptions, exception.AsTime())
+	}
+	if timeZone != "" {
+		r.Location, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, ErrInvalidRecurrenceTimeZone
+		}
+	}
+
+	return &r, nil
+}
+
+// fromPBUpdate returns the update of the fields listed in the mask. Without the mask
+// non-empty fields of the event are updated.
+func fromPBUpdate(event *eventpb.Event, paths []string) (models.EventUpdate, error) {
+	if len(paths) == 0 {
+		paths = nonEmptyFields(event)
+	}
+
+	var (
+		update        models.EventUpdate
+		hasRule       bool
+		hasExceptions bool
+		hasTimeZone   bool
+	)
+	for _, path := range paths {
+		switch path {
+		case "title":
+			title := event.GetTitle()
+			update.Title = &title
+		case "date":
+			var date time.Time
+			if event.GetDate() != nil {
+				date = event.GetDate().AsTime()
+			}
+			update.Date = &date
+		case "duration":
+			duration := event.GetDuration().AsDuration()
+			update.Duration = &duration
+		case "description":
+			description := event.GetDescription()
+			update.Description = &description
+		case "notification_interval":
+			interval := event.GetNotificationInterval().AsDuration()
+			update.NotificationInterval = &interval
+		case "reminders":
+			reminders := fromPBReminders(event.GetReminders())
+			if reminders == nil {
+				reminders = []models.Reminder{}
+			}
+			update.Reminders = &reminders
+		case "recurrence_rule":
+			hasRule = true
+		case "recurrence_exceptions":
+			hasExceptions = true
+		case "recurrence_time_zone":
+			hasTimeZone = true
+		default:
+			return models.EventUpdate{}, fmt.Errorf("%s: %s", ErrInvalidUpdateMask.Error(), path)
+		}
+	}
+
+	if hasExceptions && !hasRule {
+		return models.EventUpdate{}, ErrExceptionsWithoutRule
+	}
+	if hasTimeZone && !hasRule {
+		return models.EventUpdate{}, ErrTimeZoneWithoutRule
+	}
+	if hasRule {
+		rec, err := fromPBRecurrence(event.GetRecurrenceRule(), event.GetRecurrenceExceptions(), event.GetRecurrenceTimeZone())
+		if err != nil {
+			return models.EventUpdate{}, err
+		}
+		update.Recurrence = rec
+		update.ClearRecurrence = rec == nil
+	}
+
+	return update, nil
+}
+
+// nonEmptyFields returns the mask of the fields which are set in the event.
+func nonEmptyFields(event *eventpb.Event) []string {
+	var paths []string
+	if event.GetTitle() != "" {
+		paths = append(paths, "title")
+	}
+	if event.GetDate() != nil {
+		paths = append(paths, "date")
+	}
+	if event.GetDuration().AsDuration() != 0 {
+		paths = append(paths, "duration")
+	}
+	if event.GetDescription() != "" {
+		paths = append(paths, "description")
+	}
+	if event.GetNotificationInterval().AsDuration() != 0 {
+		paths = append(paths, "notification_interval")
+	}
+	if len(event.GetReminders()) > 0 {
+		paths = append(paths, "reminders")
+	}
+	if event.GetRecurrenceRule() != "" {
+		paths = append(paths, "recurrence_rule")
+	}
+	if len(event.GetRecurrenceExceptions()) > 0 {
+		paths = append(paths, "recurrence_exceptions")
+	}
+	if event.GetRecurrenceTimeZone() != "" {
+		paths = append(paths, "recurrence_time_zone")
+	}
+	return paths
+}
+
+func fromPBScope(scope eventpb.RecurrenceScope) models.RecurrenceScope {
+	switch scope {
+	case eventpb.RecurrenceScope_RECURRENCE_SCOPE_THIS:
+		return models.ScopeThis
+	case eventpb.RecurrenceScope_RECURRENCE_SCOPE_FOLLOWING:
+		return models.ScopeFollowing
+	default:
+		return models.ScopeAll
 	}
 }
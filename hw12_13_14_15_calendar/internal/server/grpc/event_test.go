@@ -9,24 +9,30 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
 	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/pagination"
 	event_pb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
 	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-func startGRPCServer() (*grpc.Server, *bufconn.Listener) {
+func startGRPCServer(logg logger.Logger) (*grpc.Server, *bufconn.Listener) {
 	bufferSize := 1024 * 1024
 	listener := bufconn.Listen(bufferSize)
 
-	srv := grpc.NewServer()
+	srv := grpc.NewServer(grpc.UnaryInterceptor(errorInterceptor(logg)), grpc.StreamInterceptor(errorStreamInterceptor(logg)))
 	go func() {
 		if err := srv.Serve(listener); err != nil {
 			log.Fatalf("failed to start grpc server: %v", err)
@@ -45,12 +51,12 @@ func TestHandlerGRPCCreateEvent(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	srv, lis := startGRPCServer()
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
 	defer srv.Stop()
 	defer lis.Close()
 
 	services := mock_service.NewMockServices(ctrl)
-	logger := mock_logger.NewMockLogger(ctrl)
 	handler := HandlerGRPC{
 		service: services,
 		logger:  logger,
@@ -74,8 +80,11 @@ func TestHandlerGRPCCreateEvent(t *testing.T) {
 		Date:                 time.Now().UTC(),
 		Duration:             time.Second,
 		Description:          "test",
-		UserID:               1,
 		NotificationInterval: time.Second,
+		Reminders: []models.Reminder{
+			{Before: time.Hour, Channel: models.ChannelEmail},
+			{Before: time.Second, Channel: models.ChannelLog},
+		},
 	}
 
 	pbEvent := &event_pb.CreateEventRequest{
@@ -83,11 +92,14 @@ func TestHandlerGRPCCreateEvent(t *testing.T) {
 		Date:                 timestamppb.New(event.Date),
 		Duration:             durationpb.New(event.Duration),
 		Description:          event.Description,
-		UserId:               int64(event.UserID),
 		NotificationInterval: durationpb.New(event.NotificationInterval),
+		Reminders: []*event_pb.Reminder{
+			{Before: durationpb.New(time.Hour), Channel: event_pb.ReminderChannel_REMINDER_CHANNEL_EMAIL},
+			{Before: durationpb.New(time.Second)},
+		},
 	}
 
-	services.EXPECT().CreateEvent(gomock.Any(), event).Return(id, nil)
+	services.EXPECT().CreateEvent(gomock.Any(), event, models.EventOptions{}).Return(id, nil)
 
 	res, err := client.CreateEvent(ctx, pbEvent)
 	require.NoError(t, err)
@@ -98,12 +110,12 @@ func TestHandlerGRPCCreateEventError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	srv, lis := startGRPCServer()
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
 	defer srv.Stop()
 	defer lis.Close()
 
 	services := mock_service.NewMockServices(ctrl)
-	logger := mock_logger.NewMockLogger(ctrl)
 	handler := HandlerGRPC{
 		service: services,
 		logger:  logger,
@@ -127,7 +139,6 @@ func TestHandlerGRPCCreateEventError(t *testing.T) {
 		Date:                 time.Now().UTC(),
 		Duration:             time.Second,
 		Description:          "test",
-		UserID:               1,
 		NotificationInterval: time.Second,
 	}
 
@@ -136,14 +147,574 @@ func TestHandlerGRPCCreateEventError(t *testing.T) {
 		Date:                 timestamppb.New(event.Date),
 		Duration:             durationpb.New(event.Duration),
 		Description:          event.Description,
-		UserId:               int64(event.UserID),
 		NotificationInterval: durationpb.New(event.NotificationInterval),
 	}
 
-	services.EXPECT().CreateEvent(gomock.Any(), event).Return("", errors.New("title cannot be empty"))
+	services.EXPECT().CreateEvent(gomock.Any(), event, models.EventOptions{}).Return("", errors.New("title cannot be empty"))
+	// the details of the internal error are logged instead of being sent to the client
+	logger.EXPECT().Error("internal error", gomock.Any(), gomock.Any())
 
 	res, err := client.CreateEvent(ctx, pbEvent)
-	expectedErr := "rpc error: code = Internal desc = title cannot be empty"
+	expectedErr := "rpc error: code = Internal desc = internal error"
 	require.Equal(t, expectedErr, err.Error())
 	require.Equal(t, id, res.GetId())
 }
+
+func TestHandlerGRPCCreateRecurringEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	id := uuid.New().String()
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	event := models.Event{
+		Title:    "standup",
+		Date:     date,
+		Duration: 15 * time.Minute,
+		Recurrence: &models.Recurrence{
+			Frequency:  models.FrequencyWeekly,
+			Interval:   1,
+			ByDay:      []time.Weekday{time.Monday, time.Wednesday},
+			Exceptions: []time.Time{date.AddDate(0, 0, 7)},
+		},
+	}
+
+	pbEvent := &event_pb.CreateEventRequest{
+		Title:                event.Title,
+		Date:                 timestamppb.New(event.Date),
+		Duration:             durationpb.New(event.Duration),
+		RecurrenceRule:       "FREQ=WEEKLY;BYDAY=MO,WE",
+		RecurrenceExceptions: []*timestamppb.Timestamp{timestamppb.New(date.AddDate(0, 0, 7))},
+		NotificationInterval: durationpb.New(0),
+	}
+
+	services.EXPECT().CreateEvent(gomock.Any(), event, models.EventOptions{}).Return(id, nil)
+
+	res, err := client.CreateEvent(ctx, pbEvent)
+	require.NoError(t, err)
+	require.Equal(t, id, res.GetId())
+
+	// the series keeps the wall clock time in its time zone
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	event.Recurrence.Location = berlin
+	pbEvent.RecurrenceTimeZone = "Europe/Berlin"
+
+	services.EXPECT().CreateEvent(gomock.Any(), event, models.EventOptions{}).Return(id, nil)
+
+	_, err = client.CreateEvent(ctx, pbEvent)
+	require.NoError(t, err)
+
+	pbEvent.RecurrenceTimeZone = "Mars/Olympus"
+	_, err = client.CreateEvent(ctx, pbEvent)
+	require.ErrorContains(t, err, ErrInvalidRecurrenceTimeZone.Error())
+
+	pbEvent.RecurrenceRule = "FREQ=HOURLY"
+	_, err = client.CreateEvent(ctx, pbEvent)
+	require.ErrorContains(t, err, "code = InvalidArgument")
+
+	pbEvent.RecurrenceRule = ""
+	pbEvent.RecurrenceExceptions = nil
+	pbEvent.RecurrenceTimeZone = "Europe/Berlin"
+	_, err = client.CreateEvent(ctx, pbEvent)
+	require.ErrorContains(t, err, ErrTimeZoneWithoutRule.Error())
+}
+
+func TestHandlerGRPCUpdateEventMask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	id := uuid.New().String()
+	event := models.Event{
+		ID:       id,
+		Title:    "renamed",
+		Date:     time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+		UserID:   1,
+	}
+
+	// the description and the notification interval are removed, the title in the mask is changed,
+	// the duration outside of the mask is ignored
+	title, description, interval := "renamed", "", time.Duration(0)
+	update := models.EventUpdate{
+		Title:                &title,
+		Description:          &description,
+		NotificationInterval: &interval,
+		ClearRecurrence:      true,
+	}
+
+	services.EXPECT().UpdateEvent(gomock.Any(), id, int64(1), update, models.EventOptions{}).Return(event, nil)
+
+	res, err := client.UpdateEvent(ctx, &event_pb.UpdateEventRequest{
+		Event: &event_pb.Event{
+			Id:       id,
+			Title:    "renamed",
+			Duration: durationpb.New(2 * time.Hour),
+		},
+		UpdateMask: &fieldmaskpb.FieldMask{
+			Paths: []string{"title", "description", "notification_interval", "recurrence_rule"},
+		},
+		ExpectedVersion: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "renamed", res.GetEvent().GetTitle())
+	require.Equal(t, time.Hour, res.GetEvent().GetDuration().AsDuration())
+
+	// without the mask only non-empty fields are updated
+	duration := 2 * time.Hour
+	services.EXPECT().UpdateEvent(gomock.Any(), id, int64(1), models.EventUpdate{Duration: &duration},
+		models.EventOptions{}).Return(event, nil)
+
+	_, err = client.UpdateEvent(ctx, &event_pb.UpdateEventRequest{
+		Event: &event_pb.Event{
+			Id:       id,
+			Duration: durationpb.New(duration),
+		},
+		ExpectedVersion: 1,
+	})
+	require.NoError(t, err)
+
+	_, err = client.UpdateEvent(ctx, &event_pb.UpdateEventRequest{
+		Event:           &event_pb.Event{Id: id},
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"user_id"}},
+		ExpectedVersion: 1,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.UpdateEvent(ctx, &event_pb.UpdateEventRequest{
+		Event:           &event_pb.Event{Id: id},
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"recurrence_exceptions"}},
+		ExpectedVersion: 1,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.UpdateEvent(ctx, &event_pb.UpdateEventRequest{
+		Event:           &event_pb.Event{Id: id, RecurrenceTimeZone: "Europe/Berlin"},
+		ExpectedVersion: 1,
+	})
+	require.ErrorContains(t, err, ErrTimeZoneWithoutRule.Error())
+}
+
+func TestHandlerGRPCGetEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	event := models.Event{
+		ID:       uuid.New().String(),
+		Title:    "test",
+		Date:     time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+		UserID:   1,
+		Reminders: []models.Reminder{{
+			ID:       2,
+			Before:   time.Minute,
+			Channel:  models.ChannelWebhook,
+			Status:   models.StatusQueued,
+			QueuedAt: time.Date(2023, 7, 24, 9, 58, 50, 0, time.UTC),
+		}},
+	}
+	missingID := uuid.New().String()
+
+	services.EXPECT().GetEventByID(gomock.Any(), event.ID).Return(event, nil)
+	services.EXPECT().GetEventByID(gomock.Any(), missingID).Return(models.Event{}, customerror.CustomError{
+		Field:   "id",
+		Message: "no event with id " + missingID,
+		Err:     customerror.ErrNotFound,
+	})
+
+	res, err := client.GetEvent(ctx, &event_pb.GetEventRequest{Id: event.ID})
+	require.NoError(t, err)
+	require.Equal(t, event.ID, res.GetEvent().GetId())
+	require.Equal(t, event.Title, res.GetEvent().GetTitle())
+	require.Equal(t, event.Date, res.GetEvent().GetDate().AsTime())
+	require.Len(t, res.GetEvent().GetReminders(), 1)
+	reminder := res.GetEvent().GetReminders()[0]
+	require.Equal(t, int64(2), reminder.GetId())
+	require.Equal(t, time.Minute, reminder.GetBefore().AsDuration())
+	require.Equal(t, event_pb.ReminderChannel_REMINDER_CHANNEL_WEBHOOK, reminder.GetChannel())
+	require.Equal(t, event_pb.ReminderStatus_REMINDER_STATUS_QUEUED, reminder.GetStatus())
+	require.Equal(t, event.Reminders[0].QueuedAt, reminder.GetQueuedAt().AsTime())
+	require.Nil(t, reminder.GetSentAt())
+
+	_, err = client.GetEvent(ctx, &event_pb.GetEventRequest{Id: missingID})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetEvent(ctx, &event_pb.GetEventRequest{Id: "invalid"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHandlerGRPCDeleteEventOccurrence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	id := uuid.New().String()
+	occurrence := time.Date(2023, 7, 26, 10, 0, 0, 0, time.UTC)
+
+	services.EXPECT().DeleteEventOccurrence(gomock.Any(), id, int64(1), occurrence, models.ScopeFollowing).Return(nil)
+
+	_, err = client.DeleteEvent(ctx, &event_pb.DeleteEventRequest{
+		Id:              id,
+		OccurrenceDate:  timestamppb.New(occurrence),
+		Scope:           event_pb.RecurrenceScope_RECURRENCE_SCOPE_FOLLOWING,
+		ExpectedVersion: 1,
+	})
+	require.NoError(t, err)
+}
+
+func TestHandlerGRPCEventVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	id := uuid.New().String()
+	title := "renamed"
+	mismatch := customerror.CustomError{
+		Field:   "version",
+		Message: "event with id " + id + " has version 3, not 2",
+		Err:     customerror.ErrVersionMismatch,
+	}
+
+	// the version is required
+	_, err = client.UpdateEvent(ctx, &event_pb.UpdateEventRequest{
+		Event: &event_pb.Event{Id: id, Title: title},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.DeleteEvent(ctx, &event_pb.DeleteEventRequest{Id: id})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// the event has been changed by another client
+	services.EXPECT().UpdateEvent(gomock.Any(), id, int64(2), models.EventUpdate{Title: &title}, models.EventOptions{}).
+		Return(models.Event{}, mismatch)
+	services.EXPECT().DeleteEvent(gomock.Any(), id, int64(2)).Return(mismatch)
+
+	_, err = client.UpdateEvent(ctx, &event_pb.UpdateEventRequest{
+		Event:           &event_pb.Event{Id: id, Title: title},
+		ExpectedVersion: 2,
+	})
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = client.DeleteEvent(ctx, &event_pb.DeleteEventRequest{Id: id, ExpectedVersion: 2})
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	// the current version is returned with the event
+	updatedAt := time.Date(2023, 7, 24, 9, 0, 0, 0, time.UTC)
+	services.EXPECT().UpdateEvent(gomock.Any(), id, int64(3), models.EventUpdate{Title: &title}, models.EventOptions{}).
+		Return(models.Event{ID: id, Title: title, Version: 4, UpdatedAt: updatedAt}, nil)
+
+	res, err := client.UpdateEvent(ctx, &event_pb.UpdateEventRequest{
+		Event:           &event_pb.Event{Id: id, Title: title},
+		ExpectedVersion: 3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), res.GetEvent().GetVersion())
+	require.Equal(t, updatedAt, res.GetEvent().GetUpdatedAt().AsTime())
+}
+
+func TestHandlerGRPCCreateEventDateBusy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	event := models.Event{
+		Title:    "test",
+		Date:     time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+	}
+
+	pbEvent := &event_pb.CreateEventRequest{
+		Title:    event.Title,
+		Date:     timestamppb.New(event.Date),
+		Duration: durationpb.New(event.Duration),
+	}
+
+	busyErr := customerror.CustomError{
+		Field:   "date",
+		Message: "user already has an event at this time",
+		Err:     customerror.ErrDateBusy,
+	}
+	services.EXPECT().CreateEvent(gomock.Any(), event, models.EventOptions{}).Return("", busyErr)
+
+	_, err = client.CreateEvent(ctx, pbEvent)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	pbEvent.AllowOverlap = true
+	services.EXPECT().CreateEvent(gomock.Any(), event, models.EventOptions{AllowOverlap: true}).Return("id", nil)
+
+	res, err := client.CreateEvent(ctx, pbEvent)
+	require.NoError(t, err)
+	require.Equal(t, "id", res.GetId())
+}
+
+func TestHandlerGRPCGetEventsInRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	after := models.Cursor{Date: from.AddDate(0, 0, 3), ID: "1"}
+	next := models.Cursor{Date: from.AddDate(0, 0, 5), ID: "2"}
+
+	event := models.Event{
+		ID:       next.ID,
+		Title:    "standup",
+		Date:     next.Date,
+		Duration: time.Hour,
+		UserID:   1,
+	}
+
+	services.EXPECT().GetEventsInRange(gomock.Any(), models.EventRange{
+		From:  from,
+		To:    to,
+		Query: "stand",
+		Order: models.SortDesc,
+		After: &after,
+		Limit: 1,
+	}).Return(models.EventPage{Events: []models.Event{event}, Next: &next}, nil)
+
+	res, err := client.GetEventsInRange(ctx, &event_pb.GetEventsInRangeRequest{
+		From:      timestamppb.New(from),
+		To:        timestamppb.New(to),
+		Query:     "stand",
+		Order:     event_pb.SortOrder_SORT_ORDER_DESC,
+		PageSize:  1,
+		PageToken: pagination.EncodeCursor(after),
+	})
+	require.NoError(t, err)
+	require.Len(t, res.GetEvents(), 1)
+	require.Equal(t, event.ID, res.GetEvents()[0].GetId())
+	require.Equal(t, pagination.EncodeCursor(next), res.GetNextPageToken())
+
+	_, err = client.GetEventsInRange(ctx, &event_pb.GetEventsInRangeRequest{
+		From:      timestamppb.New(from),
+		To:        timestamppb.New(to),
+		PageToken: "invalid",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetEventsInRange(ctx, &event_pb.GetEventsInRangeRequest{
+		From: timestamppb.New(from),
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHandlerGRPCListEventsByWeekTimeZone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	date := time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC)
+	event := models.Event{
+		ID:     uuid.New().String(),
+		Title:  "standup",
+		Date:   date,
+		UserID: 1,
+	}
+
+	services.EXPECT().GetAllByWeekEvents(gomock.Any(), date, newYork, time.Sunday).Return([]models.Event{event}, nil)
+	services.EXPECT().GetAllByWeekEvents(gomock.Any(), date, time.UTC, time.Monday).Return(nil, nil)
+
+	res, err := client.ListEventsByWeek(ctx, &event_pb.ListEventsRequest{
+		Date:      timestamppb.New(date),
+		TimeZone:  "America/New_York",
+		WeekStart: event_pb.Weekday_WEEKDAY_SUNDAY,
+	})
+	require.NoError(t, err)
+	require.Len(t, res.GetEvents(), 1)
+	require.Equal(t, event.ID, res.GetEvents()[0].GetId())
+
+	res, err = client.ListEventsByWeek(ctx, &event_pb.ListEventsRequest{
+		Date: timestamppb.New(date),
+	})
+	require.NoError(t, err)
+	require.Len(t, res.GetEvents(), 0)
+
+	_, err = client.ListEventsByWeek(ctx, &event_pb.ListEventsRequest{
+		Date:     timestamppb.New(date),
+		TimeZone: "Mars/Olympus",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	eventpb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrUnspecifiedWeekday = errors.New("weekdays cannot contain unspecified weekday")
+
+func (h *HandlerGRPC) GetFreeBusy(ctx context.Context, req *eventpb.GetFreeBusyRequest) (*eventpb.GetFreeBusyResponse, error) { //nolint:lll
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, invalidArgument("from", ErrMissingPeriod)
+	}
+
+	calendars, err := h.service.GetFreeBusy(ctx, fromPBUserIDs(req.GetUserIds()), req.GetFrom().AsTime(),
+		req.GetTo().AsTime())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &eventpb.GetFreeBusyResponse{
+		Calendars: make([]*eventpb.FreeBusy, 0, len(calendars)),
+	}
+	for _, calendar := range calendars {
+		resp.Calendars = append(resp.Calendars, &eventpb.FreeBusy{
+			UserId: int64(calendar.UserID),
+			Busy:   toPBIntervals(calendar.Busy),
+		})
+	}
+
+	return resp, nil
+}
+
+func (h *HandlerGRPC) FindSlots(ctx context.Context, req *eventpb.FindSlotsRequest) (*eventpb.FindSlotsResponse, error) {
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, invalidArgument("from", ErrMissingPeriod)
+	}
+
+	loc, err := time.LoadLocation(req.GetTimeZone())
+	if err != nil {
+		return nil, invalidArgument("time_zone", ErrInvalidTimeZone)
+	}
+
+	query := models.SlotQuery{
+		UserIDs:      fromPBUserIDs(req.GetUserIds()),
+		From:         req.GetFrom().AsTime(),
+		To:           req.GetTo().AsTime(),
+		Duration:     req.GetDuration().AsDuration(),
+		WorkdayStart: req.GetWorkdayStart().AsDuration(),
+		WorkdayEnd:   req.GetWorkdayEnd().AsDuration(),
+		Location:     loc,
+		Limit:        int(req.GetLimit()),
+	}
+	for _, day := range req.GetWeekdays() {
+		if day == eventpb.Weekday_WEEKDAY_UNSPECIFIED {
+			return nil, invalidArgument("weekdays", ErrUnspecifiedWeekday)
+		}
+		query.Weekdays = append(query.Weekdays, fromPBWeekday(day))
+	}
+
+	slots, err := h.service.FindSlots(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventpb.FindSlotsResponse{
+		Slots: toPBIntervals(slots),
+	}, nil
+}
+
+func fromPBUserIDs(userIDs []int64) []int {
+	result := make([]int, 0, len(userIDs))
+	for _, userID := range userIDs {
+		result = append(result, int(userID))
+	}
+	return result
+}
+
+func toPBIntervals(intervals []models.Interval) []*eventpb.Interval {
+	result := make([]*eventpb.Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		result = append(result, &eventpb.Interval{
+			Start: timestamppb.New(interval.Start),
+			End:   timestamppb.New(interval.End),
+		})
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	event_pb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestHandlerGRPCFreeBusy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	busy := models.Interval{Start: from.Add(9 * time.Hour), End: from.Add(10 * time.Hour)}
+
+	// the range is required
+	_, err = client.GetFreeBusy(ctx, &event_pb.GetFreeBusyRequest{UserIds: []int64{1}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	services.EXPECT().GetFreeBusy(gomock.Any(), []int{1, 2}, from, to).
+		Return([]models.FreeBusy{{UserID: 1, Busy: []models.Interval{busy}}, {UserID: 2}}, nil)
+
+	freeBusy, err := client.GetFreeBusy(ctx, &event_pb.GetFreeBusyRequest{
+		UserIds: []int64{1, 2},
+		From:    timestamppb.New(from),
+		To:      timestamppb.New(to),
+	})
+	require.NoError(t, err)
+	require.Len(t, freeBusy.GetCalendars(), 2)
+	require.Equal(t, int64(1), freeBusy.GetCalendars()[0].GetUserId())
+	require.Equal(t, busy.Start, freeBusy.GetCalendars()[0].GetBusy()[0].GetStart().AsTime())
+	require.Equal(t, busy.End, freeBusy.GetCalendars()[0].GetBusy()[0].GetEnd().AsTime())
+	require.Empty(t, freeBusy.GetCalendars()[1].GetBusy())
+
+	_, err = client.FindSlots(ctx, &event_pb.FindSlotsRequest{
+		UserIds:  []int64{1},
+		From:     timestamppb.New(from),
+		To:       timestamppb.New(to),
+		Weekdays: []event_pb.Weekday{event_pb.Weekday_WEEKDAY_UNSPECIFIED},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	services.EXPECT().FindSlots(gomock.Any(), models.SlotQuery{
+		UserIDs:      []int{1, 2},
+		From:         from,
+		To:           to,
+		Duration:     time.Hour,
+		WorkdayStart: 9 * time.Hour,
+		WorkdayEnd:   17 * time.Hour,
+		Location:     time.UTC,
+		Weekdays:     []time.Weekday{time.Monday, time.Sunday},
+		Limit:        3,
+	}).Return([]models.Interval{busy}, nil)
+
+	slots, err := client.FindSlots(ctx, &event_pb.FindSlotsRequest{
+		UserIds:      []int64{1, 2},
+		From:         timestamppb.New(from),
+		To:           timestamppb.New(to),
+		Duration:     durationpb.New(time.Hour),
+		WorkdayStart: durationpb.New(9 * time.Hour),
+		WorkdayEnd:   durationpb.New(17 * time.Hour),
+		Weekdays:     []event_pb.Weekday{event_pb.Weekday_WEEKDAY_MONDAY, event_pb.Weekday_WEEKDAY_SUNDAY},
+		Limit:        3,
+	})
+	require.NoError(t, err)
+	require.Len(t, slots.GetSlots(), 1)
+	require.Equal(t, busy.Start, slots.GetSlots()[0].GetStart().AsTime())
+}
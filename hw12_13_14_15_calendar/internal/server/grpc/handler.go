@@ -1,15 +1,27 @@
 package grpc
 
 import (
+	"context"
+	"errors"
+	"sync"
+
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
 	event_pb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
 )
 
 type HandlerGRPC struct {
 	event_pb.UnimplementedEventServiceServer
 	service service.Services
 	logger  logger.Logger
+	// stopping is closed on shutdown to end the streams, the graceful stop waits for them otherwise.
+	stopping chan struct{}
+	stopOnce sync.Once
 }
 
 func NewHandlerGRPC(services service.Services, logger logger.Logger) *HandlerGRPC {
@@ -17,5 +29,90 @@ func NewHandlerGRPC(services service.Services, logger logger.Logger) *HandlerGRP
 		UnimplementedEventServiceServer: event_pb.UnimplementedEventServiceServer{},
 		service:                         services,
 		logger:                          logger,
+		stopping:                        make(chan struct{}),
+	}
+}
+
+func (h *HandlerGRPC) stopStreams() {
+	h.stopOnce.Do(func() {
+		close(h.stopping)
+	})
+}
+
+// errorDomain is the domain of google.rpc.ErrorInfo details.
+const errorDomain = "calendar"
+
+// internalMessage is sent instead of the messages of internal errors, they may expose the details of the storage.
+const internalMessage = "internal error"
+
+// errorCode returns gRPC status code for the kind of the error returned by the service.
+func errorCode(err error) codes.Code {
+	switch customerror.KindOf(err) {
+	case customerror.KindValidation:
+		return codes.InvalidArgument
+	case customerror.KindUnauthenticated:
+		return codes.Unauthenticated
+	case customerror.KindPermission:
+		return codes.PermissionDenied
+	case customerror.KindNotFound:
+		return codes.NotFound
+	case customerror.KindConflict:
+		switch {
+		case errors.Is(err, customerror.ErrDateBusy):
+			return codes.FailedPrecondition
+		case errors.Is(err, customerror.ErrVersionMismatch):
+			return codes.Aborted
+		}
+		return codes.AlreadyExists
+	case customerror.KindUnavailable:
+		switch {
+		case errors.Is(err, context.Canceled):
+			return codes.Canceled
+		case errors.Is(err, context.DeadlineExceeded):
+			return codes.DeadlineExceeded
+		}
+		return codes.Unavailable
+	case customerror.KindInternal:
+	}
+	return codes.Internal
+}
+
+// errorStatus returns the status of the error with google.rpc.ErrorInfo details and
+// google.rpc.BadRequest details for the invalid field. Internal errors get the generic message.
+func errorStatus(err error) *status.Status {
+	code := errorCode(err)
+	message := err.Error()
+	if code == codes.Internal || code == codes.Unknown {
+		message = internalMessage
+	}
+	st := status.New(code, message)
+
+	details := []protoiface.MessageV1{
+		&errdetails.ErrorInfo{
+			Reason: customerror.Reason(err),
+			Domain: errorDomain,
+		},
+	}
+	if field := customerror.FieldOf(err); field != "" && customerror.KindOf(err) == customerror.KindValidation {
+		details = append(details, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: field, Description: err.Error()},
+			},
+		})
+	}
+
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st
+	}
+	return withDetails
+}
+
+// invalidArgument returns the validation error of the request field.
+func invalidArgument(field string, err error) error {
+	return customerror.CustomError{
+		Field:   field,
+		Message: err.Error(),
+		Err:     customerror.ErrValidation,
 	}
 }
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	eventpb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+)
+
+func (h *HandlerGRPC) ExportEvents(ctx context.Context, req *eventpb.ExportEventsRequest) (*eventpb.ExportEventsResponse, error) { //nolint:lll
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, invalidArgument("from", ErrMissingPeriod)
+	}
+
+	data, err := h.service.ExportEvents(ctx, req.GetFrom().AsTime(), req.GetTo().AsTime())
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventpb.ExportEventsResponse{
+		Calendar: data,
+	}, nil
+}
+
+func (h *HandlerGRPC) ImportEvents(ctx context.Context, req *eventpb.ImportEventsRequest) (*eventpb.ImportEventsResponse, error) { //nolint:lll
+	opts := models.EventOptions{
+		AllowOverlap: req.GetAllowOverlap(),
+	}
+
+	results, err := h.service.ImportEvents(ctx, req.GetCalendar(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &eventpb.ImportEventsResponse{
+		Total:   int32(len(results)),
+		Results: make([]*eventpb.ImportEventResult, 0, len(results)),
+	}
+	for _, result := range results {
+		res := &eventpb.ImportEventResult{
+			Uid: result.UID,
+			Id:  result.ID,
+		}
+		if result.Err != nil {
+			res.Error = result.Err.Error()
+		} else {
+			response.Created++
+		}
+		response.Results = append(response.Results, res)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	event_pb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const calendar = "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"
+
+func TestHandlerGRPCExportEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	services.EXPECT().ExportEvents(gomock.Any(), from, to).Return([]byte(calendar), nil)
+
+	res, err := client.ExportEvents(ctx, &event_pb.ExportEventsRequest{
+		From: timestamppb.New(from),
+		To:   timestamppb.New(to),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte(calendar), res.GetCalendar())
+
+	_, err = client.ExportEvents(ctx, &event_pb.ExportEventsRequest{})
+	require.ErrorContains(t, err, "code = InvalidArgument")
+}
+
+func TestHandlerGRPCImportEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	services.EXPECT().ImportEvents(gomock.Any(), []byte(calendar), models.EventOptions{}).Return([]models.ImportResult{
+		{UID: "1", ID: "created id"},
+		{UID: "2", Err: errors.New("DTSTART is required")},
+	}, nil)
+
+	res, err := client.ImportEvents(ctx, &event_pb.ImportEventsRequest{
+		Calendar: []byte(calendar),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), res.GetTotal())
+	require.Equal(t, int32(1), res.GetCreated())
+	require.Equal(t, "created id", res.GetResults()[0].GetId())
+	require.Equal(t, "DTSTART is required", res.GetResults()[1].GetError())
+}
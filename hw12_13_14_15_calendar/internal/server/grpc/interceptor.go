@@ -4,11 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/auth"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/identity"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/metrics"
 	"golang.org/x/exp/slog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func loggingInterceptor(log logger.Logger, logPath string) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) { //nolint:lll
@@ -17,24 +24,173 @@ func loggingInterceptor(log logger.Logger, logPath string) func(ctx context.Cont
 
 		resp, err := handler(ctx, req)
 
-		duration := time.Since(start)
+		logRequest(log, logPath, info.FullMethod, time.Since(start), err)
 
-		logErr := err
-		if logErr == nil {
-			logErr = errors.New("empty")
+		return resp, err
+	}
+}
+
+// loggingStreamInterceptor logs streams when they end, the processing time is the lifetime of the stream.
+func loggingStreamInterceptor(log logger.Logger, logPath string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		err := handler(srv, ss)
+
+		logRequest(log, logPath, info.FullMethod, time.Since(start), err)
+
+		return err
+	}
+}
+
+func logRequest(log logger.Logger, logPath, method string, duration time.Duration, err error) {
+	logErr := err
+	if logErr == nil {
+		logErr = errors.New("empty")
+	}
+
+	log.Info("Request info GRPC",
+		slog.String("method", method),
+		slog.String("processing time", duration.String()),
+		slog.String("errors", logErr.Error()),
+	)
+
+	logInFileString := fmt.Sprintf("GRPC: %s %s %s", method, duration, logErr.Error())
+	if err := log.WriteLogInFile(logPath, logInFileString); err != nil {
+		log.Error(fmt.Sprintf("errors wriging log in file with path %s: %s", logPath, err.Error()))
+	}
+}
+
+var (
+	grpcRequests = metrics.NewCounter("calendar_grpc_requests_total",
+		"gRPC requests by method and status code.", "method", "code")
+	grpcDuration = metrics.NewHistogram("calendar_grpc_request_duration_seconds",
+		"Latency of gRPC requests by method.", metrics.DefBuckets, "method")
+)
+
+// metricsInterceptor counts requests by the codes of their statuses, so it goes before errorInterceptor in the chain.
+func metricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) { //nolint:lll
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	grpcRequests.Inc(info.FullMethod, status.Code(err).String())
+	grpcDuration.Observe(time.Since(start).Seconds(), info.FullMethod)
+
+	return resp, err
+}
+
+// metricsStreamInterceptor counts streams the same way as metricsInterceptor does unary calls.
+func metricsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error { //nolint:lll
+	start := time.Now()
+
+	err := handler(srv, ss)
+
+	grpcRequests.Inc(info.FullMethod, status.Code(err).String())
+	grpcDuration.Observe(time.Since(start).Seconds(), info.FullMethod)
+
+	return err
+}
+
+// errorInterceptor translates errors returned by handlers into statuses with codes and details
+// of their kinds. Errors which already are statuses are returned as they are.
+func errorInterceptor(log logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
 		}
+		return nil, statusError(log, info.FullMethod, err)
+	}
+}
+
+// errorStreamInterceptor translates errors of streams the same way as errorInterceptor does.
+func errorStreamInterceptor(log logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if err == nil {
+			return nil
+		}
+		return statusError(log, info.FullMethod, err)
+	}
+}
+
+// statusError returns the status of the error. Internal errors are logged, because the client
+// gets only the generic message.
+func statusError(log logger.Logger, method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	st := errorStatus(err)
+	if st.Code() == codes.Internal {
+		log.Error("internal error",
+			slog.String("method", method),
+			slog.String("error", err.Error()))
+	}
+	return st.Err()
+}
 
-		log.Info("Request info GRPC",
-			slog.String("method", info.FullMethod),
-			slog.String("processing time", duration.String()),
-			slog.String("errors", logErr.Error()),
-		)
+// authInterceptor puts the caller authenticated by the bearer token or the API key from the metadata
+// into the context. Health checks are anonymous.
+func authInterceptor(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) { //nolint:lll
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(ctx, req)
+		}
 
-		logInFileString := fmt.Sprintf("GRPC: %s %s %s", info.FullMethod, duration, logErr.Error())
-		if err := log.WriteLogInFile(logPath, logInFileString); err != nil {
-			log.Error(fmt.Sprintf("errors wriging log in file with path %s: %s", logPath, err.Error()))
+		ctx, err := authenticate(ctx, authenticator)
+		if err != nil {
+			return nil, err
 		}
 
-		return resp, err
+		return handler(ctx, req)
+	}
+}
+
+// authStreamInterceptor authenticates streams the same way as authInterceptor does unary calls.
+func authStreamInterceptor(authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(srv, ss)
+		}
+
+		ctx, err := authenticate(ss.Context(), authenticator)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream is the stream whose context carries the caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func authenticate(ctx context.Context, authenticator *auth.Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	principal, err := authenticator.Authenticate(auth.Credentials{
+		Authorization: firstValue(md, auth.MetadataAuthorization),
+		APIKey:        firstValue(md, auth.MetadataAPIKey),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return identity.WithPrincipal(ctx, principal), nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
 	}
+	return ""
 }
@@ -0,0 +1,345 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/auth"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/identity"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	event_pb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/exp/slog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestHandlerGRPCAuthInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bufferSize := 1024 * 1024
+	lis := bufconn.Listen(bufferSize)
+	defer lis.Close()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(errorInterceptor(logger), authInterceptor(newTestAuthenticator(t))))
+	defer srv.Stop()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	id := uuid.New().String()
+
+	_, err = client.DeleteEvent(ctx, &event_pb.DeleteEventRequest{Id: id})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	services.EXPECT().DeleteEvent(gomock.Any(), id, int64(1)).DoAndReturn(func(ctx context.Context, _ string, _ int64) error {
+		userID, ok := identity.UserID(ctx)
+		require.True(t, ok)
+		require.Equal(t, 2, userID)
+		return customerror.CustomError{
+			Field:   "id",
+			Message: "event with id " + id + " belongs to another user",
+			Err:     customerror.ErrForbidden,
+		}
+	})
+
+	ctx = metadata.AppendToOutgoingContext(ctx, auth.MetadataAPIKey, "other-key")
+	_, err = client.DeleteEvent(ctx, &event_pb.DeleteEventRequest{Id: id, ExpectedVersion: 1})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuthStreamInterceptor(t *testing.T) {
+	interceptor := authStreamInterceptor(newTestAuthenticator(t))
+	info := &grpc.StreamServerInfo{FullMethod: "/" + event_pb.EventService_ServiceDesc.ServiceName + "/Watch"}
+
+	var userID int
+	handler := func(_ interface{}, ss grpc.ServerStream) error {
+		userID, _ = identity.UserID(ss.Context())
+		return nil
+	}
+
+	err := interceptor(nil, &testServerStream{ctx: context.Background()}, info, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth.MetadataAPIKey, "owner-key"))
+	require.NoError(t, interceptor(nil, &testServerStream{ctx: ctx}, info, handler))
+	require.Equal(t, 1, userID)
+
+	// health watches are anonymous
+	info.FullMethod = healthServicePrefix + "Watch"
+	require.NoError(t, interceptor(nil, &testServerStream{ctx: context.Background()}, info, handler))
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	logger.EXPECT().Info("Request info GRPC", gomock.Any(), gomock.Any(), gomock.Any())
+	logger.EXPECT().WriteLogInFile("grpc.log", gomock.Any()).Return(nil)
+
+	// the chain of the server without authentication
+	chain := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return loggingStreamInterceptor(logger, "grpc.log")(srv, ss, info,
+			func(srv interface{}, ss grpc.ServerStream) error {
+				return metricsStreamInterceptor(srv, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+					return errorStreamInterceptor(logger)(srv, ss, info, handler)
+				})
+			})
+	}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/" + event_pb.EventService_ServiceDesc.ServiceName + "/WatchEvents"}
+	invalid := grpcRequests.Value(info.FullMethod, codes.InvalidArgument.String())
+
+	err := chain(nil, &testServerStream{ctx: context.Background()}, info, func(interface{}, grpc.ServerStream) error {
+		return customerror.CustomError{Field: "from", Message: "from cannot be after to", Err: customerror.ErrValidation}
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, invalid+1, grpcRequests.Value(info.FullMethod, codes.InvalidArgument.String()))
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func newTestAuthenticator(t *testing.T) *auth.Authenticator {
+	t.Helper()
+
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+
+	authenticator, err := auth.New(auth.Config{
+		APIKeys: []auth.APIKey{
+			{Name: "owner", UserID: 1, SHA256: hash("owner-key")},
+			{Name: "other", UserID: 2, SHA256: hash("other-key")},
+		},
+	})
+	require.NoError(t, err)
+	return authenticator
+}
+
+func TestHandlerGRPCErrorInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	testCases := []struct {
+		name           string
+		serviceErr     error
+		expectedCode   codes.Code
+		expectedReason string
+		expectedField  string
+	}{
+		{
+			name: "validation",
+			serviceErr: customerror.CustomError{
+				Field:   "title",
+				Message: "title cannot be empty",
+				Err:     customerror.ErrValidation,
+			},
+			expectedCode:   codes.InvalidArgument,
+			expectedReason: "VALIDATION",
+			expectedField:  "title",
+		},
+		{
+			name: "date is busy",
+			serviceErr: customerror.CustomError{
+				Field:   "date",
+				Message: "user already has an event at this time",
+				Err:     customerror.ErrDateBusy,
+			},
+			expectedCode:   codes.FailedPrecondition,
+			expectedReason: "DATE_BUSY",
+		},
+		{
+			name: "duplicate",
+			serviceErr: customerror.CustomError{
+				Message: "duplicate key value violates unique constraint",
+				Err:     customerror.ErrConflict,
+			},
+			expectedCode:   codes.AlreadyExists,
+			expectedReason: "CONFLICT",
+		},
+		{
+			name: "unavailable",
+			serviceErr: customerror.CustomError{
+				Message: "connection refused",
+				Err:     customerror.ErrUnavailable,
+			},
+			expectedCode:   codes.Unavailable,
+			expectedReason: "UNAVAILABLE",
+		},
+		{
+			name:           "internal",
+			serviceErr:     errors.New("no lines were inserted"),
+			expectedCode:   codes.Internal,
+			expectedReason: "INTERNAL",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			services.EXPECT().CreateEvent(gomock.Any(), gomock.Any(), gomock.Any()).Return("", tc.serviceErr)
+
+			// the details of internal errors are logged instead of being sent to the client
+			expectedMessage := tc.serviceErr.Error()
+			if tc.expectedCode == codes.Internal {
+				expectedMessage = internalMessage
+				logger.EXPECT().Error("internal error",
+					slog.String("method", "/"+event_pb.EventService_ServiceDesc.ServiceName+"/CreateEvent"),
+					slog.String("error", tc.serviceErr.Error()))
+			}
+
+			_, err := client.CreateEvent(ctx, &event_pb.CreateEventRequest{Title: "test"})
+
+			st := status.Convert(err)
+			require.Equal(t, tc.expectedCode, st.Code())
+			require.Equal(t, expectedMessage, st.Message())
+
+			var (
+				info       *errdetails.ErrorInfo
+				badRequest *errdetails.BadRequest
+			)
+			for _, detail := range st.Details() {
+				switch d := detail.(type) {
+				case *errdetails.ErrorInfo:
+					info = d
+				case *errdetails.BadRequest:
+					badRequest = d
+				}
+			}
+
+			require.NotNil(t, info)
+			require.Equal(t, tc.expectedReason, info.GetReason())
+			require.Equal(t, errorDomain, info.GetDomain())
+
+			if tc.expectedField == "" {
+				require.Nil(t, badRequest)
+				return
+			}
+			require.NotNil(t, badRequest)
+			require.Len(t, badRequest.GetFieldViolations(), 1)
+			require.Equal(t, tc.expectedField, badRequest.GetFieldViolations()[0].GetField())
+		})
+	}
+
+	// invalid requests are reported with the invalid field
+	_, err = client.GetEvent(ctx, &event_pb.GetEventRequest{Id: "invalid"})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 2)
+	require.Equal(t, "id", st.Details()[1].(*errdetails.BadRequest).GetFieldViolations()[0].GetField())
+}
+
+func TestHandlerGRPCHealthAndMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bufferSize := 1024 * 1024
+	lis := bufconn.Listen(bufferSize)
+	defer lis.Close()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(metricsInterceptor, errorInterceptor(logger),
+		authInterceptor(newTestAuthenticator(t))))
+	defer srv.Stop()
+
+	handler := HandlerGRPC{
+		service: mock_service.NewMockServices(ctrl),
+		logger:  logger,
+	}
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	ctx := context.Background()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	method := "/" + event_pb.EventService_ServiceDesc.ServiceName + "/DeleteEvent"
+	unauthenticated := grpcRequests.Value(method, codes.Unauthenticated.String())
+
+	_, err = event_pb.NewEventServiceClient(conn).DeleteEvent(ctx, &event_pb.DeleteEventRequest{Id: uuid.New().String()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Equal(t, unauthenticated+1, grpcRequests.Value(method, codes.Unauthenticated.String()))
+
+	// health checks do not require the user
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+}
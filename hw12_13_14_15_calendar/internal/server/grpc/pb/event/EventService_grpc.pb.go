@@ -20,12 +20,21 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	EventService_CreateEvent_FullMethodName       = "/event.EventService/CreateEvent"
-	EventService_UpdateEvent_FullMethodName       = "/event.EventService/UpdateEvent"
-	EventService_DeleteEvent_FullMethodName       = "/event.EventService/DeleteEvent"
-	EventService_ListEventsByDay_FullMethodName   = "/event.EventService/ListEventsByDay"
-	EventService_ListEventsByWeek_FullMethodName  = "/event.EventService/ListEventsByWeek"
-	EventService_ListEventsByMonth_FullMethodName = "/event.EventService/ListEventsByMonth"
+	EventService_CreateEvent_FullMethodName         = "/event.EventService/CreateEvent"
+	EventService_UpdateEvent_FullMethodName         = "/event.EventService/UpdateEvent"
+	EventService_GetEvent_FullMethodName            = "/event.EventService/GetEvent"
+	EventService_DeleteEvent_FullMethodName         = "/event.EventService/DeleteEvent"
+	EventService_ListEventsByDay_FullMethodName     = "/event.EventService/ListEventsByDay"
+	EventService_ListEventsByWeek_FullMethodName    = "/event.EventService/ListEventsByWeek"
+	EventService_ListEventsByMonth_FullMethodName   = "/event.EventService/ListEventsByMonth"
+	EventService_GetEventsInRange_FullMethodName    = "/event.EventService/GetEventsInRange"
+	EventService_ExportEvents_FullMethodName        = "/event.EventService/ExportEvents"
+	EventService_ImportEvents_FullMethodName        = "/event.EventService/ImportEvents"
+	EventService_WatchEvents_FullMethodName         = "/event.EventService/WatchEvents"
+	EventService_InviteAttendees_FullMethodName     = "/event.EventService/InviteAttendees"
+	EventService_RespondToInvitation_FullMethodName = "/event.EventService/RespondToInvitation"
+	EventService_GetFreeBusy_FullMethodName         = "/event.EventService/GetFreeBusy"
+	EventService_FindSlots_FullMethodName           = "/event.EventService/FindSlots"
 )
 
 // EventServiceClient is the client API for EventService service.
@@ -34,10 +43,19 @@ const (
 type EventServiceClient interface {
 	CreateEvent(ctx context.Context, in *CreateEventRequest, opts ...grpc.CallOption) (*CreateEventResponse, error)
 	UpdateEvent(ctx context.Context, in *UpdateEventRequest, opts ...grpc.CallOption) (*UpdateEventResponse, error)
+	GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*GetEventResponse, error)
 	DeleteEvent(ctx context.Context, in *DeleteEventRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	ListEventsByDay(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
 	ListEventsByWeek(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
 	ListEventsByMonth(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
+	GetEventsInRange(ctx context.Context, in *GetEventsInRangeRequest, opts ...grpc.CallOption) (*GetEventsInRangeResponse, error)
+	ExportEvents(ctx context.Context, in *ExportEventsRequest, opts ...grpc.CallOption) (*ExportEventsResponse, error)
+	ImportEvents(ctx context.Context, in *ImportEventsRequest, opts ...grpc.CallOption) (*ImportEventsResponse, error)
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (EventService_WatchEventsClient, error)
+	InviteAttendees(ctx context.Context, in *InviteAttendeesRequest, opts ...grpc.CallOption) (*InviteAttendeesResponse, error)
+	RespondToInvitation(ctx context.Context, in *RespondToInvitationRequest, opts ...grpc.CallOption) (*RespondToInvitationResponse, error)
+	GetFreeBusy(ctx context.Context, in *GetFreeBusyRequest, opts ...grpc.CallOption) (*GetFreeBusyResponse, error)
+	FindSlots(ctx context.Context, in *FindSlotsRequest, opts ...grpc.CallOption) (*FindSlotsResponse, error)
 }
 
 type eventServiceClient struct {
@@ -66,6 +84,15 @@ func (c *eventServiceClient) UpdateEvent(ctx context.Context, in *UpdateEventReq
 	return out, nil
 }
 
+func (c *eventServiceClient) GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*GetEventResponse, error) {
+	out := new(GetEventResponse)
+	err := c.cc.Invoke(ctx, EventService_GetEvent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *eventServiceClient) DeleteEvent(ctx context.Context, in *DeleteEventRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, EventService_DeleteEvent_FullMethodName, in, out, opts...)
@@ -102,16 +129,120 @@ func (c *eventServiceClient) ListEventsByMonth(ctx context.Context, in *ListEven
 	return out, nil
 }
 
+func (c *eventServiceClient) GetEventsInRange(ctx context.Context, in *GetEventsInRangeRequest, opts ...grpc.CallOption) (*GetEventsInRangeResponse, error) {
+	out := new(GetEventsInRangeResponse)
+	err := c.cc.Invoke(ctx, EventService_GetEventsInRange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) ExportEvents(ctx context.Context, in *ExportEventsRequest, opts ...grpc.CallOption) (*ExportEventsResponse, error) {
+	out := new(ExportEventsResponse)
+	err := c.cc.Invoke(ctx, EventService_ExportEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) ImportEvents(ctx context.Context, in *ImportEventsRequest, opts ...grpc.CallOption) (*ImportEventsResponse, error) {
+	out := new(ImportEventsResponse)
+	err := c.cc.Invoke(ctx, EventService_ImportEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (EventService_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &EventService_ServiceDesc.Streams[0], EventService_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &eventServiceWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type EventService_WatchEventsClient interface {
+	Recv() (*EventChange, error)
+	grpc.ClientStream
+}
+
+type eventServiceWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *eventServiceWatchEventsClient) Recv() (*EventChange, error) {
+	m := new(EventChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *eventServiceClient) InviteAttendees(ctx context.Context, in *InviteAttendeesRequest, opts ...grpc.CallOption) (*InviteAttendeesResponse, error) {
+	out := new(InviteAttendeesResponse)
+	err := c.cc.Invoke(ctx, EventService_InviteAttendees_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) RespondToInvitation(ctx context.Context, in *RespondToInvitationRequest, opts ...grpc.CallOption) (*RespondToInvitationResponse, error) {
+	out := new(RespondToInvitationResponse)
+	err := c.cc.Invoke(ctx, EventService_RespondToInvitation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) GetFreeBusy(ctx context.Context, in *GetFreeBusyRequest, opts ...grpc.CallOption) (*GetFreeBusyResponse, error) {
+	out := new(GetFreeBusyResponse)
+	err := c.cc.Invoke(ctx, EventService_GetFreeBusy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) FindSlots(ctx context.Context, in *FindSlotsRequest, opts ...grpc.CallOption) (*FindSlotsResponse, error) {
+	out := new(FindSlotsResponse)
+	err := c.cc.Invoke(ctx, EventService_FindSlots_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // EventServiceServer is the server API for EventService service.
 // All implementations must embed UnimplementedEventServiceServer
 // for forward compatibility
 type EventServiceServer interface {
 	CreateEvent(context.Context, *CreateEventRequest) (*CreateEventResponse, error)
 	UpdateEvent(context.Context, *UpdateEventRequest) (*UpdateEventResponse, error)
+	GetEvent(context.Context, *GetEventRequest) (*GetEventResponse, error)
 	DeleteEvent(context.Context, *DeleteEventRequest) (*emptypb.Empty, error)
 	ListEventsByDay(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
 	ListEventsByWeek(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
 	ListEventsByMonth(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
+	GetEventsInRange(context.Context, *GetEventsInRangeRequest) (*GetEventsInRangeResponse, error)
+	ExportEvents(context.Context, *ExportEventsRequest) (*ExportEventsResponse, error)
+	ImportEvents(context.Context, *ImportEventsRequest) (*ImportEventsResponse, error)
+	WatchEvents(*WatchEventsRequest, EventService_WatchEventsServer) error
+	InviteAttendees(context.Context, *InviteAttendeesRequest) (*InviteAttendeesResponse, error)
+	RespondToInvitation(context.Context, *RespondToInvitationRequest) (*RespondToInvitationResponse, error)
+	GetFreeBusy(context.Context, *GetFreeBusyRequest) (*GetFreeBusyResponse, error)
+	FindSlots(context.Context, *FindSlotsRequest) (*FindSlotsResponse, error)
 	mustEmbedUnimplementedEventServiceServer()
 }
 
@@ -125,6 +256,9 @@ func (UnimplementedEventServiceServer) CreateEvent(context.Context, *CreateEvent
 func (UnimplementedEventServiceServer) UpdateEvent(context.Context, *UpdateEventRequest) (*UpdateEventResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateEvent not implemented")
 }
+func (UnimplementedEventServiceServer) GetEvent(context.Context, *GetEventRequest) (*GetEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEvent not implemented")
+}
 func (UnimplementedEventServiceServer) DeleteEvent(context.Context, *DeleteEventRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteEvent not implemented")
 }
@@ -137,6 +271,30 @@ func (UnimplementedEventServiceServer) ListEventsByWeek(context.Context, *ListEv
 func (UnimplementedEventServiceServer) ListEventsByMonth(context.Context, *ListEventsRequest) (*ListEventsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListEventsByMonth not implemented")
 }
+func (UnimplementedEventServiceServer) GetEventsInRange(context.Context, *GetEventsInRangeRequest) (*GetEventsInRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEventsInRange not implemented")
+}
+func (UnimplementedEventServiceServer) ExportEvents(context.Context, *ExportEventsRequest) (*ExportEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportEvents not implemented")
+}
+func (UnimplementedEventServiceServer) ImportEvents(context.Context, *ImportEventsRequest) (*ImportEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportEvents not implemented")
+}
+func (UnimplementedEventServiceServer) WatchEvents(*WatchEventsRequest, EventService_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedEventServiceServer) InviteAttendees(context.Context, *InviteAttendeesRequest) (*InviteAttendeesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InviteAttendees not implemented")
+}
+func (UnimplementedEventServiceServer) RespondToInvitation(context.Context, *RespondToInvitationRequest) (*RespondToInvitationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RespondToInvitation not implemented")
+}
+func (UnimplementedEventServiceServer) GetFreeBusy(context.Context, *GetFreeBusyRequest) (*GetFreeBusyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFreeBusy not implemented")
+}
+func (UnimplementedEventServiceServer) FindSlots(context.Context, *FindSlotsRequest) (*FindSlotsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindSlots not implemented")
+}
 func (UnimplementedEventServiceServer) mustEmbedUnimplementedEventServiceServer() {}
 
 // UnsafeEventServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -186,6 +344,24 @@ func _EventService_UpdateEvent_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _EventService_GetEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).GetEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_GetEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).GetEvent(ctx, req.(*GetEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _EventService_DeleteEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteEventRequest)
 	if err := dec(in); err != nil {
@@ -258,6 +434,153 @@ func _EventService_ListEventsByMonth_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _EventService_GetEventsInRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEventsInRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).GetEventsInRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_GetEventsInRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).GetEventsInRange(ctx, req.(*GetEventsInRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_ExportEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).ExportEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_ExportEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).ExportEvents(ctx, req.(*ExportEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_ImportEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).ImportEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_ImportEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).ImportEvents(ctx, req.(*ImportEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventServiceServer).WatchEvents(m, &eventServiceWatchEventsServer{stream})
+}
+
+type EventService_WatchEventsServer interface {
+	Send(*EventChange) error
+	grpc.ServerStream
+}
+
+type eventServiceWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *eventServiceWatchEventsServer) Send(m *EventChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _EventService_InviteAttendees_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InviteAttendeesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).InviteAttendees(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_InviteAttendees_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).InviteAttendees(ctx, req.(*InviteAttendeesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_RespondToInvitation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RespondToInvitationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).RespondToInvitation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_RespondToInvitation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).RespondToInvitation(ctx, req.(*RespondToInvitationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_GetFreeBusy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFreeBusyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).GetFreeBusy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_GetFreeBusy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).GetFreeBusy(ctx, req.(*GetFreeBusyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_FindSlots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindSlotsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).FindSlots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_FindSlots_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).FindSlots(ctx, req.(*FindSlotsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // EventService_ServiceDesc is the grpc.ServiceDesc for EventService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -273,6 +596,10 @@ var EventService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateEvent",
 			Handler:    _EventService_UpdateEvent_Handler,
 		},
+		{
+			MethodName: "GetEvent",
+			Handler:    _EventService_GetEvent_Handler,
+		},
 		{
 			MethodName: "DeleteEvent",
 			Handler:    _EventService_DeleteEvent_Handler,
@@ -289,7 +616,41 @@ var EventService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListEventsByMonth",
 			Handler:    _EventService_ListEventsByMonth_Handler,
 		},
+		{
+			MethodName: "GetEventsInRange",
+			Handler:    _EventService_GetEventsInRange_Handler,
+		},
+		{
+			MethodName: "ExportEvents",
+			Handler:    _EventService_ExportEvents_Handler,
+		},
+		{
+			MethodName: "ImportEvents",
+			Handler:    _EventService_ImportEvents_Handler,
+		},
+		{
+			MethodName: "InviteAttendees",
+			Handler:    _EventService_InviteAttendees_Handler,
+		},
+		{
+			MethodName: "RespondToInvitation",
+			Handler:    _EventService_RespondToInvitation_Handler,
+		},
+		{
+			MethodName: "GetFreeBusy",
+			Handler:    _EventService_GetFreeBusy_Handler,
+		},
+		{
+			MethodName: "FindSlots",
+			Handler:    _EventService_FindSlots_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _EventService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "event/EventService.proto",
 }
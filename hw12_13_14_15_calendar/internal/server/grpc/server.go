@@ -1,13 +1,19 @@
 package grpc
 
 import (
+	"context"
 	"net"
 	"time"
 
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/auth"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
 	event_pb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/tlsconfig"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -18,17 +24,41 @@ type Config struct {
 	MaxConnectionAge  time.Duration
 	Time              time.Duration
 	Timeout           time.Duration
+	TLS               tlsconfig.Config
 }
 
+// healthServicePrefix is the prefix of full methods of the standard health service.
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
 type ServerGRPC struct {
 	srv     *grpc.Server
 	handler *HandlerGRPC
+	health  *health.Server
+	tls     *tlsconfig.Reloader
 }
 
-func NewServerGRPC(handler *HandlerGRPC, log logger.Logger, cfg Config, logPath string) *ServerGRPC {
+// NewServerGRPC returns the error if TLS is enabled and its files cannot be loaded.
+func NewServerGRPC(handler *HandlerGRPC, log logger.Logger, cfg Config, logPath string,
+	authenticator *auth.Authenticator,
+) (*ServerGRPC, error) {
+	creds := insecure.NewCredentials()
+
+	var reloader *tlsconfig.Reloader
+	if cfg.TLS.Enabled() {
+		var err error
+		reloader, err = tlsconfig.NewReloader(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(reloader.TLSConfig())
+	}
+
 	serverOptions := []grpc.ServerOption{
-		grpc.Creds(insecure.NewCredentials()),
-		grpc.UnaryInterceptor(loggingInterceptor(log, logPath)),
+		grpc.Creds(creds),
+		grpc.ChainUnaryInterceptor(loggingInterceptor(log, logPath), metricsInterceptor, errorInterceptor(log),
+			authInterceptor(authenticator)),
+		grpc.ChainStreamInterceptor(loggingStreamInterceptor(log, logPath), metricsStreamInterceptor,
+			errorStreamInterceptor(log), authStreamInterceptor(authenticator)),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			MaxConnectionIdle: cfg.MaxConnectionIdle,
 			MaxConnectionAge:  cfg.MaxConnectionAge,
@@ -39,9 +69,35 @@ func NewServerGRPC(handler *HandlerGRPC, log logger.Logger, cfg Config, logPath
 
 	srv := grpc.NewServer(serverOptions...)
 
+	// the server is not serving until the first readiness check passes
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(event_pb.EventService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+
 	return &ServerGRPC{
 		srv:     srv,
 		handler: handler,
+		health:  healthServer,
+		tls:     reloader,
+	}, nil
+}
+
+// SetServing sets the status of the server and the event service reported by the health service.
+func (s *ServerGRPC) SetServing(serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(event_pb.EventService_ServiceDesc.ServiceName, status)
+}
+
+// WatchTLS reloads the changed certificate files until ctx is done, it returns at once if TLS is disabled.
+func (s *ServerGRPC) WatchTLS(ctx context.Context, onError func(err error)) {
+	if s.tls != nil {
+		s.tls.Watch(ctx, onError)
 	}
 }
 
@@ -57,5 +113,8 @@ func (s *ServerGRPC) Start(cfg Config) error {
 }
 
 func (s *ServerGRPC) Stop() {
+	// watchers see NOT_SERVING before the connections are closed
+	s.health.Shutdown()
+	s.handler.stopStreams()
 	s.srv.GracefulStop()
 }
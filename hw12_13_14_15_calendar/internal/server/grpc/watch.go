@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	eventpb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// WatchEvents streams changes of the caller's events until the client cancels the call.
+// The watcher which falls behind or is connected to the stopping server gets Unavailable
+// and has to reload the events and watch again.
+func (h *HandlerGRPC) WatchEvents(req *eventpb.WatchEventsRequest, stream eventpb.EventService_WatchEventsServer) error { //nolint:lll
+	var from, to time.Time
+	if req.GetFrom() != nil {
+		from = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		to = req.GetTo().AsTime()
+	}
+
+	ctx := stream.Context()
+
+	sub, err := h.service.WatchEvents(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-h.stopping:
+			return status.Error(codes.Unavailable, ErrShuttingDown.Error())
+		case change, ok := <-sub.Changes():
+			if !ok {
+				return status.Error(codes.Unavailable, sub.Err().Error())
+			}
+			if err := stream.Send(toPBChange(change)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toPBChange(change models.EventChange) *eventpb.EventChange {
+	res := &eventpb.EventChange{
+		Type:      toPBChangeType(change.Type),
+		EventId:   change.EventID,
+		Version:   change.Version,
+		ChangedAt: timestamppb.New(change.ChangedAt),
+	}
+	if change.Event != nil {
+		res.Event = toPBEvent(*change.Event)
+	}
+	return res
+}
+
+func toPBChangeType(changeType models.ChangeType) eventpb.ChangeType {
+	switch changeType {
+	case models.ChangeCreated:
+		return eventpb.ChangeType_CHANGE_TYPE_CREATED
+	case models.ChangeUpdated:
+		return eventpb.ChangeType_CHANGE_TYPE_UPDATED
+	case models.ChangeDeleted:
+		return eventpb.ChangeType_CHANGE_TYPE_DELETED
+	}
+	return eventpb.ChangeType_CHANGE_TYPE_UNSPECIFIED
+}
@@ -0,0 +1,120 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	event_pb "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/server/grpc/pb/event"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestHandlerGRPCWatchEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := mock_logger.NewMockLogger(ctrl)
+	srv, lis := startGRPCServer(logger)
+	defer srv.Stop()
+	defer lis.Close()
+
+	services := mock_service.NewMockServices(ctrl)
+	handler := HandlerGRPC{
+		service: services,
+		logger:  logger,
+	}
+
+	event_pb.RegisterEventServiceServer(srv, &handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "",
+		grpc.WithContextDialer(getDialer(lis)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := event_pb.NewEventServiceClient(conn)
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	changedAt := time.Date(2023, 7, 2, 0, 0, 0, 0, time.UTC)
+	event := models.Event{
+		ID:       "id",
+		Title:    "title",
+		Date:     from.Add(time.Hour),
+		Duration: time.Hour,
+		UserID:   1,
+		Version:  2,
+	}
+
+	bus := changefeed.NewBus(changefeed.DefaultBuffer)
+	subscribed := make(chan *changefeed.Subscription, 1)
+	services.EXPECT().WatchEvents(gomock.Any(), from, time.Time{}).
+		DoAndReturn(func(context.Context, time.Time, time.Time) (*changefeed.Subscription, error) {
+			sub := bus.Subscribe(changefeed.Filter{UserID: 1, From: from})
+			subscribed <- sub
+			return sub, nil
+		})
+
+	stream, err := client.WatchEvents(ctx, &event_pb.WatchEventsRequest{From: timestamppb.New(from)})
+	require.NoError(t, err)
+	sub := <-subscribed
+
+	bus.Publish(ctx, models.EventChange{
+		Type:      models.ChangeUpdated,
+		EventID:   event.ID,
+		UserID:    1,
+		Version:   2,
+		ChangedAt: changedAt,
+		Event:     &event,
+	})
+	bus.Publish(ctx, models.EventChange{
+		Type:      models.ChangeDeleted,
+		EventID:   "other",
+		UserID:    1,
+		Version:   5,
+		ChangedAt: changedAt,
+	})
+
+	change, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, event_pb.ChangeType_CHANGE_TYPE_UPDATED, change.GetType())
+	require.Equal(t, event.ID, change.GetEventId())
+	require.Equal(t, int64(2), change.GetVersion())
+	require.Equal(t, changedAt, change.GetChangedAt().AsTime())
+	require.Equal(t, event.Title, change.GetEvent().GetTitle())
+
+	change, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, event_pb.ChangeType_CHANGE_TYPE_DELETED, change.GetType())
+	require.Equal(t, "other", change.GetEventId())
+	require.Nil(t, change.GetEvent())
+
+	// the closed subscription ends the stream, so the client watches again
+	sub.Close()
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	services.EXPECT().WatchEvents(gomock.Any(), time.Time{}, time.Time{}).Return(nil, customerror.CustomError{
+		Field:   "user_id",
+		Message: "user id of the caller is missing",
+		Err:     customerror.ErrUnauthenticated,
+	})
+
+	stream, err = client.WatchEvents(ctx, &event_pb.WatchEventsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
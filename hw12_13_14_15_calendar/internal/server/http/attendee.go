@@ -0,0 +1,117 @@
+package internalhttp
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+var (
+	inviteAction  = "invite"
+	respondAction = "respond"
+)
+
+var (
+	ErrParsingUserIDs = errors.New("user_ids must be a list of user ids")
+	ErrParsingStatus  = errors.New("status must be one of accepted, declined, tentative")
+)
+
+type bodyInvitation struct {
+	UserIDs []int `json:"user_ids"`
+}
+
+type bodyResponse struct {
+	Status string `json:"status"`
+}
+
+type attendeeResponse struct {
+	UserID      int    `json:"user_id"`
+	Status      string `json:"status"`
+	RespondedAt string `json:"responded_at,omitempty"`
+}
+
+// InviteAttendees invites users to the caller's event of the version in the If-Match header.
+func (h *HandlerHTTP) InviteAttendees(c *gin.Context) {
+	id := c.Param("id")
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		resp := newResponse(inviteAction, "id (param)", ErrInvalidID.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	version, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		resp := newResponse(inviteAction, "If-Match (header)", err.Error(), err)
+		h.sentResponse(c, ifMatchStatus(err), resp)
+		return
+	}
+
+	var invitation bodyInvitation
+
+	if err := c.ShouldBindJSON(&invitation); err != nil {
+		resp := newResponse(inviteAction, "user_ids", ErrParsingUserIDs.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	event, err := h.services.InviteAttendees(c, parsedID.String(), version, invitation.UserIDs)
+	if err != nil {
+		message := "error inviting attendees"
+		resp := newResponse(inviteAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+
+	c.Header("ETag", versionTag(event.Version))
+	c.JSON(http.StatusOK, toResponse(event))
+}
+
+// RespondToInvitation records the caller's answer to the invitation to the event.
+func (h *HandlerHTTP) RespondToInvitation(c *gin.Context) {
+	id := c.Param("id")
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		resp := newResponse(respondAction, "id (param)", ErrInvalidID.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	var answer bodyResponse
+
+	if err := c.ShouldBindJSON(&answer); err != nil {
+		resp := newResponse(respondAction, "status", ErrParsingStatus.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	event, err := h.services.RespondToInvitation(c, parsedID.String(), models.AttendeeStatus(answer.Status))
+	if err != nil {
+		message := "error responding to invitation"
+		resp := newResponse(respondAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+
+	c.Header("ETag", versionTag(event.Version))
+	c.JSON(http.StatusOK, toResponse(event))
+}
+
+func toAttendeeResponses(attendees []models.Attendee) []attendeeResponse {
+	var responses []attendeeResponse
+	for _, attendee := range attendees {
+		r := attendeeResponse{
+			UserID: attendee.UserID,
+			Status: string(attendee.Status),
+		}
+		if !attendee.RespondedAt.IsZero() {
+			r.RespondedAt = attendee.RespondedAt.Format(time.RFC3339)
+		}
+		responses = append(responses, r)
+	}
+	return responses
+}
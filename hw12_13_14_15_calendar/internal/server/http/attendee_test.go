@@ -0,0 +1,205 @@
+package internalhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/exp/slog"
+)
+
+func TestHandlerHTTPInviteAttendees(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	id := uuid.New().String()
+	respondedAt := time.Date(2023, 7, 21, 9, 0, 0, 0, time.UTC)
+
+	event := models.Event{
+		ID:       id,
+		Title:    "Planning",
+		Date:     time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+		UserID:   1,
+		Attendees: []models.Attendee{
+			{UserID: 2, Status: models.AttendeeAccepted, RespondedAt: respondedAt},
+			{UserID: 3, Status: models.AttendeeNeedsAction},
+		},
+		Version: 4,
+	}
+
+	services.EXPECT().InviteAttendees(gomock.Any(), id, int64(3), []int{2, 3}).Return(event, nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.POST(url+"/:id/attendees", handler.InviteAttendees)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/"+id+"/attendees",
+		bytes.NewBufferString(`{"user_ids":[2,3]}`))
+	require.NoError(t, err)
+	req.Header.Set("If-Match", `"3"`)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	var responseBody Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &responseBody))
+	require.Equal(t, []attendeeResponse{
+		{UserID: 2, Status: "accepted", RespondedAt: "2023-07-21T09:00:00Z"},
+		{UserID: 3, Status: "needs-action"},
+	}, responseBody.Attendees)
+}
+
+func TestHandlerHTTPInviteAttendeesError(t *testing.T) {
+	id := uuid.New().String()
+
+	testCases := []struct {
+		name         string
+		ifMatch      string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{
+			name:         "missing If-Match",
+			body:         `{"user_ids":[2]}`,
+			expectedCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:         "invalid user ids",
+			ifMatch:      `"1"`,
+			body:         `{"user_ids":["bob"]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:    "stale version",
+			ifMatch: `"1"`,
+			body:    `{"user_ids":[2]}`,
+			serviceErr: customerror.CustomError{
+				Field:   "version",
+				Message: "event has version 2, not 1",
+				Err:     customerror.ErrVersionMismatch,
+			},
+			expectedCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:    "other user",
+			ifMatch: `"1"`,
+			body:    `{"user_ids":[2]}`,
+			serviceErr: customerror.CustomError{
+				Field:   "id",
+				Message: "event belongs to another user",
+				Err:     customerror.ErrForbidden,
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			if tc.serviceErr != nil {
+				services.EXPECT().InviteAttendees(gomock.Any(), id, int64(1), []int{2}).Return(models.Event{}, tc.serviceErr)
+			}
+			logger.EXPECT().Error(gomock.Any(), slog.String("action", inviteAction), gomock.Any())
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.POST(url+"/:id/attendees", handler.InviteAttendees)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/"+id+"/attendees",
+				bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandlerHTTPRespondToInvitation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	id := uuid.New().String()
+
+	event := models.Event{
+		ID:        id,
+		Title:     "Planning",
+		Date:      time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
+		Duration:  time.Hour,
+		UserID:    1,
+		Attendees: []models.Attendee{{UserID: 2, Status: models.AttendeeTentative}},
+		Version:   5,
+	}
+
+	services.EXPECT().RespondToInvitation(gomock.Any(), id, models.AttendeeTentative).Return(event, nil)
+	services.EXPECT().RespondToInvitation(gomock.Any(), id, models.AttendeeStatus("maybe")).
+		Return(models.Event{}, customerror.CustomError{
+			Field:   "status",
+			Message: "response must be one of accepted, declined, tentative",
+			Err:     customerror.ErrValidation,
+		})
+	logger.EXPECT().Error("error responding to invitation", slog.String("action", respondAction), gomock.Any())
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.POST(url+"/:id/rsvp", handler.RespondToInvitation)
+
+	ctx := context.Background()
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/"+id+"/rsvp",
+		bytes.NewBufferString(`{"status":"tentative"}`))
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `"5"`, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, url+"/"+id+"/rsvp",
+		bytes.NewBufferString(`{"status":"maybe"}`))
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,13 +1,22 @@
 package internalhttp
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/pagination"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/period"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
 )
 
 var (
@@ -17,6 +26,8 @@ var (
 	getByDayAction   = "get by day"
 	getByWeekAction  = "get by week"
 	getByMonthAction = "get by month"
+	getInRangeAction = "get in range"
+	getByIDAction    = "get by id"
 )
 
 var (
@@ -25,15 +36,41 @@ var (
 	ErrParsingDuration             = errors.New("duration must be represented only in hours, minutes, seconds")
 	ErrParsingNotificationInterval = errors.New("notification_interval must be represented only in hours, minutes, seconds")
 	ErrInvalidID                   = errors.New("invalid id")
+	ErrParsingReminders            = errors.New("reminders must be a list of reminders with before in hours, minutes, seconds")
+	ErrParsingRecurrenceRule       = errors.New("recurrence_rule must be in RFC 5545 RRULE format")
+	ErrParsingRecurrenceExceptions = errors.New("recurrence_exceptions must be in RFC3339 format and require a recurring event")
+	ErrParsingRecurrenceTimeZone   = errors.New("recurrence_time_zone must be an IANA time zone name and require a recurring event")
+	ErrParsingOccurrence           = errors.New("occurrence must be in RFC3339 format")
+	ErrParsingAllowOverlap         = errors.New("allow_overlap must be a boolean")
+	ErrParsingLimit                = errors.New("limit must be a non-negative integer")
+	ErrParsingTimeZone             = errors.New("tz must be an IANA time zone name")
+	ErrRemovingRequiredField       = errors.New("title, date and duration cannot be removed")
+	ErrUnsupportedPatch            = errors.New("patch must be application/merge-patch+json or application/json")
+	ErrMissingIfMatch              = errors.New("If-Match header with the event version is required")
+	ErrParsingIfMatch              = errors.New("If-Match must be the strong ETag of the event")
 )
 
+// mergePatchContentType is the media type of JSON merge patches.
+const mergePatchContentType = "application/merge-patch+json"
+
+var nullMember = []byte("null")
+
 type bodyEvent struct {
-	Title                string `json:"title"`
-	Date                 string `json:"date"`
-	Duration             string `json:"duration"`
-	Description          string `json:"description"`
-	UserID               int    `json:"user_id"`
-	NotificationInterval string `json:"notification_interval"`
+	Title                string         `json:"title"`
+	Date                 string         `json:"date"`
+	Duration             string         `json:"duration"`
+	Description          string         `json:"description"`
+	NotificationInterval string         `json:"notification_interval"`
+	Reminders            []bodyReminder `json:"reminders"`
+	RecurrenceRule       string         `json:"recurrence_rule"`
+	RecurrenceExceptions []string       `json:"recurrence_exceptions"`
+	RecurrenceTimeZone   string         `json:"recurrence_time_zone"`
+}
+
+// bodyReminder is the reminder sent before the start of the event. The channel defaults to log.
+type bodyReminder struct {
+	Before  string `json:"before"`
+	Channel string `json:"channel"`
 }
 
 func (h *HandlerHTTP) CreateEvent(c *gin.Context) {
@@ -69,18 +106,41 @@ func (h *HandlerHTTP) CreateEvent(c *gin.Context) {
 		}
 	}
 
+	reminders, err := parseReminders(eventFromBody.Reminders)
+	if err != nil {
+		resp := newResponse(createAction, "reminders", err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	rec, field, err := parseRecurrence(eventFromBody.RecurrenceRule, eventFromBody.RecurrenceExceptions,
+		eventFromBody.RecurrenceTimeZone)
+	if err != nil {
+		resp := newResponse(createAction, field, err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	opts, err := parseEventOptions(c)
+	if err != nil {
+		resp := newResponse(createAction, "allow_overlap (query)", err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
 	event.Title = eventFromBody.Title
 	event.Date = date
 	event.Duration = duration
 	event.Description = eventFromBody.Description
-	event.UserID = eventFromBody.UserID
 	event.NotificationInterval = notificationInterval
+	event.Reminders = reminders
+	event.Recurrence = rec
 
-	id, err := h.services.CreateEvent(c, event)
+	id, err := h.services.CreateEvent(c, event, opts)
 	if err != nil {
 		message := "error creating event"
 		resp := newResponse(createAction, "notification_interval", message, err)
-		h.sentResponse(c, http.StatusInternalServerError, resp)
+		h.sentResponse(c, errorStatus(err), resp)
 		return
 	}
 
@@ -90,15 +150,49 @@ func (h *HandlerHTTP) CreateEvent(c *gin.Context) {
 }
 
 type Response struct {
-	ID                   string `json:"id"`
-	Title                string `json:"title"`
-	Date                 string `json:"date"`
-	Duration             string `json:"duration"`
-	Description          string `json:"description"`
-	UserID               int    `json:"user_id"`
-	NotificationInterval string `json:"notification_interval"`
+	ID                   string             `json:"id"`
+	Title                string             `json:"title"`
+	Date                 string             `json:"date"`
+	Duration             string             `json:"duration"`
+	Description          string             `json:"description"`
+	UserID               int                `json:"user_id"`
+	NotificationInterval string             `json:"notification_interval"`
+	Reminders            []reminderResponse `json:"reminders,omitempty"`
+	Attendees            []attendeeResponse `json:"attendees,omitempty"`
+	RecurrenceRule       string             `json:"recurrence_rule,omitempty"`
+	RecurrenceExceptions []string           `json:"recurrence_exceptions,omitempty"`
+	RecurrenceTimeZone   string             `json:"recurrence_time_zone,omitempty"`
+	RecurrenceID         string             `json:"recurrence_id,omitempty"`
+	OriginalDate         string             `json:"original_date,omitempty"`
+	Version              int64              `json:"version"`
+	UpdatedAt            string             `json:"updated_at,omitempty"`
 }
 
+type reminderResponse struct {
+	ID       int64  `json:"id"`
+	Before   string `json:"before"`
+	Channel  string `json:"channel"`
+	Status   string `json:"status"`
+	QueuedAt string `json:"queued_at,omitempty"`
+	SentAt   string `json:"sent_at,omitempty"`
+}
+
+// patchEvent is the JSON merge patch (RFC 7396) of the event. Members missing from the patch are nil
+// and leave the fields unchanged, null members remove the values of the fields.
+type patchEvent struct {
+	Title                json.RawMessage `json:"title"`
+	Date                 json.RawMessage `json:"date"`
+	Duration             json.RawMessage `json:"duration"`
+	Description          json.RawMessage `json:"description"`
+	NotificationInterval json.RawMessage `json:"notification_interval"`
+	Reminders            json.RawMessage `json:"reminders"`
+	RecurrenceRule       json.RawMessage `json:"recurrence_rule"`
+	RecurrenceExceptions json.RawMessage `json:"recurrence_exceptions"`
+	RecurrenceTimeZone   json.RawMessage `json:"recurrence_time_zone"`
+}
+
+// UpdateEvent applies the JSON merge patch to the event or to its occurrences
+// if the If-Match header holds the current ETag of the event.
 func (h *HandlerHTTP) UpdateEvent(c *gin.Context) {
 	id := c.Param("id")
 	parsedID, err := uuid.Parse(id)
@@ -108,70 +202,191 @@ func (h *HandlerHTTP) UpdateEvent(c *gin.Context) {
 		return
 	}
 
-	var eventFromBody bodyEvent
+	version, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		resp := newResponse(updateAction, "If-Match (header)", err.Error(), err)
+		h.sentResponse(c, ifMatchStatus(err), resp)
+		return
+	}
 
-	if err := c.ShouldBindJSON(&eventFromBody); err != nil {
+	switch c.ContentType() {
+	case "", mergePatchContentType, binding.MIMEJSON:
+	default:
+		resp := newResponse(updateAction, "Content-Type (header)", ErrUnsupportedPatch.Error(), ErrUnsupportedPatch)
+		h.sentResponse(c, http.StatusUnsupportedMediaType, resp)
+		return
+	}
+
+	var patch patchEvent
+
+	if err := c.ShouldBindJSON(&patch); err != nil {
 		resp := newResponse(updateAction, "", ErrParsingBody.Error(), err)
 		h.sentResponse(c, http.StatusBadRequest, resp)
 		return
 	}
 
-	var date time.Time
-	if eventFromBody.Date != "" {
-		date, err = time.Parse(time.RFC3339, eventFromBody.Date)
-		if err != nil {
-			resp := newResponse(updateAction, "date", ErrParsingDate.Error(), err)
-			h.sentResponse(c, http.StatusBadRequest, resp)
-			return
-		}
+	update, field, err := parsePatch(patch)
+	if err != nil {
+		resp := newResponse(updateAction, field, err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
 	}
 
-	var duration time.Duration
-	if eventFromBody.Duration != "" {
-		duration, err = time.ParseDuration(eventFromBody.Duration)
-		if err != nil {
-			resp := newResponse(updateAction, "duration", ErrParsingDuration.Error(), err)
-			h.sentResponse(c, http.StatusBadRequest, resp)
-			return
-		}
+	occurrence, scope, err := parseOccurrence(c)
+	if err != nil {
+		resp := newResponse(updateAction, "occurrence (query)", err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
 	}
 
-	var notificationInterval time.Duration
-	if eventFromBody.NotificationInterval != "" {
-		notificationInterval, err = time.ParseDuration(eventFromBody.NotificationInterval)
-		if err != nil {
-			resp := newResponse(updateAction, "notification_interval", ErrParsingNotificationInterval.Error(), err)
-			h.sentResponse(c, http.StatusBadRequest, resp)
-			return
-		}
+	opts, err := parseEventOptions(c)
+	if err != nil {
+		resp := newResponse(updateAction, "allow_overlap (query)", err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
 	}
 
-	event := models.Event{
-		Title:                eventFromBody.Title,
-		Date:                 date,
-		Duration:             duration,
-		Description:          eventFromBody.Description,
-		UserID:               eventFromBody.UserID,
-		NotificationInterval: notificationInterval,
+	var updatedEvent models.Event
+	if occurrence.IsZero() {
+		updatedEvent, err = h.services.UpdateEvent(c, parsedID.String(), version, update, opts)
+	} else {
+		updatedEvent, err = h.services.UpdateEventOccurrence(c, parsedID.String(), version, occurrence, scope, update, opts)
 	}
-
-	updatedEvent, err := h.services.UpdateEvent(c, parsedID.String(), event)
 	if err != nil {
 		message := "error updating event"
 		resp := newResponse(updateAction, "", message, err)
-		h.sentResponse(c, http.StatusInternalServerError, resp)
+		h.sentResponse(c, errorStatus(err), resp)
 		return
 	}
 
-	c.JSON(http.StatusOK, Response{
-		ID:                   updatedEvent.ID,
-		Title:                updatedEvent.Title,
-		Date:                 updatedEvent.Date.Format(time.RFC3339),
-		Duration:             updatedEvent.Duration.String(),
-		Description:          updatedEvent.Description,
-		UserID:               updatedEvent.UserID,
-		NotificationInterval: updatedEvent.NotificationInterval.String(),
-	})
+	c.Header("ETag", versionTag(updatedEvent.Version))
+	c.JSON(http.StatusOK, toResponse(updatedEvent))
+}
+
+// GetEventByID returns the event of the caller. The ETag of the response is the version of the event,
+// it lets clients skip downloading the unchanged event with If-None-Match and update it with If-Match.
+func (h *HandlerHTTP) GetEventByID(c *gin.Context) {
+	id := c.Param("id")
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		resp := newResponse(getByIDAction, "id (param)", ErrInvalidID.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	event, err := h.services.GetEventByID(c, parsedID.String())
+	if err != nil {
+		message := "error getting event"
+		resp := newResponse(getByIDAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+
+	tag := versionTag(event.Version)
+	c.Header("ETag", tag)
+	if matchesETag(c.GetHeader("If-None-Match"), tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, toResponse(event))
+}
+
+func toResponse(event models.Event) Response {
+	rule, exceptions, timeZone := formatRecurrence(event.Recurrence)
+
+	response := Response{
+		ID:                   event.ID,
+		Title:                event.Title,
+		Date:                 event.Date.Format(time.RFC3339),
+		Duration:             event.Duration.String(),
+		Description:          event.Description,
+		UserID:               event.UserID,
+		NotificationInterval: event.NotificationInterval.String(),
+		RecurrenceRule:       rule,
+		RecurrenceTimeZone:   timeZone,
+		RecurrenceID:         event.RecurrenceID,
+		Attendees:            toAttendeeResponses(event.Attendees),
+		Version:              event.Version,
+	}
+	for _, reminder := range event.Reminders {
+		r := reminderResponse{
+			ID:      reminder.ID,
+			Before:  reminder.Before.String(),
+			Channel: string(reminder.Channel),
+			Status:  string(reminder.Status),
+		}
+		if !reminder.QueuedAt.IsZero() {
+			r.QueuedAt = reminder.QueuedAt.Format(time.RFC3339)
+		}
+		if !reminder.SentAt.IsZero() {
+			r.SentAt = reminder.SentAt.Format(time.RFC3339)
+		}
+		response.Reminders = append(response.Reminders, r)
+	}
+	for _, exception := range exceptions {
+		response.RecurrenceExceptions = append(response.RecurrenceExceptions, exception.Format(time.RFC3339))
+	}
+	if !event.OriginalDate.IsZero() {
+		response.OriginalDate = event.OriginalDate.Format(time.RFC3339)
+	}
+	if !event.UpdatedAt.IsZero() {
+		response.UpdatedAt = event.UpdatedAt.Format(time.RFC3339Nano)
+	}
+
+	return response
+}
+
+// versionTag returns the strong ETag of the event version.
+func versionTag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch returns the event version from the If-Match header. Weak tags and "*" are rejected
+// because they cannot guarantee that the client has seen the current version of the event.
+func parseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, ErrMissingIfMatch
+	}
+
+	unquoted, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return 0, ErrParsingIfMatch
+	}
+	unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	if !ok {
+		return 0, ErrParsingIfMatch
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrParsingIfMatch
+	}
+	return version, nil
+}
+
+// ifMatchStatus returns HTTP status code for the error returned by parseIfMatch.
+func ifMatchStatus(err error) int {
+	if errors.Is(err, ErrMissingIfMatch) {
+		return http.StatusPreconditionRequired
+	}
+	return http.StatusBadRequest
+}
+
+// matchesETag reports whether the If-None-Match header contains the tag. Weak tags
+// are compared by their opaque part as required for If-None-Match.
+func matchesETag(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
 }
 
 func (h *HandlerHTTP) DeleteEvent(c *gin.Context) {
@@ -183,11 +398,29 @@ func (h *HandlerHTTP) DeleteEvent(c *gin.Context) {
 		return
 	}
 
-	err = h.services.DeleteEvent(c, parsedID.String())
+	version, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		resp := newResponse(deleteAction, "If-Match (header)", err.Error(), err)
+		h.sentResponse(c, ifMatchStatus(err), resp)
+		return
+	}
+
+	occurrence, scope, err := parseOccurrence(c)
+	if err != nil {
+		resp := newResponse(deleteAction, "occurrence (query)", err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	if occurrence.IsZero() {
+		err = h.services.DeleteEvent(c, parsedID.String(), version)
+	} else {
+		err = h.services.DeleteEventOccurrence(c, parsedID.String(), version, occurrence, scope)
+	}
 	if err != nil {
 		message := "error deleting event"
 		resp := newResponse(deleteAction, "", message, err)
-		h.sentResponse(c, http.StatusInternalServerError, resp)
+		h.sentResponse(c, errorStatus(err), resp)
 		return
 	}
 
@@ -195,18 +428,9 @@ func (h *HandlerHTTP) DeleteEvent(c *gin.Context) {
 }
 
 type eventsResponse struct {
-	Total int            `json:"total"`
-	Data  []eventDetails `json:"data"`
-}
-
-type eventDetails struct {
-	ID                   string        `json:"id"`
-	Title                string        `json:"title"`
-	Date                 time.Time     `json:"date"`
-	Duration             time.Duration `json:"duration"`
-	Description          string        `json:"description"`
-	UserID               int           `json:"user_id"`
-	NotificationInterval time.Duration `json:"notification_interval"`
+	Total      int        `json:"total"`
+	Data       []Response `json:"data"`
+	NextCursor string     `json:"next_cursor,omitempty"`
 }
 
 func (h *HandlerHTTP) GetAllByDayEvents(c *gin.Context) {
@@ -218,11 +442,18 @@ func (h *HandlerHTTP) GetAllByDayEvents(c *gin.Context) {
 		return
 	}
 
-	events, err := h.services.GetAllByDayEvents(c, parsedDate)
+	loc, _, field, err := parseCalendarQuery(c)
+	if err != nil {
+		resp := newResponse(getByDayAction, field, err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	events, err := h.services.GetAllByDayEvents(c, parsedDate, loc)
 	if err != nil {
 		message := "error getting events by day"
 		resp := newResponse(getByDayAction, "", message, err)
-		h.sentResponse(c, http.StatusInternalServerError, resp)
+		h.sentResponse(c, errorStatus(err), resp)
 		return
 	}
 
@@ -238,11 +469,18 @@ func (h *HandlerHTTP) GetAllByWeekEvents(c *gin.Context) {
 		return
 	}
 
-	events, err := h.services.GetAllByWeekEvents(c, parsedDate)
+	loc, weekStart, field, err := parseCalendarQuery(c)
+	if err != nil {
+		resp := newResponse(getByWeekAction, field, err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	events, err := h.services.GetAllByWeekEvents(c, parsedDate, loc, weekStart)
 	if err != nil {
 		message := "error getting events by week"
 		resp := newResponse(getByWeekAction, "", message, err)
-		h.sentResponse(c, http.StatusInternalServerError, resp)
+		h.sentResponse(c, errorStatus(err), resp)
 		return
 	}
 
@@ -258,30 +496,352 @@ func (h *HandlerHTTP) GetAllByMonthEvents(c *gin.Context) {
 		return
 	}
 
-	events, err := h.services.GetAllByMonthEvents(c, parsedDate)
+	loc, _, field, err := parseCalendarQuery(c)
+	if err != nil {
+		resp := newResponse(getByMonthAction, field, err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	events, err := h.services.GetAllByMonthEvents(c, parsedDate, loc)
 	if err != nil {
 		message := "error getting events by month"
 		resp := newResponse(getByMonthAction, "", message, err)
-		h.sentResponse(c, http.StatusInternalServerError, resp)
+		h.sentResponse(c, errorStatus(err), resp)
 		return
 	}
 
 	c.JSON(http.StatusOK, formResponseGetBy(events))
 }
 
+// GetEventsInRange returns the page of the caller's events which start in [from, to).
+func (h *HandlerHTTP) GetEventsInRange(c *gin.Context) {
+	rng, field, err := parseEventRange(c)
+	if err != nil {
+		resp := newResponse(getInRangeAction, field, err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	page, err := h.services.GetEventsInRange(c, rng)
+	if err != nil {
+		message := "error getting events in range"
+		resp := newResponse(getInRangeAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+
+	response := formResponseGetBy(page.Events)
+	if page.Next != nil {
+		response.NextCursor = pagination.EncodeCursor(*page.Next)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseCalendarQuery reads "tz" and "week_start" query parameters. Nil location means the offset of the date.
+// It returns the name of the invalid parameter with the error.
+func parseCalendarQuery(c *gin.Context) (*time.Location, time.Weekday, string, error) {
+	var loc *time.Location
+	if tz := c.Query("tz"); tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, 0, "tz (query)", ErrParsingTimeZone
+		}
+	}
+
+	weekStart := period.DefaultWeekStart
+	if weekStartStr := c.Query("week_start"); weekStartStr != "" {
+		var err error
+		weekStart, err = period.ParseWeekday(weekStartStr)
+		if err != nil {
+			return nil, 0, "week_start (query)", err
+		}
+	}
+
+	return loc, weekStart, "", nil
+}
+
+// parseEventRange reads "from", "to", "q", "order", "limit" and "cursor" query parameters.
+// It returns the name of the invalid parameter with the error.
+func parseEventRange(c *gin.Context) (models.EventRange, string, error) {
+	rng := models.EventRange{
+		Query: c.Query("q"),
+		Order: models.SortOrder(c.Query("order")),
+	}
+
+	var err error
+
+	rng.From, err = time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		return models.EventRange{}, "from (query)", ErrParsingDate
+	}
+
+	rng.To, err = time.Parse(time.RFC3339, c.Query("to"))
+	if err != nil {
+		return models.EventRange{}, "to (query)", ErrParsingDate
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		rng.Limit, err = strconv.Atoi(limit)
+		if err != nil || rng.Limit < 0 {
+			return models.EventRange{}, "limit (query)", ErrParsingLimit
+		}
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := pagination.DecodeCursor(cursor)
+		if err != nil {
+			return models.EventRange{}, "cursor (query)", err
+		}
+		rng.After = &after
+	}
+
+	return rng, "", nil
+}
+
 func formResponseGetBy(events []models.Event) eventsResponse {
 	var response eventsResponse
 	response.Total = len(events)
 	for _, event := range events {
-		response.Data = append(response.Data, eventDetails{
-			ID:                   event.ID,
-			Title:                event.Title,
-			Date:                 event.Date,
-			Duration:             event.Duration,
-			Description:          event.Description,
-			UserID:               event.UserID,
-			NotificationInterval: event.NotificationInterval,
-		})
+		response.Data = append(response.Data, toResponse(event))
 	}
 	return response
 }
+
+// parsePatch converts the merge patch to the update and returns the name of the invalid member on error.
+// recurrence_rule replaces the recurrence together with the exceptions and the time zone sent with it,
+// without it recurrence_exceptions and recurrence_time_zone change only themselves.
+func parsePatch(patch patchEvent) (models.EventUpdate, string, error) {
+	var update models.EventUpdate
+
+	title, err := patchString(patch.Title, true)
+	if err != nil {
+		return models.EventUpdate{}, "title", err
+	}
+	update.Title = title
+
+	date, err := patchString(patch.Date, true)
+	if err != nil {
+		return models.EventUpdate{}, "date", err
+	}
+	if date != nil {
+		parsed, err := time.Parse(time.RFC3339, *date)
+		if err != nil {
+			return models.EventUpdate{}, "date", fmt.Errorf("%s: %w", ErrParsingDate.Error(), err)
+		}
+		update.Date = &parsed
+	}
+
+	duration, err := patchString(patch.Duration, true)
+	if err != nil {
+		return models.EventUpdate{}, "duration", err
+	}
+	if duration != nil {
+		parsed, err := time.ParseDuration(*duration)
+		if err != nil {
+			return models.EventUpdate{}, "duration", fmt.Errorf("%s: %w", ErrParsingDuration.Error(), err)
+		}
+		update.Duration = &parsed
+	}
+
+	update.Description, err = patchString(patch.Description, false)
+	if err != nil {
+		return models.EventUpdate{}, "description", err
+	}
+
+	interval, err := patchString(patch.NotificationInterval, false)
+	if err != nil {
+		return models.EventUpdate{}, "notification_interval", err
+	}
+	if interval != nil {
+		var parsed time.Duration
+		if *interval != "" {
+			parsed, err = time.ParseDuration(*interval)
+			if err != nil {
+				return models.EventUpdate{}, "notification_interval", fmt.Errorf("%s: %w", ErrParsingNotificationInterval.Error(), err)
+			}
+		}
+		update.NotificationInterval = &parsed
+	}
+
+	// null removes all reminders, the list replaces them
+	if patch.Reminders != nil {
+		var body []bodyReminder
+		if err := json.Unmarshal(patch.Reminders, &body); err != nil {
+			return models.EventUpdate{}, "reminders", ErrParsingReminders
+		}
+		reminders, err := parseReminders(body)
+		if err != nil {
+			return models.EventUpdate{}, "reminders", err
+		}
+		if reminders == nil {
+			reminders = []models.Reminder{}
+		}
+		update.Reminders = &reminders
+	}
+
+	rule, err := patchString(patch.RecurrenceRule, false)
+	if err != nil {
+		return models.EventUpdate{}, "recurrence_rule", err
+	}
+	var exceptions []string
+	if patch.RecurrenceExceptions != nil {
+		if err := json.Unmarshal(patch.RecurrenceExceptions, &exceptions); err != nil {
+			return models.EventUpdate{}, "recurrence_exceptions", ErrParsingRecurrenceExceptions
+		}
+	}
+	var timeZone string
+	if patch.RecurrenceTimeZone != nil {
+		if err := json.Unmarshal(patch.RecurrenceTimeZone, &timeZone); err != nil {
+			return models.EventUpdate{}, "recurrence_time_zone", ErrParsingRecurrenceTimeZone
+		}
+	}
+	if rule != nil {
+		rec, field, err := parseRecurrence(*rule, exceptions, timeZone)
+		if err != nil {
+			return models.EventUpdate{}, field, err
+		}
+		update.Recurrence = rec
+		update.ClearRecurrence = rec == nil
+		return update, "", nil
+	}
+
+	// without the rule the sent members change the stored recurrence, null removes their values
+	if patch.RecurrenceExceptions != nil {
+		parsed := []time.Time{}
+		for _, exception := range exceptions {
+			date, err := time.Parse(time.RFC3339, exception)
+			if err != nil {
+				return models.EventUpdate{}, "recurrence_exceptions", ErrParsingRecurrenceExceptions
+			}
+			parsed = append(parsed, date)
+		}
+		update.RecurrenceExceptions = &parsed
+	}
+	if patch.RecurrenceTimeZone != nil {
+		loc := time.UTC
+		if timeZone != "" {
+			loc, err = time.LoadLocation(timeZone)
+			if err != nil {
+				return models.EventUpdate{}, "recurrence_time_zone", ErrParsingRecurrenceTimeZone
+			}
+		}
+		update.RecurrenceLocation = loc
+	}
+
+	return update, "", nil
+}
+
+// patchString decodes the string member of the merge patch. It returns nil if the member is missing
+// and the empty string if the member is null, unless the field is required and cannot be removed.
+func patchString(raw json.RawMessage, required bool) (*string, error) {
+	if raw == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	var value string
+	if bytes.Equal(raw, nullMember) {
+		if required {
+			return nil, ErrRemovingRequiredField
+		}
+		return &value, nil
+	}
+
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("%s: %w", ErrParsingBody.Error(), err)
+	}
+	return &value, nil
+}
+
+// parseReminders returns nil if there are no reminders. Channels are validated by the service.
+func parseReminders(body []bodyReminder) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	for _, r := range body {
+		before, err := time.ParseDuration(r.Before)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrParsingReminders.Error(), err)
+		}
+		reminders = append(reminders, models.Reminder{
+			Before:  before,
+			Channel: models.ReminderChannel(r.Channel),
+		})
+	}
+	return reminders, nil
+}
+
+// parseRecurrence returns nil recurrence if the rule is empty and the name of the invalid field otherwise.
+func parseRecurrence(rule string, exceptions []string, timeZone string) (*models.Recurrence, string, error) {
+	if rule == "" {
+		if len(exceptions) > 0 {
+			return nil, "recurrence_exceptions", ErrParsingRecurrenceExceptions
+		}
+		if timeZone != "" {
+			return nil, "recurrence_time_zone", ErrParsingRecurrenceTimeZone
+		}
+		return nil, "", nil
+	}
+
+	r, err := recurrence.Parse(rule)
+	if err != nil {
+		return nil, "recurrence_rule", fmt.Errorf("%s: %w", ErrParsingRecurrenceRule.Error(), err)
+	}
+
+	for _, exception := range exceptions {
+		date, err := time.Parse(time.RFC3339, exception)
+		if err != nil {
+			return nil, "recurrence_exceptions", ErrParsingRecurrenceExceptions
+		}
+		r.Exceptions = append(r.Exceptions, date)
+	}
+
+	if timeZone != "" {
+		r.Location, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, "recurrence_time_zone", ErrParsingRecurrenceTimeZone
+		}
+	}
+
+	return &r, "", nil
+}
+
+func formatRecurrence(r *models.Recurrence) (string, []time.Time, string) {
+	if r == nil {
+		return "", nil, ""
+	}
+	return recurrence.Format(*r), r.Exceptions, recurrence.TimeZone(*r)
+}
+
+// parseOccurrence reads "occurrence" and "scope" query parameters which point to occurrences
+// of the recurring event. Zero occurrence means the whole event.
+func parseOccurrence(c *gin.Context) (time.Time, models.RecurrenceScope, error) {
+	occurrenceStr := c.Query("occurrence")
+	if occurrenceStr == "" {
+		return time.Time{}, models.ScopeAll, nil
+	}
+
+	occurrence, err := time.Parse(time.RFC3339, occurrenceStr)
+	if err != nil {
+		return time.Time{}, "", ErrParsingOccurrence
+	}
+
+	scope := models.RecurrenceScope(c.DefaultQuery("scope", string(models.ScopeThis)))
+
+	return occurrence, scope, nil
+}
+
+// parseEventOptions reads the "allow_overlap" query parameter.
+func parseEventOptions(c *gin.Context) (models.EventOptions, error) {
+	var opts models.EventOptions
+
+	if allowOverlap := c.Query("allow_overlap"); allowOverlap != "" {
+		var err error
+		opts.AllowOverlap, err = strconv.ParseBool(allowOverlap)
+		if err != nil {
+			return models.EventOptions{}, ErrParsingAllowOverlap
+		}
+	}
+
+	return opts, nil
+}
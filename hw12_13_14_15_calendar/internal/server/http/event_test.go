@@ -7,13 +7,16 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	neturl "net/url"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/pagination"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service"
 	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
 	"github.com/stretchr/testify/require"
@@ -34,24 +37,32 @@ func TestHandlerHTTPCreateEvent(t *testing.T) {
 		Date:                 time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
 		Duration:             1*time.Hour + 30*time.Minute,
 		Description:          "This is a test event",
-		UserID:               1,
 		NotificationInterval: 10 * time.Minute,
+		Reminders: []models.Reminder{
+			{Before: 24 * time.Hour, Channel: models.ChannelEmail},
+			{Before: 10 * time.Minute},
+		},
 	}
 	expectedID := "test uuid"
-	services.EXPECT().CreateEvent(gomock.Any(), expectedEvent).Return(expectedID, nil)
+	services.EXPECT().CreateEvent(gomock.Any(), expectedEvent, models.EventOptions{}).Return(expectedID, nil)
 
 	handler := NewHandlerHTTP(services, logger)
 
 	r := gin.Default()
 	r.POST(url, handler.CreateEvent)
 
+	// the owner is the authenticated caller rather than the user in the body
 	requestBody := map[string]interface{}{
 		"title":                 "Test Event",
 		"date":                  "2023-07-22T12:00:00Z",
 		"duration":              "1h30m",
 		"description":           "This is a test event",
-		"user_id":               1,
+		"user_id":               2,
 		"notification_interval": "10m",
+		"reminders": []map[string]interface{}{
+			{"before": "24h", "channel": "email"},
+			{"before": "10m"},
+		},
 	}
 
 	jsonBody, err := json.Marshal(requestBody)
@@ -140,6 +151,50 @@ func TestHandlerHTTPCreateEventError(t *testing.T) {
 				"notification_interval": "interval",
 			},
 		},
+		{
+			name: "invalid reminder",
+			expectedResponse: response{
+				Action:  createAction,
+				Message: ErrParsingReminders.Error() + ": time: invalid duration \"day\"",
+				Error:   ErrParsingReminders.Error() + ": time: invalid duration \"day\"",
+			},
+			requestBody: map[string]interface{}{
+				"title":     "test",
+				"date":      "2023-07-22T12:00:00Z",
+				"duration":  "1h30m",
+				"user_id":   1,
+				"reminders": []map[string]interface{}{{"before": "day"}},
+			},
+		},
+		{
+			name: "invalid recurrence_time_zone",
+			expectedResponse: response{
+				Action:  createAction,
+				Message: ErrParsingRecurrenceTimeZone.Error(),
+				Error:   ErrParsingRecurrenceTimeZone.Error(),
+			},
+			requestBody: map[string]interface{}{
+				"title":                "test",
+				"date":                 "2023-07-22T12:00:00Z",
+				"duration":             "1h30m",
+				"recurrence_rule":      "FREQ=DAILY",
+				"recurrence_time_zone": "Mars/Olympus",
+			},
+		},
+		{
+			name: "recurrence_time_zone without rule",
+			expectedResponse: response{
+				Action:  createAction,
+				Message: ErrParsingRecurrenceTimeZone.Error(),
+				Error:   ErrParsingRecurrenceTimeZone.Error(),
+			},
+			requestBody: map[string]interface{}{
+				"title":                "test",
+				"date":                 "2023-07-22T12:00:00Z",
+				"duration":             "1h30m",
+				"recurrence_time_zone": "Europe/Berlin",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -204,55 +259,11 @@ func TestHandlerHTTPCreateEventErrorCreatingEvent(t *testing.T) {
 				Title:    "",
 				Date:     time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
 				Duration: 1*time.Hour + 30*time.Minute,
-				UserID:   1,
 			},
 			requestBody: map[string]interface{}{
 				"title":    "",
 				"date":     "2023-07-22T12:00:00Z",
 				"duration": "1h30m",
-				"user_id":  1,
-			},
-		},
-		{
-			name: "user id is 0",
-			expectedResponse: response{
-				Action:  createAction,
-				Field:   "user_id",
-				Message: "error creating event",
-				Error:   "user id must not be positive number",
-			},
-			expectedEvent: models.Event{
-				Title:    "test",
-				Date:     time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
-				Duration: 1*time.Hour + 30*time.Minute,
-				UserID:   0,
-			},
-			requestBody: map[string]interface{}{
-				"title":    "test",
-				"date":     "2023-07-22T12:00:00Z",
-				"duration": "1h30m",
-				"user_id":  0,
-			},
-		},
-		{
-			name: "user id is -1",
-			expectedResponse: response{
-				Action:  createAction,
-				Field:   "user_id",
-				Message: "error creating event",
-				Error:   "user id must not be positive number",
-			},
-			expectedEvent: models.Event{
-				Title:    "test",
-				Date:     time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
-				Duration: 1*time.Hour + 30*time.Minute,
-				UserID:   -1,
-			},
-			requestBody: map[string]interface{}{
-				"title":    "test",
-				"date":     "2023-07-22T12:00:00Z",
-				"duration": "1h30m",
-				"user_id":  -1,
 			},
 		},
 		{
@@ -267,13 +278,11 @@ func TestHandlerHTTPCreateEventErrorCreatingEvent(t *testing.T) {
 				Title:    "test",
 				Date:     time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
 				Duration: 0,
-				UserID:   1,
 			},
 			requestBody: map[string]interface{}{
 				"title":    "test",
 				"date":     "2023-07-22T12:00:00Z",
 				"duration": "0s",
-				"user_id":  1,
 			},
 		},
 		{
@@ -288,13 +297,11 @@ func TestHandlerHTTPCreateEventErrorCreatingEvent(t *testing.T) {
 				Title:    "test",
 				Date:     time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
 				Duration: -1 * time.Hour,
-				UserID:   1,
 			},
 			requestBody: map[string]interface{}{
 				"title":    "test",
 				"date":     "2023-07-22T12:00:00Z",
 				"duration": "-1h",
-				"user_id":  1,
 			},
 		},
 		{
@@ -309,14 +316,12 @@ func TestHandlerHTTPCreateEventErrorCreatingEvent(t *testing.T) {
 				Title:                "test",
 				Date:                 time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
 				Duration:             1 * time.Hour,
-				UserID:               1,
 				NotificationInterval: -time.Hour,
 			},
 			requestBody: map[string]interface{}{
 				"title":                 "test",
 				"date":                  "2023-07-22T12:00:00Z",
 				"duration":              "1h",
-				"user_id":               1,
 				"notification_interval": "-1h",
 			},
 		},
@@ -330,7 +335,8 @@ func TestHandlerHTTPCreateEventErrorCreatingEvent(t *testing.T) {
 			services := mock_service.NewMockServices(ctrl)
 			logger := mock_logger.NewMockLogger(ctrl)
 
-			services.EXPECT().CreateEvent(gomock.Any(), tc.expectedEvent).Return("", errors.New(tc.expectedResponse.Error))
+			services.EXPECT().CreateEvent(gomock.Any(), tc.expectedEvent, models.EventOptions{}).
+				Return("", errors.New(tc.expectedResponse.Error))
 			logger.EXPECT().Error(tc.expectedResponse.Message,
 				slog.String("action", tc.expectedResponse.Action),
 				slog.String("errors", tc.expectedResponse.Error))
@@ -374,18 +380,26 @@ func TestHandlerHTTPUpdateEvent(t *testing.T) {
 	id := uuid.New().String()
 
 	event := models.Event{
+		ID:                   id,
 		Title:                "Test Event update",
 		Date:                 time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
 		Duration:             1*time.Hour + 30*time.Minute,
 		Description:          "This is a test event update",
 		UserID:               1,
 		NotificationInterval: 10 * time.Minute,
+		Version:              2,
 	}
 
-	expectedEvent := event
-	expectedEvent.ID = id
+	// id and user_id cannot be changed and are ignored
+	update := models.EventUpdate{
+		Title:                &event.Title,
+		Date:                 &event.Date,
+		Duration:             &event.Duration,
+		Description:          &event.Description,
+		NotificationInterval: &event.NotificationInterval,
+	}
 
-	services.EXPECT().UpdateEvent(gomock.Any(), id, event).Return(expectedEvent, nil)
+	services.EXPECT().UpdateEvent(gomock.Any(), id, int64(1), update, models.EventOptions{}).Return(event, nil)
 
 	handler := NewHandlerHTTP(services, logger)
 
@@ -410,6 +424,7 @@ func TestHandlerHTTPUpdateEvent(t *testing.T) {
 	ctx := context.Background()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url+"/"+id, bytes.NewBuffer(jsonBody))
 	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
 	req.Header.Set("Content-Type", "application/json")
 
 	r.ServeHTTP(w, req)
@@ -428,9 +443,113 @@ func TestHandlerHTTPUpdateEvent(t *testing.T) {
 		Description:          "This is a test event update",
 		UserID:               1,
 		NotificationInterval: "10m0s",
+		Version:              2,
 	}
 
 	require.Equal(t, expectedBody, responseBody)
+	require.Equal(t, `"2"`, w.Header().Get("ETag"))
+}
+
+func TestHandlerHTTPEventPrecondition(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		ifMatch      string
+		serviceErr   error
+		expectedCode int
+	}{
+		{
+			name:         "update without If-Match",
+			method:       http.MethodPatch,
+			expectedCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:         "update with weak tag",
+			method:       http.MethodPatch,
+			ifMatch:      `W/"1"`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:    "update of changed event",
+			method:  http.MethodPatch,
+			ifMatch: `"1"`,
+			serviceErr: customerror.CustomError{
+				Field:   "version",
+				Message: "event has version 2, not 1",
+				Err:     customerror.ErrVersionMismatch,
+			},
+			expectedCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:         "delete without If-Match",
+			method:       http.MethodDelete,
+			expectedCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:         "delete with any tag",
+			method:       http.MethodDelete,
+			ifMatch:      "*",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:    "delete of changed event",
+			method:  http.MethodDelete,
+			ifMatch: `"1"`,
+			serviceErr: customerror.CustomError{
+				Field:   "version",
+				Message: "event has version 2, not 1",
+				Err:     customerror.ErrVersionMismatch,
+			},
+			expectedCode: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			id := uuid.New().String()
+			title := "new title"
+
+			action := updateAction
+			if tc.method == http.MethodDelete {
+				action = deleteAction
+			}
+
+			if tc.serviceErr != nil {
+				if tc.method == http.MethodDelete {
+					services.EXPECT().DeleteEvent(gomock.Any(), id, int64(1)).Return(tc.serviceErr)
+				} else {
+					services.EXPECT().UpdateEvent(gomock.Any(), id, int64(1), models.EventUpdate{Title: &title},
+						models.EventOptions{}).Return(models.Event{}, tc.serviceErr)
+				}
+			}
+			logger.EXPECT().Error(gomock.Any(), slog.String("action", action), gomock.Any())
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.PATCH(url+"/:id", handler.UpdateEvent)
+			r.DELETE(url+"/:id", handler.DeleteEvent)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, tc.method, url+"/"+id, bytes.NewBufferString(`{"title":"new title"}`))
+			require.NoError(t, err)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
 }
 
 func TestHandlerHTTPUpdateEventError(t *testing.T) {
@@ -455,9 +574,9 @@ func TestHandlerHTTPUpdateEventError(t *testing.T) {
 			name: "invalid request body",
 			expectedResponse: response{
 				Action:  updateAction,
-				Field:   "",
-				Message: ErrParsingBody.Error(),
-				Error:   "json: cannot unmarshal bool into Go struct field bodyEvent.title of type string",
+				Field:   "title",
+				Message: ErrParsingBody.Error() + ": json: cannot unmarshal bool into Go value of type string",
+				Error:   ErrParsingBody.Error() + ": json: cannot unmarshal bool into Go value of type string",
 			},
 			id: uuid.New().String(),
 			requestBody: map[string]interface{}{
@@ -474,8 +593,8 @@ func TestHandlerHTTPUpdateEventError(t *testing.T) {
 			expectedResponse: response{
 				Action:  updateAction,
 				Field:   "date",
-				Message: ErrParsingDate.Error(),
-				Error:   "parsing time \"date\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"date\" as \"2006\"",
+				Message: ErrParsingDate.Error() + `: parsing time "date" as "2006-01-02T15:04:05Z07:00": cannot parse "date" as "2006"`,
+				Error:   ErrParsingDate.Error() + `: parsing time "date" as "2006-01-02T15:04:05Z07:00": cannot parse "date" as "2006"`,
 			},
 			id: uuid.New().String(),
 			requestBody: map[string]interface{}{
@@ -492,8 +611,8 @@ func TestHandlerHTTPUpdateEventError(t *testing.T) {
 			expectedResponse: response{
 				Action:  updateAction,
 				Field:   "duration",
-				Message: ErrParsingDuration.Error(),
-				Error:   "time: invalid duration \"interval\"",
+				Message: ErrParsingDuration.Error() + ": time: invalid duration \"duration\"",
+				Error:   ErrParsingDuration.Error() + ": time: invalid duration \"duration\"",
 			},
 			id: uuid.New().String(),
 			requestBody: map[string]interface{}{
@@ -510,8 +629,8 @@ func TestHandlerHTTPUpdateEventError(t *testing.T) {
 			expectedResponse: response{
 				Action:  updateAction,
 				Field:   "notification_interval",
-				Message: ErrParsingNotificationInterval.Error(),
-				Error:   "time: invalid duration \"interval\"",
+				Message: ErrParsingNotificationInterval.Error() + ": time: invalid duration \"interval\"",
+				Error:   ErrParsingNotificationInterval.Error() + ": time: invalid duration \"interval\"",
 			},
 			id: uuid.New().String(),
 			requestBody: map[string]interface{}{
@@ -523,6 +642,45 @@ func TestHandlerHTTPUpdateEventError(t *testing.T) {
 				"notification_interval": "interval",
 			},
 		},
+		{
+			name: "removing title",
+			expectedResponse: response{
+				Action:  updateAction,
+				Field:   "title",
+				Message: ErrRemovingRequiredField.Error(),
+				Error:   ErrRemovingRequiredField.Error(),
+			},
+			id: uuid.New().String(),
+			requestBody: map[string]interface{}{
+				"title": nil,
+			},
+		},
+		{
+			name: "invalid exceptions without rule",
+			expectedResponse: response{
+				Action:  updateAction,
+				Field:   "recurrence_exceptions",
+				Message: ErrParsingRecurrenceExceptions.Error(),
+				Error:   ErrParsingRecurrenceExceptions.Error(),
+			},
+			id: uuid.New().String(),
+			requestBody: map[string]interface{}{
+				"recurrence_exceptions": []string{"22.07.2023"},
+			},
+		},
+		{
+			name: "invalid time zone without rule",
+			expectedResponse: response{
+				Action:  updateAction,
+				Field:   "recurrence_time_zone",
+				Message: ErrParsingRecurrenceTimeZone.Error(),
+				Error:   ErrParsingRecurrenceTimeZone.Error(),
+			},
+			id: uuid.New().String(),
+			requestBody: map[string]interface{}{
+				"recurrence_time_zone": "Mars/Olympus",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -550,6 +708,7 @@ func TestHandlerHTTPUpdateEventError(t *testing.T) {
 			ctx := context.Background()
 			req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url+"/"+tc.id, bytes.NewBuffer(jsonBody))
 			require.NoError(t, err)
+			req.Header.Set("If-Match", `"1"`)
 			req.Header.Set("Content-Type", "application/json")
 
 			r.ServeHTTP(w, req)
@@ -571,7 +730,7 @@ func TestHandlerHTTPUpdateEventErrorUpdatingEvent(t *testing.T) {
 	testCases := []struct {
 		name             string
 		expectedResponse response
-		expectedEvent    models.Event
+		expectedUpdate   models.EventUpdate
 		requestBody      map[string]interface{}
 	}{
 		{
@@ -582,8 +741,8 @@ func TestHandlerHTTPUpdateEventErrorUpdatingEvent(t *testing.T) {
 				Message: "error updating event",
 				Error:   service.ErrInvalidDuration.Error(),
 			},
-			expectedEvent: models.Event{
-				Duration: -1 * time.Hour,
+			expectedUpdate: models.EventUpdate{
+				Duration: durationPtr(-1 * time.Hour),
 			},
 			requestBody: map[string]interface{}{
 				"duration": "-1h",
@@ -597,28 +756,13 @@ func TestHandlerHTTPUpdateEventErrorUpdatingEvent(t *testing.T) {
 				Message: "error updating event",
 				Error:   service.ErrInvalidDuration.Error(),
 			},
-			expectedEvent: models.Event{
-				NotificationInterval: -time.Hour,
+			expectedUpdate: models.EventUpdate{
+				NotificationInterval: durationPtr(-time.Hour),
 			},
 			requestBody: map[string]interface{}{
 				"notification_interval": "-1h",
 			},
 		},
-		{
-			name: "user id is -1",
-			expectedResponse: response{
-				Action:  updateAction,
-				Field:   "notification_interval",
-				Message: "error updating event",
-				Error:   service.ErrInvalidUserID.Error(),
-			},
-			expectedEvent: models.Event{
-				UserID: -1,
-			},
-			requestBody: map[string]interface{}{
-				"user_id": -1,
-			},
-		},
 		{
 			name: "title contains only spaces",
 			expectedResponse: response{
@@ -627,8 +771,8 @@ func TestHandlerHTTPUpdateEventErrorUpdatingEvent(t *testing.T) {
 				Message: "error updating event",
 				Error:   service.ErrEmptyTitle.Error(),
 			},
-			expectedEvent: models.Event{
-				Title: "                 ",
+			expectedUpdate: models.EventUpdate{
+				Title: stringPtr("                 "),
 			},
 			requestBody: map[string]interface{}{
 				"title": "                 ",
@@ -646,7 +790,7 @@ func TestHandlerHTTPUpdateEventErrorUpdatingEvent(t *testing.T) {
 
 			id := uuid.New().String()
 
-			services.EXPECT().UpdateEvent(gomock.Any(), id, tc.expectedEvent).
+			services.EXPECT().UpdateEvent(gomock.Any(), id, int64(1), tc.expectedUpdate, models.EventOptions{}).
 				Return(models.Event{}, errors.New(tc.expectedResponse.Error))
 			logger.EXPECT().Error(tc.expectedResponse.Message,
 				slog.String("action", "update"),
@@ -665,6 +809,7 @@ func TestHandlerHTTPUpdateEventErrorUpdatingEvent(t *testing.T) {
 			ctx := context.Background()
 			req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url+"/"+id, bytes.NewBuffer(jsonBody))
 			require.NoError(t, err)
+			req.Header.Set("If-Match", `"1"`)
 			req.Header.Set("Content-Type", "application/json")
 
 			r.ServeHTTP(w, req)
@@ -682,7 +827,7 @@ func TestHandlerHTTPUpdateEventErrorUpdatingEvent(t *testing.T) {
 	}
 }
 
-func TestHandlerHTTPDeleteEvent(t *testing.T) {
+func TestHandlerHTTPUpdateEventMergePatch(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	services := mock_service.NewMockServices(ctrl)
@@ -690,97 +835,529 @@ func TestHandlerHTTPDeleteEvent(t *testing.T) {
 
 	id := uuid.New().String()
 
-	services.EXPECT().DeleteEvent(gomock.Any(), id).Return(nil)
+	event := models.Event{
+		ID:       id,
+		Title:    "Test Event",
+		Date:     time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+		UserID:   1,
+	}
+
+	// null members remove values, missing members are left unchanged
+	update := models.EventUpdate{
+		Description:          stringPtr(""),
+		NotificationInterval: durationPtr(0),
+		Reminders:            &[]models.Reminder{},
+		ClearRecurrence:      true,
+	}
+
+	services.EXPECT().UpdateEvent(gomock.Any(), id, int64(1), update, models.EventOptions{}).Return(event, nil)
 
 	handler := NewHandlerHTTP(services, logger)
 
 	r := gin.Default()
-	r.DELETE(url+"/:id", handler.DeleteEvent)
+	r.PATCH(url+"/:id", handler.UpdateEvent)
+
+	body := `{"description": null, "notification_interval": null, "reminders": null, "recurrence_rule": null}`
 
 	w := httptest.NewRecorder()
 
 	ctx := context.Background()
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url+"/"+id, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url+"/"+id, bytes.NewBufferString(body))
 	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
 
 	r.ServeHTTP(w, req)
 
 	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody Response
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	require.Equal(t, "", responseBody.Description)
+	require.Equal(t, "0s", responseBody.NotificationInterval)
+	require.Equal(t, "", responseBody.RecurrenceRule)
 }
 
-func TestHandlerHTTPDeleteEventError(t *testing.T) {
+func TestHandlerHTTPUpdateEventRecurrenceTimeZone(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	services := mock_service.NewMockServices(ctrl)
 	logger := mock_logger.NewMockLogger(ctrl)
 
-	id := "test id"
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
 
-	expectedMessage := "invalid id"
-	expectedError := "invalid UUID length: 7"
+	id := uuid.New().String()
+	rec := &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Location: berlin}
 
-	logger.EXPECT().Error(expectedMessage,
-		slog.String("action", "delete"),
-		slog.String("errors", expectedError))
+	event := models.Event{
+		ID:         id,
+		Title:      "Test Event",
+		Date:       time.Date(2023, 7, 22, 7, 0, 0, 0, time.UTC),
+		Duration:   time.Hour,
+		UserID:     1,
+		Recurrence: rec,
+	}
+
+	services.EXPECT().UpdateEvent(gomock.Any(), id, int64(1), models.EventUpdate{Recurrence: rec}, models.EventOptions{}).
+		Return(event, nil)
 
 	handler := NewHandlerHTTP(services, logger)
 
 	r := gin.Default()
-	r.DELETE(url+"/:id", handler.DeleteEvent)
+	r.PATCH(url+"/:id", handler.UpdateEvent)
+
+	body := `{"recurrence_rule": "FREQ=DAILY", "recurrence_time_zone": "Europe/Berlin"}`
 
 	w := httptest.NewRecorder()
 
 	ctx := context.Background()
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url+"/"+id, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url+"/"+id, bytes.NewBufferString(body))
 	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
 
 	r.ServeHTTP(w, req)
 
-	require.Equal(t, http.StatusBadRequest, w.Code)
-}
+	require.Equal(t, http.StatusOK, w.Code)
 
-func TestHandlerHTTPDeleteEventErrorDeletingEvent(t *testing.T) {
-	ctrl := gomock.NewController(t)
+	var responseBody Response
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
 
-	services := mock_service.NewMockServices(ctrl)
-	logger := mock_logger.NewMockLogger(ctrl)
+	require.Equal(t, "FREQ=DAILY", responseBody.RecurrenceRule)
+	require.Equal(t, "Europe/Berlin", responseBody.RecurrenceTimeZone)
+}
 
-	id := uuid.New().String()
+func TestHandlerHTTPUpdateEventRecurrenceMembers(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
 
-	expectedResponse := response{
-		Action:  deleteAction,
-		Field:   "id (param)",
-		Message: "error deleting event",
-		Error:   "no event with id" + id,
+	exception := time.Date(2023, 7, 29, 7, 0, 0, 0, time.UTC)
+
+	// without recurrence_rule the sent members change the stored recurrence and the rest of it is kept
+	testCases := []struct {
+		name           string
+		body           string
+		expectedUpdate models.EventUpdate
+	}{
+		{
+			name:           "only exceptions",
+			body:           `{"recurrence_exceptions": ["2023-07-29T07:00:00Z"]}`,
+			expectedUpdate: models.EventUpdate{RecurrenceExceptions: &[]time.Time{exception}},
+		},
+		{
+			name:           "exceptions removed",
+			body:           `{"recurrence_exceptions": null}`,
+			expectedUpdate: models.EventUpdate{RecurrenceExceptions: &[]time.Time{}},
+		},
+		{
+			name:           "only time zone",
+			body:           `{"recurrence_time_zone": "Europe/Berlin"}`,
+			expectedUpdate: models.EventUpdate{RecurrenceLocation: berlin},
+		},
+		{
+			name:           "time zone removed",
+			body:           `{"recurrence_time_zone": null}`,
+			expectedUpdate: models.EventUpdate{RecurrenceLocation: time.UTC},
+		},
 	}
 
-	services.EXPECT().DeleteEvent(gomock.Any(), id).Return(errors.New(expectedResponse.Error))
-	logger.EXPECT().Error(expectedResponse.Message,
-		slog.String("action", "delete"),
-		slog.String("errors", expectedResponse.Error))
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
 
-	handler := NewHandlerHTTP(services, logger)
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
 
-	r := gin.Default()
-	r.DELETE(url+"/:id", handler.DeleteEvent)
+			id := uuid.New().String()
+			event := models.Event{
+				ID:         id,
+				Title:      "Test Event",
+				Date:       time.Date(2023, 7, 22, 7, 0, 0, 0, time.UTC),
+				Duration:   time.Hour,
+				UserID:     1,
+				Recurrence: &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1},
+			}
 
-	w := httptest.NewRecorder()
+			services.EXPECT().UpdateEvent(gomock.Any(), id, int64(1), tc.expectedUpdate, models.EventOptions{}).
+				Return(event, nil)
 
-	ctx := context.Background()
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url+"/"+id, nil)
-	require.NoError(t, err)
+			handler := NewHandlerHTTP(services, logger)
 
-	r.ServeHTTP(w, req)
+			r := gin.Default()
+			r.PATCH(url+"/:id", handler.UpdateEvent)
 
-	require.Equal(t, http.StatusInternalServerError, w.Code)
-}
+			w := httptest.NewRecorder()
 
-func TestHandlerHTTPGetAllEvents(t *testing.T) {
-	dateStr := "2023-07-22T12:00:00Z"
-	date, err := time.Parse(time.RFC3339, dateStr)
-	require.NoError(t, err)
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url+"/"+id, bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("If-Match", `"1"`)
+			req.Header.Set("Content-Type", "application/merge-patch+json")
 
-	testCases := []struct {
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+func TestHandlerHTTPUpdateEventUnsupportedMediaType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	logger.EXPECT().Error(ErrUnsupportedPatch.Error(),
+		slog.String("action", "update"),
+		slog.String("errors", ErrUnsupportedPatch.Error()))
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.PATCH(url+"/:id", handler.UpdateEvent)
+
+	id := uuid.New().String()
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url+"/"+id, bytes.NewBufferString("title=new"))
+	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestHandlerHTTPGetEventByID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	id := uuid.New().String()
+
+	event := models.Event{
+		ID:                   id,
+		Title:                "Test Event",
+		Date:                 time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
+		Duration:             time.Hour,
+		Description:          "This is a test event",
+		UserID:               1,
+		NotificationInterval: 10 * time.Minute,
+		Reminders: []models.Reminder{{
+			ID:       7,
+			Before:   10 * time.Minute,
+			Channel:  models.ChannelLog,
+			Status:   models.StatusSent,
+			QueuedAt: time.Date(2023, 7, 22, 11, 49, 50, 0, time.UTC),
+			SentAt:   time.Date(2023, 7, 22, 11, 49, 51, 0, time.UTC),
+		}},
+		Version:   3,
+		UpdatedAt: time.Date(2023, 7, 20, 8, 30, 0, 0, time.UTC),
+	}
+
+	services.EXPECT().GetEventByID(gomock.Any(), id).Return(event, nil).Times(2)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.GET(url+"/:id", handler.GetEventByID)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/"+id, nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody Response
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	expectedBody := Response{
+		ID:                   id,
+		Title:                "Test Event",
+		Date:                 "2023-07-22T12:00:00Z",
+		Duration:             "1h0m0s",
+		Description:          "This is a test event",
+		UserID:               1,
+		NotificationInterval: "10m0s",
+		Reminders: []reminderResponse{{
+			ID:       7,
+			Before:   "10m0s",
+			Channel:  "log",
+			Status:   "sent",
+			QueuedAt: "2023-07-22T11:49:50Z",
+			SentAt:   "2023-07-22T11:49:51Z",
+		}},
+		Version:   3,
+		UpdatedAt: "2023-07-20T08:30:00Z",
+	}
+	require.Equal(t, expectedBody, responseBody)
+
+	tag := w.Header().Get("ETag")
+	require.Equal(t, `"3"`, tag)
+
+	// the unchanged event is not sent again
+	w = httptest.NewRecorder()
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, url+"/"+id, nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", "W/"+tag)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Equal(t, tag, w.Header().Get("ETag"))
+	require.Empty(t, w.Body.Bytes())
+}
+
+func TestHandlerHTTPEventJSONShape(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	date := time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC)
+	event := models.Event{
+		ID:                   uuid.New().String(),
+		Title:                "Test Event",
+		Date:                 date,
+		Duration:             90 * time.Minute,
+		Description:          "This is a test event",
+		UserID:               1,
+		NotificationInterval: 10 * time.Minute,
+		Reminders: []models.Reminder{{
+			ID:       7,
+			Before:   10 * time.Minute,
+			Channel:  models.ChannelLog,
+			Status:   models.StatusSent,
+			QueuedAt: date.Add(-10 * time.Minute),
+			SentAt:   date.Add(-10 * time.Minute),
+		}},
+		Attendees: []models.Attendee{{UserID: 2, Status: models.AttendeeAccepted, RespondedAt: date.AddDate(0, 0, -1)}},
+		Recurrence: &models.Recurrence{
+			Frequency:  models.FrequencyWeekly,
+			Interval:   1,
+			Exceptions: []time.Time{date.AddDate(0, 0, 7)},
+		},
+		Version:   3,
+		UpdatedAt: date.AddDate(0, 0, -2),
+	}
+
+	services.EXPECT().GetEventByID(gomock.Any(), event.ID).Return(event, nil)
+	services.EXPECT().GetAllByDayEvents(gomock.Any(), date, gomock.Nil()).Return([]models.Event{event}, nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.GET(url+"/:id", handler.GetEventByID)
+	r.GET(url+"/day/:date", handler.GetAllByDayEvents)
+
+	get := func(path string) []byte {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url+path, nil)
+		require.NoError(t, err)
+
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.Bytes()
+	}
+
+	var single map[string]interface{}
+	require.NoError(t, json.Unmarshal(get("/"+event.ID), &single))
+
+	var list struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(get("/day/"+date.Format(time.RFC3339)), &list))
+	require.Len(t, list.Data, 1)
+
+	// the event is represented the same way in both payloads, durations included
+	require.Equal(t, single, list.Data[0])
+	require.Equal(t, "1h30m0s", single["duration"])
+}
+
+func TestHandlerHTTPGetEventByIDError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		id           string
+		serviceErr   error
+		expectedCode int
+	}{
+		{
+			name:         "invalid id",
+			id:           "1234567",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "not found",
+			id:   uuid.New().String(),
+			serviceErr: customerror.CustomError{
+				Field:   "id",
+				Message: "no event with id",
+				Err:     customerror.ErrNotFound,
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "other user",
+			id:   uuid.New().String(),
+			serviceErr: customerror.CustomError{
+				Field:   "id",
+				Message: "event belongs to another user",
+				Err:     customerror.ErrForbidden,
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			if tc.serviceErr != nil {
+				services.EXPECT().GetEventByID(gomock.Any(), tc.id).Return(models.Event{}, tc.serviceErr)
+			}
+			logger.EXPECT().Error(gomock.Any(), slog.String("action", getByIDAction), gomock.Any())
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.GET(url+"/:id", handler.GetEventByID)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/"+tc.id, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandlerHTTPDeleteEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	id := uuid.New().String()
+
+	services.EXPECT().DeleteEvent(gomock.Any(), id, int64(1)).Return(nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.DELETE(url+"/:id", handler.DeleteEvent)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url+"/"+id, nil)
+	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHandlerHTTPDeleteEventError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	id := "test id"
+
+	expectedMessage := "invalid id"
+	expectedError := "invalid UUID length: 7"
+
+	logger.EXPECT().Error(expectedMessage,
+		slog.String("action", "delete"),
+		slog.String("errors", expectedError))
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.DELETE(url+"/:id", handler.DeleteEvent)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url+"/"+id, nil)
+	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandlerHTTPDeleteEventErrorDeletingEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	id := uuid.New().String()
+
+	expectedResponse := response{
+		Action:  deleteAction,
+		Field:   "id (param)",
+		Message: "error deleting event",
+		Error:   "no event with id" + id,
+	}
+
+	services.EXPECT().DeleteEvent(gomock.Any(), id, int64(1)).Return(errors.New(expectedResponse.Error))
+	logger.EXPECT().Error(expectedResponse.Message,
+		slog.String("action", "delete"),
+		slog.String("errors", expectedResponse.Error))
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.DELETE(url+"/:id", handler.DeleteEvent)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url+"/"+id, nil)
+	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandlerHTTPGetAllEvents(t *testing.T) {
+	dateStr := "2023-07-22T12:00:00Z"
+	date, err := time.Parse(time.RFC3339, dateStr)
+	require.NoError(t, err)
+
+	testCases := []struct {
 		name           string
 		period         string
 		expectedEvents []models.Event
@@ -902,13 +1479,13 @@ func TestHandlerHTTPGetAllEvents(t *testing.T) {
 
 			switch tc.period {
 			case "day":
-				services.EXPECT().GetAllByDayEvents(gomock.Any(), date).Return(tc.expectedEvents, nil)
+				services.EXPECT().GetAllByDayEvents(gomock.Any(), date, gomock.Nil()).Return(tc.expectedEvents, nil)
 				r.GET(url+"/"+tc.period+"/:date", handler.GetAllByDayEvents)
 			case "week":
-				services.EXPECT().GetAllByWeekEvents(gomock.Any(), date).Return(tc.expectedEvents, nil)
+				services.EXPECT().GetAllByWeekEvents(gomock.Any(), date, gomock.Nil(), time.Monday).Return(tc.expectedEvents, nil)
 				r.GET(url+"/"+tc.period+"/:date", handler.GetAllByWeekEvents)
 			case "month":
-				services.EXPECT().GetAllByMonthEvents(gomock.Any(), date).Return(tc.expectedEvents, nil)
+				services.EXPECT().GetAllByMonthEvents(gomock.Any(), date, gomock.Nil()).Return(tc.expectedEvents, nil)
 				r.GET(url+"/"+tc.period+"/:date", handler.GetAllByMonthEvents)
 			}
 
@@ -994,3 +1571,419 @@ func TestHandlerHTTPGetAllEventsError(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerHTTPUpdateEventOccurrence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	id := uuid.New().String()
+	detachedID := uuid.New().String()
+	occurrence := time.Date(2023, 7, 26, 10, 0, 0, 0, time.UTC)
+
+	update := models.EventUpdate{
+		Title: stringPtr("moved standup"),
+	}
+
+	expectedEvent := models.Event{
+		ID:           detachedID,
+		Title:        "moved standup",
+		Date:         occurrence,
+		Duration:     15 * time.Minute,
+		UserID:       1,
+		RecurrenceID: id,
+		OriginalDate: occurrence,
+	}
+
+	services.EXPECT().UpdateEventOccurrence(gomock.Any(), id, int64(1), occurrence, models.ScopeThis, update, models.EventOptions{}).
+		Return(expectedEvent, nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.PATCH(url+"/:id", handler.UpdateEvent)
+
+	jsonBody, err := json.Marshal(map[string]interface{}{"title": "moved standup"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	target := url + "/" + id + "?occurrence=2023-07-26T10:00:00Z"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, target, bytes.NewBuffer(jsonBody))
+	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Content-Type", "application/json")
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody Response
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	expectedBody := Response{
+		ID:                   detachedID,
+		Title:                "moved standup",
+		Date:                 "2023-07-26T10:00:00Z",
+		Duration:             "15m0s",
+		UserID:               1,
+		NotificationInterval: "0s",
+		RecurrenceID:         id,
+		OriginalDate:         "2023-07-26T10:00:00Z",
+	}
+
+	require.Equal(t, expectedBody, responseBody)
+}
+
+func TestHandlerHTTPDeleteEventOccurrence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	id := uuid.New().String()
+	occurrence := time.Date(2023, 7, 26, 10, 0, 0, 0, time.UTC)
+
+	services.EXPECT().DeleteEventOccurrence(gomock.Any(), id, int64(1), occurrence, models.ScopeFollowing).Return(nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.DELETE(url+"/:id", handler.DeleteEvent)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	target := url + "/" + id + "?occurrence=2023-07-26T10:00:00Z&scope=following"
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, target, nil)
+	require.NoError(t, err)
+	req.Header.Set("If-Match", `"1"`)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHandlerHTTPCreateEventDateBusy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	event := models.Event{
+		Title:    "Test Event",
+		Date:     time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+	}
+	busyErr := customerror.CustomError{
+		Field:   "date",
+		Message: "user already has an event at this time",
+		Err:     customerror.ErrDateBusy,
+	}
+	services.EXPECT().CreateEvent(gomock.Any(), event, models.EventOptions{}).Return("", busyErr)
+	services.EXPECT().CreateEvent(gomock.Any(), event, models.EventOptions{AllowOverlap: true}).Return("id", nil)
+	logger.EXPECT().Error("error creating event",
+		slog.String("action", createAction),
+		slog.String("errors", busyErr.Error()))
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.POST(url, handler.CreateEvent)
+
+	requestBody := map[string]interface{}{
+		"title":    "Test Event",
+		"date":     "2023-07-22T12:00:00Z",
+		"duration": "1h",
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		query        string
+		expectedCode int
+	}{
+		{query: "", expectedCode: http.StatusConflict},
+		{query: "?allow_overlap=true", expectedCode: http.StatusCreated},
+		{query: "?allow_overlap=maybe", expectedCode: http.StatusBadRequest},
+	}
+
+	logger.EXPECT().Error(ErrParsingAllowOverlap.Error(),
+		slog.String("action", createAction),
+		slog.String("errors", ErrParsingAllowOverlap.Error()))
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+
+		ctx := context.Background()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+tc.query, bytes.NewBuffer(jsonBody))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, tc.expectedCode, w.Code)
+	}
+}
+
+func TestHandlerHTTPCreateEventErrorKinds(t *testing.T) {
+	testCases := []struct {
+		name           string
+		serviceErr     error
+		expectedCode   int
+		expectedReason string
+	}{
+		{
+			name: "validation",
+			serviceErr: customerror.CustomError{
+				Field:   "title",
+				Message: "title cannot be empty",
+				Err:     customerror.ErrValidation,
+			},
+			expectedCode:   http.StatusBadRequest,
+			expectedReason: "VALIDATION",
+		},
+		{
+			name: "duplicate",
+			serviceErr: customerror.CustomError{
+				Message: "duplicate key value violates unique constraint",
+				Err:     customerror.ErrConflict,
+			},
+			expectedCode:   http.StatusConflict,
+			expectedReason: "CONFLICT",
+		},
+		{
+			name: "unavailable",
+			serviceErr: customerror.CustomError{
+				Message: "connection refused",
+				Err:     customerror.ErrUnavailable,
+			},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedReason: "UNAVAILABLE",
+		},
+		{
+			name: "timeout",
+			serviceErr: customerror.CustomError{
+				Message: "context deadline exceeded",
+				Err:     context.DeadlineExceeded,
+			},
+			expectedCode:   http.StatusGatewayTimeout,
+			expectedReason: "DEADLINE_EXCEEDED",
+		},
+		{
+			name: "internal",
+			serviceErr: customerror.CustomError{
+				Message: "no lines were inserted",
+			},
+			expectedCode:   http.StatusInternalServerError,
+			expectedReason: "INTERNAL",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			services.EXPECT().CreateEvent(gomock.Any(), gomock.Any(), models.EventOptions{}).Return("", tc.serviceErr)
+			logger.EXPECT().Error("error creating event", slog.String("action", createAction), gomock.Any())
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.POST(url, handler.CreateEvent)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			body := `{"title":"Test Event","date":"2023-07-22T12:00:00Z","duration":"1h"}`
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+
+			var responseBody response
+			err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedReason, responseBody.Reason)
+			require.Equal(t, tc.serviceErr.Error(), responseBody.Error)
+		})
+	}
+}
+
+func TestHandlerHTTPGetEventsInRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	after := models.Cursor{Date: from.AddDate(0, 0, 3), ID: "1"}
+	next := models.Cursor{Date: from.AddDate(0, 0, 5), ID: "2"}
+
+	event := models.Event{
+		ID:       next.ID,
+		Title:    "standup",
+		Date:     next.Date,
+		Duration: time.Hour,
+		UserID:   1,
+	}
+
+	services.EXPECT().GetEventsInRange(gomock.Any(), models.EventRange{
+		From:  from,
+		To:    to,
+		Query: "stand",
+		Order: models.SortDesc,
+		After: &after,
+		Limit: 1,
+	}).Return(models.EventPage{Events: []models.Event{event}, Next: &next}, nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.GET(url, handler.GetEventsInRange)
+
+	query := neturl.Values{}
+	query.Set("from", "2023-07-01T00:00:00Z")
+	query.Set("to", "2023-08-01T00:00:00Z")
+	query.Set("q", "stand")
+	query.Set("order", "desc")
+	query.Set("limit", "1")
+	query.Set("cursor", pagination.EncodeCursor(after))
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"?"+query.Encode(), nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var responseBody eventsResponse
+	err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, responseBody.Total)
+	require.Equal(t, event.ID, responseBody.Data[0].ID)
+	require.Equal(t, pagination.EncodeCursor(next), responseBody.NextCursor)
+}
+
+func TestHandlerHTTPGetEventsInRangeError(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		expectedError error
+	}{
+		{
+			name:          "invalid from",
+			query:         "from=date&to=2023-08-01T00:00:00Z",
+			expectedError: ErrParsingDate,
+		},
+		{
+			name:          "missing to",
+			query:         "from=2023-07-01T00:00:00Z",
+			expectedError: ErrParsingDate,
+		},
+		{
+			name:          "negative limit",
+			query:         "from=2023-07-01T00:00:00Z&to=2023-08-01T00:00:00Z&limit=-1",
+			expectedError: ErrParsingLimit,
+		},
+		{
+			name:          "invalid cursor",
+			query:         "from=2023-07-01T00:00:00Z&to=2023-08-01T00:00:00Z&cursor=invalid",
+			expectedError: pagination.ErrInvalidCursor,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			logger.EXPECT().Error(tc.expectedError.Error(),
+				slog.String("action", getInRangeAction),
+				slog.String("errors", tc.expectedError.Error()))
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.GET(url, handler.GetEventsInRange)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"?"+tc.query, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestHandlerHTTPGetAllByWeekEventsTimeZone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	date := time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC)
+	services.EXPECT().GetAllByWeekEvents(gomock.Any(), date, newYork, time.Sunday).Return(nil, nil)
+	logger.EXPECT().Error(ErrParsingTimeZone.Error(),
+		slog.String("action", getByWeekAction),
+		slog.String("errors", ErrParsingTimeZone.Error()))
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.GET(url+"/week/:date", handler.GetAllByWeekEvents)
+
+	testCases := []struct {
+		query        string
+		expectedCode int
+	}{
+		{query: "?tz=America/New_York&week_start=sunday", expectedCode: http.StatusOK},
+		{query: "?tz=Mars/Olympus", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+
+		ctx := context.Background()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/week/2023-07-22T12:00:00Z"+tc.query, nil)
+		require.NoError(t, err)
+
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, tc.expectedCode, w.Code)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,222 @@
+package internalhttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/period"
+)
+
+var (
+	freeBusyAction  = "get free busy"
+	findSlotsAction = "find slots"
+)
+
+var (
+	ErrParsingWorkingHours = errors.New("workday_start and workday_end must be in HH:MM format")
+	ErrParsingWeekdays     = errors.New("weekdays must be a list of weekday names")
+)
+
+type bodyFreeBusy struct {
+	UserIDs []int  `json:"user_ids"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// bodySlots asks for common free windows. Working hours are the whole day if they are empty.
+type bodySlots struct {
+	UserIDs      []int    `json:"user_ids"`
+	From         string   `json:"from"`
+	To           string   `json:"to"`
+	Duration     string   `json:"duration"`
+	WorkdayStart string   `json:"workday_start"`
+	WorkdayEnd   string   `json:"workday_end"`
+	TimeZone     string   `json:"tz"`
+	Weekdays     []string `json:"weekdays"`
+	Limit        int      `json:"limit"`
+}
+
+type intervalDetails struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type freeBusyDetails struct {
+	UserID int               `json:"user_id"`
+	Busy   []intervalDetails `json:"busy"`
+}
+
+type freeBusyResponse struct {
+	Calendars []freeBusyDetails `json:"calendars"`
+}
+
+type slotsResponse struct {
+	Total int               `json:"total"`
+	Data  []intervalDetails `json:"data"`
+}
+
+// GetFreeBusy returns the merged busy intervals of the users in [from, to).
+func (h *HandlerHTTP) GetFreeBusy(c *gin.Context) {
+	var body bodyFreeBusy
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		resp := newResponse(freeBusyAction, "", ErrParsingBody.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	from, to, field, err := parsePeriod(body.From, body.To)
+	if err != nil {
+		resp := newResponse(freeBusyAction, field, err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	calendars, err := h.services.GetFreeBusy(c, body.UserIDs, from, to)
+	if err != nil {
+		message := "error getting free busy"
+		resp := newResponse(freeBusyAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+
+	response := freeBusyResponse{
+		Calendars: make([]freeBusyDetails, 0, len(calendars)),
+	}
+	for _, calendar := range calendars {
+		response.Calendars = append(response.Calendars, freeBusyDetails{
+			UserID: calendar.UserID,
+			Busy:   toIntervalDetails(calendar.Busy),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// FindSlots returns the windows of the working hours in which all users are free for the duration.
+func (h *HandlerHTTP) FindSlots(c *gin.Context) {
+	var body bodySlots
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		resp := newResponse(findSlotsAction, "", ErrParsingBody.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	query, field, err := parseSlotQuery(body)
+	if err != nil {
+		resp := newResponse(findSlotsAction, field, err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	slots, err := h.services.FindSlots(c, query)
+	if err != nil {
+		message := "error finding slots"
+		resp := newResponse(findSlotsAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, slotsResponse{
+		Total: len(slots),
+		Data:  toIntervalDetails(slots),
+	})
+}
+
+// parsePeriod returns the name of the invalid field with the error.
+func parsePeriod(fromStr, toStr string) (time.Time, time.Time, string, error) {
+	from, err := time.Parse(time.RFC3339, fromStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, "from", ErrParsingDate
+	}
+	to, err := time.Parse(time.RFC3339, toStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, "to", ErrParsingDate
+	}
+	return from, to, "", nil
+}
+
+// parseSlotQuery returns the name of the invalid field with the error.
+func parseSlotQuery(body bodySlots) (models.SlotQuery, string, error) {
+	query := models.SlotQuery{
+		UserIDs: body.UserIDs,
+		Limit:   body.Limit,
+	}
+
+	var (
+		field string
+		err   error
+	)
+
+	query.From, query.To, field, err = parsePeriod(body.From, body.To)
+	if err != nil {
+		return models.SlotQuery{}, field, err
+	}
+
+	query.Duration, err = time.ParseDuration(body.Duration)
+	if err != nil {
+		return models.SlotQuery{}, "duration", ErrParsingDuration
+	}
+
+	if body.WorkdayStart != "" || body.WorkdayEnd != "" {
+		query.WorkdayStart, err = parseClock(body.WorkdayStart)
+		if err != nil {
+			return models.SlotQuery{}, "workday_start", ErrParsingWorkingHours
+		}
+		query.WorkdayEnd, err = parseClock(body.WorkdayEnd)
+		if err != nil {
+			return models.SlotQuery{}, "workday_end", ErrParsingWorkingHours
+		}
+	}
+
+	if body.TimeZone != "" {
+		query.Location, err = time.LoadLocation(body.TimeZone)
+		if err != nil {
+			return models.SlotQuery{}, "tz", ErrParsingTimeZone
+		}
+	}
+
+	for _, day := range body.Weekdays {
+		weekday, err := period.ParseWeekday(day)
+		if err != nil {
+			return models.SlotQuery{}, "weekdays", ErrParsingWeekdays
+		}
+		query.Weekdays = append(query.Weekdays, weekday)
+	}
+
+	return query, "", nil
+}
+
+// parseClock parses HH:MM into the offset from midnight, 24:00 is the end of the day.
+func parseClock(s string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(s, ":")
+	if !ok || len(hours) != 2 || len(minutes) != 2 {
+		return 0, ErrParsingWorkingHours
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 24 {
+		return 0, ErrParsingWorkingHours
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, ErrParsingWorkingHours
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func toIntervalDetails(intervals []models.Interval) []intervalDetails {
+	details := make([]intervalDetails, 0, len(intervals))
+	for _, interval := range intervals {
+		details = append(details, intervalDetails{
+			Start: interval.Start,
+			End:   interval.End,
+		})
+	}
+	return details
+}
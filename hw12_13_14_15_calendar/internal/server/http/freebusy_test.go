@@ -0,0 +1,189 @@
+package internalhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/exp/slog"
+)
+
+const freeBusyURL = "/api/v1/freebusy"
+
+func TestHandlerHTTPGetFreeBusy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	services.EXPECT().GetFreeBusy(gomock.Any(), []int{1, 2}, from, to).Return([]models.FreeBusy{
+		{UserID: 1, Busy: []models.Interval{{Start: from.Add(9 * time.Hour), End: from.Add(10 * time.Hour)}}},
+		{UserID: 2},
+	}, nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.POST(freeBusyURL, handler.GetFreeBusy)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, freeBusyURL,
+		bytes.NewBufferString(`{"user_ids":[1,2],"from":"2026-10-19T00:00:00Z","to":"2026-10-20T00:00:00Z"}`))
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"calendars":[
+		{"user_id":1,"busy":[{"start":"2026-10-19T09:00:00Z","end":"2026-10-19T10:00:00Z"}]},
+		{"user_id":2,"busy":[]}
+	]}`, w.Body.String())
+}
+
+func TestHandlerHTTPFindSlots(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	slot := models.Interval{Start: from.Add(13 * time.Hour), End: from.Add(16 * time.Hour)}
+
+	services.EXPECT().FindSlots(gomock.Any(), models.SlotQuery{
+		UserIDs:      []int{1, 2},
+		From:         from,
+		To:           from.AddDate(0, 0, 7),
+		Duration:     30 * time.Minute,
+		WorkdayStart: 9*time.Hour + 30*time.Minute,
+		WorkdayEnd:   24 * time.Hour,
+		Location:     newYork,
+		Weekdays:     []time.Weekday{time.Monday, time.Friday},
+		Limit:        5,
+	}).Return([]models.Interval{slot}, nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.POST(freeBusyURL+"/slots", handler.FindSlots)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, freeBusyURL+"/slots",
+		bytes.NewBufferString(`{"user_ids":[1,2],"from":"2026-10-19T00:00:00Z","to":"2026-10-26T00:00:00Z",
+			"duration":"30m","workday_start":"09:30","workday_end":"24:00","tz":"America/New_York",
+			"weekdays":["mon","Friday"],"limit":5}`))
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response slotsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Total)
+	require.True(t, slot.Start.Equal(response.Data[0].Start))
+	require.True(t, slot.End.Equal(response.Data[0].End))
+}
+
+func TestHandlerHTTPFindSlotsError(t *testing.T) {
+	const period = `"user_ids":[1],"from":"2026-10-19T00:00:00Z","to":"2026-10-26T00:00:00Z"`
+
+	testCases := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{
+			name:         "invalid duration",
+			body:         `{` + period + `,"duration":"half an hour"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid working hours",
+			body:         `{` + period + `,"duration":"30m","workday_start":"9:00","workday_end":"17:00"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid weekday",
+			body:         `{` + period + `,"duration":"30m","weekdays":["someday"]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid time zone",
+			body:         `{` + period + `,"duration":"30m","tz":"Mars/Olympus"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "too many users",
+			body: `{` + period + `,"duration":"30m"}`,
+			serviceErr: customerror.CustomError{
+				Field:   "user_ids",
+				Message: "cannot look up more than 50 users",
+				Err:     customerror.ErrValidation,
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "unauthenticated",
+			body: `{` + period + `,"duration":"30m"}`,
+			serviceErr: customerror.CustomError{
+				Field:   "user_id",
+				Message: "user id of the caller is missing",
+				Err:     customerror.ErrUnauthenticated,
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			if tc.serviceErr != nil {
+				services.EXPECT().FindSlots(gomock.Any(), gomock.Any()).Return(nil, tc.serviceErr)
+			}
+			logger.EXPECT().Error(gomock.Any(), slog.String("action", findSlotsAction), gomock.Any())
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.POST(freeBusyURL+"/slots", handler.FindSlots)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, freeBusyURL+"/slots",
+				bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
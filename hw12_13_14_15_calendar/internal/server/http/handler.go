@@ -1,8 +1,13 @@
 package internalhttp
 
 import (
+	"sync"
+
 	"github.com/gin-gonic/gin"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/auth"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/health"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/metrics"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service"
 )
 
@@ -10,18 +15,37 @@ type HandlerHTTP struct {
 	engine   *gin.Engine
 	services service.Services
 	logger   logger.Logger
+	// stopping is closed on shutdown to end the streams, the graceful shutdown waits for them otherwise.
+	stopping chan struct{}
+	stopOnce sync.Once
 }
 
 func NewHandlerHTTP(services service.Services, logger logger.Logger) *HandlerHTTP {
 	return &HandlerHTTP{
 		services: services,
 		logger:   logger,
+		stopping: make(chan struct{}),
 	}
 }
 
-func (h *HandlerHTTP) InitRoutes(logPath string) *gin.Engine {
+// StopStreams ends the event streams, it is called before the server is stopped.
+func (h *HandlerHTTP) StopStreams() {
+	h.stopOnce.Do(func() {
+		close(h.stopping)
+	})
+}
+
+func (h *HandlerHTTP) InitRoutes(logPath string, checker *health.Checker, authenticator *auth.Authenticator) *gin.Engine {
 	router := gin.New()
-	router.Use(loggerMiddleware(h.logger, logPath))
+	// services receive *gin.Context, so values of the request context must be visible through it
+	router.ContextWithFallback = true
+
+	// probes and scrapes are registered before the middlewares, so they are neither logged nor measured
+	router.GET("/healthz", gin.WrapH(checker.LivenessHandler()))
+	router.GET("/readyz", gin.WrapH(checker.ReadinessHandler()))
+	router.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+
+	router.Use(loggerMiddleware(h.logger, logPath), metricsMiddleware())
 	gin.SetMode(gin.ReleaseMode)
 	h.engine = router
 
@@ -29,14 +53,26 @@ func (h *HandlerHTTP) InitRoutes(logPath string) *gin.Engine {
 	{
 		version := api.Group("/v1")
 		{
-			adverts := version.Group("/events")
+			adverts := version.Group("/events", h.authMiddleware(authenticator))
 			{
 				adverts.POST("", h.CreateEvent)
+				adverts.GET("", h.GetEventsInRange)
+				adverts.GET("/:id", h.GetEventByID)
 				adverts.PATCH("/:id", h.UpdateEvent)
 				adverts.DELETE("/:id", h.DeleteEvent)
+				adverts.POST("/:id/attendees", h.InviteAttendees)
+				adverts.POST("/:id/rsvp", h.RespondToInvitation)
 				adverts.GET("/day/:date", h.GetAllByDayEvents)
 				adverts.GET("/week/:date", h.GetAllByWeekEvents)
 				adverts.GET("/month/:date", h.GetAllByMonthEvents)
+				adverts.GET("/export", h.ExportEvents)
+				adverts.POST("/import", h.ImportEvents)
+				adverts.GET("/watch", h.WatchEvents)
+			}
+			freeBusy := version.Group("/freebusy", h.authMiddleware(authenticator))
+			{
+				freeBusy.POST("", h.GetFreeBusy)
+				freeBusy.POST("/slots", h.FindSlots)
 			}
 		}
 	}
@@ -0,0 +1,131 @@
+package internalhttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	exportAction = "export"
+	importAction = "import"
+)
+
+var ErrReadingCalendar = errors.New("error reading calendar")
+
+const (
+	calendarContentType = "text/calendar; charset=utf-8"
+	calendarFileName    = "events.ics"
+	calendarFormFile    = "file"
+	maxCalendarSize     = 10 << 20 // 10 MB
+)
+
+type importResult struct {
+	UID   string `json:"uid,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type importResponse struct {
+	Total   int            `json:"total"`
+	Created int            `json:"created"`
+	Results []importResult `json:"results"`
+}
+
+// ExportEvents renders the caller's events in [from, to] as the .ics file.
+func (h *HandlerHTTP) ExportEvents(c *gin.Context) {
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		resp := newResponse(exportAction, "from (query)", ErrParsingDate.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	to, err := time.Parse(time.RFC3339, c.Query("to"))
+	if err != nil {
+		resp := newResponse(exportAction, "to (query)", ErrParsingDate.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	data, err := h.services.ExportEvents(c, from, to)
+	if err != nil {
+		message := "error exporting events"
+		resp := newResponse(exportAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+calendarFileName+`"`)
+	c.Data(http.StatusOK, calendarContentType, data)
+}
+
+// ImportEvents creates events from the .ics file sent either as multipart form file or as the request body.
+func (h *HandlerHTTP) ImportEvents(c *gin.Context) {
+	data, err := readCalendar(c)
+	if err != nil {
+		resp := newResponse(importAction, calendarFormFile, ErrReadingCalendar.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	opts, err := parseEventOptions(c)
+	if err != nil {
+		resp := newResponse(importAction, "allow_overlap (query)", err.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	results, err := h.services.ImportEvents(c, data, opts)
+	if err != nil {
+		message := "error importing events"
+		resp := newResponse(importAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+
+	response := importResponse{
+		Total:   len(results),
+		Results: make([]importResult, 0, len(results)),
+	}
+	for _, result := range results {
+		res := importResult{
+			UID: result.UID,
+			ID:  result.ID,
+		}
+		if result.Err != nil {
+			res.Error = result.Err.Error()
+		} else {
+			response.Created++
+		}
+		response.Results = append(response.Results, res)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func readCalendar(c *gin.Context) ([]byte, error) {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxCalendarSize)
+
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return io.ReadAll(body)
+	}
+
+	c.Request.Body = body
+	fileHeader, err := c.FormFile(calendarFormFile)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
@@ -0,0 +1,190 @@
+package internalhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/exp/slog"
+)
+
+const calendar = "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"
+
+func TestHandlerHTTPExportEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	services.EXPECT().ExportEvents(gomock.Any(), from, to).Return([]byte(calendar), nil)
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.Default()
+	r.GET(url+"/export", handler.ExportEvents)
+
+	w := httptest.NewRecorder()
+
+	ctx := context.Background()
+	target := url + "/export?from=2023-07-01T00:00:00Z&to=2023-08-01T00:00:00Z"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, calendarContentType, w.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename="events.ics"`, w.Header().Get("Content-Disposition"))
+	require.Equal(t, calendar, w.Body.String())
+}
+
+func TestHandlerHTTPExportEventsError(t *testing.T) {
+	testCases := []struct {
+		name             string
+		query            string
+		expectedResponse response
+	}{
+		{
+			name:  "invalid from",
+			query: "?to=2023-08-01T00:00:00Z",
+			expectedResponse: response{
+				Action:  exportAction,
+				Field:   "from (query)",
+				Message: ErrParsingDate.Error(),
+				Error:   `parsing time "" as "2006-01-02T15:04:05Z07:00": cannot parse "" as "2006"`,
+			},
+		},
+		{
+			name:  "invalid to",
+			query: "?from=2023-07-01T00:00:00Z&to=2023-08-01",
+			expectedResponse: response{
+				Action:  exportAction,
+				Field:   "to (query)",
+				Message: ErrParsingDate.Error(),
+				Error:   `parsing time "2023-08-01" as "2006-01-02T15:04:05Z07:00": cannot parse "" as "T"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			logger.EXPECT().Error(tc.expectedResponse.Message,
+				slog.String("action", tc.expectedResponse.Action),
+				slog.String("errors", tc.expectedResponse.Error))
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.GET(url+"/export", handler.ExportEvents)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/export"+tc.query, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var responseBody response
+			err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedResponse, responseBody)
+		})
+	}
+}
+
+func TestHandlerHTTPImportEvents(t *testing.T) {
+	results := []models.ImportResult{
+		{UID: "1", ID: "created id"},
+		{UID: "2", Err: errors.New("DTSTART is required")},
+	}
+	expectedBody := importResponse{
+		Total:   2,
+		Created: 1,
+		Results: []importResult{
+			{UID: "1", ID: "created id"},
+			{UID: "2", Error: "DTSTART is required"},
+		},
+	}
+
+	multipartBody := &bytes.Buffer{}
+	writer := multipart.NewWriter(multipartBody)
+	part, err := writer.CreateFormFile(calendarFormFile, "events.ics")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(calendar))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	testCases := []struct {
+		name        string
+		body        []byte
+		contentType string
+	}{
+		{
+			name:        "raw body",
+			body:        []byte(calendar),
+			contentType: "text/calendar",
+		},
+		{
+			name:        "multipart form",
+			body:        multipartBody.Bytes(),
+			contentType: writer.FormDataContentType(),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			services.EXPECT().ImportEvents(gomock.Any(), []byte(calendar), models.EventOptions{}).Return(results, nil)
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.POST(url+"/import", handler.ImportEvents)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/import", bytes.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var responseBody importResponse
+			err = json.Unmarshal(w.Body.Bytes(), &responseBody)
+			require.NoError(t, err)
+			require.Equal(t, expectedBody, responseBody)
+		})
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/auth"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/identity"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/metrics"
 	"golang.org/x/exp/slog"
 )
 
@@ -58,6 +61,54 @@ func loggerMiddleware(log logger.Logger, logPath string) gin.HandlerFunc {
 	}
 }
 
+var (
+	httpRequests = metrics.NewCounter("calendar_http_requests_total",
+		"HTTP requests by method, route and status code.", "method", "route", "status")
+	httpDuration = metrics.NewHistogram("calendar_http_request_duration_seconds",
+		"Latency of HTTP requests by method and route.", metrics.DefBuckets, "method", "route")
+)
+
+// unmatchedRoute is the route of requests which match none of the routes, so paths do not blow up labels.
+const unmatchedRoute = "unmatched"
+
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		httpRequests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+		httpDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route)
+	}
+}
+
+var authAction = "authenticate user"
+
+// authMiddleware puts the caller authenticated by the bearer token or the API key into the request context.
+func (h *HandlerHTTP) authMiddleware(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := authenticator.Authenticate(auth.Credentials{
+			Authorization: c.GetHeader(auth.HeaderAuthorization),
+			APIKey:        c.GetHeader(auth.HeaderAPIKey),
+		})
+		if err != nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			resp := newResponse(authAction, auth.HeaderAuthorization+" or "+auth.HeaderAPIKey+" (header)", err.Error(), err)
+			h.sentResponse(c, http.StatusUnauthorized, resp)
+			return
+		}
+
+		c.Request = c.Request.WithContext(identity.WithPrincipal(c.Request.Context(), principal))
+
+		c.Next()
+	}
+}
+
 func requestInformation(r *http.Request, duration time.Duration) RequestInfo {
 	clientIP := r.RemoteAddr
 	date := time.Now().Format("02/Jan/2006:15:04:05 -0700")
@@ -0,0 +1,150 @@
+package internalhttp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/auth"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/identity"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/exp/slog"
+)
+
+func TestHandlerHTTPAuthMiddleware(t *testing.T) {
+	authenticator, err := auth.New(auth.Config{
+		APIKeys: []auth.APIKey{
+			{Name: "owner", UserID: 1, SHA256: hashAPIKey("owner-key")},
+			{Name: "other", UserID: 2, SHA256: hashAPIKey("other-key")},
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		header       string
+		userID       int
+		expectedCode int
+		serviceErr   error
+	}{
+		{
+			name:         "owner",
+			header:       "owner-key",
+			userID:       1,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "missing header",
+			header:       "",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "invalid key",
+			header:       "abc",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "not owner",
+			header:       "other-key",
+			userID:       2,
+			expectedCode: http.StatusForbidden,
+			serviceErr: customerror.CustomError{
+				Field:   "id",
+				Message: "event belongs to another user",
+				Err:     customerror.ErrForbidden,
+			},
+		},
+		{
+			name:         "not found",
+			header:       "owner-key",
+			userID:       1,
+			expectedCode: http.StatusNotFound,
+			serviceErr: customerror.CustomError{
+				Field:   "id",
+				Message: "no event",
+				Err:     customerror.ErrNotFound,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			services := mock_service.NewMockServices(ctrl)
+			logger := mock_logger.NewMockLogger(ctrl)
+
+			id := uuid.New().String()
+
+			if tc.expectedCode == http.StatusUnauthorized {
+				logger.EXPECT().Error(gomock.Any(), slog.String("action", authAction), gomock.Any())
+			} else {
+				services.EXPECT().DeleteEvent(gomock.Any(), id, int64(1)).DoAndReturn(func(ctx context.Context, _ string, _ int64) error {
+					userID, ok := identity.UserID(ctx)
+					require.True(t, ok)
+					require.Equal(t, tc.userID, userID)
+					return tc.serviceErr
+				})
+				if tc.serviceErr != nil {
+					logger.EXPECT().Error("error deleting event", slog.String("action", deleteAction), gomock.Any())
+				}
+			}
+
+			handler := NewHandlerHTTP(services, logger)
+
+			r := gin.Default()
+			r.ContextWithFallback = true
+			r.DELETE(url+"/:id", handler.authMiddleware(authenticator), handler.DeleteEvent)
+
+			w := httptest.NewRecorder()
+
+			ctx := context.Background()
+			req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url+"/"+id, nil)
+			require.NoError(t, err)
+			req.Header.Set("If-Match", `"1"`)
+			if tc.header != "" {
+				req.Header.Set(auth.HeaderAPIKey, tc.header)
+			}
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
+
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	r := gin.New()
+	r.Use(metricsMiddleware())
+	r.GET("/test/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	route := "/test/:id"
+	requests := httpRequests.Value(http.MethodGet, route, "204")
+	unmatched := httpRequests.Value(http.MethodGet, unmatchedRoute, "404")
+	observed := httpDuration.Count(http.MethodGet, route)
+
+	for _, path := range []string{"/test/1", "/test/2", "/unknown"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// requests are counted by the route rather than the path
+	require.Equal(t, requests+2, httpRequests.Value(http.MethodGet, route, "204"))
+	require.Equal(t, unmatched+1, httpRequests.Value(http.MethodGet, unmatchedRoute, "404"))
+	require.Equal(t, observed+2, httpDuration.Count(http.MethodGet, route))
+}
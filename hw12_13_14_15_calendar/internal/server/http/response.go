@@ -1,7 +1,9 @@
 package internalhttp
 
 import (
+	"context"
 	"errors"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
@@ -13,6 +15,8 @@ type response struct {
 	Field   string `json:"field,omitempty"`
 	Message string `json:"message"`
 	Error   string `json:"error"`
+	// Reason is the machine-readable reason of the errors returned by the service.
+	Reason string `json:"reason,omitempty"`
 }
 
 func newResponse(action, field, message string, err error) response {
@@ -24,6 +28,7 @@ func newResponse(action, field, message string, err error) response {
 			Field:   customError.Field,
 			Message: message,
 			Error:   customError.Error(),
+			Reason:  customerror.Reason(err),
 		}
 		return resp
 	}
@@ -46,3 +51,30 @@ func (h *HandlerHTTP) sentResponse(c *gin.Context, code int, resp response) {
 	}
 	c.AbortWithStatusJSON(code, resp)
 }
+
+// errorStatus returns HTTP status code for the kind of the error returned by the service.
+func errorStatus(err error) int {
+	switch customerror.KindOf(err) {
+	case customerror.KindValidation:
+		return http.StatusBadRequest
+	case customerror.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case customerror.KindPermission:
+		return http.StatusForbidden
+	case customerror.KindNotFound:
+		return http.StatusNotFound
+	case customerror.KindConflict:
+		// the stale version is the failed If-Match precondition rather than a conflict of the request
+		if errors.Is(err, customerror.ErrVersionMismatch) {
+			return http.StatusPreconditionFailed
+		}
+		return http.StatusConflict
+	case customerror.KindUnavailable:
+		if errors.Is(err, context.DeadlineExceeded) {
+			return http.StatusGatewayTimeout
+		}
+		return http.StatusServiceUnavailable
+	case customerror.KindInternal:
+	}
+	return http.StatusInternalServerError
+}
@@ -5,6 +5,8 @@ import (
 	"net"
 	"net/http"
 	"time"
+
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/tlsconfig"
 )
 
 type Config struct {
@@ -12,13 +14,16 @@ type Config struct {
 	Port         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	TLS          tlsconfig.Config
 }
 
 type ServerHTTP struct {
 	srv *http.Server
+	tls *tlsconfig.Reloader
 }
 
-func NewServerHTTP(cfg Config, handler http.Handler) *ServerHTTP {
+// NewServerHTTP returns the error if TLS is enabled and its files cannot be loaded.
+func NewServerHTTP(cfg Config, handler http.Handler) (*ServerHTTP, error) {
 	srv := &http.Server{
 		Addr:           net.JoinHostPort(cfg.Host, cfg.Port),
 		Handler:        handler,
@@ -26,15 +31,38 @@ func NewServerHTTP(cfg Config, handler http.Handler) *ServerHTTP {
 		ReadTimeout:    cfg.ReadTimeout,
 		WriteTimeout:   cfg.WriteTimeout,
 	}
+
+	var reloader *tlsconfig.Reloader
+	if cfg.TLS.Enabled() {
+		var err error
+		reloader, err = tlsconfig.NewReloader(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+	}
+
 	return &ServerHTTP{
 		srv: srv,
-	}
+		tls: reloader,
+	}, nil
 }
 
 func (s *ServerHTTP) Start() error {
+	if s.tls != nil {
+		// the certificate is taken from TLSConfig
+		return s.srv.ListenAndServeTLS("", "")
+	}
 	return s.srv.ListenAndServe()
 }
 
+// WatchTLS reloads the changed certificate files until ctx is done, it returns at once if TLS is disabled.
+func (s *ServerHTTP) WatchTLS(ctx context.Context, onError func(err error)) {
+	if s.tls != nil {
+		s.tls.Watch(ctx, onError)
+	}
+}
+
 func (s *ServerHTTP) Stop(ctx context.Context) error {
 	return s.srv.Shutdown(ctx)
 }
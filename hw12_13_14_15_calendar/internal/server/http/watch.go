@@ -0,0 +1,113 @@
+package internalhttp
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+var watchAction = "watch"
+
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// sseKeepAlive is how often the comment is sent to the idle stream, so proxies do not close it.
+const sseKeepAlive = 15 * time.Second
+
+// sseErrorEvent ends the stream, the client has to reload the events and watch again.
+const sseErrorEvent = "error"
+
+type changeDetails struct {
+	Type      string    `json:"type"`
+	EventID   string    `json:"event_id"`
+	Version   int64     `json:"version"`
+	ChangedAt time.Time `json:"changed_at"`
+	Event     *Response `json:"event,omitempty"`
+}
+
+// WatchEvents streams changes of the caller's events as Server-Sent Events named after the type of the change.
+// Optional "from" and "to" query parameters select events which take place in [from, to).
+func (h *HandlerHTTP) WatchEvents(c *gin.Context) {
+	from, err := parseOptionalDate(c.Query("from"))
+	if err != nil {
+		resp := newResponse(watchAction, "from (query)", ErrParsingDate.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	to, err := parseOptionalDate(c.Query("to"))
+	if err != nil {
+		resp := newResponse(watchAction, "to (query)", ErrParsingDate.Error(), err)
+		h.sentResponse(c, http.StatusBadRequest, resp)
+		return
+	}
+
+	sub, err := h.services.WatchEvents(c, from, to)
+	if err != nil {
+		message := "error watching events"
+		resp := newResponse(watchAction, "", message, err)
+		h.sentResponse(c, errorStatus(err), resp)
+		return
+	}
+	defer sub.Close()
+
+	// the stream outlives the write timeout of the server
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.stopping:
+			c.SSEvent(sseErrorEvent, gin.H{"error": ErrShuttingDown.Error()})
+			c.Writer.Flush()
+			return
+		case <-ticker.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+		case change, ok := <-sub.Changes():
+			if !ok {
+				c.SSEvent(sseErrorEvent, gin.H{"error": sub.Err().Error()})
+				c.Writer.Flush()
+				return
+			}
+			c.SSEvent(string(change.Type), toChangeDetails(change))
+		}
+		c.Writer.Flush()
+	}
+}
+
+// parseOptionalDate returns zero time for the empty value.
+func parseOptionalDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func toChangeDetails(change models.EventChange) changeDetails {
+	details := changeDetails{
+		Type:      string(change.Type),
+		EventID:   change.EventID,
+		Version:   change.Version,
+		ChangedAt: change.ChangedAt,
+	}
+	if change.Event != nil {
+		event := toResponse(*change.Event)
+		details.Event = &event
+	}
+	return details
+}
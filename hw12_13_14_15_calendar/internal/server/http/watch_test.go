@@ -0,0 +1,188 @@
+package internalhttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
+	mock_logger "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/logger/mock"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	mock_service "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/service/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/exp/slog"
+)
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readSSEvent reads lines of the next event skipping comments.
+func readSSEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case line == "" && event.name != "":
+			return event
+		case strings.HasPrefix(line, "event:"):
+			event.name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			event.data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+}
+
+func TestHandlerHTTPWatchEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	changedAt := time.Date(2023, 7, 2, 0, 0, 0, 0, time.UTC)
+	event := models.Event{
+		ID:       "id",
+		Title:    "title",
+		Date:     from.Add(time.Hour),
+		Duration: time.Hour,
+		UserID:   1,
+		Version:  1,
+	}
+
+	bus := changefeed.NewBus(changefeed.DefaultBuffer)
+	subscribed := make(chan *changefeed.Subscription, 1)
+	services.EXPECT().WatchEvents(gomock.Any(), from, time.Time{}).
+		DoAndReturn(func(context.Context, time.Time, time.Time) (*changefeed.Subscription, error) {
+			sub := bus.Subscribe(changefeed.Filter{UserID: 1, From: from})
+			subscribed <- sub
+			return sub, nil
+		})
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.New()
+	r.GET(url+"/watch", handler.WatchEvents)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+url+"/watch?from=2023-07-01T00:00:00Z", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	sub := <-subscribed
+	bus.Publish(ctx, models.EventChange{
+		Type:      models.ChangeCreated,
+		EventID:   event.ID,
+		UserID:    1,
+		Version:   1,
+		ChangedAt: changedAt,
+		Event:     &event,
+	})
+	bus.Publish(ctx, models.EventChange{
+		Type:      models.ChangeDeleted,
+		EventID:   "other",
+		UserID:    1,
+		Version:   3,
+		ChangedAt: changedAt,
+	})
+
+	reader := bufio.NewReader(resp.Body)
+
+	created := readSSEvent(t, reader)
+	require.Equal(t, string(models.ChangeCreated), created.name)
+	expectedEvent := toResponse(event)
+	expected, err := json.Marshal(changeDetails{
+		Type:      string(models.ChangeCreated),
+		EventID:   event.ID,
+		Version:   1,
+		ChangedAt: changedAt,
+		Event:     &expectedEvent,
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), created.data)
+
+	deleted := readSSEvent(t, reader)
+	require.Equal(t, string(models.ChangeDeleted), deleted.name)
+	require.JSONEq(t, `{"type":"deleted","event_id":"other","version":3,"changed_at":"2023-07-02T00:00:00Z"}`, deleted.data)
+
+	// the closed subscription ends the stream with the error event
+	sub.Close()
+	closed := readSSEvent(t, reader)
+	require.Equal(t, sseErrorEvent, closed.name)
+	require.JSONEq(t, `{"error":"`+changefeed.ErrClosed.Error()+`"}`, closed.data)
+
+	// streams are ended on shutdown
+	services.EXPECT().WatchEvents(gomock.Any(), time.Time{}, time.Time{}).
+		Return(bus.Subscribe(changefeed.Filter{UserID: 1}), nil)
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+url+"/watch", nil)
+	require.NoError(t, err)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	handler.StopStreams()
+	stopped := readSSEvent(t, bufio.NewReader(resp.Body))
+	require.Equal(t, sseErrorEvent, stopped.name)
+	require.JSONEq(t, `{"error":"`+ErrShuttingDown.Error()+`"}`, stopped.data)
+}
+
+func TestHandlerHTTPWatchEventsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	services := mock_service.NewMockServices(ctrl)
+	logger := mock_logger.NewMockLogger(ctrl)
+
+	expectedResponse := response{
+		Action:  watchAction,
+		Field:   "to (query)",
+		Message: ErrParsingDate.Error(),
+		Error:   `parsing time "2023-08-01" as "2006-01-02T15:04:05Z07:00": cannot parse "" as "T"`,
+	}
+	logger.EXPECT().Error(expectedResponse.Message,
+		slog.String("action", expectedResponse.Action),
+		slog.String("errors", expectedResponse.Error))
+
+	handler := NewHandlerHTTP(services, logger)
+
+	r := gin.New()
+	r.GET(url+"/watch", handler.WatchEvents)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/watch?to=2023-08-01", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var responseBody response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &responseBody))
+	require.Equal(t, expectedResponse, responseBody)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+var (
+	ErrNoAttendees      = errors.New("attendees cannot be empty")
+	ErrInvalidAttendee  = errors.New("attendee must be the positive user id of another user")
+	ErrTooManyAttendees = fmt.Errorf("event cannot have more than %d attendees", MaxAttendees)
+	ErrInvalidResponse  = errors.New("response must be one of accepted, declined, tentative")
+)
+
+// MaxAttendees is the maximum number of users invited to the event.
+const MaxAttendees = 100
+
+// InviteAttendees invites the users to the caller's event if it still has the given version.
+// Users who are already invited keep their answers.
+func (e *EventService) InviteAttendees(ctx context.Context, id string, version int64, attendees []int,
+) (models.Event, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	if len(attendees) == 0 {
+		return models.Event{}, customerror.CustomError{
+			Field:   "user_ids",
+			Message: ErrNoAttendees.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	unique := make([]int, 0, len(attendees))
+	seen := make(map[int]struct{}, len(attendees))
+	for _, attendee := range attendees {
+		if attendee <= 0 || attendee == userID {
+			return models.Event{}, customerror.CustomError{
+				Field:   "user_ids",
+				Message: ErrInvalidAttendee.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+		if _, ok := seen[attendee]; ok {
+			continue
+		}
+		seen[attendee] = struct{}{}
+		unique = append(unique, attendee)
+	}
+
+	current, err := e.event.GetEventByID(ctx, id)
+	if err != nil {
+		return models.Event{}, err
+	}
+	invited := len(current.Attendees)
+	for _, attendee := range unique {
+		if _, ok := models.FindAttendee(current.Attendees, attendee); !ok {
+			invited++
+		}
+	}
+	if invited > MaxAttendees {
+		return models.Event{}, customerror.CustomError{
+			Field:   "user_ids",
+			Message: ErrTooManyAttendees.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	event, err := e.event.InviteAttendees(ctx, userID, id, version, unique)
+	if err != nil {
+		return models.Event{}, err
+	}
+	e.publish(ctx, models.ChangeUpdated, event)
+
+	return event, nil
+}
+
+// RespondToInvitation records the caller's answer to the invitation to the event.
+func (e *EventService) RespondToInvitation(ctx context.Context, id string, status models.AttendeeStatus,
+) (models.Event, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	switch status {
+	case models.AttendeeAccepted, models.AttendeeDeclined, models.AttendeeTentative:
+	default:
+		return models.Event{}, customerror.CustomError{
+			Field:   "status",
+			Message: ErrInvalidResponse.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	event, err := e.event.RespondToInvitation(ctx, userID, id, status)
+	if err != nil {
+		return models.Event{}, err
+	}
+	e.publish(ctx, models.ChangeUpdated, event)
+
+	return event, nil
+}
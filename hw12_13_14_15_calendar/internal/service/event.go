@@ -1,44 +1,81 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
 	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/ical"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/identity"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/period"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage"
 )
 
 var (
 	ErrInvalidUserID               = errors.New("user id must be positive number")
 	ErrEmptyTitle                  = errors.New("title cannot be empty")
+	ErrEmptyDate                   = errors.New("date cannot be empty")
 	ErrInvalidDuration             = errors.New("duration cannot be non-positive")
 	ErrInvalidNotificationInterval = errors.New("notification interval cannot be negative")
+	ErrInvalidRecurrenceScope      = errors.New("recurrence scope must be one of all, this, following")
+	ErrNotRecurringEvent           = errors.New("recurrence exceptions and time zone require a recurring event")
+	ErrInvalidPeriod               = errors.New("from cannot be after to")
+	ErrMissingCaller               = errors.New("user id of the caller is missing")
+	ErrInvalidSortOrder            = errors.New("sort order must be one of asc, desc")
+	ErrInvalidLimit                = fmt.Errorf("limit must be between 0 and %d", MaxPageSize)
+	ErrInvalidReminderBefore       = errors.New("reminder cannot be after the start of the event")
+	ErrInvalidReminderChannel      = errors.New("reminder channel must be one of log, email, webhook")
+	ErrDuplicateReminder           = errors.New("reminders cannot repeat the time and the channel")
 )
 
+// MaxPageSize is the maximum number of events in the page of GetEventsInRange.
+const MaxPageSize = 1000
+
+// DefaultPageSize is the number of events in the page of GetEventsInRange if the limit is not set.
+const DefaultPageSize = 100
+
 type EventService struct {
-	event storage.EventStorage
+	event   storage.EventStorage
+	changes changefeed.Feed
 }
 
-func NewEventService(event storage.EventStorage) *EventService {
-	return &EventService{event: event}
+func NewEventService(event storage.EventStorage, changes changefeed.Feed) *EventService {
+	return &EventService{
+		event:   event,
+		changes: changes,
+	}
 }
 
-func (e *EventService) CreateEvent(ctx context.Context, event models.Event) (string, error) {
+// CreateEvent creates the event owned by the caller.
+func (e *EventService) CreateEvent(ctx context.Context, event models.Event, opts models.EventOptions) (string, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return "", err
+	}
+	event.UserID = userID
+
 	event.Title = strings.TrimSpace(event.Title)
 	if event.Title == "" {
 		return "", customerror.CustomError{
 			Field:   "title",
 			Message: ErrEmptyTitle.Error(),
+			Err:     customerror.ErrValidation,
 		}
 	}
 	if event.Duration <= 0 {
 		return "", customerror.CustomError{
 			Field:   "duration",
 			Message: ErrInvalidDuration.Error(),
+			Err:     customerror.ErrValidation,
 		}
 	}
 	event.Description = strings.TrimSpace(event.Description)
@@ -46,61 +83,589 @@ func (e *EventService) CreateEvent(ctx context.Context, event models.Event) (str
 		return "", customerror.CustomError{
 			Field:   "user_id",
 			Message: ErrInvalidUserID.Error(),
+			Err:     customerror.ErrValidation,
 		}
 	}
 	if event.NotificationInterval < 0 {
 		return "", customerror.CustomError{
 			Field:   "notification_interval",
 			Message: ErrInvalidNotificationInterval.Error(),
+			Err:     customerror.ErrValidation,
 		}
 	}
+	// the notification interval is the shorthand for the single reminder
+	if len(event.Reminders) == 0 && event.NotificationInterval > 0 {
+		event.Reminders = []models.Reminder{{Before: event.NotificationInterval}}
+	}
+	event.Reminders, err = normalizeReminders(event.Reminders)
+	if err != nil {
+		return "", err
+	}
+	if err := validateRecurrence(event.Recurrence); err != nil {
+		return "", err
+	}
 
 	id := uuid.New().String()
 	event.ID = id
-	return e.event.CreateEvent(ctx, event)
+	id, err = e.event.CreateEvent(ctx, event, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// the storage creates the first version of the event
+	event.Version = 1
+	e.publish(ctx, models.ChangeCreated, event)
+
+	return id, nil
 }
 
-func (e *EventService) UpdateEvent(ctx context.Context, id string, event models.Event) (models.Event, error) {
-	event.Title = strings.TrimSpace(event.Title)
-	if event.Duration < 0 {
-		return models.Event{}, customerror.CustomError{
-			Field:   "duration",
-			Message: ErrInvalidDuration.Error(),
-		}
+// UpdateEvent applies the fields set in the partial update to the caller's event if it still has the given version.
+func (e *EventService) UpdateEvent(ctx context.Context, id string, version int64, update models.EventUpdate,
+	opts models.EventOptions,
+) (models.Event, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return models.Event{}, err
 	}
-	event.Description = strings.TrimSpace(event.Description)
-	if event.UserID < 0 {
+
+	update, err = validateUpdate(update)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	// the watchers of the event before the update are notified too
+	previous, err := e.event.GetEventByID(ctx, id)
+	if err != nil {
+		return models.Event{}, err
+	}
+	update, err = mergeRecurrence(update, previous)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	updated, err := e.event.UpdateEvent(ctx, userID, id, version, update, opts)
+	if err != nil {
+		return models.Event{}, err
+	}
+	e.publishUpdated(ctx, previous, updated)
+
+	return updated, nil
+}
+
+// DeleteEvent deletes the caller's event if it still has the given version.
+func (e *EventService) DeleteEvent(ctx context.Context, id string, version int64) error {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	// the attendees of the deleted event are notified too
+	deleted, err := e.event.GetEventByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := e.event.DeleteEvent(ctx, userID, id, version); err != nil {
+		return err
+	}
+	e.publishDeleted(ctx, userID, id, version, models.AttendeeIDs(deleted.Attendees))
+
+	return nil
+}
+
+// GetEventByID returns the event of the caller or the event the caller is invited to.
+func (e *EventService) GetEventByID(ctx context.Context, id string) (models.Event, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	event, err := e.event.GetEventByID(ctx, id)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	if _, invited := models.FindAttendee(event.Attendees, userID); event.UserID != userID && !invited {
 		return models.Event{}, customerror.CustomError{
-			Field:   "user_id",
-			Message: ErrInvalidUserID.Error(),
+			Field:   "id",
+			Message: "event with id " + id + " belongs to another user",
+			Err:     customerror.ErrForbidden,
 		}
 	}
-	if event.NotificationInterval < 0 {
+
+	return event, nil
+}
+
+// UpdateEventOccurrence updates only the given occurrence or the given and following occurrences
+// of the recurring event of the given version. The changed occurrences become a new event which is returned.
+func (e *EventService) UpdateEventOccurrence(ctx context.Context, id string, version int64, occurrence time.Time,
+	scope models.RecurrenceScope, update models.EventUpdate, opts models.EventOptions,
+) (models.Event, error) {
+	switch scope {
+	case models.ScopeAll:
+		return e.UpdateEvent(ctx, id, version, update, opts)
+	case models.ScopeThis, models.ScopeFollowing:
+	default:
 		return models.Event{}, customerror.CustomError{
-			Field:   "notification_interval",
-			Message: ErrInvalidNotificationInterval.Error(),
+			Field:   "scope",
+			Message: ErrInvalidRecurrenceScope.Error(),
+			Err:     customerror.ErrValidation,
 		}
 	}
 
-	return e.event.UpdateEvent(ctx, id, event)
+	userID, err := callerID(ctx)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	update, err = validateUpdate(update)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	newID := uuid.New().String()
+
+	// the watchers of the series before the change are notified too
+	series, err := e.event.GetEventByID(ctx, id)
+	if err != nil {
+		return models.Event{}, err
+	}
+	update, err = mergeRecurrence(update, series)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	changed, err := e.event.UpdateEventOccurrence(ctx, userID, id, version, occurrence, scope, newID, update, opts)
+	if err != nil {
+		return models.Event{}, err
+	}
+	e.publishSeries(ctx, userID, series, version)
+	e.publish(ctx, models.ChangeCreated, changed)
+
+	return changed, nil
 }
 
-func (e *EventService) DeleteEvent(ctx context.Context, id string) error {
-	return e.event.DeleteEvent(ctx, id)
+func (e *EventService) DeleteEventOccurrence(ctx context.Context, id string, version int64, occurrence time.Time,
+	scope models.RecurrenceScope,
+) error {
+	switch scope {
+	case models.ScopeAll:
+		return e.DeleteEvent(ctx, id, version)
+	case models.ScopeThis, models.ScopeFollowing:
+		userID, err := callerID(ctx)
+		if err != nil {
+			return err
+		}
+		series, err := e.event.GetEventByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := e.event.DeleteEventOccurrence(ctx, userID, id, version, occurrence, scope); err != nil {
+			return err
+		}
+		e.publishSeries(ctx, userID, series, version)
+		return nil
+	}
+
+	return customerror.CustomError{
+		Field:   "scope",
+		Message: ErrInvalidRecurrenceScope.Error(),
+		Err:     customerror.ErrValidation,
+	}
 }
 
 func (e *EventService) DeleteOutdatedEvents(ctx context.Context) error {
 	return e.event.DeleteOutdatedEvents(ctx)
 }
 
-func (e *EventService) GetAllByDayEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
-	return e.event.GetAllByDayEvents(ctx, date)
+// WatchEvents subscribes to changes of the caller's events which take place in [from, to).
+// Zero from or to leaves the period open on that side.
+func (e *EventService) WatchEvents(ctx context.Context, from, to time.Time) (*changefeed.Subscription, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return nil, customerror.CustomError{
+			Field:   "from",
+			Message: ErrInvalidPeriod.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	return e.changes.Subscribe(changefeed.Filter{
+		UserID: userID,
+		From:   from,
+		To:     to,
+	}), nil
+}
+
+func (e *EventService) publish(ctx context.Context, changeType models.ChangeType, event models.Event) {
+	e.changes.Publish(ctx, models.EventChange{
+		Type:      changeType,
+		EventID:   event.ID,
+		UserID:    event.UserID,
+		Version:   event.Version,
+		ChangedAt: time.Now().UTC(),
+		Event:     &event,
+	})
+}
+
+func (e *EventService) publishDeleted(ctx context.Context, userID int, id string, version int64, attendees []int) {
+	e.changes.Publish(ctx, models.EventChange{
+		Type:      models.ChangeDeleted,
+		EventID:   id,
+		UserID:    userID,
+		Version:   version,
+		ChangedAt: time.Now().UTC(),
+		Attendees: attendees,
+	})
+}
+
+// publishUpdated publishes the update of the event which has been in the previous state.
+func (e *EventService) publishUpdated(ctx context.Context, previous, event models.Event) {
+	state := models.StateOf(previous)
+	e.changes.Publish(ctx, models.EventChange{
+		Type:      models.ChangeUpdated,
+		EventID:   event.ID,
+		UserID:    event.UserID,
+		Version:   event.Version,
+		ChangedAt: time.Now().UTC(),
+		Event:     &event,
+		Previous:  &state,
+	})
+}
+
+// publishSeries publishes the change of the series of the given version made by the change
+// of its occurrences. The series without occurrences left is deleted by the storage, so its attendees
+// are taken from the series before the change.
+func (e *EventService) publishSeries(ctx context.Context, userID int, previous models.Event, version int64) {
+	series, err := e.event.GetEventByID(ctx, previous.ID)
+	switch {
+	case errors.Is(err, customerror.ErrNotFound):
+		e.publishDeleted(ctx, userID, previous.ID, version, models.AttendeeIDs(previous.Attendees))
+	case err == nil:
+		e.publishUpdated(ctx, previous, series)
+	}
+}
+
+// GetEventsInRange returns the page of the caller's events, the events the caller is invited to
+// and has not declined, and occurrences of recurring events which start in [rng.From, rng.To) sorted by date.
+func (e *EventService) GetEventsInRange(ctx context.Context, rng models.EventRange) (models.EventPage, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return models.EventPage{}, err
+	}
+
+	if rng.From.After(rng.To) {
+		return models.EventPage{}, customerror.CustomError{
+			Field:   "from",
+			Message: ErrInvalidPeriod.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	switch rng.Order {
+	case "":
+		rng.Order = models.SortAsc
+	case models.SortAsc, models.SortDesc:
+	default:
+		return models.EventPage{}, customerror.CustomError{
+			Field:   "order",
+			Message: ErrInvalidSortOrder.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	if rng.Limit < 0 || rng.Limit > MaxPageSize {
+		return models.EventPage{}, customerror.CustomError{
+			Field:   "limit",
+			Message: ErrInvalidLimit.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+	if rng.Limit == 0 {
+		rng.Limit = DefaultPageSize
+	}
+
+	return e.event.GetEventsInRange(ctx, userID, rng)
+}
+
+// GetAllByDayEvents returns the caller's events of the calendar day containing date in loc.
+// Nil loc means the location of date.
+func (e *EventService) GetAllByDayEvents(ctx context.Context, date time.Time, loc *time.Location) ([]models.Event, error) {
+	from, to := period.Day(date, locationOrDefault(date, loc))
+	return e.getAllInPeriod(ctx, from, to)
+}
+
+// GetAllByWeekEvents returns the caller's events of the calendar week containing date in loc
+// which starts on weekStart. Nil loc means the location of date.
+func (e *EventService) GetAllByWeekEvents(ctx context.Context, date time.Time, loc *time.Location,
+	weekStart time.Weekday,
+) ([]models.Event, error) {
+	from, to := period.Week(date, locationOrDefault(date, loc), weekStart)
+	return e.getAllInPeriod(ctx, from, to)
+}
+
+// GetAllByMonthEvents returns the caller's events of the calendar month containing date in loc.
+// Nil loc means the location of date.
+func (e *EventService) GetAllByMonthEvents(ctx context.Context, date time.Time, loc *time.Location) ([]models.Event, error) {
+	from, to := period.Month(date, locationOrDefault(date, loc))
+	return e.getAllInPeriod(ctx, from, to)
+}
+
+// getAllInPeriod returns all events of the calendar period, the period is short enough to be read at once.
+func (e *EventService) getAllInPeriod(ctx context.Context, from, to time.Time) ([]models.Event, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := e.event.GetEventsInRange(ctx, userID, models.EventRange{From: from, To: to, Order: models.SortAsc})
+	if err != nil {
+		return nil, err
+	}
+	return page.Events, nil
+}
+
+func locationOrDefault(date time.Time, loc *time.Location) *time.Location {
+	if loc == nil {
+		return date.Location()
+	}
+	return loc
+}
+
+// ExportEvents renders the caller's events in [from, to] as the iCalendar (RFC 5545) object.
+func (e *EventService) ExportEvents(ctx context.Context, from, to time.Time) ([]byte, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if from.After(to) {
+		return nil, customerror.CustomError{
+			Field:   "from",
+			Message: ErrInvalidPeriod.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	events, err := e.event.GetUserEventsByPeriod(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, events); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
 }
 
-func (e *EventService) GetAllByWeekEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
-	return e.event.GetAllByWeekEvents(ctx, date)
+// ImportEvents creates the caller's events from the iCalendar (RFC 5545) object.
+// Every VEVENT is created separately, so the error of one of them does not stop the import.
+func (e *EventService) ImportEvents(ctx context.Context, data []byte,
+	opts models.EventOptions,
+) ([]models.ImportResult, error) {
+	if _, err := callerID(ctx); err != nil {
+		return nil, err
+	}
+
+	decoded, err := ical.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, customerror.CustomError{
+			Field:   "calendar",
+			Message: err.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	results := make([]models.ImportResult, 0, len(decoded))
+	for _, res := range decoded {
+		result := models.ImportResult{
+			UID: res.UID,
+			Err: res.Err,
+		}
+		if result.Err == nil {
+			result.ID, result.Err = e.CreateEvent(ctx, res.Event, opts)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
 }
 
-func (e *EventService) GetAllByMonthEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
-	return e.event.GetAllByMonthEvents(ctx, date)
+// validateUpdate checks the fields set by the update. Required fields of the event
+// cannot be set to zero values.
+func validateUpdate(update models.EventUpdate) (models.EventUpdate, error) {
+	if update.Title != nil {
+		title := strings.TrimSpace(*update.Title)
+		if title == "" {
+			return models.EventUpdate{}, customerror.CustomError{
+				Field:   "title",
+				Message: ErrEmptyTitle.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+		update.Title = &title
+	}
+	if update.Date != nil && update.Date.IsZero() {
+		return models.EventUpdate{}, customerror.CustomError{
+			Field:   "date",
+			Message: ErrEmptyDate.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+	if update.Duration != nil && *update.Duration <= 0 {
+		return models.EventUpdate{}, customerror.CustomError{
+			Field:   "duration",
+			Message: ErrInvalidDuration.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+	if update.Description != nil {
+		description := strings.TrimSpace(*update.Description)
+		update.Description = &description
+	}
+	if update.NotificationInterval != nil && *update.NotificationInterval < 0 {
+		return models.EventUpdate{}, customerror.CustomError{
+			Field:   "notification_interval",
+			Message: ErrInvalidNotificationInterval.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+	if update.Reminders == nil && update.NotificationInterval != nil {
+		var reminders []models.Reminder
+		if *update.NotificationInterval > 0 {
+			reminders = []models.Reminder{{Before: *update.NotificationInterval}}
+		}
+		update.Reminders = &reminders
+	}
+	if update.Reminders != nil {
+		reminders, err := normalizeReminders(*update.Reminders)
+		if err != nil {
+			return models.EventUpdate{}, err
+		}
+		update.Reminders = &reminders
+	}
+	if update.Recurrence != nil {
+		r := *update.Recurrence
+		if err := validateRecurrence(&r); err != nil {
+			return models.EventUpdate{}, err
+		}
+		update.Recurrence = &r
+	}
+	return update, nil
+}
+
+// normalizeReminders validates reminders and returns their pending copies sorted from the earliest one.
+// The channel defaults to log.
+func normalizeReminders(reminders []models.Reminder) ([]models.Reminder, error) {
+	if len(reminders) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]models.Reminder, 0, len(reminders))
+
+	for _, reminder := range reminders {
+		if reminder.Before < 0 {
+			return nil, customerror.CustomError{
+				Field:   "reminders",
+				Message: ErrInvalidReminderBefore.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+
+		switch reminder.Channel {
+		case "":
+			reminder.Channel = models.ChannelLog
+		case models.ChannelLog, models.ChannelEmail, models.ChannelWebhook:
+		default:
+			return nil, customerror.CustomError{
+				Field:   "reminders",
+				Message: ErrInvalidReminderChannel.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+
+		next := models.Reminder{
+			Before:  reminder.Before,
+			Channel: reminder.Channel,
+			Status:  models.StatusPending,
+		}
+		for _, other := range normalized {
+			if other.SameAs(next) {
+				return nil, customerror.CustomError{
+					Field:   "reminders",
+					Message: ErrDuplicateReminder.Error(),
+					Err:     customerror.ErrValidation,
+				}
+			}
+		}
+		normalized = append(normalized, next)
+	}
+
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].Before > normalized[j].Before
+	})
+
+	return normalized, nil
+}
+
+// callerID returns ID of the user the request is made on behalf of.
+func callerID(ctx context.Context) (int, error) {
+	userID, ok := identity.UserID(ctx)
+	if !ok {
+		return 0, customerror.CustomError{
+			Field:   "user_id",
+			Message: ErrMissingCaller.Error(),
+			Err:     customerror.ErrUnauthenticated,
+		}
+	}
+	return userID, nil
+}
+
+// mergeRecurrence folds the exceptions and the time zone of the update into the recurrence
+// the update leaves the stored event with, so they change only the members which were sent.
+func mergeRecurrence(update models.EventUpdate, stored models.Event) (models.EventUpdate, error) {
+	if update.RecurrenceExceptions == nil && update.RecurrenceLocation == nil {
+		return update, nil
+	}
+
+	r := update.Apply(stored).Recurrence
+	if r == nil {
+		field := "recurrence_exceptions"
+		if update.RecurrenceExceptions == nil {
+			field = "recurrence_time_zone"
+		}
+		return models.EventUpdate{}, customerror.CustomError{
+			Field:   field,
+			Message: ErrNotRecurringEvent.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+	if err := validateRecurrence(r); err != nil {
+		return models.EventUpdate{}, err
+	}
+
+	update.Recurrence = r
+	update.ClearRecurrence = false
+	update.RecurrenceExceptions = nil
+	update.RecurrenceLocation = nil
+	return update, nil
+}
+
+func validateRecurrence(r *models.Recurrence) error {
+	if r == nil {
+		return nil
+	}
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	if err := recurrence.Validate(*r); err != nil {
+		return customerror.CustomError{
+			Field:   "recurrence_rule",
+			Message: err.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+	return nil
 }
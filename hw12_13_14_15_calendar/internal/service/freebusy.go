@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/freebusy"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+var (
+	ErrNoFreeBusyUsers      = errors.New("user ids cannot be empty")
+	ErrTooManyFreeBusyUsers = fmt.Errorf("cannot look up more than %d users", MaxFreeBusyUsers)
+	ErrFreeBusyRangeTooLong = fmt.Errorf("range cannot be longer than %d days", MaxFreeBusyRange/(24*time.Hour))
+	ErrInvalidSlotDuration  = errors.New("slot duration must be positive")
+	ErrInvalidWorkingHours  = errors.New("working hours must be within the day and end after they start")
+	ErrInvalidSlotLimit     = fmt.Errorf("limit must be between 0 and %d", MaxSlots)
+)
+
+const (
+	// MaxFreeBusyUsers is the maximum number of users looked up at once.
+	MaxFreeBusyUsers = 50
+	// MaxFreeBusyRange limits the range in which occurrences of recurring events are expanded.
+	MaxFreeBusyRange = 90 * 24 * time.Hour
+	// MaxSlots is the maximum number of windows returned by FindSlots, it is used when the limit is not set.
+	MaxSlots = 100
+)
+
+// GetFreeBusy returns the merged busy intervals of every user in [from, to) in the order of userIDs.
+// Only the time is exposed, so the caller does not have to be invited to the events.
+func (e *EventService) GetFreeBusy(ctx context.Context, userIDs []int, from, to time.Time,
+) ([]models.FreeBusy, error) {
+	if _, err := callerID(ctx); err != nil {
+		return nil, err
+	}
+
+	users, err := validateFreeBusy(userIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.event.GetFreeBusy(ctx, users, from, to)
+}
+
+// FindSlots returns the windows of the working hours in which all users of the query are free
+// for at least the duration of the query, sorted from the earliest one.
+func (e *EventService) FindSlots(ctx context.Context, q models.SlotQuery) ([]models.Interval, error) {
+	if q.Duration <= 0 {
+		return nil, customerror.CustomError{
+			Field:   "duration",
+			Message: ErrInvalidSlotDuration.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	if q.WorkdayStart != 0 || q.WorkdayEnd != 0 {
+		if q.WorkdayStart < 0 || q.WorkdayEnd > 24*time.Hour || q.WorkdayStart >= q.WorkdayEnd {
+			return nil, customerror.CustomError{
+				Field:   "working_hours",
+				Message: ErrInvalidWorkingHours.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+	}
+
+	if q.Limit < 0 || q.Limit > MaxSlots {
+		return nil, customerror.CustomError{
+			Field:   "limit",
+			Message: ErrInvalidSlotLimit.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+	if q.Limit == 0 {
+		q.Limit = MaxSlots
+	}
+
+	calendars, err := e.GetFreeBusy(ctx, q.UserIDs, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return freebusy.FindSlots(calendars, q), nil
+}
+
+// validateFreeBusy returns the users without duplicates.
+func validateFreeBusy(userIDs []int, from, to time.Time) ([]int, error) {
+	if len(userIDs) == 0 {
+		return nil, customerror.CustomError{
+			Field:   "user_ids",
+			Message: ErrNoFreeBusyUsers.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	users := make([]int, 0, len(userIDs))
+	seen := make(map[int]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		if userID <= 0 {
+			return nil, customerror.CustomError{
+				Field:   "user_ids",
+				Message: ErrInvalidUserID.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		users = append(users, userID)
+	}
+	if len(users) > MaxFreeBusyUsers {
+		return nil, customerror.CustomError{
+			Field:   "user_ids",
+			Message: ErrTooManyFreeBusyUsers.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	if from.After(to) {
+		return nil, customerror.CustomError{
+			Field:   "from",
+			Message: ErrInvalidPeriod.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+	if to.Sub(from) > MaxFreeBusyRange {
+		return nil, customerror.CustomError{
+			Field:   "to",
+			Message: ErrFreeBusyRangeTooLong.Error(),
+			Err:     customerror.ErrValidation,
+		}
+	}
+
+	return users, nil
+}
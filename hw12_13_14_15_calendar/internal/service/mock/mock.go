@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	changefeed "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
 	models "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -37,32 +38,46 @@ func (m *MockEvent) EXPECT() *MockEventMockRecorder {
 }
 
 // CreateEvent mocks base method.
-func (m *MockEvent) CreateEvent(ctx context.Context, event models.Event) (string, error) {
+func (m *MockEvent) CreateEvent(ctx context.Context, event models.Event, opts models.EventOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateEvent", ctx, event)
+	ret := m.ctrl.Call(m, "CreateEvent", ctx, event, opts)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateEvent indicates an expected call of CreateEvent.
-func (mr *MockEventMockRecorder) CreateEvent(ctx, event interface{}) *gomock.Call {
+func (mr *MockEventMockRecorder) CreateEvent(ctx, event, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockEvent)(nil).CreateEvent), ctx, event)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockEvent)(nil).CreateEvent), ctx, event, opts)
 }
 
 // DeleteEvent mocks base method.
-func (m *MockEvent) DeleteEvent(ctx context.Context, id string) error {
+func (m *MockEvent) DeleteEvent(ctx context.Context, id string, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteEvent", ctx, id)
+	ret := m.ctrl.Call(m, "DeleteEvent", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteEvent indicates an expected call of DeleteEvent.
-func (mr *MockEventMockRecorder) DeleteEvent(ctx, id interface{}) *gomock.Call {
+func (mr *MockEventMockRecorder) DeleteEvent(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEvent", reflect.TypeOf((*MockEvent)(nil).DeleteEvent), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEvent", reflect.TypeOf((*MockEvent)(nil).DeleteEvent), ctx, id, version)
+}
+
+// DeleteEventOccurrence mocks base method.
+func (m *MockEvent) DeleteEventOccurrence(ctx context.Context, id string, version int64, occurrence time.Time, scope models.RecurrenceScope) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteEventOccurrence", ctx, id, version, occurrence, scope)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteEventOccurrence indicates an expected call of DeleteEventOccurrence.
+func (mr *MockEventMockRecorder) DeleteEventOccurrence(ctx, id, version, occurrence, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEventOccurrence", reflect.TypeOf((*MockEvent)(nil).DeleteEventOccurrence), ctx, id, version, occurrence, scope)
 }
 
 // DeleteOutdatedEvents mocks base method.
@@ -79,64 +94,214 @@ func (mr *MockEventMockRecorder) DeleteOutdatedEvents(ctx interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOutdatedEvents", reflect.TypeOf((*MockEvent)(nil).DeleteOutdatedEvents), ctx)
 }
 
+// ExportEvents mocks base method.
+func (m *MockEvent) ExportEvents(ctx context.Context, from, to time.Time) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportEvents", ctx, from, to)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportEvents indicates an expected call of ExportEvents.
+func (mr *MockEventMockRecorder) ExportEvents(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportEvents", reflect.TypeOf((*MockEvent)(nil).ExportEvents), ctx, from, to)
+}
+
+// FindSlots mocks base method.
+func (m *MockEvent) FindSlots(ctx context.Context, q models.SlotQuery) ([]models.Interval, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSlots", ctx, q)
+	ret0, _ := ret[0].([]models.Interval)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSlots indicates an expected call of FindSlots.
+func (mr *MockEventMockRecorder) FindSlots(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSlots", reflect.TypeOf((*MockEvent)(nil).FindSlots), ctx, q)
+}
+
 // GetAllByDayEvents mocks base method.
-func (m *MockEvent) GetAllByDayEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+func (m *MockEvent) GetAllByDayEvents(ctx context.Context, date time.Time, loc *time.Location) ([]models.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllByDayEvents", ctx, date)
+	ret := m.ctrl.Call(m, "GetAllByDayEvents", ctx, date, loc)
 	ret0, _ := ret[0].([]models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllByDayEvents indicates an expected call of GetAllByDayEvents.
-func (mr *MockEventMockRecorder) GetAllByDayEvents(ctx, date interface{}) *gomock.Call {
+func (mr *MockEventMockRecorder) GetAllByDayEvents(ctx, date, loc interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByDayEvents", reflect.TypeOf((*MockEvent)(nil).GetAllByDayEvents), ctx, date)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByDayEvents", reflect.TypeOf((*MockEvent)(nil).GetAllByDayEvents), ctx, date, loc)
 }
 
 // GetAllByMonthEvents mocks base method.
-func (m *MockEvent) GetAllByMonthEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+func (m *MockEvent) GetAllByMonthEvents(ctx context.Context, date time.Time, loc *time.Location) ([]models.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllByMonthEvents", ctx, date)
+	ret := m.ctrl.Call(m, "GetAllByMonthEvents", ctx, date, loc)
 	ret0, _ := ret[0].([]models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllByMonthEvents indicates an expected call of GetAllByMonthEvents.
-func (mr *MockEventMockRecorder) GetAllByMonthEvents(ctx, date interface{}) *gomock.Call {
+func (mr *MockEventMockRecorder) GetAllByMonthEvents(ctx, date, loc interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByMonthEvents", reflect.TypeOf((*MockEvent)(nil).GetAllByMonthEvents), ctx, date)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByMonthEvents", reflect.TypeOf((*MockEvent)(nil).GetAllByMonthEvents), ctx, date, loc)
 }
 
 // GetAllByWeekEvents mocks base method.
-func (m *MockEvent) GetAllByWeekEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+func (m *MockEvent) GetAllByWeekEvents(ctx context.Context, date time.Time, loc *time.Location, weekStart time.Weekday) ([]models.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllByWeekEvents", ctx, date)
+	ret := m.ctrl.Call(m, "GetAllByWeekEvents", ctx, date, loc, weekStart)
 	ret0, _ := ret[0].([]models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllByWeekEvents indicates an expected call of GetAllByWeekEvents.
-func (mr *MockEventMockRecorder) GetAllByWeekEvents(ctx, date interface{}) *gomock.Call {
+func (mr *MockEventMockRecorder) GetAllByWeekEvents(ctx, date, loc, weekStart interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByWeekEvents", reflect.TypeOf((*MockEvent)(nil).GetAllByWeekEvents), ctx, date)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByWeekEvents", reflect.TypeOf((*MockEvent)(nil).GetAllByWeekEvents), ctx, date, loc, weekStart)
+}
+
+// GetEventByID mocks base method.
+func (m *MockEvent) GetEventByID(ctx context.Context, id string) (models.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEventByID", ctx, id)
+	ret0, _ := ret[0].(models.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEventByID indicates an expected call of GetEventByID.
+func (mr *MockEventMockRecorder) GetEventByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEventByID", reflect.TypeOf((*MockEvent)(nil).GetEventByID), ctx, id)
+}
+
+// GetEventsInRange mocks base method.
+func (m *MockEvent) GetEventsInRange(ctx context.Context, rng models.EventRange) (models.EventPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEventsInRange", ctx, rng)
+	ret0, _ := ret[0].(models.EventPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEventsInRange indicates an expected call of GetEventsInRange.
+func (mr *MockEventMockRecorder) GetEventsInRange(ctx, rng interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEventsInRange", reflect.TypeOf((*MockEvent)(nil).GetEventsInRange), ctx, rng)
+}
+
+// GetFreeBusy mocks base method.
+func (m *MockEvent) GetFreeBusy(ctx context.Context, userIDs []int, from, to time.Time) ([]models.FreeBusy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFreeBusy", ctx, userIDs, from, to)
+	ret0, _ := ret[0].([]models.FreeBusy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFreeBusy indicates an expected call of GetFreeBusy.
+func (mr *MockEventMockRecorder) GetFreeBusy(ctx, userIDs, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFreeBusy", reflect.TypeOf((*MockEvent)(nil).GetFreeBusy), ctx, userIDs, from, to)
+}
+
+// ImportEvents mocks base method.
+func (m *MockEvent) ImportEvents(ctx context.Context, data []byte, opts models.EventOptions) ([]models.ImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportEvents", ctx, data, opts)
+	ret0, _ := ret[0].([]models.ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportEvents indicates an expected call of ImportEvents.
+func (mr *MockEventMockRecorder) ImportEvents(ctx, data, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportEvents", reflect.TypeOf((*MockEvent)(nil).ImportEvents), ctx, data, opts)
+}
+
+// InviteAttendees mocks base method.
+func (m *MockEvent) InviteAttendees(ctx context.Context, id string, version int64, attendees []int) (models.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InviteAttendees", ctx, id, version, attendees)
+	ret0, _ := ret[0].(models.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InviteAttendees indicates an expected call of InviteAttendees.
+func (mr *MockEventMockRecorder) InviteAttendees(ctx, id, version, attendees interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InviteAttendees", reflect.TypeOf((*MockEvent)(nil).InviteAttendees), ctx, id, version, attendees)
+}
+
+// RespondToInvitation mocks base method.
+func (m *MockEvent) RespondToInvitation(ctx context.Context, id string, status models.AttendeeStatus) (models.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RespondToInvitation", ctx, id, status)
+	ret0, _ := ret[0].(models.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RespondToInvitation indicates an expected call of RespondToInvitation.
+func (mr *MockEventMockRecorder) RespondToInvitation(ctx, id, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RespondToInvitation", reflect.TypeOf((*MockEvent)(nil).RespondToInvitation), ctx, id, status)
 }
 
 // UpdateEvent mocks base method.
-func (m *MockEvent) UpdateEvent(ctx context.Context, id string, event models.Event) (models.Event, error) {
+func (m *MockEvent) UpdateEvent(ctx context.Context, id string, version int64, update models.EventUpdate, opts models.EventOptions) (models.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateEvent", ctx, id, event)
+	ret := m.ctrl.Call(m, "UpdateEvent", ctx, id, version, update, opts)
 	ret0, _ := ret[0].(models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateEvent indicates an expected call of UpdateEvent.
-func (mr *MockEventMockRecorder) UpdateEvent(ctx, id, event interface{}) *gomock.Call {
+func (mr *MockEventMockRecorder) UpdateEvent(ctx, id, version, update, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEvent", reflect.TypeOf((*MockEvent)(nil).UpdateEvent), ctx, id, version, update, opts)
+}
+
+// UpdateEventOccurrence mocks base method.
+func (m *MockEvent) UpdateEventOccurrence(ctx context.Context, id string, version int64, occurrence time.Time, scope models.RecurrenceScope, update models.EventUpdate, opts models.EventOptions) (models.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEventOccurrence", ctx, id, version, occurrence, scope, update, opts)
+	ret0, _ := ret[0].(models.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateEventOccurrence indicates an expected call of UpdateEventOccurrence.
+func (mr *MockEventMockRecorder) UpdateEventOccurrence(ctx, id, version, occurrence, scope, update, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEvent", reflect.TypeOf((*MockEvent)(nil).UpdateEvent), ctx, id, event)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEventOccurrence", reflect.TypeOf((*MockEvent)(nil).UpdateEventOccurrence), ctx, id, version, occurrence, scope, update, opts)
+}
+
+// WatchEvents mocks base method.
+func (m *MockEvent) WatchEvents(ctx context.Context, from, to time.Time) (*changefeed.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchEvents", ctx, from, to)
+	ret0, _ := ret[0].(*changefeed.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchEvents indicates an expected call of WatchEvents.
+func (mr *MockEventMockRecorder) WatchEvents(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchEvents", reflect.TypeOf((*MockEvent)(nil).WatchEvents), ctx, from, to)
 }
 
 // MockNotification is a mock of Notification interface.
@@ -162,33 +327,38 @@ func (m *MockNotification) EXPECT() *MockNotificationMockRecorder {
 	return m.recorder
 }
 
-// GetNotificationInAdvance mocks base method.
-func (m *MockNotification) GetNotificationInAdvance(ctx context.Context) ([]models.Notification, error) {
+// ClaimNotifications mocks base method.
+func (m *MockNotification) ClaimNotifications(ctx context.Context, claim models.NotificationClaim) ([]models.Notification, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetNotificationInAdvance", ctx)
+	ret := m.ctrl.Call(m, "ClaimNotifications", ctx, claim)
 	ret0, _ := ret[0].([]models.Notification)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetNotificationInAdvance indicates an expected call of GetNotificationInAdvance.
-func (mr *MockNotificationMockRecorder) GetNotificationInAdvance(ctx interface{}) *gomock.Call {
+// ClaimNotifications indicates an expected call of ClaimNotifications.
+func (mr *MockNotificationMockRecorder) ClaimNotifications(ctx, claim interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationInAdvance", reflect.TypeOf((*MockNotification)(nil).GetNotificationInAdvance), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimNotifications", reflect.TypeOf((*MockNotification)(nil).ClaimNotifications), ctx, claim)
 }
 
-// UpdateScheduledNotification mocks base method.
-func (m *MockNotification) UpdateScheduledNotification(ctx context.Context, id string) error {
+// ScheduleNotification mocks base method.
+func (m *MockNotification) ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateScheduledNotification", ctx, id)
+	varargs := []interface{}{ctx, reminderID}
+	for _, a := range payloads {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ScheduleNotification", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateScheduledNotification indicates an expected call of UpdateScheduledNotification.
-func (mr *MockNotificationMockRecorder) UpdateScheduledNotification(ctx, id interface{}) *gomock.Call {
+// ScheduleNotification indicates an expected call of ScheduleNotification.
+func (mr *MockNotificationMockRecorder) ScheduleNotification(ctx, reminderID interface{}, payloads ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledNotification", reflect.TypeOf((*MockNotification)(nil).UpdateScheduledNotification), ctx, id)
+	varargs := append([]interface{}{ctx, reminderID}, payloads...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleNotification", reflect.TypeOf((*MockNotification)(nil).ScheduleNotification), varargs...)
 }
 
 // MockServices is a mock of Services interface.
@@ -214,33 +384,62 @@ func (m *MockServices) EXPECT() *MockServicesMockRecorder {
 	return m.recorder
 }
 
+// ClaimNotifications mocks base method.
+func (m *MockServices) ClaimNotifications(ctx context.Context, claim models.NotificationClaim) ([]models.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimNotifications", ctx, claim)
+	ret0, _ := ret[0].([]models.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimNotifications indicates an expected call of ClaimNotifications.
+func (mr *MockServicesMockRecorder) ClaimNotifications(ctx, claim interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimNotifications", reflect.TypeOf((*MockServices)(nil).ClaimNotifications), ctx, claim)
+}
+
 // CreateEvent mocks base method.
-func (m *MockServices) CreateEvent(ctx context.Context, event models.Event) (string, error) {
+func (m *MockServices) CreateEvent(ctx context.Context, event models.Event, opts models.EventOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateEvent", ctx, event)
+	ret := m.ctrl.Call(m, "CreateEvent", ctx, event, opts)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateEvent indicates an expected call of CreateEvent.
-func (mr *MockServicesMockRecorder) CreateEvent(ctx, event interface{}) *gomock.Call {
+func (mr *MockServicesMockRecorder) CreateEvent(ctx, event, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockServices)(nil).CreateEvent), ctx, event)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockServices)(nil).CreateEvent), ctx, event, opts)
 }
 
 // DeleteEvent mocks base method.
-func (m *MockServices) DeleteEvent(ctx context.Context, id string) error {
+func (m *MockServices) DeleteEvent(ctx context.Context, id string, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteEvent", ctx, id)
+	ret := m.ctrl.Call(m, "DeleteEvent", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteEvent indicates an expected call of DeleteEvent.
-func (mr *MockServicesMockRecorder) DeleteEvent(ctx, id interface{}) *gomock.Call {
+func (mr *MockServicesMockRecorder) DeleteEvent(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEvent", reflect.TypeOf((*MockServices)(nil).DeleteEvent), ctx, id, version)
+}
+
+// DeleteEventOccurrence mocks base method.
+func (m *MockServices) DeleteEventOccurrence(ctx context.Context, id string, version int64, occurrence time.Time, scope models.RecurrenceScope) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteEventOccurrence", ctx, id, version, occurrence, scope)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteEventOccurrence indicates an expected call of DeleteEventOccurrence.
+func (mr *MockServicesMockRecorder) DeleteEventOccurrence(ctx, id, version, occurrence, scope interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEvent", reflect.TypeOf((*MockServices)(nil).DeleteEvent), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEventOccurrence", reflect.TypeOf((*MockServices)(nil).DeleteEventOccurrence), ctx, id, version, occurrence, scope)
 }
 
 // DeleteOutdatedEvents mocks base method.
@@ -257,91 +456,231 @@ func (mr *MockServicesMockRecorder) DeleteOutdatedEvents(ctx interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOutdatedEvents", reflect.TypeOf((*MockServices)(nil).DeleteOutdatedEvents), ctx)
 }
 
+// ExportEvents mocks base method.
+func (m *MockServices) ExportEvents(ctx context.Context, from, to time.Time) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportEvents", ctx, from, to)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportEvents indicates an expected call of ExportEvents.
+func (mr *MockServicesMockRecorder) ExportEvents(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportEvents", reflect.TypeOf((*MockServices)(nil).ExportEvents), ctx, from, to)
+}
+
+// FindSlots mocks base method.
+func (m *MockServices) FindSlots(ctx context.Context, q models.SlotQuery) ([]models.Interval, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSlots", ctx, q)
+	ret0, _ := ret[0].([]models.Interval)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSlots indicates an expected call of FindSlots.
+func (mr *MockServicesMockRecorder) FindSlots(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSlots", reflect.TypeOf((*MockServices)(nil).FindSlots), ctx, q)
+}
+
 // GetAllByDayEvents mocks base method.
-func (m *MockServices) GetAllByDayEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+func (m *MockServices) GetAllByDayEvents(ctx context.Context, date time.Time, loc *time.Location) ([]models.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllByDayEvents", ctx, date)
+	ret := m.ctrl.Call(m, "GetAllByDayEvents", ctx, date, loc)
 	ret0, _ := ret[0].([]models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllByDayEvents indicates an expected call of GetAllByDayEvents.
-func (mr *MockServicesMockRecorder) GetAllByDayEvents(ctx, date interface{}) *gomock.Call {
+func (mr *MockServicesMockRecorder) GetAllByDayEvents(ctx, date, loc interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByDayEvents", reflect.TypeOf((*MockServices)(nil).GetAllByDayEvents), ctx, date)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByDayEvents", reflect.TypeOf((*MockServices)(nil).GetAllByDayEvents), ctx, date, loc)
 }
 
 // GetAllByMonthEvents mocks base method.
-func (m *MockServices) GetAllByMonthEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+func (m *MockServices) GetAllByMonthEvents(ctx context.Context, date time.Time, loc *time.Location) ([]models.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllByMonthEvents", ctx, date)
+	ret := m.ctrl.Call(m, "GetAllByMonthEvents", ctx, date, loc)
 	ret0, _ := ret[0].([]models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllByMonthEvents indicates an expected call of GetAllByMonthEvents.
-func (mr *MockServicesMockRecorder) GetAllByMonthEvents(ctx, date interface{}) *gomock.Call {
+func (mr *MockServicesMockRecorder) GetAllByMonthEvents(ctx, date, loc interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByMonthEvents", reflect.TypeOf((*MockServices)(nil).GetAllByMonthEvents), ctx, date)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByMonthEvents", reflect.TypeOf((*MockServices)(nil).GetAllByMonthEvents), ctx, date, loc)
 }
 
 // GetAllByWeekEvents mocks base method.
-func (m *MockServices) GetAllByWeekEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+func (m *MockServices) GetAllByWeekEvents(ctx context.Context, date time.Time, loc *time.Location, weekStart time.Weekday) ([]models.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllByWeekEvents", ctx, date)
+	ret := m.ctrl.Call(m, "GetAllByWeekEvents", ctx, date, loc, weekStart)
 	ret0, _ := ret[0].([]models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllByWeekEvents indicates an expected call of GetAllByWeekEvents.
-func (mr *MockServicesMockRecorder) GetAllByWeekEvents(ctx, date interface{}) *gomock.Call {
+func (mr *MockServicesMockRecorder) GetAllByWeekEvents(ctx, date, loc, weekStart interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByWeekEvents", reflect.TypeOf((*MockServices)(nil).GetAllByWeekEvents), ctx, date)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByWeekEvents", reflect.TypeOf((*MockServices)(nil).GetAllByWeekEvents), ctx, date, loc, weekStart)
 }
 
-// GetNotificationInAdvance mocks base method.
-func (m *MockServices) GetNotificationInAdvance(ctx context.Context) ([]models.Notification, error) {
+// GetEventByID mocks base method.
+func (m *MockServices) GetEventByID(ctx context.Context, id string) (models.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetNotificationInAdvance", ctx)
-	ret0, _ := ret[0].([]models.Notification)
+	ret := m.ctrl.Call(m, "GetEventByID", ctx, id)
+	ret0, _ := ret[0].(models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetNotificationInAdvance indicates an expected call of GetNotificationInAdvance.
-func (mr *MockServicesMockRecorder) GetNotificationInAdvance(ctx interface{}) *gomock.Call {
+// GetEventByID indicates an expected call of GetEventByID.
+func (mr *MockServicesMockRecorder) GetEventByID(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationInAdvance", reflect.TypeOf((*MockServices)(nil).GetNotificationInAdvance), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEventByID", reflect.TypeOf((*MockServices)(nil).GetEventByID), ctx, id)
 }
 
-// UpdateEvent mocks base method.
-func (m *MockServices) UpdateEvent(ctx context.Context, id string, event models.Event) (models.Event, error) {
+// GetEventsInRange mocks base method.
+func (m *MockServices) GetEventsInRange(ctx context.Context, rng models.EventRange) (models.EventPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEventsInRange", ctx, rng)
+	ret0, _ := ret[0].(models.EventPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEventsInRange indicates an expected call of GetEventsInRange.
+func (mr *MockServicesMockRecorder) GetEventsInRange(ctx, rng interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEventsInRange", reflect.TypeOf((*MockServices)(nil).GetEventsInRange), ctx, rng)
+}
+
+// GetFreeBusy mocks base method.
+func (m *MockServices) GetFreeBusy(ctx context.Context, userIDs []int, from, to time.Time) ([]models.FreeBusy, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateEvent", ctx, id, event)
+	ret := m.ctrl.Call(m, "GetFreeBusy", ctx, userIDs, from, to)
+	ret0, _ := ret[0].([]models.FreeBusy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFreeBusy indicates an expected call of GetFreeBusy.
+func (mr *MockServicesMockRecorder) GetFreeBusy(ctx, userIDs, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFreeBusy", reflect.TypeOf((*MockServices)(nil).GetFreeBusy), ctx, userIDs, from, to)
+}
+
+// ImportEvents mocks base method.
+func (m *MockServices) ImportEvents(ctx context.Context, data []byte, opts models.EventOptions) ([]models.ImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportEvents", ctx, data, opts)
+	ret0, _ := ret[0].([]models.ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportEvents indicates an expected call of ImportEvents.
+func (mr *MockServicesMockRecorder) ImportEvents(ctx, data, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportEvents", reflect.TypeOf((*MockServices)(nil).ImportEvents), ctx, data, opts)
+}
+
+// InviteAttendees mocks base method.
+func (m *MockServices) InviteAttendees(ctx context.Context, id string, version int64, attendees []int) (models.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InviteAttendees", ctx, id, version, attendees)
 	ret0, _ := ret[0].(models.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// UpdateEvent indicates an expected call of UpdateEvent.
-func (mr *MockServicesMockRecorder) UpdateEvent(ctx, id, event interface{}) *gomock.Call {
+// InviteAttendees indicates an expected call of InviteAttendees.
+func (mr *MockServicesMockRecorder) InviteAttendees(ctx, id, version, attendees interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InviteAttendees", reflect.TypeOf((*MockServices)(nil).InviteAttendees), ctx, id, version, attendees)
+}
+
+// RespondToInvitation mocks base method.
+func (m *MockServices) RespondToInvitation(ctx context.Context, id string, status models.AttendeeStatus) (models.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RespondToInvitation", ctx, id, status)
+	ret0, _ := ret[0].(models.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RespondToInvitation indicates an expected call of RespondToInvitation.
+func (mr *MockServicesMockRecorder) RespondToInvitation(ctx, id, status interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEvent", reflect.TypeOf((*MockServices)(nil).UpdateEvent), ctx, id, event)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RespondToInvitation", reflect.TypeOf((*MockServices)(nil).RespondToInvitation), ctx, id, status)
 }
 
-// UpdateScheduledNotification mocks base method.
-func (m *MockServices) UpdateScheduledNotification(ctx context.Context, id string) error {
+// ScheduleNotification mocks base method.
+func (m *MockServices) ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateScheduledNotification", ctx, id)
+	varargs := []interface{}{ctx, reminderID}
+	for _, a := range payloads {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ScheduleNotification", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateScheduledNotification indicates an expected call of UpdateScheduledNotification.
-func (mr *MockServicesMockRecorder) UpdateScheduledNotification(ctx, id interface{}) *gomock.Call {
+// ScheduleNotification indicates an expected call of ScheduleNotification.
+func (mr *MockServicesMockRecorder) ScheduleNotification(ctx, reminderID interface{}, payloads ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, reminderID}, payloads...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleNotification", reflect.TypeOf((*MockServices)(nil).ScheduleNotification), varargs...)
+}
+
+// UpdateEvent mocks base method.
+func (m *MockServices) UpdateEvent(ctx context.Context, id string, version int64, update models.EventUpdate, opts models.EventOptions) (models.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEvent", ctx, id, version, update, opts)
+	ret0, _ := ret[0].(models.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateEvent indicates an expected call of UpdateEvent.
+func (mr *MockServicesMockRecorder) UpdateEvent(ctx, id, version, update, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEvent", reflect.TypeOf((*MockServices)(nil).UpdateEvent), ctx, id, version, update, opts)
+}
+
+// UpdateEventOccurrence mocks base method.
+func (m *MockServices) UpdateEventOccurrence(ctx context.Context, id string, version int64, occurrence time.Time, scope models.RecurrenceScope, update models.EventUpdate, opts models.EventOptions) (models.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEventOccurrence", ctx, id, version, occurrence, scope, update, opts)
+	ret0, _ := ret[0].(models.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateEventOccurrence indicates an expected call of UpdateEventOccurrence.
+func (mr *MockServicesMockRecorder) UpdateEventOccurrence(ctx, id, version, occurrence, scope, update, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEventOccurrence", reflect.TypeOf((*MockServices)(nil).UpdateEventOccurrence), ctx, id, version, occurrence, scope, update, opts)
+}
+
+// WatchEvents mocks base method.
+func (m *MockServices) WatchEvents(ctx context.Context, from, to time.Time) (*changefeed.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchEvents", ctx, from, to)
+	ret0, _ := ret[0].(*changefeed.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchEvents indicates an expected call of WatchEvents.
+func (mr *MockServicesMockRecorder) WatchEvents(ctx, from, to interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledNotification", reflect.TypeOf((*MockServices)(nil).UpdateScheduledNotification), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchEvents", reflect.TypeOf((*MockServices)(nil).WatchEvents), ctx, from, to)
 }
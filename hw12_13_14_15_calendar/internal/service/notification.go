@@ -15,10 +15,12 @@ func NewNotificationService(notification storage.NotificationStorage) *Notificat
 	return &NotificationService{notification: notification}
 }
 
-func (n *NotificationService) GetNotificationInAdvance(ctx context.Context) ([]models.Notification, error) {
-	return n.notification.GetNotificationsInAdvance(ctx)
+func (n *NotificationService) ClaimNotifications(ctx context.Context,
+	claim models.NotificationClaim,
+) ([]models.Notification, error) {
+	return n.notification.ClaimNotifications(ctx, claim)
 }
 
-func (n *NotificationService) UpdateScheduledNotification(ctx context.Context, id string) error {
-	return n.notification.UpdateScheduledNotification(ctx, id)
+func (n *NotificationService) ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error {
+	return n.notification.ScheduleNotification(ctx, reminderID, payloads...)
 }
@@ -6,23 +6,38 @@ import (
 	"context"
 	"time"
 
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/changefeed"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage"
 )
 
 type Event interface {
-	CreateEvent(ctx context.Context, event models.Event) (string, error)
-	UpdateEvent(ctx context.Context, id string, event models.Event) (models.Event, error)
-	DeleteEvent(ctx context.Context, id string) error
+	CreateEvent(ctx context.Context, event models.Event, opts models.EventOptions) (string, error)
+	UpdateEvent(ctx context.Context, id string, version int64, update models.EventUpdate,
+		opts models.EventOptions) (models.Event, error)
+	DeleteEvent(ctx context.Context, id string, version int64) error
+	UpdateEventOccurrence(ctx context.Context, id string, version int64, occurrence time.Time, scope models.RecurrenceScope,
+		update models.EventUpdate, opts models.EventOptions) (models.Event, error)
+	DeleteEventOccurrence(ctx context.Context, id string, version int64, occurrence time.Time,
+		scope models.RecurrenceScope) error
 	DeleteOutdatedEvents(ctx context.Context) error
-	GetAllByDayEvents(ctx context.Context, date time.Time) ([]models.Event, error)
-	GetAllByWeekEvents(ctx context.Context, date time.Time) ([]models.Event, error)
-	GetAllByMonthEvents(ctx context.Context, date time.Time) ([]models.Event, error)
+	GetEventByID(ctx context.Context, id string) (models.Event, error)
+	GetEventsInRange(ctx context.Context, rng models.EventRange) (models.EventPage, error)
+	GetAllByDayEvents(ctx context.Context, date time.Time, loc *time.Location) ([]models.Event, error)
+	GetAllByWeekEvents(ctx context.Context, date time.Time, loc *time.Location, weekStart time.Weekday) ([]models.Event, error)
+	GetAllByMonthEvents(ctx context.Context, date time.Time, loc *time.Location) ([]models.Event, error)
+	ExportEvents(ctx context.Context, from, to time.Time) ([]byte, error)
+	ImportEvents(ctx context.Context, data []byte, opts models.EventOptions) ([]models.ImportResult, error)
+	WatchEvents(ctx context.Context, from, to time.Time) (*changefeed.Subscription, error)
+	InviteAttendees(ctx context.Context, id string, version int64, attendees []int) (models.Event, error)
+	RespondToInvitation(ctx context.Context, id string, status models.AttendeeStatus) (models.Event, error)
+	GetFreeBusy(ctx context.Context, userIDs []int, from, to time.Time) ([]models.FreeBusy, error)
+	FindSlots(ctx context.Context, q models.SlotQuery) ([]models.Interval, error)
 }
 
 type Notification interface {
-	UpdateScheduledNotification(ctx context.Context, id string) error
-	GetNotificationInAdvance(ctx context.Context) ([]models.Notification, error)
+	ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error
+	ClaimNotifications(ctx context.Context, claim models.NotificationClaim) ([]models.Notification, error)
 }
 
 type Services interface {
@@ -35,9 +50,9 @@ type Service struct {
 	Notification
 }
 
-func NewService(repo storage.Storage) *Service {
+func NewService(repo storage.Storage, changes changefeed.Feed) *Service {
 	return &Service{
-		NewEventService(repo),
+		NewEventService(repo, changes),
 		NewNotificationService(repo),
 	}
 }
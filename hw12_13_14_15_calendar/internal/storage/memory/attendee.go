@@ -0,0 +1,117 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+func (s *Storage) InviteAttendees(ctx context.Context, userID int, id string, version int64, attendees []int,
+) (models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return models.Event{}, customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	event, err := s.ownedEvent(userID, id, version)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	invited := append([]models.Attendee(nil), event.Attendees...)
+	for _, attendeeID := range attendees {
+		if _, ok := models.FindAttendee(invited, attendeeID); ok {
+			continue
+		}
+		invited = append(invited, models.Attendee{
+			UserID: attendeeID,
+			Status: models.AttendeeNeedsAction,
+		})
+	}
+	sortAttendees(invited)
+	event.Attendees = invited
+
+	s.events[id] = changed(event)
+
+	return s.events[id], nil
+}
+
+func (s *Storage) RespondToInvitation(ctx context.Context, userID int, id string, status models.AttendeeStatus,
+) (models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return models.Event{}, customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	event, ok := s.events[id]
+	if !ok {
+		return models.Event{}, customerror.CustomError{
+			Field:   "id",
+			Message: "no event with id " + id,
+			Err:     customerror.ErrNotFound,
+		}
+	}
+
+	// the attendees of the stored event are shared with its copies, so they are replaced rather than changed
+	attendees := append([]models.Attendee(nil), event.Attendees...)
+	responded := false
+	for i := range attendees {
+		if attendees[i].UserID == userID {
+			attendees[i].Status = status
+			attendees[i].RespondedAt = time.Now().UTC()
+			responded = true
+		}
+	}
+	if !responded {
+		return models.Event{}, notInvited(id)
+	}
+	event.Attendees = attendees
+
+	s.events[id] = changed(event)
+
+	return s.events[id], nil
+}
+
+// acceptedAttendees returns ids of the attendees of the event who have accepted the invitation.
+func acceptedAttendees(event models.Event) []int {
+	var accepted []int
+	for _, attendee := range event.Attendees {
+		if attendee.Status == models.AttendeeAccepted {
+			accepted = append(accepted, attendee.UserID)
+		}
+	}
+	return accepted
+}
+
+func sortAttendees(attendees []models.Attendee) {
+	sort.Slice(attendees, func(i, j int) bool {
+		return attendees[i].UserID < attendees[j].UserID
+	})
+}
+
+func notInvited(id string) error {
+	return customerror.CustomError{
+		Field:   "id",
+		Message: "user is not invited to event with id " + id,
+		Err:     customerror.ErrForbidden,
+	}
+}
@@ -2,15 +2,18 @@ package memorystorage
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/freebusy"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/pagination"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
 )
 
-const day = 24 * time.Hour
-
-func (s *Storage) CreateEvent(ctx context.Context, event models.Event) (string, error) {
+func (s *Storage) CreateEvent(ctx context.Context, event models.Event, opts models.EventOptions) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -19,16 +22,25 @@ func (s *Storage) CreateEvent(ctx context.Context, event models.Event) (string,
 		return "", customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
 
-	s.events[event.ID] = event
+	if !opts.AllowOverlap {
+		if err := s.checkOverlap(event); err != nil {
+			return "", err
+		}
+	}
+
+	s.events[event.ID] = created(s.withReminderIDs(event))
 
 	return event.ID, nil
 }
 
-func (s *Storage) UpdateEvent(ctx context.Context, id string, event models.Event) (models.Event, error) {
+func (s *Storage) UpdateEvent(ctx context.Context, userID int, id string, version int64, update models.EventUpdate,
+	opts models.EventOptions,
+) (models.Event, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -37,23 +49,45 @@ func (s *Storage) UpdateEvent(ctx context.Context, id string, event models.Event
 		return models.Event{}, customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
 
-	if _, ok := s.events[id]; !ok {
-		return models.Event{}, customerror.CustomError{
-			Field:   "id",
-			Message: "no event with id " + id,
+	current, err := s.ownedEvent(userID, id, version)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	updated := update.Apply(current)
+	if !opts.AllowOverlap {
+		if err := s.checkOverlap(updated, id); err != nil {
+			return models.Event{}, err
+		}
+	}
+
+	switch {
+	// reminders of the moved event are sent again
+	case update.Date != nil:
+		updated.Reminders = pendingReminders(updated.Reminders, updated.Date)
+		for _, reminder := range updated.Reminders {
+			delete(s.leases, reminder.ID)
 		}
+	// the single event has the only occurrence
+	case update.ClearRecurrence:
+		updated.Reminders = remindersAt(updated.Reminders, updated.Date, true)
+	// the changed series may have occurrences again, claims move reminders to them
+	case update.Recurrence != nil:
+		updated.Reminders = remindersAt(updated.Reminders, updated.Date, false)
 	}
 
-	s.events[id] = event
+	s.events[id] = changed(s.withReminderIDs(updated))
+	s.deleteStaleOutbox()
 
 	return s.events[id], nil
 }
 
-func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
+func (s *Storage) DeleteEvent(ctx context.Context, userID int, id string, version int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -62,20 +96,267 @@ func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
 		return customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
 
-	if _, ok := s.events[id]; !ok {
+	if _, err := s.ownedEvent(userID, id, version); err != nil {
+		return err
+	}
+
+	delete(s.events, id)
+	s.deleteDetachedEvents(id, time.Time{})
+	s.deleteStaleOutbox()
+
+	return nil
+}
+
+func (s *Storage) UpdateEventOccurrence(ctx context.Context, userID int, id string, version int64,
+	occurrence time.Time, scope models.RecurrenceScope, newID string, update models.EventUpdate, opts models.EventOptions,
+) (models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return models.Event{}, customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	series, err := s.ownedEvent(userID, id, version)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	switch scope {
+	case models.ScopeThis:
+		updatedSeries, detached, err := recurrence.Detach(series, occurrence, newID, update)
+		if err != nil {
+			return models.Event{}, customerror.CustomError{
+				Field:   "occurrence",
+				Message: err.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+		// the occurrence is checked against the rest of the series
+		if !opts.AllowOverlap {
+			if err := s.checkReplacing(detached, series, updatedSeries, true); err != nil {
+				return models.Event{}, err
+			}
+		}
+
+		s.events[id] = changed(updatedSeries)
+		s.events[detached.ID] = created(s.withReminderIDs(detached))
+
+		return s.events[detached.ID], nil
+	case models.ScopeFollowing:
+		head, tail, ok, err := recurrence.Split(series, occurrence, newID, update)
+		if err != nil {
+			return models.Event{}, customerror.CustomError{
+				Field:   "occurrence",
+				Message: err.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+		// the occurrences detached from the following ones are replaced by the tail
+		if !opts.AllowOverlap {
+			if err := s.checkReplacing(tail, series, head, ok, s.detachedEvents(id, occurrence)...); err != nil {
+				return models.Event{}, err
+			}
+		}
+
+		if ok {
+			s.events[id] = changed(head)
+		} else {
+			delete(s.events, id)
+		}
+		s.deleteDetachedEvents(id, occurrence)
+		s.deleteStaleOutbox()
+		s.events[tail.ID] = created(s.withReminderIDs(tail))
+
+		return s.events[tail.ID], nil
+	}
+
+	return models.Event{}, customerror.CustomError{
+		Field:   "scope",
+		Message: "unsupported recurrence scope " + string(scope),
+		Err:     customerror.ErrValidation,
+	}
+}
+
+func (s *Storage) DeleteEventOccurrence(ctx context.Context, userID int, id string, version int64,
+	occurrence time.Time, scope models.RecurrenceScope,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
 		return customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	series, err := s.ownedEvent(userID, id, version)
+	if err != nil {
+		return err
+	}
+
+	switch scope {
+	case models.ScopeThis:
+		updatedSeries, err := recurrence.Exclude(series, occurrence)
+		if err != nil {
+			return customerror.CustomError{
+				Field:   "occurrence",
+				Message: err.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+
+		s.events[id] = changed(updatedSeries)
+
+		return nil
+	case models.ScopeFollowing:
+		head, ok, err := recurrence.Truncate(series, occurrence)
+		if err != nil {
+			return customerror.CustomError{
+				Field:   "occurrence",
+				Message: err.Error(),
+				Err:     customerror.ErrValidation,
+			}
+		}
+
+		if ok {
+			s.events[id] = changed(head)
+		} else {
+			delete(s.events, id)
+		}
+		s.deleteDetachedEvents(id, occurrence)
+		s.deleteStaleOutbox()
+
+		return nil
+	}
+
+	return customerror.CustomError{
+		Field:   "scope",
+		Message: "unsupported recurrence scope " + string(scope),
+		Err:     customerror.ErrValidation,
+	}
+}
+
+// ownedEvent returns the event if it exists, belongs to the user and has the expected version.
+func (s *Storage) ownedEvent(userID int, id string, version int64) (models.Event, error) {
+	event, ok := s.events[id]
+	if !ok {
+		return models.Event{}, customerror.CustomError{
 			Field:   "id",
 			Message: "no event with id " + id,
+			Err:     customerror.ErrNotFound,
+		}
+	}
+	if event.UserID != userID {
+		return models.Event{}, customerror.CustomError{
+			Field:   "id",
+			Message: "event with id " + id + " belongs to another user",
+			Err:     customerror.ErrForbidden,
 		}
 	}
+	if event.Version != version {
+		return models.Event{}, customerror.CustomError{
+			Field:   "version",
+			Message: fmt.Sprintf("event with id %s has version %d, not %d", id, event.Version, version),
+			Err:     customerror.ErrVersionMismatch,
+		}
+	}
+	return event, nil
+}
 
-	delete(s.events, id)
+// created sets the first version of the new event.
+func created(event models.Event) models.Event {
+	event.Version = 1
+	event.UpdatedAt = time.Now().UTC()
+	return event
+}
 
-	return nil
+// changed increments the version of the event.
+func changed(event models.Event) models.Event {
+	event.Version++
+	event.UpdatedAt = time.Now().UTC()
+	return event
+}
+
+// withReminderIDs assigns ids to new reminders of the event, they remind of its first occurrence.
+// Reminders are copied, so the stored event does not share them with the caller.
+func (s *Storage) withReminderIDs(event models.Event) models.Event {
+	if len(event.Reminders) == 0 {
+		event.Reminders = nil
+		return event
+	}
+
+	reminders := make([]models.Reminder, 0, len(event.Reminders))
+	for _, reminder := range event.Reminders {
+		if reminder.ID == 0 {
+			s.reminderSeq++
+			reminder.ID = s.reminderSeq
+			reminder.Occurrence = event.Date
+		}
+		reminders = append(reminders, reminder)
+	}
+	event.Reminders = reminders
+
+	return event
+}
+
+// pendingReminders returns copies of reminders which are not delivered yet for the occurrence at the date.
+func pendingReminders(reminders []models.Reminder, date time.Time) []models.Reminder {
+	pending := make([]models.Reminder, 0, len(reminders))
+	for _, reminder := range reminders {
+		reminder.Status = models.StatusPending
+		reminder.QueuedAt = time.Time{}
+		reminder.SentAt = time.Time{}
+		reminder.Occurrence = date
+		pending = append(pending, reminder)
+	}
+	return pending
+}
+
+// remindersAt returns copies of reminders which remind of the occurrence at the date. Unless all is set,
+// only reminders without occurrences left are changed.
+func remindersAt(reminders []models.Reminder, date time.Time, all bool) []models.Reminder {
+	result := make([]models.Reminder, 0, len(reminders))
+	for _, reminder := range reminders {
+		if all || reminder.Occurrence.IsZero() {
+			reminder.Occurrence = date
+		}
+		result = append(result, reminder)
+	}
+	return result
+}
+
+// deleteDetachedEvents deletes events detached from the series with original date not before the given one.
+func (s *Storage) deleteDetachedEvents(seriesID string, from time.Time) {
+	for _, id := range s.detachedEvents(seriesID, from) {
+		delete(s.events, id)
+	}
+}
+
+// detachedEvents returns ids of the occurrences of the series detached at or after from.
+func (s *Storage) detachedEvents(seriesID string, from time.Time) []string {
+	var ids []string
+	for id, event := range s.events {
+		if event.RecurrenceID == seriesID && !event.OriginalDate.Before(from) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
 
 func (s *Storage) DeleteOutdatedEvents(ctx context.Context) error {
@@ -87,6 +368,7 @@ func (s *Storage) DeleteOutdatedEvents(ctx context.Context) error {
 		return customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
@@ -94,39 +376,59 @@ func (s *Storage) DeleteOutdatedEvents(ctx context.Context) error {
 	now := time.Now()
 
 	for id, event := range s.events {
-		if event.Date.Before(now.Add(-time.Hour * 8760)) {
+		end := event.Date
+		if event.Recurrence != nil {
+			var ok bool
+			if end, ok = recurrence.End(event.Date, *event.Recurrence); !ok {
+				continue
+			}
+		}
+		if end.Before(now.Add(-time.Hour * 8760)) {
 			delete(s.events, id)
 		}
 	}
+	s.deleteStaleOutbox()
 
 	return nil
 }
 
-func (s *Storage) GetAllByDayEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+// GetEventsInRange returns the page of user's events, the events the user is invited to and has not declined,
+// and occurrences of recurring events which start in the range.
+func (s *Storage) GetEventsInRange(ctx context.Context, userID int, rng models.EventRange) (models.EventPage, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	select {
 	case <-ctx.Done():
-		return nil, customerror.CustomError{
+		return models.EventPage{}, customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
 
-	var events []models.Event
+	_, to := pagination.Window(rng)
+
+	var single, series []models.Event
 
 	for _, event := range s.events {
-		if event.Date == date {
-			events = append(events, event)
+		if (event.UserID != userID && !event.IsInvited(userID)) || !pagination.Matches(event, rng.Query) {
+			continue
+		}
+		if event.Recurrence == nil {
+			single = append(single, event)
+		} else if !event.Date.After(to) {
+			series = append(series, event)
 		}
 	}
 
-	return events, nil
+	return pagination.Page(single, series, rng), nil
 }
 
-func (s *Storage) GetAllByWeekEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+// GetUserEventsByPeriod returns user's events which take place in [from, to].
+// Recurring events are returned once without expansion.
+func (s *Storage) GetUserEventsByPeriod(ctx context.Context, userID int, from, to time.Time) ([]models.Event, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -135,6 +437,7 @@ func (s *Storage) GetAllByWeekEvents(ctx context.Context, date time.Time) ([]mod
 		return nil, customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
@@ -142,15 +445,55 @@ func (s *Storage) GetAllByWeekEvents(ctx context.Context, date time.Time) ([]mod
 	var events []models.Event
 
 	for _, event := range s.events {
-		if inTimeSpan(date, date.Add(6*day), event.Date) {
+		if event.UserID != userID {
+			continue
+		}
+		if event.Recurrence != nil {
+			if len(recurrence.Occurrences(event.Date, *event.Recurrence, from, to)) > 0 {
+				events = append(events, event)
+			}
+			continue
+		}
+		if inTimeSpan(from, to, event.Date) {
 			events = append(events, event)
 		}
 	}
 
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
+
 	return events, nil
 }
 
-func (s *Storage) GetAllByMonthEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+func (s *Storage) GetEventByID(ctx context.Context, id string) (models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return models.Event{}, customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	event, ok := s.events[id]
+	if !ok {
+		return models.Event{}, customerror.CustomError{
+			Field:   "id",
+			Message: "no event with id " + id,
+			Err:     customerror.ErrNotFound,
+		}
+	}
+
+	return event, nil
+}
+
+// GetIntersectingEvents returns user's events and occurrences of recurring events which take place in [from, to).
+func (s *Storage) GetIntersectingEvents(ctx context.Context, userID int, from, to time.Time) ([]models.Event, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -159,19 +502,66 @@ func (s *Storage) GetAllByMonthEvents(ctx context.Context, date time.Time) ([]mo
 		return nil, customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
 
+	return s.intersectingEvents(userID, from, to), nil
+}
+
+func (s *Storage) intersectingEvents(userID int, from, to time.Time) []models.Event {
 	var events []models.Event
 
 	for _, event := range s.events {
-		if inTimeSpan(date, date.Add(29*day), event.Date) {
-			events = append(events, event)
+		if !isBusy(event, userID) || !event.Date.Before(to) {
+			continue
+		}
+		for _, occurrence := range recurrence.Expand(event, from.Add(-event.Duration), to) {
+			if occurrence.Date.Before(to) && occurrence.Date.Add(occurrence.Duration).After(from) {
+				events = append(events, occurrence)
+			}
 		}
 	}
 
-	return events, nil
+	return events
+}
+
+// isBusy reports whether the user owns the event or has accepted the invitation to it.
+func isBusy(event models.Event, userID int) bool {
+	if event.UserID == userID {
+		return true
+	}
+	attendee, ok := models.FindAttendee(event.Attendees, userID)
+	return ok && attendee.Status == models.AttendeeAccepted
+}
+
+// checkOverlap returns the error if the owner of the event is busy at its time.
+// The events with ignoreIDs and the occurrences detached from them are skipped.
+func (s *Storage) checkOverlap(event models.Event, ignoreIDs ...string) error {
+	intervals := freebusy.Occupied(event)
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	busy := s.intersectingEvents(event.UserID, intervals[0].Start, intervals[len(intervals)-1].End)
+
+	return freebusy.CheckOverlap(intervals, busy, ignoreIDs...)
+}
+
+// checkReplacing checks the overlap of the event which replaces occurrences of the series with the rest
+// of the series given by head, nothing is left of the series unless ok is set. The series is restored after the check.
+func (s *Storage) checkReplacing(event, series, head models.Event, ok bool, ignoreIDs ...string) error {
+	if ok {
+		s.events[series.ID] = head
+	} else {
+		delete(s.events, series.ID)
+	}
+	defer func() {
+		s.events[series.ID] = series
+	}()
+
+	return s.checkOverlap(event, ignoreIDs...)
 }
 
 func inTimeSpan(start, end, check time.Time) bool {
@@ -7,10 +7,17 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/storagetest"
 	"github.com/stretchr/testify/require"
 )
 
+const testUserID = 1
+
+var allowOverlap = models.EventOptions{AllowOverlap: true}
+
 func TestStorageCreateEvent(t *testing.T) {
 	st := NewStorageMemory()
 	ctx := context.Background()
@@ -18,15 +25,16 @@ func TestStorageCreateEvent(t *testing.T) {
 	events := generateEvents("test create")
 
 	for j, event := range events {
-		id, err := st.CreateEvent(ctx, event)
+		id, err := st.CreateEvent(ctx, event, allowOverlap)
 		require.NoError(t, err)
 		events[j].ID = id
 	}
 
 	for _, event := range events {
-		if eventCreated, ok := st.events[event.ID]; !ok || eventCreated != event {
-			t.Errorf("event not match")
-		}
+		eventCreated, ok := st.events[event.ID]
+		require.True(t, ok)
+		require.Equal(t, int64(1), eventCreated.Version)
+		require.Equal(t, event, withoutVersion(eventCreated))
 	}
 }
 
@@ -40,21 +48,22 @@ func TestStorageUpdateEvent(t *testing.T) {
 	eventsResult := make([]models.Event, len(eventAfter))
 
 	for j, event := range eventBefore {
-		id, err := st.CreateEvent(ctx, event)
+		id, err := st.CreateEvent(ctx, event, allowOverlap)
 		require.NoError(t, err)
 		eventAfter[j].ID = id
 	}
 
 	for j, event := range eventAfter {
-		updatedEvent, err := st.UpdateEvent(ctx, event.ID, event)
+		updatedEvent, err := st.UpdateEvent(ctx, testUserID, event.ID, 1, eventUpdate(event), allowOverlap)
 		require.NoError(t, err)
 		eventsResult[j] = updatedEvent
 	}
 
 	for j, updatedEvent := range eventsResult {
-		if event, ok := st.events[updatedEvent.ID]; !ok || eventAfter[j] != event {
-			t.Errorf("event not match")
-		}
+		event, ok := st.events[updatedEvent.ID]
+		require.True(t, ok)
+		require.Equal(t, int64(2), event.Version)
+		require.Equal(t, eventAfter[j], withoutVersion(event))
 	}
 }
 
@@ -66,12 +75,12 @@ func TestStorageUpdateEventError(t *testing.T) {
 	eventAfter := generateEvents("test update after")
 
 	for _, event := range eventBefore {
-		_, err := st.CreateEvent(ctx, event)
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
 		require.NoError(t, err)
 	}
 
 	for _, event := range eventAfter {
-		updatedEvent, err := st.UpdateEvent(ctx, event.ID, event)
+		updatedEvent, err := st.UpdateEvent(ctx, testUserID, event.ID, 1, eventUpdate(event), allowOverlap)
 		require.Error(t, err)
 		require.EqualError(t, err, fmt.Errorf("no event with id %s", event.ID).Error())
 		require.Equal(t, models.Event{}, updatedEvent)
@@ -87,13 +96,13 @@ func TestStorageDeleteEvent(t *testing.T) {
 	IDs := make([]string, len(events))
 
 	for j, event := range events {
-		id, err := st.CreateEvent(ctx, event)
+		id, err := st.CreateEvent(ctx, event, allowOverlap)
 		require.NoError(t, err)
 		IDs[j] = id
 	}
 
 	for _, id := range IDs {
-		err := st.DeleteEvent(ctx, id)
+		err := st.DeleteEvent(ctx, testUserID, id, 1)
 		require.NoError(t, err)
 	}
 
@@ -109,13 +118,13 @@ func TestStorageDeleteEventError(t *testing.T) {
 	IDs := make([]string, len(events))
 
 	for j, event := range events {
-		id, err := st.CreateEvent(ctx, event)
+		id, err := st.CreateEvent(ctx, event, allowOverlap)
 		require.NoError(t, err)
 		IDs[j] = id + "suffix" // create nonexistent id
 	}
 
 	for _, id := range IDs {
-		err := st.DeleteEvent(ctx, id)
+		err := st.DeleteEvent(ctx, testUserID, id, 1)
 		require.Error(t, err)
 		require.EqualError(t, err, fmt.Errorf("no event with id %s", id).Error())
 	}
@@ -123,27 +132,68 @@ func TestStorageDeleteEventError(t *testing.T) {
 	require.Len(t, st.events, 100, "must be full")
 }
 
+func TestStorageOtherUserEvent(t *testing.T) {
+	st := NewStorageMemory()
+	ctx := context.Background()
+
+	event := generateEvents("test other user")[0]
+	_, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+
+	otherUserID := testUserID + 1
+
+	_, err = st.UpdateEvent(ctx, otherUserID, event.ID, 1, eventUpdate(event), allowOverlap)
+	require.ErrorIs(t, err, customerror.ErrForbidden)
+
+	err = st.DeleteEvent(ctx, otherUserID, event.ID, 1)
+	require.ErrorIs(t, err, customerror.ErrForbidden)
+
+	err = st.DeleteEvent(ctx, otherUserID, uuid.New().String(), 1)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	events, err := getEventsInPeriod(ctx, st, otherUserID, event.Date, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+
+	events, err = getEventsInPeriod(ctx, st, testUserID, event.Date, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, event, withoutVersion(events[0]))
+}
+
 func TestStorageDeleteOutdatedEvents(t *testing.T) {
 	st := NewStorageMemory()
 	ctx := context.Background()
 
+	now := time.Now()
+
 	st.events["id1"] = models.Event{
-		Date: time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local),
+		Date: now.AddDate(-2, 0, 0),
 	}
 	st.events["id2"] = models.Event{
-		Date: time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local),
+		Date: now.AddDate(0, -1, 0),
 	}
 	st.events["id3"] = models.Event{
-		Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local),
+		Date: now.AddDate(-3, 0, 0),
+	}
+	st.events["id4"] = models.Event{
+		Date:       now.AddDate(-3, 0, 0),
+		Recurrence: &models.Recurrence{Frequency: models.FrequencyYearly, Interval: 1},
+	}
+	st.events["id5"] = models.Event{
+		Date:       now.AddDate(-3, 0, 0),
+		Recurrence: &models.Recurrence{Frequency: models.FrequencyYearly, Interval: 1, Count: 2},
 	}
 
 	err := st.DeleteOutdatedEvents(ctx)
 	require.NoError(t, err)
 
-	require.Len(t, st.events, 1)
+	require.Len(t, st.events, 2)
+	require.Contains(t, st.events, "id2")
+	require.Contains(t, st.events, "id4")
 }
 
-func TestStorageGetAllByDayEvents(t *testing.T) {
+func TestStorageGetEventsInRangeByDay(t *testing.T) {
 	testCases := []struct {
 		day       time.Time
 		expected  []time.Time
@@ -169,12 +219,12 @@ func TestStorageGetAllByDayEvents(t *testing.T) {
 			events := generateEvents("test get all by day")
 
 			for _, event := range events {
-				_, _ = st.CreateEvent(ctx, event)
+				_, _ = st.CreateEvent(ctx, event, allowOverlap)
 			}
 
 			actualDates := make([]time.Time, 0)
 
-			actualEvents, err := st.GetAllByDayEvents(ctx, tc.day)
+			actualEvents, err := getEventsInPeriod(ctx, st, testUserID, tc.day, 1)
 			require.NoError(t, err)
 
 			for _, events := range actualEvents {
@@ -187,7 +237,7 @@ func TestStorageGetAllByDayEvents(t *testing.T) {
 	}
 }
 
-func TestStorageGetAllByWeekEvents(t *testing.T) {
+func TestStorageGetEventsInRangeByWeek(t *testing.T) {
 	testCases := []struct {
 		fromDay   time.Time
 		expected  func(day time.Time) []time.Time
@@ -248,14 +298,14 @@ func TestStorageGetAllByWeekEvents(t *testing.T) {
 			events := generateEvents("test get all by week")
 
 			for _, event := range events {
-				_, _ = st.CreateEvent(ctx, event)
+				_, _ = st.CreateEvent(ctx, event, allowOverlap)
 			}
 
 			expectedDates := tc.expected(tc.fromDay)
 
 			actualDates := make([]time.Time, 0)
 
-			actualEvents, err := st.GetAllByWeekEvents(ctx, tc.fromDay)
+			actualEvents, err := getEventsInPeriod(ctx, st, testUserID, tc.fromDay, 7)
 			require.NoError(t, err)
 
 			for _, events := range actualEvents {
@@ -268,7 +318,7 @@ func TestStorageGetAllByWeekEvents(t *testing.T) {
 	}
 }
 
-func TestStorageGetAllByMonthEvents(t *testing.T) {
+func TestStorageGetEventsInRangeByMonth(t *testing.T) {
 	testCases := []struct {
 		fromDay   time.Time
 		expected  func(day time.Time) []time.Time
@@ -329,14 +379,14 @@ func TestStorageGetAllByMonthEvents(t *testing.T) {
 			events := generateEvents("test get all by month")
 
 			for _, event := range events {
-				_, _ = st.CreateEvent(ctx, event)
+				_, _ = st.CreateEvent(ctx, event, allowOverlap)
 			}
 
 			expectedDates := tc.expected(tc.fromDay)
 
 			actualDates := make([]time.Time, 0)
 
-			actualEvents, err := st.GetAllByMonthEvents(ctx, tc.fromDay)
+			actualEvents, err := getEventsInPeriod(ctx, st, testUserID, tc.fromDay, 30)
 			require.NoError(t, err)
 
 			for _, events := range actualEvents {
@@ -349,6 +399,234 @@ func TestStorageGetAllByMonthEvents(t *testing.T) {
 	}
 }
 
+func TestStorageGetEventsInRangeRecurring(t *testing.T) {
+	st := NewStorageMemory()
+	ctx := context.Background()
+
+	start := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC) // monday
+
+	series := models.Event{
+		ID:       uuid.New().String(),
+		Title:    "standup",
+		Date:     start,
+		Duration: 15 * time.Minute,
+		UserID:   1,
+		Recurrence: &models.Recurrence{
+			Frequency: models.FrequencyWeekly,
+			Interval:  1,
+			ByDay:     []time.Weekday{time.Monday, time.Wednesday, time.Friday},
+		},
+	}
+
+	_, err := st.CreateEvent(ctx, series, allowOverlap)
+	require.NoError(t, err)
+
+	events, err := getEventsInPeriod(ctx, st, testUserID, start.AddDate(0, 0, 14), 7)
+	require.NoError(t, err)
+
+	actualDates := make([]time.Time, 0, len(events))
+	for _, event := range events {
+		require.Equal(t, series.ID, event.ID)
+		require.Equal(t, event.Date, event.OriginalDate)
+		actualDates = append(actualDates, event.Date)
+	}
+
+	require.ElementsMatch(t, []time.Time{
+		start.AddDate(0, 0, 14),
+		start.AddDate(0, 0, 16),
+		start.AddDate(0, 0, 18),
+	}, actualDates)
+
+	events, err = getEventsInPeriod(ctx, st, testUserID, start.AddDate(0, 0, 1), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+}
+
+func TestStorageUpdateEventOccurrence(t *testing.T) {
+	start := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		scope         models.RecurrenceScope
+		expectedDates []time.Time
+		expectedTitle map[time.Time]string
+	}{
+		{
+			name:  "this occurrence",
+			scope: models.ScopeThis,
+			expectedDates: []time.Time{
+				start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2).Add(time.Hour), start.AddDate(0, 0, 3),
+			},
+			expectedTitle: map[time.Time]string{
+				start:                                 "standup",
+				start.AddDate(0, 0, 1):                "standup",
+				start.AddDate(0, 0, 2).Add(time.Hour): "moved standup",
+				start.AddDate(0, 0, 3):                "standup",
+			},
+		},
+		{
+			name:  "this and following occurrences",
+			scope: models.ScopeFollowing,
+			expectedDates: []time.Time{
+				start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2).Add(time.Hour), start.AddDate(0, 0, 3).Add(time.Hour),
+			},
+			expectedTitle: map[time.Time]string{
+				start:                                 "standup",
+				start.AddDate(0, 0, 1):                "standup",
+				start.AddDate(0, 0, 2).Add(time.Hour): "moved standup",
+				start.AddDate(0, 0, 3).Add(time.Hour): "moved standup",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			st := NewStorageMemory()
+			ctx := context.Background()
+
+			series := models.Event{
+				ID:         uuid.New().String(),
+				Title:      "standup",
+				Date:       start,
+				Duration:   15 * time.Minute,
+				UserID:     1,
+				Recurrence: &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 4},
+			}
+
+			_, err := st.CreateEvent(ctx, series, allowOverlap)
+			require.NoError(t, err)
+
+			occurrence := start.AddDate(0, 0, 2)
+			title, date := "moved standup", occurrence.Add(time.Hour)
+			update := models.EventUpdate{Title: &title, Date: &date}
+			newID := uuid.New().String()
+
+			updated, err := st.UpdateEventOccurrence(ctx, testUserID, series.ID, 1, occurrence, tc.scope, newID, update, allowOverlap)
+			require.NoError(t, err)
+			require.Equal(t, newID, updated.ID)
+
+			events, err := getEventsInPeriod(ctx, st, testUserID, start, 7)
+			require.NoError(t, err)
+
+			actualDates := make([]time.Time, 0, len(events))
+			for _, event := range events {
+				actualDates = append(actualDates, event.Date)
+				require.Equal(t, tc.expectedTitle[event.Date], event.Title)
+			}
+			require.ElementsMatch(t, tc.expectedDates, actualDates)
+
+			_, err = st.UpdateEventOccurrence(ctx, testUserID, series.ID, 2, occurrence.Add(time.Minute), tc.scope,
+				uuid.New().String(), update, allowOverlap)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestStorageDeleteEventOccurrence(t *testing.T) {
+	start := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		scope         models.RecurrenceScope
+		occurrence    time.Time
+		expectedDates []time.Time
+		seriesExists  bool
+	}{
+		{
+			name:          "this occurrence",
+			scope:         models.ScopeThis,
+			occurrence:    start.AddDate(0, 0, 1),
+			expectedDates: []time.Time{start, start.AddDate(0, 0, 2), start.AddDate(0, 0, 3)},
+			seriesExists:  true,
+		},
+		{
+			name:          "this and following occurrences",
+			scope:         models.ScopeFollowing,
+			occurrence:    start.AddDate(0, 0, 2),
+			expectedDates: []time.Time{start, start.AddDate(0, 0, 1)},
+			seriesExists:  true,
+		},
+		{
+			name:          "all occurrences starting from the first one",
+			scope:         models.ScopeFollowing,
+			occurrence:    start,
+			expectedDates: []time.Time{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			st := NewStorageMemory()
+			ctx := context.Background()
+
+			series := models.Event{
+				ID:         uuid.New().String(),
+				Title:      "standup",
+				Date:       start,
+				Duration:   15 * time.Minute,
+				UserID:     1,
+				Recurrence: &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 4},
+			}
+
+			_, err := st.CreateEvent(ctx, series, allowOverlap)
+			require.NoError(t, err)
+
+			err = st.DeleteEventOccurrence(ctx, testUserID, series.ID, 1, tc.occurrence, tc.scope)
+			require.NoError(t, err)
+
+			events, err := getEventsInPeriod(ctx, st, testUserID, start, 7)
+			require.NoError(t, err)
+
+			actualDates := make([]time.Time, 0, len(events))
+			for _, event := range events {
+				actualDates = append(actualDates, event.Date)
+			}
+			require.ElementsMatch(t, tc.expectedDates, actualDates)
+
+			_, ok := st.events[series.ID]
+			require.Equal(t, tc.seriesExists, ok)
+		})
+	}
+}
+
+func TestStorageGetUserEventsByPeriod(t *testing.T) {
+	st := NewStorageMemory()
+	ctx := context.Background()
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 7, 31, 23, 59, 59, 0, time.UTC)
+
+	events := []models.Event{
+		{ID: "in range", Date: from.AddDate(0, 0, 10), UserID: 1},
+		{ID: "other user", Date: from.AddDate(0, 0, 10), UserID: 2},
+		{ID: "out of range", Date: to.AddDate(0, 0, 1), UserID: 1},
+		{
+			ID:         "series",
+			Date:       from.AddDate(0, -1, 0),
+			UserID:     1,
+			Recurrence: &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1},
+		},
+		{
+			ID:         "finished series",
+			Date:       from.AddDate(0, -1, 0),
+			UserID:     1,
+			Recurrence: &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 3},
+		},
+	}
+	for _, event := range events {
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	actual, err := st.GetUserEventsByPeriod(ctx, 1, from, to)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	require.Equal(t, events[3], withoutVersion(actual[0]))
+	require.Equal(t, events[0], withoutVersion(actual[1]))
+}
+
 func generateEvents(titleText string) []models.Event {
 	var events []models.Event
 
@@ -363,7 +641,7 @@ func generateEvents(titleText string) []models.Event {
 			Date:                 currentDate,
 			Duration:             time.Duration(i),
 			Description:          "",
-			UserID:               i,
+			UserID:               testUserID,
 			NotificationInterval: time.Duration(i),
 		}
 
@@ -372,3 +650,131 @@ func generateEvents(titleText string) []models.Event {
 
 	return events
 }
+
+// withoutVersion clears the fields set by the storage.
+func withoutVersion(event models.Event) models.Event {
+	event.Version = 0
+	event.UpdatedAt = time.Time{}
+	return event
+}
+
+// eventUpdate returns the update which sets all editable fields of the event.
+func eventUpdate(event models.Event) models.EventUpdate {
+	return models.EventUpdate{
+		Title:                &event.Title,
+		Date:                 &event.Date,
+		Duration:             &event.Duration,
+		Description:          &event.Description,
+		NotificationInterval: &event.NotificationInterval,
+		Recurrence:           event.Recurrence,
+		ClearRecurrence:      event.Recurrence == nil,
+	}
+}
+
+func TestStorageGetIntersectingEvents(t *testing.T) {
+	st := NewStorageMemory()
+	ctx := context.Background()
+
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{Title: "touching", Date: date.Add(-time.Hour), Duration: time.Hour, UserID: testUserID},
+		{Title: "overlapping", Date: date.Add(-30 * time.Minute), Duration: time.Hour, UserID: testUserID},
+		{Title: "other user", Date: date, Duration: time.Hour, UserID: testUserID + 1},
+		{
+			Title:      "series",
+			Date:       date.AddDate(0, 0, -7).Add(45 * time.Minute),
+			Duration:   time.Hour,
+			UserID:     testUserID,
+			Recurrence: &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1},
+		},
+	}
+	for _, event := range events {
+		event.ID = uuid.New().String()
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	actual, err := st.GetIntersectingEvents(ctx, testUserID, date, date.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+
+	titles := []string{actual[0].Title, actual[1].Title}
+	require.ElementsMatch(t, []string{"overlapping", "series"}, titles)
+}
+
+func TestStorageGetEventsInRangePagination(t *testing.T) {
+	st := NewStorageMemory()
+	ctx := context.Background()
+
+	for _, event := range generateEvents("test pagination") {
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	from := time.Date(2000, 1, 2, 0, 0, 0, 0, time.Local)
+	rng := models.EventRange{
+		From:  from,
+		To:    from.AddDate(0, 0, 10),
+		Order: models.SortDesc,
+		Limit: 4,
+	}
+
+	var actualDates []time.Time
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+
+		page, err := st.GetEventsInRange(ctx, testUserID, rng)
+		require.NoError(t, err)
+
+		for _, event := range page.Events {
+			actualDates = append(actualDates, event.Date)
+		}
+		if page.Next == nil {
+			break
+		}
+		rng.After = page.Next
+	}
+
+	require.Len(t, actualDates, 10)
+	for i, date := range actualDates {
+		require.Equal(t, from.AddDate(0, 0, 9-i), date)
+	}
+}
+
+func TestStorageGetEventsInRangeQuery(t *testing.T) {
+	st := NewStorageMemory()
+	ctx := context.Background()
+
+	events := generateEvents("test query")
+	events[1].Description = "Weekly SYNC with the team"
+	events[2].Title = "sync"
+	for _, event := range events {
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	page, err := st.GetEventsInRange(ctx, testUserID, models.EventRange{
+		From:  events[0].Date,
+		To:    events[len(events)-1].Date,
+		Query: "Sync",
+	})
+	require.NoError(t, err)
+	require.Nil(t, page.Next)
+	require.Len(t, page.Events, 2)
+	require.Equal(t, events[1].ID, page.Events[0].ID)
+	require.Equal(t, events[2].ID, page.Events[1].ID)
+}
+
+func getEventsInPeriod(ctx context.Context, st *Storage, userID int, from time.Time, days int) ([]models.Event, error) {
+	page, err := st.GetEventsInRange(ctx, userID, models.EventRange{
+		From: from,
+		To:   from.AddDate(0, 0, days),
+	})
+	return page.Events, err
+}
+
+func TestStorageConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return NewStorageMemory()
+	})
+}
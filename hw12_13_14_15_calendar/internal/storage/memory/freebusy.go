@@ -0,0 +1,55 @@
+package memorystorage
+
+import (
+	"context"
+	"time"
+
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/freebusy"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+// GetFreeBusy returns the busy time of the users in [from, to), events are scanned once for all of them.
+func (s *Storage) GetFreeBusy(ctx context.Context, userIDs []int, from, to time.Time) ([]models.FreeBusy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	events := make(map[int][]models.Event, len(userIDs))
+	for _, userID := range userIDs {
+		events[userID] = nil
+	}
+
+	for _, event := range s.events {
+		if !event.Date.Before(to) {
+			continue
+		}
+		if _, ok := events[event.UserID]; ok {
+			events[event.UserID] = append(events[event.UserID], event)
+		}
+		for _, attendee := range event.Attendees {
+			if _, ok := events[attendee.UserID]; ok && attendee.Status != models.AttendeeDeclined {
+				events[attendee.UserID] = append(events[attendee.UserID], event)
+			}
+		}
+	}
+
+	calendars := make([]models.FreeBusy, 0, len(userIDs))
+	for _, userID := range userIDs {
+		calendars = append(calendars, models.FreeBusy{
+			UserID: userID,
+			Busy:   freebusy.Busy(events[userID], from, to),
+		})
+	}
+
+	return calendars, nil
+}
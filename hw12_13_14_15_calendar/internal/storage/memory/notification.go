@@ -3,13 +3,15 @@ package memorystorage
 import (
 	"context"
 	"sort"
+	"strconv"
 	"time"
 
 	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
 )
 
-func (s *Storage) GetNotificationsInAdvance(ctx context.Context) ([]models.Notification, error) {
+func (s *Storage) ClaimNotifications(ctx context.Context, claim models.NotificationClaim) ([]models.Notification, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -18,43 +20,105 @@ func (s *Storage) GetNotificationsInAdvance(ctx context.Context) ([]models.Notif
 		return nil, customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
 
+	from, to := claim.From(), claim.To()
+
 	var notifications []models.Notification
 
-	now := time.Now()
 	for _, event := range s.events {
-		var notification models.Notification
-
-		if !event.Scheduled && now.Before(event.Date.Add(-event.NotificationInterval)) {
-			notification = models.Notification{
-				EventID:  event.ID,
-				Title:    event.Title,
-				Date:     event.Date,
-				UserID:   event.UserID,
-				Interval: event.NotificationInterval,
-			}
+		if event.Recurrence != nil {
+			event = s.advanceReminders(event, claim)
 		}
 
-		if notification.EventID != "" {
+		for _, reminder := range event.Reminders {
+			if reminder.Status != models.StatusPending || reminder.Occurrence.IsZero() || s.isLeased(reminder, claim) {
+				continue
+			}
+
+			notification := models.Notification{
+				ReminderID: reminder.ID,
+				Channel:    reminder.Channel,
+				EventID:    event.ID,
+				Title:      event.Title,
+				Date:       reminder.Occurrence,
+				UserID:     event.UserID,
+				Interval:   reminder.Before,
+				Attendees:  acceptedAttendees(event),
+			}
+			if notifyAt := notification.NotifyAt(); notifyAt.Before(from) || notifyAt.After(to) {
+				continue
+			}
+
 			notifications = append(notifications, notification)
 		}
 	}
 
 	sort.Slice(notifications, func(i, j int) bool {
-		return notifications[i].Date.Add(-notifications[i].Interval).Before(notifications[j].Date.Add(-notifications[j].Interval))
+		return notifications[i].NotifyAt().Before(notifications[j].NotifyAt())
 	})
 
-	if len(notifications) > 10 {
-		notifications = notifications[:10]
+	if claim.Limit > 0 && len(notifications) > claim.Limit {
+		notifications = notifications[:claim.Limit]
+	}
+
+	for _, notification := range notifications {
+		s.leases[notification.ReminderID] = claim.Now.Add(claim.Lease)
 	}
 
 	return notifications, nil
 }
 
-func (s *Storage) UpdateScheduledNotification(ctx context.Context, id string) error {
+// advanceReminders moves sent and overdue reminders of the recurring event to the occurrences they are due for
+// and stores the event if they are moved. Reminders of occurrences removed from the series are moved too.
+func (s *Storage) advanceReminders(event models.Event, claim models.NotificationClaim) models.Event {
+	var reminders []models.Reminder
+
+	for i, reminder := range event.Reminders {
+		if reminder.Occurrence.IsZero() || s.isLeased(reminder, claim) {
+			continue
+		}
+		due := reminder.Status == models.StatusPending && !reminder.Occurrence.Add(-reminder.Before).After(claim.To())
+		if reminder.Status != models.StatusSent && !due {
+			continue
+		}
+
+		occurrence, _ := recurrence.ReminderOccurrence(event, reminder, claim.From())
+		if reminder.Status == models.StatusPending && occurrence.Equal(reminder.Occurrence) {
+			continue
+		}
+
+		if reminders == nil {
+			reminders = append([]models.Reminder(nil), event.Reminders...)
+		}
+		reminders[i] = models.Reminder{
+			ID:         reminder.ID,
+			Before:     reminder.Before,
+			Channel:    reminder.Channel,
+			Status:     models.StatusPending,
+			Occurrence: occurrence,
+		}
+	}
+
+	if reminders == nil {
+		return event
+	}
+
+	event.Reminders = reminders
+	s.events[event.ID] = event
+
+	return event
+}
+
+func (s *Storage) isLeased(reminder models.Reminder, claim models.NotificationClaim) bool {
+	leasedUntil, ok := s.leases[reminder.ID]
+	return ok && leasedUntil.After(claim.Now)
+}
+
+func (s *Storage) ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -63,13 +127,67 @@ func (s *Storage) UpdateScheduledNotification(ctx context.Context, id string) er
 		return customerror.CustomError{
 			Field:   "",
 			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
 		}
 	default:
 	}
 
-	event := s.events[id]
-	event.Scheduled = true
-	s.events[id] = event
+	now := time.Now()
+
+	event, ok := s.updateReminder(reminderID, func(reminder *models.Reminder) bool {
+		if reminder.Status != models.StatusPending {
+			return false
+		}
+		reminder.Status = models.StatusQueued
+		reminder.QueuedAt = now
+		return true
+	})
+	if !ok {
+		return customerror.CustomError{
+			Field:   "reminder_id",
+			Message: "notification wasn't updated with reminder id: " + strconv.FormatInt(reminderID, 10),
+			Err:     customerror.ErrNotFound,
+		}
+	}
+
+	delete(s.leases, reminderID)
+
+	for _, payload := range payloads {
+		s.outboxSeq++
+		s.outbox[s.outboxSeq] = models.OutboxMessage{
+			ID:            s.outboxSeq,
+			EventID:       event.ID,
+			ReminderID:    reminderID,
+			Payload:       append([]byte(nil), payload...),
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+	}
 
 	return nil
 }
+
+// updateReminder applies fn to the copy of the reminder with the given id and stores the copy if fn returns true.
+// It returns the event of the reminder and whether the reminder is updated.
+func (s *Storage) updateReminder(id int64, fn func(reminder *models.Reminder) bool) (models.Event, bool) {
+	for _, event := range s.events {
+		for i, reminder := range event.Reminders {
+			if reminder.ID != id {
+				continue
+			}
+
+			if !fn(&reminder) {
+				return models.Event{}, false
+			}
+
+			reminders := append([]models.Reminder(nil), event.Reminders...)
+			reminders[i] = reminder
+			event.Reminders = reminders
+			s.events[event.ID] = event
+
+			return event, true
+		}
+	}
+
+	return models.Event{}, false
+}
@@ -5,50 +5,112 @@ import (
 	"testing"
 	"time"
 
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
 	"github.com/stretchr/testify/require"
 )
 
-func TestStorage_GetNotificationsInAdvance(t *testing.T) {
+func TestStorage_ClaimNotifications(t *testing.T) {
 	st := NewStorageMemory()
 	ctx := context.Background()
 
+	now := time.Now()
+
+	// reminders refer to the only occurrences of the events
 	st.events["id1"] = models.Event{
-		ID:                   "id1",
-		Date:                 time.Now().Add(time.Hour),
-		NotificationInterval: 0,
-		Scheduled:            false,
+		ID:        "id1",
+		Date:      now.Add(time.Hour),
+		Reminders: []models.Reminder{{ID: 1, Status: models.StatusPending, Occurrence: now.Add(time.Hour)}},
 	}
 	st.events["id2"] = models.Event{
-		ID:                   "id2",
-		Date:                 time.Now().Add(time.Hour),
-		NotificationInterval: 0,
-		Scheduled:            true,
+		ID:        "id2",
+		Date:      now.Add(time.Minute),
+		Reminders: []models.Reminder{{ID: 2, Status: models.StatusSent, Occurrence: now.Add(time.Minute)}},
 	}
 	st.events["id3"] = models.Event{
-		ID:                   "id3",
-		Date:                 time.Now().Add(time.Minute * 30),
-		NotificationInterval: 0,
-		Scheduled:            false,
+		ID:        "id3",
+		Date:      now.Add(time.Second * 30),
+		Reminders: []models.Reminder{{ID: 3, Status: models.StatusPending, Occurrence: now.Add(time.Second * 30)}},
 	}
 	st.events["id4"] = models.Event{
-		ID:                   "id4",
-		Date:                 time.Now().Add(-time.Hour),
-		NotificationInterval: 0,
-		Scheduled:            false,
+		ID:        "id4",
+		Date:      now.Add(-time.Hour),
+		Reminders: []models.Reminder{{ID: 4, Status: models.StatusPending, Occurrence: now.Add(-time.Hour)}},
 	}
 	st.events["id5"] = models.Event{
-		ID:                   "id5",
-		Date:                 time.Now().Add(time.Second * 3),
-		NotificationInterval: 0,
-		Scheduled:            false,
+		ID:   "id5",
+		Date: now.Add(time.Second * 3),
+		Reminders: []models.Reminder{
+			{ID: 5, Status: models.StatusPending, Occurrence: now.Add(time.Second * 3)},
+			{ID: 6, Before: time.Minute, Status: models.StatusPending, Occurrence: now.Add(time.Second * 3)},
+		},
+	}
+
+	claim := models.NotificationClaim{
+		Now:    now,
+		Window: time.Minute,
+		Grace:  time.Minute,
+		Lease:  time.Second * 10,
+		Limit:  10,
 	}
 
-	notifications, err := st.GetNotificationsInAdvance(ctx)
+	notifications, err := st.ClaimNotifications(ctx, claim)
 	require.NoError(t, err)
 	require.Len(t, notifications, 3)
 
-	require.Equal(t, notifications[0].Date, st.events["id5"].Date)
-	require.Equal(t, notifications[1].Date, st.events["id3"].Date)
-	require.Equal(t, notifications[2].Date, st.events["id1"].Date)
+	require.Equal(t, int64(6), notifications[0].ReminderID)
+	require.Equal(t, int64(5), notifications[1].ReminderID)
+	require.Equal(t, int64(3), notifications[2].ReminderID)
+	require.Equal(t, notifications[2].Date, st.events["id3"].Date)
+	require.Equal(t, now.Add(claim.Lease), st.leases[3])
+
+	notifications, err = st.ClaimNotifications(ctx, claim)
+	require.NoError(t, err)
+	require.Empty(t, notifications)
+
+	claim.Now = now.Add(claim.Lease)
+	claim.Limit = 1
+
+	// reminder 6 is beyond the grace period now
+	notifications, err = st.ClaimNotifications(ctx, claim)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	require.Equal(t, int64(5), notifications[0].ReminderID)
+}
+
+func TestStorage_ScheduleNotification(t *testing.T) {
+	st := NewStorageMemory()
+	ctx := context.Background()
+
+	_, err := st.CreateEvent(ctx, models.Event{
+		ID:        "id1",
+		Date:      time.Now().Add(time.Hour),
+		Reminders: []models.Reminder{{Before: time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}},
+	}, allowOverlap)
+	require.NoError(t, err)
+
+	event, err := st.GetEventByID(ctx, "id1")
+	require.NoError(t, err)
+
+	st.leases[1] = time.Now().Add(time.Minute)
+
+	payload := []byte("id1")
+	require.NoError(t, st.ScheduleNotification(ctx, 1, payload))
+	require.NotContains(t, st.leases, int64(1))
+	require.Equal(t, models.StatusQueued, st.events["id1"].Reminders[0].Status)
+
+	// the event returned before is not changed by the storage
+	require.Equal(t, models.StatusPending, event.Reminders[0].Status)
+
+	// the outbox keeps its own copy of the payload
+	payload[0] = 'x'
+
+	require.Len(t, st.outbox, 1)
+	require.Equal(t, "id1", st.outbox[1].EventID)
+	require.Equal(t, int64(1), st.outbox[1].ReminderID)
+	require.Equal(t, []byte("id1"), st.outbox[1].Payload)
+
+	err = st.ScheduleNotification(ctx, 1, payload)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+	require.Len(t, st.outbox, 1)
 }
@@ -0,0 +1,163 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+func (s *Storage) ClaimOutboxMessages(ctx context.Context, claim models.OutboxClaim) ([]models.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	var messages []models.OutboxMessage
+
+	for _, message := range s.outbox {
+		if leasedUntil, ok := s.outboxLeases[message.ID]; ok && leasedUntil.After(claim.Now) {
+			continue
+		}
+		if !message.NextAttemptAt.After(claim.Now) {
+			message.Payload = append([]byte(nil), message.Payload...)
+			messages = append(messages, message)
+		}
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].NextAttemptAt.Equal(messages[j].NextAttemptAt) {
+			return messages[i].ID < messages[j].ID
+		}
+		return messages[i].NextAttemptAt.Before(messages[j].NextAttemptAt)
+	})
+
+	if len(messages) > claim.Limit {
+		messages = messages[:claim.Limit]
+	}
+
+	for _, message := range messages {
+		s.outboxLeases[message.ID] = claim.Now.Add(claim.Lease)
+	}
+
+	return messages, nil
+}
+
+func (s *Storage) CompleteOutboxMessage(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	message, ok := s.outbox[id]
+	if !ok {
+		return outboxNotFound(id)
+	}
+	delete(s.outbox, id)
+	delete(s.outboxLeases, id)
+
+	// the reminder is sent when the messages to all its recipients are published
+	for _, other := range s.outbox {
+		if other.ReminderID == message.ReminderID {
+			return nil
+		}
+	}
+	if !s.isQueuedBy(message) {
+		return nil
+	}
+
+	now := time.Now()
+
+	s.updateReminder(message.ReminderID, func(reminder *models.Reminder) bool {
+		reminder.Status = models.StatusSent
+		reminder.SentAt = now
+		return true
+	})
+
+	return nil
+}
+
+func (s *Storage) RetryOutboxMessage(ctx context.Context, id int64, next time.Time, lastErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return customerror.CustomError{
+			Field:   "",
+			Message: ctx.Err().Error(),
+			Err:     ctx.Err(),
+		}
+	default:
+	}
+
+	message, ok := s.outbox[id]
+	if !ok {
+		return outboxNotFound(id)
+	}
+	message.Attempts++
+	message.NextAttemptAt = next
+	message.LastError = lastErr
+	s.outbox[id] = message
+	delete(s.outboxLeases, id)
+
+	return nil
+}
+
+// deleteStaleOutbox deletes unpublished messages of deleted events and reminders and of reminders
+// which are not queued by them anymore, as foreign keys and the reset of reminders do in postgres.
+func (s *Storage) deleteStaleOutbox() {
+	for id, message := range s.outbox {
+		if !s.isQueuedBy(message) {
+			delete(s.outbox, id)
+			delete(s.outboxLeases, id)
+		}
+	}
+}
+
+// isQueuedBy reports whether the reminder of the message is queued in the same call which has created the message.
+func (s *Storage) isQueuedBy(message models.OutboxMessage) bool {
+	event, ok := s.events[message.EventID]
+	if !ok {
+		return false
+	}
+
+	reminder, ok := findReminder(event.Reminders, message.ReminderID)
+	return ok && reminder.Status == models.StatusQueued && reminder.QueuedAt.Equal(message.CreatedAt)
+}
+
+func findReminder(reminders []models.Reminder, id int64) (models.Reminder, bool) {
+	for _, reminder := range reminders {
+		if reminder.ID == id {
+			return reminder, true
+		}
+	}
+	return models.Reminder{}, false
+}
+
+func outboxNotFound(id int64) error {
+	return customerror.CustomError{
+		Field:   "id",
+		Message: "no outbox message with id " + strconv.FormatInt(id, 10),
+		Err:     customerror.ErrNotFound,
+	}
+}
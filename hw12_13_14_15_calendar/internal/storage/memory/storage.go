@@ -2,6 +2,7 @@ package memorystorage
 
 import (
 	"sync"
+	"time"
 
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
 )
@@ -9,10 +10,22 @@ import (
 type Storage struct {
 	mu     sync.RWMutex
 	events map[string]models.Event
+	outbox map[int64]models.OutboxMessage
+	// leases holds the time until which the claimed reminder is hidden from other claims.
+	leases map[int64]time.Time
+	// outboxLeases holds the time until which the claimed message is hidden from other claims.
+	outboxLeases map[int64]time.Time
+	// reminderSeq is the id of the last stored reminder.
+	reminderSeq int64
+	// outboxSeq is the id of the last message put to the outbox.
+	outboxSeq int64
 }
 
 func NewStorageMemory() *Storage {
 	return &Storage{
-		events: make(map[string]models.Event),
+		events:       make(map[string]models.Event),
+		outbox:       make(map[int64]models.OutboxMessage),
+		leases:       make(map[int64]time.Time),
+		outboxLeases: make(map[int64]time.Time),
 	}
 }
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+const attendeeColumns = "user_id, status, responded_at"
+
+func (s *Storage) InviteAttendees(ctx context.Context, userID int, id string, version int64, attendees []int,
+) (models.Event, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Event{}, dbError(err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET version = version + 1, updated_at = now()
+		WHERE id = $1 AND user_id = $2 AND version = $3
+		RETURNING %s`, eventsTable, eventColumns)
+
+	event, err := scanEvent(tx.QueryRow(ctx, query, id, userID, version))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Event{}, s.ownerError(ctx, userID, id, version)
+		}
+		return models.Event{}, dbError(err)
+	}
+
+	invited := make([]models.Attendee, 0, len(attendees))
+	for _, attendeeID := range attendees {
+		invited = append(invited, models.Attendee{UserID: attendeeID, Status: models.AttendeeNeedsAction})
+	}
+	if err := insertAttendees(ctx, tx, id, invited); err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	if err := loadDetails(ctx, tx, &event); err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	return event, nil
+}
+
+func (s *Storage) RespondToInvitation(ctx context.Context, userID int, id string, status models.AttendeeStatus,
+) (models.Event, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Event{}, dbError(err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET status = $1, responded_at = now()
+		WHERE event_id = $2 AND user_id = $3`, attendeesTable)
+
+	ct, err := tx.Exec(ctx, query, string(status), id, userID)
+	if err != nil {
+		return models.Event{}, dbError(err)
+	}
+	if ct.RowsAffected() == 0 {
+		if _, err := s.GetEventByID(ctx, id); err != nil {
+			return models.Event{}, err
+		}
+		return models.Event{}, customerror.CustomError{
+			Field:   "id",
+			Message: "user is not invited to event with id " + id,
+			Err:     customerror.ErrForbidden,
+		}
+	}
+
+	// the answer changes the event, so the cached copies of the event become stale
+	query = fmt.Sprintf(`
+		UPDATE %s SET version = version + 1, updated_at = now()
+		WHERE id = $1
+		RETURNING %s`, eventsTable, eventColumns)
+
+	event, err := scanEvent(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	if err := loadDetails(ctx, tx, &event); err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	return event, nil
+}
+
+// loadDetails loads reminders and attendees of the event.
+func loadDetails(ctx context.Context, q querier, event *models.Event) error {
+	var err error
+
+	event.Reminders, err = queryReminders(ctx, q, event.ID)
+	if err != nil {
+		return err
+	}
+
+	event.Attendees, err = queryAttendees(ctx, q, event.ID)
+
+	return err
+}
+
+// queryAttendees returns attendees of the event sorted by their ids.
+func queryAttendees(ctx context.Context, q querier, eventID string) ([]models.Attendee, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE event_id = $1
+		ORDER BY user_id`, attendeeColumns, attendeesTable)
+
+	rows, err := q.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attendees []models.Attendee
+
+	for rows.Next() {
+		attendee, err := scanAttendee(rows)
+		if err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, attendee)
+	}
+
+	return attendees, rows.Err()
+}
+
+// attachAttendees loads attendees of all events with one query.
+func (s *Storage) attachAttendees(ctx context.Context, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := eventIDs(events)
+
+	query := fmt.Sprintf(`
+		SELECT event_id, %s
+		FROM %s
+		WHERE event_id = ANY($1)
+		ORDER BY user_id`, attendeeColumns, attendeesTable)
+
+	rows, err := s.db.Query(ctx, query, ids)
+	if err != nil {
+		return dbError(err)
+	}
+	defer rows.Close()
+
+	attendees := make(map[string][]models.Attendee)
+
+	for rows.Next() {
+		var eventID string
+		attendee, err := scanAttendee(rows, &eventID)
+		if err != nil {
+			return dbError(err)
+		}
+		attendees[eventID] = append(attendees[eventID], attendee)
+	}
+
+	if err := rows.Err(); err != nil {
+		return dbError(err)
+	}
+
+	for i := range events {
+		events[i].Attendees = attendees[events[i].ID]
+	}
+
+	return nil
+}
+
+// insertAttendees stores attendees of the event, attendees who are already invited keep their answers.
+func insertAttendees(ctx context.Context, tx pgx.Tx, eventID string, attendees []models.Attendee) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (event_id, %s)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id, user_id) DO NOTHING`, attendeesTable, attendeeColumns)
+
+	for _, attendee := range attendees {
+		respondedAt := sql.NullTime{
+			Time:  attendee.RespondedAt,
+			Valid: !attendee.RespondedAt.IsZero(),
+		}
+		if _, err := tx.Exec(ctx, query, eventID, attendee.UserID, string(attendee.Status), respondedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanAttendee scans attendeeColumns preceded by prefix destinations.
+func scanAttendee(row pgx.Row, prefix ...interface{}) (models.Attendee, error) {
+	var (
+		attendee    models.Attendee
+		status      string
+		respondedAt sql.NullTime
+	)
+
+	dest := append(prefix, &attendee.UserID, &status, &respondedAt)
+	if err := row.Scan(dest...); err != nil {
+		return models.Attendee{}, err
+	}
+
+	attendee.Status = models.AttendeeStatus(status)
+	attendee.RespondedAt = respondedAt.Time
+
+	return attendee, nil
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v2"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageInviteAttendees(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	id := uuid.New().String()
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`
+		UPDATE %s SET version = version + 1, updated_at = now()
+		WHERE id = $1 AND user_id = $2 AND version = $3
+		RETURNING %s`, eventsTable, eventColumns))).
+		WithArgs(id, testUserID, int64(1)).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(id, "Event 1", date, time.Hour, "", testUserID, time.Duration(0),
+			nil, nil, nil, nil, nil, int64(2), updatedAt))
+	for _, attendeeID := range []int{2, 3} {
+		mock.ExpectExec(regexp.QuoteMeta(insertAttendee)).
+			WithArgs(id, attendeeID, "needs-action", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(selectReminders)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(reminderColumnNames))
+	// the user who was invited before keeps the answer
+	mock.ExpectQuery(regexp.QuoteMeta(selectAttendees)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(attendeeColumnNames).
+			AddRow(2, "accepted", updatedAt).
+			AddRow(3, "needs-action", nil))
+	mock.ExpectCommit()
+
+	event, err := storage.InviteAttendees(ctx, testUserID, id, 1, []int{2, 3})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), event.Version)
+	require.Equal(t, []models.Attendee{
+		{UserID: 2, Status: models.AttendeeAccepted, RespondedAt: updatedAt},
+		{UserID: 3, Status: models.AttendeeNeedsAction},
+	}, event.Attendees)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStorageRespondToInvitationError(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	id := uuid.New().String()
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	updateAttendee := regexp.QuoteMeta(fmt.Sprintf(`
+		UPDATE %s SET status = $1, responded_at = now()
+		WHERE event_id = $2 AND user_id = $3`, attendeesTable))
+	selectEvent := regexp.QuoteMeta(fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, eventColumns, eventsTable))
+
+	// the event exists, but the user is not invited
+	mock.ExpectBegin()
+	mock.ExpectExec(updateAttendee).WithArgs("accepted", id, 4).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectQuery(selectEvent).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(id, "Event 1", date, time.Hour, "", testUserID, time.Duration(0),
+			nil, nil, nil, nil, nil, int64(1), updatedAt))
+	mock.ExpectQuery(regexp.QuoteMeta(selectReminders)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(reminderColumnNames))
+	mock.ExpectQuery(regexp.QuoteMeta(selectAttendees)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(attendeeColumnNames))
+	mock.ExpectRollback()
+
+	_, err = storage.RespondToInvitation(ctx, 4, id, models.AttendeeAccepted)
+	require.ErrorIs(t, err, customerror.ErrForbidden)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(updateAttendee).WithArgs("declined", id, 4).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectQuery(selectEvent).WithArgs(id).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err = storage.RespondToInvitation(ctx, 4, id, models.AttendeeDeclined)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
@@ -5,31 +5,49 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/pagination"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
 )
 
-func (s *Storage) CreateEvent(ctx context.Context, event models.Event) (string, error) {
-	query := fmt.Sprintf(`
-		INSERT INTO %s (id, title, date, duration, description, user_id, notification_interval)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`, eventsTable)
+const eventColumns = `id, title, date, duration, description, user_id, notification_interval,
+		recurrence_rule, recurrence_exceptions, recurrence_id, original_date, recurrence_time_zone, version, updated_at`
 
-	ct, err := s.db.Exec(ctx, query,
-		event.ID,
-		event.Title,
-		event.Date,
-		event.Duration,
-		event.Description,
-		event.UserID,
-		event.NotificationInterval)
+// insertColumns are eventColumns followed by the columns which are written only.
+const insertColumns = eventColumns + `, recurrence_end`
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (s *Storage) CreateEvent(ctx context.Context, event models.Event, opts models.EventOptions) (string, error) {
+	event = created(event)
+
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		return "", customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
+		return "", dbError(err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if !opts.AllowOverlap {
+		if err := lockOwner(ctx, tx, event.UserID); err != nil {
+			return "", err
 		}
+		if err := checkOverlap(ctx, tx, event); err != nil {
+			return "", err
+		}
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`, eventsTable, insertColumns)
+
+	ct, err := tx.Exec(ctx, query, eventArgs(event)...)
+	if err != nil {
+		return "", dbError(err)
 	}
 
 	if ct.RowsAffected() != 1 {
@@ -39,249 +57,727 @@ func (s *Storage) CreateEvent(ctx context.Context, event models.Event) (string,
 		}
 	}
 
+	if _, err := insertReminders(ctx, tx, event.ID, event.Date, event.Reminders); err != nil {
+		return "", dbError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", dbError(err)
+	}
+
 	return event.ID, nil
 }
 
-type eventForUpdate struct {
-	Title                sql.NullString
-	Date                 sql.NullTime
-	Duration             sql.NullString
-	Description          sql.NullString
-	UserID               sql.NullInt64
-	NotificationInterval sql.NullString
-}
+func (s *Storage) UpdateEvent(ctx context.Context, userID int, id string, version int64, update models.EventUpdate,
+	opts models.EventOptions,
+) (models.Event, error) {
+	assignments, args := updateAssignments(update)
+	assignments = append(assignments, "version = version + 1", "updated_at = now()")
+	args = append(args, id, userID, version)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Event{}, dbError(err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
 
-func (s *Storage) UpdateEvent(ctx context.Context, id string, event models.Event) (models.Event, error) {
-	var updatedEvent models.Event
+	if !opts.AllowOverlap {
+		if err := lockOwner(ctx, tx, userID); err != nil {
+			return models.Event{}, err
+		}
+	}
 
 	query := fmt.Sprintf(`
-		UPDATE %s SET 
-        	title = COALESCE($1, title),
-            date = COALESCE($2, date),
-            duration = COALESCE($3, duration),
-            description = COALESCE($4, description),
-            user_id = COALESCE($5, user_id),
-            notification_interval = COALESCE($6, notification_interval)
-        WHERE id = $7 
-        RETURNING id, title, date, duration, description, user_id, notification_interval`, eventsTable)
-
-	eventUpdating := checkEmptyFields(event)
-
-	err := s.db.QueryRow(ctx, query,
-		eventUpdating.Title,
-		eventUpdating.Date,
-		eventUpdating.Duration,
-		eventUpdating.Description,
-		eventUpdating.UserID,
-		eventUpdating.NotificationInterval,
-		id).Scan(&updatedEvent.ID, &updatedEvent.Title, &updatedEvent.Date, &updatedEvent.Duration,
-		&updatedEvent.Description, &updatedEvent.UserID, &updatedEvent.NotificationInterval)
+		UPDATE %s SET %s
+		WHERE id = $%d AND user_id = $%d AND version = $%d
+		RETURNING %s`, eventsTable, strings.Join(assignments, ", "), len(args)-2, len(args)-1, len(args), eventColumns)
+
+	updatedEvent, err := scanEvent(tx.QueryRow(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return updatedEvent, customerror.CustomError{
+			return models.Event{}, s.ownerError(ctx, userID, id, version)
+		}
+		return models.Event{}, dbError(err)
+	}
+
+	if update.Date != nil || update.Recurrence != nil || update.ClearRecurrence {
+		if err := updateRecurrenceEnd(ctx, tx, updatedEvent); err != nil {
+			return models.Event{}, dbError(err)
+		}
+	}
+
+	if !opts.AllowOverlap {
+		if err := checkOverlap(ctx, tx, updatedEvent, id); err != nil {
+			return models.Event{}, err
+		}
+	}
+
+	updatedEvent.Reminders, err = updateReminders(ctx, tx, updatedEvent, update)
+	if err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	updatedEvent.Attendees, err = queryAttendees(ctx, tx, id)
+	if err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	return updatedEvent, nil
+}
+
+// updateAssignments returns SET assignments of the fields present in the update in the order
+// of eventColumns together with their arguments. Zero values are written as they are.
+func updateAssignments(update models.EventUpdate) ([]string, []interface{}) {
+	var (
+		assignments []string
+		args        []interface{}
+	)
+	set := func(column string, arg interface{}) {
+		args = append(args, arg)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if update.Title != nil {
+		set("title", *update.Title)
+	}
+	if update.Date != nil {
+		set("date", *update.Date)
+	}
+	if update.Duration != nil {
+		set("duration", *update.Duration)
+	}
+	if update.Description != nil {
+		set("description", *update.Description)
+	}
+	if update.NotificationInterval != nil {
+		set("notification_interval", *update.NotificationInterval)
+	}
+	switch {
+	case update.Recurrence != nil:
+		set("recurrence_rule", recurrence.Format(*update.Recurrence))
+		set("recurrence_exceptions", exceptionsArg(*update.Recurrence))
+		set("recurrence_time_zone", timeZoneArg(*update.Recurrence))
+	case update.ClearRecurrence:
+		set("recurrence_rule", sql.NullString{})
+		set("recurrence_exceptions", []time.Time(nil))
+		set("recurrence_time_zone", sql.NullString{})
+	}
+
+	return assignments, args
+}
+
+func (s *Storage) DeleteEvent(ctx context.Context, userID int, id string, version int64) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND user_id = $2 AND version = $3`, eventsTable)
+
+	result, err := s.db.Exec(ctx, query, id, userID, version)
+	if err != nil {
+		return dbError(err)
+	}
+
+	rows := result.RowsAffected()
+
+	if rows == 0 {
+		return s.ownerError(ctx, userID, id, version)
+	}
+
+	return nil
+}
+
+// ownerError explains why the event with the given id and version was not found among the user's events.
+func (s *Storage) ownerError(ctx context.Context, userID int, id string, version int64) error {
+	query := fmt.Sprintf(`SELECT user_id, version FROM %s WHERE id = $1`, eventsTable)
+
+	var (
+		ownerID        int
+		currentVersion int64
+	)
+	err := s.db.QueryRow(ctx, query, id).Scan(&ownerID, &currentVersion)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customerror.CustomError{
 				Field:   "id",
 				Message: "no event with id " + id,
+				Err:     customerror.ErrNotFound,
 			}
 		}
-		return updatedEvent, customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
+		return dbError(err)
+	}
+
+	if ownerID != userID {
+		return customerror.CustomError{
+			Field:   "id",
+			Message: "event with id " + id + " belongs to another user",
+			Err:     customerror.ErrForbidden,
 		}
 	}
 
-	return updatedEvent, nil
+	return versionError(id, currentVersion, version)
 }
 
-func checkEmptyFields(event models.Event) eventForUpdate {
-	return eventForUpdate{
-		Title: sql.NullString{
-			String: event.Title,
-			Valid:  event.Title != "",
-		},
-		Date: sql.NullTime{
-			Time:  event.Date,
-			Valid: !event.Date.IsZero(),
-		},
-		Duration: sql.NullString{
-			String: event.Duration.String(),
-			Valid:  event.Duration != 0,
-		},
-		Description: sql.NullString{
-			String: event.Description,
-			Valid:  event.Description != "",
-		},
-		UserID: sql.NullInt64{
-			Int64: int64(event.UserID),
-			Valid: event.UserID != 0,
-		},
-		NotificationInterval: sql.NullString{
-			String: event.NotificationInterval.String(),
-			Valid:  event.NotificationInterval != 0,
-		},
+func versionError(id string, current, expected int64) error {
+	return customerror.CustomError{
+		Field:   "version",
+		Message: fmt.Sprintf("event with id %s has version %d, not %d", id, current, expected),
+		Err:     customerror.ErrVersionMismatch,
 	}
 }
 
-func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
-	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, eventsTable)
+// created sets the first version of the new event.
+func created(event models.Event) models.Event {
+	event.Version = 1
+	event.UpdatedAt = time.Now().UTC()
+	return event
+}
 
-	result, err := s.db.Exec(ctx, query, id)
+func (s *Storage) UpdateEventOccurrence(ctx context.Context, userID int, id string, version int64,
+	occurrence time.Time, scope models.RecurrenceScope, newID string, update models.EventUpdate, opts models.EventOptions,
+) (models.Event, error) {
+	var result models.Event
+
+	err := s.changeSeries(ctx, userID, id, version, !opts.AllowOverlap, func(tx pgx.Tx, series models.Event) error {
+		switch scope {
+		case models.ScopeThis:
+			updatedSeries, detached, err := recurrence.Detach(series, occurrence, newID, update)
+			if err != nil {
+				return customerror.CustomError{
+					Field:   "occurrence",
+					Message: err.Error(),
+					Err:     customerror.ErrValidation,
+				}
+			}
+			if err := updateRecurrence(ctx, tx, updatedSeries); err != nil {
+				return err
+			}
+			result = created(detached)
+		case models.ScopeFollowing:
+			head, tail, ok, err := recurrence.Split(series, occurrence, newID, update)
+			if err != nil {
+				return customerror.CustomError{
+					Field:   "occurrence",
+					Message: err.Error(),
+					Err:     customerror.ErrValidation,
+				}
+			}
+			if err := truncateSeries(ctx, tx, series.ID, head, ok, occurrence); err != nil {
+				return err
+			}
+			result = created(tail)
+		default:
+			return customerror.CustomError{
+				Field:   "scope",
+				Message: "unsupported recurrence scope " + string(scope),
+				Err:     customerror.ErrValidation,
+			}
+		}
+
+		// the series is already changed, so the result is checked against the rest of it
+		if !opts.AllowOverlap {
+			if err := checkOverlap(ctx, tx, result); err != nil {
+				return err
+			}
+		}
+
+		query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`, eventsTable, insertColumns)
+
+		if _, err := tx.Exec(ctx, query, eventArgs(result)...); err != nil {
+			return err
+		}
+
+		reminders, err := insertReminders(ctx, tx, result.ID, result.Date, result.Reminders)
+		if err != nil {
+			return err
+		}
+		result.Reminders = reminders
+
+		// the changed occurrences keep the attendees of the series together with their answers
+		return insertAttendees(ctx, tx, result.ID, result.Attendees)
+	})
 	if err != nil {
+		return models.Event{}, err
+	}
+
+	return result, nil
+}
+
+func (s *Storage) DeleteEventOccurrence(ctx context.Context, userID int, id string, version int64,
+	occurrence time.Time, scope models.RecurrenceScope,
+) error {
+	return s.changeSeries(ctx, userID, id, version, false, func(tx pgx.Tx, series models.Event) error {
+		switch scope {
+		case models.ScopeThis:
+			updatedSeries, err := recurrence.Exclude(series, occurrence)
+			if err != nil {
+				return customerror.CustomError{
+					Field:   "occurrence",
+					Message: err.Error(),
+					Err:     customerror.ErrValidation,
+				}
+			}
+			return updateRecurrence(ctx, tx, updatedSeries)
+		case models.ScopeFollowing:
+			head, ok, err := recurrence.Truncate(series, occurrence)
+			if err != nil {
+				return customerror.CustomError{
+					Field:   "occurrence",
+					Message: err.Error(),
+					Err:     customerror.ErrValidation,
+				}
+			}
+			return truncateSeries(ctx, tx, series.ID, head, ok, occurrence)
+		}
+
 		return customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
+			Field:   "scope",
+			Message: "unsupported recurrence scope " + string(scope),
+			Err:     customerror.ErrValidation,
 		}
+	})
+}
+
+// changeSeries locks the user's series row, checks its version and runs fn inside a transaction.
+// The user is locked by lockOwner before the row if lock is set.
+func (s *Storage) changeSeries(ctx context.Context, userID int, id string, version int64, lock bool,
+	fn func(tx pgx.Tx, series models.Event) error,
+) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return dbError(err)
 	}
+	defer tx.Rollback(ctx) //nolint:errcheck
 
-	rows := result.RowsAffected()
+	if lock {
+		if err := lockOwner(ctx, tx, userID); err != nil {
+			return err
+		}
+	}
 
-	if rows == 0 {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 FOR UPDATE`, eventColumns, eventsTable)
+
+	series, err := scanEvent(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customerror.CustomError{
+				Field:   "id",
+				Message: "no event with id " + id,
+				Err:     customerror.ErrNotFound,
+			}
+		}
+		return dbError(err)
+	}
+	if series.UserID != userID {
 		return customerror.CustomError{
 			Field:   "id",
-			Message: "no event with id " + id,
+			Message: "event with id " + id + " belongs to another user",
+			Err:     customerror.ErrForbidden,
+		}
+	}
+	if series.Version != version {
+		return versionError(id, series.Version, version)
+	}
+
+	if err := loadDetails(ctx, tx, &series); err != nil {
+		return dbError(err)
+	}
+
+	if err := fn(tx, series); err != nil {
+		var customError customerror.CustomError
+		if errors.As(err, &customError) {
+			return err
 		}
+		return dbError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return dbError(err)
 	}
 
 	return nil
 }
 
+func updateRecurrence(ctx context.Context, tx pgx.Tx, series models.Event) error {
+	query := fmt.Sprintf(`
+		UPDATE %s SET recurrence_rule = $1, recurrence_exceptions = $2, recurrence_end = $3, version = version + 1,
+			updated_at = now()
+		WHERE id = $4`, eventsTable)
+
+	_, err := tx.Exec(ctx, query,
+		recurrence.Format(*series.Recurrence),
+		exceptionsArg(*series.Recurrence),
+		recurrenceEndArg(series),
+		series.ID)
+
+	return err
+}
+
+// updateRecurrenceEnd stores the end of the series after its start or recurrence is changed.
+func updateRecurrenceEnd(ctx context.Context, tx pgx.Tx, event models.Event) error {
+	query := fmt.Sprintf(`UPDATE %s SET recurrence_end = $1 WHERE id = $2`, eventsTable)
+
+	_, err := tx.Exec(ctx, query, recurrenceEndArg(event), event.ID)
+
+	return err
+}
+
+// truncateSeries ends the series before the occurrence or deletes it when no occurrences are left.
+// Events detached from the removed occurrences are deleted as well.
+func truncateSeries(ctx context.Context, tx pgx.Tx, id string, head models.Event, ok bool, occurrence time.Time) error {
+	if !ok {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, eventsTable)
+		_, err := tx.Exec(ctx, query, id)
+		return err
+	}
+
+	if err := updateRecurrence(ctx, tx, head); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE recurrence_id = $1 AND original_date >= $2`, eventsTable)
+	_, err := tx.Exec(ctx, query, id, occurrence)
+
+	return err
+}
+
 func (s *Storage) DeleteOutdatedEvents(ctx context.Context) error {
 	now := time.Now().Format(time.RFC3339Nano)
 
-	query := fmt.Sprintf(`DELETE FROM %s WHERE date < $1::timestamp - interval '1 year'`, eventsTable)
+	query := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE recurrence_rule IS NULL AND date < $1::timestamp - interval '1 year'`, eventsTable)
 
 	_, err := s.db.Exec(ctx, query, now)
 	if err != nil {
-		return customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
+		return dbError(err)
+	}
+
+	// recurring events are outdated only when their last occurrence is outdated
+	query = fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE recurrence_rule IS NOT NULL AND date < $1::timestamp - interval '1 year'`, eventColumns, eventsTable)
+
+	series, err := s.queryEvents(ctx, query, now)
+	if err != nil {
+		return err
+	}
+
+	outdated := make([]string, 0, len(series))
+	yearAgo := time.Now().AddDate(-1, 0, 0)
+	for _, event := range series {
+		if end, ok := recurrence.End(event.Date, *event.Recurrence); ok && end.Before(yearAgo) {
+			outdated = append(outdated, event.ID)
 		}
 	}
 
+	if len(outdated) == 0 {
+		return nil
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s WHERE id = ANY($1)`, eventsTable)
+
+	_, err = s.db.Exec(ctx, query, outdated)
+	if err != nil {
+		return dbError(err)
+	}
+
 	return nil
 }
 
-//nolint:dupl
-func (s *Storage) GetAllByDayEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
-	var events []models.Event
+// GetEventsInRange returns the page of user's events, the events the user is invited to and has not declined,
+// and occurrences of recurring events which start in the range. Single events are paginated by the database
+// from the cursor, the series are expanded only as far as the page reaches.
+func (s *Storage) GetEventsInRange(ctx context.Context, userID int, rng models.EventRange) (models.EventPage, error) {
+	order, compare := "ASC", ">"
+	if rng.Order == models.SortDesc {
+		order, compare = "DESC", "<"
+	}
 
+	// ids are compared byte by byte like in pagination, NULL limit returns all events
 	query := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
-		FROM %s 
-		WHERE date = $1`, eventsTable)
+		SELECT %[1]s
+		FROM %[2]s
+		WHERE (user_id = $1 OR id IN (SELECT event_id FROM %[3]s WHERE user_id = $1 AND status <> $5))
+			AND recurrence_rule IS NULL AND date BETWEEN $2 AND $3
+			AND ($4 = '' OR title ILIKE '%%' || $4 || '%%' OR description ILIKE '%%' || $4 || '%%')
+			AND ($6::timestamptz IS NULL OR (date, id COLLATE "C") %[4]s ($6, $7))
+		ORDER BY date %[5]s, id COLLATE "C" %[5]s
+		LIMIT $8`,
+		eventColumns, eventsTable, attendeesTable, compare, order)
+
+	from, to := pagination.Window(rng)
+
+	var (
+		after   interface{}
+		afterID string
+		limit   interface{}
+	)
+	if rng.After != nil {
+		after, afterID = rng.After.Date, rng.After.ID
+	}
+	// one more event tells whether the next page exists
+	if rng.Limit > 0 {
+		limit = rng.Limit + 1
+	}
 
-	rows, err := s.db.Query(ctx, query, date)
+	single, err := s.queryEvents(ctx, query, userID, from, to, escapeLike(rng.Query), string(models.AttendeeDeclined),
+		after, afterID, limit)
 	if err != nil {
-		return nil, customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
+		return models.EventPage{}, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT %[1]s
+		FROM %[2]s
+		WHERE (user_id = $1 OR id IN (SELECT event_id FROM %[3]s WHERE user_id = $1 AND status <> $4))
+			AND recurrence_rule IS NOT NULL AND date <= $2 AND (recurrence_end IS NULL OR recurrence_end >= $5)
+			AND ($3 = '' OR title ILIKE '%%' || $3 || '%%' OR description ILIKE '%%' || $3 || '%%')`,
+		eventColumns, eventsTable, attendeesTable)
+
+	series, err := s.queryEvents(ctx, query, userID, to, escapeLike(rng.Query), string(models.AttendeeDeclined), from)
+	if err != nil {
+		return models.EventPage{}, err
+	}
+
+	page := pagination.Page(single, series, rng)
+
+	if err := s.attachReminders(ctx, page.Events); err != nil {
+		return models.EventPage{}, err
+	}
+
+	if err := s.attachAttendees(ctx, page.Events); err != nil {
+		return models.EventPage{}, err
+	}
+
+	return page, nil
+}
+
+// GetUserEventsByPeriod returns user's events which take place in [from, to].
+// Recurring events are returned once without expansion.
+func (s *Storage) GetUserEventsByPeriod(ctx context.Context, userID int, from, to time.Time) ([]models.Event, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE user_id = $1
+			AND (date BETWEEN $2 AND $3 OR (recurrence_rule IS NOT NULL AND date <= $3
+				AND (recurrence_end IS NULL OR recurrence_end >= $2)))
+		ORDER BY date`, eventColumns, eventsTable)
+
+	events, err := s.queryEvents(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Event, 0, len(events))
+	for _, event := range events {
+		if event.Recurrence != nil && len(recurrence.Occurrences(event.Date, *event.Recurrence, from, to)) == 0 {
+			continue
 		}
+		result = append(result, event)
 	}
 
-	for rows.Next() {
-		var event models.Event
-
-		err := rows.Scan(
-			&event.ID,
-			&event.Title,
-			&event.Date,
-			&event.Duration,
-			&event.Description,
-			&event.UserID,
-			&event.NotificationInterval,
-		)
-		if err != nil {
-			return nil, customerror.CustomError{
-				Field:   "",
-				Message: err.Error(),
+	if err := s.attachReminders(ctx, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Storage) GetEventByID(ctx context.Context, id string) (models.Event, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, eventColumns, eventsTable)
+
+	event, err := scanEvent(s.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Event{}, customerror.CustomError{
+				Field:   "id",
+				Message: "no event with id " + id,
+				Err:     customerror.ErrNotFound,
 			}
 		}
+		return models.Event{}, dbError(err)
+	}
 
-		events = append(events, event)
+	if err := loadDetails(ctx, s.db, &event); err != nil {
+		return models.Event{}, dbError(err)
+	}
+
+	return event, nil
+}
+
+// GetIntersectingEvents returns the events and occurrences of recurring events which take place in [from, to)
+// and which the user owns or has accepted the invitations to. Reminders of the events are not loaded.
+func (s *Storage) GetIntersectingEvents(ctx context.Context, userID int, from, to time.Time) ([]models.Event, error) {
+	events, err := intersectingEvents(ctx, s.db, userID, from, to)
+	if err != nil {
+		return nil, dbError(err)
 	}
 
 	return events, nil
 }
 
-//nolint:dupl
-func (s *Storage) GetAllByWeekEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
+func (s *Storage) queryEvents(ctx context.Context, query string, args ...interface{}) ([]models.Event, error) {
 	var events []models.Event
 
-	query := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
-		FROM %s 
-		WHERE date BETWEEN $1 AND $1 + INTERVAL '6 days'`, eventsTable)
-
-	rows, err := s.db.Query(ctx, query, date)
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
-		}
+		return nil, dbError(err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		var event models.Event
-
-		err := rows.Scan(
-			&event.ID,
-			&event.Title,
-			&event.Date,
-			&event.Duration,
-			&event.Description,
-			&event.UserID,
-			&event.NotificationInterval,
-		)
+		event, err := scanEvent(rows)
 		if err != nil {
-			return nil, customerror.CustomError{
-				Field:   "",
-				Message: err.Error(),
-			}
+			return nil, dbError(err)
 		}
 
 		events = append(events, event)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
 	return events, nil
 }
 
-//nolint:dupl
-func (s *Storage) GetAllByMonthEvents(ctx context.Context, date time.Time) ([]models.Event, error) {
-	var events []models.Event
+// eventIDs returns ids of the events, occurrences of the same series share the id.
+func eventIDs(events []models.Event) []string {
+	ids := make([]string, 0, len(events))
+	seen := make(map[string]bool, len(events))
+	for _, event := range events {
+		if !seen[event.ID] {
+			seen[event.ID] = true
+			ids = append(ids, event.ID)
+		}
+	}
+	return ids
+}
 
-	query := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
-		FROM %s 
-		WHERE date BETWEEN $1 AND $1 + INTERVAL '29 days'`, eventsTable)
+// escapeLike escapes wildcards of the LIKE pattern.
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
 
-	rows, err := s.db.Query(ctx, query, date)
-	if err != nil {
-		return nil, customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
+// eventArgs returns the values of insertColumns.
+func eventArgs(event models.Event) []interface{} {
+	var rule, timeZone sql.NullString
+	var exceptions []time.Time
+	if event.Recurrence != nil {
+		rule = sql.NullString{
+			String: recurrence.Format(*event.Recurrence),
+			Valid:  true,
 		}
+		exceptions = exceptionsArg(*event.Recurrence)
+		timeZone = timeZoneArg(*event.Recurrence)
 	}
 
-	for rows.Next() {
-		var event models.Event
-
-		err := rows.Scan(
-			&event.ID,
-			&event.Title,
-			&event.Date,
-			&event.Duration,
-			&event.Description,
-			&event.UserID,
-			&event.NotificationInterval,
-		)
+	return []interface{}{
+		event.ID,
+		event.Title,
+		event.Date,
+		event.Duration,
+		event.Description,
+		event.UserID,
+		event.NotificationInterval,
+		rule,
+		exceptions,
+		sql.NullString{
+			String: event.RecurrenceID,
+			Valid:  event.RecurrenceID != "",
+		},
+		sql.NullTime{
+			Time:  event.OriginalDate,
+			Valid: !event.OriginalDate.IsZero(),
+		},
+		timeZone,
+		event.Version,
+		event.UpdatedAt,
+		recurrenceEndArg(event),
+	}
+}
+
+// exceptionsArg returns exceptions as a non-nil slice so they are stored as an empty array instead of NULL.
+func exceptionsArg(r models.Recurrence) []time.Time {
+	if r.Exceptions == nil {
+		return []time.Time{}
+	}
+	return r.Exceptions
+}
+
+// recurrenceEndArg returns the start of the last occurrence of the series, NULL for endless series and single events.
+func recurrenceEndArg(event models.Event) sql.NullTime {
+	if event.Recurrence == nil {
+		return sql.NullTime{}
+	}
+	end, ok := recurrence.End(event.Date, *event.Recurrence)
+	return sql.NullTime{
+		Time:  end,
+		Valid: ok,
+	}
+}
+
+// timeZoneArg returns the name of the time zone of the series, UTC is stored as NULL.
+func timeZoneArg(r models.Recurrence) sql.NullString {
+	timeZone := recurrence.TimeZone(r)
+	return sql.NullString{
+		String: timeZone,
+		Valid:  timeZone != "",
+	}
+}
+
+func scanEvent(row pgx.Row) (models.Event, error) {
+	var (
+		event        models.Event
+		rule         sql.NullString
+		exceptions   []time.Time
+		recurrenceID sql.NullString
+		originalDate sql.NullTime
+		timeZone     sql.NullString
+	)
+
+	err := row.Scan(
+		&event.ID,
+		&event.Title,
+		&event.Date,
+		&event.Duration,
+		&event.Description,
+		&event.UserID,
+		&event.NotificationInterval,
+		&rule,
+		&exceptions,
+		&recurrenceID,
+		&originalDate,
+		&timeZone,
+		&event.Version,
+		&event.UpdatedAt,
+	)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	if rule.Valid {
+		r, err := recurrence.Parse(rule.String)
 		if err != nil {
-			return nil, customerror.CustomError{
-				Field:   "",
-				Message: err.Error(),
+			return models.Event{}, err
+		}
+		if len(exceptions) > 0 {
+			r.Exceptions = exceptions
+		}
+		if timeZone.Valid {
+			r.Location, err = time.LoadLocation(timeZone.String)
+			if err != nil {
+				return models.Event{}, err
 			}
 		}
-
-		events = append(events, event)
+		event.Recurrence = &r
 	}
+	event.RecurrenceID = recurrenceID.String
+	event.OriginalDate = originalDate.Time
 
-	return events, nil
+	return event, nil
 }
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"regexp"
 	"testing"
@@ -10,10 +11,71 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v2"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
 	"github.com/stretchr/testify/require"
 )
 
+const testUserID = 1
+
+var columns = []string{"id", "title", "date", "duration", "description", "user_id", "notification_interval",
+	"recurrence_rule", "recurrence_exceptions", "recurrence_id", "original_date", "recurrence_time_zone", "version", "updated_at"}
+
+var reminderColumnNames = []string{"id", "remind_before", "channel", "status", "queued_at", "sent_at", "occurrence"}
+
+var allowOverlap = models.EventOptions{AllowOverlap: true}
+
+var attendeeColumnNames = []string{"user_id", "status", "responded_at"}
+
+var (
+	selectReminders = fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE event_id = $1
+		ORDER BY remind_before DESC`, reminderColumns, remindersTable)
+	selectEventsReminders = fmt.Sprintf(`
+		SELECT event_id, %s
+		FROM %s
+		WHERE event_id = ANY($1)
+		ORDER BY remind_before DESC`, reminderColumns, remindersTable)
+	insertReminder = fmt.Sprintf(`
+		INSERT INTO %s (event_id, remind_before, channel, status, occurrence)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`, remindersTable)
+	selectAttendees = fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE event_id = $1
+		ORDER BY user_id`, attendeeColumns, attendeesTable)
+	selectEventsAttendees = fmt.Sprintf(`
+		SELECT event_id, %s
+		FROM %s
+		WHERE event_id = ANY($1)
+		ORDER BY user_id`, attendeeColumns, attendeesTable)
+	insertAttendee = fmt.Sprintf(`
+		INSERT INTO %s (event_id, %s)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id, user_id) DO NOTHING`, attendeesTable, attendeeColumns)
+	selectIntersecting = fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE (user_id = $1 OR id IN (SELECT event_id FROM %s WHERE user_id = $1 AND status = $4))
+			AND date < $3 AND (date + duration > $2
+				OR (recurrence_rule IS NOT NULL AND (recurrence_end IS NULL OR recurrence_end + duration > $2)))`,
+		eventColumns, eventsTable, attendeesTable)
+)
+
+// updatedAt is the time of the last change of events returned by the mocked database.
+var updatedAt = time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+
+// insertArgs returns arguments of inserting the first version of the event at any time.
+func insertArgs(event models.Event) []interface{} {
+	event.Version = 1
+	args := eventArgs(event)
+	args[len(args)-2] = pgxmock.AnyArg()
+	return args
+}
+
 func TestStorageCreateEvent(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
@@ -27,27 +89,26 @@ func TestStorageCreateEvent(t *testing.T) {
 		Description:          "test description",
 		UserID:               4,
 		NotificationInterval: time.Second,
+		Reminders:            []models.Reminder{{Before: time.Second, Channel: models.ChannelLog, Status: models.StatusPending}},
 	}
 
 	ctx := context.Background()
 
 	query := fmt.Sprintf(`
-		INSERT INTO %s (id, title, date, duration, description, user_id, notification_interval)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`, eventsTable)
+		INSERT INTO %s (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`, eventsTable, insertColumns)
 
-	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(
-		event.ID,
-		event.Title,
-		event.Date,
-		event.Duration,
-		event.Description,
-		event.UserID,
-		event.NotificationInterval).WillReturnResult(pgxmock.NewResult("insert", 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(insertArgs(event)...).
+		WillReturnResult(pgxmock.NewResult("insert", 1))
+	mock.ExpectQuery(regexp.QuoteMeta(insertReminder)).WithArgs(event.ID, time.Second, "log", "pending", event.Date).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(1)))
+	mock.ExpectCommit()
 
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	id, err := storage.CreateEvent(ctx, event)
+	id, err := storage.CreateEvent(ctx, event, allowOverlap)
 
 	require.NoError(t, err)
 	require.Equal(t, event.ID, id)
@@ -55,6 +116,44 @@ func TestStorageCreateEvent(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
+func TestStorageCreateEventOverlap(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	event := models.Event{
+		ID:       uuid.New().String(),
+		Title:    "test title",
+		Date:     date,
+		Duration: time.Hour,
+		UserID:   testUserID,
+	}
+
+	ctx := context.Background()
+
+	// the invitation accepted by the user takes the second half of the event
+	busyRows := pgxmock.NewRows(columns).
+		AddRow("1", "Meeting", date.Add(30*time.Minute), time.Hour, "", 2, time.Duration(0),
+			nil, nil, nil, nil, nil, int64(1), updatedAt)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).WithArgs(testUserID).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery(regexp.QuoteMeta(selectIntersecting)).
+		WithArgs(testUserID, date, date.Add(time.Hour), "accepted").
+		WillReturnRows(busyRows)
+	mock.ExpectRollback()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	_, err = storage.CreateEvent(ctx, event, models.EventOptions{})
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
 func TestStorageUpdateEvent(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
@@ -63,13 +162,29 @@ func TestStorageUpdateEvent(t *testing.T) {
 	id := uuid.New().String()
 
 	event := models.Event{
-		ID:                   id,
-		Title:                "test title update before",
-		Date:                 time.Now(),
-		Duration:             time.Second,
-		Description:          "test description update before",
-		UserID:               4,
-		NotificationInterval: time.Second,
+		ID:       id,
+		Title:    "test title update after",
+		Date:     time.Now(),
+		Duration: time.Second,
+		UserID:   4,
+	}
+
+	// the title is changed, the description and the notification interval are removed,
+	// the sent reminder is kept and the new one is added
+	var (
+		description          string
+		notificationInterval time.Duration
+	)
+	reminders := []models.Reminder{
+		{Before: time.Hour, Channel: models.ChannelLog},
+		{Before: time.Minute, Channel: models.ChannelEmail},
+	}
+	update := models.EventUpdate{
+		Title:                &event.Title,
+		Description:          &description,
+		NotificationInterval: &notificationInterval,
+		Reminders:            &reminders,
+		ClearRecurrence:      true,
 	}
 
 	ctx := context.Background()
@@ -77,33 +192,63 @@ func TestStorageUpdateEvent(t *testing.T) {
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	columnsUpdate := []string{"id", "title", "date", "duration", "description", "user_id", "notification_interval"}
-	rows := pgxmock.NewRows(columnsUpdate).AddRow(event.ID, event.Title, event.Date,
-		event.Duration, event.Description, event.UserID, event.NotificationInterval)
+	event.Version = 3
+	event.UpdatedAt = updatedAt
+
+	rows := pgxmock.NewRows(columns).AddRow(event.ID, event.Title, event.Date, event.Duration, event.Description,
+		event.UserID, event.NotificationInterval, nil, nil, nil, nil, nil, event.Version, event.UpdatedAt)
 
 	query := fmt.Sprintf(`
-		UPDATE %s SET
-       	title = COALESCE($1, title),
-           date = COALESCE($2, date),
-           duration = COALESCE($3, duration),
-           description = COALESCE($4, description),
-           user_id = COALESCE($5, user_id),
-           notification_interval = COALESCE($6, notification_interval)
-       WHERE id = $7
-       RETURNING id, title, date, duration, description, user_id, notification_interval`, eventsTable)
-
-	updatingEvent := checkEmptyFields(event)
+		UPDATE %s SET title = $1, description = $2, notification_interval = $3, recurrence_rule = $4, recurrence_exceptions = $5, `+
+		`recurrence_time_zone = $6, version = version + 1, updated_at = now()
+		WHERE id = $7 AND user_id = $8 AND version = $9
+		RETURNING %s`, eventsTable, eventColumns)
+
+	sentAt := updatedAt.Add(-time.Hour)
+	event.Reminders = []models.Reminder{
+		{
+			ID:         1,
+			Before:     time.Hour,
+			Channel:    models.ChannelLog,
+			Status:     models.StatusSent,
+			QueuedAt:   sentAt,
+			SentAt:     sentAt,
+			Occurrence: event.Date,
+		},
+		{ID: 2, Before: time.Minute, Channel: models.ChannelEmail, Status: models.StatusPending, Occurrence: event.Date},
+	}
+	event.Attendees = []models.Attendee{{UserID: 5, Status: models.AttendeeAccepted, RespondedAt: updatedAt}}
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(
-		updatingEvent.Title,
-		updatingEvent.Date,
-		updatingEvent.Duration,
-		updatingEvent.Description,
-		updatingEvent.UserID,
-		updatingEvent.NotificationInterval,
-		id).WillReturnRows(rows)
-
-	updatedEvent, err := storage.UpdateEvent(ctx, id, event)
+		event.Title,
+		"",
+		time.Duration(0),
+		sql.NullString{},
+		[]time.Time(nil),
+		sql.NullString{},
+		id,
+		event.UserID,
+		int64(2)).WillReturnRows(rows)
+	mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`UPDATE %s SET recurrence_end = $1 WHERE id = $2`, eventsTable))).
+		WithArgs(sql.NullTime{}, id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(regexp.QuoteMeta(selectReminders)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(reminderColumnNames).AddRow(int64(1), time.Hour, "log", "sent", sentAt, sentAt, event.Date))
+	mock.ExpectQuery(regexp.QuoteMeta(insertReminder)).WithArgs(id, time.Minute, "email", "pending", event.Date).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(2)))
+	mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`UPDATE %s SET occurrence = $1 WHERE event_id = $2`, remindersTable))).
+		WithArgs(event.Date, id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectQuery(regexp.QuoteMeta(selectReminders)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(reminderColumnNames).
+			AddRow(int64(1), time.Hour, "log", "sent", sentAt, sentAt, event.Date).
+			AddRow(int64(2), time.Minute, "email", "pending", nil, nil, event.Date))
+	mock.ExpectQuery(regexp.QuoteMeta(selectAttendees)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(attendeeColumnNames).AddRow(5, "accepted", updatedAt))
+	mock.ExpectCommit()
+
+	updatedEvent, err := storage.UpdateEvent(ctx, event.UserID, id, 2, update, allowOverlap)
 	require.NoError(t, err)
 	require.Equal(t, event, updatedEvent)
 
@@ -116,15 +261,8 @@ func TestStorageUpdateEventError(t *testing.T) {
 	defer mock.Close()
 
 	id := "wrong id"
-
-	event := models.Event{
-		Title:                "test title update",
-		Date:                 time.Now(),
-		Duration:             time.Second,
-		Description:          "test description update",
-		UserID:               4,
-		NotificationInterval: time.Second,
-	}
+	userID := 4
+	title := "test title update"
 
 	ctx := context.Background()
 
@@ -132,32 +270,32 @@ func TestStorageUpdateEventError(t *testing.T) {
 	storage.db = mock
 
 	query := fmt.Sprintf(`
-		UPDATE %s SET
-       	title = COALESCE($1, title),
-           date = COALESCE($2, date),
-           duration = COALESCE($3, duration),
-           description = COALESCE($4, description),
-           user_id = COALESCE($5, user_id),
-           notification_interval = COALESCE($6, notification_interval)
-       WHERE id = $7
-       RETURNING id, title, date, duration, description, user_id, notification_interval`, eventsTable)
-
-	updatingEvent := checkEmptyFields(event)
+		UPDATE %s SET title = $1, version = version + 1, updated_at = now()
+		WHERE id = $2 AND user_id = $3 AND version = $4
+		RETURNING %s`, eventsTable, eventColumns)
+	queryOwner := fmt.Sprintf(`SELECT user_id, version FROM %s WHERE id = $1`, eventsTable)
 
-	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(
-		updatingEvent.Title,
-		updatingEvent.Date,
-		updatingEvent.Duration,
-		updatingEvent.Description,
-		updatingEvent.UserID,
-		updatingEvent.NotificationInterval,
-		id).WillReturnError(pgx.ErrNoRows)
-
-	updatedEvent, err := storage.UpdateEvent(ctx, id, event)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(title, id, userID, int64(1)).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(queryOwner)).WithArgs(id).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	updatedEvent, err := storage.UpdateEvent(ctx, userID, id, 1, models.EventUpdate{Title: &title}, allowOverlap)
 	expectedError := fmt.Errorf("no event with id %s", id)
 	require.EqualError(t, err, expectedError.Error())
+	require.ErrorIs(t, err, customerror.ErrNotFound)
 	require.Equal(t, models.Event{}, updatedEvent)
 
+	// the event was changed after the client read it
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(title, id, userID, int64(1)).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(queryOwner)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "version"}).AddRow(userID, int64(2)))
+	mock.ExpectRollback()
+
+	_, err = storage.UpdateEvent(ctx, userID, id, 1, models.EventUpdate{Title: &title}, allowOverlap)
+	require.ErrorIs(t, err, customerror.ErrVersionMismatch)
+
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
@@ -173,11 +311,12 @@ func TestStorageDeleteEvent(t *testing.T) {
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	queryDelete := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, eventsTable)
+	queryDelete := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND user_id = $2 AND version = $3`, eventsTable)
 
-	mock.ExpectExec(regexp.QuoteMeta(queryDelete)).WithArgs(id).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec(regexp.QuoteMeta(queryDelete)).WithArgs(id, testUserID, int64(1)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
 
-	err = storage.DeleteEvent(ctx, id)
+	err = storage.DeleteEvent(ctx, testUserID, id, 1)
 	require.NoError(t, err)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
@@ -195,13 +334,26 @@ func TestStorageDeleteEventError(t *testing.T) {
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	queryDelete := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, eventsTable)
+	queryDelete := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND user_id = $2 AND version = $3`, eventsTable)
+
+	queryOwner := fmt.Sprintf(`SELECT user_id, version FROM %s WHERE id = $1`, eventsTable)
 
-	mock.ExpectExec(regexp.QuoteMeta(queryDelete)).WithArgs(id).WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectExec(regexp.QuoteMeta(queryDelete)).WithArgs(id, testUserID, int64(1)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectQuery(regexp.QuoteMeta(queryOwner)).WithArgs(id).WillReturnError(pgx.ErrNoRows)
 
-	err = storage.DeleteEvent(ctx, id)
+	err = storage.DeleteEvent(ctx, testUserID, id, 1)
 	expectedError := fmt.Errorf("no event with id %s", id)
 	require.EqualError(t, err, expectedError.Error())
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	mock.ExpectExec(regexp.QuoteMeta(queryDelete)).WithArgs(id, testUserID, int64(1)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectQuery(regexp.QuoteMeta(queryOwner)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "version"}).AddRow(testUserID+1, int64(1)))
+
+	err = storage.DeleteEvent(ctx, testUserID, id, 1)
+	require.ErrorIs(t, err, customerror.ErrForbidden)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
@@ -227,7 +379,29 @@ func TestStorageDeleteEventError(t *testing.T) {
 //	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 //}
 
-func TestStorageGetAllByDayEvents(t *testing.T) {
+// singleInRange returns the query of the page of single events in the order.
+func singleInRange(order, compare string) string {
+	return fmt.Sprintf(`
+		SELECT %[1]s
+		FROM %[2]s
+		WHERE (user_id = $1 OR id IN (SELECT event_id FROM %[3]s WHERE user_id = $1 AND status <> $5))
+			AND recurrence_rule IS NULL AND date BETWEEN $2 AND $3
+			AND ($4 = '' OR title ILIKE '%%' || $4 || '%%' OR description ILIKE '%%' || $4 || '%%')
+			AND ($6::timestamptz IS NULL OR (date, id COLLATE "C") %[4]s ($6, $7))
+		ORDER BY date %[5]s, id COLLATE "C" %[5]s
+		LIMIT $8`,
+		eventColumns, eventsTable, attendeesTable, compare, order)
+}
+
+var seriesInRange = fmt.Sprintf(`
+		SELECT %[1]s
+		FROM %[2]s
+		WHERE (user_id = $1 OR id IN (SELECT event_id FROM %[3]s WHERE user_id = $1 AND status <> $4))
+			AND recurrence_rule IS NOT NULL AND date <= $2 AND (recurrence_end IS NULL OR recurrence_end >= $5)
+			AND ($3 = '' OR title ILIKE '%%' || $3 || '%%' OR description ILIKE '%%' || $3 || '%%')`,
+	eventColumns, eventsTable, attendeesTable)
+
+func TestStorageGetEventsInRange(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
@@ -243,6 +417,9 @@ func TestStorageGetAllByDayEvents(t *testing.T) {
 			Description:          "Description 1",
 			UserID:               1,
 			NotificationInterval: time.Hour,
+			Reminders:            []models.Reminder{{ID: 1, Before: time.Hour, Channel: models.ChannelLog, Status: models.StatusPending}},
+			Version:              1,
+			UpdatedAt:            updatedAt,
 		},
 		{
 			ID:                   "2",
@@ -252,6 +429,9 @@ func TestStorageGetAllByDayEvents(t *testing.T) {
 			Description:          "Description 2",
 			UserID:               2,
 			NotificationInterval: 2 * time.Hour,
+			Attendees:            []models.Attendee{{UserID: testUserID, Status: models.AttendeeTentative, RespondedAt: updatedAt}},
+			Version:              1,
+			UpdatedAt:            updatedAt,
 		},
 	}
 
@@ -260,230 +440,421 @@ func TestStorageGetAllByDayEvents(t *testing.T) {
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	columns := []string{"id", "title", "date", "duration", "description", "user_id", "notification_interval"}
+	// the database returns one more event than the page holds
 	expectedRows := pgxmock.NewRows(columns).
-		AddRow("1", "Event 1", date, time.Hour, "Description 1", 1, time.Hour).
-		AddRow("2", "Event 2", date, 2*time.Hour, "Description 2", 2, 2*time.Hour)
-
-	queryGetByDay := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
-		FROM %s
-		WHERE date = $1`, eventsTable)
-
-	mock.ExpectQuery(regexp.QuoteMeta(queryGetByDay)).WithArgs(date).WillReturnRows(expectedRows)
+		AddRow("1", "Event 1", date, time.Hour, "Description 1", 1, time.Hour, nil, nil, nil, nil, nil, int64(1), updatedAt).
+		AddRow("2", "Event 2", date, 2*time.Hour, "Description 2", 2, 2*time.Hour, nil, nil, nil, nil, nil, int64(1), updatedAt).
+		AddRow("3", "Event 3", date.AddDate(0, 0, 1), time.Hour, "Description 3", 1, time.Hour, nil, nil, nil, nil,
+			nil, int64(1), updatedAt)
+
+	rng := models.EventRange{
+		From:  date,
+		To:    date.AddDate(0, 0, 7),
+		Limit: 2,
+	}
 
-	actualEvents, err := storage.GetAllByDayEvents(ctx, date)
+	mock.ExpectQuery(regexp.QuoteMeta(singleInRange("ASC", ">"))).
+		WithArgs(testUserID, date, rng.To.Add(-time.Nanosecond), "", "declined", nil, "", 3).
+		WillReturnRows(expectedRows)
+	mock.ExpectQuery(regexp.QuoteMeta(seriesInRange)).
+		WithArgs(testUserID, rng.To.Add(-time.Nanosecond), "", "declined", rng.From).
+		WillReturnRows(pgxmock.NewRows(columns))
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsReminders)).
+		WithArgs([]string{"1", "2"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, reminderColumnNames...)).
+			AddRow("1", int64(1), time.Hour, "log", "pending", nil, nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsAttendees)).
+		WithArgs([]string{"1", "2"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, attendeeColumnNames...)).
+			AddRow("2", testUserID, "tentative", updatedAt))
+
+	page, err := storage.GetEventsInRange(ctx, testUserID, rng)
 	require.NoError(t, err)
-	require.Len(t, actualEvents, 2)
-	require.ElementsMatch(t, expectedEvents, actualEvents)
+	require.Equal(t, expectedEvents, page.Events)
+	require.Equal(t, &models.Cursor{Date: date, ID: "2"}, page.Next)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
-func TestStorageGetAllByDayEventsEmpty(t *testing.T) {
+func TestStorageGetEventsInRangeEmpty(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
 
 	date := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
 
-	var expectedEvents []models.Event
-
 	ctx := context.Background()
 
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	columns := []string{"id", "title", "date", "duration", "description", "user_id", "notification_interval"}
 	expectedRows := pgxmock.NewRows(columns)
 
-	queryGetByDay := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
-		FROM %s
-		WHERE date = $1`, eventsTable)
+	rng := models.EventRange{
+		From: date,
+		To:   date.AddDate(0, 0, 1),
+	}
 
-	mock.ExpectQuery(regexp.QuoteMeta(queryGetByDay)).WithArgs(date).WillReturnRows(expectedRows)
+	mock.ExpectQuery(regexp.QuoteMeta(singleInRange("ASC", ">"))).
+		WithArgs(testUserID, date, rng.To.Add(-time.Nanosecond), "", "declined", nil, "", nil).
+		WillReturnRows(expectedRows)
+	mock.ExpectQuery(regexp.QuoteMeta(seriesInRange)).
+		WithArgs(testUserID, rng.To.Add(-time.Nanosecond), "", "declined", rng.From).
+		WillReturnRows(pgxmock.NewRows(columns))
 
-	actualEvents, err := storage.GetAllByDayEvents(ctx, date)
+	page, err := storage.GetEventsInRange(ctx, testUserID, rng)
 	require.NoError(t, err)
-	require.Len(t, actualEvents, 0)
-	require.ElementsMatch(t, expectedEvents, actualEvents)
+	require.Len(t, page.Events, 0)
+	require.Nil(t, page.Next)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
-func TestStorageGetAllByWeekEvents(t *testing.T) {
+func TestStorageGetEventsInRangeCursor(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
 
 	date := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
 
-	expectedEvents := []models.Event{
-		{
-			ID:                   "1",
-			Title:                "Event 1",
-			Date:                 date,
-			Duration:             time.Hour,
-			Description:          "Description 1",
-			UserID:               1,
-			NotificationInterval: time.Hour,
-		},
-		{
-			ID:                   "2",
-			Title:                "Event 2",
-			Date:                 date.AddDate(0, 0, 6),
-			Duration:             2 * time.Hour,
-			Description:          "Description 2",
-			UserID:               2,
-			NotificationInterval: 2 * time.Hour,
-		},
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	// the database skips the events up to the cursor
+	expectedRows := pgxmock.NewRows(columns).
+		AddRow("2", "100% done", date.AddDate(0, 0, 1), time.Hour, "", 1, time.Hour, nil, nil, nil, nil, nil, int64(1), updatedAt).
+		AddRow("1", "100% done", date, time.Hour, "", 1, time.Hour, nil, nil, nil, nil, nil, int64(1), updatedAt)
+
+	rng := models.EventRange{
+		From:  date,
+		To:    date.AddDate(0, 0, 7),
+		Query: "100%",
+		Order: models.SortDesc,
+		After: &models.Cursor{Date: date.AddDate(0, 0, 2), ID: "3"},
 	}
 
+	mock.ExpectQuery(regexp.QuoteMeta(singleInRange("DESC", "<"))).
+		WithArgs(testUserID, date, date.AddDate(0, 0, 2), `100\%`, "declined", date.AddDate(0, 0, 2), "3", nil).
+		WillReturnRows(expectedRows)
+	mock.ExpectQuery(regexp.QuoteMeta(seriesInRange)).
+		WithArgs(testUserID, date.AddDate(0, 0, 2), `100\%`, "declined", date).
+		WillReturnRows(pgxmock.NewRows(columns))
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsReminders)).
+		WithArgs([]string{"2", "1"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, reminderColumnNames...)))
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsAttendees)).
+		WithArgs([]string{"2", "1"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, attendeeColumnNames...)))
+
+	page, err := storage.GetEventsInRange(ctx, testUserID, rng)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	require.Equal(t, "2", page.Events[0].ID)
+	require.Equal(t, "1", page.Events[1].ID)
+	require.Nil(t, page.Next)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStorageGetEventsInRangeRecurring(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	date := time.Date(2000, 1, 2, 10, 0, 0, 0, time.UTC)
+	exception := date.AddDate(0, 0, 1)
+
 	ctx := context.Background()
 
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	columns := []string{"id", "title", "date", "duration", "description", "user_id", "notification_interval"}
-	expectedRows := pgxmock.NewRows(columns).
-		AddRow("1", "Event 1", date, time.Hour, "Description 1", 1, time.Hour).
-		AddRow("2", "Event 2", date.AddDate(0, 0, 6), 2*time.Hour, "Description 2", 2, 2*time.Hour)
+	seriesRows := pgxmock.NewRows(columns).
+		AddRow("1", "Event 1", date.AddDate(0, 0, -1), time.Hour, "Description 1", 1, time.Hour,
+			"FREQ=DAILY;COUNT=4", []time.Time{exception}, nil, nil, nil, int64(1), updatedAt)
+	singleRows := pgxmock.NewRows(columns).
+		AddRow("2", "Event 2", date.AddDate(0, 0, 6), time.Hour, "Description 2", 1, time.Hour,
+			nil, nil, "1", exception, nil, int64(1), updatedAt)
 
-	queryGetByWeek := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
-		FROM %s
-		WHERE date BETWEEN $1 AND $1 + INTERVAL '6 days'`, eventsTable)
-	mock.ExpectQuery(regexp.QuoteMeta(queryGetByWeek)).WithArgs(date).WillReturnRows(expectedRows)
+	rng := models.EventRange{
+		From: date,
+		To:   date.AddDate(0, 0, 7),
+	}
 
-	actualEvents, err := storage.GetAllByWeekEvents(ctx, date)
+	mock.ExpectQuery(regexp.QuoteMeta(singleInRange("ASC", ">"))).
+		WithArgs(testUserID, date, rng.To.Add(-time.Nanosecond), "", "declined", nil, "", nil).
+		WillReturnRows(singleRows)
+	mock.ExpectQuery(regexp.QuoteMeta(seriesInRange)).
+		WithArgs(testUserID, rng.To.Add(-time.Nanosecond), "", "declined", rng.From).
+		WillReturnRows(seriesRows)
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsReminders)).
+		WithArgs([]string{"1", "2"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, reminderColumnNames...)))
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsAttendees)).
+		WithArgs([]string{"1", "2"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, attendeeColumnNames...)))
+
+	page, err := storage.GetEventsInRange(ctx, testUserID, rng)
 	require.NoError(t, err)
-	require.Len(t, actualEvents, 2)
-	require.ElementsMatch(t, expectedEvents, actualEvents)
+
+	actualEvents := page.Events
+	actualDates := make([]time.Time, 0, len(actualEvents))
+	for _, event := range actualEvents {
+		actualDates = append(actualDates, event.Date)
+	}
+	require.Equal(t, []time.Time{date, date.AddDate(0, 0, 2), date.AddDate(0, 0, 6)}, actualDates)
+	require.Equal(t, "1", actualEvents[2].RecurrenceID)
+	require.Equal(t, exception, actualEvents[2].OriginalDate)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
-func TestStorageGetAllByWeekEventsEmpty(t *testing.T) {
+func TestStorageGetEventsInRangeTimeZone(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
 
-	date := time.Date(2000, 1, 2, 0, 0, 0, 0, time.Local)
-
-	var expectedEvents []models.Event
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
 
 	ctx := context.Background()
 
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	columns := []string{"id", "title", "date", "duration", "description", "user_id", "notification_interval"}
-	expectedRows := pgxmock.NewRows(columns)
+	// the series is read in the zone of the session, 09:00 CET before the change to summer time
+	seriesRows := pgxmock.NewRows(columns).
+		AddRow("1", "Event 1", time.Date(2023, 3, 24, 8, 0, 0, 0, time.UTC), time.Hour, "", 1, time.Hour,
+			"FREQ=DAILY", []time.Time{}, nil, nil, "Europe/Berlin", int64(1), updatedAt)
 
-	queryGetByWeek := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
-		FROM %s
-		WHERE date BETWEEN $1 AND $1 + INTERVAL '6 days'`, eventsTable)
-	mock.ExpectQuery(regexp.QuoteMeta(queryGetByWeek)).WithArgs(date).WillReturnRows(expectedRows)
+	rng := models.EventRange{
+		From: time.Date(2023, 3, 25, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2023, 3, 28, 0, 0, 0, 0, time.UTC),
+	}
 
-	actualEvents, err := storage.GetAllByWeekEvents(ctx, date)
+	mock.ExpectQuery(regexp.QuoteMeta(singleInRange("ASC", ">"))).
+		WithArgs(testUserID, rng.From, rng.To.Add(-time.Nanosecond), "", "declined", nil, "", nil).
+		WillReturnRows(pgxmock.NewRows(columns))
+	mock.ExpectQuery(regexp.QuoteMeta(seriesInRange)).
+		WithArgs(testUserID, rng.To.Add(-time.Nanosecond), "", "declined", rng.From).
+		WillReturnRows(seriesRows)
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsReminders)).
+		WithArgs([]string{"1"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, reminderColumnNames...)))
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsAttendees)).
+		WithArgs([]string{"1"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, attendeeColumnNames...)))
+
+	page, err := storage.GetEventsInRange(ctx, testUserID, rng)
 	require.NoError(t, err)
-	require.Len(t, actualEvents, 0)
-	require.ElementsMatch(t, expectedEvents, actualEvents)
+
+	require.Len(t, page.Events, 3)
+	for i, event := range page.Events {
+		require.Equal(t, berlin, event.Recurrence.Location)
+		require.Equal(t, time.Date(2023, 3, 25+i, 9, 0, 0, 0, berlin), event.Date)
+	}
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
-func TestStorageGetAllByMonthEvents(t *testing.T) {
+func TestStorageUpdateEventOccurrence(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
 
-	date := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	id := uuid.New().String()
+	date := time.Date(2000, 1, 2, 10, 0, 0, 0, time.UTC)
+	occurrence := date.AddDate(0, 0, 1)
 
-	expectedEvents := []models.Event{
-		{
-			ID:                   "1",
-			Title:                "Event 1",
-			Date:                 date,
-			Duration:             time.Hour,
-			Description:          "Description 1",
-			UserID:               1,
-			NotificationInterval: time.Hour,
-		},
-		{
-			ID:                   "2",
-			Title:                "Event 2",
-			Date:                 date.AddDate(0, 0, 1),
-			Duration:             2 * time.Hour,
-			Description:          "Description 2",
-			UserID:               2,
-			NotificationInterval: 2 * time.Hour,
-		},
-		{
-			ID:                   "3",
-			Title:                "Event 3",
-			Date:                 date.AddDate(0, 0, 29),
-			Duration:             3 * time.Hour,
-			Description:          "Description 3",
-			UserID:               3,
-			NotificationInterval: 3 * time.Hour,
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	newID := uuid.New().String()
+	title := "Event 1 moved"
+
+	expectedEvent := models.Event{
+		ID:                   newID,
+		Title:                title,
+		Date:                 occurrence,
+		Duration:             time.Hour,
+		Description:          "Description 1",
+		UserID:               1,
+		NotificationInterval: time.Hour,
+		Reminders: []models.Reminder{
+			{ID: 5, Before: time.Hour, Channel: models.ChannelLog, Status: models.StatusPending, Occurrence: occurrence},
 		},
+		Attendees:    []models.Attendee{{UserID: 2, Status: models.AttendeeAccepted, RespondedAt: updatedAt}},
+		RecurrenceID: id,
+		OriginalDate: occurrence,
+		Version:      1,
 	}
 
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 FOR UPDATE`, eventColumns, eventsTable))).
+		WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(id, "Event 1", date, time.Hour, "Description 1", 1, time.Hour,
+			"FREQ=DAILY", []time.Time{}, nil, nil, nil, int64(1), updatedAt))
+	mock.ExpectQuery(regexp.QuoteMeta(selectReminders)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(reminderColumnNames).AddRow(int64(4), time.Hour, "log", "sent", updatedAt, updatedAt, date))
+	mock.ExpectQuery(regexp.QuoteMeta(selectAttendees)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(attendeeColumnNames).AddRow(2, "accepted", updatedAt))
+	mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`
+		UPDATE %s SET recurrence_rule = $1, recurrence_exceptions = $2, recurrence_end = $3, version = version + 1,
+			updated_at = now()
+		WHERE id = $4`, eventsTable))).
+		WithArgs("FREQ=DAILY", []time.Time{occurrence}, sql.NullTime{}, id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`, eventsTable, insertColumns))).
+		WithArgs(insertArgs(expectedEvent)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	// the reminder of the detached occurrence is sent again
+	mock.ExpectQuery(regexp.QuoteMeta(insertReminder)).WithArgs(newID, time.Hour, "log", "pending", occurrence).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(5)))
+	// the detached occurrence keeps the answers of the attendees
+	mock.ExpectExec(regexp.QuoteMeta(insertAttendee)).
+		WithArgs(newID, 2, "accepted", sql.NullTime{Time: updatedAt, Valid: true}).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	event, err := storage.UpdateEventOccurrence(ctx, testUserID, id, 1, occurrence, models.ScopeThis, newID,
+		models.EventUpdate{Title: &title}, allowOverlap)
+	require.NoError(t, err)
+	require.False(t, event.UpdatedAt.IsZero())
+	event.UpdatedAt = time.Time{}
+	require.Equal(t, expectedEvent, event)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStorageDeleteEventOccurrenceError(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	id := uuid.New().String()
+	date := time.Date(2000, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 FOR UPDATE`, eventColumns, eventsTable))).
+		WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(id, "Event 1", date, time.Hour, "Description 1", 1, time.Hour,
+			"FREQ=WEEKLY", []time.Time{}, nil, nil, nil, int64(1), updatedAt))
+	mock.ExpectQuery(regexp.QuoteMeta(selectReminders)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(reminderColumnNames))
+	mock.ExpectQuery(regexp.QuoteMeta(selectAttendees)).WithArgs(id).
+		WillReturnRows(pgxmock.NewRows(attendeeColumnNames))
+	mock.ExpectRollback()
+
+	err = storage.DeleteEventOccurrence(ctx, testUserID, id, 1, date.AddDate(0, 0, 1), models.ScopeFollowing)
+	require.ErrorIs(t, err, customerror.CustomError{
+		Field:   "occurrence",
+		Message: "date is not an occurrence of the event: 2000-01-03T10:00:00Z",
+		Err:     customerror.ErrValidation,
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStorageGetUserEventsByPeriod(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 7, 31, 0, 0, 0, 0, time.UTC)
+	userID := 1
+
 	ctx := context.Background()
 
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	columns := []string{"id", "title", "date", "duration", "description", "user_id", "notification_interval"}
 	expectedRows := pgxmock.NewRows(columns).
-		AddRow("1", "Event 1", date, time.Hour, "Description 1", 1, time.Hour).
-		AddRow("2", "Event 2", date.AddDate(0, 0, 1), 2*time.Hour, "Description 2", 2, 2*time.Hour).
-		AddRow("3", "Event 3", date.AddDate(0, 0, 29), 3*time.Hour, "Description 3", 3, 3*time.Hour)
+		AddRow("1", "Finished series", from.AddDate(0, -1, 0), time.Hour, "", userID, time.Hour,
+			"FREQ=DAILY;COUNT=3", []time.Time{}, nil, nil, nil, int64(1), updatedAt).
+		AddRow("2", "Series", from.AddDate(0, -1, 0), time.Hour, "", userID, time.Hour,
+			"FREQ=WEEKLY", []time.Time{}, nil, nil, nil, int64(1), updatedAt).
+		AddRow("3", "Event", from.AddDate(0, 0, 2), time.Hour, "", userID, time.Hour,
+			nil, nil, nil, nil, nil, int64(1), updatedAt)
 
-	queryGetByMonth := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM %s
-		WHERE date BETWEEN $1 AND $1 + INTERVAL '29 days'`, eventsTable)
-	mock.ExpectQuery(regexp.QuoteMeta(queryGetByMonth)).WithArgs(date).WillReturnRows(expectedRows)
-
-	actualEvents, err := storage.GetAllByMonthEvents(ctx, date)
+		WHERE user_id = $1
+			AND (date BETWEEN $2 AND $3 OR (recurrence_rule IS NOT NULL AND date <= $3
+				AND (recurrence_end IS NULL OR recurrence_end >= $2)))
+		ORDER BY date`, eventColumns, eventsTable)
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(userID, from, to).WillReturnRows(expectedRows)
+	mock.ExpectQuery(regexp.QuoteMeta(selectEventsReminders)).WithArgs([]string{"2", "3"}).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, reminderColumnNames...)))
+
+	actualEvents, err := storage.GetUserEventsByPeriod(ctx, userID, from, to)
 	require.NoError(t, err)
-	require.Len(t, actualEvents, 3)
-	require.ElementsMatch(t, expectedEvents, actualEvents)
+	require.Len(t, actualEvents, 2)
+	require.Equal(t, "2", actualEvents[0].ID)
+	require.Equal(t, models.FrequencyWeekly, actualEvents[0].Recurrence.Frequency)
+	require.Equal(t, "3", actualEvents[1].ID)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
-func TestStorageGetAllByMonthEventsEmpty(t *testing.T) {
+func TestRecurrenceEndArg(t *testing.T) {
+	date := time.Date(2023, 3, 25, 10, 0, 0, 0, time.UTC)
+	event := models.Event{Date: date}
+	require.Equal(t, sql.NullTime{}, recurrenceEndArg(event))
+
+	event.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+	require.Equal(t, sql.NullTime{}, recurrenceEndArg(event), "endless series")
+
+	event.Recurrence.Count = 3
+	require.Equal(t, sql.NullTime{Time: date.AddDate(0, 0, 2), Valid: true}, recurrenceEndArg(event))
+
+	event.Recurrence.Count = 0
+	event.Recurrence.Until = date.AddDate(0, 0, 4).Add(time.Hour)
+	require.Equal(t, sql.NullTime{Time: date.AddDate(0, 0, 4), Valid: true}, recurrenceEndArg(event))
+}
+
+func TestStorageGetIntersectingEvents(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
 
-	date := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
-
-	var expectedEvents []models.Event
+	from := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
 
 	ctx := context.Background()
 
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	columns := []string{"id", "title", "date", "duration", "description", "user_id", "notification_interval"}
-	expectedRows := pgxmock.NewRows(columns)
+	expectedRows := pgxmock.NewRows(columns).
+		AddRow("1", "Series", from.AddDate(0, 0, -7).Add(30*time.Minute), time.Hour, "", testUserID, time.Hour,
+			"FREQ=WEEKLY", []time.Time{}, nil, nil, nil, int64(1), updatedAt).
+		AddRow("2", "Daily series", from.AddDate(0, 0, -7).Add(2*time.Hour), time.Hour, "", testUserID, time.Hour,
+			"FREQ=DAILY", []time.Time{}, nil, nil, nil, int64(1), updatedAt).
+		AddRow("3", "Event", from.Add(-30*time.Minute), time.Hour, "", testUserID, time.Hour,
+			nil, nil, nil, nil, nil, int64(1), updatedAt)
 
-	queryGetByMonth := fmt.Sprintf(`
-		SELECT id, title, date, duration, description, user_id, notification_interval
-		FROM %s
-		WHERE date BETWEEN $1 AND $1 + INTERVAL '29 days'`, eventsTable)
-	mock.ExpectQuery(regexp.QuoteMeta(queryGetByMonth)).WithArgs(date).WillReturnRows(expectedRows)
+	mock.ExpectQuery(regexp.QuoteMeta(selectIntersecting)).WithArgs(testUserID, from, to, "accepted").
+		WillReturnRows(expectedRows)
 
-	actualEvents, err := storage.GetAllByMonthEvents(ctx, date)
+	actualEvents, err := storage.GetIntersectingEvents(ctx, testUserID, from, to)
 	require.NoError(t, err)
-	require.Len(t, actualEvents, 0)
-	require.ElementsMatch(t, expectedEvents, actualEvents)
+	require.Len(t, actualEvents, 2)
+	require.Equal(t, "1", actualEvents[0].ID)
+	require.Equal(t, from.Add(30*time.Minute), actualEvents[0].Date)
+	require.Equal(t, "3", actualEvents[1].ID)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
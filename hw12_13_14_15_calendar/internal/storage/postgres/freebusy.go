@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/freebusy"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+// GetFreeBusy loads events of all users with one query, the owned events are found by the index
+// on user_id and date, the events the users are invited to by the index on attendees.
+func (s *Storage) GetFreeBusy(ctx context.Context, userIDs []int, from, to time.Time) ([]models.FreeBusy, error) {
+	query := fmt.Sprintf(`
+		SELECT busy.busy_user_id, %[1]s
+		FROM %[2]s
+		JOIN (
+			SELECT id AS event_id, user_id AS busy_user_id FROM %[2]s WHERE user_id = ANY($1) AND date < $3
+			UNION
+			SELECT event_id, user_id FROM %[3]s WHERE user_id = ANY($1) AND status <> $4
+		) AS busy ON busy.event_id = %[2]s.id
+		WHERE date < $3 AND (date + duration > $2
+			OR (recurrence_rule IS NOT NULL AND (recurrence_end IS NULL OR recurrence_end + duration > $2)))`,
+		eventColumns, eventsTable, attendeesTable)
+
+	rows, err := s.db.Query(ctx, query, userIDs, from, to, string(models.AttendeeDeclined))
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	events := make(map[int][]models.Event, len(userIDs))
+
+	for rows.Next() {
+		var userID int
+		event, err := scanEvent(busyRow{Row: rows, userID: &userID})
+		if err != nil {
+			return nil, dbError(err)
+		}
+		events[userID] = append(events[userID], event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	calendars := make([]models.FreeBusy, 0, len(userIDs))
+	for _, userID := range userIDs {
+		calendars = append(calendars, models.FreeBusy{
+			UserID: userID,
+			Busy:   freebusy.Busy(events[userID], from, to),
+		})
+	}
+
+	return calendars, nil
+}
+
+// busyRow scans the id of the busy user before the columns of the event.
+type busyRow struct {
+	pgx.Row
+	userID *int
+}
+
+func (r busyRow) Scan(dest ...interface{}) error {
+	return r.Row.Scan(append([]interface{}{r.userID}, dest...)...)
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageGetFreeBusy(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC)
+
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	query := fmt.Sprintf(`
+		SELECT busy.busy_user_id, %[1]s
+		FROM %[2]s
+		JOIN (
+			SELECT id AS event_id, user_id AS busy_user_id FROM %[2]s WHERE user_id = ANY($1) AND date < $3
+			UNION
+			SELECT event_id, user_id FROM %[3]s WHERE user_id = ANY($1) AND status <> $4
+		) AS busy ON busy.event_id = %[2]s.id
+		WHERE date < $3 AND (date + duration > $2
+			OR (recurrence_rule IS NOT NULL AND (recurrence_end IS NULL OR recurrence_end + duration > $2)))`,
+		eventColumns, eventsTable, attendeesTable)
+
+	// the event of the first user is attended by the second one
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs([]int{testUserID, 2, 3}, from, to, "declined").
+		WillReturnRows(pgxmock.NewRows(append([]string{"busy_user_id"}, columns...)).
+			AddRow(testUserID, "1", "Event 1", from.Add(9*time.Hour), time.Hour, "", testUserID, time.Duration(0),
+				nil, nil, nil, nil, nil, int64(1), updatedAt).
+			AddRow(2, "1", "Event 1", from.Add(9*time.Hour), time.Hour, "", testUserID, time.Duration(0),
+				nil, nil, nil, nil, nil, int64(1), updatedAt).
+			AddRow(2, "2", "Event 2", from.Add(-24*time.Hour+9*time.Hour+30*time.Minute), time.Hour, "", 2, time.Duration(0),
+				"FREQ=DAILY", nil, nil, nil, nil, int64(1), updatedAt))
+
+	calendars, err := storage.GetFreeBusy(ctx, []int{testUserID, 2, 3}, from, to)
+	require.NoError(t, err)
+	require.Equal(t, []models.FreeBusy{
+		{UserID: testUserID, Busy: []models.Interval{
+			{Start: from.Add(9 * time.Hour), End: from.Add(10 * time.Hour)},
+		}},
+		{UserID: 2, Busy: []models.Interval{
+			{Start: from.Add(9 * time.Hour), End: from.Add(10*time.Hour + 30*time.Minute)},
+			{Start: from.Add(33*time.Hour + 30*time.Minute), End: from.Add(34*time.Hour + 30*time.Minute)},
+		}},
+		{UserID: 3},
+	}, calendars)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
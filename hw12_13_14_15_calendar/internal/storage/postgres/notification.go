@@ -2,82 +2,285 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"time"
+	"sort"
+	"strconv"
 
+	"github.com/jackc/pgx/v5"
 	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
 )
 
-func (s *Storage) GetNotificationsInAdvance(ctx context.Context) ([]models.Notification, error) {
-	var notifications []models.Notification
+// ClaimNotifications moves reminders of recurring events to the occurrences they are due for and leases
+// due reminders in one transaction. Rows locked by concurrent claims are skipped rather than waited for,
+// so several schedulers can claim in parallel.
+func (s *Storage) ClaimNotifications(ctx context.Context, claim models.NotificationClaim) ([]models.Notification, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
 
-	selectNotifications := fmt.Sprintf(`
-		SELECT id, title, date, user_id, notification_interval
-		FROM %s
-		WHERE scheduled = false AND $1::timestamp <= date - notification_interval
-		ORDER BY (date - notification_interval)
-		LIMIT 10;`, eventsTable)
+	if err := advanceReminders(ctx, tx, claim); err != nil {
+		return nil, dbError(err)
+	}
 
-	nw := time.Now().UTC()
+	query := fmt.Sprintf(`
+		WITH due AS (
+			SELECT r.id
+			FROM %[1]s r
+			WHERE r.status = $1
+				AND (r.leased_until IS NULL OR r.leased_until <= $2)
+				AND r.occurrence - r.remind_before BETWEEN $3::timestamptz AND $4::timestamptz
+			ORDER BY r.occurrence - r.remind_before
+			LIMIT $5
+			FOR UPDATE OF r SKIP LOCKED
+		)
+		UPDATE %[1]s r
+		SET leased_until = $6
+		FROM due, %[2]s e
+		WHERE r.id = due.id AND e.id = r.event_id
+		RETURNING r.id, r.channel, e.id, e.title, r.occurrence, e.user_id, r.remind_before`, remindersTable, eventsTable)
 
-	rows, err := s.db.Query(ctx, selectNotifications, nw)
+	rows, err := tx.Query(ctx, query,
+		string(models.StatusPending),
+		claim.Now,
+		claim.From().UTC(),
+		claim.To().UTC(),
+		claim.Limit,
+		claim.Now.Add(claim.Lease))
 	if err != nil {
-		return nil, customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
-		}
+		return nil, dbError(err)
 	}
 
+	defer rows.Close()
+
+	var notifications []models.Notification
+
 	for rows.Next() {
-		var notification models.Notification
+		var (
+			notification models.Notification
+			channel      string
+		)
 
 		err = rows.Scan(
+			&notification.ReminderID,
+			&channel,
 			&notification.EventID,
 			&notification.Title,
 			&notification.Date,
 			&notification.UserID,
 			&notification.Interval)
 		if err != nil {
-			return nil, customerror.CustomError{
-				Field:   "",
-				Message: err.Error(),
-			}
+			return nil, dbError(err)
 		}
+		notification.Channel = models.ReminderChannel(channel)
 
 		notifications = append(notifications, notification)
 	}
 
-	if rows.Err() != nil {
-		return nil, customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
-		}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, dbError(err)
+	}
+
+	if err := s.attachAcceptedAttendees(ctx, notifications); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the selected rows
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].NotifyAt().Before(notifications[j].NotifyAt())
+	})
+
 	return notifications, nil
 }
 
-func (s *Storage) UpdateScheduledNotification(ctx context.Context, id string) error {
-	updateNotifications := fmt.Sprintf(`
+// advanceReminders moves sent and overdue reminders of recurring events to the occurrences they are due for.
+// Reminders of occurrences removed from the series are moved too, reminders without occurrences left
+// get NULL occurrence and are not claimed anymore.
+func advanceReminders(ctx context.Context, tx pgx.Tx, claim models.NotificationClaim) error {
+	query := fmt.Sprintf(`
+		SELECT event_id, %s
+		FROM %s
+		WHERE event_id IN (SELECT id FROM %s WHERE recurrence_rule IS NOT NULL)
+			AND occurrence IS NOT NULL
+			AND (leased_until IS NULL OR leased_until <= $1)
+			AND (status = $2 OR (status = $3 AND occurrence - remind_before <= $4::timestamptz))
+		FOR UPDATE SKIP LOCKED`, reminderColumns, remindersTable, eventsTable)
+
+	rows, err := tx.Query(ctx, query, claim.Now, string(models.StatusSent), string(models.StatusPending), claim.To().UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	reminders := make(map[string][]models.Reminder)
+	var ids []string
+
+	for rows.Next() {
+		var eventID string
+		reminder, err := scanReminder(rows, &eventID)
+		if err != nil {
+			return err
+		}
+		if _, ok := reminders[eventID]; !ok {
+			ids = append(ids, eventID)
+		}
+		reminders[eventID] = append(reminders[eventID], reminder)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	series, err := queryEventsByIDs(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	update := fmt.Sprintf(`
+		UPDATE %s SET status = $1, queued_at = NULL, sent_at = NULL, occurrence = $2
+		WHERE id = $3`, remindersTable)
+
+	for _, event := range series {
+		for _, reminder := range reminders[event.ID] {
+			occurrence, ok := recurrence.ReminderOccurrence(event, reminder, claim.From())
+			if reminder.Status == models.StatusPending && occurrence.Equal(reminder.Occurrence) {
+				continue
+			}
+
+			_, err := tx.Exec(ctx, update, string(models.StatusPending), sql.NullTime{Time: occurrence, Valid: ok}, reminder.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// queryEventsByIDs returns the events with the given ids.
+func queryEventsByIDs(ctx context.Context, tx pgx.Tx, ids []string) ([]models.Event, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ANY($1)`, eventColumns, eventsTable)
+
+	rows, err := tx.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// attachAcceptedAttendees loads ids of the attendees who have accepted the invitations to the events
+// of the notifications with one query.
+func (s *Storage) attachAcceptedAttendees(ctx context.Context, notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(notifications))
+	for _, notification := range notifications {
+		ids = append(ids, notification.EventID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT event_id, user_id
+		FROM %s
+		WHERE event_id = ANY($1) AND status = $2
+		ORDER BY user_id`, attendeesTable)
+
+	rows, err := s.db.Query(ctx, query, ids, string(models.AttendeeAccepted))
+	if err != nil {
+		return dbError(err)
+	}
+	defer rows.Close()
+
+	accepted := make(map[string][]int)
+
+	for rows.Next() {
+		var (
+			eventID string
+			userID  int
+		)
+		if err := rows.Scan(&eventID, &userID); err != nil {
+			return dbError(err)
+		}
+		accepted[eventID] = append(accepted[eventID], userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return dbError(err)
+	}
+
+	for i := range notifications {
+		notifications[i].Attendees = accepted[notifications[i].EventID]
+	}
+
+	return nil
+}
+
+func (s *Storage) ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return dbError(err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	updateReminder := fmt.Sprintf(`
 		UPDATE %s
-		SET scheduled = true 
-		WHERE id = $1`, eventsTable)
+		SET status = $1, queued_at = now(), leased_until = NULL
+		WHERE id = $2 AND status = $3
+		RETURNING event_id`, remindersTable)
 
-	ct, err := s.db.Exec(ctx, updateNotifications, id)
+	var eventID string
+
+	err = tx.QueryRow(ctx, updateReminder, string(models.StatusQueued), reminderID, string(models.StatusPending)).
+		Scan(&eventID)
 	if err != nil {
-		return customerror.CustomError{
-			Field:   "",
-			Message: err.Error(),
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customerror.CustomError{
+				Field:   "reminder_id",
+				Message: "notification wasn't updated with reminder id: " + strconv.FormatInt(reminderID, 10),
+				Err:     customerror.ErrNotFound,
+			}
 		}
+		return dbError(err)
 	}
 
-	if ct.RowsAffected() == 0 {
-		return customerror.CustomError{
-			Field:   "id",
-			Message: "notification wasn't updated with id: " + id,
+	insertOutbox := fmt.Sprintf(`
+		INSERT INTO %s (event_id, reminder_id, payload)
+		VALUES ($1, $2, $3)`, outboxTable)
+
+	for _, payload := range payloads {
+		if _, err := tx.Exec(ctx, insertOutbox, eventID, reminderID, payload); err != nil {
+			return dbError(err)
 		}
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return dbError(err)
+	}
+
 	return nil
 }
@@ -2,62 +2,199 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v2"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
 	"github.com/stretchr/testify/require"
 )
 
-func TestStorageUpdateScheduledNotification(t *testing.T) {
+var queryQueueReminder = fmt.Sprintf(`
+		UPDATE %s
+		SET status = $1, queued_at = now(), leased_until = NULL
+		WHERE id = $2 AND status = $3
+		RETURNING event_id`, remindersTable)
+
+func TestStorageClaimNotifications(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
 
-	id := uuid.New().String()
-
 	ctx := context.Background()
 
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	query := fmt.Sprintf(`
-		UPDATE %s
-		SET scheduled = true 
-		WHERE id = $1`, eventsTable)
-
-	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(id).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	now := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	claim := models.NotificationClaim{
+		Now:    now,
+		Window: 10 * time.Second,
+		Grace:  time.Hour,
+		Lease:  30 * time.Second,
+		Limit:  100,
+	}
+
+	advance := fmt.Sprintf(`
+		SELECT event_id, %s
+		FROM %s
+		WHERE event_id IN (SELECT id FROM %s WHERE recurrence_rule IS NOT NULL)
+			AND occurrence IS NOT NULL
+			AND (leased_until IS NULL OR leased_until <= $1)
+			AND (status = $2 OR (status = $3 AND occurrence - remind_before <= $4::timestamptz))
+		FOR UPDATE SKIP LOCKED`, reminderColumns, remindersTable, eventsTable)
+
+	weekly := now.AddDate(0, 0, -7).Add(30 * time.Minute)
+	over := now.AddDate(0, 0, -2)
 
-	err = storage.UpdateScheduledNotification(ctx, id)
+	query := fmt.Sprintf(`
+		WITH due AS (
+			SELECT r.id
+			FROM %[1]s r
+			WHERE r.status = $1
+				AND (r.leased_until IS NULL OR r.leased_until <= $2)
+				AND r.occurrence - r.remind_before BETWEEN $3::timestamptz AND $4::timestamptz
+			ORDER BY r.occurrence - r.remind_before
+			LIMIT $5
+			FOR UPDATE OF r SKIP LOCKED
+		)
+		UPDATE %[1]s r
+		SET leased_until = $6
+		FROM due, %[2]s e
+		WHERE r.id = due.id AND e.id = r.event_id
+		RETURNING r.id, r.channel, e.id, e.title, r.occurrence, e.user_id, r.remind_before`, remindersTable, eventsTable)
+
+	// the reminder sent for the last week occurrence is moved to the next one,
+	// the reminder of the series without occurrences left is not claimed anymore
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(advance)).
+		WithArgs(now, "sent", "pending", now.Add(10*time.Second)).
+		WillReturnRows(pgxmock.NewRows(append([]string{"event_id"}, reminderColumnNames...)).
+			AddRow("id3", int64(3), 30*time.Minute, "log", "sent", weekly, weekly, weekly).
+			AddRow("id4", int64(4), time.Hour, "log", "sent", over, over, over))
+	mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT %s FROM %s WHERE id = ANY($1)`, eventColumns, eventsTable))).
+		WithArgs([]string{"id3", "id4"}).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow("id3", "weekly", weekly, time.Hour, "", 1, time.Duration(0), "FREQ=WEEKLY", []time.Time{},
+				nil, nil, nil, int64(1), updatedAt).
+			AddRow("id4", "over", over, time.Hour, "", 1, time.Duration(0), "FREQ=DAILY;COUNT=1", []time.Time{},
+				nil, nil, nil, int64(1), updatedAt))
+	update := regexp.QuoteMeta(fmt.Sprintf(`
+		UPDATE %s SET status = $1, queued_at = NULL, sent_at = NULL, occurrence = $2
+		WHERE id = $3`, remindersTable))
+	mock.ExpectExec(update).
+		WithArgs("pending", sql.NullTime{Time: weekly.AddDate(0, 0, 7), Valid: true}, int64(3)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(update).
+		WithArgs("pending", sql.NullTime{}, int64(4)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	// the overdue reminder is returned after the other one by the database
+	rows := pgxmock.NewRows([]string{"id", "channel", "id", "title", "occurrence", "user_id", "remind_before"}).
+		AddRow(int64(2), "email", "id2", "soon", now.Add(time.Hour), 1, time.Hour).
+		AddRow(int64(1), "log", "id1", "overdue", now.Add(time.Minute), 1, 5*time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs("pending", now, now.Add(-time.Hour), now.Add(10*time.Second), 100, now.Add(30*time.Second)).
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`
+		SELECT event_id, user_id
+		FROM %s
+		WHERE event_id = ANY($1) AND status = $2
+		ORDER BY user_id`, attendeesTable))).
+		WithArgs([]string{"id2", "id1"}, "accepted").
+		WillReturnRows(pgxmock.NewRows([]string{"event_id", "user_id"}).AddRow("id2", 2).AddRow("id2", 3))
+
+	notifications, err := storage.ClaimNotifications(ctx, claim)
 	require.NoError(t, err)
+	require.Equal(t, []models.Notification{
+		{
+			ReminderID: 1,
+			Channel:    models.ChannelLog,
+			EventID:    "id1",
+			Title:      "overdue",
+			Date:       now.Add(time.Minute),
+			UserID:     1,
+			Interval:   5 * time.Minute,
+		},
+		{
+			ReminderID: 2,
+			Channel:    models.ChannelEmail,
+			EventID:    "id2",
+			Title:      "soon",
+			Date:       now.Add(time.Hour),
+			UserID:     1,
+			Interval:   time.Hour,
+			Attendees:  []int{2, 3},
+		},
+	}, notifications)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
 
-func TestStorageUpdateScheduledNotificationError(t *testing.T) {
+func TestStorageScheduleNotification(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
 
 	id := uuid.New().String()
+	reminderID := int64(3)
+	payloads := [][]byte{
+		[]byte(`{"event_id":"` + id + `","reminder_id":3,"user_id":1}`),
+		[]byte(`{"event_id":"` + id + `","reminder_id":3,"user_id":2}`),
+	}
 
 	ctx := context.Background()
 
 	storage := NewStoragePostgres()
 	storage.db = mock
 
-	query := fmt.Sprintf(`
-		UPDATE %s
-		SET scheduled = true 
-		WHERE id = $1`, eventsTable)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(queryQueueReminder)).
+		WithArgs("queued", reminderID, "pending").
+		WillReturnRows(pgxmock.NewRows([]string{"event_id"}).AddRow(id))
+	// every recipient gets the own message
+	for _, payload := range payloads {
+		mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`
+		INSERT INTO %s (event_id, reminder_id, payload)
+		VALUES ($1, $2, $3)`, outboxTable))).
+			WithArgs(id, reminderID, payload).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+	mock.ExpectCommit()
+
+	err = storage.ScheduleNotification(ctx, reminderID, payloads...)
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStorageScheduleNotificationError(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
 
-	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(id).WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(queryQueueReminder)).
+		WithArgs("queued", int64(7), "pending").
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
 
-	err = storage.UpdateScheduledNotification(ctx, id)
-	expectedError := "notification wasn't updated with id: " + id
-	require.EqualError(t, err, expectedError)
+	err = storage.ScheduleNotification(ctx, 7, nil)
+	require.EqualError(t, err, "notification wasn't updated with reminder id: 7")
+	require.ErrorIs(t, err, customerror.ErrNotFound)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
 }
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+const outboxColumns = "id, event_id, COALESCE(reminder_id, 0), payload, attempts, next_attempt_at, last_error, created_at"
+
+func (s *Storage) ClaimOutboxMessages(ctx context.Context, claim models.OutboxClaim) ([]models.OutboxMessage, error) {
+	query := fmt.Sprintf(`
+		UPDATE %[1]s
+		SET leased_until = $2
+		WHERE id IN (
+			SELECT id
+			FROM %[1]s
+			WHERE next_attempt_at <= $1 AND (leased_until IS NULL OR leased_until <= $1)
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %[2]s`, outboxTable, outboxColumns)
+
+	rows, err := s.db.Query(ctx, query, claim.Now, claim.Now.Add(claim.Lease), claim.Limit)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	var messages []models.OutboxMessage
+
+	for rows.Next() {
+		var message models.OutboxMessage
+
+		err = rows.Scan(
+			&message.ID,
+			&message.EventID,
+			&message.ReminderID,
+			&message.Payload,
+			&message.Attempts,
+			&message.NextAttemptAt,
+			&message.LastError,
+			&message.CreatedAt)
+		if err != nil {
+			return nil, dbError(err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	// RETURNING does not keep the order of the selected rows
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].NextAttemptAt.Equal(messages[j].NextAttemptAt) {
+			return messages[i].ID < messages[j].ID
+		}
+		return messages[i].NextAttemptAt.Before(messages[j].NextAttemptAt)
+	})
+
+	return messages, nil
+}
+
+func (s *Storage) CompleteOutboxMessage(ctx context.Context, id int64) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return dbError(err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 RETURNING reminder_id, created_at`, outboxTable)
+
+	var (
+		reminderID sql.NullInt64
+		createdAt  time.Time
+	)
+
+	if err := tx.QueryRow(ctx, query, id).Scan(&reminderID, &createdAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return outboxNotFound(id)
+		}
+		return dbError(err)
+	}
+
+	if reminderID.Valid {
+		// the reminder is sent when the messages to all its recipients are published. The message is created
+		// in the transaction which queues the reminder, so the reminder queued again is not marked by it.
+		query = fmt.Sprintf(`
+		UPDATE %s SET status = $1, sent_at = now()
+		WHERE id = $2 AND status = $3 AND queued_at = $4
+			AND NOT EXISTS (SELECT 1 FROM %s WHERE reminder_id = $2)`, remindersTable, outboxTable)
+
+		_, err := tx.Exec(ctx, query, string(models.StatusSent), reminderID.Int64, string(models.StatusQueued), createdAt)
+		if err != nil {
+			return dbError(err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RetryOutboxMessage(ctx context.Context, id int64, next time.Time, lastErr string) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET attempts = attempts + 1, next_attempt_at = $1, last_error = $2, leased_until = NULL
+		WHERE id = $3`, outboxTable)
+
+	ct, err := s.db.Exec(ctx, query, next, lastErr, id)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if ct.RowsAffected() == 0 {
+		return outboxNotFound(id)
+	}
+
+	return nil
+}
+
+func outboxNotFound(id int64) error {
+	return customerror.CustomError{
+		Field:   "id",
+		Message: "no outbox message with id " + strconv.FormatInt(id, 10),
+		Err:     customerror.ErrNotFound,
+	}
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v2"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageClaimOutboxMessages(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	before := time.Date(2023, 7, 1, 10, 0, 0, 0, time.UTC)
+	created := before.Add(-time.Minute)
+
+	expected := []models.OutboxMessage{
+		{ID: 1, EventID: "id1", ReminderID: 4, Payload: []byte("1"), NextAttemptAt: created, CreatedAt: created},
+		{ID: 2, EventID: "id2", Payload: []byte("2"), Attempts: 2, NextAttemptAt: before, LastError: "nack", CreatedAt: created},
+	}
+
+	rows := pgxmock.NewRows([]string{
+		"id", "event_id", "reminder_id", "payload", "attempts", "next_attempt_at", "last_error", "created_at",
+	})
+	// the claimed rows are returned in any order
+	for i := len(expected) - 1; i >= 0; i-- {
+		m := expected[i]
+		rows.AddRow(m.ID, m.EventID, m.ReminderID, m.Payload, m.Attempts, m.NextAttemptAt, m.LastError, m.CreatedAt)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`
+		UPDATE %[1]s
+		SET leased_until = $2
+		WHERE id IN (
+			SELECT id
+			FROM %[1]s
+			WHERE next_attempt_at <= $1 AND (leased_until IS NULL OR leased_until <= $1)
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %[2]s`, outboxTable, outboxColumns))).
+		WithArgs(before, before.Add(time.Minute), 10).
+		WillReturnRows(rows)
+
+	messages, err := storage.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: before, Lease: time.Minute, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, expected, messages)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStorageCompleteOutboxMessage(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	query := regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM %s WHERE id = $1 RETURNING reminder_id, created_at`, outboxTable))
+	queuedAt := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"reminder_id", "created_at"}).
+			AddRow(sql.NullInt64{Int64: 4, Valid: true}, queuedAt))
+	// the reminder stays queued while messages to other recipients are in the outbox,
+	// the reminder queued again after the move of its event is not marked as sent
+	mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`
+		UPDATE %s SET status = $1, sent_at = now()
+		WHERE id = $2 AND status = $3 AND queued_at = $4
+			AND NOT EXISTS (SELECT 1 FROM %s WHERE reminder_id = $2)`, remindersTable, outboxTable))).
+		WithArgs("sent", int64(4), "queued", queuedAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	// the message has no reminder
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(int64(2)).
+		WillReturnRows(pgxmock.NewRows([]string{"reminder_id", "created_at"}).AddRow(sql.NullInt64{}, queuedAt))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(int64(3)).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	require.NoError(t, storage.CompleteOutboxMessage(ctx, 1))
+	require.NoError(t, storage.CompleteOutboxMessage(ctx, 2))
+
+	err = storage.CompleteOutboxMessage(ctx, 3)
+	require.EqualError(t, err, "no outbox message with id 3")
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
+
+func TestStorageRetryOutboxMessage(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ctx := context.Background()
+
+	storage := NewStoragePostgres()
+	storage.db = mock
+
+	next := time.Date(2023, 7, 1, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`
+		UPDATE %s
+		SET attempts = attempts + 1, next_attempt_at = $1, last_error = $2, leased_until = NULL
+		WHERE id = $3`, outboxTable))).
+		WithArgs(next, "nack", int64(1)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, storage.RetryOutboxMessage(ctx, 1, next, "nack"))
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/freebusy"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+)
+
+// lockOwner serializes the checked writes of the user's events until the end of the transaction,
+// so concurrent requests cannot both pass the overlap check. The lock is taken before the rows are locked.
+func lockOwner(ctx context.Context, tx pgx.Tx, userID int) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, userID); err != nil {
+		return dbError(err)
+	}
+	return nil
+}
+
+// checkOverlap returns the error if the owner of the event is busy at its time, the owner must be locked
+// by lockOwner. The events with ignoreIDs and the occurrences detached from them are skipped.
+func checkOverlap(ctx context.Context, tx pgx.Tx, event models.Event, ignoreIDs ...string) error {
+	intervals := freebusy.Occupied(event)
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	busy, err := intersectingEvents(ctx, tx, event.UserID, intervals[0].Start, intervals[len(intervals)-1].End)
+	if err != nil {
+		return dbError(err)
+	}
+
+	return freebusy.CheckOverlap(intervals, busy, ignoreIDs...)
+}
+
+// intersectingEvents returns the events and occurrences in [from, to) which the user owns
+// or has accepted the invitations to.
+func intersectingEvents(ctx context.Context, q querier, userID int, from, to time.Time) ([]models.Event, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE (user_id = $1 OR id IN (SELECT event_id FROM %s WHERE user_id = $1 AND status = $4))
+			AND date < $3 AND (date + duration > $2
+				OR (recurrence_rule IS NOT NULL AND (recurrence_end IS NULL OR recurrence_end + duration > $2)))`,
+		eventColumns, eventsTable, attendeesTable)
+
+	rows, err := q.Query(ctx, query, userID, from, to, string(models.AttendeeAccepted))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, occurrence := range recurrence.Expand(event, from.Add(-event.Duration), to) {
+			if occurrence.Date.Before(to) && occurrence.Date.Add(occurrence.Duration).After(from) {
+				events = append(events, occurrence)
+			}
+		}
+	}
+
+	return events, rows.Err()
+}
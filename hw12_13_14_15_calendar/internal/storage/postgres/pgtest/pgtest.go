@@ -0,0 +1,198 @@
+package pgtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DSNEnv is the environment variable with the connection string of the server
+// where a temporary database can be created.
+const DSNEnv = "CALENDAR_TEST_DB_DSN"
+
+// CIEnv is the environment variable set by CI. Postgres is required there, so tests
+// fail instead of being skipped when it is unavailable.
+const CIEnv = "CI"
+
+const startTimeout = 30 * time.Second
+
+var ErrUnavailable = errors.New("postgres is unavailable: set " + DSNEnv + " or add initdb and pg_ctl to PATH")
+
+// Server is the database used by tests.
+type Server struct {
+	DSN      string
+	dir      string
+	adminDSN string
+	database string
+}
+
+// Start creates a temporary database on the server from DSNEnv if it is set. Otherwise, it initializes
+// a temporary cluster with initdb and pg_ctl from PATH and starts it on a free local port.
+// Then it applies *.up.sql migrations from migrationsDir. Neither docker nor network access is required.
+// ErrUnavailable is returned if neither is possible, unless CIEnv is set.
+func Start(ctx context.Context, migrationsDir string) (*Server, error) {
+	if dsn := os.Getenv(DSNEnv); dsn != "" {
+		return startDatabase(ctx, dsn, migrationsDir)
+	}
+
+	for _, bin := range []string{"initdb", "pg_ctl"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			if os.Getenv(CIEnv) != "" {
+				return nil, fmt.Errorf("%s is set, but %s", CIEnv, ErrUnavailable.Error())
+			}
+			return nil, ErrUnavailable
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "calendar-pg-")
+	if err != nil {
+		return nil, err
+	}
+	srv := &Server{dir: dir}
+
+	port, err := freePort()
+	if err != nil {
+		srv.Stop()
+		return nil, err
+	}
+
+	data := filepath.Join(dir, "data")
+	if err := run(ctx, "initdb", "-D", data, "-U", "postgres", "-A", "trust", "--no-sync"); err != nil {
+		srv.Stop()
+		return nil, err
+	}
+
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	if err := run(ctx, "pg_ctl", "-D", data, "-o", options, "-l", filepath.Join(dir, "postgres.log"),
+		"-t", strconv.Itoa(int(startTimeout.Seconds())), "-w", "start"); err != nil {
+		srv.Stop()
+		return nil, err
+	}
+
+	srv.DSN = fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+
+	if err := migrate(ctx, srv.DSN, migrationsDir); err != nil {
+		srv.Stop()
+		return nil, err
+	}
+
+	return srv, nil
+}
+
+// startDatabase creates the database with a unique name on the server from adminDSN and migrates it,
+// so packages tested in parallel do not share tables.
+func startDatabase(ctx context.Context, adminDSN, migrationsDir string) (*Server, error) {
+	database := fmt.Sprintf("calendar_test_%d_%d", os.Getpid(), time.Now().UnixNano())
+
+	dsn, err := withDatabase(adminDSN, database)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pgx.Connect(ctx, adminDSN)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+database); err != nil {
+		return nil, err
+	}
+	srv := &Server{DSN: dsn, adminDSN: adminDSN, database: database}
+
+	if err := migrate(ctx, srv.DSN, migrationsDir); err != nil {
+		srv.Stop()
+		return nil, err
+	}
+
+	return srv, nil
+}
+
+// withDatabase returns dsn connecting to the given database. Both URL and keyword/value forms are supported.
+func withDatabase(dsn, database string) (string, error) {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		// the last keyword wins
+		return dsn + " dbname=" + database, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + database
+
+	return u.String(), nil
+}
+
+// Stop drops the temporary database or stops the temporary cluster and removes its files.
+func (s *Server) Stop() {
+	if s.database != "" {
+		ctx := context.Background()
+		if conn, err := pgx.Connect(ctx, s.adminDSN); err == nil {
+			_, _ = conn.Exec(ctx, "DROP DATABASE IF EXISTS "+s.database+" WITH (FORCE)")
+			_ = conn.Close(ctx)
+		}
+		return
+	}
+	if s.dir == "" {
+		return
+	}
+	_ = exec.Command("pg_ctl", "-D", filepath.Join(s.dir, "data"), "-m", "immediate", "stop").Run()
+	_ = os.RemoveAll(s.dir)
+}
+
+func migrate(ctx context.Context, dsn, migrationsDir string) error {
+	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.up.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	for _, file := range files {
+		query, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		// migrations without arguments are executed with the simple protocol,
+		// so a file can contain several statements
+		if _, err := conn.Exec(ctx, string(query)); err != nil {
+			return fmt.Errorf("applying %s: %w", filepath.Base(file), err)
+		}
+	}
+
+	return nil
+}
+
+func run(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, out)
+	}
+	return nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,215 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+)
+
+const reminderColumns = "id, remind_before, channel, status, queued_at, sent_at, occurrence"
+
+// querier is implemented by both the pool and transactions.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// queryReminders returns reminders of the event from the earliest one.
+func queryReminders(ctx context.Context, q querier, eventID string) ([]models.Reminder, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE event_id = $1
+		ORDER BY remind_before DESC`, reminderColumns, remindersTable)
+
+	rows, err := q.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []models.Reminder
+
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// attachReminders loads reminders of all events with one query.
+func (s *Storage) attachReminders(ctx context.Context, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := eventIDs(events)
+
+	query := fmt.Sprintf(`
+		SELECT event_id, %s
+		FROM %s
+		WHERE event_id = ANY($1)
+		ORDER BY remind_before DESC`, reminderColumns, remindersTable)
+
+	rows, err := s.db.Query(ctx, query, ids)
+	if err != nil {
+		return dbError(err)
+	}
+	defer rows.Close()
+
+	reminders := make(map[string][]models.Reminder)
+
+	for rows.Next() {
+		var eventID string
+		reminder, err := scanReminder(rows, &eventID)
+		if err != nil {
+			return dbError(err)
+		}
+		reminders[eventID] = append(reminders[eventID], reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return dbError(err)
+	}
+
+	for i := range events {
+		events[i].Reminders = reminders[events[i].ID]
+	}
+
+	return nil
+}
+
+// insertReminders stores reminders of the event which have no ids yet and returns all reminders with ids.
+// New reminders remind of the occurrence at the date.
+func insertReminders(ctx context.Context, tx pgx.Tx, eventID string, date time.Time, reminders []models.Reminder,
+) ([]models.Reminder, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (event_id, remind_before, channel, status, occurrence)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`, remindersTable)
+
+	result := make([]models.Reminder, 0, len(reminders))
+
+	for _, reminder := range reminders {
+		if reminder.ID == 0 {
+			err := tx.QueryRow(ctx, query, eventID, reminder.Before, string(reminder.Channel), string(reminder.Status), date).
+				Scan(&reminder.ID)
+			if err != nil {
+				return nil, err
+			}
+			reminder.Occurrence = date
+		}
+		result = append(result, reminder)
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// updateReminders applies the update to reminders of the updated event and returns them.
+// Reminders of the moved event are sent again, their unpublished messages are dropped.
+func updateReminders(ctx context.Context, tx pgx.Tx, event models.Event, update models.EventUpdate,
+) ([]models.Reminder, error) {
+	current, err := queryReminders(ctx, tx, event.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Reminders == nil && update.Date == nil && update.Recurrence == nil && !update.ClearRecurrence {
+		return current, nil
+	}
+
+	if update.Reminders != nil {
+		merged := models.MergeReminders(current, *update.Reminders)
+
+		var removed []int64
+		for _, reminder := range current {
+			if !containsReminder(merged, reminder.ID) {
+				removed = append(removed, reminder.ID)
+			}
+		}
+		if len(removed) > 0 {
+			query := fmt.Sprintf(`DELETE FROM %s WHERE id = ANY($1)`, remindersTable)
+			if _, err := tx.Exec(ctx, query, removed); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := insertReminders(ctx, tx, event.ID, event.Date, merged); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case update.Date != nil:
+		query := fmt.Sprintf(`
+		UPDATE %s SET status = $1, queued_at = NULL, sent_at = NULL, leased_until = NULL, occurrence = $2
+		WHERE event_id = $3`, remindersTable)
+		if _, err := tx.Exec(ctx, query, string(models.StatusPending), event.Date, event.ID); err != nil {
+			return nil, err
+		}
+
+		query = fmt.Sprintf(`DELETE FROM %s WHERE event_id = $1`, outboxTable)
+		if _, err := tx.Exec(ctx, query, event.ID); err != nil {
+			return nil, err
+		}
+	case update.ClearRecurrence:
+		// the single event has the only occurrence
+		query := fmt.Sprintf(`UPDATE %s SET occurrence = $1 WHERE event_id = $2`, remindersTable)
+		if _, err := tx.Exec(ctx, query, event.Date, event.ID); err != nil {
+			return nil, err
+		}
+	case update.Recurrence != nil:
+		// the changed series may have occurrences again, claims move reminders to them
+		query := fmt.Sprintf(`UPDATE %s SET occurrence = $1 WHERE event_id = $2 AND occurrence IS NULL`, remindersTable)
+		if _, err := tx.Exec(ctx, query, event.Date, event.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return queryReminders(ctx, tx, event.ID)
+}
+
+func containsReminder(reminders []models.Reminder, id int64) bool {
+	for _, reminder := range reminders {
+		if reminder.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// scanReminder scans reminderColumns preceded by prefix destinations.
+func scanReminder(row pgx.Row, prefix ...interface{}) (models.Reminder, error) {
+	var (
+		reminder   models.Reminder
+		channel    string
+		status     string
+		queuedAt   sql.NullTime
+		sentAt     sql.NullTime
+		occurrence sql.NullTime
+	)
+
+	dest := append(prefix, &reminder.ID, &reminder.Before, &channel, &status, &queuedAt, &sentAt, &occurrence)
+	if err := row.Scan(dest...); err != nil {
+		return models.Reminder{}, err
+	}
+
+	reminder.Channel = models.ReminderChannel(channel)
+	reminder.Status = models.ReminderStatus(status)
+	reminder.QueuedAt = queuedAt.Time
+	reminder.SentAt = sentAt.Time
+	reminder.Occurrence = occurrence.Time
+
+	return reminder, nil
+}
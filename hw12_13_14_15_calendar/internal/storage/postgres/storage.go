@@ -2,18 +2,30 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
 )
 
-const eventsTable = "events"
+const (
+	eventsTable    = "events"
+	remindersTable = "reminders"
+	outboxTable    = "outbox"
+	attendeesTable = "attendees"
+)
+
+const uniqueViolation = "23505"
 
 type PgxIface interface {
 	Close()
+	Ping(ctx context.Context) error
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
@@ -44,6 +56,18 @@ func NewStoragePostgres() *Storage {
 }
 
 func (s *Storage) Connect(ctx context.Context, cfg Config) error {
+	db, err := NewPool(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	s.db = db
+
+	return nil
+}
+
+// NewPool connects to the database and checks the connection.
+func NewPool(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
 	connString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
 		cfg.Username,
 		cfg.Password,
@@ -55,27 +79,32 @@ func (s *Storage) Connect(ctx context.Context, cfg Config) error {
 
 	conf, err := pgxpool.ParseConfig(connString)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	conf.MaxConns = int32(cfg.MaxConns)
 	conf.MinConns = int32(cfg.MinConns)
 	conf.MaxConnLifetime = cfg.MaxConnLifetime
 	conf.MaxConnIdleTime = cfg.MaxConnIdleTime
+	conf.ConnConfig.Tracer = queryTracer{}
 
 	db, err := pgxpool.NewWithConfig(ctx, conf)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = db.Ping(ctx)
 	if err != nil {
-		return err
+		db.Close()
+		return nil, err
 	}
 
-	s.db = db
+	return db, nil
+}
 
-	return nil
+// Ping checks the connection to the database.
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
 }
 
 func (s *Storage) Close() {
@@ -84,3 +113,36 @@ func (s *Storage) Close() {
 	}
 	s.db.Close()
 }
+
+// dbError returns the error of the database with the sentinel of its kind: duplicates are conflicts,
+// lost connections and shutdowns make the storage unavailable, other errors are internal.
+func dbError(err error) error {
+	var (
+		sentinel error
+		pgErr    *pgconn.PgError
+		netErr   net.Error
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		sentinel = context.Canceled
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		sentinel = context.DeadlineExceeded
+	case errors.As(err, &pgErr):
+		switch {
+		case pgErr.Code == uniqueViolation:
+			sentinel = customerror.ErrConflict
+		// connection exceptions, shutdowns and too many connections
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "57P"), pgErr.Code == "53300":
+			sentinel = customerror.ErrUnavailable
+		}
+	case pgconn.SafeToRetry(err), errors.As(err, &netErr):
+		sentinel = customerror.ErrUnavailable
+	}
+
+	return customerror.CustomError{
+		Field:   "",
+		Message: err.Error(),
+		Err:     sentinel,
+	}
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v2"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/postgres/pgtest"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// testDSN is the connection string of the real database, empty if it is unavailable.
+var testDSN string
+
+func TestMain(m *testing.M) {
+	srv, err := pgtest.Start(context.Background(), "../../../migrations")
+	switch {
+	case err == nil:
+		testDSN = srv.DSN
+	case !errors.Is(err, pgtest.ErrUnavailable):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	if srv != nil {
+		srv.Stop()
+	}
+	os.Exit(code)
+}
+
+func TestStorageConformance(t *testing.T) {
+	storagetest.Run(t, newTestStorage)
+}
+
+// newTestStorage connects to the real database and removes all events.
+func newTestStorage(t *testing.T) storage.Storage {
+	t.Helper()
+
+	if testDSN == "" {
+		t.Skip(pgtest.ErrUnavailable.Error())
+	}
+
+	ctx := context.Background()
+
+	db, err := pgxpool.New(ctx, testDSN)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+
+	_, err = db.Exec(ctx, "TRUNCATE "+eventsTable+", "+remindersTable+", "+outboxTable+" CASCADE")
+	require.NoError(t, err)
+
+	return &Storage{db: db}
+}
+
+func TestDBError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "duplicate",
+			err:      &pgconn.PgError{Code: uniqueViolation, Message: "duplicate key value violates unique constraint"},
+			expected: customerror.ErrConflict,
+		},
+		{
+			name:     "shutdown",
+			err:      &pgconn.PgError{Code: "57P01", Message: "terminating connection due to administrator command"},
+			expected: customerror.ErrUnavailable,
+		},
+		{
+			name:     "refused",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			expected: customerror.ErrUnavailable,
+		},
+		{
+			name:     "deadline",
+			err:      fmt.Errorf("query: %w", context.DeadlineExceeded),
+			expected: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := dbError(tc.err)
+			require.ErrorIs(t, err, tc.expected)
+			require.EqualError(t, err, tc.err.Error())
+		})
+	}
+
+	err := dbError(&pgconn.PgError{Code: "42601", Message: "syntax error"})
+	require.Equal(t, customerror.KindInternal, customerror.KindOf(err))
+}
+
+func TestStoragePing(t *testing.T) {
+	mock, err := pgxmock.NewPool(pgxmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer mock.Close()
+
+	errPing := errors.New("connection refused")
+	mock.ExpectPing()
+	mock.ExpectPing().WillReturnError(errPing)
+
+	s := &Storage{db: mock}
+	require.NoError(t, s.Ping(context.Background()))
+	require.ErrorIs(t, s.Ping(context.Background()), errPing)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "there was unexpected result")
+}
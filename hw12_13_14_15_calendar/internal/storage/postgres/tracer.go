@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/metrics"
+)
+
+var (
+	queryDuration = metrics.NewHistogram("calendar_storage_query_duration_seconds",
+		"Latency of database queries by operation.", metrics.DefBuckets, "operation")
+	queryErrors = metrics.NewCounter("calendar_storage_query_errors_total",
+		"Failed database queries by operation.", "operation")
+)
+
+// queryTracer measures queries of all connections of the pool.
+type queryTracer struct{}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	operation string
+	at        time.Time
+}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{operation: queryOperation(data.SQL), at: time.Now()})
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	queryDuration.Observe(time.Since(start.at).Seconds(), start.operation)
+	if data.Err != nil {
+		queryErrors.Inc(start.operation)
+	}
+}
+
+// queryOperation returns the first keyword of the query in lower case, e.g. select or with.
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(fields[0])
+}
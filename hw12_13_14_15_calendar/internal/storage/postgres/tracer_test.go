@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTracer(t *testing.T) {
+	tracer := queryTracer{}
+
+	observed := queryDuration.Count("update")
+	failed := queryErrors.Value("update")
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "\n\t\tUPDATE events SET"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "update events SET"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("connection is closed")})
+
+	require.Equal(t, observed+2, queryDuration.Count("update"))
+	require.Equal(t, failed+1, queryErrors.Value("update"))
+
+	require.Equal(t, "unknown", queryOperation(" "))
+}
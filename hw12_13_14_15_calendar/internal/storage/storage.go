@@ -7,22 +7,59 @@ import (
 	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
 )
 
+// EventStorage keeps events. Unless opts allow overlaps, CreateEvent, UpdateEvent and UpdateEventOccurrence
+// fail with ErrDateBusy when the owner is busy at the time of the written event: in other events of the owner
+// or in the events the owner has accepted the invitations to. The check and the write are atomic.
 type EventStorage interface {
-	CreateEvent(ctx context.Context, event models.Event) (string, error)
-	UpdateEvent(ctx context.Context, id string, event models.Event) (models.Event, error)
-	DeleteEvent(ctx context.Context, id string) error
+	CreateEvent(ctx context.Context, event models.Event, opts models.EventOptions) (string, error)
+	UpdateEvent(ctx context.Context, userID int, id string, version int64, update models.EventUpdate,
+		opts models.EventOptions) (models.Event, error)
+	DeleteEvent(ctx context.Context, userID int, id string, version int64) error
+	UpdateEventOccurrence(ctx context.Context, userID int, id string, version int64, occurrence time.Time,
+		scope models.RecurrenceScope, newID string, update models.EventUpdate, opts models.EventOptions) (models.Event, error)
+	DeleteEventOccurrence(ctx context.Context, userID int, id string, version int64, occurrence time.Time,
+		scope models.RecurrenceScope) error
 	DeleteOutdatedEvents(ctx context.Context) error
-	GetAllByDayEvents(ctx context.Context, date time.Time) ([]models.Event, error)
-	GetAllByWeekEvents(ctx context.Context, date time.Time) ([]models.Event, error)
-	GetAllByMonthEvents(ctx context.Context, date time.Time) ([]models.Event, error)
+	GetEventsInRange(ctx context.Context, userID int, rng models.EventRange) (models.EventPage, error)
+	GetUserEventsByPeriod(ctx context.Context, userID int, from, to time.Time) ([]models.Event, error)
+	GetEventByID(ctx context.Context, id string) (models.Event, error)
+	// GetIntersectingEvents returns the events and occurrences which take place in [from, to) in which the user
+	// is busy: the user's events and the events the user has accepted the invitations to.
+	GetIntersectingEvents(ctx context.Context, userID int, from, to time.Time) ([]models.Event, error)
+	// InviteAttendees invites the users to the owner's event of the given version, users who are already
+	// invited keep their answers.
+	InviteAttendees(ctx context.Context, userID int, id string, version int64, attendees []int) (models.Event, error)
+	// RespondToInvitation records the answer of the attendee of the event.
+	RespondToInvitation(ctx context.Context, userID int, id string, status models.AttendeeStatus) (models.Event, error)
+	// GetFreeBusy returns the busy time in [from, to) of every user in the order of userIDs. Users are busy
+	// in their events and in the events they are invited to and have not declined.
+	GetFreeBusy(ctx context.Context, userIDs []int, from, to time.Time) ([]models.FreeBusy, error)
 }
 
 type NotificationStorage interface {
-	UpdateScheduledNotification(ctx context.Context, id string) error
-	GetNotificationsInAdvance(ctx context.Context) ([]models.Notification, error)
+	// ScheduleNotification marks the pending reminder as queued and puts payloads to the outbox atomically,
+	// one message per recipient.
+	ScheduleNotification(ctx context.Context, reminderID int64, payloads ...[]byte) error
+	// ClaimNotifications leases up to claim.Limit pending reminders due in the window of the claim
+	// and returns them sorted by the due time. Reminders leased by other claims are skipped.
+	// Reminders of recurring events are due for every occurrence in turn.
+	ClaimNotifications(ctx context.Context, claim models.NotificationClaim) ([]models.Notification, error)
+}
+
+// OutboxStorage keeps notifications until they are published to the message queue.
+type OutboxStorage interface {
+	// ClaimOutboxMessages leases up to claim.Limit messages due at claim.Now and returns them sorted
+	// by the due time. Messages leased by other claims are skipped, so relays can share the outbox.
+	ClaimOutboxMessages(ctx context.Context, claim models.OutboxClaim) ([]models.OutboxMessage, error)
+	// CompleteOutboxMessage deletes the published message and marks its reminder as sent
+	// when no other messages of the reminder are left.
+	CompleteOutboxMessage(ctx context.Context, id int64) error
+	// RetryOutboxMessage postpones the message until next, records the reason of the failure and releases the lease.
+	RetryOutboxMessage(ctx context.Context, id int64, next time.Time, lastErr string) error
 }
 
 type Storage interface {
 	EventStorage
 	NotificationStorage
+	OutboxStorage
 }
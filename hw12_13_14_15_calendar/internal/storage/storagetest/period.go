@@ -0,0 +1,118 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/period"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	userID      = 1
+	otherUserID = 2
+)
+
+// RunPeriodTests checks that the storage returns the same events of calendar days, weeks and months
+// as the other implementations. newStorage must return the empty storage.
+func RunPeriodTests(t *testing.T, newStorage func(t *testing.T) storage.EventStorage) {
+	t.Helper()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 2023-03-12 is the 23 hours sunday in New York.
+	events := map[string]time.Time{
+		"saturday night": time.Date(2023, 3, 11, 23, 30, 0, 0, newYork),
+		"sunday start":   time.Date(2023, 3, 12, 0, 0, 0, 0, newYork),
+		"sunday end":     time.Date(2023, 3, 12, 23, 59, 0, 0, newYork),
+		"monday start":   time.Date(2023, 3, 13, 0, 0, 0, 0, newYork),
+		"month end":      time.Date(2023, 3, 31, 23, 0, 0, 0, newYork),
+		"next month":     time.Date(2023, 4, 1, 0, 0, 0, 0, newYork),
+	}
+	date := time.Date(2023, 3, 12, 12, 0, 0, 0, newYork)
+
+	testCases := []struct {
+		name     string
+		bounds   func() (time.Time, time.Time)
+		expected []string
+	}{
+		{
+			name: "day in time zone",
+			bounds: func() (time.Time, time.Time) {
+				return period.Day(date, newYork)
+			},
+			expected: []string{"sunday start", "sunday end"},
+		},
+		{
+			name: "day in utc",
+			bounds: func() (time.Time, time.Time) {
+				return period.Day(date, time.UTC)
+			},
+			expected: []string{"saturday night", "sunday start"},
+		},
+		{
+			name: "iso week",
+			bounds: func() (time.Time, time.Time) {
+				return period.Week(date, newYork, time.Monday)
+			},
+			expected: []string{"saturday night", "sunday start", "sunday end"},
+		},
+		{
+			name: "week starting on sunday",
+			bounds: func() (time.Time, time.Time) {
+				return period.Week(date, newYork, time.Sunday)
+			},
+			expected: []string{"sunday start", "sunday end", "monday start"},
+		},
+		{
+			name: "month",
+			bounds: func() (time.Time, time.Time) {
+				return period.Month(date, newYork)
+			},
+			expected: []string{"saturday night", "sunday start", "sunday end", "monday start", "month end"},
+		},
+	}
+
+	ctx := context.Background()
+	st := newStorage(t)
+
+	titles := make(map[string]string, len(events))
+	for title, eventDate := range events {
+		for _, user := range []int{userID, otherUserID} {
+			event := models.Event{
+				ID:       uuid.New().String(),
+				Title:    title,
+				Date:     eventDate,
+				Duration: time.Minute,
+				UserID:   user,
+			}
+			_, err := st.CreateEvent(ctx, event, allowOverlap)
+			require.NoError(t, err)
+
+			if user == userID {
+				titles[event.ID] = title
+			}
+		}
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			from, to := tc.bounds()
+			page, err := st.GetEventsInRange(ctx, userID, models.EventRange{From: from, To: to})
+			require.NoError(t, err)
+
+			actual := make([]string, 0, len(page.Events))
+			for _, event := range page.Events {
+				require.Equal(t, userID, event.UserID)
+				actual = append(actual, titles[event.ID])
+			}
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
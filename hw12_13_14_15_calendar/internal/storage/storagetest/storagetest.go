@@ -0,0 +1,1243 @@
+package storagetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	customerror "github.com/romandnk/HW/hw12_13_14_15_calendar/internal/errors"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/models"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/recurrence"
+	"github.com/romandnk/HW/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// allowOverlap writes the events without checking that their owners are free.
+var allowOverlap = models.EventOptions{AllowOverlap: true}
+
+// Run checks that the storage follows the semantics shared by all implementations of storage.Storage.
+// newStorage must return the empty storage and is called once per subtest.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, st storage.Storage)
+	}{
+		{name: "create and get by id", fn: testCreateEvent},
+		{name: "partial update", fn: testUpdateEvent},
+		{name: "update recurrence", fn: testUpdateRecurrence},
+		{name: "versions", fn: testVersions},
+		{name: "delete", fn: testDeleteEvent},
+		{name: "ownership", fn: testOwnership},
+		{name: "update occurrence", fn: testUpdateEventOccurrence},
+		{name: "delete following occurrences", fn: testDeleteEventOccurrence},
+		{name: "range pagination", fn: testGetEventsInRange},
+		{name: "recurrence time zone", fn: testRecurrenceTimeZone},
+		{name: "user events by period", fn: testGetUserEventsByPeriod},
+		{name: "intersecting events", fn: testGetIntersectingEvents},
+		{name: "overlaps", fn: testOverlaps},
+		{name: "series overlaps", fn: testSeriesOverlaps},
+		{name: "concurrent overlaps", fn: testConcurrentOverlaps},
+		{name: "delete outdated", fn: testDeleteOutdatedEvents},
+		{name: "notifications", fn: testNotifications},
+		{name: "outbox", fn: testOutbox},
+		{name: "outbox leases", fn: testOutboxLeases},
+		{name: "stale outbox", fn: testStaleOutbox},
+		{name: "recurring notifications", fn: testRecurringNotifications},
+		{name: "attendees", fn: testAttendees},
+		{name: "attendee notifications", fn: testAttendeeNotifications},
+		{name: "free busy", fn: testFreeBusy},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStorage(t))
+		})
+	}
+
+	t.Run("periods", func(t *testing.T) {
+		RunPeriodTests(t, func(t *testing.T) storage.EventStorage {
+			return newStorage(t)
+		})
+	})
+}
+
+func testCreateEvent(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	event := newEvent("create", time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC))
+	event.Description = "description"
+	event.NotificationInterval = 15 * time.Minute
+	event.Reminders = []models.Reminder{
+		{Before: 24 * time.Hour, Channel: models.ChannelEmail, Status: models.StatusPending},
+		{Before: 15 * time.Minute, Channel: models.ChannelLog, Status: models.StatusPending},
+	}
+	event.Recurrence = &models.Recurrence{
+		Frequency:  models.FrequencyWeekly,
+		Interval:   2,
+		ByDay:      []time.Weekday{time.Monday, time.Thursday},
+		Count:      10,
+		Exceptions: []time.Time{event.Date.AddDate(0, 0, 14)},
+	}
+
+	id, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+	require.Equal(t, event.ID, id)
+
+	actual, err := st.GetEventByID(ctx, id)
+	require.NoError(t, err)
+	requireEvent(t, event, actual)
+	require.NotZero(t, actual.Reminders[0].ID)
+	require.NotEqual(t, actual.Reminders[0].ID, actual.Reminders[1].ID)
+
+	_, err = st.GetEventByID(ctx, uuid.New().String())
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+}
+
+func testUpdateEvent(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	event := newEvent("before", time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC))
+	event.Description = "description"
+	event.NotificationInterval = time.Hour
+
+	_, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+
+	// fields missing from the update are left unchanged
+	title := "after"
+	updated, err := st.UpdateEvent(ctx, userID, event.ID, 1, models.EventUpdate{Title: &title}, allowOverlap)
+	require.NoError(t, err)
+
+	expected := event
+	expected.Title = "after"
+	requireEvent(t, expected, updated)
+
+	actual, err := st.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	requireEvent(t, expected, actual)
+
+	// set fields are written even if they are zero
+	var (
+		description          string
+		notificationInterval time.Duration
+	)
+	updated, err = st.UpdateEvent(ctx, userID, event.ID, 2, models.EventUpdate{
+		Description:          &description,
+		NotificationInterval: &notificationInterval,
+	}, allowOverlap)
+	require.NoError(t, err)
+
+	expected.Description = ""
+	expected.NotificationInterval = 0
+	requireEvent(t, expected, updated)
+
+	// the empty update changes only the version
+	updated, err = st.UpdateEvent(ctx, userID, event.ID, 3, models.EventUpdate{}, allowOverlap)
+	require.NoError(t, err)
+	requireEvent(t, expected, updated)
+	require.Equal(t, int64(4), updated.Version)
+
+	// reminders are replaced as a whole, the kept ones do not change their ids
+	reminders := []models.Reminder{
+		{Before: time.Hour, Channel: models.ChannelLog},
+		{Before: 10 * time.Minute, Channel: models.ChannelWebhook},
+	}
+	updated, err = st.UpdateEvent(ctx, userID, event.ID, 4, models.EventUpdate{Reminders: &reminders}, allowOverlap)
+	require.NoError(t, err)
+
+	expected.Reminders = []models.Reminder{
+		{Before: time.Hour, Channel: models.ChannelLog, Status: models.StatusPending},
+		{Before: 10 * time.Minute, Channel: models.ChannelWebhook, Status: models.StatusPending},
+	}
+	requireEvent(t, expected, updated)
+
+	reminders = []models.Reminder{{Before: 10 * time.Minute, Channel: models.ChannelWebhook}}
+	kept, err := st.UpdateEvent(ctx, userID, event.ID, 5, models.EventUpdate{Reminders: &reminders}, allowOverlap)
+	require.NoError(t, err)
+	require.Len(t, kept.Reminders, 1)
+	require.Equal(t, updated.Reminders[1].ID, kept.Reminders[0].ID)
+
+	reminders = nil
+	updated, err = st.UpdateEvent(ctx, userID, event.ID, 6, models.EventUpdate{Reminders: &reminders}, allowOverlap)
+	require.NoError(t, err)
+	require.Empty(t, updated.Reminders)
+}
+
+func testUpdateRecurrence(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	event := newEvent("weekly", time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC))
+	_, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+
+	rule := models.Recurrence{
+		Frequency:  models.FrequencyWeekly,
+		Interval:   1,
+		Count:      3,
+		Exceptions: []time.Time{event.Date.AddDate(0, 0, 7)},
+	}
+	updated, err := st.UpdateEvent(ctx, userID, event.ID, 1, models.EventUpdate{Recurrence: &rule}, allowOverlap)
+	require.NoError(t, err)
+
+	expected := event
+	expected.Recurrence = &rule
+	requireEvent(t, expected, updated)
+
+	updated, err = st.UpdateEvent(ctx, userID, event.ID, 2, models.EventUpdate{ClearRecurrence: true}, allowOverlap)
+	require.NoError(t, err)
+	requireEvent(t, event, updated)
+
+	actual, err := st.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	requireEvent(t, event, actual)
+}
+
+func testVersions(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	event := newEvent("versioned", time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC))
+	_, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+
+	actual, err := st.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), actual.Version)
+	require.False(t, actual.UpdatedAt.IsZero())
+
+	title := "first"
+	updated, err := st.UpdateEvent(ctx, userID, event.ID, 1, models.EventUpdate{Title: &title}, allowOverlap)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), updated.Version)
+	require.False(t, updated.UpdatedAt.Before(actual.UpdatedAt))
+
+	// the concurrent update based on the same version loses
+	title = "second"
+	_, err = st.UpdateEvent(ctx, userID, event.ID, 1, models.EventUpdate{Title: &title}, allowOverlap)
+	require.ErrorIs(t, err, customerror.ErrVersionMismatch)
+
+	err = st.DeleteEvent(ctx, userID, event.ID, 1)
+	require.ErrorIs(t, err, customerror.ErrVersionMismatch)
+
+	actual, err = st.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, "first", actual.Title)
+	require.Equal(t, int64(2), actual.Version)
+
+	require.NoError(t, st.DeleteEvent(ctx, userID, event.ID, 2))
+}
+
+func testDeleteEvent(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	event := newEvent("delete", time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC))
+	_, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+
+	require.NoError(t, st.DeleteEvent(ctx, userID, event.ID, 1))
+
+	_, err = st.GetEventByID(ctx, event.ID)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	err = st.DeleteEvent(ctx, userID, event.ID, 1)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+}
+
+func testOwnership(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	event := newEvent("owned", time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC))
+	_, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+
+	title := "stolen"
+	_, err = st.UpdateEvent(ctx, otherUserID, event.ID, 1, models.EventUpdate{Title: &title}, allowOverlap)
+	require.ErrorIs(t, err, customerror.ErrForbidden)
+
+	err = st.DeleteEvent(ctx, otherUserID, event.ID, 1)
+	require.ErrorIs(t, err, customerror.ErrForbidden)
+
+	_, err = st.UpdateEvent(ctx, userID, uuid.New().String(), 1, models.EventUpdate{Title: &title}, allowOverlap)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	actual, err := st.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	requireEvent(t, event, actual)
+}
+
+func testUpdateEventOccurrence(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	series := newEvent("standup", start)
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 5}
+
+	_, err := st.CreateEvent(ctx, series, allowOverlap)
+	require.NoError(t, err)
+
+	occurrence := start.AddDate(0, 0, 2)
+	title, date := "moved standup", occurrence.Add(2*time.Hour)
+	update := models.EventUpdate{Title: &title, Date: &date}
+	newID := uuid.New().String()
+
+	detached, err := st.UpdateEventOccurrence(ctx, userID, series.ID, 1, occurrence, models.ScopeThis, newID, update, allowOverlap)
+	require.NoError(t, err)
+	require.Equal(t, newID, detached.ID)
+	require.Equal(t, int64(1), detached.Version)
+	require.Equal(t, series.ID, detached.RecurrenceID)
+	require.True(t, occurrence.Equal(detached.OriginalDate))
+
+	page, err := st.GetEventsInRange(ctx, userID, models.EventRange{From: start, To: start.AddDate(0, 0, 7)})
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{
+		start,
+		start.AddDate(0, 0, 1),
+		occurrence.Add(2 * time.Hour),
+		start.AddDate(0, 0, 3),
+		start.AddDate(0, 0, 4),
+	}, dates(page.Events))
+	require.Equal(t, "moved standup", page.Events[2].Title)
+
+	// the series is changed by detaching the occurrence
+	actual, err := st.GetEventByID(ctx, series.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), actual.Version)
+}
+
+func testDeleteEventOccurrence(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	series := newEvent("standup", start)
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+
+	_, err := st.CreateEvent(ctx, series, allowOverlap)
+	require.NoError(t, err)
+
+	err = st.DeleteEventOccurrence(ctx, userID, series.ID, 1, start.AddDate(0, 0, 3), models.ScopeFollowing)
+	require.NoError(t, err)
+
+	page, err := st.GetEventsInRange(ctx, userID, models.EventRange{From: start, To: start.AddDate(0, 0, 7)})
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)}, dates(page.Events))
+
+	err = st.DeleteEventOccurrence(ctx, otherUserID, series.ID, 2, start, models.ScopeThis)
+	require.ErrorIs(t, err, customerror.ErrForbidden)
+
+	err = st.DeleteEventOccurrence(ctx, userID, series.ID, 1, start, models.ScopeThis)
+	require.ErrorIs(t, err, customerror.ErrVersionMismatch)
+}
+
+func testGetEventsInRange(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		_, err := st.CreateEvent(ctx, newEvent("meeting", start.AddDate(0, 0, i)), allowOverlap)
+		require.NoError(t, err)
+	}
+	series := newEvent("Daily SYNC", start.Add(time.Hour))
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+	_, err := st.CreateEvent(ctx, series, allowOverlap)
+	require.NoError(t, err)
+	_, err = st.CreateEvent(ctx, newOtherUserEvent("other user", start), allowOverlap)
+	require.NoError(t, err)
+
+	rng := models.EventRange{
+		From:  start,
+		To:    start.AddDate(0, 0, 5),
+		Order: models.SortDesc,
+		Limit: 3,
+	}
+
+	var actual []models.Event
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 4)
+
+		page, err := st.GetEventsInRange(ctx, userID, rng)
+		require.NoError(t, err)
+		actual = append(actual, page.Events...)
+
+		if page.Next == nil {
+			break
+		}
+		rng.After = page.Next
+	}
+
+	require.Len(t, actual, 10)
+	for i := 1; i < len(actual); i++ {
+		require.True(t, actual[i].Date.Before(actual[i-1].Date))
+	}
+
+	// events of the same date follow each other in the byte order of ids, which differs from
+	// the order of the locale for these ids
+	for _, id := range []string{"a-c", "ab"} {
+		event := newEvent("tie", start.AddDate(0, 0, 2).Add(30*time.Minute))
+		event.ID = id
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	rng = models.EventRange{From: start.AddDate(0, 0, 2), To: start.AddDate(0, 0, 3), Limit: 1}
+
+	var ids []string
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5)
+
+		page, err := st.GetEventsInRange(ctx, userID, rng)
+		require.NoError(t, err)
+		for _, event := range page.Events {
+			ids = append(ids, event.ID)
+		}
+
+		if page.Next == nil {
+			break
+		}
+		rng.After = page.Next
+	}
+	require.Len(t, ids, 4)
+	require.Equal(t, []string{"a-c", "ab"}, ids[1:3])
+
+	page, err := st.GetEventsInRange(ctx, userID, models.EventRange{
+		From:  start,
+		To:    start.AddDate(0, 0, 5),
+		Query: "sync",
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 5)
+	for _, event := range page.Events {
+		require.Equal(t, series.ID, event.ID)
+	}
+}
+
+func testRecurrenceTimeZone(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	// 09:00 CET, the clocks go forward on March 26
+	series := newEvent("standup", time.Date(2023, 3, 24, 8, 0, 0, 0, time.UTC))
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Location: berlin}
+	_, err = st.CreateEvent(ctx, series, allowOverlap)
+	require.NoError(t, err)
+
+	event, err := st.GetEventByID(ctx, series.ID)
+	require.NoError(t, err)
+	requireEvent(t, series, event)
+
+	page, err := st.GetEventsInRange(ctx, userID, models.EventRange{
+		From: time.Date(2023, 3, 25, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2023, 3, 28, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{
+		time.Date(2023, 3, 25, 8, 0, 0, 0, time.UTC),
+		time.Date(2023, 3, 26, 7, 0, 0, 0, time.UTC),
+		time.Date(2023, 3, 27, 7, 0, 0, 0, time.UTC),
+	}, dates(page.Events))
+
+	// the series without the time zone keeps the UTC time
+	_, err = st.UpdateEvent(ctx, userID, series.ID, event.Version, models.EventUpdate{
+		Recurrence: &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1},
+	}, allowOverlap)
+	require.NoError(t, err)
+
+	page, err = st.GetEventsInRange(ctx, userID, models.EventRange{
+		From: time.Date(2023, 3, 27, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2023, 3, 28, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{time.Date(2023, 3, 27, 8, 0, 0, 0, time.UTC)}, dates(page.Events))
+}
+
+func testGetUserEventsByPeriod(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	start := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	series := newEvent("series", start.AddDate(0, -1, 0))
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1}
+	finished := newEvent("finished", start.AddDate(0, -1, 0))
+	finished.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 3}
+	single := newEvent("single", start.AddDate(0, 0, 1))
+
+	for _, event := range []models.Event{series, finished, single, newEvent("later", start.AddDate(0, 1, 0))} {
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	events, err := st.GetUserEventsByPeriod(ctx, userID, start, start.AddDate(0, 0, 7))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, series.ID, events[0].ID)
+	require.Equal(t, single.ID, events[1].ID)
+}
+
+func testGetIntersectingEvents(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	touching := newEvent("touching", date.Add(-time.Hour))
+	overlapping := newEvent("overlapping", date.Add(-30*time.Minute))
+	series := newEvent("series", date.AddDate(0, 0, -7).Add(45*time.Minute))
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1}
+
+	for _, event := range []models.Event{touching, overlapping, series, newOtherUserEvent("other user", date)} {
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	events, err := st.GetIntersectingEvents(ctx, userID, date, date.Add(time.Hour))
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	require.ElementsMatch(t, []string{overlapping.ID, series.ID}, ids)
+}
+
+func testOverlaps(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	checked := models.EventOptions{}
+
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	event := newEvent("event", date)
+	touching := newEvent("touching", date.Add(time.Hour))
+
+	for _, e := range []models.Event{event, touching, newOtherUserEvent("other user", date)} {
+		_, err := st.CreateEvent(ctx, e, checked)
+		require.NoError(t, err)
+	}
+
+	_, err := st.CreateEvent(ctx, newEvent("overlapping", date.Add(30*time.Minute)), checked)
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+	_, err = st.CreateEvent(ctx, newEvent("allowed", date), allowOverlap)
+	require.NoError(t, err)
+
+	// occurrences of the series are checked too
+	series := newEvent("series", date.AddDate(0, 0, -7))
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1}
+	_, err = st.CreateEvent(ctx, series, checked)
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+
+	// the event does not overlap itself
+	title := "renamed"
+	_, err = st.UpdateEvent(ctx, userID, touching.ID, 1, models.EventUpdate{Title: &title}, checked)
+	require.NoError(t, err)
+	moved := date.Add(-30 * time.Minute)
+	_, err = st.UpdateEvent(ctx, userID, touching.ID, 2, models.EventUpdate{Date: &moved}, checked)
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+
+	// the user is busy in the events with accepted invitations only
+	pending := newOtherUserEvent("pending", date.AddDate(0, 0, 1))
+	accepted := newOtherUserEvent("accepted", date.AddDate(0, 0, 2))
+	for _, e := range []models.Event{pending, accepted} {
+		_, err = st.CreateEvent(ctx, e, checked)
+		require.NoError(t, err)
+		_, err = st.InviteAttendees(ctx, otherUserID, e.ID, 1, []int{userID})
+		require.NoError(t, err)
+	}
+	_, err = st.RespondToInvitation(ctx, userID, accepted.ID, models.AttendeeAccepted)
+	require.NoError(t, err)
+
+	_, err = st.CreateEvent(ctx, newEvent("at pending", pending.Date), checked)
+	require.NoError(t, err)
+	_, err = st.CreateEvent(ctx, newEvent("at accepted", accepted.Date), checked)
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+
+	// the changed occurrences are checked, the rest of the series is not busy
+	series = newEvent("series", date.AddDate(0, 0, 7))
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1}
+	busy := newEvent("busy", date.AddDate(0, 0, 14).Add(4*time.Hour))
+	for _, e := range []models.Event{series, busy} {
+		_, err = st.CreateEvent(ctx, e, checked)
+		require.NoError(t, err)
+	}
+
+	later := busy.Date.Add(30 * time.Minute)
+	_, err = st.UpdateEventOccurrence(ctx, userID, series.ID, 1, date.AddDate(0, 0, 14), models.ScopeThis,
+		uuid.New().String(), models.EventUpdate{Date: &later}, checked)
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+	later = series.Date.Add(30 * time.Minute)
+	_, err = st.UpdateEventOccurrence(ctx, userID, series.ID, 1, series.Date, models.ScopeFollowing,
+		uuid.New().String(), models.EventUpdate{Date: &later}, checked)
+	require.NoError(t, err)
+}
+
+// testSeriesOverlaps checks that the moved occurrence is checked against the rest of its series
+// and that the series does not overlap its own detached occurrences.
+func testSeriesOverlaps(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	checked := models.EventOptions{}
+
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+	series := newEvent("series", date)
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+	_, err := st.CreateEvent(ctx, series, checked)
+	require.NoError(t, err)
+
+	// the moved occurrence takes the place of the next one
+	onNext := date.AddDate(0, 0, 2).Add(30 * time.Minute)
+	_, err = st.UpdateEventOccurrence(ctx, userID, series.ID, 1, date.AddDate(0, 0, 1), models.ScopeThis,
+		uuid.New().String(), models.EventUpdate{Date: &onNext}, checked)
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+
+	// the replaced occurrence itself is free
+	later := date.AddDate(0, 0, 1).Add(30 * time.Minute)
+	_, err = st.UpdateEventOccurrence(ctx, userID, series.ID, 1, date.AddDate(0, 0, 1), models.ScopeThis,
+		uuid.New().String(), models.EventUpdate{Date: &later}, checked)
+	require.NoError(t, err)
+
+	_, err = st.UpdateEventOccurrence(ctx, userID, series.ID, 2, date.AddDate(0, 0, 3), models.ScopeThis,
+		uuid.New().String(), models.EventUpdate{Date: &onNext}, allowOverlap)
+	require.NoError(t, err)
+
+	// the change of the whole series does not collide with its detached occurrences
+	description := "changed"
+	_, err = st.UpdateEvent(ctx, userID, series.ID, 3, models.EventUpdate{Description: &description}, checked)
+	require.NoError(t, err)
+
+	// other events still collide with the series
+	_, err = st.CreateEvent(ctx, newEvent("busy", date.AddDate(0, 0, 5).Add(13*time.Hour)), checked)
+	require.NoError(t, err)
+	moved := date.Add(13 * time.Hour)
+	_, err = st.UpdateEvent(ctx, userID, series.ID, 4, models.EventUpdate{Date: &moved}, checked)
+	require.ErrorIs(t, err, customerror.ErrDateBusy)
+}
+
+// testConcurrentOverlaps checks that only one of the concurrent requests takes the free time.
+func testConcurrentOverlaps(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+	date := time.Date(2023, 7, 24, 10, 0, 0, 0, time.UTC)
+
+	errs := make([]error, 8)
+
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = st.CreateEvent(ctx, newEvent("concurrent", date), models.EventOptions{})
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		require.ErrorIs(t, err, customerror.ErrDateBusy)
+	}
+	require.Equal(t, 1, created)
+}
+
+func testDeleteOutdatedEvents(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	outdated := newEvent("outdated", now.AddDate(-2, 0, 0))
+	recent := newEvent("recent", now.AddDate(0, -1, 0))
+	endless := newEvent("endless", now.AddDate(-2, 0, 0))
+	endless.Recurrence = &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1}
+	finished := newEvent("finished", now.AddDate(-2, 0, 0))
+	finished.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 3}
+
+	for _, event := range []models.Event{outdated, recent, endless, finished} {
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, st.DeleteOutdatedEvents(ctx))
+
+	for _, event := range []models.Event{outdated, finished} {
+		_, err := st.GetEventByID(ctx, event.ID)
+		require.ErrorIs(t, err, customerror.ErrNotFound, event.Title)
+	}
+	for _, event := range []models.Event{recent, endless} {
+		_, err := st.GetEventByID(ctx, event.ID)
+		require.NoError(t, err, event.Title)
+	}
+}
+
+func testNotifications(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	later := newEvent("later", now.Add(3*time.Hour))
+	later.Reminders = []models.Reminder{
+		{Before: time.Hour, Channel: models.ChannelLog, Status: models.StatusPending},
+		{Before: 10 * time.Minute, Channel: models.ChannelEmail, Status: models.StatusPending},
+	}
+	sooner := newEvent("sooner", now.Add(2*time.Hour))
+	sooner.Reminders = []models.Reminder{{Before: 30 * time.Minute, Channel: models.ChannelWebhook, Status: models.StatusPending}}
+	overdue := newEvent("overdue", now.Add(10*time.Minute))
+	overdue.Reminders = []models.Reminder{{Before: 20 * time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}}
+	stale := newEvent("stale", now.Add(-time.Hour))
+	stale.Reminders = []models.Reminder{{Before: time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}}
+
+	for _, event := range []models.Event{later, sooner, overdue, stale} {
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	claim := models.NotificationClaim{
+		Now:    now,
+		Window: 3 * time.Hour,
+		Grace:  30 * time.Minute,
+		Lease:  time.Minute,
+		Limit:  3,
+	}
+
+	// every reminder is claimed separately, overdue ones only within the grace period
+	notifications, err := st.ClaimNotifications(ctx, claim)
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+	require.Equal(t, overdue.ID, notifications[0].EventID)
+	require.Equal(t, sooner.ID, notifications[1].EventID)
+	require.Equal(t, sooner.Title, notifications[1].Title)
+	require.Equal(t, userID, notifications[1].UserID)
+	require.Equal(t, 30*time.Minute, notifications[1].Interval)
+	require.Equal(t, models.ChannelWebhook, notifications[1].Channel)
+	require.True(t, sooner.Date.Equal(notifications[1].Date))
+	require.Equal(t, later.ID, notifications[2].EventID)
+	require.Equal(t, time.Hour, notifications[2].Interval)
+
+	// leased reminders are skipped by other claims
+	leased, err := st.ClaimNotifications(ctx, claim)
+	require.NoError(t, err)
+	require.Len(t, leased, 1)
+	require.Equal(t, later.ID, leased[0].EventID)
+	require.Equal(t, 10*time.Minute, leased[0].Interval)
+
+	reminderID := notifications[1].ReminderID
+	require.NoError(t, st.ScheduleNotification(ctx, reminderID, []byte(sooner.ID)))
+
+	// expired leases are claimed again unless the reminder is scheduled
+	claim.Now = now.Add(2 * time.Minute)
+	notifications, err = st.ClaimNotifications(ctx, claim)
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+	for _, notification := range notifications {
+		require.NotEqual(t, reminderID, notification.ReminderID)
+	}
+
+	// the queued reminder is put to the outbox only once
+	err = st.ScheduleNotification(ctx, reminderID, []byte(sooner.ID))
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	err = st.ScheduleNotification(ctx, reminderID+1000, nil)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	event, err := st.GetEventByID(ctx, sooner.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusQueued, event.Reminders[0].Status)
+	require.False(t, event.Reminders[0].QueuedAt.IsZero())
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: time.Now().Add(time.Minute), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, sooner.ID, messages[0].EventID)
+	require.Equal(t, reminderID, messages[0].ReminderID)
+	require.Equal(t, []byte(sooner.ID), messages[0].Payload)
+
+	require.NoError(t, st.CompleteOutboxMessage(ctx, messages[0].ID))
+
+	event, err = st.GetEventByID(ctx, sooner.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusSent, event.Reminders[0].Status)
+	require.False(t, event.Reminders[0].SentAt.IsZero())
+
+	// the delivered reminder is kept by the replacement of reminders
+	reminders := []models.Reminder{event.Reminders[0], {Before: 5 * time.Minute, Channel: models.ChannelLog}}
+	event, err = st.UpdateEvent(ctx, userID, sooner.ID, event.Version, models.EventUpdate{Reminders: &reminders}, allowOverlap)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusSent, event.Reminders[0].Status)
+	require.Equal(t, models.StatusPending, event.Reminders[1].Status)
+
+	// reminders of the moved event are sent again
+	date := sooner.Date.Add(time.Hour)
+	event, err = st.UpdateEvent(ctx, userID, sooner.ID, event.Version, models.EventUpdate{Date: &date}, allowOverlap)
+	require.NoError(t, err)
+	require.Equal(t, reminderID, event.Reminders[0].ID)
+	require.Equal(t, models.StatusPending, event.Reminders[0].Status)
+	require.True(t, event.Reminders[0].SentAt.IsZero())
+}
+
+func testOutbox(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := newEvent("first", now.Add(time.Hour))
+	second := newEvent("second", now.Add(2*time.Hour))
+
+	for _, event := range []models.Event{first, second} {
+		event.Reminders = []models.Reminder{{Before: time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}}
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+
+		stored, err := st.GetEventByID(ctx, event.ID)
+		require.NoError(t, err)
+		require.NoError(t, st.ScheduleNotification(ctx, stored.Reminders[0].ID, []byte(event.Title)))
+	}
+
+	soon := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: soon, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, first.ID, messages[0].EventID)
+	require.Equal(t, second.ID, messages[1].EventID)
+	require.Zero(t, messages[0].Attempts)
+
+	messages, err = st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: soon, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	// the postponed message isn't due until its next attempt
+	next := soon.Add(time.Hour)
+	require.NoError(t, st.RetryOutboxMessage(ctx, messages[0].ID, next, "nack"))
+
+	messages, err = st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: soon, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, second.ID, messages[0].EventID)
+
+	require.NoError(t, st.CompleteOutboxMessage(ctx, messages[0].ID))
+
+	err = st.CompleteOutboxMessage(ctx, messages[0].ID)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	messages, err = st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: next, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, first.ID, messages[0].EventID)
+	require.Equal(t, 1, messages[0].Attempts)
+	require.Equal(t, "nack", messages[0].LastError)
+	require.True(t, next.Equal(messages[0].NextAttemptAt))
+
+	err = st.RetryOutboxMessage(ctx, messages[0].ID+100, next, "nack")
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	// the message is dropped with the deleted event
+	require.NoError(t, st.DeleteEvent(ctx, userID, first.ID, 1))
+	err = st.CompleteOutboxMessage(ctx, messages[0].ID)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+}
+
+func testAttendees(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	const guestID = 3
+
+	start := time.Date(2023, 7, 3, 10, 0, 0, 0, time.UTC)
+	series := newOtherUserEvent("planning", start)
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1, Count: 3}
+	_, err := st.CreateEvent(ctx, series, allowOverlap)
+	require.NoError(t, err)
+
+	rng := models.EventRange{From: start, To: start.AddDate(0, 0, 7)}
+
+	_, err = st.InviteAttendees(ctx, userID, series.ID, 1, []int{userID})
+	require.ErrorIs(t, err, customerror.ErrForbidden)
+	_, err = st.InviteAttendees(ctx, otherUserID, series.ID, 2, []int{userID})
+	require.ErrorIs(t, err, customerror.ErrVersionMismatch)
+
+	invited, err := st.InviteAttendees(ctx, otherUserID, series.ID, 1, []int{userID, guestID})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), invited.Version)
+	require.Equal(t, []models.Attendee{
+		{UserID: userID, Status: models.AttendeeNeedsAction},
+		{UserID: guestID, Status: models.AttendeeNeedsAction},
+	}, invited.Attendees)
+
+	// invited events are listed with the own events of the attendee
+	page, err := st.GetEventsInRange(ctx, userID, rng)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 3)
+	require.Len(t, page.Events[0].Attendees, 2)
+
+	_, err = st.RespondToInvitation(ctx, 4, series.ID, models.AttendeeAccepted)
+	require.ErrorIs(t, err, customerror.ErrForbidden)
+	_, err = st.RespondToInvitation(ctx, userID, uuid.New().String(), models.AttendeeAccepted)
+	require.ErrorIs(t, err, customerror.ErrNotFound)
+
+	accepted, err := st.RespondToInvitation(ctx, userID, series.ID, models.AttendeeAccepted)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), accepted.Version)
+	require.Equal(t, models.AttendeeAccepted, accepted.Attendees[0].Status)
+	require.False(t, accepted.Attendees[0].RespondedAt.IsZero())
+
+	// the invitation is sent again without losing the answer
+	invited, err = st.InviteAttendees(ctx, otherUserID, series.ID, 3, []int{userID})
+	require.NoError(t, err)
+	require.Equal(t, models.AttendeeAccepted, invited.Attendees[0].Status)
+
+	// the detached occurrence keeps the attendees
+	title := "moved planning"
+	detached, err := st.UpdateEventOccurrence(ctx, otherUserID, series.ID, 4, start.AddDate(0, 0, 1), models.ScopeThis,
+		uuid.New().String(), models.EventUpdate{Title: &title}, allowOverlap)
+	require.NoError(t, err)
+	require.Len(t, detached.Attendees, 2)
+	require.Equal(t, models.AttendeeAccepted, detached.Attendees[0].Status)
+
+	_, err = st.RespondToInvitation(ctx, userID, series.ID, models.AttendeeDeclined)
+	require.NoError(t, err)
+
+	// declined events are not listed
+	page, err = st.GetEventsInRange(ctx, userID, rng)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	require.Equal(t, detached.ID, page.Events[0].ID)
+
+	page, err = st.GetEventsInRange(ctx, guestID, rng)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 3)
+}
+
+func testOutboxLeases(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 6; i++ {
+		event := newEvent("leased", now.Add(time.Hour))
+		event.Reminders = []models.Reminder{{Before: time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}}
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+
+		stored, err := st.GetEventByID(ctx, event.ID)
+		require.NoError(t, err)
+		require.NoError(t, st.ScheduleNotification(ctx, stored.Reminders[0].ID, []byte(event.ID)))
+	}
+
+	// concurrent claims never return the same message
+	soon := time.Now().Add(time.Minute).Truncate(time.Second)
+	claim := models.OutboxClaim{Now: soon, Lease: time.Minute, Limit: 2}
+	claimed := make([][]models.OutboxMessage, 4)
+	errs := make([]error, len(claimed))
+
+	var wg sync.WaitGroup
+	for i := range claimed {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			claimed[i], errs[i] = st.ClaimOutboxMessages(ctx, claim)
+		}(i)
+	}
+	wg.Wait()
+
+	ids := make(map[int64]struct{})
+	for i, messages := range claimed {
+		require.NoError(t, errs[i])
+		for _, message := range messages {
+			require.NotContains(t, ids, message.ID)
+			ids[message.ID] = struct{}{}
+		}
+	}
+	require.Len(t, ids, 6)
+
+	claim.Limit = 10
+	messages, err := st.ClaimOutboxMessages(ctx, claim)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+
+	// the retried message is released at once, all messages are claimed again when their leases expire
+	retried := claimed[0][0]
+	require.NoError(t, st.RetryOutboxMessage(ctx, retried.ID, soon, "nack"))
+
+	messages, err = st.ClaimOutboxMessages(ctx, claim)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, retried.ID, messages[0].ID)
+
+	claim.Now = soon.Add(time.Minute)
+	messages, err = st.ClaimOutboxMessages(ctx, claim)
+	require.NoError(t, err)
+	require.Len(t, messages, 6)
+}
+
+func testStaleOutbox(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	event := newEvent("moved", now.Add(10*time.Minute))
+	event.Reminders = []models.Reminder{{Before: 10 * time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}}
+	_, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+
+	claim := models.NotificationClaim{Now: now, Window: time.Minute, Grace: time.Minute, Lease: time.Minute, Limit: 10}
+	schedule := func(now time.Time) {
+		t.Helper()
+
+		claim.Now = now
+		notifications, err := st.ClaimNotifications(ctx, claim)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		require.NoError(t, st.ScheduleNotification(ctx, notifications[0].ReminderID, []byte(notifications[0].Date.String())))
+	}
+	schedule(now)
+
+	// the message for the old time is dropped when the event is moved
+	date := event.Date.Add(time.Hour)
+	moved, err := st.UpdateEvent(ctx, userID, event.ID, 1, models.EventUpdate{Date: &date}, allowOverlap)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusPending, moved.Reminders[0].Status)
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: time.Now().Add(time.Minute), Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, messages)
+
+	schedule(now.Add(time.Hour))
+
+	messages, err = st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: time.Now().Add(time.Minute), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, []byte(date.String()), messages[0].Payload)
+
+	require.NoError(t, st.CompleteOutboxMessage(ctx, messages[0].ID))
+	stored, err := st.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusSent, stored.Reminders[0].Status)
+
+	// messages of the deleted event are not published
+	other := newEvent("deleted", now.Add(10*time.Minute))
+	other.Reminders = []models.Reminder{{Before: 10 * time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}}
+	_, err = st.CreateEvent(ctx, other, allowOverlap)
+	require.NoError(t, err)
+	schedule(now)
+
+	require.NoError(t, st.DeleteEvent(ctx, userID, other.ID, 1))
+
+	messages, err = st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: time.Now().Add(time.Minute), Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
+
+func testRecurringNotifications(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	series := newEvent("weekly", now.Add(10*time.Minute))
+	series.Recurrence = &models.Recurrence{Frequency: models.FrequencyWeekly, Interval: 1, Count: 3}
+	series.Reminders = []models.Reminder{{Before: 10 * time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}}
+	_, err := st.CreateEvent(ctx, series, allowOverlap)
+	require.NoError(t, err)
+
+	claim := models.NotificationClaim{Window: time.Minute, Grace: time.Minute, Lease: time.Minute, Limit: 10}
+	deliver := func(now, occurrence time.Time) {
+		t.Helper()
+
+		claim.Now = now
+		notifications, err := st.ClaimNotifications(ctx, claim)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		require.True(t, occurrence.Equal(notifications[0].Date), "expected %s, actual %s", occurrence, notifications[0].Date)
+		require.NoError(t, st.ScheduleNotification(ctx, notifications[0].ReminderID, []byte("reminder")))
+
+		messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: time.Now().Add(time.Minute), Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.NoError(t, st.CompleteOutboxMessage(ctx, messages[0].ID))
+	}
+
+	deliver(now, series.Date)
+
+	// the sent reminder is due again for the second occurrence
+	second := series.Date.AddDate(0, 0, 7)
+	deliver(now.AddDate(0, 0, 7), second)
+
+	// the excluded occurrence is not reminded of
+	require.NoError(t, st.DeleteEventOccurrence(ctx, userID, series.ID, 1, series.Date.AddDate(0, 0, 14), models.ScopeThis))
+
+	claim.Now = now.AddDate(0, 0, 14)
+	notifications, err := st.ClaimNotifications(ctx, claim)
+	require.NoError(t, err)
+	require.Empty(t, notifications)
+}
+
+func testAttendeeNotifications(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	event := newEvent("review", now.Add(10*time.Minute))
+	event.Reminders = []models.Reminder{{Before: 10 * time.Minute, Channel: models.ChannelLog, Status: models.StatusPending}}
+	_, err := st.CreateEvent(ctx, event, allowOverlap)
+	require.NoError(t, err)
+
+	_, err = st.InviteAttendees(ctx, userID, event.ID, 1, []int{otherUserID, 3})
+	require.NoError(t, err)
+	_, err = st.RespondToInvitation(ctx, otherUserID, event.ID, models.AttendeeAccepted)
+	require.NoError(t, err)
+
+	notifications, err := st.ClaimNotifications(ctx, models.NotificationClaim{
+		Now:    now,
+		Window: time.Minute,
+		Grace:  time.Minute,
+		Lease:  time.Minute,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	require.Equal(t, []int{otherUserID}, notifications[0].Attendees)
+
+	reminderID := notifications[0].ReminderID
+	require.NoError(t, st.ScheduleNotification(ctx, reminderID, []byte("owner"), []byte("attendee")))
+
+	messages, err := st.ClaimOutboxMessages(ctx, models.OutboxClaim{Now: time.Now().Add(time.Minute), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+
+	// the reminder is sent when the messages to all its recipients are published
+	require.NoError(t, st.CompleteOutboxMessage(ctx, messages[0].ID))
+	stored, err := st.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusQueued, stored.Reminders[0].Status)
+
+	require.NoError(t, st.CompleteOutboxMessage(ctx, messages[1].ID))
+	stored, err = st.GetEventByID(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusSent, stored.Reminders[0].Status)
+}
+
+func testFreeBusy(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	const (
+		guestID    = 3
+		declinedID = 4
+	)
+
+	from := time.Date(2023, 7, 3, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 2)
+
+	standup := newEvent("standup", from.Add(-24*time.Hour+9*time.Hour))
+	standup.Duration = 30 * time.Minute
+	standup.Recurrence = &models.Recurrence{Frequency: models.FrequencyDaily, Interval: 1}
+	review := newOtherUserEvent("review", from.Add(9*time.Hour+15*time.Minute))
+	overnight := newOtherUserEvent("overnight", from.Add(-2*time.Hour))
+	overnight.Duration = 3 * time.Hour
+	outside := newEvent("outside", to)
+	for _, event := range []models.Event{standup, review, overnight, outside} {
+		_, err := st.CreateEvent(ctx, event, allowOverlap)
+		require.NoError(t, err)
+	}
+
+	_, err := st.InviteAttendees(ctx, otherUserID, review.ID, 1, []int{userID, guestID, declinedID})
+	require.NoError(t, err)
+	_, err = st.RespondToInvitation(ctx, declinedID, review.ID, models.AttendeeDeclined)
+	require.NoError(t, err)
+
+	calendars, err := st.GetFreeBusy(ctx, []int{userID, otherUserID, guestID, declinedID}, from, to)
+	require.NoError(t, err)
+	require.Len(t, calendars, 4)
+
+	// occurrences of the series and the invitation are merged
+	require.Equal(t, userID, calendars[0].UserID)
+	require.Equal(t, []models.Interval{
+		{Start: from.Add(9 * time.Hour), End: from.Add(10*time.Hour + 15*time.Minute)},
+		{Start: from.Add(33 * time.Hour), End: from.Add(33*time.Hour + 30*time.Minute)},
+	}, intervalsUTC(calendars[0].Busy))
+
+	// the event started before the range is cut
+	require.Equal(t, otherUserID, calendars[1].UserID)
+	require.Equal(t, []models.Interval{
+		{Start: from, End: from.Add(time.Hour)},
+		{Start: from.Add(9*time.Hour + 15*time.Minute), End: from.Add(10*time.Hour + 15*time.Minute)},
+	}, intervalsUTC(calendars[1].Busy))
+
+	// the user who has not answered is busy, the one who has declined is not
+	require.Equal(t, []models.Interval{
+		{Start: from.Add(9*time.Hour + 15*time.Minute), End: from.Add(10*time.Hour + 15*time.Minute)},
+	}, intervalsUTC(calendars[2].Busy))
+	require.Empty(t, calendars[3].Busy)
+}
+
+// newEvent returns the one hour event of userID.
+func newEvent(title string, date time.Time) models.Event {
+	return models.Event{
+		ID:       uuid.New().String(),
+		Title:    title,
+		Date:     date,
+		Duration: time.Hour,
+		UserID:   userID,
+	}
+}
+
+func newOtherUserEvent(title string, date time.Time) models.Event {
+	event := newEvent(title, date)
+	event.UserID = otherUserID
+	return event
+}
+
+// requireEvent compares events ignoring locations of dates which are not kept by every storage.
+func requireEvent(t *testing.T, expected, actual models.Event) {
+	t.Helper()
+
+	require.Equal(t, expected.ID, actual.ID)
+	require.Equal(t, expected.Title, actual.Title)
+	require.True(t, expected.Date.Equal(actual.Date), "expected date %s, actual %s", expected.Date, actual.Date)
+	require.Equal(t, expected.Duration, actual.Duration)
+	require.Equal(t, expected.Description, actual.Description)
+	require.Equal(t, expected.UserID, actual.UserID)
+	require.Equal(t, expected.NotificationInterval, actual.NotificationInterval)
+	require.Equal(t, reminderStates(expected.Reminders), reminderStates(actual.Reminders))
+	require.Equal(t, expected.RecurrenceID, actual.RecurrenceID)
+	require.True(t, expected.OriginalDate.Equal(actual.OriginalDate))
+
+	if expected.Recurrence == nil {
+		require.Nil(t, actual.Recurrence)
+		return
+	}
+	require.NotNil(t, actual.Recurrence)
+	require.Equal(t, expected.Recurrence.Frequency, actual.Recurrence.Frequency)
+	require.Equal(t, expected.Recurrence.Interval, actual.Recurrence.Interval)
+	require.Equal(t, expected.Recurrence.ByDay, actual.Recurrence.ByDay)
+	require.Equal(t, expected.Recurrence.Count, actual.Recurrence.Count)
+	require.True(t, expected.Recurrence.Until.Equal(actual.Recurrence.Until))
+	require.Equal(t, utc(expected.Recurrence.Exceptions), utc(actual.Recurrence.Exceptions))
+	require.Equal(t, recurrence.Location(*expected.Recurrence).String(), recurrence.Location(*actual.Recurrence).String())
+}
+
+// reminderStates returns reminders without ids and timestamps which are assigned by the storage.
+func reminderStates(reminders []models.Reminder) []models.Reminder {
+	var states []models.Reminder
+	for _, reminder := range reminders {
+		states = append(states, models.Reminder{
+			Before:  reminder.Before,
+			Channel: reminder.Channel,
+			Status:  reminder.Status,
+		})
+	}
+	return states
+}
+
+// dates returns dates of the events in UTC.
+func dates(events []models.Event) []time.Time {
+	result := make([]time.Time, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Date.UTC())
+	}
+	return result
+}
+
+func utc(dates []time.Time) []time.Time {
+	result := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		result = append(result, date.UTC())
+	}
+	return result
+}
+
+func intervalsUTC(intervals []models.Interval) []models.Interval {
+	result := make([]models.Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		result = append(result, models.Interval{Start: interval.Start.UTC(), End: interval.End.UTC()})
+	}
+	return result
+}
@@ -0,0 +1,253 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	PolicyDefault = "default"
+	// PolicyStrict allows only TLS 1.2 suites with forward secrecy and AEAD ciphers.
+	PolicyStrict = "strict"
+)
+
+// DefaultReloadInterval is how often the files are checked for changes if the interval is not set.
+const DefaultReloadInterval = 30 * time.Second
+
+var (
+	ErrMinVersion   = errors.New("tls min version must be 1.2 or 1.3")
+	ErrCipherPolicy = errors.New("tls cipher policy must be default or strict")
+	ErrNoClientCA   = errors.New("no certificates in tls client ca file")
+	ErrNoClientCert = errors.New("client certificate is missing")
+)
+
+var strictCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Config enables TLS if the cert file is set. Clients must present certificates signed by
+// the client CA if it is set.
+type Config struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	// MinVersion is 1.2 or 1.3, 1.2 if empty.
+	MinVersion string
+	// CipherPolicy is default or strict, default if empty. TLS 1.3 suites are not configurable.
+	CipherPolicy   string
+	ReloadInterval time.Duration
+}
+
+func (c Config) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// ParseVersion returns the TLS version of the min version.
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, ErrMinVersion
+	}
+}
+
+// CipherSuites returns the suites of the policy, nil means the defaults of crypto/tls.
+func CipherSuites(policy string) ([]uint16, error) {
+	switch policy {
+	case "", PolicyDefault:
+		return nil, nil
+	case PolicyStrict:
+		return strictCipherSuites, nil
+	default:
+		return nil, ErrCipherPolicy
+	}
+}
+
+// fileStamp changes when the file is replaced or rewritten.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader serves the certificate and verifies clients with the CA of the files it was loaded from
+// last time, so the files are replaced without restarting the servers.
+type Reloader struct {
+	cfg          Config
+	minVersion   uint16
+	cipherSuites []uint16
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    map[string]fileStamp
+}
+
+func NewReloader(cfg Config) (*Reloader, error) {
+	minVersion, err := ParseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := CipherSuites(cfg.CipherPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = DefaultReloadInterval
+	}
+
+	r := &Reloader{
+		cfg:          cfg,
+		minVersion:   minVersion,
+		cipherSuites: cipherSuites,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the files, the previous certificate and CA are kept if they cannot be loaded.
+func (r *Reloader) Reload() error {
+	stamps, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("error loading tls certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("error reading tls client ca: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return ErrNoClientCA
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.stamps = stamps
+
+	return nil
+}
+
+func (r *Reloader) stat() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp, 3)
+	for _, path := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading tls file: %w", err)
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+// changed reports whether any file differs from the loaded one.
+func (r *Reloader) changed() (bool, error) {
+	stamps, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for path, stamp := range stamps {
+		if loaded, ok := r.stamps[path]; !ok || !loaded.modTime.Equal(stamp.modTime) || loaded.size != stamp.size {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Watch checks the files every reload interval and reloads them if they change until ctx is done.
+// The files are polled rather than watched, so certificates replaced through symlinks are noticed too.
+func (r *Reloader) Watch(ctx context.Context, onError func(err error)) {
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.changed()
+			if err == nil && changed {
+				err = r.Reload()
+			}
+			if err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// TLSConfig returns the server config which always uses the last loaded certificate and client CA.
+func (r *Reloader) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:   r.minVersion,
+		CipherSuites: r.cipherSuites,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+	}
+
+	if r.cfg.ClientCAFile != "" {
+		// clients are verified after the handshake against the current pool, because ClientCAs cannot be swapped
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = r.verifyClient
+	}
+
+	return cfg
+}
+
+func (r *Reloader) verifyClient(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return ErrNoClientCert
+	}
+
+	r.mu.RLock()
+	roots := r.clientCAs
+	r.mu.RUnlock()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
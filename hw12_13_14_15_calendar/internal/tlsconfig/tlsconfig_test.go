@@ -0,0 +1,229 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func (c testCert) keyPair(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func (c testCert) keyPEM(t *testing.T) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+// newCert issues the certificate signed by the parent, the certificate is self-signed if the parent is nil.
+func newCert(t *testing.T, name string, parent *testCert, usage x509.ExtKeyUsage) testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return testCert{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// handshake connects the client to the server config and returns the certificate presented by the server.
+func handshake(t *testing.T, serverConfig *tls.Config, client *tls.Config) (*x509.Certificate, error) {
+	t.Helper()
+
+	lsn, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer lsn.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := lsn.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", lsn.Addr().String(), client)
+	if err != nil {
+		<-serverErr
+		return nil, err
+	}
+	defer conn.Close()
+
+	// the server verifies the client after the client is done with the handshake
+	if err := <-serverErr; err != nil {
+		return nil, err
+	}
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		MinVersion:   "1.2",
+		CipherPolicy: PolicyStrict,
+	}
+
+	ca := newCert(t, "ca", nil, x509.ExtKeyUsageAny)
+	server := newCert(t, "localhost", &ca, x509.ExtKeyUsageServerAuth)
+	client := newCert(t, "client", &ca, x509.ExtKeyUsageClientAuth)
+	stranger := newCert(t, "stranger", nil, x509.ExtKeyUsageClientAuth)
+
+	modTime := time.Now().Add(-time.Minute)
+	writeFile(t, cfg.CertFile, server.pem, modTime)
+	writeFile(t, cfg.KeyFile, server.keyPEM(t), modTime)
+	writeFile(t, cfg.ClientCAFile, ca.pem, modTime)
+
+	r, err := NewReloader(cfg)
+	require.NoError(t, err)
+
+	serverConfig := r.TLSConfig()
+	require.Equal(t, uint16(tls.VersionTLS12), serverConfig.MinVersion)
+	require.Equal(t, strictCipherSuites, serverConfig.CipherSuites)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientConfig := func(cert *testCert) *tls.Config {
+		c := &tls.Config{RootCAs: roots, ServerName: "localhost", MinVersion: tls.VersionTLS12}
+		if cert != nil {
+			c.Certificates = []tls.Certificate{cert.keyPair(t)}
+		}
+		return c
+	}
+
+	presented, err := handshake(t, serverConfig, clientConfig(&client))
+	require.NoError(t, err)
+	require.Equal(t, server.cert.SerialNumber, presented.SerialNumber)
+
+	_, err = handshake(t, serverConfig, clientConfig(nil))
+	require.Error(t, err)
+
+	_, err = handshake(t, serverConfig, clientConfig(&stranger))
+	require.Error(t, err)
+
+	changed, err := r.changed()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// the broken certificate is not loaded, the previous one is served
+	writeFile(t, cfg.CertFile, []byte("broken"), modTime.Add(time.Second))
+	changed, err = r.changed()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Error(t, r.Reload())
+
+	presented, err = handshake(t, serverConfig, clientConfig(&client))
+	require.NoError(t, err)
+	require.Equal(t, server.cert.SerialNumber, presented.SerialNumber)
+
+	// the renewed certificate and the new CA are used without restarting the server
+	renewed := newCert(t, "localhost", &ca, x509.ExtKeyUsageServerAuth)
+	writeFile(t, cfg.CertFile, renewed.pem, modTime.Add(2*time.Second))
+	writeFile(t, cfg.KeyFile, renewed.keyPEM(t), modTime.Add(2*time.Second))
+	writeFile(t, cfg.ClientCAFile, append(ca.pem, stranger.pem...), modTime.Add(2*time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r.cfg.ReloadInterval = 10 * time.Millisecond
+	go r.Watch(ctx, func(err error) { t.Error(err) })
+
+	require.Eventually(t, func() bool {
+		changed, err := r.changed()
+		return err == nil && !changed
+	}, time.Second, 10*time.Millisecond)
+
+	presented, err = handshake(t, serverConfig, clientConfig(&stranger))
+	require.NoError(t, err)
+	require.Equal(t, renewed.cert.SerialNumber, presented.SerialNumber)
+}
+
+func TestNewReloaderError(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewReloader(Config{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: filepath.Join(dir, "missing.key")})
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = NewReloader(Config{CertFile: "server.crt", MinVersion: "1.1"})
+	require.ErrorIs(t, err, ErrMinVersion)
+
+	_, err = NewReloader(Config{CertFile: "server.crt", CipherPolicy: "legacy"})
+	require.ErrorIs(t, err, ErrCipherPolicy)
+
+	ca := newCert(t, "ca", nil, x509.ExtKeyUsageAny)
+	server := newCert(t, "localhost", &ca, x509.ExtKeyUsageServerAuth)
+	cfg := Config{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	writeFile(t, cfg.CertFile, server.pem, time.Now())
+	writeFile(t, cfg.KeyFile, server.keyPEM(t), time.Now())
+	writeFile(t, cfg.ClientCAFile, []byte("not a certificate"), time.Now())
+
+	_, err = NewReloader(cfg)
+	require.ErrorIs(t, err, ErrNoClientCA)
+}
@@ -0,0 +1,7 @@
+DROP INDEX idx_events_recurrence_id;
+
+ALTER TABLE events
+    DROP COLUMN recurrence_rule,
+    DROP COLUMN recurrence_exceptions,
+    DROP COLUMN recurrence_id,
+    DROP COLUMN original_date;
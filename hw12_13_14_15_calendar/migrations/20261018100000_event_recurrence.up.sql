@@ -0,0 +1,7 @@
+ALTER TABLE events
+    ADD COLUMN recurrence_rule VARCHAR(255),
+    ADD COLUMN recurrence_exceptions TIMESTAMPTZ[],
+    ADD COLUMN recurrence_id VARCHAR(36) REFERENCES events (id) ON DELETE CASCADE,
+    ADD COLUMN original_date TIMESTAMPTZ;
+
+CREATE INDEX idx_events_recurrence_id ON events (recurrence_id);
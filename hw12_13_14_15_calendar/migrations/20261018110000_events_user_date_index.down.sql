@@ -0,0 +1 @@
+DROP INDEX idx_events_user_id_date;
@@ -0,0 +1 @@
+CREATE INDEX idx_events_user_id_date ON events (user_id, date);
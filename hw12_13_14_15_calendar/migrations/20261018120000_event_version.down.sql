@@ -0,0 +1,3 @@
+ALTER TABLE events
+    DROP COLUMN version,
+    DROP COLUMN updated_at;
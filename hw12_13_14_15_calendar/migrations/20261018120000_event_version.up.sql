@@ -0,0 +1,3 @@
+ALTER TABLE events
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1,
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,11 @@
+ALTER TABLE outbox
+    DROP COLUMN reminder_id;
+
+ALTER TABLE events
+    ADD COLUMN scheduled boolean DEFAULT FALSE NOT NULL;
+
+UPDATE events
+SET scheduled = true
+WHERE id IN (SELECT event_id FROM reminders WHERE status <> 'pending');
+
+DROP TABLE IF EXISTS reminders;
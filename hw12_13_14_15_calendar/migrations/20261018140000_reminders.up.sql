@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS reminders
+(
+    id            BIGSERIAL PRIMARY KEY,
+    event_id      VARCHAR(36) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    remind_before INTERVAL    NOT NULL,
+    channel       TEXT        NOT NULL DEFAULT 'log',
+    status        TEXT        NOT NULL DEFAULT 'pending',
+    queued_at     TIMESTAMPTZ,
+    sent_at       TIMESTAMPTZ,
+    UNIQUE (event_id, remind_before, channel)
+);
+CREATE INDEX idx_reminders_status ON reminders (status);
+
+-- the notification interval of the event becomes its only reminder
+INSERT INTO reminders (event_id, remind_before, status)
+SELECT id, notification_interval, CASE WHEN scheduled THEN 'sent' ELSE 'pending' END
+FROM events
+WHERE notification_interval > interval '0';
+
+ALTER TABLE events
+    DROP COLUMN scheduled;
+
+ALTER TABLE outbox
+    ADD COLUMN reminder_id BIGINT;
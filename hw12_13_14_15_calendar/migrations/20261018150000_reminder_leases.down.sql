@@ -0,0 +1,2 @@
+ALTER TABLE reminders
+    DROP COLUMN leased_until;
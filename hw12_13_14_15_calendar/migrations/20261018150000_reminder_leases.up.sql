@@ -0,0 +1,3 @@
+-- claimed reminders are hidden from other schedulers until the lease expires
+ALTER TABLE reminders
+    ADD COLUMN leased_until TIMESTAMPTZ;
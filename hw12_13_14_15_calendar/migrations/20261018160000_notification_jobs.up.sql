@@ -0,0 +1,13 @@
+-- the queue of notifications for the postgres message queue backend
+CREATE TABLE IF NOT EXISTS notification_jobs
+(
+    id           BIGSERIAL PRIMARY KEY,
+    payload      BYTEA       NOT NULL,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    leased_until TIMESTAMPTZ,
+    last_error   TEXT        NOT NULL DEFAULT '',
+    dead_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_notification_jobs_available_at ON notification_jobs (available_at) WHERE dead_at IS NULL;
@@ -0,0 +1,8 @@
+-- keys of delivered notifications, so duplicates published by the scheduler are dropped by the sender
+CREATE TABLE IF NOT EXISTS notification_deliveries
+(
+    key        TEXT PRIMARY KEY,
+    delivered  BOOLEAN     NOT NULL DEFAULT false,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_notification_deliveries_expires_at ON notification_deliveries (expires_at);
@@ -0,0 +1,10 @@
+-- users invited to events and their answers
+CREATE TABLE IF NOT EXISTS attendees
+(
+    event_id     VARCHAR(36) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id      INTEGER     NOT NULL,
+    status       TEXT        NOT NULL DEFAULT 'needs-action',
+    responded_at TIMESTAMPTZ,
+    PRIMARY KEY (event_id, user_id)
+);
+CREATE INDEX idx_attendees_user_id ON attendees (user_id);
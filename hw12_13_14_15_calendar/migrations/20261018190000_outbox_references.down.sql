@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_outbox_reminder_id;
+DROP INDEX IF EXISTS idx_outbox_event_id;
+
+ALTER TABLE outbox
+    DROP CONSTRAINT IF EXISTS outbox_reminder_id_fkey,
+    DROP CONSTRAINT IF EXISTS outbox_event_id_fkey;
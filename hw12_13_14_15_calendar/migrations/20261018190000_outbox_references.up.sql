@@ -0,0 +1,10 @@
+-- unpublished messages of deleted events and reminders are dropped with them
+DELETE FROM outbox o
+WHERE NOT EXISTS (SELECT 1 FROM events e WHERE e.id = o.event_id)
+   OR (o.reminder_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM reminders r WHERE r.id = o.reminder_id));
+
+ALTER TABLE outbox
+    ADD CONSTRAINT outbox_event_id_fkey FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE,
+    ADD CONSTRAINT outbox_reminder_id_fkey FOREIGN KEY (reminder_id) REFERENCES reminders (id) ON DELETE CASCADE;
+CREATE INDEX idx_outbox_event_id ON outbox (event_id);
+CREATE INDEX idx_outbox_reminder_id ON outbox (reminder_id);
@@ -0,0 +1,9 @@
+-- every occurrence of the recurring event is reminded of in turn, the status refers to this occurrence.
+-- It is NULL when no occurrences are left
+ALTER TABLE reminders
+    ADD COLUMN occurrence TIMESTAMPTZ;
+
+UPDATE reminders r
+SET occurrence = e.date
+FROM events e
+WHERE e.id = r.event_id;
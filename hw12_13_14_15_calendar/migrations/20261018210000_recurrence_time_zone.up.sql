@@ -0,0 +1,3 @@
+-- IANA name of the time zone in which occurrences of the series keep their wall clock time, NULL means UTC
+ALTER TABLE events
+    ADD COLUMN recurrence_time_zone VARCHAR(64);
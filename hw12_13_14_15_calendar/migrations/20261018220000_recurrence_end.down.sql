@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_events_user_id_recurrence_end;
+
+ALTER TABLE events
+    DROP COLUMN recurrence_end;
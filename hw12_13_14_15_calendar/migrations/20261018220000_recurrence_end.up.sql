@@ -0,0 +1,6 @@
+-- start of the last occurrence of the series, NULL if the series is endless. Series written before
+-- are read as endless until they are changed
+ALTER TABLE events
+    ADD COLUMN recurrence_end TIMESTAMPTZ;
+
+CREATE INDEX idx_events_user_id_recurrence_end ON events (user_id, recurrence_end) WHERE recurrence_rule IS NOT NULL;
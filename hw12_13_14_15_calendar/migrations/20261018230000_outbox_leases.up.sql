@@ -0,0 +1,3 @@
+-- claimed messages are hidden from other relays until the lease expires
+ALTER TABLE outbox
+    ADD COLUMN leased_until TIMESTAMPTZ;